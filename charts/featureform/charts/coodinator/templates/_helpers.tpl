@@ -60,3 +60,14 @@ Create the name of the service account to use
 {{- default "default" .Values.serviceAccount.name }}
 {{- end }}
 {{- end }}
+
+{{/*
+The job pod node selector as comma-separated key=value pairs
+*/}}
+{{- define "coordinator.jobNodeSelector" -}}
+{{- $pairs := list }}
+{{- range $key, $value := .Values.jobs.nodeSelector }}
+{{- $pairs = append $pairs (printf "%s=%s" $key $value) }}
+{{- end }}
+{{- join "," $pairs }}
+{{- end }}
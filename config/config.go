@@ -1,6 +1,11 @@
 package config
 
-import "github.com/featureform/helpers"
+import (
+	"strings"
+	"time"
+
+	"github.com/featureform/helpers"
+)
 
 // image paths
 const (
@@ -8,6 +13,35 @@ const (
 	WorkerImage     = "featureformcom/worker"
 )
 
+// The number of rows a materialization chunk reads ahead of its online writes
+const MaterializeBufferSize = 1000
+
+// Files larger than UploadPartSize bytes are uploaded to blob stores in parts
+// of that size, and each part is attempted up to UploadAttempts times
+const (
+	UploadPartSize = 8 << 20
+	UploadAttempts = 3
+)
+
+// The rows of a directory's part files are counted NumRowsParallelism files
+// at a time
+const NumRowsParallelism = 8
+
+// The coordinator keeps up to MaxIdleProviders providers open between the jobs
+// that use them, and replaces each one after ProviderMaxLifetimeMinutes
+const (
+	MaxIdleProviders           = 10
+	ProviderMaxLifetimeMinutes = 30
+)
+
+// Job runners that take longer than RunnerTimeoutSeconds are given
+// RunnerGracePeriodSeconds more to finish before their job fails. A timeout of
+// zero waits on runners indefinitely.
+const (
+	RunnerTimeoutSeconds     = 0
+	RunnerGracePeriodSeconds = 300
+)
+
 // script paths
 const (
 	SparkLocalScriptPath  = "/app/provider/scripts/spark/offline_store_spark_runner.py"
@@ -39,3 +73,78 @@ func GetPythonLocalInitPath() string {
 func GetPythonRemoteInitPath() string {
 	return helpers.GetEnv("PYTHON_REMOTE_INIT_PATH", PythonRemoteInitPath)
 }
+
+func GetMaterializeBufferSize() int {
+	return helpers.GetEnvInt("MATERIALIZE_BUFFER_SIZE", MaterializeBufferSize)
+}
+
+func GetMaxIdleProviders() int {
+	return helpers.GetEnvInt("MAX_IDLE_PROVIDERS", MaxIdleProviders)
+}
+
+func GetProviderMaxLifetime() time.Duration {
+	return time.Duration(helpers.GetEnvInt("PROVIDER_MAX_LIFETIME_MINUTES", ProviderMaxLifetimeMinutes)) * time.Minute
+}
+
+func GetUploadPartSize() int {
+	return helpers.GetEnvInt("UPLOAD_PART_SIZE", UploadPartSize)
+}
+
+func GetUploadAttempts() int {
+	return helpers.GetEnvInt("UPLOAD_ATTEMPTS", UploadAttempts)
+}
+
+func GetNumRowsParallelism() int {
+	return helpers.GetEnvInt("NUM_ROWS_PARALLELISM", NumRowsParallelism)
+}
+
+func GetRunnerTimeout() time.Duration {
+	return time.Duration(helpers.GetEnvInt("RUNNER_TIMEOUT_SECONDS", RunnerTimeoutSeconds)) * time.Second
+}
+
+func GetRunnerGracePeriod() time.Duration {
+	return time.Duration(helpers.GetEnvInt("RUNNER_GRACE_PERIOD_SECONDS", RunnerGracePeriodSeconds)) * time.Second
+}
+
+// Default CPU and memory requests and limits of Kubernetes job pods. Empty
+// ones aren't set.
+func GetJobCPURequest() string {
+	return helpers.GetEnv("JOB_CPU_REQUEST", "")
+}
+
+func GetJobCPULimit() string {
+	return helpers.GetEnv("JOB_CPU_LIMIT", "")
+}
+
+func GetJobMemoryRequest() string {
+	return helpers.GetEnv("JOB_MEMORY_REQUEST", "")
+}
+
+func GetJobMemoryLimit() string {
+	return helpers.GetEnv("JOB_MEMORY_LIMIT", "")
+}
+
+// GetJobNodeSelector returns the node labels that Kubernetes job pods are
+// scheduled on, set as comma-separated key=value pairs. Pairs without a key are
+// skipped, and nil is returned when none are set.
+func GetJobNodeSelector() map[string]string {
+	var selector map[string]string
+	for _, pair := range strings.Split(helpers.GetEnv("JOB_NODE_SELECTOR", ""), ",") {
+		key, value, _ := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if selector == nil {
+			selector = make(map[string]string)
+		}
+		selector[key] = strings.TrimSpace(value)
+	}
+	return selector
+}
+
+// GetJobTolerations returns the JSON list of Kubernetes tolerations set on job
+// pods, or an empty string when none are set.
+func GetJobTolerations() string {
+	return helpers.GetEnv("JOB_TOLERATIONS", "")
+}
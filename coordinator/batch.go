@@ -0,0 +1,320 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/featureform/metadata"
+)
+
+// BatchReport is the outcome of ExecuteBatch. Every resource in the batch is in
+// exactly one of Succeeded or Failed.
+type BatchReport struct {
+	Succeeded []metadata.ResourceID
+	Failed    map[metadata.ResourceID]error
+}
+
+// Err aggregates the batch's failures into a single error, or returns nil if
+// every job succeeded.
+func (r BatchReport) Err() error {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(r.Failed))
+	for resID, err := range r.Failed {
+		msgs = append(msgs, fmt.Sprintf("%s %s (%s): %v", resID.Type, resID.Name, resID.Variant, err))
+	}
+	return fmt.Errorf("%d of %d jobs failed: %s", len(r.Failed), len(r.Failed)+len(r.Succeeded), strings.Join(msgs, "; "))
+}
+
+// ExecuteBatch runs the jobs for a set of resources created together, such as
+// by a single CreateAll, so that each resource runs after the resources it
+// depends on. A failure doesn't stop the batch: independent jobs still run, and
+// jobs that depend on a failed resource are marked FAILED rather than being
+// left CREATED.
+func (c *Coordinator) ExecuteBatch(resources []metadata.ResourceID) BatchReport {
+	return c.executeBatch(context.Background(), resources, 1)
+}
+
+// buildUnreadyTransformations runs the jobs of the transformations that resID
+// reads from, directly or through other transformations, that haven't started
+// building yet. This lets a feature be materialized over a transformation that
+// nothing else has built, rather than waiting on it indefinitely. Transformations
+// that are already pending are left to whoever is building them. Transformations
+// that haven't started when ctx is done aren't built.
+func (c *Coordinator) buildUnreadyTransformations(ctx context.Context, resID metadata.ResourceID) error {
+	unready := make([]metadata.ResourceID, 0)
+	if err := c.collectUnreadyTransformations(ctx, resID, make(map[metadata.ResourceID]bool), &unready); err != nil {
+		return fmt.Errorf("resolve transformation dependencies: %w", err)
+	}
+	if len(unready) == 0 {
+		return nil
+	}
+	c.Logger.Infow("Building unready transformations", "resource", resID, "transformations", unready)
+	report := c.executeBatch(ctx, unready, 1)
+	for transformation, err := range report.Failed {
+		// Another coordinator ran the job after we checked its status, so the
+		// caller will pick up its result when it waits on the source
+		var jobErr *JobDoesNotExistError
+		if errors.As(err, &jobErr) {
+			continue
+		}
+		return fmt.Errorf("build transformation %s (%s): %w", transformation.Name, transformation.Variant, err)
+	}
+	return nil
+}
+
+func (c *Coordinator) collectUnreadyTransformations(ctx context.Context, resID metadata.ResourceID, seen map[metadata.ResourceID]bool, unready *[]metadata.ResourceID) error {
+	deps, err := c.batchDependencies(resID)
+	if err != nil {
+		return err
+	}
+	for _, dep := range deps {
+		if dep.Type != metadata.SOURCE_VARIANT || seen[dep] {
+			continue
+		}
+		seen[dep] = true
+		source, err := c.Metadata.GetSourceVariant(ctx, metadata.NameVariant{Name: dep.Name, Variant: dep.Variant})
+		if err != nil {
+			return err
+		}
+		if !source.IsSQLTransformation() && !source.IsDFTransformation() {
+			continue
+		}
+		if status := source.Status(); status != metadata.CREATED && status != metadata.NO_STATUS {
+			continue
+		}
+		if err := c.collectUnreadyTransformations(ctx, dep, seen, unready); err != nil {
+			return err
+		}
+		*unready = append(*unready, dep)
+	}
+	return nil
+}
+
+// ExecuteJobs runs the jobs for a set of resources like ExecuteBatch, but starts
+// each job as soon as the resources it depends on in the set have finished,
+// with up to MaxConcurrentJobs jobs running at once. It returns the outcome of
+// every resource's job, which is nil if the job succeeded. Jobs that haven't
+// started when ctx is done fail with its error, and their resources are marked
+// FAILED; jobs that already started are waited on.
+func (c *Coordinator) ExecuteJobs(ctx context.Context, resources []metadata.ResourceID) map[metadata.ResourceID]error {
+	report := c.executeBatch(ctx, resources, c.MaxConcurrentJobs)
+	results := make(map[metadata.ResourceID]error, len(report.Succeeded)+len(report.Failed))
+	for _, resID := range report.Succeeded {
+		results[resID] = nil
+	}
+	for resID, err := range report.Failed {
+		results[resID] = err
+	}
+	return results
+}
+
+// executeBatch runs the jobs of ExecuteBatch and ExecuteJobs, with up to limit
+// of them running at once. Each job starts once the resources it depends on in
+// the batch have succeeded.
+func (c *Coordinator) executeBatch(ctx context.Context, resources []metadata.ResourceID, limit int) BatchReport {
+	report := BatchReport{
+		Succeeded: make([]metadata.ResourceID, 0, len(resources)),
+		Failed:    make(map[metadata.ResourceID]error),
+	}
+	dependencies := make(map[metadata.ResourceID][]metadata.ResourceID, len(resources))
+	unique := make([]metadata.ResourceID, 0, len(resources))
+	for _, resID := range resources {
+		if _, has := dependencies[resID]; has {
+			continue
+		}
+		if _, failed := report.Failed[resID]; failed {
+			continue
+		}
+		deps, err := c.batchDependencies(resID)
+		if err != nil {
+			c.skipJob(report, resID, fmt.Errorf("get dependencies: %w", err))
+			continue
+		}
+		dependencies[resID] = deps
+		unique = append(unique, resID)
+	}
+	waiting, cyclic := orderBatch(unique, dependencies)
+	for _, resID := range cyclic {
+		c.skipJob(report, resID, fmt.Errorf("resource is in, or depends on, a dependency cycle"))
+	}
+	inBatch := make(map[metadata.ResourceID]bool, len(resources))
+	for _, resID := range resources {
+		inBatch[resID] = true
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	type outcome struct {
+		resID metadata.ResourceID
+		err   error
+	}
+	done := make(chan outcome)
+	succeeded := make(map[metadata.ResourceID]bool, len(resources))
+	running := 0
+	for len(waiting) > 0 || running > 0 {
+		blocked := make([]metadata.ResourceID, 0, len(waiting))
+		for _, resID := range waiting {
+			if err := ctx.Err(); err != nil {
+				c.skipJob(report, resID, err)
+				continue
+			}
+			ready, err := dependenciesFinished(resID, dependencies[resID], inBatch, succeeded, report.Failed)
+			if err != nil {
+				c.skipJob(report, resID, err)
+				continue
+			}
+			if !ready || running >= limit {
+				blocked = append(blocked, resID)
+				continue
+			}
+			running++
+			go func(resID metadata.ResourceID) {
+				done <- outcome{resID: resID, err: c.ExecuteJob(metadata.GetJobKey(resID))}
+			}(resID)
+		}
+		waiting = blocked
+		if running == 0 {
+			// Only possible if a job waits on a resource that will never finish,
+			// which orderBatch rules out
+			for _, resID := range waiting {
+				c.skipJob(report, resID, fmt.Errorf("dependencies of resource never finished"))
+			}
+			break
+		}
+		finished := <-done
+		running--
+		if finished.err != nil {
+			report.Failed[finished.resID] = finished.err
+			continue
+		}
+		succeeded[finished.resID] = true
+		report.Succeeded = append(report.Succeeded, finished.resID)
+	}
+	return report
+}
+
+// skipJob fails the job of a resource that won't run, and marks the resource
+// FAILED so that it isn't left CREATED.
+func (c *Coordinator) skipJob(report BatchReport, resID metadata.ResourceID, err error) {
+	report.Failed[resID] = err
+	if statusErr := c.setStatus(resID, metadata.FAILED, err.Error()); statusErr != nil {
+		c.Logger.Errorw("Could not set status of skipped job", "resource", resID, "error", statusErr)
+	}
+}
+
+// dependenciesFinished reports whether every dependency of resID that's in the
+// batch has succeeded, or returns an error if any of them failed.
+func dependenciesFinished(resID metadata.ResourceID, deps []metadata.ResourceID, inBatch, succeeded map[metadata.ResourceID]bool, failed map[metadata.ResourceID]error) (bool, error) {
+	ready := true
+	for _, dep := range deps {
+		if !inBatch[dep] || dep == resID {
+			continue
+		}
+		if _, has := failed[dep]; has {
+			return false, DependencyFailedError{resourceID: resID, dependency: dep}
+		}
+		if !succeeded[dep] {
+			ready = false
+		}
+	}
+	return ready, nil
+}
+
+// orderBatch sorts resources so that every resource comes after the resources in
+// the batch it depends on, keeping the submitted order where possible.
+// Dependencies outside of the batch are ignored. Resources that can't be ordered
+// because of a cycle are returned separately.
+func orderBatch(resources []metadata.ResourceID, dependencies map[metadata.ResourceID][]metadata.ResourceID) ([]metadata.ResourceID, []metadata.ResourceID) {
+	inBatch := make(map[metadata.ResourceID]bool, len(resources))
+	for _, resID := range resources {
+		inBatch[resID] = true
+	}
+	done := make(map[metadata.ResourceID]bool, len(resources))
+	ordered := make([]metadata.ResourceID, 0, len(resources))
+	for len(ordered) < len(resources) {
+		progressed := false
+		for _, resID := range resources {
+			if done[resID] {
+				continue
+			}
+			ready := true
+			for _, dep := range dependencies[resID] {
+				if inBatch[dep] && !done[dep] && dep != resID {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				done[resID] = true
+				ordered = append(ordered, resID)
+				progressed = true
+			}
+		}
+		if !progressed {
+			break
+		}
+	}
+	cyclic := make([]metadata.ResourceID, 0)
+	for _, resID := range resources {
+		if !done[resID] {
+			cyclic = append(cyclic, resID)
+		}
+	}
+	return ordered, cyclic
+}
+
+func (c *Coordinator) batchDependencies(resID metadata.ResourceID) ([]metadata.ResourceID, error) {
+	ctx := context.Background()
+	nameVariant := metadata.NameVariant{Name: resID.Name, Variant: resID.Variant}
+	sourceIDs := func(sources []metadata.NameVariant) []metadata.ResourceID {
+		ids := make([]metadata.ResourceID, len(sources))
+		for i, source := range sources {
+			ids[i] = metadata.ResourceID{Name: source.Name, Variant: source.Variant, Type: metadata.SOURCE_VARIANT}
+		}
+		return ids
+	}
+	switch resID.Type {
+	case metadata.SOURCE_VARIANT:
+		source, err := c.Metadata.GetSourceVariant(ctx, nameVariant)
+		if err != nil {
+			return nil, err
+		}
+		if source.IsSQLTransformation() {
+			return sourceIDs(source.SQLTransformationSources()), nil
+		}
+		if source.IsDFTransformation() {
+			return sourceIDs(source.DFTransformationSources()), nil
+		}
+		return nil, nil
+	case metadata.FEATURE_VARIANT:
+		feature, err := c.Metadata.GetFeatureVariant(ctx, nameVariant)
+		if err != nil {
+			return nil, err
+		}
+		return sourceIDs([]metadata.NameVariant{feature.Source()}), nil
+	case metadata.LABEL_VARIANT:
+		label, err := c.Metadata.GetLabelVariant(ctx, nameVariant)
+		if err != nil {
+			return nil, err
+		}
+		return sourceIDs([]metadata.NameVariant{label.Source()}), nil
+	case metadata.TRAINING_SET_VARIANT:
+		ts, err := c.Metadata.GetTrainingSetVariant(ctx, nameVariant)
+		if err != nil {
+			return nil, err
+		}
+		deps := make([]metadata.ResourceID, 0, len(ts.Features())+1)
+		for _, feature := range ts.Features() {
+			deps = append(deps, metadata.ResourceID{Name: feature.Name, Variant: feature.Variant, Type: metadata.FEATURE_VARIANT})
+		}
+		label := ts.Label()
+		deps = append(deps, metadata.ResourceID{Name: label.Name, Variant: label.Variant, Type: metadata.LABEL_VARIANT})
+		return deps, nil
+	default:
+		return nil, fmt.Errorf("not a valid resource type for running jobs")
+	}
+}
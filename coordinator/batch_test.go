@@ -0,0 +1,40 @@
+package coordinator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/featureform/metadata"
+)
+
+func TestOrderBatch(t *testing.T) {
+	source := metadata.ResourceID{Name: "source", Type: metadata.SOURCE_VARIANT}
+	transformation := metadata.ResourceID{Name: "transformation", Type: metadata.SOURCE_VARIANT}
+	feature := metadata.ResourceID{Name: "feature", Type: metadata.FEATURE_VARIANT}
+	label := metadata.ResourceID{Name: "label", Type: metadata.LABEL_VARIANT}
+	ts := metadata.ResourceID{Name: "ts", Type: metadata.TRAINING_SET_VARIANT}
+	external := metadata.ResourceID{Name: "external", Type: metadata.SOURCE_VARIANT}
+	dependencies := map[metadata.ResourceID][]metadata.ResourceID{
+		ts:             {feature, label},
+		feature:        {transformation},
+		label:          {external},
+		transformation: {source},
+	}
+	ordered, cyclic := orderBatch([]metadata.ResourceID{ts, feature, label, transformation, source}, dependencies)
+	expected := []metadata.ResourceID{label, source, transformation, feature, ts}
+	if !reflect.DeepEqual(ordered, expected) {
+		t.Fatalf("expected order %v, got %v", expected, ordered)
+	}
+	if len(cyclic) != 0 {
+		t.Fatalf("expected no cycles, got %v", cyclic)
+	}
+
+	dependencies[source] = []metadata.ResourceID{feature}
+	ordered, cyclic = orderBatch([]metadata.ResourceID{ts, feature, label, transformation, source}, dependencies)
+	if !reflect.DeepEqual(ordered, []metadata.ResourceID{label}) {
+		t.Fatalf("expected only the label to be ordered, got %v", ordered)
+	}
+	if len(cyclic) != 4 {
+		t.Fatalf("expected 4 resources to be caught in or behind the cycle, got %v", cyclic)
+	}
+}
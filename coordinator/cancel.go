@@ -0,0 +1,117 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/featureform/metadata"
+)
+
+// Prefix of the keys CancelJob creates to ask the coordinator running a job to
+// stop it
+const cancelJobPrefix = "CANCELJOB__"
+
+// How long a cancellation request is kept. Coordinators only act on requests
+// made while they're running the job, so it just has to outlive the request
+// being delivered.
+const cancelRequestTTL = 60
+
+func cancelJobKey(jobKey string) string {
+	return cancelJobPrefix + jobKey
+}
+
+// runningJob is a job this coordinator is executing
+type runningJob struct {
+	cancel    context.CancelFunc
+	cancelled int32
+}
+
+func (j *runningJob) cancelWithRequest() {
+	atomic.StoreInt32(&j.cancelled, 1)
+	j.cancel()
+}
+
+// wasCancelled reports whether the job was stopped by CancelJob, rather than
+// by its own context.
+func (j *runningJob) wasCancelled() bool {
+	return atomic.LoadInt32(&j.cancelled) == 1
+}
+
+// CancelJob stops a resource's job, on whichever coordinator is running it, and
+// marks the resource FAILED. The job is deleted straight away, so the resource
+// is no longer waiting on a job, but its lock is only released by the
+// coordinator running it once its runners have stopped. Jobs stop the next time
+// they check whether they've been cancelled, and runners that implement
+// runner.CancellableRunner stop while they run.
+func (c *Coordinator) CancelJob(resID metadata.ResourceID) error {
+	jobKey := metadata.GetJobKey(resID)
+	resp, err := (*c.KVClient).Get(context.Background(), jobKey)
+	if err != nil {
+		return fmt.Errorf("get job: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return &JobDoesNotExistError{key: jobKey}
+	}
+	c.Logger.Infow("Cancelling job", "resource", resID)
+	lease, err := c.EtcdClient.Grant(context.Background(), cancelRequestTTL)
+	if err != nil {
+		return fmt.Errorf("grant lease: %w", err)
+	}
+	if _, err := (*c.KVClient).Put(context.Background(), cancelJobKey(jobKey), "", clientv3.WithLease(lease.ID)); err != nil {
+		return fmt.Errorf("request cancellation: %w", err)
+	}
+	c.cancelRunningJob(jobKey)
+	if _, err := (*c.KVClient).Delete(context.Background(), jobKey); err != nil {
+		return fmt.Errorf("delete job: %w", err)
+	}
+	if err := c.setStatus(resID, metadata.FAILED, ErrJobCancelled.Error()); err != nil {
+		return fmt.Errorf("set cancelled status: %w", err)
+	}
+	return nil
+}
+
+// trackRunningJob returns a context for executing a job that's cancelled when
+// CancelJob is called for it, along with the job, which must be released once
+// it's done.
+func (c *Coordinator) trackRunningJob(ctx context.Context, jobKey string) (context.Context, *runningJob, func()) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	job := &runningJob{cancel: cancel}
+	c.runningMtx.Lock()
+	if c.running == nil {
+		c.running = make(map[string]*runningJob)
+	}
+	c.running[jobKey] = job
+	c.runningMtx.Unlock()
+	// Requests made on other coordinators arrive through etcd
+	go func() {
+		for wresp := range c.EtcdClient.Watch(jobCtx, cancelJobKey(jobKey)) {
+			for _, ev := range wresp.Events {
+				if ev.Type == mvccpb.PUT {
+					job.cancelWithRequest()
+					return
+				}
+			}
+		}
+	}()
+	release := func() {
+		cancel()
+		c.runningMtx.Lock()
+		defer c.runningMtx.Unlock()
+		if c.running[jobKey] == job {
+			delete(c.running, jobKey)
+		}
+	}
+	return jobCtx, job, release
+}
+
+func (c *Coordinator) cancelRunningJob(jobKey string) {
+	c.runningMtx.Lock()
+	defer c.runningMtx.Unlock()
+	if job, has := c.running[jobKey]; has {
+		job.cancelWithRequest()
+	}
+}
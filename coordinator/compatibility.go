@@ -0,0 +1,193 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/featureform/metadata"
+	"github.com/featureform/provider"
+)
+
+// checkSourceCompatibility compares the columns of a source variant's table
+// with the most recent ready variant of the same source. If the new table is
+// missing a column that a feature or label of the previous variant reads, those
+// resources would break once they're moved to the new variant, so the job fails,
+// or only logs a warning if WarnOnIncompatibleSource is set. The columns are
+// only read if the previous variant has columns in use.
+func (c *Coordinator) checkSourceCompatibility(source *metadata.SourceVariant, resID metadata.ResourceID, getColumns func() ([]string, error)) error {
+	previous, err := c.previousSourceVariant(source)
+	if err != nil {
+		return fmt.Errorf("get previous source variant: %w", err)
+	}
+	if previous == nil {
+		return nil
+	}
+	uses, err := c.sourceColumnUses(previous)
+	if err != nil {
+		return fmt.Errorf("get columns used by previous source variant: %w", err)
+	}
+	if len(uses) == 0 {
+		return nil
+	}
+	columns, err := getColumns()
+	if err != nil {
+		return fmt.Errorf("get source columns: %w", err)
+	}
+	has := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		has[column] = true
+	}
+	missing := make(map[string][]string)
+	for column, users := range uses {
+		if !has[column] {
+			missing[column] = users
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	compatErr := IncompatibleSourceSchemaError{
+		resourceID: resID,
+		previous:   metadata.NameVariant{Name: previous.Name(), Variant: previous.Variant()},
+		missing:    missing,
+	}
+	if c.WarnOnIncompatibleSource {
+		c.Logger.Warnw("Registered source is incompatible with its previous variant", "resource", resID, "error", compatErr)
+		return nil
+	}
+	return compatErr
+}
+
+// checkRegisteredSourceCompatibility checks a source's primary table once it's
+// registered, deleting the table if the check fails so that it isn't left
+// behind without a ready source.
+func (c *Coordinator) checkRegisteredSourceCompatibility(source *metadata.SourceVariant, resID metadata.ResourceID, store provider.OfflineStore, table provider.PrimaryTable) error {
+	err := c.checkSourceCompatibility(source, resID, func() ([]string, error) {
+		return primaryTableColumns(table)
+	})
+	if err == nil {
+		return nil
+	}
+	id := provider.ResourceID{Name: resID.Name, Variant: resID.Variant, Type: provider.Primary}
+	if deletable, ok := store.(provider.DeletablePrimaryOfflineStore); ok {
+		if deleteErr := deletable.DeletePrimaryTable(id); deleteErr != nil {
+			c.Logger.Errorw("Could not delete primary table of incompatible source", "resource", id, "error", deleteErr)
+		}
+	} else {
+		c.Logger.Warnw("Primary table of incompatible source can't be deleted", "resource", id, "store", store.Type())
+	}
+	return err
+}
+
+// previousSourceVariant returns the most recently created ready variant of the
+// source, other than source itself, or nil if there isn't one.
+func (c *Coordinator) previousSourceVariant(source *metadata.SourceVariant) (*metadata.SourceVariant, error) {
+	ctx := context.Background()
+	parent, err := c.Metadata.GetSource(ctx, source.Name())
+	if err != nil {
+		return nil, err
+	}
+	variants, err := parent.FetchVariants(c.Metadata, ctx)
+	if err != nil {
+		return nil, err
+	}
+	var previous *metadata.SourceVariant
+	for _, variant := range variants {
+		if variant.Variant() == source.Variant() || variant.Status() != metadata.READY {
+			continue
+		}
+		if previous == nil || variant.Created().After(previous.Created()) {
+			previous = variant
+		}
+	}
+	return previous, nil
+}
+
+// sourceColumnUses maps each column of a source variant that its features and
+// labels read to a description of the resources reading it.
+func (c *Coordinator) sourceColumnUses(source *metadata.SourceVariant) (map[string][]string, error) {
+	ctx := context.Background()
+	uses := make(map[string][]string)
+	addColumns := func(resource string, location interface{}) {
+		columns, ok := location.(metadata.ResourceVariantColumns)
+		if !ok {
+			return
+		}
+		for _, column := range []string{columns.Entity, columns.Value, columns.TS} {
+			if column != "" {
+				uses[column] = append(uses[column], resource)
+			}
+		}
+	}
+	features, err := source.FetchFeatures(c.Metadata, ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, feature := range features {
+		addColumns(fmt.Sprintf("feature %s (%s)", feature.Name(), feature.Variant()), feature.LocationColumns())
+	}
+	labels, err := source.FetchLabels(c.Metadata, ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, label := range labels {
+		addColumns(fmt.Sprintf("label %s (%s)", label.Name(), label.Variant()), label.LocationColumns())
+	}
+	return uses, nil
+}
+
+// checkLocationColumns checks that the source columns a feature or label maps
+// to its entity, value and timestamp exist in its source's table, so that a
+// mistyped column fails with the columns that are available rather than an
+// error from the offline store. Columns are compared case insensitively, since
+// some stores report them upper cased. If the table's columns can't be read,
+// the check is skipped and the offline store is left to report any problem.
+func (c *Coordinator) checkLocationColumns(table provider.PrimaryTable, location metadata.ResourceVariantColumns) error {
+	columns, err := primaryTableColumns(table)
+	if err != nil {
+		c.Logger.Warnw("Could not get source columns to check", "table", table.GetName(), "error", err)
+		return nil
+	}
+	has := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		has[strings.ToLower(column)] = true
+	}
+	roles := []struct {
+		role, column string
+	}{
+		{"entity", location.Entity},
+		{"value", location.Value},
+		{"timestamp", location.TS},
+	}
+	missing := make([]string, 0)
+	for _, role := range roles {
+		if role.column != "" && !has[strings.ToLower(role.column)] {
+			missing = append(missing, fmt.Sprintf("%s column %q", role.role, role.column))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s not in source table %s, which has columns %s", strings.Join(missing, ", "), table.GetName(), strings.Join(columns, ", "))
+	}
+	return nil
+}
+
+func primaryTableColumns(table provider.PrimaryTable) ([]string, error) {
+	if schemaTable, ok := table.(provider.SchemaPrimaryTable); ok {
+		schema, err := schemaTable.GetSchema()
+		if err != nil {
+			return nil, err
+		}
+		columns := make([]string, len(schema.Columns))
+		for i, column := range schema.Columns {
+			columns[i] = column.Name
+		}
+		return columns, nil
+	}
+	iter, err := table.IterateSegment(0)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	return iter.Columns(), nil
+}
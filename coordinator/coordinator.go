@@ -3,9 +3,16 @@ package coordinator
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	db "github.com/jackc/pgx/v4"
@@ -13,8 +20,10 @@ import (
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/client/v3/concurrency"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 
 	cfg "github.com/featureform/config"
+	"github.com/featureform/filestore"
 	"github.com/featureform/kubernetes"
 	"github.com/featureform/metadata"
 	"github.com/featureform/provider"
@@ -24,58 +33,236 @@ import (
 	"github.com/featureform/types"
 )
 
+// backoffStrategy returns how long to wait after the given failed attempt,
+// counting from zero.
+type backoffStrategy func(attempt int) time.Duration
+
+func constantBackoff(delay time.Duration) backoffStrategy {
+	return func(int) time.Duration {
+		return delay
+	}
+}
+
+// exponentialBackoff doubles the delay after each failed attempt, starting at
+// base and capped at maxDelay. With jitter, each delay is instead picked at
+// random between half of it and all of it, so that callers that failed at the
+// same time don't all retry at the same time.
+func exponentialBackoff(base, maxDelay time.Duration, jitter *rand.Rand) backoffStrategy {
+	return func(attempt int) time.Duration {
+		delay := base
+		for i := 0; i < attempt && delay < maxDelay; i++ {
+			delay *= 2
+		}
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+		if jitter != nil && delay > 1 {
+			delay = delay/2 + time.Duration(jitter.Int63n(int64(delay/2)+1))
+		}
+		return delay
+	}
+}
+
+// backoffOption configures retryWithBackoff
+type backoffOption func(*backoffOptions)
+
+type backoffOptions struct {
+	jitter bool
+	seed   int64
+}
+
+// withJitter randomizes each delay. A nonzero seed makes the delays the same on
+// every call, such as for tests.
+func withJitter(seed int64) backoffOption {
+	return func(opts *backoffOptions) {
+		opts.jitter = true
+		opts.seed = seed
+	}
+}
+
+// retryWithBackoff calls idempotentFunction up to maxRetries times until it
+// succeeds, waiting exponentially longer after each failure as described by
+// exponentialBackoff.
+func retryWithBackoff(name string, maxRetries int, base time.Duration, maxDelay time.Duration, idempotentFunction func() error, opts ...backoffOption) error {
+	options := backoffOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	var jitter *rand.Rand
+	if options.jitter {
+		seed := options.seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		jitter = rand.New(rand.NewSource(seed))
+	}
+	return retryWithStrategy(name, maxRetries, exponentialBackoff(base, maxDelay, jitter), idempotentFunction)
+}
+
 func retryWithDelays(name string, retries int, delay time.Duration, idempotentFunction func() error) error {
+	return retryWithStrategy(name, retries, constantBackoff(delay), idempotentFunction)
+}
+
+func retryWithStrategy(name string, retries int, backoff backoffStrategy, idempotentFunction func() error) error {
 	var err error
 	for i := 0; i < retries; i++ {
 		if err = idempotentFunction(); err == nil {
 			return nil
 		}
-		time.Sleep(delay)
+		if i < retries-1 {
+			time.Sleep(backoff(i))
+		}
 	}
 	return fmt.Errorf("retried %s %d times unsuccessfully: Latest error message: %v", name, retries, err)
 }
 
 type Config []byte
 
-func templateReplace(template string, replacements map[string]string, offlineStore provider.OfflineStore) (string, error) {
+// JobRetryPolicy is how many times a job's runner is attempted, and how long to
+// wait between attempts. If MaxDelay is set, the delay doubles after each
+// failed attempt, up to MaxDelay, with jitter.
+type JobRetryPolicy struct {
+	Attempts int
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+func (c *Coordinator) retryPolicy(resType metadata.ResourceType) JobRetryPolicy {
+	policy, has := c.JobRetries[resType]
+	if !has || policy.Attempts < 1 {
+		return JobRetryPolicy{Attempts: 1}
+	}
+	return policy
+}
+
+// runWithRetries runs a job's runner using the retry policy for its resource type.
+func (c *Coordinator) runWithRetries(resType metadata.ResourceType, name string, run func() error) error {
+	policy := c.retryPolicy(resType)
+	if policy.Attempts == 1 {
+		return run()
+	}
+	logged := func() error {
+		err := run()
+		if err != nil {
+			c.Logger.Warnw("Job attempt failed", "job", name, "type", resType, "error", err)
+		}
+		return err
+	}
+	if policy.MaxDelay > 0 {
+		return retryWithBackoff(name, policy.Attempts, policy.Delay, policy.MaxDelay, logged, withJitter(0))
+	}
+	return retryWithDelays(name, policy.Attempts, policy.Delay, logged)
+}
+
+// templateSubstitution is a {{name.variant}} token found in a transformation
+// query and the table reference it was rendered as.
+type templateSubstitution struct {
+	Token string
+	Table string
+}
+
+// templateReport describes the tokens templateReplaceWithReport found, in the
+// order they appear in the template.
+type templateReport struct {
+	Substitutions []templateSubstitution
+	// Tokens without a replacement. They're left in the rendered query as is.
+	Unresolved []string
+}
+
+func templateReplace(template string, replacements map[string]string, offlineStore provider.OfflineStore, allowUnmatched bool) (string, error) {
+	query, _, err := templateReplaceWithReport(template, replacements, offlineStore, allowUnmatched)
+	if err != nil {
+		return "", err
+	}
+	return query, nil
+}
+
+// templateReplaceWithReport renders a transformation query like templateReplace
+// and also reports what each token resolved to, so that tooling can check a
+// query before running it. Rather than stopping at the first token without a
+// replacement, it renders the rest of the query and returns an error listing
+// every unresolved token, unless allowUnmatched is set, in which case they're
+// only reported.
+func templateReplaceWithReport(template string, replacements map[string]string, offlineStore provider.OfflineStore, allowUnmatched bool) (string, templateReport, error) {
+	report := templateReport{
+		Substitutions: make([]templateSubstitution, 0),
+		Unresolved:    make([]string, 0),
+	}
+	parts, err := metadata.SplitTemplate(template)
+	if err != nil {
+		return "", report, err
+	}
 	formattedString := ""
-	numEscapes := strings.Count(template, "{{")
-	for i := 0; i < numEscapes; i++ {
-		split := strings.SplitN(template, "{{", 2)
-		afterSplit := strings.SplitN(split[1], "}}", 2)
-		key := strings.TrimSpace(afterSplit[0])
-		replacement, has := replacements[key]
+	for _, part := range parts {
+		if !part.IsToken {
+			formattedString += part.Text
+			continue
+		}
+		key := strings.TrimSpace(part.Token)
+		replacement, has := lookupReplacement(replacements, key, provider.IdentifierCasing(offlineStore.Type()))
 		if !has {
-			return "", fmt.Errorf("no key set")
+			report.Unresolved = append(report.Unresolved, key)
+			formattedString += fmt.Sprintf("{{%s}}", part.Token)
+			continue
 		}
 
 		if offlineStore.Type() == pt.BigQueryOffline {
 			bqConfig := pc.BigQueryConfig{}
 			bqConfig.Deserialize(offlineStore.Config())
-			replacement = fmt.Sprintf("`%s.%s.%s`", bqConfig.ProjectId, bqConfig.DatasetId, replacement)
-		} else {
-			replacement = sanitize(replacement)
+			replacement = fmt.Sprintf("%s.%s.%s", bqConfig.ProjectId, bqConfig.DatasetId, replacement)
 		}
-		formattedString += fmt.Sprintf("%s%s", split[0], replacement)
-		template = afterSplit[1]
+		// Featureform's tables are created quoted, so they're referenced by their exact case
+		replacement, err := provider.SanitizeIdentifier(offlineStore.Type(), replacement)
+		if err != nil {
+			return "", report, fmt.Errorf("source %s: %w", key, err)
+		}
+		report.Substitutions = append(report.Substitutions, templateSubstitution{Token: key, Table: replacement})
+		formattedString += replacement
 	}
-	formattedString += template
-	return formattedString, nil
+	if len(report.Unresolved) > 0 && !allowUnmatched {
+		return formattedString, report, fmt.Errorf("no key set for %s", strings.Join(report.Unresolved, ", "))
+	}
+	return formattedString, report, nil
 }
 
-func getSourceMapping(template string, replacements map[string]string) ([]provider.SourceMapping, error) {
+// lookupReplacement finds the table for a template token. If the offline store
+// doesn't distinguish the case of unquoted names, a token that only differs in
+// case from a single source is matched to it, the same as when the source was
+// validated.
+func lookupReplacement(replacements map[string]string, key string, casing provider.IdentifierCase) (string, bool) {
+	names := make([]string, 0, len(replacements))
+	for name := range replacements {
+		names = append(names, name)
+	}
+	i := metadata.MatchTemplateSource(names, key, casing != provider.CaseSensitiveIdentifiers)
+	if i == -1 {
+		return "", false
+	}
+	return replacements[names[i]], true
+}
+
+// getSourceMapping maps the tables of the sources a template's tokens name to
+// how they're referenced in the rendered query. Tokens are matched to sources
+// by lookupReplacement, the same as templateReplace does.
+func getSourceMapping(template string, replacements map[string]string, casing provider.IdentifierCase, allowUnmatched bool) ([]provider.SourceMapping, error) {
 	sourceMap := []provider.SourceMapping{}
-	numEscapes := strings.Count(template, "{{")
-	for i := 0; i < numEscapes; i++ {
-		split := strings.SplitN(template, "{{", 2)
-		afterSplit := strings.SplitN(split[1], "}}", 2)
-		key := strings.TrimSpace(afterSplit[0])
-		replacement, has := replacements[key]
+	parts, err := metadata.SplitTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts {
+		if !part.IsToken {
+			continue
+		}
+		key := strings.TrimSpace(part.Token)
+		replacement, has := lookupReplacement(replacements, key, casing)
 		if !has {
-			return nil, fmt.Errorf("no key set")
+			if allowUnmatched {
+				continue
+			}
+			return nil, fmt.Errorf("no key set for %s", key)
 		}
 		sourceMap = append(sourceMap, provider.SourceMapping{Template: sanitize(replacement), Source: replacement})
-		template = afterSplit[1]
 	}
 	return sourceMap, nil
 }
@@ -87,6 +274,117 @@ type Coordinator struct {
 	KVClient   *clientv3.KV
 	Spawner    JobSpawner
 	Timeout    int
+	EventSink  EventSink
+	// Also write each job's outcome to etcd so it survives restarts
+	PersistHistory bool
+	// Clear a feature's online table before materializing it, so entities that
+	// were dropped from the source stop being served
+	TruncateBeforeMaterialize bool
+	// How many times to attempt each type of job's runner before failing it.
+	// Types without an entry are attempted once.
+	JobRetries map[metadata.ResourceType]JobRetryPolicy
+	// Only log a warning, rather than failing the job, when a newly registered
+	// source variant drops columns that its previous variant's features or
+	// labels read
+	WarnOnIncompatibleSource bool
+	// How materialization rows are split between chunks. Defaults to
+	// contiguous ranges of rows.
+	MaterializeChunkOrder runner.ChunkOrder
+	// Whether a materialization that fails part way keeps the rows its
+	// successful chunks wrote or rolls them back. Defaults to keeping them.
+	MaterializeChunkFailurePolicy runner.ChunkFailurePolicy
+	// The most rows each materialization chunk copies to the online store.
+	// Defaults to runner.MAXIMUM_CHUNK_ROWS.
+	MaterializeChunkRows int64
+	// How many of a materialization's chunks copy rows at once. Defaults to
+	// all of them. A feature is only READY once every chunk has succeeded.
+	MaterializeParallelism int
+	// How every feature materialization job runs. By default, jobs of features
+	// that are already READY fail.
+	MaterializeOptions MaterializeOptions
+	// Replace a primary or transformation table that already has data when
+	// its source is registered again, rather than failing the job with
+	// ErrTableExists
+	OverwriteSourceTables bool
+	// How many jobs ExecuteJobs runs at once. Defaults to one. The coordinator's
+	// constructor also sizes the pool that WatchForNewJobs runs jobs in by it.
+	MaxConcurrentJobs int
+	// Check a sample of each feature's online values against its source after
+	// materializing it, and fail the job if more than
+	// MaxMaterializationMismatches of them are missing or differ
+	VerifyMaterializations       bool
+	MaxMaterializationMismatches int
+	// Called with the progress of each feature materialization while it runs,
+	// and once more when it succeeds
+	OnMaterializeProgress func(resID metadata.ResourceID, progress runner.MaterializeProgress)
+	// Build training sets whose label and feature sources have at most this
+	// many rows in total by joining them in the coordinator, rather than
+	// running a training set job. Only offline stores that implement
+	// provider.InMemoryTrainingSetStore support it. Zero disables it.
+	InMemoryTrainingSetMaxRows int64
+	// How long to wait for a transformation, materialization or training set
+	// job's runner before treating it as stuck. Zero waits indefinitely.
+	RunnerTimeout time.Duration
+	// How much longer a job that exceeded RunnerTimeout is given to finish, or
+	// for its output to appear, before it's marked FAILED
+	RunnerGracePeriod time.Duration
+	// Materialize one-off features over primary sources by streaming the
+	// source table straight into the online store, rather than staging a copy
+	// of it in the offline store for the materialize runner. Features that
+	// can't be streamed are staged as usual.
+	StreamPrimaryMaterializations bool
+	// The most source rows each type of job may read. A job over its quota
+	// fails with ErrQuotaExceeded before it's run. Types without an entry are
+	// unlimited.
+	JobRowQuotas map[metadata.ResourceType]int64
+	// Leave {{...}} tokens in transformation queries that don't name one of
+	// the transformation's sources as they are, rather than failing the job.
+	// Literal double braces can always be written as {{{{ instead.
+	AllowUnmatchedTemplateTokens bool
+	// Where jobs get the providers of their sources and training sets from,
+	// so that jobs over the same provider share its connections. Nil opens a
+	// provider for each job. The constructor creates one sized by
+	// config.GetMaxIdleProviders and config.GetProviderMaxLifetime.
+	Providers *provider.ProviderPool
+
+	// Held by each job WatchForNewJobs is running. Nil runs every job at once.
+	jobSlots chan struct{}
+
+	history   *jobHistory
+	metrics   *jobMetrics
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+	closedMtx sync.RWMutex
+	closed    bool
+	// Set by Shutdown, after which no more jobs are started
+	draining bool
+	// The jobs being executed, which Shutdown waits for
+	inFlight sync.WaitGroup
+	// The goroutines doing the work of jobs. A job that's stopped or times
+	// out returns without waiting for its work, which is cancelled but keeps
+	// using the coordinator until it notices.
+	jobWork sync.WaitGroup
+	// Closed once Shutdown stops waiting for jobs, to stop the ones left
+	stopJobs     chan struct{}
+	stopJobsInit sync.Once
+	stopJobsOnce sync.Once
+
+	// Cancels the loop of each recurring job this coordinator runs, by the
+	// key of its schedule
+	recurring    map[string]context.CancelFunc
+	recurringMtx sync.Mutex
+
+	// The jobs this coordinator is executing, by job key, so that CancelJob
+	// can stop them
+	running    map[string]*runningJob
+	runningMtx sync.Mutex
+
+	healthAddr     string
+	healthServer   *http.Server
+	healthListener net.Listener
+	// One of watchNotStarted, watchRunning or watchStopped
+	watchState int32
 }
 
 type ETCDConfig struct {
@@ -112,9 +410,14 @@ func (c *ETCDConfig) Deserialize(config Config) error {
 }
 
 func (c *Coordinator) AwaitPendingSource(sourceNameVariant metadata.NameVariant) (*metadata.SourceVariant, error) {
+	return c.awaitPendingSource(context.Background(), sourceNameVariant)
+}
+
+// awaitPendingSource waits for a source to be ready, or for ctx to be done
+func (c *Coordinator) awaitPendingSource(ctx context.Context, sourceNameVariant metadata.NameVariant) (*metadata.SourceVariant, error) {
 	sourceStatus := metadata.PENDING
 	for sourceStatus != metadata.READY {
-		source, err := c.Metadata.GetSourceVariant(context.Background(), sourceNameVariant)
+		source, err := c.Metadata.GetSourceVariant(ctx, sourceNameVariant)
 		if err != nil {
 			return nil, err
 		}
@@ -125,15 +428,24 @@ func (c *Coordinator) AwaitPendingSource(sourceNameVariant metadata.NameVariant)
 		if sourceStatus == metadata.READY {
 			return source, nil
 		}
-		time.Sleep(3 * time.Second)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for source %s (%s): %w", sourceNameVariant.Name, sourceNameVariant.Variant, ctx.Err())
+		case <-time.After(3 * time.Second):
+		}
 	}
-	return c.Metadata.GetSourceVariant(context.Background(), sourceNameVariant)
+	return c.Metadata.GetSourceVariant(ctx, sourceNameVariant)
 }
 
 func (c *Coordinator) AwaitPendingFeature(featureNameVariant metadata.NameVariant) (*metadata.FeatureVariant, error) {
+	return c.awaitPendingFeature(context.Background(), featureNameVariant)
+}
+
+// awaitPendingFeature waits for a feature to be ready, or for ctx to be done
+func (c *Coordinator) awaitPendingFeature(ctx context.Context, featureNameVariant metadata.NameVariant) (*metadata.FeatureVariant, error) {
 	featureStatus := metadata.PENDING
 	for featureStatus != metadata.READY {
-		feature, err := c.Metadata.GetFeatureVariant(context.Background(), featureNameVariant)
+		feature, err := c.Metadata.GetFeatureVariant(ctx, featureNameVariant)
 		if err != nil {
 			return nil, err
 		}
@@ -144,15 +456,24 @@ func (c *Coordinator) AwaitPendingFeature(featureNameVariant metadata.NameVarian
 		if featureStatus == metadata.READY {
 			return feature, nil
 		}
-		time.Sleep(1 * time.Second)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for feature %s (%s): %w", featureNameVariant.Name, featureNameVariant.Variant, ctx.Err())
+		case <-time.After(1 * time.Second):
+		}
 	}
-	return c.Metadata.GetFeatureVariant(context.Background(), featureNameVariant)
+	return c.Metadata.GetFeatureVariant(ctx, featureNameVariant)
 }
 
 func (c *Coordinator) AwaitPendingLabel(labelNameVariant metadata.NameVariant) (*metadata.LabelVariant, error) {
+	return c.awaitPendingLabel(context.Background(), labelNameVariant)
+}
+
+// awaitPendingLabel waits for a label to be ready, or for ctx to be done
+func (c *Coordinator) awaitPendingLabel(ctx context.Context, labelNameVariant metadata.NameVariant) (*metadata.LabelVariant, error) {
 	labelStatus := metadata.PENDING
 	for labelStatus != metadata.READY {
-		label, err := c.Metadata.GetLabelVariant(context.Background(), labelNameVariant)
+		label, err := c.Metadata.GetLabelVariant(ctx, labelNameVariant)
 		if err != nil {
 			return nil, err
 		}
@@ -163,17 +484,31 @@ func (c *Coordinator) AwaitPendingLabel(labelNameVariant metadata.NameVariant) (
 		if labelStatus == metadata.READY {
 			return label, nil
 		}
-		time.Sleep(1 * time.Second)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for label %s (%s): %w", labelNameVariant.Name, labelNameVariant.Variant, ctx.Err())
+		case <-time.After(1 * time.Second):
+		}
 	}
-	return c.Metadata.GetLabelVariant(context.Background(), labelNameVariant)
+	return c.Metadata.GetLabelVariant(ctx, labelNameVariant)
 }
 
+// JobSpawner creates the runner for each job. args are the Kubernetes
+// arguments the job's resource was given, which spawners that don't run jobs
+// on Kubernetes ignore.
 type JobSpawner interface {
-	GetJobRunner(jobName string, config runner.Config, resourceId metadata.ResourceID) (types.Runner, error)
+	GetJobRunner(jobName string, config runner.Config, resourceId metadata.ResourceID, args metadata.KubernetesArgs) (types.Runner, error)
 }
 
 type KubernetesJobSpawner struct {
 	EtcdConfig clientv3.Config
+	// CPU and memory requests and limits of every job's pod. The specs in a
+	// job's Kubernetes arguments take precedence. Empty ones aren't set.
+	Specs metadata.KubernetesResourceSpecs
+	// Node labels and taints that restrict which nodes every job's pods are
+	// scheduled on. Nil leaves them to be scheduled on any node.
+	NodeSelector map[string]string
+	Tolerations  []corev1.Toleration
 }
 
 type MemoryJobSpawner struct{}
@@ -182,35 +517,72 @@ func GetLockKey(jobKey string) string {
 	return fmt.Sprintf("LOCK_%s", jobKey)
 }
 
-func (k *KubernetesJobSpawner) GetJobRunner(jobName string, config runner.Config, resourceId metadata.ResourceID) (types.Runner, error) {
+func (k *KubernetesJobSpawner) GetJobRunner(jobName string, config runner.Config, resourceId metadata.ResourceID, args metadata.KubernetesArgs) (types.Runner, error) {
+	kubeConfig, err := k.runnerConfig(jobName, config, resourceId, args)
+	if err != nil {
+		return nil, err
+	}
+	jobRunner, err := kubernetes.NewKubernetesRunner(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return jobRunner, nil
+}
+
+// runnerConfig describes the worker pod that runs a job. The pandas runner
+// image is passed to it as PANDAS_RUNNER_IMAGE, which the worker's offline
+// stores and materialize runner read through config.GetPandasRunnerImage, so
+// a custom image in args reaches every pod the job starts.
+func (k *KubernetesJobSpawner) runnerConfig(jobName string, config runner.Config, resourceId metadata.ResourceID, args metadata.KubernetesArgs) (kubernetes.KubernetesRunnerConfig, error) {
 	etcdConfig := &ETCDConfig{Endpoints: k.EtcdConfig.Endpoints, Username: k.EtcdConfig.Username, Password: k.EtcdConfig.Password}
 	serializedETCD, err := etcdConfig.Serialize()
 	if err != nil {
-		return nil, err
+		return kubernetes.KubernetesRunnerConfig{}, err
 	}
 	pandasImage := cfg.GetPandasRunnerImage()
+	if args.DockerImage != "" {
+		pandasImage = args.DockerImage
+	}
 	workerImage := cfg.GetWorkerImage()
 	fmt.Println("GETJOBRUNNERID:", resourceId)
-	kubeConfig := kubernetes.KubernetesRunnerConfig{
+	return kubernetes.KubernetesRunnerConfig{
 		EnvVars: map[string]string{
-			"NAME":             jobName,
-			"CONFIG":           string(config),
-			"ETCD_CONFIG":      string(serializedETCD),
-			"K8S_RUNNER_IMAGE": pandasImage,
+			"NAME":                jobName,
+			"CONFIG":              string(config),
+			"ETCD_CONFIG":         string(serializedETCD),
+			"K8S_RUNNER_IMAGE":    pandasImage,
+			"PANDAS_RUNNER_IMAGE": pandasImage,
 		},
-		JobPrefix: "runner",
-		Image:     workerImage,
-		NumTasks:  1,
-		Resource:  resourceId,
+		JobPrefix:    "runner",
+		Image:        workerImage,
+		NumTasks:     1,
+		Resource:     resourceId,
+		Specs:        overrideResourceSpecs(k.Specs, args.Specs),
+		NodeSelector: k.NodeSelector,
+		Tolerations:  k.Tolerations,
+	}, nil
+}
+
+// overrideResourceSpecs returns defaults with each spec set in overrides
+// replaced
+func overrideResourceSpecs(defaults, overrides metadata.KubernetesResourceSpecs) metadata.KubernetesResourceSpecs {
+	specs := defaults
+	if overrides.CPURequest != "" {
+		specs.CPURequest = overrides.CPURequest
 	}
-	jobRunner, err := kubernetes.NewKubernetesRunner(kubeConfig)
-	if err != nil {
-		return nil, err
+	if overrides.CPULimit != "" {
+		specs.CPULimit = overrides.CPULimit
 	}
-	return jobRunner, nil
+	if overrides.MemoryRequest != "" {
+		specs.MemoryRequest = overrides.MemoryRequest
+	}
+	if overrides.MemoryLimit != "" {
+		specs.MemoryLimit = overrides.MemoryLimit
+	}
+	return specs
 }
 
-func (k *MemoryJobSpawner) GetJobRunner(jobName string, config runner.Config, resourceId metadata.ResourceID) (types.Runner, error) {
+func (k *MemoryJobSpawner) GetJobRunner(jobName string, config runner.Config, resourceId metadata.ResourceID, args metadata.KubernetesArgs) (types.Runner, error) {
 	jobRunner, err := runner.Create(jobName, config)
 	if err != nil {
 		return nil, err
@@ -218,22 +590,115 @@ func (k *MemoryJobSpawner) GetJobRunner(jobName string, config runner.Config, re
 	return jobRunner, nil
 }
 
-func NewCoordinator(meta *metadata.Client, logger *zap.SugaredLogger, cli *clientv3.Client, spawner JobSpawner) (*Coordinator, error) {
-	logger.Info("Creating new coordinator")
+func NewCoordinator(meta *metadata.Client, logger *zap.SugaredLogger, cli *clientv3.Client, spawner JobSpawner, opts ...CoordinatorOption) (*Coordinator, error) {
+	return NewCoordinatorWithConcurrency(meta, logger, cli, spawner, 1, opts...)
+}
+
+// NewCoordinatorWithConcurrency creates a coordinator that runs up to
+// maxConcurrent jobs at once, both from WatchForNewJobs and ExecuteJobs. Jobs
+// that wait on another resource hold their slot while they wait.
+func NewCoordinatorWithConcurrency(meta *metadata.Client, logger *zap.SugaredLogger, cli *clientv3.Client, spawner JobSpawner, maxConcurrent int, opts ...CoordinatorOption) (*Coordinator, error) {
+	if maxConcurrent < 1 {
+		return nil, fmt.Errorf("max concurrent jobs must be at least 1, got %d", maxConcurrent)
+	}
+	logger.Infow("Creating new coordinator", "max_concurrent_jobs", maxConcurrent)
 	kvc := clientv3.NewKV(cli)
-	return &Coordinator{
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Coordinator{
 		Metadata:   meta,
 		Logger:     logger,
 		EtcdClient: cli,
 		KVClient:   &kvc,
 		Spawner:    spawner,
 		Timeout:    600,
-	}, nil
+		EventSink:  &NoopEventSink{},
+		history:    newJobHistory(),
+		metrics:    newJobMetrics(),
+		ctx:        ctx,
+		cancel:     cancel,
+
+		MaxConcurrentJobs: maxConcurrent,
+		jobSlots:          make(chan struct{}, maxConcurrent),
+		Providers:         provider.NewProviderPool(cfg.GetMaxIdleProviders(), cfg.GetProviderMaxLifetime()),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.healthAddr != "" {
+		if err := c.startHealthServer(); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Close stops the watch loops and releases the coordinator's metadata, etcd and
+// event sink connections before flushing the logger. It is safe to call more than
+// once; only the first call does any work. Jobs executed after Close return a
+// CoordinatorClosedError.
+func (c *Coordinator) Close() error {
+	var errs []string
+	c.closeOnce.Do(func() {
+		c.closedMtx.Lock()
+		c.closed = true
+		c.closedMtx.Unlock()
+		if c.cancel != nil {
+			c.cancel()
+		}
+		if err := c.closeHealthServer(); err != nil {
+			errs = append(errs, fmt.Sprintf("close health server: %v", err))
+		}
+		if c.EventSink != nil {
+			if err := c.EventSink.Close(); err != nil {
+				errs = append(errs, fmt.Sprintf("close event sink: %v", err))
+			}
+		}
+		if c.Metadata != nil {
+			c.Metadata.Close()
+		}
+		if err := c.Providers.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+		if c.EtcdClient != nil {
+			if err := c.EtcdClient.Close(); err != nil {
+				errs = append(errs, fmt.Sprintf("close etcd client: %v", err))
+			}
+		}
+		// Sync commonly fails on stdout/stderr, which can't be fsynced, so it's
+		// not treated as a close failure.
+		_ = c.Logger.Sync()
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("close coordinator: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (c *Coordinator) isClosed() bool {
+	c.closedMtx.RLock()
+	defer c.closedMtx.RUnlock()
+	return c.closed
+}
+
+func (c *Coordinator) watchContext() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
 }
 
 const MAX_ATTEMPTS = 3
 
 func (c *Coordinator) checkError(err error, jobName string) {
+	if errors.Is(err, ErrJobLocked) {
+		c.Logger.Debugw("job is already running. Ignoring....", "key", jobName)
+		return
+	}
+	if errors.Is(err, ErrShutdown) {
+		c.Logger.Infow("coordinator shut down before job finished. Leaving it for another coordinator....", "key", jobName)
+		return
+	}
 	switch err.(type) {
 	case JobDoesNotExistError:
 		c.Logger.Info(err)
@@ -241,63 +706,79 @@ func (c *Coordinator) checkError(err error, jobName string) {
 		c.Logger.Infow("resource has failed previously. Ignoring....", "key", jobName)
 	case ResourceAlreadyCompleteError:
 		c.Logger.Infow("resource has already completed. Ignoring....", "key", jobName)
+	case CoordinatorClosedError:
+		c.Logger.Infow("coordinator closed. Ignoring....", "key", jobName)
 	default:
 		c.Logger.Errorw("Error executing job", "job_name", jobName, "error", err)
 	}
 }
 
+// setStatus updates the resource's status in metadata and then publishes the
+// transition to the coordinator's EventSink. A failure to publish is logged
+// rather than returned, since the status change itself has already succeeded.
+func (c *Coordinator) setStatus(resID metadata.ResourceID, status metadata.ResourceStatus, errorMessage string) error {
+	if err := c.Metadata.SetStatus(context.Background(), resID, status, errorMessage); err != nil {
+		return err
+	}
+	if c.EventSink == nil {
+		return nil
+	}
+	if err := c.EventSink.Publish(NewResourceStatusEvent(resID, status)); err != nil {
+		c.Logger.Errorw("Could not publish resource status event", "resource", resID, "status", status.String(), "error", err)
+	}
+	return nil
+}
+
+// setReady marks a job's resource READY, unless the job's ctx is done. A job
+// that timed out or was cancelled is marked FAILED without waiting for it, so
+// it may still be running, and it mustn't overwrite that when it finishes.
+func (c *Coordinator) setReady(ctx context.Context, resID metadata.ResourceID) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s job stopped: %w", resID.Type, err)
+	}
+	return c.setStatus(resID, metadata.READY, "")
+}
+
 func (c *Coordinator) WatchForNewJobs() error {
 	c.Logger.Info("Watching for new jobs")
 	getResp, err := (*c.KVClient).Get(context.Background(), "JOB_", clientv3.WithPrefix())
 	if err != nil {
 		return fmt.Errorf("get existing etcd jobs: %v", err)
 	}
-	for _, kv := range getResp.Kvs {
+	jobKeys := make([]string, len(getResp.Kvs))
+	for i, kv := range getResp.Kvs {
+		jobKeys[i] = string(kv.Key)
+	}
+	sortJobQueue(jobKeys)
+	for _, jobKey := range jobKeys {
 		time.Sleep(1 * time.Second)
-		go func(kv *mvccpb.KeyValue) {
-			err := c.ExecuteJob(string(kv.Key))
-			if err != nil {
-				c.checkError(err, string(kv.Key))
-			}
-		}(kv)
+		c.queueJob(jobKey)
 	}
-	for {
-		rch := c.EtcdClient.Watch(context.Background(), "JOB_", clientv3.WithPrefix())
-		for wresp := range rch {
-			for _, ev := range wresp.Events {
-				time.Sleep(1 * time.Second)
-				if ev.Type == mvccpb.PUT {
-					go func(ev *clientv3.Event) {
-						err := c.ExecuteJob(string(ev.Kv.Key))
-						if err != nil {
-							c.checkError(err, string(ev.Kv.Key))
-						}
-					}(ev)
-				}
-
-			}
+	atomic.StoreInt32(&c.watchState, watchRunning)
+	defer atomic.StoreInt32(&c.watchState, watchStopped)
+	// Start from the revision of the jobs just listed so that no job created
+	// in between is missed
+	c.watchPrefix("JOB_", getResp.Header.Revision+1, func(ev *clientv3.Event) {
+		time.Sleep(1 * time.Second)
+		if ev.Type == mvccpb.PUT {
+			c.queueJob(string(ev.Kv.Key))
 		}
-	}
+	})
+	return nil
 }
 
 func (c *Coordinator) WatchForUpdateEvents() error {
 	c.Logger.Info("Watching for new update events")
-	for {
-		rch := c.EtcdClient.Watch(context.Background(), "UPDATE_EVENT_", clientv3.WithPrefix())
-		for wresp := range rch {
-			for _, ev := range wresp.Events {
-				if ev.Type == 0 {
-					go func(ev *clientv3.Event) {
-						err := c.signalResourceUpdate(string(ev.Kv.Key), string(ev.Kv.Value))
-						if err != nil {
-							c.Logger.Errorw("Error executing update event catch: Polling search", "error", err)
-						}
-					}(ev)
+	c.watchPrefix("UPDATE_EVENT_", 0, func(ev *clientv3.Event) {
+		if ev.Type == 0 {
+			go func(ev *clientv3.Event) {
+				err := c.signalResourceUpdate(string(ev.Kv.Key), string(ev.Kv.Value))
+				if err != nil {
+					c.Logger.Errorw("Error executing update event catch: Polling search", "error", err)
 				}
-
-			}
+			}(ev)
 		}
-	}
+	})
 	return nil
 }
 
@@ -315,22 +796,16 @@ func (c *Coordinator) WatchForScheduleChanges() error {
 			}
 		}(kv)
 	}
-	for {
-		rch := c.EtcdClient.Watch(context.Background(), "SCHEDULEJOB_", clientv3.WithPrefix())
-		for wresp := range rch {
-			for _, ev := range wresp.Events {
-				if ev.Type == 0 {
-					go func(ev *clientv3.Event) {
-						err := c.changeJobSchedule(string(ev.Kv.Key), string(ev.Kv.Value))
-						if err != nil {
-							c.Logger.Errorw("Error executing job schedule change: Polling search", "error", err)
-						}
-					}(ev)
+	c.watchPrefix("SCHEDULEJOB_", getResp.Header.Revision+1, func(ev *clientv3.Event) {
+		if ev.Type == 0 {
+			go func(ev *clientv3.Event) {
+				err := c.changeJobSchedule(string(ev.Kv.Key), string(ev.Kv.Value))
+				if err != nil {
+					c.Logger.Errorw("Error executing job schedule change: Polling search", "error", err)
 				}
-
-			}
+			}(ev)
 		}
-	}
+	})
 	return nil
 }
 
@@ -373,10 +848,13 @@ func sanitize(ident string) string {
 	return db.Identifier{ident}.Sanitize()
 }
 
-func (c *Coordinator) verifyCompletionOfSources(sources []metadata.NameVariant) error {
+func (c *Coordinator) verifyCompletionOfSources(ctx context.Context, sources []metadata.NameVariant) error {
 	allReady := false
 	for !allReady {
-		sourceVariants, err := c.Metadata.GetSourceVariants(context.Background(), sources)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		sourceVariants, err := c.Metadata.GetSourceVariants(ctx, sources)
 		if err != nil {
 			return fmt.Errorf("could not get source variant: %v ", err)
 		}
@@ -395,7 +873,7 @@ func (c *Coordinator) verifyCompletionOfSources(sources []metadata.NameVariant)
 	return nil
 }
 
-func (c *Coordinator) runTransformationJob(transformationConfig provider.TransformationConfig, resID metadata.ResourceID, schedule string, sourceProvider *metadata.Provider) error {
+func (c *Coordinator) runTransformationJob(ctx context.Context, transformationConfig provider.TransformationConfig, resID metadata.ResourceID, schedule string, sourceProvider *metadata.Provider, offlineStore provider.OfflineStore) error {
 	transformation, err := c.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{resID.Name, resID.Variant})
 	if err != nil {
 		return fmt.Errorf("get label variant: %v", err)
@@ -412,9 +890,12 @@ func (c *Coordinator) runTransformationJob(transformationConfig provider.Transfo
 			resourceID: resID,
 		}
 	}
-	if err := c.Metadata.SetStatus(context.Background(), resID, metadata.PENDING, ""); err != nil {
+	if err := c.setStatus(resID, metadata.PENDING, ""); err != nil {
 		return fmt.Errorf("set pending status for transformation job: %v", err)
 	}
+	if err := c.clearExistingSourceTable(offlineStore, transformationConfig.TargetTableID); err != nil {
+		return err
+	}
 
 	createTransformationConfig := runner.CreateTransformationConfig{
 		OfflineType:          pt.Type(sourceProvider.Type()),
@@ -428,21 +909,43 @@ func (c *Coordinator) runTransformationJob(transformationConfig provider.Transfo
 		return fmt.Errorf("serialize transformation config: %v", err)
 	}
 	c.Logger.Debugw("Transformation Get Job Runner")
-	jobRunner, err := c.Spawner.GetJobRunner(runner.CREATE_TRANSFORMATION, serialized, resID)
+	// Transformations without Kubernetes args have nil Args
+	kubernetesArgs, _ := transformationConfig.Args.(metadata.KubernetesArgs)
+	jobRunner, err := c.Spawner.GetJobRunner(runner.CREATE_TRANSFORMATION, serialized, resID, kubernetesArgs)
 	if err != nil {
-		return fmt.Errorf("spawn create transformation job runner: %v", err)
+		return fmt.Errorf("spawn create transformation job runner: %w", err)
+	}
+	runnerCtx, stopRunner := context.WithCancel(ctx)
+	defer stopRunner()
+	if cancellable, ok := jobRunner.(runner.CancellableRunner); ok {
+		cancellable.SetCancel(runnerCtx.Done())
 	}
 	c.Logger.Debugw("Transformation Run Job")
-	completionWatcher, err := jobRunner.Run()
+	err = c.runWithRetries(metadata.SOURCE_VARIANT, "transformation job", func() error {
+		completionWatcher, err := jobRunner.Run()
+		if err != nil {
+			return fmt.Errorf("run transformation job runner: %v", err)
+		}
+		c.Logger.Debugw("Transformation Waiting For Completion")
+		transformationExists := func() bool {
+			_, err := offlineStore.GetTransformationTable(transformationConfig.TargetTableID)
+			return err == nil
+		}
+		if err := c.awaitRunner(resID, completionWatcher.Wait, transformationExists, stopRunner); err != nil {
+			return fmt.Errorf("wait for transformation job runner completion: %v", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("run transformation job runner: %v", err)
+		return err
 	}
-	c.Logger.Debugw("Transformation Waiting For Completion")
-	if err := completionWatcher.Wait(); err != nil {
-		return fmt.Errorf("wait for transformation job runner completion: %v", err)
+	if table, err := offlineStore.GetTransformationTable(transformationConfig.TargetTableID); err != nil {
+		c.Logger.Errorw("Could not get transformation table to record its stats", "resource", resID, "error", err)
+	} else {
+		c.recordSourceStats(resID, table)
 	}
 	c.Logger.Debugw("Transformation Setting Status")
-	if err := retryWithDelays("set status to ready", 5, time.Millisecond*10, func() error { return c.Metadata.SetStatus(context.Background(), resID, metadata.READY, "") }); err != nil {
+	if err := retryWithDelays("set status to ready", 5, time.Millisecond*10, func() error { return c.setReady(ctx, resID) }); err != nil {
 		return fmt.Errorf("set transformation job runner done status: %v", err)
 	}
 	c.Logger.Debugw("Transformation Complete")
@@ -457,7 +960,7 @@ func (c *Coordinator) runTransformationJob(transformationConfig provider.Transfo
 		if err != nil {
 			return fmt.Errorf("serialize schedule transformation config: %v", err)
 		}
-		jobRunnerUpdate, err := c.Spawner.GetJobRunner(runner.CREATE_TRANSFORMATION, serializedUpdate, resID)
+		jobRunnerUpdate, err := c.Spawner.GetJobRunner(runner.CREATE_TRANSFORMATION, serializedUpdate, resID, kubernetesArgs)
 		if err != nil {
 			return fmt.Errorf("run ransformation schedule job runner: %v", err)
 		}
@@ -468,39 +971,80 @@ func (c *Coordinator) runTransformationJob(transformationConfig provider.Transfo
 		if err := cronRunner.ScheduleJob(kubernetes.CronSchedule(schedule)); err != nil {
 			return fmt.Errorf("schedule transformation job in kubernetes: %v", err)
 		}
-		if err := c.Metadata.SetStatus(context.Background(), resID, metadata.READY, ""); err != nil {
+		if err := c.setReady(ctx, resID); err != nil {
 			return fmt.Errorf("set transformation succesful schedule status: %v", err)
 		}
 	}
 	return nil
 }
 
-func (c *Coordinator) runSQLTransformationJob(transformSource *metadata.SourceVariant, resID metadata.ResourceID, offlineStore provider.OfflineStore, schedule string, sourceProvider *metadata.Provider) error {
+// The source property that sets the file type a transformation's result is
+// written as, such as "csv". Results are written as parquet if it isn't set.
+const TransformationOutputFormatProperty = "output_format"
+
+func transformationOutputFormat(source *metadata.SourceVariant) filestore.FileType {
+	return filestore.FileType(source.Properties()[TransformationOutputFormatProperty])
+}
+
+// The source and training set properties that override the compression codec,
+// such as "zstd", and rows per row group their offline store writes parquet
+// outputs with
+const (
+	ParquetCompressionProperty  = "parquet_compression"
+	ParquetRowGroupSizeProperty = "parquet_row_group_size"
+)
+
+func parquetWriteConfig(properties metadata.Properties) (provider.ParquetWriteConfig, error) {
+	config := provider.ParquetWriteConfig{
+		Compression: provider.ParquetCompression(properties[ParquetCompressionProperty]),
+	}
+	if rowGroupSize, has := properties[ParquetRowGroupSizeProperty]; has {
+		size, err := strconv.ParseInt(rowGroupSize, 10, 64)
+		if err != nil || size <= 0 {
+			return provider.ParquetWriteConfig{}, fmt.Errorf("invalid %s property: %q", ParquetRowGroupSizeProperty, rowGroupSize)
+		}
+		config.RowGroupSize = size
+	}
+	return config, nil
+}
+
+func (c *Coordinator) runSQLTransformationJob(ctx context.Context, transformSource *metadata.SourceVariant, resID metadata.ResourceID, offlineStore provider.OfflineStore, schedule string, sourceProvider *metadata.Provider) error {
 	c.Logger.Info("Running SQL transformation job on resource: ", resID)
+	defer c.metrics.timeJob(sqlTransformationJobType)()
 	templateString := transformSource.SQLTransformationQuery()
 	sources := transformSource.SQLTransformationSources()
 
-	err := c.verifyCompletionOfSources(sources)
+	if !allowsNonSelectQuery(transformSource) {
+		if statement := nonSelectStatement(templateString); statement != "" {
+			return NonSelectQueryError{resourceID: resID, statement: statement}
+		}
+	}
+
+	err := c.verifyCompletionOfSources(ctx, sources)
 	if err != nil {
-		return fmt.Errorf("the sources were not completed: %s", err)
+		return c.transformationQueryError(resID, templateString, false, fmt.Errorf("the sources were not completed: %s", err))
 	}
 
 	sourceMap, err := c.mapNameVariantsToTables(sources)
 	if err != nil {
-		return fmt.Errorf("map name: %v sources: %v", err, sources)
+		return c.transformationQueryError(resID, templateString, false, fmt.Errorf("map name: %v sources: %v", err, sources))
 	}
-	sourceMapping, err := getSourceMapping(templateString, sourceMap)
+	sourceMapping, err := getSourceMapping(templateString, sourceMap, provider.IdentifierCasing(offlineStore.Type()), c.AllowUnmatchedTemplateTokens)
 	if err != nil {
-		return fmt.Errorf("getSourceMapping replace: %v source map: %v, template: %s", err, sourceMap, templateString)
+		return c.transformationQueryError(resID, templateString, false, fmt.Errorf("getSourceMapping replace: %v source map: %v", err, sourceMap))
 	}
 
 	var query string
-	query, err = templateReplace(templateString, sourceMap, offlineStore)
+	query, err = templateReplace(templateString, sourceMap, offlineStore, c.AllowUnmatchedTemplateTokens)
 	if err != nil {
-		return fmt.Errorf("template replace: %v source map: %v, template: %s", err, sourceMap, templateString)
+		return c.transformationQueryError(resID, templateString, false, fmt.Errorf("template replace: %v source map: %v", err, sourceMap))
 	}
 
 	c.Logger.Debugw("Created transformation query", "query", query)
+	parquetConfig, err := parquetWriteConfig(transformSource.Properties())
+	if err != nil {
+		return err
+	}
 	providerResourceID := provider.ResourceID{Name: resID.Name, Variant: resID.Variant, Type: provider.Transformation}
 	transformationConfig := provider.TransformationConfig{
 		Type:          provider.SQLTransformation,
@@ -508,22 +1052,40 @@ func (c *Coordinator) runSQLTransformationJob(transformSource *metadata.SourceVa
 		Query:         query,
 		SourceMapping: sourceMapping,
 		Args:          transformSource.TransformationArgs(),
+		OutputFormat:  transformationOutputFormat(transformSource),
+		ParquetConfig: parquetConfig,
 	}
 
-	err = c.runTransformationJob(transformationConfig, resID, schedule, sourceProvider)
-	if err != nil {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("run transformation: %w", err)
+	}
+	err = c.runTransformationJob(ctx, transformationConfig, resID, schedule, sourceProvider, offlineStore)
+	switch err.(type) {
+	case nil:
+	case ResourceAlreadyCompleteError, ResourceAlreadyFailedError:
 		return err
+	default:
+		return c.transformationQueryError(resID, query, true, err)
 	}
-
+	c.tagTable(offlineStore, providerResourceID, transformSource.Owner(), sourceProvider)
 	return nil
 }
 
-func (c *Coordinator) runDFTransformationJob(transformSource *metadata.SourceVariant, resID metadata.ResourceID, offlineStore provider.OfflineStore, schedule string, sourceProvider *metadata.Provider) error {
+// transformationQueryError logs the query of a SQL transformation that failed
+// and attaches it to err, so that it's shown in the transformation's status.
+// Queries only name the tables they read, not the credentials used to read
+// them.
+func (c *Coordinator) transformationQueryError(resID metadata.ResourceID, query string, rendered bool, err error) error {
+	c.Logger.Debugw("SQL transformation failed", "resource", resID, "query", query, "rendered", rendered, "error", err)
+	return TransformationQueryError{query: query, rendered: rendered, err: err}
+}
+
+func (c *Coordinator) runDFTransformationJob(ctx context.Context, transformSource *metadata.SourceVariant, resID metadata.ResourceID, offlineStore provider.OfflineStore, schedule string, sourceProvider *metadata.Provider) error {
 	c.Logger.Info("Running DF transformation job on resource: ", resID)
 	code := transformSource.DFTransformationQuery()
 	sources := transformSource.DFTransformationSources()
 
-	err := c.verifyCompletionOfSources(sources)
+	err := c.verifyCompletionOfSources(ctx, sources)
 	if err != nil {
 		return fmt.Errorf("the sources were not completed: %s", err)
 	}
@@ -539,6 +1101,10 @@ func (c *Coordinator) runDFTransformationJob(transformSource *metadata.SourceVar
 	}
 
 	c.Logger.Debugw("Created transformation query")
+	parquetConfig, err := parquetWriteConfig(transformSource.Properties())
+	if err != nil {
+		return err
+	}
 	providerResourceID := provider.ResourceID{Name: resID.Name, Variant: resID.Variant, Type: provider.Transformation}
 	transformationConfig := provider.TransformationConfig{
 		Type:          provider.DFTransformation,
@@ -546,20 +1112,27 @@ func (c *Coordinator) runDFTransformationJob(transformSource *metadata.SourceVar
 		Code:          code,
 		SourceMapping: sourceMapping,
 		Args:          transformSource.TransformationArgs(),
+		OutputFormat:  transformationOutputFormat(transformSource),
+		ParquetConfig: parquetConfig,
 	}
 
-	err = c.runTransformationJob(transformationConfig, resID, schedule, sourceProvider)
+	err = c.runTransformationJob(ctx, transformationConfig, resID, schedule, sourceProvider, offlineStore)
 	if err != nil {
 		return err
 	}
-
+	c.tagTable(offlineStore, providerResourceID, transformSource.Owner(), sourceProvider)
 	return nil
 }
 
 func getOrderedSourceMappings(sources []metadata.NameVariant, sourceMap map[string]string) ([]provider.SourceMapping, error) {
 	sourceMapping := make([]provider.SourceMapping, len(sources))
+	declared := make(map[metadata.NameVariant]bool, len(sources))
 	for i, nv := range sources {
 		sourceKey := nv.ClientString()
+		if declared[nv] {
+			return nil, fmt.Errorf("source %s is declared more than once", sourceKey)
+		}
+		declared[nv] = true
 		tableName, hasKey := sourceMap[sourceKey]
 		if !hasKey {
 			return nil, fmt.Errorf("key %s not in source map", sourceKey)
@@ -569,60 +1142,247 @@ func getOrderedSourceMappings(sources []metadata.NameVariant, sourceMap map[stri
 	return sourceMapping, nil
 }
 
-func (c *Coordinator) runPrimaryTableJob(transformSource *metadata.SourceVariant, resID metadata.ResourceID, offlineStore provider.OfflineStore, schedule string) error {
+// tagTable tags the table a resource is stored in with its owner and the team of
+// its offline provider, if the offline store supports it. Tags are only used to
+// allocate costs, so a table that can't be tagged doesn't fail the job.
+func (c *Coordinator) tagTable(store provider.OfflineStore, id provider.ResourceID, owner string, offlineProvider *metadata.Provider) {
+	taggable, ok := store.(provider.TaggableOfflineStore)
+	if !ok {
+		return
+	}
+	tags := provider.ResourceTags{Owner: owner}
+	if offlineProvider != nil {
+		tags.Team = offlineProvider.Team()
+	}
+	if err := taggable.TagTable(id, tags); err != nil {
+		c.Logger.Warnw("Could not tag table", "resource", id, "tags", tags, "error", err)
+	}
+}
+
+// tagSourceTable tags a source's primary table, fetching its provider for the
+// team to tag it with.
+func (c *Coordinator) tagSourceTable(store provider.OfflineStore, id provider.ResourceID, source *metadata.SourceVariant) {
+	if _, ok := store.(provider.TaggableOfflineStore); !ok {
+		return
+	}
+	sourceProvider, err := source.FetchProvider(c.Metadata, context.Background())
+	if err != nil {
+		c.Logger.Warnw("Could not fetch provider to tag table", "resource", id, "error", err)
+	}
+	c.tagTable(store, id, source.Owner(), sourceProvider)
+}
+
+func (c *Coordinator) runPrimaryTableJob(ctx context.Context, transformSource *metadata.SourceVariant, resID metadata.ResourceID, offlineStore provider.OfflineStore, schedule string) error {
 	c.Logger.Info("Running primary table job on resource: ", resID)
 	providerResourceID := provider.ResourceID{Name: resID.Name, Variant: resID.Variant, Type: provider.Primary}
 	sourceName := transformSource.PrimaryDataSQLTableName()
 	if sourceName == "" {
 		return fmt.Errorf("no source name set")
 	}
-	if _, err := offlineStore.RegisterPrimaryFromSourceTable(providerResourceID, sourceName); err != nil {
-		return fmt.Errorf("register primary table from source table in offline store: %v", err)
+	newestFiles, err := sourceNewestFiles(transformSource)
+	if err != nil {
+		return err
+	}
+	// Stores that can read the source table's columns are checked before
+	// anything is created for it. If they can't, the table is checked once it's
+	// registered instead. A directory of snapshots is always checked once it's
+	// registered, since its columns are those of its newest files.
+	checked := false
+	if columnStore, ok := offlineStore.(provider.SourceColumnsOfflineStore); ok && newestFiles == 0 {
+		err := c.checkSourceCompatibility(transformSource, resID, func() ([]string, error) {
+			return columnStore.SourceTableColumns(sourceName)
+		})
+		var compatErr IncompatibleSourceSchemaError
+		if errors.As(err, &compatErr) {
+			return err
+		} else if err != nil {
+			c.Logger.Warnw("Could not check source before registering it", "resource", resID, "error", err)
+		}
+		checked = err == nil
+	}
+	if err := c.clearExistingSourceTable(offlineStore, providerResourceID); err != nil {
+		return err
+	}
+	var primaryTable provider.PrimaryTable
+	if newestFiles > 0 {
+		snapshotStore, ok := offlineStore.(provider.SnapshotPrimaryOfflineStore)
+		if !ok {
+			return fmt.Errorf("offline store %s does not support the %s property", offlineStore.Type(), SourceNewestFilesProperty)
+		}
+		primaryTable, err = snapshotStore.RegisterPrimaryFromNewestFiles(providerResourceID, sourceName, newestFiles)
+		if err != nil {
+			return fmt.Errorf("register primary table from newest files in offline store: %v", err)
+		}
+	} else {
+		primaryTable, err = offlineStore.RegisterPrimaryFromSourceTable(providerResourceID, sourceName)
+		if err != nil {
+			return fmt.Errorf("register primary table from source table in offline store: %v", err)
+		}
 	}
-	if err := c.Metadata.SetStatus(context.Background(), resID, metadata.READY, ""); err != nil {
+	if !checked {
+		if err := c.checkRegisteredSourceCompatibility(transformSource, resID, offlineStore, primaryTable); err != nil {
+			return err
+		}
+	}
+	c.tagSourceTable(offlineStore, providerResourceID, transformSource)
+	c.recordSourceStats(resID, primaryTable)
+	if err := c.setReady(ctx, resID); err != nil {
 		return fmt.Errorf("set done status for registering primary table: %v", err)
 	}
 	return nil
 }
 
-func (c *Coordinator) runRegisterSourceJob(resID metadata.ResourceID, schedule string) error {
+// The source property that registers a directory of snapshot files, such as
+// daily exports of a slowly changing table, as the union of its newest files.
+// Its value is how many of the newest files to serve, such as "7".
+const SourceNewestFilesProperty = "newest_files"
+
+func sourceNewestFiles(source *metadata.SourceVariant) (int, error) {
+	value, has := source.Properties()[SourceNewestFilesProperty]
+	if !has {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s property: %q", SourceNewestFilesProperty, value)
+	}
+	return n, nil
+}
+
+// clearExistingSourceTable guards against a source being registered again
+// over a primary or transformation table that already has data, which would
+// overwrite or duplicate it. An empty table, or any table if
+// OverwriteSourceTables is set, is deleted so that it can be registered again.
+func (c *Coordinator) clearExistingSourceTable(offlineStore provider.OfflineStore, id provider.ResourceID) error {
+	var existing interface{ NumRows() (int64, error) }
+	var err error
+	if id.Type == provider.Transformation {
+		existing, err = offlineStore.GetTransformationTable(id)
+	} else {
+		existing, err = offlineStore.GetPrimaryTable(id)
+	}
+	var notFound *provider.TableNotFound
+	if errors.As(err, &notFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("get existing table: %w", err)
+	}
+	numRows, err := existing.NumRows()
+	if err != nil {
+		return fmt.Errorf("get number of rows in existing table: %w", err)
+	}
+	if numRows > 0 && !c.OverwriteSourceTables {
+		return fmt.Errorf("%w: %s %s has %d rows", ErrTableExists, id.Name, id.Variant, numRows)
+	}
+	c.Logger.Infow("Deleting existing source table", "resource", id, "rows", numRows)
+	if id.Type == provider.Transformation {
+		deletable, ok := offlineStore.(provider.DeletableOfflineStore)
+		if !ok {
+			return fmt.Errorf("cannot replace transformation table in offline store %s", offlineStore.Type())
+		}
+		err = deletable.DeleteTransformationTable(id)
+	} else {
+		deletable, ok := offlineStore.(provider.DeletablePrimaryOfflineStore)
+		if !ok {
+			return fmt.Errorf("cannot replace primary table in offline store %s", offlineStore.Type())
+		}
+		err = deletable.DeletePrimaryTable(id)
+	}
+	if err != nil {
+		return fmt.Errorf("delete existing table: %w", err)
+	}
+	return nil
+}
+
+func (c *Coordinator) runQuerySourceJob(ctx context.Context, source *metadata.SourceVariant, resID metadata.ResourceID, offlineStore provider.OfflineStore) error {
+	c.Logger.Info("Running query source job on resource: ", resID)
+	query := source.PrimaryDataQuery()
+	if err := provider.ValidateReadOnlyQuery(query); err != nil {
+		return fmt.Errorf("validate source query: %w", err)
+	}
+	queryStore, ok := offlineStore.(provider.QueryPrimaryOfflineStore)
+	if !ok {
+		return fmt.Errorf("offline store %s does not support query sources", offlineStore.Type())
+	}
+	providerResourceID := provider.ResourceID{Name: resID.Name, Variant: resID.Variant, Type: provider.Primary}
+	primaryTable, err := queryStore.RegisterPrimaryFromQuery(providerResourceID, query)
+	if err != nil {
+		return fmt.Errorf("register primary table from query in offline store: %v", err)
+	}
+	if err := c.checkRegisteredSourceCompatibility(source, resID, offlineStore, primaryTable); err != nil {
+		return err
+	}
+	c.tagSourceTable(offlineStore, providerResourceID, source)
+	c.recordSourceStats(resID, primaryTable)
+	if err := c.setReady(ctx, resID); err != nil {
+		return fmt.Errorf("set done status for registering query source: %v", err)
+	}
+	return nil
+}
+
+func (c *Coordinator) runIcebergTableJob(ctx context.Context, source *metadata.SourceVariant, resID metadata.ResourceID, offlineStore provider.OfflineStore) error {
+	c.Logger.Info("Running iceberg table job on resource: ", resID)
+	icebergStore, ok := offlineStore.(provider.IcebergPrimaryOfflineStore)
+	if !ok {
+		return fmt.Errorf("offline store %s does not support iceberg tables", offlineStore.Type())
+	}
+	providerResourceID := provider.ResourceID{Name: resID.Name, Variant: resID.Variant, Type: provider.Primary}
+	primaryTable, err := icebergStore.RegisterPrimaryFromIcebergTable(providerResourceID, source.PrimaryDataIcebergTableLocation())
+	if err != nil {
+		return fmt.Errorf("register primary table from iceberg table in offline store: %v", err)
+	}
+	if err := c.checkRegisteredSourceCompatibility(source, resID, offlineStore, primaryTable); err != nil {
+		return err
+	}
+	c.tagSourceTable(offlineStore, providerResourceID, source)
+	c.recordSourceStats(resID, primaryTable)
+	if err := c.setReady(ctx, resID); err != nil {
+		return fmt.Errorf("set done status for registering iceberg table: %v", err)
+	}
+	return nil
+}
+
+func (c *Coordinator) runRegisterSourceJob(ctx context.Context, resID metadata.ResourceID, schedule string) error {
 	c.Logger.Info("Running register source job on resource: ", resID)
-	source, err := c.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{resID.Name, resID.Variant})
+	source, err := c.Metadata.GetSourceVariant(ctx, metadata.NameVariant{resID.Name, resID.Variant})
 	if err != nil {
 		return fmt.Errorf("get source variant from metadata: %v", err)
 	}
-	sourceProvider, err := source.FetchProvider(c.Metadata, context.Background())
+	sourceProvider, err := source.FetchProvider(c.Metadata, ctx)
 	if err != nil {
 		return fmt.Errorf("fetch source's dependent provider in metadata: %v", err)
 	}
-	p, err := provider.Get(pt.Type(sourceProvider.Type()), sourceProvider.SerializedConfig())
+	p, release, err := c.Providers.Get(pt.Type(sourceProvider.Type()), sourceProvider.SerializedConfig())
 	if err != nil {
-		return fmt.Errorf("get source's dependent provider in offline store: %v", err)
+		return fmt.Errorf("get source's dependent provider in offline store: %w", err)
 	}
+	defer func() {
+		if err := release(); err != nil {
+			c.Logger.Errorf("could not release offline store: %v", err)
+		}
+	}()
 	sourceStore, err := p.AsOfflineStore()
 	if err != nil {
-		return fmt.Errorf("convert source provider to offline store interface: %v", err)
+		return fmt.Errorf("convert source provider to offline store interface: %w", err)
 	}
-	defer func(sourceStore provider.OfflineStore) {
-		err := sourceStore.Close()
-		if err != nil {
-			c.Logger.Errorf("could not close offline store: %v", err)
-		}
-	}(sourceStore)
 	if source.IsSQLTransformation() {
-		return c.runSQLTransformationJob(source, resID, sourceStore, schedule, sourceProvider)
+		return c.runSQLTransformationJob(ctx, source, resID, sourceStore, schedule, sourceProvider)
 	} else if source.IsDFTransformation() {
-		return c.runDFTransformationJob(source, resID, sourceStore, schedule, sourceProvider)
+		return c.runDFTransformationJob(ctx, source, resID, sourceStore, schedule, sourceProvider)
 	} else if source.IsPrimaryDataSQLTable() {
-		return c.runPrimaryTableJob(source, resID, sourceStore, schedule)
+		return c.runPrimaryTableJob(ctx, source, resID, sourceStore, schedule)
+	} else if source.IsPrimaryDataQuery() {
+		return c.runQuerySourceJob(ctx, source, resID, sourceStore)
+	} else if source.IsPrimaryDataIcebergTable() {
+		return c.runIcebergTableJob(ctx, source, resID, sourceStore)
 	} else {
 		return fmt.Errorf("source type not implemented")
 	}
 }
 
-func (c *Coordinator) runLabelRegisterJob(resID metadata.ResourceID, schedule string) error {
+func (c *Coordinator) runLabelRegisterJob(ctx context.Context, resID metadata.ResourceID, schedule string) error {
 	c.Logger.Info("Running label register job: ", resID)
-	label, err := c.Metadata.GetLabelVariant(context.Background(), metadata.NameVariant{resID.Name, resID.Variant})
+	label, err := c.Metadata.GetLabelVariant(ctx, metadata.NameVariant{resID.Name, resID.Variant})
 	if err != nil {
 		return fmt.Errorf("get label variant: %v", err)
 	}
@@ -637,50 +1397,37 @@ func (c *Coordinator) runLabelRegisterJob(resID metadata.ResourceID, schedule st
 			resourceID: resID,
 		}
 	}
-	if err := c.Metadata.SetStatus(context.Background(), resID, metadata.PENDING, ""); err != nil {
+	if err := c.setStatus(resID, metadata.PENDING, ""); err != nil {
 		return fmt.Errorf("set pending status for label variant: %v", err)
 	}
 
 	sourceNameVariant := label.Source()
 	c.Logger.Infow("feature obj", "name", label.Name(), "source", label.Source(), "location", label.Location(), "location_col", label.LocationColumns())
 
-	source, err := c.AwaitPendingSource(sourceNameVariant)
+	source, err := c.awaitPendingSource(ctx, sourceNameVariant)
 	if err != nil {
 		return fmt.Errorf("source of could not complete job: %v", err)
 	}
-	sourceProvider, err := source.FetchProvider(c.Metadata, context.Background())
+	sourceProvider, err := source.FetchProvider(c.Metadata, ctx)
 	if err != nil {
 		return fmt.Errorf("could not fetch online provider: %v", err)
 	}
-	p, err := provider.Get(pt.Type(sourceProvider.Type()), sourceProvider.SerializedConfig())
+	p, release, err := c.Providers.Get(pt.Type(sourceProvider.Type()), sourceProvider.SerializedConfig())
 	if err != nil {
-		return fmt.Errorf("could not get offline provider config: %v", err)
+		return fmt.Errorf("could not get offline provider config: %w", err)
 	}
+	defer func() {
+		if err := release(); err != nil {
+			c.Logger.Errorf("could not release offline store: %v", err)
+		}
+	}()
 	sourceStore, err := p.AsOfflineStore()
 	if err != nil {
-		return fmt.Errorf("convert source provider to offline store interface: %v", err)
+		return fmt.Errorf("convert source provider to offline store interface: %w", err)
 	}
-	defer func(sourceStore provider.OfflineStore) {
-		err := sourceStore.Close()
-		if err != nil {
-			c.Logger.Errorf("could not close offline store: %v", err)
-		}
-	}(sourceStore)
-	var sourceTableName string
-	if source.IsSQLTransformation() || source.IsDFTransformation() {
-		sourceResourceID := provider.ResourceID{sourceNameVariant.Name, sourceNameVariant.Variant, provider.Transformation}
-		sourceTable, err := sourceStore.GetTransformationTable(sourceResourceID)
-		if err != nil {
-			return err
-		}
-		sourceTableName = sourceTable.GetName()
-	} else if source.IsPrimaryDataSQLTable() {
-		sourceResourceID := provider.ResourceID{sourceNameVariant.Name, sourceNameVariant.Variant, provider.Primary}
-		sourceTable, err := sourceStore.GetPrimaryTable(sourceResourceID)
-		if err != nil {
-			return err
-		}
-		sourceTableName = sourceTable.GetName()
+	sourceTable, err := featureSourceTable(sourceStore, source)
+	if err != nil {
+		return fmt.Errorf("get feature source table: %w", err)
 	}
 
 	labelID := provider.ResourceID{
@@ -689,73 +1436,127 @@ func (c *Coordinator) runLabelRegisterJob(resID metadata.ResourceID, schedule st
 		Type:    provider.Label,
 	}
 	tmpSchema := label.LocationColumns().(metadata.ResourceVariantColumns)
+	if err := c.checkLocationColumns(sourceTable, tmpSchema); err != nil {
+		return fmt.Errorf("label %s (%s): %w", resID.Name, resID.Variant, err)
+	}
 	schema := provider.ResourceSchema{
 		Entity:      tmpSchema.Entity,
 		Value:       tmpSchema.Value,
 		TS:          tmpSchema.TS,
-		SourceTable: sourceTableName,
+		SourceTable: sourceTable.GetName(),
 	}
 	c.Logger.Debugw("Creating Label Resource Table", "id", labelID, "schema", schema)
 	_, err = sourceStore.RegisterResourceFromSourceTable(labelID, schema)
 	if err != nil {
 		return fmt.Errorf("register from source: %v", err)
 	}
+	c.tagTable(sourceStore, labelID, label.Owner(), sourceProvider)
 	c.Logger.Debugw("Resource Table Created", "id", labelID, "schema", schema)
 
-	if err := c.Metadata.SetStatus(context.Background(), resID, metadata.READY, ""); err != nil {
+	if err := c.setReady(ctx, resID); err != nil {
 		return fmt.Errorf("set ready status for label variant: %v", err)
 	}
 	return nil
 }
 
-func (c *Coordinator) runFeatureMaterializeJob(resID metadata.ResourceID, schedule string) error {
+// The feature property that opts a feature into keeping every materialized
+// value in its online store, rather than only the latest, so that values can be
+// read as of a past time. Set it to "true" to enable it.
+const FeatureOnlineHistoryProperty = "online_history"
+
+// The feature property that combines each materialized value with the one
+// already in the online store rather than replacing it, such as "sum" for a
+// counter or "union" for a JSON array of tags. Values are replaced if it isn't
+// set.
+const FeatureMergeStrategyProperty = "merge_strategy"
+
+// MaterializeOptions changes how feature materialization jobs run.
+type MaterializeOptions struct {
+	// Force materializes a feature that's already READY again, rather than
+	// failing with ResourceAlreadyCompleteError, so that retrying a job that
+	// succeeded is harmless. Its online table is updated in place, with each
+	// entity's value overwritten by its newest value in the source. Entities
+	// that were written before, including by a run that failed part way, but
+	// are no longer in the source keep their old values unless
+	// TruncateBeforeMaterialize is set. The feature is PENDING while it's
+	// materialized again, and FAILED if that fails.
+	Force bool
+	// Incremental materializes a feature that's already READY again, like
+	// Force, but only writes the rows with a timestamp newer than the newest
+	// one written before, for sources that are only ever appended to. The
+	// newest timestamp is kept in etcd for each feature. A feature's first
+	// materialization, and any with Force also set, write every row.
+	// TruncateBeforeMaterialize only applies to those.
+	Incremental bool
+}
+
+func (c *Coordinator) runFeatureMaterializeJob(ctx context.Context, resID metadata.ResourceID, schedule string) error {
+	defer c.metrics.timeJob(materializeJobType)()
+	return c.materializeFeature(ctx, resID, schedule, c.MaterializeOptions)
+}
+
+func (c *Coordinator) materializeFeature(ctx context.Context, resID metadata.ResourceID, schedule string, opts MaterializeOptions) error {
 	c.Logger.Info("Running feature materialization job on resource: ", resID)
-	feature, err := c.Metadata.GetFeatureVariant(context.Background(), metadata.NameVariant{resID.Name, resID.Variant})
+	feature, err := c.Metadata.GetFeatureVariant(ctx, metadata.NameVariant{resID.Name, resID.Variant})
 	if err != nil {
 		return fmt.Errorf("get feature variant from metadata: %v", err)
 	}
 	status := feature.Status()
 	featureType := feature.Type()
-	if status == metadata.READY {
+	// Materializing a READY feature again writes over its online table, and
+	// its resource table, which only depends on the feature, is reused
+	rematerialize := status == metadata.READY && (opts.Force || opts.Incremental)
+	if status == metadata.READY && !rematerialize {
 		return ResourceAlreadyCompleteError{
 			resourceID: resID,
 		}
 	}
+	if rematerialize {
+		c.Logger.Infow("Materializing READY feature again", "resource", resID)
+	}
 	if status == metadata.FAILED {
 		return ResourceAlreadyFailedError{
 			resourceID: resID,
 		}
 	}
-	if err := c.Metadata.SetStatus(context.Background(), resID, metadata.PENDING, ""); err != nil {
+	var since time.Time
+	if rematerialize && opts.Incremental && !opts.Force {
+		if since, err = c.getMaterializeWatermark(resID); err != nil {
+			return err
+		}
+	}
+	if err := c.setStatus(resID, metadata.PENDING, ""); err != nil {
 		return fmt.Errorf("set feature variant status to pending: %v", err)
 	}
 
 	sourceNameVariant := feature.Source()
 	c.Logger.Infow("feature obj", "name", feature.Name(), "source", feature.Source(), "location", feature.Location(), "location_col", feature.LocationColumns())
 
-	source, err := c.AwaitPendingSource(sourceNameVariant)
+	if err := c.buildUnreadyTransformations(ctx, resID); err != nil {
+		return fmt.Errorf("build feature's source: %v", err)
+	}
+	source, err := c.awaitPendingSource(ctx, sourceNameVariant)
 	if err != nil {
 		return fmt.Errorf("source of could not complete job: %v", err)
 	}
-	sourceProvider, err := source.FetchProvider(c.Metadata, context.Background())
+	sourceProvider, err := source.FetchProvider(c.Metadata, ctx)
 	if err != nil {
 		return fmt.Errorf("could not fetch online provider: %v", err)
 	}
-	p, err := provider.Get(pt.Type(sourceProvider.Type()), sourceProvider.SerializedConfig())
+	p, release, err := c.Providers.Get(pt.Type(sourceProvider.Type()), sourceProvider.SerializedConfig())
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if err := release(); err != nil {
+			c.Logger.Errorf("could not release offline store: %v", err)
+		}
+	}()
 	sourceStore, err := p.AsOfflineStore()
 	if err != nil {
 		return err
 	}
-	defer func(sourceStore provider.OfflineStore) {
-		err := sourceStore.Close()
-		if err != nil {
-			c.Logger.Errorf("could not close offline store: %v", err)
-		}
-	}(sourceStore)
-	featureProvider, err := feature.FetchProvider(c.Metadata, context.Background())
+	featureProvider, err := feature.FetchProvider(c.Metadata, ctx)
 	if err != nil {
 		return fmt.Errorf("could not fetch  onlineprovider: %v", err)
 	}
@@ -773,34 +1574,28 @@ func (c *Coordinator) runFeatureMaterializeJob(resID metadata.ResourceID, schedu
 		return err
 	}
 	materializedRunnerConfig := runner.MaterializedRunnerConfig{
-		OnlineType:    pt.Type(featureProvider.Type()),
-		OfflineType:   pt.Type(sourceProvider.Type()),
-		OnlineConfig:  featureProvider.SerializedConfig(),
-		OfflineConfig: sourceProvider.SerializedConfig(),
-		ResourceID:    provider.ResourceID{Name: resID.Name, Variant: resID.Variant, Type: provider.Feature},
-		VType:         provider.ValueTypeJSONWrapper{ValueType: vType},
-		Cloud:         runner.LocalMaterializeRunner,
-		IsUpdate:      false,
-	}
-	serialized, err := materializedRunnerConfig.Serialize()
-	if err != nil {
-		return fmt.Errorf("could not get online provider config: %v", err)
-	}
-	var sourceTableName string
-	if source.IsSQLTransformation() || source.IsDFTransformation() {
-		sourceResourceID := provider.ResourceID{sourceNameVariant.Name, sourceNameVariant.Variant, provider.Transformation}
-		sourceTable, err := sourceStore.GetTransformationTable(sourceResourceID)
-		if err != nil {
-			return err
-		}
-		sourceTableName = sourceTable.GetName()
-	} else if source.IsPrimaryDataSQLTable() {
-		sourceResourceID := provider.ResourceID{sourceNameVariant.Name, sourceNameVariant.Variant, provider.Primary}
-		sourceTable, err := sourceStore.GetPrimaryTable(sourceResourceID)
-		if err != nil {
-			return err
-		}
-		sourceTableName = sourceTable.GetName()
+		OnlineType:         pt.Type(featureProvider.Type()),
+		OfflineType:        pt.Type(sourceProvider.Type()),
+		OnlineConfig:       featureProvider.SerializedConfig(),
+		OfflineConfig:      sourceProvider.SerializedConfig(),
+		ResourceID:         provider.ResourceID{Name: resID.Name, Variant: resID.Variant, Type: provider.Feature},
+		VType:              provider.ValueTypeJSONWrapper{ValueType: vType},
+		Cloud:              runner.LocalMaterializeRunner,
+		IsUpdate:           rematerialize,
+		BufferSize:         cfg.GetMaterializeBufferSize(),
+		Truncate:           c.TruncateBeforeMaterialize && since.IsZero(),
+		ChunkOrder:         c.MaterializeChunkOrder,
+		Historical:         feature.Properties()[FeatureOnlineHistoryProperty] == "true",
+		ChunkFailurePolicy: c.MaterializeChunkFailurePolicy,
+		MaxChunkRows:       c.MaterializeChunkRows,
+		Parallelism:        c.MaterializeParallelism,
+		Since:              since,
+		TTL:                feature.TTL(),
+		MergeStrategy:      provider.MergeStrategy(feature.Properties()[FeatureMergeStrategyProperty]),
+	}
+	sourceTable, err := featureSourceTable(sourceStore, source)
+	if err != nil {
+		return fmt.Errorf("get feature source table: %w", err)
 	}
 
 	featID := provider.ResourceID{
@@ -809,52 +1604,114 @@ func (c *Coordinator) runFeatureMaterializeJob(resID metadata.ResourceID, schedu
 		Type:    provider.Feature,
 	}
 	tmpSchema := feature.LocationColumns().(metadata.ResourceVariantColumns)
-	schema := provider.ResourceSchema{
-		Entity:      tmpSchema.Entity,
-		Value:       tmpSchema.Value,
-		TS:          tmpSchema.TS,
-		SourceTable: sourceTableName,
-	}
-	c.Logger.Debugw("Creating Resource Table", "id", featID, "schema", schema)
-	_, err = sourceStore.RegisterResourceFromSourceTable(featID, schema)
-	if err != nil {
-		return fmt.Errorf("materialize feature register: %v", err)
+	if err := c.checkLocationColumns(sourceTable, tmpSchema); err != nil {
+		return fmt.Errorf("feature %s (%s): %w", resID.Name, resID.Variant, err)
 	}
-	c.Logger.Debugw("Resource Table Created", "id", featID, "schema", schema)
 	needsOnlineMaterialization := strings.Split(string(featureProvider.Type()), "_")[1] == "ONLINE"
-	if needsOnlineMaterialization {
-		c.Logger.Info("Starting Materialize")
-		jobRunner, err := c.Spawner.GetJobRunner(runner.MATERIALIZE, serialized, resID)
+	streamed := false
+	// Streaming creates the online table, so features materialized again are
+	// always staged
+	if needsOnlineMaterialization && !rematerialize && c.canStreamMaterialization(source, feature, schedule) {
+		streamed, err = c.streamMaterialization(resID, feature, sourceTable, tmpSchema, vType)
 		if err != nil {
-			return fmt.Errorf("could not use store as online store: %w", err)
+			return fmt.Errorf("stream materialization: %w", err)
 		}
-		completionWatcher, err := jobRunner.Run()
+	}
+	// A streamed feature's resource table is only registered if a training
+	// set needs it
+	if !streamed {
+		err := c.registerFeatureResourceTable(sourceStore, featID, feature, sourceTable)
+		var exists *provider.TableAlreadyExists
+		if err != nil && !(rematerialize && errors.As(err, &exists)) {
+			return fmt.Errorf("materialize feature register: %v", err)
+		}
+		c.tagTable(sourceStore, featID, feature.Owner(), sourceProvider)
+	}
+	if needsOnlineMaterialization && !streamed {
+		c.Logger.Info("Starting Materialize")
+		clearProgress := func() {}
+		defer func() { clearProgress() }()
+		attemptConfig := materializedRunnerConfig
+		err = c.runWithRetries(metadata.FEATURE_VARIANT, "materialize job", func() error {
+			serialized, err := attemptConfig.Serialize()
+			if err != nil {
+				return fmt.Errorf("could not get online provider config: %v", err)
+			}
+			// A failed attempt may have already created the materialization
+			// and the online table, so the attempts after it update them
+			attemptConfig.IsUpdate = true
+			jobRunner, err := c.Spawner.GetJobRunner(runner.MATERIALIZE, serialized, resID, feature.KubernetesArgs())
+			if err != nil {
+				return fmt.Errorf("could not use store as online store: %w", err)
+			}
+			clearProgress = c.recordJobProgress(resID, jobRunner)
+			runnerCtx, stopRunner := context.WithCancel(ctx)
+			defer stopRunner()
+			if cancellable, ok := jobRunner.(runner.CancellableRunner); ok {
+				cancellable.SetCancel(runnerCtx.Done())
+			}
+			completionWatcher, err := jobRunner.Run()
+			if err != nil {
+				return fmt.Errorf("creating watcher for completion runner: %w", err)
+			}
+			// Online tables are created before any values are written to them,
+			// so the work has only landed once every chunk has finished. An
+			// incremental materialization's watermark is only known once the
+			// runner finishes, so it has to be waited on.
+			var chunksWritten completionProbe
+			if progressWatcher, ok := completionWatcher.(runner.ProgressWatcher); ok && !opts.Incremental {
+				chunksWritten = func() bool {
+					progress := progressWatcher.Progress()
+					return progress.TotalChunks > 0 && progress.CompletedChunks == progress.TotalChunks
+				}
+			}
+			wait := func() error { return c.waitWithProgress(resID, completionWatcher) }
+			if err := c.awaitRunner(resID, wait, chunksWritten, stopRunner); err != nil {
+				return fmt.Errorf("completion watcher running: %w", err)
+			}
+			marked, ok := completionWatcher.(runner.WatermarkWatcher)
+			if !opts.Incremental || !ok || marked.Watermark().IsZero() {
+				return nil
+			}
+			return c.setMaterializeWatermark(resID, marked.Watermark())
+		})
 		if err != nil {
-			return fmt.Errorf("creating watcher for completion runner: %w", err)
+			return err
 		}
-		if err := completionWatcher.Wait(); err != nil {
-			return fmt.Errorf("completion watcher running: %w", err)
+		if c.VerifyMaterializations {
+			if err := c.checkMaterialization(resID); err != nil {
+				return err
+			}
 		}
 	}
-	if err := c.Metadata.SetStatus(context.Background(), resID, metadata.READY, ""); err != nil {
-		return fmt.Errorf("materialize set success: %v", err)
+	if err := c.setReady(ctx, resID); err != nil {
+		return fmt.Errorf("materialize set success: %w", err)
 	}
 	if schedule != "" && needsOnlineMaterialization {
 		scheduleMaterializeRunnerConfig := runner.MaterializedRunnerConfig{
-			OnlineType:    pt.Type(featureProvider.Type()),
-			OfflineType:   pt.Type(sourceProvider.Type()),
-			OnlineConfig:  featureProvider.SerializedConfig(),
-			OfflineConfig: sourceProvider.SerializedConfig(),
-			ResourceID:    provider.ResourceID{Name: resID.Name, Variant: resID.Variant, Type: provider.Feature},
-			VType:         provider.ValueTypeJSONWrapper{ValueType: vType},
-			Cloud:         runner.LocalMaterializeRunner,
-			IsUpdate:      true,
+			OnlineType:         pt.Type(featureProvider.Type()),
+			OfflineType:        pt.Type(sourceProvider.Type()),
+			OnlineConfig:       featureProvider.SerializedConfig(),
+			OfflineConfig:      sourceProvider.SerializedConfig(),
+			ResourceID:         provider.ResourceID{Name: resID.Name, Variant: resID.Variant, Type: provider.Feature},
+			VType:              provider.ValueTypeJSONWrapper{ValueType: vType},
+			Cloud:              runner.LocalMaterializeRunner,
+			IsUpdate:           true,
+			BufferSize:         cfg.GetMaterializeBufferSize(),
+			Truncate:           c.TruncateBeforeMaterialize,
+			ChunkOrder:         c.MaterializeChunkOrder,
+			Historical:         feature.Properties()[FeatureOnlineHistoryProperty] == "true",
+			ChunkFailurePolicy: c.MaterializeChunkFailurePolicy,
+			MaxChunkRows:       c.MaterializeChunkRows,
+			Parallelism:        c.MaterializeParallelism,
+			TTL:                feature.TTL(),
+			MergeStrategy:      provider.MergeStrategy(feature.Properties()[FeatureMergeStrategyProperty]),
 		}
 		serializedUpdate, err := scheduleMaterializeRunnerConfig.Serialize()
 		if err != nil {
 			return fmt.Errorf("serialize materialize runner config: %v", err)
 		}
-		jobRunnerUpdate, err := c.Spawner.GetJobRunner(runner.MATERIALIZE, serializedUpdate, resID)
+		jobRunnerUpdate, err := c.Spawner.GetJobRunner(runner.MATERIALIZE, serializedUpdate, resID, feature.KubernetesArgs())
 		if err != nil {
 			return fmt.Errorf("creating materialize job schedule job runner: %v", err)
 		}
@@ -865,16 +1722,185 @@ func (c *Coordinator) runFeatureMaterializeJob(resID metadata.ResourceID, schedu
 		if err := cronRunner.ScheduleJob(kubernetes.CronSchedule(schedule)); err != nil {
 			return fmt.Errorf("schedule materialize job in kubernetes: %v", err)
 		}
-		if err := c.Metadata.SetStatus(context.Background(), resID, metadata.READY, ""); err != nil {
+		if err := c.setReady(ctx, resID); err != nil {
 			return fmt.Errorf("set succesful update status for materialize job in kubernetes: %v", err)
 		}
 	}
 	return nil
 }
 
-func (c *Coordinator) runTrainingSetJob(resID metadata.ResourceID, schedule string) error {
+// How often a running materialization's progress is reported
+const materializeProgressInterval = time.Second
+
+// waitWithProgress waits for a materialization to finish, reporting its
+// progress every materializeProgressInterval if its runner can report it.
+func (c *Coordinator) waitWithProgress(resID metadata.ResourceID, watcher types.CompletionWatcher) error {
+	progressWatcher, ok := watcher.(runner.ProgressWatcher)
+	if !ok {
+		return watcher.Wait()
+	}
+	done := make(chan struct{})
+	var reporter sync.WaitGroup
+	reporter.Add(1)
+	go func() {
+		defer reporter.Done()
+		ticker := time.NewTicker(materializeProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				c.reportMaterializeProgress(resID, progressWatcher.Progress())
+			}
+		}
+	}()
+	err := watcher.Wait()
+	close(done)
+	reporter.Wait()
+	if err != nil {
+		return err
+	}
+	c.reportMaterializeProgress(resID, progressWatcher.Progress())
+	return nil
+}
+
+func (c *Coordinator) reportMaterializeProgress(resID metadata.ResourceID, progress runner.MaterializeProgress) {
+	c.Logger.Infow("Materialization progress", "resource", resID, "rows_written", progress.RowsWritten, "total_rows", progress.TotalRows, "completed_chunks", progress.CompletedChunks, "total_chunks", progress.TotalChunks, "percent", progress.Percent())
+	if c.OnMaterializeProgress != nil {
+		c.OnMaterializeProgress(resID, progress)
+	}
+}
+
+// registerFeatureResourceTable registers the resource table of a feature over
+// its source table in the offline store
+func (c *Coordinator) registerFeatureResourceTable(store provider.OfflineStore, id provider.ResourceID, feature *metadata.FeatureVariant, sourceTable provider.PrimaryTable) error {
+	columns := feature.LocationColumns().(metadata.ResourceVariantColumns)
+	schema := provider.ResourceSchema{
+		Entity:      columns.Entity,
+		Value:       columns.Value,
+		TS:          columns.TS,
+		SourceTable: sourceTable.GetName(),
+		Filter:      feature.Filter(),
+	}
+	c.Logger.Debugw("Creating Resource Table", "id", id, "schema", schema)
+	if _, err := store.RegisterResourceFromSourceTable(id, schema); err != nil {
+		return err
+	}
+	c.Logger.Debugw("Resource Table Created", "id", id, "schema", schema)
+	return nil
+}
+
+// registerStreamedFeatures registers the resource tables of a training set's
+// features that were streamed into the online store without one.
+func (c *Coordinator) registerStreamedFeatures(ctx context.Context, store provider.OfflineStore, features []provider.ResourceID) error {
+	for _, id := range features {
+		if _, err := store.GetResourceTable(id); err == nil {
+			continue
+		}
+		feature, err := c.Metadata.GetFeatureVariant(ctx, metadata.NameVariant{Name: id.Name, Variant: id.Variant})
+		if err != nil {
+			return fmt.Errorf("get feature %s (%s): %v", id.Name, id.Variant, err)
+		}
+		source, err := c.Metadata.GetSourceVariant(ctx, feature.Source())
+		if err != nil {
+			return fmt.Errorf("get source of feature %s (%s): %v", id.Name, id.Variant, err)
+		}
+		sourceTable, err := featureSourceTable(store, source)
+		if err != nil {
+			return fmt.Errorf("get source table of feature %s (%s): %w", id.Name, id.Variant, err)
+		}
+		err = c.registerFeatureResourceTable(store, id, feature, sourceTable)
+		var exists *provider.TableAlreadyExists
+		if err != nil && !errors.As(err, &exists) {
+			return fmt.Errorf("register feature %s (%s): %v", id.Name, id.Variant, err)
+		}
+	}
+	return nil
+}
+
+// featureSourceTable returns the table a feature over source reads from. A
+// transformation is read from its output table, so a feature can be defined
+// directly over a transformation without registering its output as a source.
+func featureSourceTable(sourceStore provider.OfflineStore, source *metadata.SourceVariant) (provider.PrimaryTable, error) {
+	switch {
+	case source.IsSQLTransformation() || source.IsDFTransformation():
+		return sourceStore.GetTransformationTable(provider.ResourceID{Name: source.Name(), Variant: source.Variant(), Type: provider.Transformation})
+	case source.IsPrimaryDataSQLTable() || source.IsPrimaryDataQuery() || source.IsPrimaryDataIcebergTable():
+		return sourceStore.GetPrimaryTable(provider.ResourceID{Name: source.Name(), Variant: source.Variant(), Type: provider.Primary})
+	default:
+		return nil, fmt.Errorf("source %s (%s) is neither a transformation nor a primary table", source.Name(), source.Variant())
+	}
+}
+
+// The training set property that sets what happens to label rows whose entity
+// has no value for a feature: "left_outer" (the default) keeps them with a null
+// feature value and "inner" drops them.
+const TrainingSetJoinPolicyProperty = "join_policy"
+
+// The training set property that sets which feature value is joined to each
+// label row: "point_in_time" (the default) joins the latest value at or before
+// the label's timestamp and "latest" joins the latest value.
+const TrainingSetJoinStrategyProperty = "join_strategy"
+
+// buildSmallTrainingSet builds a training set in memory if its store supports
+// it and its sources have at most InMemoryTrainingSetMaxRows rows, and returns
+// whether it did.
+func (c *Coordinator) buildSmallTrainingSet(store provider.OfflineStore, def provider.TrainingSetDef) (bool, error) {
+	memoryStore, ok := store.(provider.InMemoryTrainingSetStore)
+	if c.InMemoryTrainingSetMaxRows <= 0 || !ok || len(def.LagFeatures) > 0 {
+		return false, nil
+	}
+	rows, err := memoryStore.TrainingSetSourceRows(def)
+	if err != nil {
+		return false, fmt.Errorf("count training set source rows: %v", err)
+	}
+	if rows > c.InMemoryTrainingSetMaxRows {
+		return false, nil
+	}
+	c.Logger.Infow("Building training set in memory", "id", def.ID, "source_rows", rows)
+	if err := memoryStore.CreateTrainingSetInMemory(def); err != nil {
+		return false, fmt.Errorf("create training set in memory: %v", err)
+	}
+	return true, nil
+}
+
+func (c *Coordinator) runTrainingSetRunner(ctx context.Context, resID metadata.ResourceID, config runner.TrainingSetRunnerConfig, args metadata.KubernetesArgs, store provider.OfflineStore) error {
+	serialized, _ := config.Serialize()
+	jobRunner, err := c.Spawner.GetJobRunner(runner.CREATE_TRAINING_SET, serialized, resID, args)
+	if err != nil {
+		return fmt.Errorf("create training set job runner: %w", err)
+	}
+	runnerCtx, stopRunner := context.WithCancel(ctx)
+	defer stopRunner()
+	if cancellable, ok := jobRunner.(runner.CancellableRunner); ok {
+		cancellable.SetCancel(runnerCtx.Done())
+	}
+	return c.runWithRetries(metadata.TRAINING_SET_VARIANT, "training set job", func() error {
+		completionWatcher, err := jobRunner.Run()
+		if err != nil {
+			return fmt.Errorf("start training set job runner: %v", err)
+		}
+		// An updated training set already exists, so only the runner can tell
+		// when it's done
+		var trainingSetExists completionProbe
+		if !config.IsUpdate {
+			trainingSetExists = func() bool {
+				_, err := store.GetTrainingSet(config.Def.ID)
+				return err == nil
+			}
+		}
+		if err := c.awaitRunner(resID, completionWatcher.Wait, trainingSetExists, stopRunner); err != nil {
+			return fmt.Errorf("wait for training set job runner completion: %v", err)
+		}
+		return nil
+	})
+}
+
+func (c *Coordinator) runTrainingSetJob(ctx context.Context, resID metadata.ResourceID, schedule string) error {
 	c.Logger.Info("Running training set job on resource: ", "name", resID.Name, "variant", resID.Variant)
-	ts, err := c.Metadata.GetTrainingSetVariant(context.Background(), metadata.NameVariant{resID.Name, resID.Variant})
+	defer c.metrics.timeJob(trainingSetJobType)()
+	ts, err := c.Metadata.GetTrainingSetVariant(ctx, metadata.NameVariant{resID.Name, resID.Variant})
 	if err != nil {
 		return fmt.Errorf("fetch training set variant from metadata: %v", err)
 	}
@@ -889,48 +1915,107 @@ func (c *Coordinator) runTrainingSetJob(resID metadata.ResourceID, schedule stri
 			resourceID: resID,
 		}
 	}
-	if err := c.Metadata.SetStatus(context.Background(), resID, metadata.PENDING, ""); err != nil {
+	if err := c.setStatus(resID, metadata.PENDING, ""); err != nil {
 		return fmt.Errorf("set training set variant status to pending: %v", err)
 	}
-	providerEntry, err := ts.FetchProvider(c.Metadata, context.Background())
+	providerEntry, err := ts.FetchProvider(c.Metadata, ctx)
 	if err != nil {
 		return fmt.Errorf("fetch training set variant offline provider: %v", err)
 	}
-	p, err := provider.Get(pt.Type(providerEntry.Type()), providerEntry.SerializedConfig())
+	p, release, err := c.Providers.Get(pt.Type(providerEntry.Type()), providerEntry.SerializedConfig())
 	if err != nil {
-		return fmt.Errorf("fetch offline store interface of training set provider: %v", err)
+		return fmt.Errorf("fetch offline store interface of training set provider: %w", err)
 	}
+	defer func() {
+		if err := release(); err != nil {
+			c.Logger.Errorf("could not release offline store: %v", err)
+		}
+	}()
 	store, err := p.AsOfflineStore()
 	if err != nil {
-		return fmt.Errorf("convert training set provider to offline store interface: %v", err)
+		return fmt.Errorf("convert training set provider to offline store interface: %w", err)
 	}
-	defer func(store provider.OfflineStore) {
-		err := store.Close()
-		if err != nil {
-			c.Logger.Errorf("could not close offline store: %v", err)
-		}
-	}(store)
 	providerResID := provider.ResourceID{Name: resID.Name, Variant: resID.Variant, Type: provider.TrainingSet}
 
 	if _, err := store.GetTrainingSet(providerResID); err == nil {
 		return fmt.Errorf("training set (%v) already exists: %v", resID, err)
 	}
+	trainingSetDef, err := c.trainingSetDef(ctx, ts, providerResID)
+	if err != nil {
+		return err
+	}
+	if err := c.registerStreamedFeatures(ctx, store, trainingSetDef.Features); err != nil {
+		return err
+	}
+	builtInMemory, err := c.buildSmallTrainingSet(store, trainingSetDef)
+	if err != nil {
+		return err
+	}
+	if !builtInMemory {
+		tsRunnerConfig := runner.TrainingSetRunnerConfig{
+			OfflineType:   pt.Type(providerEntry.Type()),
+			OfflineConfig: providerEntry.SerializedConfig(),
+			Def:           trainingSetDef,
+			IsUpdate:      false,
+		}
+		if err := c.runTrainingSetRunner(ctx, resID, tsRunnerConfig, ts.KubernetesArgs(), store); err != nil {
+			return err
+		}
+	}
+	c.tagTable(store, providerResID, ts.Owner(), providerEntry)
+	if err := c.setReady(ctx, resID); err != nil {
+		return fmt.Errorf("set training set job runner status: %v", err)
+	}
+	if schedule != "" {
+		scheduleTrainingSetRunnerConfig := runner.TrainingSetRunnerConfig{
+			OfflineType:   pt.Type(providerEntry.Type()),
+			OfflineConfig: providerEntry.SerializedConfig(),
+			Def:           trainingSetDef,
+			IsUpdate:      true,
+		}
+		serializedUpdate, err := scheduleTrainingSetRunnerConfig.Serialize()
+		if err != nil {
+			return fmt.Errorf("serialize training set schedule runner config: %v", err)
+		}
+		jobRunnerUpdate, err := c.Spawner.GetJobRunner(runner.CREATE_TRAINING_SET, serializedUpdate, resID, ts.KubernetesArgs())
+		if err != nil {
+			return fmt.Errorf("spawn training set job runner: %v", err)
+		}
+		cronRunner, isCronRunner := jobRunnerUpdate.(kubernetes.CronRunner)
+		if !isCronRunner {
+			// Runners that can't be scheduled themselves are rerun by the
+			// coordinators instead
+			return c.scheduleJob(resID, schedule)
+		}
+		if err := cronRunner.ScheduleJob(kubernetes.CronSchedule(schedule)); err != nil {
+			return fmt.Errorf("schedule training set job in kubernetes: %v", err)
+		}
+		if err := c.setReady(ctx, resID); err != nil {
+			return fmt.Errorf("update training set scheduler job status: %v", err)
+		}
+	}
+	return nil
+}
+
+// trainingSetDef waits for a training set's features and label to be ready
+// and returns the definition its table is built from.
+func (c *Coordinator) trainingSetDef(ctx context.Context, ts *metadata.TrainingSetVariant, providerResID provider.ResourceID) (provider.TrainingSetDef, error) {
 	features := ts.Features()
 	featureList := make([]provider.ResourceID, len(features))
 	for i, feature := range features {
 		featureList[i] = provider.ResourceID{Name: feature.Name, Variant: feature.Variant, Type: provider.Feature}
-		featureResource, err := c.Metadata.GetFeatureVariant(context.Background(), feature)
+		featureResource, err := c.Metadata.GetFeatureVariant(ctx, feature)
 		if err != nil {
-			return fmt.Errorf("failed to get fetch dependent feature: %v", err)
+			return provider.TrainingSetDef{}, fmt.Errorf("failed to get fetch dependent feature: %v", err)
 		}
 		sourceNameVariant := featureResource.Source()
-		_, err = c.AwaitPendingSource(sourceNameVariant)
+		_, err = c.awaitPendingSource(ctx, sourceNameVariant)
 		if err != nil {
-			return fmt.Errorf("source of feature could not complete job: %v", err)
+			return provider.TrainingSetDef{}, fmt.Errorf("source of feature could not complete job: %v", err)
 		}
-		_, err = c.AwaitPendingFeature(metadata.NameVariant{feature.Name, feature.Variant})
+		_, err = c.awaitPendingFeature(ctx, metadata.NameVariant{feature.Name, feature.Variant})
 		if err != nil {
-			return fmt.Errorf("feature could not complete job: %v", err)
+			return provider.TrainingSetDef{}, fmt.Errorf("feature could not complete job: %v", err)
 		}
 	}
 
@@ -945,73 +2030,32 @@ func (c *Coordinator) runTrainingSetJob(resID metadata.ResourceID, schedule stri
 		}
 	}
 
-	label, err := ts.FetchLabel(c.Metadata, context.Background())
+	label, err := ts.FetchLabel(c.Metadata, ctx)
 	if err != nil {
-		return fmt.Errorf("fetch training set label: %v", err)
+		return provider.TrainingSetDef{}, fmt.Errorf("fetch training set label: %v", err)
 	}
 	labelSourceNameVariant := label.Source()
-	_, err = c.AwaitPendingSource(labelSourceNameVariant)
+	_, err = c.awaitPendingSource(ctx, labelSourceNameVariant)
 	if err != nil {
-		return fmt.Errorf("source of label could not complete job: %v", err)
-	}
-	label, err = c.AwaitPendingLabel(metadata.NameVariant{label.Name(), label.Variant()})
-	if err != nil {
-		return fmt.Errorf("label could not complete job: %v", err)
-	}
-	trainingSetDef := provider.TrainingSetDef{
-		ID:          providerResID,
-		Label:       provider.ResourceID{Name: label.Name(), Variant: label.Variant(), Type: provider.Label},
-		Features:    featureList,
-		LagFeatures: lagFeaturesList,
-	}
-	tsRunnerConfig := runner.TrainingSetRunnerConfig{
-		OfflineType:   pt.Type(providerEntry.Type()),
-		OfflineConfig: providerEntry.SerializedConfig(),
-		Def:           trainingSetDef,
-		IsUpdate:      false,
+		return provider.TrainingSetDef{}, fmt.Errorf("source of label could not complete job: %v", err)
 	}
-	serialized, _ := tsRunnerConfig.Serialize()
-	jobRunner, err := c.Spawner.GetJobRunner(runner.CREATE_TRAINING_SET, serialized, resID)
+	label, err = c.awaitPendingLabel(ctx, metadata.NameVariant{label.Name(), label.Variant()})
 	if err != nil {
-		return fmt.Errorf("create training set job runner: %v", err)
+		return provider.TrainingSetDef{}, fmt.Errorf("label could not complete job: %v", err)
 	}
-	completionWatcher, err := jobRunner.Run()
+	parquetConfig, err := parquetWriteConfig(ts.Properties())
 	if err != nil {
-		return fmt.Errorf("start training set job runner: %v", err)
+		return provider.TrainingSetDef{}, err
 	}
-	if err := completionWatcher.Wait(); err != nil {
-		return fmt.Errorf("wait for training set job runner completion: %v", err)
-	}
-	if err := c.Metadata.SetStatus(context.Background(), resID, metadata.READY, ""); err != nil {
-		return fmt.Errorf("set training set job runner status: %v", err)
-	}
-	if schedule != "" {
-		scheduleTrainingSetRunnerConfig := runner.TrainingSetRunnerConfig{
-			OfflineType:   pt.Type(providerEntry.Type()),
-			OfflineConfig: providerEntry.SerializedConfig(),
-			Def:           trainingSetDef,
-			IsUpdate:      true,
-		}
-		serializedUpdate, err := scheduleTrainingSetRunnerConfig.Serialize()
-		if err != nil {
-			return fmt.Errorf("serialize training set schedule runner config: %v", err)
-		}
-		jobRunnerUpdate, err := c.Spawner.GetJobRunner(runner.CREATE_TRAINING_SET, serializedUpdate, resID)
-		if err != nil {
-			return fmt.Errorf("spawn training set job runner: %v", err)
-		}
-		cronRunner, isCronRunner := jobRunnerUpdate.(kubernetes.CronRunner)
-		if !isCronRunner {
-			return fmt.Errorf("kubernetes runner does not implement schedule")
-		}
-		if err := cronRunner.ScheduleJob(kubernetes.CronSchedule(schedule)); err != nil {
-			return fmt.Errorf("schedule training set job in kubernetes: %v", err)
-		}
-		if err := c.Metadata.SetStatus(context.Background(), resID, metadata.READY, ""); err != nil {
-			return fmt.Errorf("update training set scheduler job status: %v", err)
-		}
-	}
-	return nil
+	return provider.TrainingSetDef{
+		ID:            providerResID,
+		Label:         provider.ResourceID{Name: label.Name(), Variant: label.Variant(), Type: provider.Label},
+		Features:      featureList,
+		LagFeatures:   lagFeaturesList,
+		JoinPolicy:    provider.JoinPolicy(ts.Properties()[TrainingSetJoinPolicyProperty]),
+		JoinStrategy:  provider.JoinStrategy(ts.Properties()[TrainingSetJoinStrategyProperty]),
+		ParquetConfig: parquetConfig,
+	}, nil
 }
 
 func (c *Coordinator) getJob(mtx *concurrency.Mutex, key string) (*metadata.CoordinatorJob, error) {
@@ -1088,9 +2132,26 @@ func (c *Coordinator) hasJob(id metadata.ResourceID) (bool, error) {
 	return false, nil
 }
 
-func (c *Coordinator) createJobLock(jobKey string, s *concurrency.Session) (*concurrency.Mutex, error) {
+// createJobLock locks a job so that only one run of it, across every
+// coordinator, executes it. Unless wait is set, it returns ErrJobLocked rather
+// than waiting for a lock that's already held.
+func (c *Coordinator) createJobLock(ctx context.Context, jobKey string, s *concurrency.Session, wait bool) (*concurrency.Mutex, error) {
 	mtx := concurrency.NewMutex(s, GetLockKey(jobKey))
-	if err := mtx.Lock(context.Background()); err != nil {
+	if !wait {
+		err := mtx.TryLock(ctx)
+		if errors.Is(err, concurrency.ErrLocked) {
+			return nil, fmt.Errorf("%w: %s", ErrJobLocked, jobKey)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return mtx, nil
+	}
+	if err := mtx.Lock(ctx); err != nil {
+		// Such as when the coordinator is shut down while waiting for the lock
+		if ctx.Err() != nil {
+			return nil, err
+		}
 		c.Logger.Debugw("could not create job lock restarting.....", "error", err)
 		os.Exit(1)
 	}
@@ -1098,21 +2159,47 @@ func (c *Coordinator) createJobLock(jobKey string, s *concurrency.Session) (*con
 }
 
 func (c *Coordinator) ExecuteJob(jobKey string) error {
+	return c.executeJob(context.Background(), jobKey, true)
+}
+
+// ExecuteJobWithTimeout runs a job like ExecuteJob, but gives up on it once
+// timeout has passed. A job that times out is marked FAILED and deleted, so it
+// can be retried by creating it again.
+func (c *Coordinator) ExecuteJobWithTimeout(jobKey string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.executeJob(ctx, jobKey, true)
+}
+
+// executeJob runs a job once it holds the job's lock. Unless waitForLock is
+// set, it returns ErrJobLocked if the lock is already held.
+func (c *Coordinator) executeJob(ctx context.Context, jobKey string, waitForLock bool) (err error) {
+	jobDone, err := c.startJob()
+	if err != nil {
+		return err
+	}
+	defer jobDone()
+	ctx, stopJob := c.jobContext(ctx)
+	defer stopJob()
 	c.Logger.Info("Executing new job with key ", jobKey)
 	s, err := concurrency.NewSession(c.EtcdClient, concurrency.WithTTL(1))
 	if err != nil {
 		return fmt.Errorf("new session: %v", err)
 	}
 	defer s.Close()
-	mtx, err := c.createJobLock(jobKey, s)
+	mtx, err := c.createJobLock(ctx, jobKey, s, waitForLock)
 	if err != nil {
-		return fmt.Errorf("job lock: %v", err)
+		return fmt.Errorf("job lock: %w", err)
 	}
 	defer func() {
 		if err := mtx.Unlock(context.Background()); err != nil {
 			c.Logger.Debugw("Error unlocking mutex:", "error", err)
 		}
 	}()
+	// Tracked before the job is read, so that a cancellation can't slip in
+	// between reading the job and running it
+	ctx, running, release := c.trackRunningJob(ctx, jobKey)
+	defer release()
 	job, err := c.getJob(mtx, jobKey)
 	if err != nil {
 		return err
@@ -1123,12 +2210,15 @@ func (c *Coordinator) ExecuteJob(jobKey string) error {
 			c.Logger.Debugw("Error deleting job", "error", err)
 			return fmt.Errorf("job delete: %v", err)
 		}
-		return fmt.Errorf("job failed after %d attempts. Cancelling coordinator flow", MAX_ATTEMPTS)
+		err := fmt.Errorf("job failed after %d attempts. Cancelling coordinator flow", MAX_ATTEMPTS)
+		c.recordJob(job, err)
+		c.recordFailedJob(job, err)
+		return err
 	}
 	if err := c.incrementJobAttempts(mtx, job, jobKey); err != nil {
 		return fmt.Errorf("increment attempt: %v", err)
 	}
-	type jobFunction func(metadata.ResourceID, string) error
+	type jobFunction func(context.Context, metadata.ResourceID, string) error
 	fns := map[metadata.ResourceType]jobFunction{
 		metadata.TRAINING_SET_VARIANT: c.runTrainingSetJob,
 		metadata.FEATURE_VARIANT:      c.runFeatureMaterializeJob,
@@ -1139,17 +2229,62 @@ func (c *Coordinator) ExecuteJob(jobKey string) error {
 	if !has {
 		return fmt.Errorf("not a valid resource type for running jobs")
 	}
+	jobFinished := c.metrics.jobStarted(job.Resource.Type)
+	defer func() { jobFinished(err) }()
 
-	if err := jobFunc(job.Resource, job.Schedule); err != nil {
+	done := make(chan error, 1)
+	c.jobWork.Add(1)
+	go func() {
+		defer c.jobWork.Done()
+		err := c.checkQuota(job.Resource)
+		if err == nil {
+			err = jobFunc(ctx, job.Resource, job.Schedule)
+		}
+		done <- err
+	}()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		if running.wasCancelled() {
+			// CancelJob has already failed and deleted the job. The lock is
+			// held until the job's runners have stopped, so that the resource
+			// isn't run again while they're still writing to it.
+			<-done
+			err = fmt.Errorf("job %s: %w", jobKey, ErrJobCancelled)
+			c.recordJob(job, err)
+			return err
+		}
+		if c.jobsStopped() {
+			// The job is kept, and its resource left PENDING, so that another
+			// coordinator runs it once the lock is released
+			err = fmt.Errorf("job %s: %w", jobKey, ErrShutdown)
+			c.recordJob(job, err)
+			return err
+		}
+		// The job is left to notice ctx is done on its own, but it's failed and
+		// deleted now so that the lock is released and it can be retried
+		err = fmt.Errorf("job %s timed out: %w", jobKey, ctx.Err())
+		c.recordJob(job, err)
+		c.recordFailedJob(job, err)
+		statusErr := c.setStatus(job.Resource, metadata.FAILED, err.Error())
+		if deleteErr := c.deleteJob(mtx, jobKey); deleteErr != nil {
+			c.Logger.Debugw("Error deleting timed out job", "error", deleteErr)
+		}
+		return fmt.Errorf("%s job failed: %w: %v", job.Resource.Type, err, statusErr)
+	}
+	c.recordJob(job, err)
+	if err != nil {
 		switch err.(type) {
 		case ResourceAlreadyFailedError:
 			return err
 		default:
-			statusErr := c.Metadata.SetStatus(context.Background(), job.Resource, metadata.FAILED, err.Error())
+			c.recordFailedJob(job, err)
+			statusErr := c.setStatus(job.Resource, metadata.FAILED, failureStatusMessage(err))
 			return fmt.Errorf("%s job failed: %w: %v", job.Resource.Type, err, statusErr)
 		}
 	}
 	c.Logger.Info("Successfully executed job with key: ", jobKey)
+	c.clearFailedJob(job.Resource)
 	if err := c.deleteJob(mtx, jobKey); err != nil {
 		c.Logger.Debugw("Error deleting job", "error", err)
 		return fmt.Errorf("job delete: %v", err)
@@ -1185,7 +2320,7 @@ func (c *Coordinator) signalResourceUpdate(key string, value string) error {
 		return fmt.Errorf("create new concurrency session for resource update job: %v", err)
 	}
 	defer s.Close()
-	mtx, err := c.createJobLock(key, s)
+	mtx, err := c.createJobLock(context.Background(), key, s, true)
 	if err != nil {
 		return fmt.Errorf("create lock on resource update job with key %s: %v", key, err)
 	}
@@ -1198,7 +2333,7 @@ func (c *Coordinator) signalResourceUpdate(key string, value string) error {
 	if err := resUpdatedEvent.Deserialize(Config(value)); err != nil {
 		return fmt.Errorf("deserialize resource update event: %v", err)
 	}
-	if err := c.Metadata.SetStatus(context.Background(), resUpdatedEvent.ResourceID, metadata.READY, ""); err != nil {
+	if err := c.setStatus(resUpdatedEvent.ResourceID, metadata.READY, ""); err != nil {
 		return fmt.Errorf("set resource update status: %v", err)
 	}
 	c.Logger.Info("Succesfully set update status for update job with key: ", key)
@@ -1220,7 +2355,7 @@ func (c *Coordinator) changeJobSchedule(key string, value string) error {
 			c.Logger.Debugw("Error closing scheduling session", "error", err)
 		}
 	}(s)
-	mtx, err := c.createJobLock(key, s)
+	mtx, err := c.createJobLock(context.Background(), key, s, true)
 	if err != nil {
 		return fmt.Errorf("create lock on resource update job with key %s: %v", key, err)
 	}
@@ -1250,7 +2385,7 @@ func (c *Coordinator) changeJobSchedule(key string, value string) error {
 	if _, err := jobClient.UpdateCronJob(cronJob); err != nil {
 		return fmt.Errorf("update kubernetes cron job: %v", err)
 	}
-	if err := c.Metadata.SetStatus(context.Background(), coordinatorScheduleJob.Resource, metadata.READY, ""); err != nil {
+	if err := c.setStatus(coordinatorScheduleJob.Resource, metadata.READY, ""); err != nil {
 		return fmt.Errorf("set schedule job update status in metadata: %v", err)
 	}
 	c.Logger.Info("Successfully updated schedule for job in kubernetes with key: ", key)
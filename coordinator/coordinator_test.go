@@ -3,27 +3,39 @@ package coordinator
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"net"
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	cfg "github.com/featureform/config"
 	help "github.com/featureform/helpers"
 	"github.com/google/uuid"
 
+	"github.com/featureform/kubernetes"
 	"github.com/featureform/metadata"
 	"github.com/featureform/provider"
 	pc "github.com/featureform/provider/provider_config"
 	pt "github.com/featureform/provider/provider_type"
 	"github.com/featureform/runner"
+	"github.com/featureform/types"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 func createSafeUUID() string {
@@ -50,6 +62,9 @@ var postgresConfig = pc.PostgresConfig{
 var redisPort = help.GetEnv("REDIS_INSECURE_PORT", "6379")
 var redisHost = "localhost"
 
+// Cassandra is only used by the tests that set it
+var cassandraAddr = os.Getenv("CASSANDRA_ADDR")
+
 var etcdHost = "localhost"
 var etcdPort = "2379"
 
@@ -82,6 +97,192 @@ func TestRetryWithDelays(t *testing.T) {
 	}
 }
 
+func TestExponentialBackoff(t *testing.T) {
+	backoff := exponentialBackoff(time.Millisecond, 10*time.Millisecond, nil)
+	expected := []time.Duration{1, 2, 4, 8, 10, 10}
+	for attempt, delay := range expected {
+		if actual := backoff(attempt); actual != delay*time.Millisecond {
+			t.Fatalf("expected delay after attempt %d to be %v, got %v", attempt, delay*time.Millisecond, actual)
+		}
+	}
+}
+
+func TestBackoffJitterIsDeterministic(t *testing.T) {
+	delays := func(seed int64) []time.Duration {
+		backoff := exponentialBackoff(time.Millisecond, 50*time.Millisecond, rand.New(rand.NewSource(seed)))
+		delays := make([]time.Duration, 8)
+		for i := range delays {
+			delays[i] = backoff(i)
+		}
+		return delays
+	}
+	first, second := delays(42), delays(42)
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("expected the same seed to give the same delays, got %v and %v", first, second)
+	}
+	unjittered := exponentialBackoff(time.Millisecond, 50*time.Millisecond, nil)
+	for attempt, delay := range first {
+		max := unjittered(attempt)
+		if delay < max/2 || delay > max {
+			t.Fatalf("expected jittered delay after attempt %d to be between %v and %v, got %v", attempt, max/2, max, delay)
+		}
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	failsTwice := failingRunner{2}
+	start := time.Now()
+	if err := retryWithBackoff("run runner", 5, 10*time.Millisecond, time.Second, failsTwice.Run, withJitter(1)); err != nil {
+		t.Fatalf("retry with backoff failed on runner that fails twice: %v", err)
+	}
+	// Jitter waits at least half of the 10ms and 20ms delays
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Fatalf("expected retries to back off for at least 15ms, took %v", elapsed)
+	}
+	alwaysFails := failingRunner{-1}
+	if err := retryWithBackoff("run runner", 3, time.Millisecond, time.Millisecond, alwaysFails.Run); err == nil {
+		t.Fatalf("retry with backoff doesn't fail on always failing runner")
+	}
+}
+
+// alwaysFailingJobRunner counts how many times it was started.
+type alwaysFailingJobRunner struct {
+	runs int
+}
+
+func (r *alwaysFailingJobRunner) Run() (types.CompletionWatcher, error) {
+	r.runs++
+	return nil, fmt.Errorf("failure")
+}
+
+func (r *alwaysFailingJobRunner) Resource() metadata.ResourceID {
+	return metadata.ResourceID{}
+}
+
+func (r *alwaysFailingJobRunner) IsUpdateJob() bool {
+	return false
+}
+
+func TestJobRetriesPerType(t *testing.T) {
+	c := &Coordinator{
+		Logger: zap.NewExample().Sugar(),
+		JobRetries: map[metadata.ResourceType]JobRetryPolicy{
+			metadata.FEATURE_VARIANT:      {Attempts: 10, Delay: time.Millisecond},
+			metadata.TRAINING_SET_VARIANT: {Attempts: 3, Delay: time.Millisecond},
+		},
+	}
+	expectedAttempts := map[metadata.ResourceType]int{
+		metadata.FEATURE_VARIANT:      10,
+		metadata.TRAINING_SET_VARIANT: 3,
+		metadata.SOURCE_VARIANT:       1,
+	}
+	for resType, expected := range expectedAttempts {
+		jobRunner := &alwaysFailingJobRunner{}
+		err := c.runWithRetries(resType, "test job", func() error {
+			_, err := jobRunner.Run()
+			return err
+		})
+		if err == nil {
+			t.Fatalf("%s: expected always failing job to fail", resType)
+		}
+		if jobRunner.runs != expected {
+			t.Fatalf("%s: expected %d attempts, got %d", resType, expected, jobRunner.runs)
+		}
+	}
+}
+
+func TestSortJobQueue(t *testing.T) {
+	job := func(resType metadata.ResourceType, name string) string {
+		return metadata.GetJobKey(metadata.ResourceID{Name: name, Type: resType})
+	}
+	jobKeys := []string{
+		job(metadata.TRAINING_SET_VARIANT, "ts"),
+		job(metadata.FEATURE_VARIANT, "f1"),
+		job(metadata.SOURCE_VARIANT, "s1"),
+		job(metadata.FEATURE_VARIANT, "f2"),
+		job(metadata.LABEL_VARIANT, "l"),
+		job(metadata.SOURCE_VARIANT, "s2"),
+	}
+	expected := []string{
+		job(metadata.SOURCE_VARIANT, "s1"),
+		job(metadata.SOURCE_VARIANT, "s2"),
+		job(metadata.LABEL_VARIANT, "l"),
+		job(metadata.FEATURE_VARIANT, "f1"),
+		job(metadata.FEATURE_VARIANT, "f2"),
+		job(metadata.TRAINING_SET_VARIANT, "ts"),
+	}
+	sortJobQueue(jobKeys)
+	if !reflect.DeepEqual(jobKeys, expected) {
+		t.Fatalf("expected jobs queued as %v, got %v", expected, jobKeys)
+	}
+}
+
+func TestNewCoordinatorWithConcurrencyRejectsZero(t *testing.T) {
+	if _, err := NewCoordinatorWithConcurrency(nil, zap.NewExample().Sugar(), nil, &MemoryJobSpawner{}, 0); err == nil {
+		t.Fatalf("expected a coordinator without job slots to be rejected")
+	}
+}
+
+// A job that times out is marked FAILED while it's still running, so it can't
+// mark its resource READY once it finishes. The coordinator has no metadata
+// client, so setting a status would panic.
+func TestSetReadyAfterJobStopped(t *testing.T) {
+	c := &Coordinator{Logger: zap.NewExample().Sugar()}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resID := metadata.ResourceID{Name: "transformation", Variant: "v", Type: metadata.SOURCE_VARIANT}
+	if err := c.setReady(ctx, resID); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected stopped job not to be marked ready, got %v", err)
+	}
+}
+
+func TestAwaitRunnerGracePeriod(t *testing.T) {
+	c := &Coordinator{
+		Logger:            zap.NewExample().Sugar(),
+		RunnerTimeout:     10 * time.Millisecond,
+		RunnerGracePeriod: time.Second,
+	}
+	resID := metadata.ResourceID{Name: "name", Variant: "variant", Type: metadata.FEATURE_VARIANT}
+	finishesAfter := func(delay time.Duration) func() error {
+		return func() error {
+			time.Sleep(delay)
+			return nil
+		}
+	}
+	stopped := false
+	stop := func() { stopped = true }
+	if err := c.awaitRunner(resID, finishesAfter(100*time.Millisecond), nil, stop); err != nil {
+		t.Fatalf("expected job finishing within grace period to succeed: %v", err)
+	}
+	if stopped {
+		t.Fatalf("expected runner that finished not to be stopped")
+	}
+	landed := func() bool { return true }
+	if err := c.awaitRunner(resID, finishesAfter(time.Hour), landed, stop); err != nil {
+		t.Fatalf("expected job whose work landed to succeed: %v", err)
+	}
+	if !stopped {
+		t.Fatalf("expected runner whose work landed to be stopped")
+	}
+	stopped = false
+	c.RunnerGracePeriod = 50 * time.Millisecond
+	err := c.awaitRunner(resID, finishesAfter(time.Hour), func() bool { return false }, stop)
+	if _, ok := err.(JobTimeoutError); !ok {
+		t.Fatalf("expected JobTimeoutError after grace period, got %v", err)
+	}
+	if !stopped {
+		t.Fatalf("expected timed out runner to be stopped")
+	}
+	failure := fmt.Errorf("failure")
+	err = c.awaitRunner(resID, func() error {
+		time.Sleep(20 * time.Millisecond)
+		return failure
+	}, nil, stop)
+	if err != failure {
+		t.Fatalf("expected runner error within grace period to be returned, got %v", err)
+	}
+}
+
 func startServ(t *testing.T) (*metadata.MetadataServer, string) {
 	logger := zap.NewExample().Sugar()
 	storageProvider := metadata.EtcdStorageProvider{
@@ -127,502 +328,663 @@ func createNewCoordinator(addr string) (*Coordinator, error) {
 	return NewCoordinator(client, logger, cli, &memJobSpawner)
 }
 
-// may cause an error depending on kubernetes implementation
-func TestKubernetesJobRunnerError(t *testing.T) {
-	kubeJobSpawner := KubernetesJobSpawner{}
-	if _, err := kubeJobSpawner.GetJobRunner("ghost_job", []byte{}, metadata.ResourceID{}); err == nil {
-		t.Fatalf("did not trigger error getting nonexistent runner")
+// createFeatureWithInvalidProvider registers a feature whose source is READY
+// but whose provider can't be created, so its materialization job always fails.
+func createFeatureWithInvalidProvider(client *metadata.Client) (metadata.ResourceID, error) {
+	userName := createSafeUUID()
+	providerName := createSafeUUID()
+	entityName := createSafeUUID()
+	sourceName := createSafeUUID()
+	featureName := createSafeUUID()
+	defs := []metadata.ResourceDef{
+		metadata.UserDef{Name: userName},
+		metadata.ProviderDef{Name: providerName, Type: "INVALID_PROVIDER", SerializedConfig: []byte{}},
+		metadata.EntityDef{Name: entityName},
+		metadata.SourceDef{
+			Name:     sourceName,
+			Owner:    userName,
+			Provider: providerName,
+			Definition: metadata.PrimaryDataSource{
+				Location: metadata.SQLTable{Name: createSafeUUID()},
+			},
+		},
+		metadata.FeatureDef{
+			Name:     featureName,
+			Source:   metadata.NameVariant{Name: sourceName, Variant: ""},
+			Type:     string(provider.Int),
+			Entity:   entityName,
+			Owner:    userName,
+			Provider: providerName,
+			Location: metadata.ResourceVariantColumns{Entity: "entity", Value: "value", TS: "ts"},
+		},
 	}
-}
-
-func TestMemoryJobRunnerError(t *testing.T) {
-	memJobSpawner := MemoryJobSpawner{}
-	if _, err := memJobSpawner.GetJobRunner("ghost_job", []byte{}, metadata.ResourceID{}); err == nil {
-		t.Fatalf("did not trigger error getting nonexistent runner")
+	if err := client.CreateAll(context.Background(), defs); err != nil {
+		return metadata.ResourceID{}, fmt.Errorf("could not create metadata entries: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := client.SetStatus(context.Background(), sourceID, metadata.READY, ""); err != nil {
+		return metadata.ResourceID{}, fmt.Errorf("could not set source variant to ready: %v", err)
 	}
+	return metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}, nil
 }
 
-func TestRunSQLJobError(t *testing.T) {
-	if testing.Short() {
-		return
+func testCoordinatorBatch(addr string) error {
+	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
 	}
-	serv, addr := startServ(t)
-	defer serv.Stop()
+	defer runner.UnregisterFactory(string(runner.COPY_TO_ONLINE))
+	if err := runner.RegisterFactory(string(runner.MATERIALIZE), runner.MaterializeRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.MATERIALIZE))
 	coord, err := createNewCoordinator(addr)
 	if err != nil {
-		t.Fatalf("could not create new basic coordinator")
+		return fmt.Errorf("Failed to set up coordinator")
 	}
-	defer coord.Metadata.Close()
-	sourceGhostDependency := createSafeUUID()
-	providerName := createSafeUUID()
-	userName := createSafeUUID()
-	defs := []metadata.ResourceDef{
-		metadata.UserDef{
-			Name: userName,
-		},
-		metadata.ProviderDef{
-			Name:             providerName,
-			Description:      "",
-			Type:             "POSTGRES_OFFLINE",
-			Software:         "",
-			Team:             "",
-			SerializedConfig: postgresConfig.Serialize(),
-		},
-		metadata.SourceDef{
-			Name:        sourceGhostDependency,
-			Variant:     "",
-			Description: "",
-			Owner:       userName,
-			Provider:    providerName,
-			Definition: metadata.TransformationSource{
-				TransformationType: metadata.SQLTransformationType{
-					Query:   "{{ghost_source.}}",
-					Sources: []metadata.NameVariant{{"ghost_source", ""}},
-				},
-			},
-			Schedule: "",
-		},
+	defer coord.Close()
+	redisConfig := &pc.RedisConfig{Addr: fmt.Sprintf("%s:%s", redisHost, redisPort)}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
 	}
-	if err := coord.Metadata.CreateAll(context.Background(), defs); err != nil {
-		t.Fatalf("could not create test metadata entries: %v", err)
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, originalTableName, ""); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
 	}
-	transformSource, err := coord.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{sourceGhostDependency, ""})
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	failingFeatureID, err := createFeatureWithInvalidProvider(coord.Metadata)
 	if err != nil {
-		t.Fatalf("could not fetch created source variant: %v", err)
+		return err
 	}
-	providerEntry, err := transformSource.FetchProvider(coord.Metadata, context.Background())
+	// The failing feature is submitted first to make sure it doesn't stop the rest of the batch
+	report := coord.ExecuteBatch([]metadata.ResourceID{failingFeatureID, featureID, sourceID})
+	if report.Err() == nil {
+		return fmt.Errorf("expected batch to report a failure")
+	}
+	if _, failed := report.Failed[failingFeatureID]; !failed || len(report.Failed) != 1 {
+		return fmt.Errorf("expected only the feature with an invalid provider to fail, got %v", report.Failed)
+	}
+	if !reflect.DeepEqual(report.Succeeded, []metadata.ResourceID{sourceID, featureID}) {
+		return fmt.Errorf("expected source then feature to succeed, got %v", report.Succeeded)
+	}
+	feature, err := coord.Metadata.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: featureName, Variant: ""})
 	if err != nil {
-		t.Fatalf("could not fetch provider entry in metadata, provider entry not set: %v", err)
+		return fmt.Errorf("could not get feature: %v", err)
 	}
-	provider, err := provider.Get(pt.PostgresOffline, postgresConfig.Serialize())
+	if feature.Status() != metadata.READY {
+		return fmt.Errorf("expected feature to be READY, got %s", feature.Status().String())
+	}
+	// A feature whose source fails in the same batch is never run, and is
+	// marked FAILED rather than being left behind
+	failingSourceID, dependentID, err := createFeatureOverFailingSource(coord.Metadata)
 	if err != nil {
-		t.Fatalf("could not get provider: %v", err)
+		return err
 	}
-	offlineProvider, err := provider.AsOfflineStore()
+	report = coord.ExecuteBatch([]metadata.ResourceID{dependentID, failingSourceID})
+	if _, failed := report.Failed[failingSourceID]; !failed {
+		return fmt.Errorf("expected source with an invalid provider to fail, got %v", report.Failed)
+	}
+	var dependencyErr DependencyFailedError
+	if !errors.As(report.Failed[dependentID], &dependencyErr) {
+		return fmt.Errorf("expected feature to fail with DependencyFailedError, got %v", report.Failed[dependentID])
+	}
+	dependent, err := coord.Metadata.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: dependentID.Name, Variant: dependentID.Variant})
 	if err != nil {
-		t.Fatalf("could not get provider as offline store: %v", err)
+		return fmt.Errorf("could not get feature: %v", err)
 	}
-	sourceResourceID := metadata.ResourceID{sourceGhostDependency, "", metadata.SOURCE_VARIANT}
-	if err := coord.runSQLTransformationJob(transformSource, sourceResourceID, offlineProvider, "", providerEntry); err == nil {
-		t.Fatalf("did not catch error trying to run primary table job with no source table set")
+	if dependent.Status() != metadata.FAILED {
+		return fmt.Errorf("expected feature of failed source to be FAILED, got %s", dependent.Status().String())
 	}
+	return nil
 }
 
-func TestFeatureMaterializeJobError(t *testing.T) {
-	if testing.Short() {
-		return
+// createFeatureOverFailingSource registers a feature over a source whose
+// provider can't be created, so the source's job always fails.
+func createFeatureOverFailingSource(client *metadata.Client) (metadata.ResourceID, metadata.ResourceID, error) {
+	featureID, err := createFeatureWithInvalidProvider(client)
+	if err != nil {
+		return metadata.ResourceID{}, metadata.ResourceID{}, err
 	}
-	serv, addr := startServ(t)
-	defer serv.Stop()
-	coord, err := createNewCoordinator(addr)
+	feature, err := client.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: featureID.Name, Variant: featureID.Variant})
 	if err != nil {
-		t.Fatalf("could not create new basic coordinator")
+		return metadata.ResourceID{}, metadata.ResourceID{}, fmt.Errorf("could not get feature: %v", err)
 	}
-	defer coord.Metadata.Close()
-	if err := coord.runFeatureMaterializeJob(metadata.ResourceID{"ghost_resource", "", metadata.FEATURE_VARIANT}, ""); err == nil {
-		t.Fatalf("did not catch error when trying to materialize nonexistent feature")
+	sourceID := metadata.ResourceID{Name: feature.Source().Name, Variant: feature.Source().Variant, Type: metadata.SOURCE_VARIANT}
+	if err := client.SetStatus(context.Background(), sourceID, metadata.CREATED, ""); err != nil {
+		return metadata.ResourceID{}, metadata.ResourceID{}, fmt.Errorf("could not reset source variant status: %v", err)
 	}
-	liveAddr := fmt.Sprintf("%s:%s", redisHost, redisPort)
-	redisConfig := &pc.RedisConfig{
-		Addr: liveAddr,
+	return sourceID, featureID, nil
+}
+
+func testMaterializeFeatureOverUnreadyTransformation(addr string) error {
+	factories := map[runner.RunnerName]runner.RunnerFactory{
+		runner.CREATE_TRANSFORMATION: runner.CreateTransformationRunnerFactory,
+		runner.COPY_TO_ONLINE:        runner.MaterializedChunkRunnerFactory,
+		runner.MATERIALIZE:           runner.MaterializeRunnerFactory,
+	}
+	for name, factory := range factories {
+		if err := runner.RegisterFactory(string(name), factory); err != nil {
+			return fmt.Errorf("Failed to register %s runner factory: %v", name, err)
+		}
+		defer runner.UnregisterFactory(string(name))
 	}
-	featureName := createSafeUUID()
-	sourceName := createSafeUUID()
-	originalTableName := createSafeUUID()
-	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, originalTableName, ""); err != nil {
-		t.Fatalf("could not create example feature, %v", err)
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator")
 	}
-	if err := coord.Metadata.SetStatus(context.Background(), metadata.ResourceID{featureName, "", metadata.FEATURE_VARIANT}, metadata.READY, ""); err != nil {
-		t.Fatalf("could not set feature to ready")
+	defer coord.Close()
+	eventSink := &memoryEventSink{}
+	coord.EventSink = eventSink
+	serialPGConfig := postgresConfig.Serialize()
+	redisConfig := &pc.RedisConfig{Addr: fmt.Sprintf("%s:%s", redisHost, redisPort)}
+	tableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(tableName); err != nil {
+		return err
 	}
-	if err := coord.runFeatureMaterializeJob(metadata.ResourceID{featureName, "", metadata.FEATURE_VARIANT}, ""); err == nil {
-		t.Fatalf("did not catch error when trying to materialize feature already set to ready")
+	sourceName := strings.Replace(createSafeUUID(), "-", "", -1)
+	if err := createSourceWithProvider(coord.Metadata, serialPGConfig, sourceName, tableName); err != nil {
+		return fmt.Errorf("could not register source in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	transformationName := strings.Replace(createSafeUUID(), "-", "", -1)
+	transformationQuery := fmt.Sprintf("SELECT * FROM {{%s.}}", sourceName)
+	if err := createTransformationWithProvider(coord.Metadata, serialPGConfig, transformationName, transformationQuery, []metadata.NameVariant{{Name: sourceName, Variant: ""}}, ""); err != nil {
+		return err
 	}
-	providerName := createSafeUUID()
 	userName := createSafeUUID()
-	sourceName = createSafeUUID()
+	onlineProviderName := createSafeUUID()
 	entityName := createSafeUUID()
-	originalTableName = createSafeUUID()
-	featureName = createSafeUUID()
+	featureName := createSafeUUID()
 	defs := []metadata.ResourceDef{
 		metadata.UserDef{
 			Name: userName,
 		},
 		metadata.ProviderDef{
-			Name:             providerName,
-			Description:      "",
-			Type:             "INVALID_PROVIDER",
-			Software:         "",
-			Team:             "",
-			SerializedConfig: []byte{},
+			Name:             onlineProviderName,
+			Type:             "REDIS_ONLINE",
+			SerializedConfig: redisConfig.Serialized(),
 		},
 		metadata.EntityDef{
-			Name:        entityName,
-			Description: "",
-		},
-		metadata.SourceDef{
-			Name:        sourceName,
-			Variant:     "",
-			Description: "",
-			Owner:       userName,
-			Provider:    providerName,
-			Definition: metadata.PrimaryDataSource{
-				Location: metadata.SQLTable{
-					Name: originalTableName,
-				},
-			},
-			Schedule: "",
+			Name: entityName,
 		},
 		metadata.FeatureDef{
-			Name:        featureName,
-			Variant:     "",
-			Source:      metadata.NameVariant{sourceName, ""},
-			Type:        string(provider.Int),
-			Entity:      entityName,
-			Owner:       userName,
-			Description: "",
-			Provider:    providerName,
+			Name:     featureName,
+			Variant:  "",
+			Source:   metadata.NameVariant{Name: transformationName, Variant: ""},
+			Type:     string(provider.Int),
+			Entity:   entityName,
+			Owner:    userName,
+			Provider: onlineProviderName,
 			Location: metadata.ResourceVariantColumns{
 				Entity: "entity",
 				Value:  "value",
 				TS:     "ts",
 			},
-			Schedule: "",
 		},
 	}
 	if err := coord.Metadata.CreateAll(context.Background(), defs); err != nil {
-		t.Fatalf("could not create metadata entries: %v", err)
+		return fmt.Errorf("could not create feature in metadata: %v", err)
 	}
-	if err := coord.Metadata.SetStatus(context.Background(), metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}, metadata.READY, ""); err != nil {
-		t.Fatalf("could not set source variant to ready")
+	transformationID := metadata.ResourceID{Name: transformationName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	// Nothing is built once the job's context is done
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := coord.buildUnreadyTransformations(cancelled, featureID); err == nil {
+		return fmt.Errorf("expected building transformations with a cancelled context to fail")
 	}
-	if err := coord.runFeatureMaterializeJob(metadata.ResourceID{featureName, "", metadata.FEATURE_VARIANT}, ""); err == nil {
-		t.Fatalf("did not trigger error trying to run job with nonexistent provider")
+	if has, err := coord.hasJob(transformationID); err != nil || !has {
+		return fmt.Errorf("expected transformation job to be left unrun: %v", err)
 	}
-	providerName = createSafeUUID()
-	userName = createSafeUUID()
-	sourceName = createSafeUUID()
-	entityName = createSafeUUID()
-	originalTableName = createSafeUUID()
-	featureName = createSafeUUID()
-	defs = []metadata.ResourceDef{
-		metadata.UserDef{
-			Name: userName,
-		},
-		metadata.ProviderDef{
-			Name:             providerName,
-			Description:      "",
-			Type:             "REDIS_ONLINE",
-			Software:         "",
-			Team:             "",
-			SerializedConfig: redisConfig.Serialized(),
-		},
-		metadata.EntityDef{
-			Name:        entityName,
-			Description: "",
-		},
-		metadata.SourceDef{
-			Name:        sourceName,
-			Variant:     "",
-			Description: "",
-			Owner:       userName,
-			Provider:    providerName,
-			Definition: metadata.PrimaryDataSource{
-				Location: metadata.SQLTable{
-					Name: originalTableName,
-				},
-			},
-			Schedule: "",
-		},
-		metadata.FeatureDef{
-			Name:        featureName,
-			Variant:     "",
-			Source:      metadata.NameVariant{sourceName, ""},
-			Type:        string(provider.Int),
-			Entity:      entityName,
-			Owner:       userName,
-			Description: "",
-			Provider:    providerName,
-			Location: metadata.ResourceVariantColumns{
-				Entity: "entity",
-				Value:  "value",
-				TS:     "ts",
-			},
-			Schedule: "",
-		},
-	}
-	if err := coord.Metadata.CreateAll(context.Background(), defs); err != nil {
-		t.Fatalf("could not create metadata entries: %v", err)
+	// Only the feature's job is run; the transformation's job has to be run by it
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+		return err
 	}
-	if err := coord.Metadata.SetStatus(context.Background(), metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}, metadata.READY, ""); err != nil {
-		t.Fatalf("could not set source variant to ready")
+	transformation, err := coord.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{Name: transformationName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get transformation: %v", err)
 	}
-	if err := coord.runFeatureMaterializeJob(metadata.ResourceID{featureName, "", metadata.FEATURE_VARIANT}, ""); err == nil {
-		t.Fatalf("did not trigger error trying to use online store as offline store")
+	if transformation.Status() != metadata.READY {
+		return fmt.Errorf("expected transformation to be READY, got %s", transformation.Status().String())
 	}
-	providerName = createSafeUUID()
-	offlineProviderName := createSafeUUID()
-	userName = createSafeUUID()
-	sourceName = createSafeUUID()
-	entityName = createSafeUUID()
-	originalTableName = createSafeUUID()
-	featureName = createSafeUUID()
-	defs = []metadata.ResourceDef{
-		metadata.UserDef{
-			Name: userName,
-		},
-		metadata.ProviderDef{
-			Name:             offlineProviderName,
-			Description:      "",
-			Type:             "POSTGRES_OFFLINE",
-			Software:         "",
-			Team:             "",
-			SerializedConfig: postgresConfig.Serialize(),
-		},
-		metadata.ProviderDef{
-			Name:             providerName,
-			Description:      "",
-			Type:             "INVALID_PROVIDER",
-			Software:         "",
-			Team:             "",
-			SerializedConfig: []byte{},
-		},
-		metadata.EntityDef{
-			Name:        entityName,
-			Description: "",
-		},
-		metadata.SourceDef{
-			Name:        sourceName,
-			Variant:     "",
-			Description: "",
-			Owner:       userName,
-			Provider:    offlineProviderName,
-			Definition: metadata.PrimaryDataSource{
-				Location: metadata.SQLTable{
-					Name: originalTableName,
-				},
-			},
-			Schedule: "",
-		},
-		metadata.FeatureDef{
-			Name:        featureName,
-			Variant:     "",
-			Source:      metadata.NameVariant{sourceName, ""},
-			Type:        string(provider.Int),
-			Entity:      entityName,
-			Owner:       userName,
-			Description: "",
-			Provider:    providerName,
-			Location: metadata.ResourceVariantColumns{
-				Entity: "entity",
-				Value:  "value",
-				TS:     "ts",
-			},
-			Schedule: "",
-		},
+	feature, err := coord.Metadata.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: featureName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get feature: %v", err)
 	}
-	if err := coord.Metadata.CreateAll(context.Background(), defs); err != nil {
-		t.Fatalf("could not create metadata entries: %v", err)
+	if feature.Status() != metadata.READY {
+		return fmt.Errorf("expected feature to be READY, got %s", feature.Status().String())
 	}
-	if err := coord.Metadata.SetStatus(context.Background(), metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}, metadata.READY, ""); err != nil {
-		t.Fatalf("could not set source variant to ready")
+	transformationReady := eventSink.eventIndex(transformationID, metadata.READY)
+	featureReady := eventSink.eventIndex(featureID, metadata.READY)
+	if transformationReady == -1 || featureReady == -1 || transformationReady > featureReady {
+		return fmt.Errorf("expected transformation to be built before the feature, got events %v", eventSink.events)
 	}
-	if err := coord.runFeatureMaterializeJob(metadata.ResourceID{featureName, "", metadata.FEATURE_VARIANT}, ""); err == nil {
-		t.Fatalf("did not trigger error trying to get invalid feature provider")
+	if has, err := coord.hasJob(transformationID); err != nil || has {
+		return fmt.Errorf("expected transformation job to be finished: %v", err)
 	}
+	return nil
 }
 
-func TestTrainingSetJobError(t *testing.T) {
-	if testing.Short() {
-		return
+func testMaterializeFeatureFromTransformation(addr string) error {
+	factories := map[runner.RunnerName]runner.RunnerFactory{
+		runner.CREATE_TRANSFORMATION: runner.CreateTransformationRunnerFactory,
+		runner.COPY_TO_ONLINE:        runner.MaterializedChunkRunnerFactory,
+		runner.MATERIALIZE:           runner.MaterializeRunnerFactory,
+	}
+	for name, factory := range factories {
+		if err := runner.RegisterFactory(string(name), factory); err != nil {
+			return fmt.Errorf("Failed to register %s runner factory: %v", name, err)
+		}
+		defer runner.UnregisterFactory(string(name))
 	}
-	serv, addr := startServ(t)
-	defer serv.Stop()
 	coord, err := createNewCoordinator(addr)
 	if err != nil {
-		t.Fatalf("could not create new basic coordinator")
+		return fmt.Errorf("Failed to set up coordinator")
 	}
-	defer coord.Metadata.Close()
-	if err := coord.runTrainingSetJob(metadata.ResourceID{"ghost_training_set", "", metadata.TRAINING_SET_VARIANT}, ""); err == nil {
-		t.Fatalf("did not trigger error trying to run job for nonexistent training set")
+	defer coord.Close()
+	serialPGConfig := postgresConfig.Serialize()
+	redisConfig := &pc.RedisConfig{Addr: fmt.Sprintf("%s:%s", redisHost, redisPort)}
+	tableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(tableName); err != nil {
+		return err
+	}
+	sourceName := strings.Replace(createSafeUUID(), "-", "", -1)
+	if err := createSourceWithProvider(coord.Metadata, serialPGConfig, sourceName, tableName); err != nil {
+		return fmt.Errorf("could not register source in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	// The transformation changes every value, so the feature can only have the
+	// expected values if it was read from the transformation's output
+	transformationName := strings.Replace(createSafeUUID(), "-", "", -1)
+	transformationQuery := fmt.Sprintf("SELECT entity, value * 10 AS value, ts FROM {{%s.}}", sourceName)
+	if err := createTransformationWithProvider(coord.Metadata, serialPGConfig, transformationName, transformationQuery, []metadata.NameVariant{{Name: sourceName, Variant: ""}}, ""); err != nil {
+		return err
+	}
+	transformationID := metadata.ResourceID{Name: transformationName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(transformationID)); err != nil {
+		return fmt.Errorf("could not build transformation: %v", err)
 	}
-	providerName := createSafeUUID()
 	userName := createSafeUUID()
-	sourceName := createSafeUUID()
+	onlineProviderName := createSafeUUID()
 	entityName := createSafeUUID()
-	labelName := createSafeUUID()
-	originalTableName := createSafeUUID()
 	featureName := createSafeUUID()
-	tsName := createSafeUUID()
 	defs := []metadata.ResourceDef{
 		metadata.UserDef{
 			Name: userName,
 		},
 		metadata.ProviderDef{
-			Name:             providerName,
-			Description:      "",
-			Type:             "INVALID_PROVIDER",
-			Software:         "",
-			Team:             "",
-			SerializedConfig: []byte{},
+			Name:             onlineProviderName,
+			Type:             "REDIS_ONLINE",
+			SerializedConfig: redisConfig.Serialized(),
 		},
 		metadata.EntityDef{
-			Name:        entityName,
-			Description: "",
-		},
-		metadata.SourceDef{
-			Name:        sourceName,
-			Variant:     "",
-			Description: "",
-			Owner:       userName,
-			Provider:    providerName,
-			Definition: metadata.PrimaryDataSource{
-				Location: metadata.SQLTable{
-					Name: originalTableName,
-				},
-			},
-			Schedule: "",
-		},
-		metadata.LabelDef{
-			Name:        labelName,
-			Variant:     "",
-			Description: "",
-			Type:        string(provider.Int),
-			Source:      metadata.NameVariant{sourceName, ""},
-			Entity:      entityName,
-			Owner:       userName,
-			Provider:    providerName,
-			Location: metadata.ResourceVariantColumns{
-				Entity: "entity",
-				Value:  "value",
-				TS:     "ts",
-			},
+			Name: entityName,
 		},
 		metadata.FeatureDef{
-			Name:        featureName,
-			Variant:     "",
-			Source:      metadata.NameVariant{sourceName, ""},
-			Type:        string(provider.Int),
-			Entity:      entityName,
-			Owner:       userName,
-			Description: "",
-			Provider:    providerName,
+			Name:     featureName,
+			Variant:  "",
+			Source:   metadata.NameVariant{Name: transformationName, Variant: ""},
+			Type:     string(provider.Int),
+			Entity:   entityName,
+			Owner:    userName,
+			Provider: onlineProviderName,
 			Location: metadata.ResourceVariantColumns{
 				Entity: "entity",
 				Value:  "value",
 				TS:     "ts",
 			},
-			Schedule: "",
-		},
-		metadata.TrainingSetDef{
-			Name:        tsName,
-			Variant:     "",
-			Description: "",
-			Owner:       userName,
-			Provider:    providerName,
-			Label:       metadata.NameVariant{labelName, ""},
-			Features:    []metadata.NameVariant{{featureName, ""}},
-			Schedule:    "",
 		},
 	}
 	if err := coord.Metadata.CreateAll(context.Background(), defs); err != nil {
-		t.Fatalf("could not create metadata entries: %v", err)
+		return fmt.Errorf("could not create feature in metadata: %v", err)
 	}
-	if err := coord.runTrainingSetJob(metadata.ResourceID{tsName, "", metadata.TRAINING_SET_VARIANT}, ""); err == nil {
-		t.Fatalf("did not trigger error trying to run job with nonexistent provider")
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+		return fmt.Errorf("could not materialize feature over transformation: %v", err)
 	}
-	providerName = createSafeUUID()
-	userName = createSafeUUID()
-	sourceName = createSafeUUID()
-	entityName = createSafeUUID()
-	labelName = createSafeUUID()
-	originalTableName = createSafeUUID()
-	featureName = createSafeUUID()
-	tsName = createSafeUUID()
-	liveAddr := fmt.Sprintf("%s:%s", redisHost, redisPort)
-	redisConfig := &pc.RedisConfig{
-		Addr: liveAddr,
+	p, err := provider.Get(pt.RedisOnline, redisConfig.Serialized())
+	if err != nil {
+		return fmt.Errorf("could not get online provider: %v", err)
 	}
-	defs = []metadata.ResourceDef{
-		metadata.UserDef{
-			Name: userName,
-		},
-		metadata.ProviderDef{
-			Name:             providerName,
-			Description:      "",
-			Type:             "REDIS_ONLINE",
-			Software:         "",
-			Team:             "",
-			SerializedConfig: redisConfig.Serialized(),
-		},
-		metadata.EntityDef{
-			Name:        entityName,
-			Description: "",
-		},
-		metadata.SourceDef{
-			Name:        sourceName,
-			Variant:     "",
-			Description: "",
-			Owner:       userName,
-			Provider:    providerName,
-			Definition: metadata.PrimaryDataSource{
-				Location: metadata.SQLTable{
-					Name: originalTableName,
-				},
-			},
-			Schedule: "",
-		},
-		metadata.LabelDef{
-			Name:        labelName,
-			Variant:     "",
-			Description: "",
-			Type:        string(provider.Int),
-			Source:      metadata.NameVariant{sourceName, ""},
-			Entity:      entityName,
-			Owner:       userName,
-			Provider:    providerName,
-			Location: metadata.ResourceVariantColumns{
-				Entity: "entity",
-				Value:  "value",
-				TS:     "ts",
-			},
-		},
-		metadata.FeatureDef{
-			Name:        featureName,
-			Variant:     "",
-			Source:      metadata.NameVariant{sourceName, ""},
-			Type:        string(provider.Int),
-			Entity:      entityName,
-			Owner:       userName,
-			Description: "",
-			Provider:    providerName,
-			Location: metadata.ResourceVariantColumns{
-				Entity: "entity",
-				Value:  "value",
-				TS:     "ts",
-			},
-			Schedule: "",
-		},
-		metadata.TrainingSetDef{
-			Name:        tsName,
-			Variant:     "",
-			Description: "",
-			Owner:       userName,
-			Provider:    providerName,
-			Label:       metadata.NameVariant{labelName, ""},
-			Features:    []metadata.NameVariant{{featureName, ""}},
-			Schedule:    "",
-		},
+	onlineStore, err := p.AsOnlineStore()
+	if err != nil {
+		return fmt.Errorf("could not get provider as online store")
 	}
-	if err := coord.Metadata.CreateAll(context.Background(), defs); err != nil {
-		t.Fatalf("could not create metadata entries: %v", err)
+	resourceTable, err := onlineStore.GetTable(featureName, "")
+	if err != nil {
+		return err
 	}
-	if err := coord.runTrainingSetJob(metadata.ResourceID{tsName, "", metadata.TRAINING_SET_VARIANT}, ""); err == nil {
-		t.Fatalf("did not trigger error trying to convert online provider to offline")
+	for _, record := range testOfflineTableValues {
+		value, err := resourceTable.Get(record.Entity)
+		if err != nil {
+			return fmt.Errorf("could not get entity %s: %v", record.Entity, err)
+		}
+		if expected := record.Value.(int) * 10; !reflect.DeepEqual(value, expected) {
+			return fmt.Errorf("expected transformed value %d for entity %s, got %v", expected, record.Entity, value)
+		}
 	}
+	return nil
 }
 
-func TestRunPrimaryTableJobError(t *testing.T) {
+func testExecuteJobsCancelled(addr string) error {
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator")
+	}
+	defer coord.Close()
+	sourceName := createSafeUUID()
+	featureName := createSafeUUID()
+	labelName := createSafeUUID()
+	tsName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	if err := createTrainingSetWithProvider(coord.Metadata, postgresConfig.Serialize(), sourceName, featureName, labelName, tsName, originalTableName, ""); err != nil {
+		return fmt.Errorf("could not create training set %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := coord.ExecuteJobs(ctx, []metadata.ResourceID{featureID, sourceID})
+	for _, resID := range []metadata.ResourceID{sourceID, featureID} {
+		if err := results[resID]; !errors.Is(err, context.Canceled) {
+			return fmt.Errorf("expected %s %s to fail with the batch's context, got %v", resID.Type, resID.Name, err)
+		}
+	}
+	// Neither job started, so they're marked FAILED rather than left CREATED
+	source, err := coord.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{Name: sourceName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get source: %v", err)
+	}
+	if source.Status() != metadata.FAILED {
+		return fmt.Errorf("expected unstarted source to be FAILED, got %s", source.Status())
+	}
+	feature, err := coord.Metadata.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: featureName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get feature: %v", err)
+	}
+	if feature.Status() != metadata.FAILED {
+		return fmt.Errorf("expected unstarted feature to be FAILED, got %s", feature.Status())
+	}
+	return nil
+}
+
+func testCoordinatorExecuteJobs(addr string) error {
+	if err := runner.RegisterFactory(string(runner.CREATE_TRAINING_SET), runner.TrainingSetRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.CREATE_TRAINING_SET))
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator")
+	}
+	defer coord.Close()
+	coord.MaxConcurrentJobs = 2
+	sourceName := createSafeUUID()
+	featureName := createSafeUUID()
+	labelName := createSafeUUID()
+	tsName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	if err := createTrainingSetWithProvider(coord.Metadata, postgresConfig.Serialize(), sourceName, featureName, labelName, tsName, originalTableName, ""); err != nil {
+		return fmt.Errorf("could not create training set %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	labelID := metadata.ResourceID{Name: labelName, Variant: "", Type: metadata.LABEL_VARIANT}
+	tsID := metadata.ResourceID{Name: tsName, Variant: "", Type: metadata.TRAINING_SET_VARIANT}
+	// Every resource is submitted before the resources it depends on
+	results := coord.ExecuteJobs(context.Background(), []metadata.ResourceID{tsID, labelID, featureID, sourceID})
+	if len(results) != 4 {
+		return fmt.Errorf("expected a result for each of the 4 resources, got %v", results)
+	}
+	for resID, err := range results {
+		if err != nil {
+			return fmt.Errorf("%s %s failed: %v", resID.Type, resID.Name, err)
+		}
+	}
+	ctx := context.Background()
+	nameVariant := func(name string) metadata.NameVariant {
+		return metadata.NameVariant{Name: name, Variant: ""}
+	}
+	source, err := coord.Metadata.GetSourceVariant(ctx, nameVariant(sourceName))
+	if err != nil {
+		return fmt.Errorf("could not get source: %v", err)
+	}
+	feature, err := coord.Metadata.GetFeatureVariant(ctx, nameVariant(featureName))
+	if err != nil {
+		return fmt.Errorf("could not get feature: %v", err)
+	}
+	label, err := coord.Metadata.GetLabelVariant(ctx, nameVariant(labelName))
+	if err != nil {
+		return fmt.Errorf("could not get label: %v", err)
+	}
+	ts, err := coord.Metadata.GetTrainingSetVariant(ctx, nameVariant(tsName))
+	if err != nil {
+		return fmt.Errorf("could not get training set: %v", err)
+	}
+	statuses := map[metadata.ResourceID]metadata.ResourceStatus{
+		sourceID:  source.Status(),
+		featureID: feature.Status(),
+		labelID:   label.Status(),
+		tsID:      ts.Status(),
+	}
+	for resID, status := range statuses {
+		if status != metadata.READY {
+			return fmt.Errorf("expected %s %s to be READY, got %s", resID.Type, resID.Name, status.String())
+		}
+	}
+	return nil
+}
+
+func testCoordinatorHistory(addr string) error {
+	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.COPY_TO_ONLINE))
+	if err := runner.RegisterFactory(string(runner.MATERIALIZE), runner.MaterializeRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.MATERIALIZE))
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator")
+	}
+	defer coord.Close()
+	redisConfig := &pc.RedisConfig{Addr: fmt.Sprintf("%s:%s", redisHost, redisPort)}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, originalTableName, ""); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+		return err
+	}
+	failingFeatureID, err := createFeatureWithInvalidProvider(coord.Metadata)
+	if err != nil {
+		return err
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(failingFeatureID)); err == nil {
+		return fmt.Errorf("expected job with invalid provider to fail")
+	}
+	expected := map[metadata.ResourceID]metadata.ResourceStatus{
+		sourceID:         metadata.READY,
+		featureID:        metadata.READY,
+		failingFeatureID: metadata.FAILED,
+	}
+	history := coord.History(0)
+	if len(history) != len(expected) {
+		return fmt.Errorf("expected %d records in history, got %d: %v", len(expected), len(history), history)
+	}
+	for _, record := range history {
+		status, has := expected[record.Resource]
+		if !has {
+			return fmt.Errorf("unexpected resource in history: %v", record.Resource)
+		}
+		if record.Status != status {
+			return fmt.Errorf("expected %v to be %s, got %s", record.Resource, status.String(), record.Status.String())
+		}
+		if record.Attempts != 1 {
+			return fmt.Errorf("expected %v to have run once, got %d attempts", record.Resource, record.Attempts)
+		}
+	}
+	if latest := coord.History(1); len(latest) != 1 || latest[0].Resource != failingFeatureID {
+		return fmt.Errorf("expected most recent job to be the failed feature, got %v", latest)
+	}
+	return nil
+}
+
+func testCoordinatorClose(addr string) error {
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator")
+	}
+	if err := coord.Close(); err != nil {
+		return fmt.Errorf("could not close coordinator: %v", err)
+	}
+	if err := coord.Close(); err != nil {
+		return fmt.Errorf("second close was not a no-op: %v", err)
+	}
+	err = coord.ExecuteJob(metadata.GetJobKey(metadata.ResourceID{Name: "closed", Variant: "", Type: metadata.FEATURE_VARIANT}))
+	if _, ok := err.(CoordinatorClosedError); !ok {
+		return fmt.Errorf("expected coordinator closed error, got: %v", err)
+	}
+	if err := coord.WatchForUpdateEvents(); err != nil {
+		return fmt.Errorf("watch loop did not stop after close: %v", err)
+	}
+	return nil
+}
+
+// may cause an error depending on kubernetes implementation
+func TestKubernetesJobRunnerError(t *testing.T) {
+	kubeJobSpawner := KubernetesJobSpawner{}
+	if _, err := kubeJobSpawner.GetJobRunner("ghost_job", []byte{}, metadata.ResourceID{}, metadata.KubernetesArgs{}); err == nil {
+		t.Fatalf("did not trigger error getting nonexistent runner")
+	}
+}
+
+func TestKubernetesJobSpawnerImage(t *testing.T) {
+	kubeJobSpawner := KubernetesJobSpawner{}
+	resID := metadata.ResourceID{Name: "feature", Variant: "v1", Type: metadata.FEATURE_VARIANT}
+	tests := []struct {
+		name     string
+		args     metadata.KubernetesArgs
+		expected string
+	}{
+		{"Default Image", metadata.KubernetesArgs{}, cfg.GetPandasRunnerImage()},
+		{"Custom Image", metadata.KubernetesArgs{DockerImage: "my_image:latest"}, "my_image:latest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeConfig, err := kubeJobSpawner.runnerConfig(string(runner.MATERIALIZE), []byte{}, resID, tt.args)
+			if err != nil {
+				t.Fatalf("could not get runner config: %v", err)
+			}
+			if image := kubeConfig.EnvVars["PANDAS_RUNNER_IMAGE"]; image != tt.expected {
+				t.Fatalf("expected pandas runner image %s, got %s", tt.expected, image)
+			}
+			if kubeConfig.Image != cfg.GetWorkerImage() {
+				t.Fatalf("expected worker image %s, got %s", cfg.GetWorkerImage(), kubeConfig.Image)
+			}
+		})
+	}
+}
+
+func TestKubernetesJobSpawnerPodSpec(t *testing.T) {
+	toleration := corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "featureform", Effect: corev1.TaintEffectNoSchedule}
+	kubeJobSpawner := KubernetesJobSpawner{
+		Specs:        metadata.KubernetesResourceSpecs{CPURequest: "500m", MemoryRequest: "1Gi", MemoryLimit: "2Gi"},
+		NodeSelector: map[string]string{"pool": "jobs"},
+		Tolerations:  []corev1.Toleration{toleration},
+	}
+	resID := metadata.ResourceID{Name: "feature", Variant: "v1", Type: metadata.FEATURE_VARIANT}
+	args := metadata.KubernetesArgs{Specs: metadata.KubernetesResourceSpecs{CPULimit: "2", MemoryLimit: "8Gi"}}
+	kubeConfig, err := kubeJobSpawner.runnerConfig(string(runner.MATERIALIZE), []byte{}, resID, args)
+	if err != nil {
+		t.Fatalf("could not get runner config: %v", err)
+	}
+	jobSpec, err := kubernetes.NewJobSpec(kubeConfig)
+	if err != nil {
+		t.Fatalf("could not create job spec: %v", err)
+	}
+	podSpec := jobSpec.Template.Spec
+	resources := podSpec.Containers[0].Resources
+	expected := map[string]struct {
+		actual   resource.Quantity
+		expected string
+	}{
+		"cpu request":    {resources.Requests[corev1.ResourceCPU], "500m"},
+		"cpu limit":      {resources.Limits[corev1.ResourceCPU], "2"},
+		"memory request": {resources.Requests[corev1.ResourceMemory], "1Gi"},
+		"memory limit":   {resources.Limits[corev1.ResourceMemory], "8Gi"},
+	}
+	for name, quantity := range expected {
+		if !quantity.actual.Equal(resource.MustParse(quantity.expected)) {
+			t.Fatalf("expected %s %s, got %s", name, quantity.expected, quantity.actual.String())
+		}
+	}
+	if !reflect.DeepEqual(podSpec.NodeSelector, kubeJobSpawner.NodeSelector) {
+		t.Fatalf("expected node selector %v, got %v", kubeJobSpawner.NodeSelector, podSpec.NodeSelector)
+	}
+	if !reflect.DeepEqual(podSpec.Tolerations, []corev1.Toleration{toleration}) {
+		t.Fatalf("expected tolerations %v, got %v", []corev1.Toleration{toleration}, podSpec.Tolerations)
+	}
+
+	defaultConfig, err := (&KubernetesJobSpawner{}).runnerConfig(string(runner.MATERIALIZE), []byte{}, resID, metadata.KubernetesArgs{})
+	if err != nil {
+		t.Fatalf("could not get default runner config: %v", err)
+	}
+	defaultSpec, err := kubernetes.NewJobSpec(defaultConfig)
+	if err != nil {
+		t.Fatalf("could not create default job spec: %v", err)
+	}
+	defaultPod := defaultSpec.Template.Spec
+	if len(defaultPod.Containers[0].Resources.Requests) != 0 || len(defaultPod.Containers[0].Resources.Limits) != 0 {
+		t.Fatalf("expected no requests or limits by default, got %v", defaultPod.Containers[0].Resources)
+	}
+	if defaultPod.NodeSelector != nil || defaultPod.Tolerations != nil {
+		t.Fatalf("expected no node selector or tolerations by default, got %v %v", defaultPod.NodeSelector, defaultPod.Tolerations)
+	}
+
+	args.Specs.CPURequest = "lots"
+	invalidConfig, err := kubeJobSpawner.runnerConfig(string(runner.MATERIALIZE), []byte{}, resID, args)
+	if err != nil {
+		t.Fatalf("could not get runner config: %v", err)
+	}
+	if _, err := kubernetes.NewJobSpec(invalidConfig); err == nil {
+		t.Fatalf("expected error for invalid cpu request")
+	}
+}
+
+func TestMemoryJobRunnerError(t *testing.T) {
+	memJobSpawner := MemoryJobSpawner{}
+	if _, err := memJobSpawner.GetJobRunner("ghost_job", []byte{}, metadata.ResourceID{}, metadata.KubernetesArgs{}); err == nil {
+		t.Fatalf("did not trigger error getting nonexistent runner")
+	}
+}
+
+func TestRunSQLJobError(t *testing.T) {
 	if testing.Short() {
 		return
 	}
@@ -633,7 +995,7 @@ func TestRunPrimaryTableJobError(t *testing.T) {
 		t.Fatalf("could not create new basic coordinator")
 	}
 	defer coord.Metadata.Close()
-	sourceNoPrimaryNameSet := createSafeUUID()
+	sourceGhostDependency := createSafeUUID()
 	providerName := createSafeUUID()
 	userName := createSafeUUID()
 	defs := []metadata.ResourceDef{
@@ -649,26 +1011,31 @@ func TestRunPrimaryTableJobError(t *testing.T) {
 			SerializedConfig: postgresConfig.Serialize(),
 		},
 		metadata.SourceDef{
-			Name:        sourceNoPrimaryNameSet,
+			Name:        sourceGhostDependency,
 			Variant:     "",
 			Description: "",
 			Owner:       userName,
 			Provider:    providerName,
-			Definition: metadata.PrimaryDataSource{
-				Location: metadata.SQLTable{
-					Name: "",
+			Definition: metadata.TransformationSource{
+				TransformationType: metadata.SQLTransformationType{
+					Query:   "{{ghost_source.}}",
+					Sources: []metadata.NameVariant{{"ghost_source", ""}},
 				},
 			},
 			Schedule: "",
 		},
 	}
 	if err := coord.Metadata.CreateAll(context.Background(), defs); err != nil {
-		t.Fatalf("could not create test metadata entries")
+		t.Fatalf("could not create test metadata entries: %v", err)
 	}
-	transformSource, err := coord.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{sourceNoPrimaryNameSet, ""})
+	transformSource, err := coord.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{sourceGhostDependency, ""})
 	if err != nil {
 		t.Fatalf("could not fetch created source variant: %v", err)
 	}
+	providerEntry, err := transformSource.FetchProvider(coord.Metadata, context.Background())
+	if err != nil {
+		t.Fatalf("could not fetch provider entry in metadata, provider entry not set: %v", err)
+	}
 	provider, err := provider.Get(pt.PostgresOffline, postgresConfig.Serialize())
 	if err != nil {
 		t.Fatalf("could not get provider: %v", err)
@@ -677,53 +1044,17 @@ func TestRunPrimaryTableJobError(t *testing.T) {
 	if err != nil {
 		t.Fatalf("could not get provider as offline store: %v", err)
 	}
-	sourceResourceID := metadata.ResourceID{sourceNoPrimaryNameSet, "", metadata.SOURCE_VARIANT}
-	if err := coord.runPrimaryTableJob(transformSource, sourceResourceID, offlineProvider, ""); err == nil {
+	sourceResourceID := metadata.ResourceID{sourceGhostDependency, "", metadata.SOURCE_VARIANT}
+	err = coord.runSQLTransformationJob(context.Background(), transformSource, sourceResourceID, offlineProvider, "", providerEntry)
+	if err == nil {
 		t.Fatalf("did not catch error trying to run primary table job with no source table set")
 	}
-	sourceNoActualPrimaryTable := createSafeUUID()
-	newProviderName := createSafeUUID()
-	newUserName := createSafeUUID()
-	newDefs := []metadata.ResourceDef{
-		metadata.UserDef{
-			Name: newUserName,
-		},
-		metadata.ProviderDef{
-			Name:             newProviderName,
-			Description:      "",
-			Type:             "POSTGRES_OFFLINE",
-			Software:         "",
-			Team:             "",
-			SerializedConfig: postgresConfig.Serialize(),
-		},
-		metadata.SourceDef{
-			Name:        sourceNoActualPrimaryTable,
-			Variant:     "",
-			Description: "",
-			Owner:       newUserName,
-			Provider:    newProviderName,
-			Definition: metadata.PrimaryDataSource{
-				Location: metadata.SQLTable{
-					Name: "ghost_primary_table",
-				},
-			},
-			Schedule: "",
-		},
-	}
-	if err := coord.Metadata.CreateAll(context.Background(), newDefs); err != nil {
-		t.Fatalf("could not create test metadata entries: %v", err)
-	}
-	newTransformSource, err := coord.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{sourceNoActualPrimaryTable, ""})
-	if err != nil {
-		t.Fatalf("could not fetch created source variant: %v", err)
-	}
-	newSourceResourceID := metadata.ResourceID{sourceNoActualPrimaryTable, "", metadata.SOURCE_VARIANT}
-	if err := coord.runPrimaryTableJob(newTransformSource, newSourceResourceID, offlineProvider, ""); err == nil {
-		t.Fatalf("did not catch error trying to create primary table when no source table exists in database")
+	if message := failureStatusMessage(err); !strings.Contains(message, "{{ghost_source.}}") {
+		t.Fatalf("expected failed status message to contain the query, got %s", message)
 	}
 }
 
-func TestMapNameVariantsToTablesError(t *testing.T) {
+func TestFeatureMaterializeJobError(t *testing.T) {
 	if testing.Short() {
 		return
 	}
@@ -734,15 +1065,31 @@ func TestMapNameVariantsToTablesError(t *testing.T) {
 		t.Fatalf("could not create new basic coordinator")
 	}
 	defer coord.Metadata.Close()
-	ghostResourceName := createSafeUUID()
-	ghostNameVariants := []metadata.NameVariant{{ghostResourceName, ""}}
-	if _, err := coord.mapNameVariantsToTables(ghostNameVariants); err == nil {
-		t.Fatalf("did not catch error creating map from nonexistent resource")
+	if err := coord.runFeatureMaterializeJob(context.Background(), metadata.ResourceID{"ghost_resource", "", metadata.FEATURE_VARIANT}, ""); err == nil {
+		t.Fatalf("did not catch error when trying to materialize nonexistent feature")
+	}
+	liveAddr := fmt.Sprintf("%s:%s", redisHost, redisPort)
+	redisConfig := &pc.RedisConfig{
+		Addr: liveAddr,
+	}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, originalTableName, ""); err != nil {
+		t.Fatalf("could not create example feature, %v", err)
+	}
+	if err := coord.Metadata.SetStatus(context.Background(), metadata.ResourceID{featureName, "", metadata.FEATURE_VARIANT}, metadata.READY, ""); err != nil {
+		t.Fatalf("could not set feature to ready")
+	}
+	if err := coord.runFeatureMaterializeJob(context.Background(), metadata.ResourceID{featureName, "", metadata.FEATURE_VARIANT}, ""); err == nil {
+		t.Fatalf("did not catch error when trying to materialize feature already set to ready")
 	}
-	sourceNotReady := createSafeUUID()
 	providerName := createSafeUUID()
-	tableName := createSafeUUID()
 	userName := createSafeUUID()
+	sourceName = createSafeUUID()
+	entityName := createSafeUUID()
+	originalTableName = createSafeUUID()
+	featureName = createSafeUUID()
 	defs := []metadata.ResourceDef{
 		metadata.UserDef{
 			Name: userName,
@@ -750,301 +1097,224 @@ func TestMapNameVariantsToTablesError(t *testing.T) {
 		metadata.ProviderDef{
 			Name:             providerName,
 			Description:      "",
-			Type:             "POSTGRES_OFFLINE",
+			Type:             "INVALID_PROVIDER",
 			Software:         "",
 			Team:             "",
 			SerializedConfig: []byte{},
 		},
+		metadata.EntityDef{
+			Name:        entityName,
+			Description: "",
+		},
 		metadata.SourceDef{
-			Name:        sourceNotReady,
+			Name:        sourceName,
 			Variant:     "",
 			Description: "",
 			Owner:       userName,
 			Provider:    providerName,
 			Definition: metadata.PrimaryDataSource{
 				Location: metadata.SQLTable{
-					Name: tableName,
+					Name: originalTableName,
 				},
 			},
 			Schedule: "",
 		},
+		metadata.FeatureDef{
+			Name:        featureName,
+			Variant:     "",
+			Source:      metadata.NameVariant{sourceName, ""},
+			Type:        string(provider.Int),
+			Entity:      entityName,
+			Owner:       userName,
+			Description: "",
+			Provider:    providerName,
+			Location: metadata.ResourceVariantColumns{
+				Entity: "entity",
+				Value:  "value",
+				TS:     "ts",
+			},
+			Schedule: "",
+		},
 	}
 	if err := coord.Metadata.CreateAll(context.Background(), defs); err != nil {
-		t.Fatalf("could not create test metadata entries")
-	}
-	notReadyNameVariants := []metadata.NameVariant{{sourceNotReady, ""}}
-	if _, err := coord.mapNameVariantsToTables(notReadyNameVariants); err == nil {
-		t.Fatalf("did not catch error creating map from not ready resource")
-	}
-}
-
-func TestRegisterSourceJobErrors(t *testing.T) {
-	if testing.Short() {
-		return
+		t.Fatalf("could not create metadata entries: %v", err)
 	}
-	serv, addr := startServ(t)
-	defer serv.Stop()
-	coord, err := createNewCoordinator(addr)
-	if err != nil {
-		t.Fatalf("could not create new basic coordinator")
+	if err := coord.Metadata.SetStatus(context.Background(), metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}, metadata.READY, ""); err != nil {
+		t.Fatalf("could not set source variant to ready")
 	}
-	defer coord.Metadata.Close()
-	ghostResourceName := createSafeUUID()
-	ghostResourceID := metadata.ResourceID{ghostResourceName, "", metadata.SOURCE_VARIANT}
-	if err := coord.runRegisterSourceJob(ghostResourceID, ""); err == nil {
-		t.Fatalf("did not catch error registering nonexistent resource")
+	err = coord.runFeatureMaterializeJob(context.Background(), metadata.ResourceID{featureName, "", metadata.FEATURE_VARIANT}, "")
+	var unknownType *provider.UnknownProviderTypeError
+	if !errors.As(err, &unknownType) {
+		t.Fatalf("expected unknown provider type error trying to run job with nonexistent provider, got %v", err)
 	}
-	sourceWithoutProvider := createSafeUUID()
-	ghostProviderName := createSafeUUID()
-	ghostTableName := createSafeUUID()
-	userName := createSafeUUID()
-	providerErrorDefs := []metadata.ResourceDef{
+	providerName = createSafeUUID()
+	userName = createSafeUUID()
+	sourceName = createSafeUUID()
+	entityName = createSafeUUID()
+	originalTableName = createSafeUUID()
+	featureName = createSafeUUID()
+	defs = []metadata.ResourceDef{
 		metadata.UserDef{
 			Name: userName,
 		},
 		metadata.ProviderDef{
-			Name:             ghostProviderName,
+			Name:             providerName,
 			Description:      "",
-			Type:             "GHOST_PROVIDER",
+			Type:             "REDIS_ONLINE",
 			Software:         "",
 			Team:             "",
-			SerializedConfig: []byte{},
+			SerializedConfig: redisConfig.Serialized(),
+		},
+		metadata.EntityDef{
+			Name:        entityName,
+			Description: "",
 		},
 		metadata.SourceDef{
-			Name:        sourceWithoutProvider,
+			Name:        sourceName,
 			Variant:     "",
 			Description: "",
 			Owner:       userName,
-			Provider:    ghostProviderName,
+			Provider:    providerName,
 			Definition: metadata.PrimaryDataSource{
 				Location: metadata.SQLTable{
-					Name: ghostTableName,
+					Name: originalTableName,
 				},
 			},
 			Schedule: "",
 		},
+		metadata.FeatureDef{
+			Name:        featureName,
+			Variant:     "",
+			Source:      metadata.NameVariant{sourceName, ""},
+			Type:        string(provider.Int),
+			Entity:      entityName,
+			Owner:       userName,
+			Description: "",
+			Provider:    providerName,
+			Location: metadata.ResourceVariantColumns{
+				Entity: "entity",
+				Value:  "value",
+				TS:     "ts",
+			},
+			Schedule: "",
+		},
 	}
-	if err := coord.Metadata.CreateAll(context.Background(), providerErrorDefs); err != nil {
-		t.Fatalf("could not create test metadata entries")
+	if err := coord.Metadata.CreateAll(context.Background(), defs); err != nil {
+		t.Fatalf("could not create metadata entries: %v", err)
 	}
-	sourceWithoutProviderResourceID := metadata.ResourceID{sourceWithoutProvider, "", metadata.SOURCE_VARIANT}
-	if err := coord.runRegisterSourceJob(sourceWithoutProviderResourceID, ""); err == nil {
-		t.Fatalf("did not catch error registering registering resource without provider in offline store")
+	if err := coord.Metadata.SetStatus(context.Background(), metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}, metadata.READY, ""); err != nil {
+		t.Fatalf("could not set source variant to ready")
 	}
-	sourceWithoutOfflineProvider := createSafeUUID()
-	onlineProviderName := createSafeUUID()
-	newTableName := createSafeUUID()
-	newUserName := createSafeUUID()
-	liveAddr := fmt.Sprintf("%s:%s", redisHost, redisPort)
-	redisConfig := &pc.RedisConfig{
-		Addr: liveAddr,
+	err = coord.runFeatureMaterializeJob(context.Background(), metadata.ResourceID{featureName, "", metadata.FEATURE_VARIANT}, "")
+	var notOffline *provider.NotOfflineStoreError
+	if !errors.As(err, &notOffline) {
+		t.Fatalf("expected not offline store error trying to use online store as offline store, got %v", err)
 	}
-	serialRedisConfig := redisConfig.Serialized()
-	onlineErrorDefs := []metadata.ResourceDef{
+	providerName = createSafeUUID()
+	offlineProviderName := createSafeUUID()
+	userName = createSafeUUID()
+	sourceName = createSafeUUID()
+	entityName = createSafeUUID()
+	originalTableName = createSafeUUID()
+	featureName = createSafeUUID()
+	defs = []metadata.ResourceDef{
 		metadata.UserDef{
-			Name: newUserName,
+			Name: userName,
 		},
 		metadata.ProviderDef{
-			Name:             onlineProviderName,
+			Name:             offlineProviderName,
 			Description:      "",
-			Type:             "REDIS_ONLINE",
+			Type:             "POSTGRES_OFFLINE",
 			Software:         "",
 			Team:             "",
-			SerializedConfig: serialRedisConfig,
+			SerializedConfig: postgresConfig.Serialize(),
+		},
+		metadata.ProviderDef{
+			Name:             providerName,
+			Description:      "",
+			Type:             "INVALID_PROVIDER",
+			Software:         "",
+			Team:             "",
+			SerializedConfig: []byte{},
+		},
+		metadata.EntityDef{
+			Name:        entityName,
+			Description: "",
 		},
 		metadata.SourceDef{
-			Name:        sourceWithoutOfflineProvider,
+			Name:        sourceName,
 			Variant:     "",
 			Description: "",
-			Owner:       newUserName,
-			Provider:    onlineProviderName,
+			Owner:       userName,
+			Provider:    offlineProviderName,
 			Definition: metadata.PrimaryDataSource{
 				Location: metadata.SQLTable{
-					Name: newTableName,
+					Name: originalTableName,
 				},
 			},
 			Schedule: "",
 		},
+		metadata.FeatureDef{
+			Name:        featureName,
+			Variant:     "",
+			Source:      metadata.NameVariant{sourceName, ""},
+			Type:        string(provider.Int),
+			Entity:      entityName,
+			Owner:       userName,
+			Description: "",
+			Provider:    providerName,
+			Location: metadata.ResourceVariantColumns{
+				Entity: "entity",
+				Value:  "value",
+				TS:     "ts",
+			},
+			Schedule: "",
+		},
 	}
-	if err := coord.Metadata.CreateAll(context.Background(), onlineErrorDefs); err != nil {
-		t.Fatalf("could not create test metadata entries")
+	if err := coord.Metadata.CreateAll(context.Background(), defs); err != nil {
+		t.Fatalf("could not create metadata entries: %v", err)
 	}
-	sourceWithOnlineProvider := metadata.ResourceID{sourceWithoutOfflineProvider, "", metadata.SOURCE_VARIANT}
-	if err := coord.runRegisterSourceJob(sourceWithOnlineProvider, ""); err == nil {
-		t.Fatalf("did not catch error registering registering resource with online provider")
+	if err := coord.Metadata.SetStatus(context.Background(), metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}, metadata.READY, ""); err != nil {
+		t.Fatalf("could not set source variant to ready")
+	}
+	if err := coord.runFeatureMaterializeJob(context.Background(), metadata.ResourceID{featureName, "", metadata.FEATURE_VARIANT}, ""); err == nil {
+		t.Fatalf("did not trigger error trying to get invalid feature provider")
 	}
 }
 
-func TestTemplateReplace(t *testing.T) {
+func TestTrainingSetJobError(t *testing.T) {
 	if testing.Short() {
-		t.Skip()
+		return
 	}
-	err := godotenv.Load(".env")
+	serv, addr := startServ(t)
+	defer serv.Stop()
+	coord, err := createNewCoordinator(addr)
 	if err != nil {
-		fmt.Println(err)
+		t.Fatalf("could not create new basic coordinator")
 	}
-
-	bigQueryConfig := getBigQueryConfig(t)
-	bqPrefix := fmt.Sprintf("%s.%s", bigQueryConfig.ProjectId, bigQueryConfig.DatasetId)
-
-	cases := []struct {
-		name            string
-		provider        pt.Type
-		config          pc.SerializedConfig
-		templateString  string
-		replacements    map[string]string
-		expectedResults string
-		expectedFailure bool
-	}{
-		{
-			"PostgresSuccess",
-			pt.PostgresOffline,
-			postgresConfig.Serialize(),
-			"Some example text {{name1.variant1}} and more {{name2.variant2}}",
-			map[string]string{"name1.variant1": "replacement1", "name2.variant2": "replacement2"},
-			"Some example text \"replacement1\" and more \"replacement2\"",
-			false,
-		},
-		{
-			"PostgresFailure",
-			pt.PostgresOffline,
-			postgresConfig.Serialize(),
-			"Some example text {{name1.variant1}} and more {{name2.variant2}}",
-			map[string]string{"name1.variant1": "replacement1", "name3.variant3": "replacement2"},
-			"",
-			true,
-		},
-		{
-			"BigQuerySuccess",
-			pt.BigQueryOffline,
-			bigQueryConfig.Serialize(),
-			"Some example text {{name1.variant1}} and more {{name2.variant2}}",
-			map[string]string{"name1.variant1": "replacement1", "name2.variant2": "replacement2"},
-			fmt.Sprintf("Some example text `%s.replacement1` and more `%s.replacement2`", bqPrefix, bqPrefix),
-			false,
-		},
-		{
-			"BigQueryFailure",
-			pt.BigQueryOffline,
-			bigQueryConfig.Serialize(),
-			"Some example text {{name1.variant1}} and more {{name2.variant2}}",
-			map[string]string{"name1.variant1": "replacement1", "name3.variant3": "replacement2"},
-			"",
-			true,
-		},
-	}
-
-	for _, tt := range cases {
-		t.Run(tt.name, func(t *testing.T) {
-			offlineProvider := getOfflineStore(t, tt.provider, tt.config)
-			result, err := templateReplace(tt.templateString, tt.replacements, offlineProvider)
-			if !tt.expectedFailure && err != nil {
-				t.Fatalf("template replace did not run correctly: %v", err)
-			}
-			if !tt.expectedFailure && result != tt.expectedResults {
-				t.Fatalf("template replace did not replace values correctly. Expected %s, got %s", tt.expectedResults, result)
-			}
-		})
-	}
-}
-
-func getOfflineStore(t *testing.T, providerName pt.Type, config pc.SerializedConfig) provider.OfflineStore {
-	provider, err := provider.Get(providerName, config)
-	if err != nil {
-		t.Fatalf("could not get provider: %v", err)
-	}
-	offlineProvider, err := provider.AsOfflineStore()
-	if err != nil {
-		t.Fatalf("could not get provider as offline store: %v", err)
-	}
-	return offlineProvider
-}
-
-func getBigQueryConfig(t *testing.T) pc.BigQueryConfig {
-	bigqueryCredentials := os.Getenv("BIGQUERY_CREDENTIALS")
-	JSONCredentials, err := ioutil.ReadFile(bigqueryCredentials)
-	if err != nil {
-		panic(fmt.Errorf("cannot find big query credentials: %v", err))
-	}
-
-	bigQueryDatasetId := strings.Replace(strings.ToUpper(uuid.NewString()), "-", "_", -1)
-	os.Setenv("BIGQUERY_DATASET_ID", bigQueryDatasetId)
-	t.Log("BigQuery Dataset: ", bigQueryDatasetId)
-
-	var serializedCreds map[string]interface{}
-	err = json.Unmarshal(JSONCredentials, &serializedCreds)
-	if err != nil {
-		panic(fmt.Errorf("cannot deserialize big query credentials: %v", err))
-	}
-
-	var bigQueryConfig = pc.BigQueryConfig{
-		ProjectId:   os.Getenv("BIGQUERY_PROJECT_ID"),
-		DatasetId:   os.Getenv("BIGQUERY_DATASET_ID"),
-		Credentials: serializedCreds,
-	}
-
-	return bigQueryConfig
-}
-
-func TestCoordinatorCalls(t *testing.T) {
-	if testing.Short() {
-		return
-	}
-	serv, addr := startServ(t)
-	defer serv.Stop()
-	logger := zap.NewExample().Sugar()
-	client, err := metadata.NewClient(addr, logger)
-	if err != nil {
-		t.Fatalf("could not set up metadata client: %v", err)
-	}
-	defer client.Close()
-	if err := testCoordinatorMaterializeFeature(addr); err != nil {
-		t.Fatalf("coordinator could not materialize feature: %v", err)
-	}
-	if err := testCoordinatorTrainingSet(addr); err != nil {
-		t.Fatalf("coordinator could not create training set: %v", err)
-	}
-	if err := testRegisterPrimaryTableFromSource(addr); err != nil {
-		t.Fatalf("coordinator could not register primary table from source: %v", err)
-	}
-	if err := testRegisterTransformationFromSource(addr); err != nil {
-		t.Fatalf("coordinator could not register transformation from source and transformation: %v", err)
+	defer coord.Metadata.Close()
+	if err := coord.runTrainingSetJob(context.Background(), metadata.ResourceID{"ghost_training_set", "", metadata.TRAINING_SET_VARIANT}, ""); err == nil {
+		t.Fatalf("did not trigger error trying to run job for nonexistent training set")
 	}
-	// if err := testScheduleTrainingSet(addr); err != nil {
-	// 	t.Fatalf("coordinator could not schedule training set to be updated: %v", err)
-	// }
-	// if err := testScheduleTransformation(addr); err != nil {
-	// 	t.Fatalf("coordinator could not schedule transformation to be updated: %v", err)
-	// }
-	// if err := testScheduleFeatureMaterialization(addr); err != nil {
-	// 	t.Fatalf("coordinator could not schedule materialization to be updated: %v", err)
-	// }
-}
-
-func materializeFeatureWithProvider(client *metadata.Client, offlineConfig pc.SerializedConfig, onlineConfig pc.SerializedConfig, featureName string, sourceName string, originalTableName string, schedule string) error {
-	offlineProviderName := createSafeUUID()
-	onlineProviderName := createSafeUUID()
+	providerName := createSafeUUID()
 	userName := createSafeUUID()
+	sourceName := createSafeUUID()
 	entityName := createSafeUUID()
+	labelName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	featureName := createSafeUUID()
+	tsName := createSafeUUID()
 	defs := []metadata.ResourceDef{
 		metadata.UserDef{
 			Name: userName,
 		},
 		metadata.ProviderDef{
-			Name:             offlineProviderName,
-			Description:      "",
-			Type:             "POSTGRES_OFFLINE",
-			Software:         "",
-			Team:             "",
-			SerializedConfig: offlineConfig,
-		},
-		metadata.ProviderDef{
-			Name:             onlineProviderName,
+			Name:             providerName,
 			Description:      "",
-			Type:             "REDIS_ONLINE",
+			Type:             "INVALID_PROVIDER",
 			Software:         "",
 			Team:             "",
-			SerializedConfig: onlineConfig,
+			SerializedConfig: []byte{},
 		},
 		metadata.EntityDef{
 			Name:        entityName,
@@ -1055,7 +1325,7 @@ func materializeFeatureWithProvider(client *metadata.Client, offlineConfig pc.Se
 			Variant:     "",
 			Description: "",
 			Owner:       userName,
-			Provider:    offlineProviderName,
+			Provider:    providerName,
 			Definition: metadata.PrimaryDataSource{
 				Location: metadata.SQLTable{
 					Name: originalTableName,
@@ -1063,6 +1333,21 @@ func materializeFeatureWithProvider(client *metadata.Client, offlineConfig pc.Se
 			},
 			Schedule: "",
 		},
+		metadata.LabelDef{
+			Name:        labelName,
+			Variant:     "",
+			Description: "",
+			Type:        string(provider.Int),
+			Source:      metadata.NameVariant{sourceName, ""},
+			Entity:      entityName,
+			Owner:       userName,
+			Provider:    providerName,
+			Location: metadata.ResourceVariantColumns{
+				Entity: "entity",
+				Value:  "value",
+				TS:     "ts",
+			},
+		},
 		metadata.FeatureDef{
 			Name:        featureName,
 			Variant:     "",
@@ -1071,35 +1356,58 @@ func materializeFeatureWithProvider(client *metadata.Client, offlineConfig pc.Se
 			Entity:      entityName,
 			Owner:       userName,
 			Description: "",
-			Provider:    onlineProviderName,
+			Provider:    providerName,
 			Location: metadata.ResourceVariantColumns{
 				Entity: "entity",
 				Value:  "value",
 				TS:     "ts",
 			},
-			Schedule: schedule,
+			Schedule: "",
+		},
+		metadata.TrainingSetDef{
+			Name:        tsName,
+			Variant:     "",
+			Description: "",
+			Owner:       userName,
+			Provider:    providerName,
+			Label:       metadata.NameVariant{labelName, ""},
+			Features:    []metadata.NameVariant{{featureName, ""}},
+			Schedule:    "",
 		},
 	}
-	if err := client.CreateAll(context.Background(), defs); err != nil {
-		return err
+	if err := coord.Metadata.CreateAll(context.Background(), defs); err != nil {
+		t.Fatalf("could not create metadata entries: %v", err)
 	}
-	return nil
-}
-
-func createSourceWithProvider(client *metadata.Client, config pc.SerializedConfig, sourceName string, tableName string) error {
-	userName := createSafeUUID()
-	providerName := createSafeUUID()
-	defs := []metadata.ResourceDef{
+	if err := coord.runTrainingSetJob(context.Background(), metadata.ResourceID{tsName, "", metadata.TRAINING_SET_VARIANT}, ""); err == nil {
+		t.Fatalf("did not trigger error trying to run job with nonexistent provider")
+	}
+	providerName = createSafeUUID()
+	userName = createSafeUUID()
+	sourceName = createSafeUUID()
+	entityName = createSafeUUID()
+	labelName = createSafeUUID()
+	originalTableName = createSafeUUID()
+	featureName = createSafeUUID()
+	tsName = createSafeUUID()
+	liveAddr := fmt.Sprintf("%s:%s", redisHost, redisPort)
+	redisConfig := &pc.RedisConfig{
+		Addr: liveAddr,
+	}
+	defs = []metadata.ResourceDef{
 		metadata.UserDef{
 			Name: userName,
 		},
 		metadata.ProviderDef{
 			Name:             providerName,
 			Description:      "",
-			Type:             "POSTGRES_OFFLINE",
+			Type:             "REDIS_ONLINE",
 			Software:         "",
 			Team:             "",
-			SerializedConfig: config,
+			SerializedConfig: redisConfig.Serialized(),
+		},
+		metadata.EntityDef{
+			Name:        entityName,
+			Description: "",
 		},
 		metadata.SourceDef{
 			Name:        sourceName,
@@ -1109,98 +1417,24 @@ func createSourceWithProvider(client *metadata.Client, config pc.SerializedConfi
 			Provider:    providerName,
 			Definition: metadata.PrimaryDataSource{
 				Location: metadata.SQLTable{
-					Name: tableName,
+					Name: originalTableName,
 				},
 			},
+			Schedule: "",
 		},
-	}
-	if err := client.CreateAll(context.Background(), defs); err != nil {
-		return err
-	}
-	return nil
-}
-
-func createTransformationWithProvider(client *metadata.Client, config pc.SerializedConfig, sourceName string, transformationQuery string, sources []metadata.NameVariant, schedule string) error {
-	userName := createSafeUUID()
-	providerName := createSafeUUID()
-	defs := []metadata.ResourceDef{
-		metadata.UserDef{
-			Name: userName,
-		},
-		metadata.ProviderDef{
-			Name:             providerName,
-			Description:      "",
-			Type:             "POSTGRES_OFFLINE",
-			Software:         "",
-			Team:             "",
-			SerializedConfig: config,
-		},
-		metadata.SourceDef{
-			Name:        sourceName,
+		metadata.LabelDef{
+			Name:        labelName,
 			Variant:     "",
 			Description: "",
+			Type:        string(provider.Int),
+			Source:      metadata.NameVariant{sourceName, ""},
+			Entity:      entityName,
 			Owner:       userName,
 			Provider:    providerName,
-			Definition: metadata.TransformationSource{
-				TransformationType: metadata.SQLTransformationType{
-					Query:   transformationQuery,
-					Sources: sources,
-				},
-			},
-			Schedule: schedule,
-		},
-	}
-	if err := client.CreateAll(context.Background(), defs); err != nil {
-		return err
-	}
-	return nil
-}
-
-func createTrainingSetWithProvider(client *metadata.Client, config pc.SerializedConfig, sourceName string, featureName string, labelName string, tsName string, originalTableName string, schedule string) error {
-	providerName := createSafeUUID()
-	userName := createSafeUUID()
-	entityName := createSafeUUID()
-	defs := []metadata.ResourceDef{
-		metadata.UserDef{
-			Name: userName,
-		},
-		metadata.ProviderDef{
-			Name:             providerName,
-			Description:      "",
-			Type:             "POSTGRES_OFFLINE",
-			Software:         "",
-			Team:             "",
-			SerializedConfig: config,
-		},
-		metadata.EntityDef{
-			Name:        entityName,
-			Description: "",
-		},
-		metadata.SourceDef{
-			Name:        sourceName,
-			Variant:     "",
-			Description: "",
-			Owner:       userName,
-			Provider:    providerName,
-			Definition: metadata.PrimaryDataSource{
-				Location: metadata.SQLTable{
-					Name: originalTableName,
-				},
-			},
-		},
-		metadata.LabelDef{
-			Name:        labelName,
-			Variant:     "",
-			Description: "",
-			Type:        string(provider.Int),
-			Source:      metadata.NameVariant{sourceName, ""},
-			Entity:      entityName,
-			Owner:       userName,
-			Provider:    providerName,
-			Location: metadata.ResourceVariantColumns{
-				Entity: "entity",
-				Value:  "value",
-				TS:     "ts",
+			Location: metadata.ResourceVariantColumns{
+				Entity: "entity",
+				Value:  "value",
+				TS:     "ts",
 			},
 		},
 		metadata.FeatureDef{
@@ -1217,6 +1451,7 @@ func createTrainingSetWithProvider(client *metadata.Client, config pc.Serialized
 				Value:  "value",
 				TS:     "ts",
 			},
+			Schedule: "",
 		},
 		metadata.TrainingSetDef{
 			Name:        tsName,
@@ -1226,142 +1461,3116 @@ func createTrainingSetWithProvider(client *metadata.Client, config pc.Serialized
 			Provider:    providerName,
 			Label:       metadata.NameVariant{labelName, ""},
 			Features:    []metadata.NameVariant{{featureName, ""}},
-			Schedule:    schedule,
+			Schedule:    "",
 		},
 	}
-	if err := client.CreateAll(context.Background(), defs); err != nil {
-		return err
+	if err := coord.Metadata.CreateAll(context.Background(), defs); err != nil {
+		t.Fatalf("could not create metadata entries: %v", err)
+	}
+	if err := coord.runTrainingSetJob(context.Background(), metadata.ResourceID{tsName, "", metadata.TRAINING_SET_VARIANT}, ""); err == nil {
+		t.Fatalf("did not trigger error trying to convert online provider to offline")
 	}
-	return nil
 }
 
-func testCoordinatorTrainingSet(addr string) error {
-	if err := runner.RegisterFactory(string(runner.CREATE_TRAINING_SET), runner.TrainingSetRunnerFactory); err != nil {
-		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+func TestRunPrimaryTableJobError(t *testing.T) {
+	if testing.Short() {
+		return
 	}
-	defer runner.UnregisterFactory(string(runner.CREATE_TRAINING_SET))
-	logger := zap.NewExample().Sugar()
-	client, err := metadata.NewClient(addr, logger)
+	serv, addr := startServ(t)
+	defer serv.Stop()
+	coord, err := createNewCoordinator(addr)
 	if err != nil {
-		return fmt.Errorf("Failed to connect: %v", err)
+		t.Fatalf("could not create new basic coordinator")
 	}
-	defer client.Close()
-	etcdConnect := fmt.Sprintf("%s:%s", etcdHost, etcdPort)
-	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{etcdConnect}})
+	defer coord.Metadata.Close()
+	sourceNoPrimaryNameSet := createSafeUUID()
+	providerName := createSafeUUID()
+	userName := createSafeUUID()
+	defs := []metadata.ResourceDef{
+		metadata.UserDef{
+			Name: userName,
+		},
+		metadata.ProviderDef{
+			Name:             providerName,
+			Description:      "",
+			Type:             "POSTGRES_OFFLINE",
+			Software:         "",
+			Team:             "",
+			SerializedConfig: postgresConfig.Serialize(),
+		},
+		metadata.SourceDef{
+			Name:        sourceNoPrimaryNameSet,
+			Variant:     "",
+			Description: "",
+			Owner:       userName,
+			Provider:    providerName,
+			Definition: metadata.PrimaryDataSource{
+				Location: metadata.SQLTable{
+					Name: "",
+				},
+			},
+			Schedule: "",
+		},
+	}
+	if err := coord.Metadata.CreateAll(context.Background(), defs); err != nil {
+		t.Fatalf("could not create test metadata entries")
+	}
+	transformSource, err := coord.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{sourceNoPrimaryNameSet, ""})
 	if err != nil {
-		return err
+		t.Fatalf("could not fetch created source variant: %v", err)
 	}
-	defer cli.Close()
-	featureName := createSafeUUID()
-	labelName := createSafeUUID()
-	tsName := createSafeUUID()
-	serialPGConfig := postgresConfig.Serialize()
-	my_provider, err := provider.Get(pt.PostgresOffline, serialPGConfig)
+	provider, err := provider.Get(pt.PostgresOffline, postgresConfig.Serialize())
 	if err != nil {
-		return fmt.Errorf("could not get provider: %v", err)
+		t.Fatalf("could not get provider: %v", err)
 	}
-	my_offline, err := my_provider.AsOfflineStore()
+	offlineProvider, err := provider.AsOfflineStore()
 	if err != nil {
-		return fmt.Errorf("could not get provider as offline store: %v", err)
+		t.Fatalf("could not get provider as offline store: %v", err)
 	}
-	offline_feature := provider.ResourceID{Name: featureName, Variant: "", Type: provider.Feature}
-	originalTableName := createSafeUUID()
-	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
-		return err
+	sourceResourceID := metadata.ResourceID{sourceNoPrimaryNameSet, "", metadata.SOURCE_VARIANT}
+	if err := coord.runPrimaryTableJob(context.Background(), transformSource, sourceResourceID, offlineProvider, ""); err == nil {
+		t.Fatalf("did not catch error trying to run primary table job with no source table set")
 	}
-	sourceName := createSafeUUID()
-	if err := createTrainingSetWithProvider(client, serialPGConfig, sourceName, featureName, labelName, tsName, originalTableName, ""); err != nil {
-		return fmt.Errorf("could not create training set %v", err)
+	sourceNoActualPrimaryTable := createSafeUUID()
+	newProviderName := createSafeUUID()
+	newUserName := createSafeUUID()
+	newDefs := []metadata.ResourceDef{
+		metadata.UserDef{
+			Name: newUserName,
+		},
+		metadata.ProviderDef{
+			Name:             newProviderName,
+			Description:      "",
+			Type:             "POSTGRES_OFFLINE",
+			Software:         "",
+			Team:             "",
+			SerializedConfig: postgresConfig.Serialize(),
+		},
+		metadata.SourceDef{
+			Name:        sourceNoActualPrimaryTable,
+			Variant:     "",
+			Description: "",
+			Owner:       newUserName,
+			Provider:    newProviderName,
+			Definition: metadata.PrimaryDataSource{
+				Location: metadata.SQLTable{
+					Name: "ghost_primary_table",
+				},
+			},
+			Schedule: "",
+		},
 	}
-	ctx := context.Background()
-	tsID := metadata.ResourceID{Name: tsName, Variant: "", Type: metadata.TRAINING_SET_VARIANT}
-	tsCreated, err := client.GetTrainingSetVariant(ctx, metadata.NameVariant{Name: tsName, Variant: ""})
+	if err := coord.Metadata.CreateAll(context.Background(), newDefs); err != nil {
+		t.Fatalf("could not create test metadata entries: %v", err)
+	}
+	newTransformSource, err := coord.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{sourceNoActualPrimaryTable, ""})
 	if err != nil {
-		return fmt.Errorf("could not get training set")
+		t.Fatalf("could not fetch created source variant: %v", err)
 	}
-	if tsCreated.Status() != metadata.CREATED {
-		return fmt.Errorf("Training set not set to created with no coordinator running")
+	newSourceResourceID := metadata.ResourceID{sourceNoActualPrimaryTable, "", metadata.SOURCE_VARIANT}
+	if err := coord.runPrimaryTableJob(context.Background(), newTransformSource, newSourceResourceID, offlineProvider, ""); err == nil {
+		t.Fatalf("did not catch error trying to create primary table when no source table exists in database")
 	}
-	memJobSpawner := MemoryJobSpawner{}
-	coord, err := NewCoordinator(client, logger, cli, &memJobSpawner)
+}
+
+func TestMapNameVariantsToTablesError(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	serv, addr := startServ(t)
+	defer serv.Stop()
+	coord, err := createNewCoordinator(addr)
 	if err != nil {
-		return fmt.Errorf("Failed to set up coordinator")
+		t.Fatalf("could not create new basic coordinator")
 	}
-	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
-	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
-		return err
+	defer coord.Metadata.Close()
+	ghostResourceName := createSafeUUID()
+	ghostNameVariants := []metadata.NameVariant{{ghostResourceName, ""}}
+	if _, err := coord.mapNameVariantsToTables(ghostNameVariants); err == nil {
+		t.Fatalf("did not catch error creating map from nonexistent resource")
 	}
-	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
-	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
-		return err
+	sourceNotReady := createSafeUUID()
+	providerName := createSafeUUID()
+	tableName := createSafeUUID()
+	userName := createSafeUUID()
+	defs := []metadata.ResourceDef{
+		metadata.UserDef{
+			Name: userName,
+		},
+		metadata.ProviderDef{
+			Name:             providerName,
+			Description:      "",
+			Type:             "POSTGRES_OFFLINE",
+			Software:         "",
+			Team:             "",
+			SerializedConfig: []byte{},
+		},
+		metadata.SourceDef{
+			Name:        sourceNotReady,
+			Variant:     "",
+			Description: "",
+			Owner:       userName,
+			Provider:    providerName,
+			Definition: metadata.PrimaryDataSource{
+				Location: metadata.SQLTable{
+					Name: tableName,
+				},
+			},
+			Schedule: "",
+		},
 	}
-	labelID := metadata.ResourceID{Name: labelName, Variant: "", Type: metadata.LABEL_VARIANT}
-	if err := coord.ExecuteJob(metadata.GetJobKey(labelID)); err != nil {
-		return err
+	if err := coord.Metadata.CreateAll(context.Background(), defs); err != nil {
+		t.Fatalf("could not create test metadata entries")
 	}
-	featureTable, err := my_offline.GetResourceTable(offline_feature)
-	if err != nil {
-		return fmt.Errorf("could not create feature table: %v", err)
+	notReadyNameVariants := []metadata.NameVariant{{sourceNotReady, ""}}
+	if _, err := coord.mapNameVariantsToTables(notReadyNameVariants); err == nil {
+		t.Fatalf("did not catch error creating map from not ready resource")
 	}
-	for _, value := range testOfflineTableValues {
-		if err := featureTable.Write(value); err != nil {
-			return fmt.Errorf("could not write to offline feature table")
-		}
+}
+
+func TestRegisterSourceJobErrors(t *testing.T) {
+	if testing.Short() {
+		return
 	}
-	offline_label := provider.ResourceID{Name: labelName, Variant: "", Type: provider.Label}
-	labelTable, err := my_offline.GetResourceTable(offline_label)
+	serv, addr := startServ(t)
+	defer serv.Stop()
+	coord, err := createNewCoordinator(addr)
 	if err != nil {
-		return fmt.Errorf("could not create label table: %v", err)
+		t.Fatalf("could not create new basic coordinator")
+	}
+	defer coord.Metadata.Close()
+	ghostResourceName := createSafeUUID()
+	ghostResourceID := metadata.ResourceID{ghostResourceName, "", metadata.SOURCE_VARIANT}
+	if err := coord.runRegisterSourceJob(context.Background(), ghostResourceID, ""); err == nil {
+		t.Fatalf("did not catch error registering nonexistent resource")
+	}
+	sourceWithoutProvider := createSafeUUID()
+	ghostProviderName := createSafeUUID()
+	ghostTableName := createSafeUUID()
+	userName := createSafeUUID()
+	providerErrorDefs := []metadata.ResourceDef{
+		metadata.UserDef{
+			Name: userName,
+		},
+		metadata.ProviderDef{
+			Name:             ghostProviderName,
+			Description:      "",
+			Type:             "GHOST_PROVIDER",
+			Software:         "",
+			Team:             "",
+			SerializedConfig: []byte{},
+		},
+		metadata.SourceDef{
+			Name:        sourceWithoutProvider,
+			Variant:     "",
+			Description: "",
+			Owner:       userName,
+			Provider:    ghostProviderName,
+			Definition: metadata.PrimaryDataSource{
+				Location: metadata.SQLTable{
+					Name: ghostTableName,
+				},
+			},
+			Schedule: "",
+		},
+	}
+	if err := coord.Metadata.CreateAll(context.Background(), providerErrorDefs); err != nil {
+		t.Fatalf("could not create test metadata entries")
+	}
+	sourceWithoutProviderResourceID := metadata.ResourceID{sourceWithoutProvider, "", metadata.SOURCE_VARIANT}
+	if err := coord.runRegisterSourceJob(context.Background(), sourceWithoutProviderResourceID, ""); err == nil {
+		t.Fatalf("did not catch error registering registering resource without provider in offline store")
+	}
+	sourceWithoutOfflineProvider := createSafeUUID()
+	onlineProviderName := createSafeUUID()
+	newTableName := createSafeUUID()
+	newUserName := createSafeUUID()
+	liveAddr := fmt.Sprintf("%s:%s", redisHost, redisPort)
+	redisConfig := &pc.RedisConfig{
+		Addr: liveAddr,
+	}
+	serialRedisConfig := redisConfig.Serialized()
+	onlineErrorDefs := []metadata.ResourceDef{
+		metadata.UserDef{
+			Name: newUserName,
+		},
+		metadata.ProviderDef{
+			Name:             onlineProviderName,
+			Description:      "",
+			Type:             "REDIS_ONLINE",
+			Software:         "",
+			Team:             "",
+			SerializedConfig: serialRedisConfig,
+		},
+		metadata.SourceDef{
+			Name:        sourceWithoutOfflineProvider,
+			Variant:     "",
+			Description: "",
+			Owner:       newUserName,
+			Provider:    onlineProviderName,
+			Definition: metadata.PrimaryDataSource{
+				Location: metadata.SQLTable{
+					Name: newTableName,
+				},
+			},
+			Schedule: "",
+		},
+	}
+	if err := coord.Metadata.CreateAll(context.Background(), onlineErrorDefs); err != nil {
+		t.Fatalf("could not create test metadata entries")
+	}
+	sourceWithOnlineProvider := metadata.ResourceID{sourceWithoutOfflineProvider, "", metadata.SOURCE_VARIANT}
+	if err := coord.runRegisterSourceJob(context.Background(), sourceWithOnlineProvider, ""); err == nil {
+		t.Fatalf("did not catch error registering registering resource with online provider")
+	}
+}
+
+func TestTemplateReplace(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	err := godotenv.Load(".env")
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	bigQueryConfig := getBigQueryConfig(t)
+	bqPrefix := fmt.Sprintf("%s.%s", bigQueryConfig.ProjectId, bigQueryConfig.DatasetId)
+	sqliteConfig := pc.SQLiteConfig{Path: pc.SQLiteMemoryPath}
+
+	cases := []struct {
+		name            string
+		provider        pt.Type
+		config          pc.SerializedConfig
+		templateString  string
+		replacements    map[string]string
+		expectedResults string
+		expectedFailure bool
+		expectedReport  templateReport
+	}{
+		{
+			"PostgresSuccess",
+			pt.PostgresOffline,
+			postgresConfig.Serialize(),
+			"Some example text {{name1.variant1}} and more {{name2.variant2}}",
+			map[string]string{"name1.variant1": "replacement1", "name2.variant2": "replacement2"},
+			"Some example text \"replacement1\" and more \"replacement2\"",
+			false,
+			templateReport{
+				Substitutions: []templateSubstitution{{"name1.variant1", "\"replacement1\""}, {"name2.variant2", "\"replacement2\""}},
+				Unresolved:    []string{},
+			},
+		},
+		{
+			"PostgresFailure",
+			pt.PostgresOffline,
+			postgresConfig.Serialize(),
+			"Some example text {{name1.variant1}} and more {{name2.variant2}}",
+			map[string]string{"name1.variant1": "replacement1", "name3.variant3": "replacement2"},
+			"",
+			true,
+			templateReport{
+				Substitutions: []templateSubstitution{{"name1.variant1", "\"replacement1\""}},
+				Unresolved:    []string{"name2.variant2"},
+			},
+		},
+		{
+			"PostgresMixedCase",
+			pt.PostgresOffline,
+			postgresConfig.Serialize(),
+			"SELECT * FROM {{Transactions.Daily}} JOIN {{users.}}",
+			map[string]string{"Transactions.Daily": "featureform_primary__Transactions__Daily", "Users.": "featureform_primary__Users__"},
+			"SELECT * FROM \"featureform_primary__Transactions__Daily\" JOIN \"featureform_primary__Users__\"",
+			false,
+			templateReport{
+				Substitutions: []templateSubstitution{
+					{"Transactions.Daily", "\"featureform_primary__Transactions__Daily\""},
+					{"users.", "\"featureform_primary__Users__\""},
+				},
+				Unresolved: []string{},
+			},
+		},
+		{
+			"SQLiteMixedCase",
+			pt.SQLiteOffline,
+			sqliteConfig.Serialize(),
+			"SELECT * FROM {{Transactions.Daily}} JOIN {{users.}}",
+			map[string]string{"Transactions.Daily": "featureform_primary__Transactions__Daily", "Users.": "featureform_primary__Users__"},
+			"SELECT * FROM \"featureform_primary__Transactions__Daily\" JOIN \"featureform_primary__Users__\"",
+			false,
+			templateReport{
+				Substitutions: []templateSubstitution{
+					{"Transactions.Daily", "\"featureform_primary__Transactions__Daily\""},
+					{"users.", "\"featureform_primary__Users__\""},
+				},
+				Unresolved: []string{},
+			},
+		},
+		{
+			"BigQuerySuccess",
+			pt.BigQueryOffline,
+			bigQueryConfig.Serialize(),
+			"Some example text {{name1.variant1}} and more {{name2.variant2}}",
+			map[string]string{"name1.variant1": "replacement1", "name2.variant2": "replacement2"},
+			fmt.Sprintf("Some example text `%s.replacement1` and more `%s.replacement2`", bqPrefix, bqPrefix),
+			false,
+			templateReport{
+				Substitutions: []templateSubstitution{
+					{"name1.variant1", fmt.Sprintf("`%s.replacement1`", bqPrefix)},
+					{"name2.variant2", fmt.Sprintf("`%s.replacement2`", bqPrefix)},
+				},
+				Unresolved: []string{},
+			},
+		},
+		{
+			"BigQueryMixedCase",
+			pt.BigQueryOffline,
+			bigQueryConfig.Serialize(),
+			"SELECT * FROM {{Transactions.Daily}} JOIN {{users.}}",
+			map[string]string{"Transactions.Daily": "featureform_primary__Transactions__Daily", "Users.": "featureform_primary__Users__"},
+			fmt.Sprintf("SELECT * FROM `%s.featureform_primary__Transactions__Daily` JOIN {{users.}}", bqPrefix),
+			true,
+			templateReport{
+				// BigQuery names are case sensitive, so users. doesn't match Users.
+				Substitutions: []templateSubstitution{{"Transactions.Daily", fmt.Sprintf("`%s.featureform_primary__Transactions__Daily`", bqPrefix)}},
+				Unresolved:    []string{"users."},
+			},
+		},
+		{
+			"BigQueryFailure",
+			pt.BigQueryOffline,
+			bigQueryConfig.Serialize(),
+			"Some example text {{name1.variant1}} and more {{name2.variant2}}",
+			map[string]string{"name1.variant1": "replacement1", "name3.variant3": "replacement2"},
+			"",
+			true,
+			templateReport{
+				Substitutions: []templateSubstitution{{"name1.variant1", fmt.Sprintf("`%s.replacement1`", bqPrefix)}},
+				Unresolved:    []string{"name2.variant2"},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.provider == pt.SQLiteOffline && !provider.SQLiteSupported {
+				t.Skip("sqlite needs a build with cgo")
+			}
+			offlineProvider := getOfflineStore(t, tt.provider, tt.config)
+			result, err := templateReplace(tt.templateString, tt.replacements, offlineProvider, false)
+			if !tt.expectedFailure && err != nil {
+				t.Fatalf("template replace did not run correctly: %v", err)
+			}
+			if !tt.expectedFailure && result != tt.expectedResults {
+				t.Fatalf("template replace did not replace values correctly. Expected %s, got %s", tt.expectedResults, result)
+			}
+			_, report, err := templateReplaceWithReport(tt.templateString, tt.replacements, offlineProvider, false)
+			if tt.expectedFailure != (err != nil) {
+				t.Fatalf("expected failure %v from template replace with report, got %v", tt.expectedFailure, err)
+			}
+			if !reflect.DeepEqual(report, tt.expectedReport) {
+				t.Fatalf("template replace reported the wrong substitutions. Expected %v, got %v", tt.expectedReport, report)
+			}
+		})
+	}
+}
+
+func TestTemplateReplaceEscapes(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	offlineProvider := getOfflineStore(t, pt.PostgresOffline, postgresConfig.Serialize())
+	replacements := map[string]string{"name1.variant1": "replacement1"}
+	cases := []struct {
+		name            string
+		templateString  string
+		allowUnmatched  bool
+		expectedResults string
+		expectedFailure bool
+	}{
+		{
+			"EscapedBraces",
+			"SELECT '{{{{not a token}}' AS literal FROM {{name1.variant1}}",
+			false,
+			"SELECT '{{not a token}}' AS literal FROM \"replacement1\"",
+			false,
+		},
+		{
+			"JSONLiteral",
+			`SELECT '{"nested": {{{{"a": 1}}}' :: jsonb AS payload FROM {{ name1.variant1 }}`,
+			false,
+			`SELECT '{"nested": {{"a": 1}}}' :: jsonb AS payload FROM "replacement1"`,
+			false,
+		},
+		{
+			"EscapeBeforeToken",
+			"SELECT '{{{{' || id FROM {{name1.variant1}}",
+			false,
+			"SELECT '{{' || id FROM \"replacement1\"",
+			false,
+		},
+		{
+			"UnmatchedStrict",
+			`SELECT '{{"a": 1}}' FROM {{name1.variant1}}`,
+			false,
+			"",
+			true,
+		},
+		{
+			"UnmatchedAllowed",
+			`SELECT '{{"a": 1}}' FROM {{name1.variant1}}`,
+			true,
+			`SELECT '{{"a": 1}}' FROM "replacement1"`,
+			false,
+		},
+		{
+			"Unterminated",
+			"SELECT * FROM {{name1.variant1",
+			true,
+			"",
+			true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := templateReplace(tt.templateString, replacements, offlineProvider, tt.allowUnmatched)
+			if tt.expectedFailure != (err != nil) {
+				t.Fatalf("expected failure %v, got %v", tt.expectedFailure, err)
+			}
+			if !tt.expectedFailure && result != tt.expectedResults {
+				t.Fatalf("template replace did not replace values correctly. Expected %s, got %s", tt.expectedResults, result)
+			}
+		})
+	}
+}
+
+func getOfflineStore(t *testing.T, providerName pt.Type, config pc.SerializedConfig) provider.OfflineStore {
+	provider, err := provider.Get(providerName, config)
+	if err != nil {
+		t.Fatalf("could not get provider: %v", err)
+	}
+	offlineProvider, err := provider.AsOfflineStore()
+	if err != nil {
+		t.Fatalf("could not get provider as offline store: %v", err)
+	}
+	return offlineProvider
+}
+
+func getBigQueryConfig(t *testing.T) pc.BigQueryConfig {
+	bigqueryCredentials := os.Getenv("BIGQUERY_CREDENTIALS")
+	JSONCredentials, err := ioutil.ReadFile(bigqueryCredentials)
+	if err != nil {
+		panic(fmt.Errorf("cannot find big query credentials: %v", err))
+	}
+
+	bigQueryDatasetId := strings.Replace(strings.ToUpper(uuid.NewString()), "-", "_", -1)
+	os.Setenv("BIGQUERY_DATASET_ID", bigQueryDatasetId)
+	t.Log("BigQuery Dataset: ", bigQueryDatasetId)
+
+	var serializedCreds map[string]interface{}
+	err = json.Unmarshal(JSONCredentials, &serializedCreds)
+	if err != nil {
+		panic(fmt.Errorf("cannot deserialize big query credentials: %v", err))
+	}
+
+	var bigQueryConfig = pc.BigQueryConfig{
+		ProjectId:   os.Getenv("BIGQUERY_PROJECT_ID"),
+		DatasetId:   os.Getenv("BIGQUERY_DATASET_ID"),
+		Credentials: serializedCreds,
+	}
+
+	return bigQueryConfig
+}
+
+func TestCoordinatorCalls(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	serv, addr := startServ(t)
+	defer serv.Stop()
+	logger := zap.NewExample().Sugar()
+	client, err := metadata.NewClient(addr, logger)
+	if err != nil {
+		t.Fatalf("could not set up metadata client: %v", err)
+	}
+	defer client.Close()
+	if err := testCoordinatorMaterializeFeature(addr); err != nil {
+		t.Fatalf("coordinator could not materialize feature: %v", err)
+	}
+	if err := testCoordinatorMaterializeFilteredFeature(addr); err != nil {
+		t.Fatalf("coordinator could not materialize filtered feature: %v", err)
+	}
+	if err := testCoordinatorMaterializeMappedColumns(addr); err != nil {
+		t.Fatalf("coordinator could not materialize feature with mapped columns: %v", err)
+	}
+	if err := testCoordinatorTrainingSet(addr); err != nil {
+		t.Fatalf("coordinator could not create training set: %v", err)
+	}
+	if err := testRegisterPrimaryTableFromSource(addr); err != nil {
+		t.Fatalf("coordinator could not register primary table from source: %v", err)
+	}
+	if err := testRegisterTransformationFromSource(addr); err != nil {
+		t.Fatalf("coordinator could not register transformation from source and transformation: %v", err)
+	}
+	if err := testMixedCaseTransformationToken(addr); err != nil {
+		t.Fatalf("coordinator could not run transformation with a mixed case token: %v", err)
+	}
+	if err := testTransformationQueryFailureStatus(addr); err != nil {
+		t.Fatalf("failed transformation's status did not show its query: %v", err)
+	}
+	if err := testVerifyMaterialization(addr); err != nil {
+		t.Fatalf("Verify materialization test failed: %v", err)
+	}
+	if err := testServeFeature(addr); err != nil {
+		t.Fatalf("Serve feature test failed: %v", err)
+	}
+	if err := testMaterializeProgress(addr); err != nil {
+		t.Fatalf("Materialize progress test failed: %v", err)
+	}
+	if err := testMaterializeWithinGracePeriod(addr); err != nil {
+		t.Fatalf("Materialize within grace period test failed: %v", err)
+	}
+	if err := testMaterializeRetry(addr); err != nil {
+		t.Fatalf("Materialize retry test failed: %v", err)
+	}
+	if err := testDeterministicPrimaryTableName(addr); err != nil {
+		t.Fatalf("coordinator did not create deterministically named primary table: %v", err)
+	}
+	if err := testPrimaryTableOverwriteGuard(addr); err != nil {
+		t.Fatalf("Primary table overwrite guard test failed: %v", err)
+	}
+	if err := testTransformationOverwriteGuard(addr); err != nil {
+		t.Fatalf("Transformation table overwrite guard test failed: %v", err)
+	}
+	if err := testRetryFailedJob(addr); err != nil {
+		t.Fatalf("Retry failed job test failed: %v", err)
+	}
+	if err := testForceMaterialize(addr); err != nil {
+		t.Fatalf("Force materialize test failed: %v", err)
+	}
+	if err := testParallelMaterialize(addr); err != nil {
+		t.Fatalf("Parallel materialize test failed: %v", err)
+	}
+	if err := testIncrementalMaterialize(addr); err != nil {
+		t.Fatalf("Incremental materialize test failed: %v", err)
+	}
+	if err := testJobMetrics(addr); err != nil {
+		t.Fatalf("Job metrics test failed: %v", err)
+	}
+	if err := testSourceSchemaCompatibility(addr); err != nil {
+		t.Fatalf("coordinator did not check source schema compatibility: %v", err)
+	}
+	if err := testListVariants(addr); err != nil {
+		t.Fatalf("List variants test failed: %v", err)
+	}
+	if err := testMaterializeFeatureOverUnreadyTransformation(addr); err != nil {
+		t.Fatalf("Feature over unready transformation test failed: %v", err)
+	}
+	if err := testMaterializeFeatureFromTransformation(addr); err != nil {
+		t.Fatalf("Feature from transformation test failed: %v", err)
+	}
+	if err := testCoordinatorBatch(addr); err != nil {
+		t.Fatalf("coordinator could not execute batch: %v", err)
+	}
+	if err := testCoordinatorExecuteJobs(addr); err != nil {
+		t.Fatalf("Execute jobs test failed: %v", err)
+	}
+	if err := testExecuteJobsCancelled(addr); err != nil {
+		t.Fatalf("Unstarted jobs were not failed: %v", err)
+	}
+	if err := testCoordinatorHistory(addr); err != nil {
+		t.Fatalf("coordinator did not record job history: %v", err)
+	}
+	if err := testReclaimAbandonedJob(addr); err != nil {
+		t.Fatalf("Abandoned job was not reclaimed: %v", err)
+	}
+	if err := testReclaimSkipsHeldJob(addr); err != nil {
+		t.Fatalf("Held job was reclaimed: %v", err)
+	}
+	if err := testJobRowQuota(addr); err != nil {
+		t.Fatalf("Job row quota was not enforced: %v", err)
+	}
+	if err := testStreamPrimaryMaterialization(addr); err != nil {
+		t.Fatalf("Feature was not streamed from its primary source: %v", err)
+	}
+	if err := testExecuteJobTimeout(addr); err != nil {
+		t.Fatalf("Job did not time out: %v", err)
+	}
+	if err := testTrainingSetJobTimeout(addr); err != nil {
+		t.Fatalf("Training set job did not time out: %v", err)
+	}
+	if err := testPoolSkipsLockedJob(addr); err != nil {
+		t.Fatalf("Locked job was not skipped: %v", err)
+	}
+	if err := testCancelJob(addr); err != nil {
+		t.Fatalf("Job was not cancelled: %v", err)
+	}
+	if err := testRecurringJobTickClaimedOnce(addr); err != nil {
+		t.Fatalf("Recurring job tick was not claimed once: %v", err)
+	}
+	if err := testCoordinatorClose(addr); err != nil {
+		t.Fatalf("coordinator could not be closed: %v", err)
+	}
+	if err := testShutdownWaitsForJobs(addr); err != nil {
+		t.Fatalf("Shutdown did not wait for jobs: %v", err)
+	}
+	if err := testShutdownReleasesJobs(addr); err != nil {
+		t.Fatalf("Shutdown did not release unfinished jobs: %v", err)
+	}
+	// if err := testScheduleTrainingSet(addr); err != nil {
+	// 	t.Fatalf("coordinator could not schedule training set to be updated: %v", err)
+	// }
+	// if err := testScheduleTransformation(addr); err != nil {
+	// 	t.Fatalf("coordinator could not schedule transformation to be updated: %v", err)
+	// }
+	// if err := testScheduleFeatureMaterialization(addr); err != nil {
+	// 	t.Fatalf("coordinator could not schedule materialization to be updated: %v", err)
+	// }
+}
+
+func materializeFeatureWithProvider(client *metadata.Client, offlineConfig pc.SerializedConfig, onlineConfig pc.SerializedConfig, featureName string, sourceName string, originalTableName string, schedule string) error {
+	return materializeFilteredFeatureWithProvider(client, offlineConfig, onlineConfig, featureName, sourceName, originalTableName, schedule, "")
+}
+
+func materializeFilteredFeatureWithProvider(client *metadata.Client, offlineConfig pc.SerializedConfig, onlineConfig pc.SerializedConfig, featureName string, sourceName string, originalTableName string, schedule string, filter string) error {
+	location := metadata.ResourceVariantColumns{
+		Entity: "entity",
+		Value:  "value",
+		TS:     "ts",
+	}
+	return materializeMappedFeatureWithProvider(client, offlineConfig, onlineConfig, featureName, sourceName, originalTableName, schedule, filter, location)
+}
+
+// materializeMappedFeatureWithProvider creates a feature that reads its entity,
+// value and timestamp from the source columns named in location.
+func materializeMappedFeatureWithProvider(client *metadata.Client, offlineConfig pc.SerializedConfig, onlineConfig pc.SerializedConfig, featureName string, sourceName string, originalTableName string, schedule string, filter string, location metadata.ResourceVariantColumns) error {
+	return materializeFeatureWithOnlineProvider(client, offlineConfig, pt.RedisOnline, onlineConfig, featureName, sourceName, originalTableName, schedule, filter, location)
+}
+
+func materializeFeatureWithOnlineProvider(client *metadata.Client, offlineConfig pc.SerializedConfig, onlineType pt.Type, onlineConfig pc.SerializedConfig, featureName string, sourceName string, originalTableName string, schedule string, filter string, location metadata.ResourceVariantColumns) error {
+	offlineProviderName := createSafeUUID()
+	onlineProviderName := createSafeUUID()
+	userName := createSafeUUID()
+	entityName := createSafeUUID()
+	defs := []metadata.ResourceDef{
+		metadata.UserDef{
+			Name: userName,
+		},
+		metadata.ProviderDef{
+			Name:             offlineProviderName,
+			Description:      "",
+			Type:             "POSTGRES_OFFLINE",
+			Software:         "",
+			Team:             "",
+			SerializedConfig: offlineConfig,
+		},
+		metadata.ProviderDef{
+			Name:             onlineProviderName,
+			Description:      "",
+			Type:             string(onlineType),
+			Software:         "",
+			Team:             "",
+			SerializedConfig: onlineConfig,
+		},
+		metadata.EntityDef{
+			Name:        entityName,
+			Description: "",
+		},
+		metadata.SourceDef{
+			Name:        sourceName,
+			Variant:     "",
+			Description: "",
+			Owner:       userName,
+			Provider:    offlineProviderName,
+			Definition: metadata.PrimaryDataSource{
+				Location: metadata.SQLTable{
+					Name: originalTableName,
+				},
+			},
+			Schedule: "",
+		},
+		metadata.FeatureDef{
+			Name:        featureName,
+			Variant:     "",
+			Source:      metadata.NameVariant{sourceName, ""},
+			Type:        string(provider.Int),
+			Entity:      entityName,
+			Owner:       userName,
+			Description: "",
+			Provider:    onlineProviderName,
+			Location:    location,
+			Schedule:    schedule,
+			Filter:      filter,
+		},
+	}
+	if err := client.CreateAll(context.Background(), defs); err != nil {
+		return err
+	}
+	return nil
+}
+
+func createSourceWithProvider(client *metadata.Client, config pc.SerializedConfig, sourceName string, tableName string) error {
+	userName := createSafeUUID()
+	providerName := createSafeUUID()
+	defs := []metadata.ResourceDef{
+		metadata.UserDef{
+			Name: userName,
+		},
+		metadata.ProviderDef{
+			Name:             providerName,
+			Description:      "",
+			Type:             "POSTGRES_OFFLINE",
+			Software:         "",
+			Team:             "",
+			SerializedConfig: config,
+		},
+		metadata.SourceDef{
+			Name:        sourceName,
+			Variant:     "",
+			Description: "",
+			Owner:       userName,
+			Provider:    providerName,
+			Definition: metadata.PrimaryDataSource{
+				Location: metadata.SQLTable{
+					Name: tableName,
+				},
+			},
+		},
+	}
+	if err := client.CreateAll(context.Background(), defs); err != nil {
+		return err
+	}
+	return nil
+}
+
+func createTransformationWithProvider(client *metadata.Client, config pc.SerializedConfig, sourceName string, transformationQuery string, sources []metadata.NameVariant, schedule string) error {
+	userName := createSafeUUID()
+	providerName := createSafeUUID()
+	defs := []metadata.ResourceDef{
+		metadata.UserDef{
+			Name: userName,
+		},
+		metadata.ProviderDef{
+			Name:             providerName,
+			Description:      "",
+			Type:             "POSTGRES_OFFLINE",
+			Software:         "",
+			Team:             "",
+			SerializedConfig: config,
+		},
+		metadata.SourceDef{
+			Name:        sourceName,
+			Variant:     "",
+			Description: "",
+			Owner:       userName,
+			Provider:    providerName,
+			Definition: metadata.TransformationSource{
+				TransformationType: metadata.SQLTransformationType{
+					Query:   transformationQuery,
+					Sources: sources,
+				},
+			},
+			Schedule: schedule,
+		},
+	}
+	if err := client.CreateAll(context.Background(), defs); err != nil {
+		return err
+	}
+	return nil
+}
+
+func createTrainingSetWithProvider(client *metadata.Client, config pc.SerializedConfig, sourceName string, featureName string, labelName string, tsName string, originalTableName string, schedule string) error {
+	providerName := createSafeUUID()
+	userName := createSafeUUID()
+	entityName := createSafeUUID()
+	defs := []metadata.ResourceDef{
+		metadata.UserDef{
+			Name: userName,
+		},
+		metadata.ProviderDef{
+			Name:             providerName,
+			Description:      "",
+			Type:             "POSTGRES_OFFLINE",
+			Software:         "",
+			Team:             "",
+			SerializedConfig: config,
+		},
+		metadata.EntityDef{
+			Name:        entityName,
+			Description: "",
+		},
+		metadata.SourceDef{
+			Name:        sourceName,
+			Variant:     "",
+			Description: "",
+			Owner:       userName,
+			Provider:    providerName,
+			Definition: metadata.PrimaryDataSource{
+				Location: metadata.SQLTable{
+					Name: originalTableName,
+				},
+			},
+		},
+		metadata.LabelDef{
+			Name:        labelName,
+			Variant:     "",
+			Description: "",
+			Type:        string(provider.Int),
+			Source:      metadata.NameVariant{sourceName, ""},
+			Entity:      entityName,
+			Owner:       userName,
+			Provider:    providerName,
+			Location: metadata.ResourceVariantColumns{
+				Entity: "entity",
+				Value:  "value",
+				TS:     "ts",
+			},
+		},
+		metadata.FeatureDef{
+			Name:        featureName,
+			Variant:     "",
+			Source:      metadata.NameVariant{sourceName, ""},
+			Type:        string(provider.Int),
+			Entity:      entityName,
+			Owner:       userName,
+			Description: "",
+			Provider:    providerName,
+			Location: metadata.ResourceVariantColumns{
+				Entity: "entity",
+				Value:  "value",
+				TS:     "ts",
+			},
+		},
+		metadata.TrainingSetDef{
+			Name:        tsName,
+			Variant:     "",
+			Description: "",
+			Owner:       userName,
+			Provider:    providerName,
+			Label:       metadata.NameVariant{labelName, ""},
+			Features:    []metadata.NameVariant{{featureName, ""}},
+			Schedule:    schedule,
+		},
+	}
+	if err := client.CreateAll(context.Background(), defs); err != nil {
+		return err
+	}
+	return nil
+}
+
+func testCoordinatorTrainingSet(addr string) error {
+	if err := runner.RegisterFactory(string(runner.CREATE_TRAINING_SET), runner.TrainingSetRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.CREATE_TRAINING_SET))
+	logger := zap.NewExample().Sugar()
+	client, err := metadata.NewClient(addr, logger)
+	if err != nil {
+		return fmt.Errorf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	etcdConnect := fmt.Sprintf("%s:%s", etcdHost, etcdPort)
+	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{etcdConnect}})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	featureName := createSafeUUID()
+	labelName := createSafeUUID()
+	tsName := createSafeUUID()
+	serialPGConfig := postgresConfig.Serialize()
+	my_provider, err := provider.Get(pt.PostgresOffline, serialPGConfig)
+	if err != nil {
+		return fmt.Errorf("could not get provider: %v", err)
+	}
+	my_offline, err := my_provider.AsOfflineStore()
+	if err != nil {
+		return fmt.Errorf("could not get provider as offline store: %v", err)
+	}
+	offline_feature := provider.ResourceID{Name: featureName, Variant: "", Type: provider.Feature}
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	sourceName := createSafeUUID()
+	if err := createTrainingSetWithProvider(client, serialPGConfig, sourceName, featureName, labelName, tsName, originalTableName, ""); err != nil {
+		return fmt.Errorf("could not create training set %v", err)
+	}
+	ctx := context.Background()
+	tsID := metadata.ResourceID{Name: tsName, Variant: "", Type: metadata.TRAINING_SET_VARIANT}
+	tsCreated, err := client.GetTrainingSetVariant(ctx, metadata.NameVariant{Name: tsName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get training set")
+	}
+	if tsCreated.Status() != metadata.CREATED {
+		return fmt.Errorf("Training set not set to created with no coordinator running")
+	}
+	memJobSpawner := MemoryJobSpawner{}
+	coord, err := NewCoordinator(client, logger, cli, &memJobSpawner)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator")
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+		return err
+	}
+	labelID := metadata.ResourceID{Name: labelName, Variant: "", Type: metadata.LABEL_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(labelID)); err != nil {
+		return err
+	}
+	featureTable, err := my_offline.GetResourceTable(offline_feature)
+	if err != nil {
+		return fmt.Errorf("could not create feature table: %v", err)
+	}
+	for _, value := range testOfflineTableValues {
+		if err := featureTable.Write(value); err != nil {
+			return fmt.Errorf("could not write to offline feature table")
+		}
+	}
+	offline_label := provider.ResourceID{Name: labelName, Variant: "", Type: provider.Label}
+	labelTable, err := my_offline.GetResourceTable(offline_label)
+	if err != nil {
+		return fmt.Errorf("could not create label table: %v", err)
 	}
 	for _, value := range testOfflineTableValues {
 		if err := labelTable.Write(value); err != nil {
 			return fmt.Errorf("could not write to offline label table")
 		}
 	}
-	if err := coord.ExecuteJob(metadata.GetJobKey(tsID)); err != nil {
+	if err := coord.ExecuteJob(metadata.GetJobKey(tsID)); err != nil {
+		return err
+	}
+	startWaitDelete := time.Now()
+	elapsed := time.Since(startWaitDelete)
+	for has, _ := coord.hasJob(tsID); has && elapsed < time.Duration(10)*time.Second; has, _ = coord.hasJob(tsID) {
+		time.Sleep(1 * time.Second)
+		elapsed = time.Since(startWaitDelete)
+		fmt.Printf("waiting for job %v to be deleted\n", tsID)
+	}
+	if elapsed >= time.Duration(10)*time.Second {
+		return fmt.Errorf("timed out waiting for job to delete")
+	}
+	ts_complete, err := client.GetTrainingSetVariant(ctx, metadata.NameVariant{Name: tsName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get training set variant")
+	}
+	if metadata.READY != ts_complete.Status() {
+		return fmt.Errorf("Training set not set to ready once job completes")
+	}
+	if err := coord.runTrainingSetJob(context.Background(), tsID, ""); err == nil {
+		return fmt.Errorf("run training set job did not trigger error when tried to create training set that already exists")
+	}
+	providerTsID := provider.ResourceID{Name: tsID.Name, Variant: tsID.Variant, Type: provider.TrainingSet}
+	tsIterator, err := my_offline.GetTrainingSet(providerTsID)
+	if err != nil {
+		return fmt.Errorf("Coordinator did not create training set")
+	}
+
+	for i := 0; tsIterator.Next(); i++ {
+		retrievedFeatures := tsIterator.Features()
+		retrievedLabel := tsIterator.Label()
+		if !reflect.DeepEqual(retrievedFeatures[0], testOfflineTableValues[i].Value) {
+			return fmt.Errorf("Features not copied into training set")
+		}
+		if !reflect.DeepEqual(retrievedLabel, testOfflineTableValues[i].Value) {
+			return fmt.Errorf("Label not copied into training set")
+		}
+
+	}
+	return nil
+}
+
+func testCoordinatorMaterializeFeature(addr string) error {
+	redisConfig := &pc.RedisConfig{
+		Addr: fmt.Sprintf("%s:%s", redisHost, redisPort),
+	}
+	return testCoordinatorMaterializeFeatureToStore(addr, pt.RedisOnline, redisConfig.Serialized())
+}
+
+// Materializes a feature the same way as testCoordinatorMaterializeFeature,
+// into a Cassandra online store, so that both stores serve the same values.
+func TestCoordinatorMaterializeFeatureCassandra(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	if cassandraAddr == "" {
+		t.Skip("CASSANDRA_ADDR is not set")
+	}
+	serv, addr := startServ(t)
+	defer serv.Stop()
+	cassandraConfig := &pc.CassandraConfig{
+		Keyspace:    "ff_coordinator_test",
+		Addr:        cassandraAddr,
+		Username:    os.Getenv("CASSANDRA_USER"),
+		Password:    os.Getenv("CASSANDRA_PASSWORD"),
+		Consistency: "ONE",
+		Replication: 1,
+	}
+	if err := testCoordinatorMaterializeFeatureToStore(addr, pt.CassandraOnline, cassandraConfig.Serialized()); err != nil {
+		t.Fatalf("coordinator could not materialize feature to cassandra: %v", err)
+	}
+}
+
+func testCoordinatorMaterializeFeatureToStore(addr string, onlineType pt.Type, serialOnlineConfig pc.SerializedConfig) error {
+	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.COPY_TO_ONLINE))
+	if err := runner.RegisterFactory(string(runner.MATERIALIZE), runner.MaterializeRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.MATERIALIZE))
+	logger := zap.NewExample().Sugar()
+	client, err := metadata.NewClient(addr, logger)
+	if err != nil {
+		return fmt.Errorf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	etcdConnect := fmt.Sprintf("%s:%s", etcdHost, etcdPort)
+	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{etcdConnect}})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	serialPGConfig := postgresConfig.Serialize()
+	p, err := provider.Get(onlineType, serialOnlineConfig)
+	if err != nil {
+		return fmt.Errorf("could not get online provider: %v", err)
+	}
+	onlineStore, err := p.AsOnlineStore()
+	if err != nil {
+		return fmt.Errorf("could not get provider as online store")
+	}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	location := metadata.ResourceVariantColumns{
+		Entity: "entity",
+		Value:  "value",
+		TS:     "ts",
+	}
+	if err := materializeFeatureWithOnlineProvider(client, serialPGConfig, onlineType, serialOnlineConfig, featureName, sourceName, originalTableName, "", "", location); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
+	}
+	if err := client.SetStatus(context.Background(), metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}, metadata.READY, ""); err != nil {
+		return err
+	}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureCreated, err := client.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: featureName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get feature: %v", err)
+	}
+	if featureCreated.Status() != metadata.CREATED {
+		return fmt.Errorf("Feature not set to created with no coordinator running")
+	}
+	memJobSpawner := MemoryJobSpawner{}
+	coord, err := NewCoordinator(client, logger, cli, &memJobSpawner)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator")
+	}
+	eventSink := &memoryEventSink{}
+	coord.EventSink = eventSink
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+		return err
+	}
+	if !eventSink.hasEvent(featureID, metadata.READY) {
+		return fmt.Errorf("READY event not published for materialized feature")
+	}
+	startWaitDelete := time.Now()
+	elapsed := time.Since(startWaitDelete)
+	for has, _ := coord.hasJob(featureID); has && elapsed < time.Duration(10)*time.Second; has, _ = coord.hasJob(featureID) {
+		time.Sleep(1 * time.Second)
+		elapsed = time.Since(startWaitDelete)
+		fmt.Printf("waiting for job %v to be deleted\n", featureID)
+	}
+	if elapsed >= time.Duration(10)*time.Second {
+		return fmt.Errorf("timed out waiting for job to delete")
+	}
+	featureComplete, err := client.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: featureName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get feature variant")
+	}
+	if metadata.READY != featureComplete.Status() {
+		return fmt.Errorf("Feature not set to ready once job completes")
+	}
+	resourceTable, err := onlineStore.GetTable(featureName, "")
+	if err != nil {
+		return err
+	}
+	entities := make([]string, len(testOfflineTableValues))
+	for i, record := range testOfflineTableValues {
+		entities[i] = record.Entity
+	}
+	values, err := provider.BatchGet(resourceTable, entities)
+	if err != nil {
+		return err
+	}
+	for _, record := range testOfflineTableValues {
+		if !reflect.DeepEqual(values[record.Entity], record.Value) {
+			return fmt.Errorf("Feature value did not materialize")
+		}
+	}
+	return nil
+}
+
+// Materializes a feature from a source whose columns are named user_id, score
+// and event_time rather than entity, value and ts
+func testCoordinatorMaterializeMappedColumns(addr string) error {
+	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.COPY_TO_ONLINE))
+	if err := runner.RegisterFactory(string(runner.MATERIALIZE), runner.MaterializeRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.MATERIALIZE))
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
+	}
+	defer coord.Metadata.Close()
+	defer coord.EtcdClient.Close()
+	redisConfig := &pc.RedisConfig{
+		Addr: fmt.Sprintf("%s:%s", redisHost, redisPort),
+	}
+	p, err := provider.Get(pt.RedisOnline, redisConfig.Serialized())
+	if err != nil {
+		return fmt.Errorf("could not get online provider: %v", err)
+	}
+	onlineStore, err := p.AsOnlineStore()
+	if err != nil {
+		return fmt.Errorf("could not get provider as online store")
+	}
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	url := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", postgresConfig.Username, postgresConfig.Password, postgresConfig.Host, postgresConfig.Port, postgresConfig.Database)
+	conn, err := pgxpool.Connect(context.Background(), url)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	mappedTableName := createSafeUUID()
+	viewQuery := fmt.Sprintf("CREATE VIEW %s AS SELECT entity AS user_id, value AS score, ts AS event_time FROM %s", sanitize(mappedTableName), sanitize(originalTableName))
+	if _, err := conn.Exec(context.Background(), viewQuery); err != nil {
+		return err
+	}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	location := metadata.ResourceVariantColumns{
+		Entity: "user_id",
+		Value:  "score",
+		TS:     "event_time",
+	}
+	if err := materializeMappedFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, mappedTableName, "", "", location); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+		return err
+	}
+	resourceTable, err := onlineStore.GetTable(featureName, "")
+	if err != nil {
+		return err
+	}
+	for _, record := range testOfflineTableValues {
+		value, err := resourceTable.Get(record.Entity)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(value, record.Value) {
+			return fmt.Errorf("expected %s to materialize as %v from its score column, got %v", record.Entity, record.Value, value)
+		}
+	}
+
+	misspelledName := createSafeUUID()
+	misspelledSource := createSafeUUID()
+	location.Value = "scores"
+	if err := materializeMappedFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), misspelledName, misspelledSource, mappedTableName, "", "", location); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(metadata.ResourceID{Name: misspelledSource, Variant: "", Type: metadata.SOURCE_VARIANT})); err != nil {
+		return err
+	}
+	err = coord.ExecuteJob(metadata.GetJobKey(metadata.ResourceID{Name: misspelledName, Variant: "", Type: metadata.FEATURE_VARIANT}))
+	if err == nil || !strings.Contains(err.Error(), `value column "scores"`) {
+		return fmt.Errorf("expected a mapping to a missing column to fail naming it, got %v", err)
+	}
+	return nil
+}
+
+func testCoordinatorMaterializeFilteredFeature(addr string) error {
+	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.COPY_TO_ONLINE))
+	if err := runner.RegisterFactory(string(runner.MATERIALIZE), runner.MaterializeRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.MATERIALIZE))
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
+	}
+	defer coord.Metadata.Close()
+	defer coord.EtcdClient.Close()
+	redisConfig := &pc.RedisConfig{
+		Addr: fmt.Sprintf("%s:%s", redisHost, redisPort),
+	}
+	p, err := provider.Get(pt.RedisOnline, redisConfig.Serialized())
+	if err != nil {
+		return fmt.Errorf("could not get online provider: %v", err)
+	}
+	onlineStore, err := p.AsOnlineStore()
+	if err != nil {
+		return fmt.Errorf("could not get provider as online store")
+	}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	if err := materializeFilteredFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, originalTableName, "", "value > 2"); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+		return err
+	}
+	resourceTable, err := onlineStore.GetTable(featureName, "")
+	if err != nil {
+		return err
+	}
+	for _, record := range testOfflineTableValues {
+		value, err := resourceTable.Get(record.Entity)
+		if record.Value.(int) > 2 {
+			if err != nil {
+				return fmt.Errorf("could not get filtered entity %s: %v", record.Entity, err)
+			}
+			if !reflect.DeepEqual(value, record.Value) {
+				return fmt.Errorf("Feature value did not materialize")
+			}
+		} else if _, isNotFound := err.(*provider.EntityNotFound); !isNotFound {
+			return fmt.Errorf("entity %s should have been excluded by the filter: value %v, error %v", record.Entity, value, err)
+		}
+	}
+	return nil
+}
+
+func testVerifyMaterialization(addr string) error {
+	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.COPY_TO_ONLINE))
+	if err := runner.RegisterFactory(string(runner.MATERIALIZE), runner.MaterializeRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.MATERIALIZE))
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
+	}
+	defer coord.Metadata.Close()
+	defer coord.EtcdClient.Close()
+	coord.VerifyMaterializations = true
+	redisConfig := &pc.RedisConfig{
+		Addr: fmt.Sprintf("%s:%s", redisHost, redisPort),
+	}
+	p, err := provider.Get(pt.RedisOnline, redisConfig.Serialized())
+	if err != nil {
+		return fmt.Errorf("could not get online provider: %v", err)
+	}
+	onlineStore, err := p.AsOnlineStore()
+	if err != nil {
+		return fmt.Errorf("could not get provider as online store")
+	}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, originalTableName, ""); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+		return fmt.Errorf("materialization failed verification: %v", err)
+	}
+	featureNameVariant := metadata.NameVariant{Name: featureName, Variant: ""}
+	mismatches, err := coord.VerifyMaterialization(featureNameVariant)
+	if err != nil {
+		return fmt.Errorf("could not verify materialization: %v", err)
+	}
+	if mismatches != 0 {
+		return fmt.Errorf("expected no mismatches after materializing, got %d", mismatches)
+	}
+	resourceTable, err := onlineStore.GetTable(featureName, "")
+	if err != nil {
+		return err
+	}
+	corrupted := testOfflineTableValues[0]
+	if err := resourceTable.Set(corrupted.Entity, corrupted.Value.(int)+1000); err != nil {
+		return fmt.Errorf("could not corrupt online value: %v", err)
+	}
+	mismatches, err = coord.VerifyMaterialization(featureNameVariant)
+	if err != nil {
+		return fmt.Errorf("could not verify corrupted materialization: %v", err)
+	}
+	if mismatches != 1 {
+		return fmt.Errorf("expected corrupted value of %s to be the only mismatch, got %d", corrupted.Entity, mismatches)
+	}
+	return nil
+}
+
+func testServeFeature(addr string) error {
+	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.COPY_TO_ONLINE))
+	if err := runner.RegisterFactory(string(runner.MATERIALIZE), runner.MaterializeRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.MATERIALIZE))
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
+	}
+	defer coord.Metadata.Close()
+	defer coord.EtcdClient.Close()
+	redisConfig := &pc.RedisConfig{
+		Addr: fmt.Sprintf("%s:%s", redisHost, redisPort),
+	}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, originalTableName, ""); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+		return err
+	}
+	featureNameVariant := metadata.NameVariant{Name: featureName, Variant: ""}
+	for _, record := range testOfflineTableValues {
+		value, err := coord.ServeFeature(context.Background(), featureNameVariant, record.Entity)
+		if err != nil {
+			return fmt.Errorf("could not serve entity %s: %v", record.Entity, err)
+		}
+		if !reflect.DeepEqual(value, record.Value) {
+			return fmt.Errorf("expected %v for entity %s, got %v", record.Value, record.Entity, value)
+		}
+	}
+	if _, err := coord.ServeFeature(context.Background(), featureNameVariant, "missing_entity"); err == nil {
+		return fmt.Errorf("expected serving a missing entity to fail")
+	} else if _, isNotFound := err.(*provider.EntityNotFound); !isNotFound {
+		return fmt.Errorf("expected entity not found error for missing entity, got %v", err)
+	}
+	return nil
+}
+
+func testMaterializeProgress(addr string) error {
+	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.COPY_TO_ONLINE))
+	if err := runner.RegisterFactory(string(runner.MATERIALIZE), runner.MaterializeRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.MATERIALIZE))
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
+	}
+	defer coord.Metadata.Close()
+	defer coord.EtcdClient.Close()
+	var progressMtx sync.Mutex
+	reported := make([]runner.MaterializeProgress, 0)
+	coord.OnMaterializeProgress = func(resID metadata.ResourceID, progress runner.MaterializeProgress) {
+		progressMtx.Lock()
+		defer progressMtx.Unlock()
+		reported = append(reported, progress)
+	}
+	redisConfig := &pc.RedisConfig{
+		Addr: fmt.Sprintf("%s:%s", redisHost, redisPort),
+	}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, originalTableName, ""); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+		return err
+	}
+	progressMtx.Lock()
+	defer progressMtx.Unlock()
+	if len(reported) == 0 {
+		return fmt.Errorf("no progress reported for materialization")
+	}
+	for i, progress := range reported {
+		if progress.TotalRows != int64(len(testOfflineTableValues)) {
+			return fmt.Errorf("progress %d: expected %d total rows, got %d", i, len(testOfflineTableValues), progress.TotalRows)
+		}
+		if i > 0 && progress.Percent() < reported[i-1].Percent() {
+			return fmt.Errorf("progress went backwards from %v%% to %v%%", reported[i-1].Percent(), progress.Percent())
+		}
+	}
+	final := reported[len(reported)-1]
+	if final.RowsWritten != final.TotalRows || final.Percent() != 100 {
+		return fmt.Errorf("expected materialization to finish at 100%%, got %d of %d rows (%v%%)", final.RowsWritten, final.TotalRows, final.Percent())
+	}
+	if _, err := coord.GetJobProgress(featureID); !errors.Is(err, ErrNoJobProgress) {
+		return fmt.Errorf("expected job progress to be cleaned up once the job finished, got %v", err)
+	}
+	return nil
+}
+
+// delayedRunner finishes its wrapped runner's job, then waits delay more before
+// reporting it done, like a runner on a briefly slow provider.
+type delayedRunner struct {
+	types.Runner
+	delay  time.Duration
+	cancel <-chan struct{}
+}
+
+func (r *delayedRunner) SetCancel(cancel <-chan struct{}) {
+	r.cancel = cancel
+	if cancellable, ok := r.Runner.(runner.CancellableRunner); ok {
+		cancellable.SetCancel(cancel)
+	}
+}
+
+func (r *delayedRunner) Run() (types.CompletionWatcher, error) {
+	watcher, err := r.Runner.Run()
+	if err != nil {
+		return nil, err
+	}
+	return &delayedWatcher{CompletionWatcher: watcher, delay: r.delay, cancel: r.cancel}, nil
+}
+
+// delayedWatcher finishes delay after the watcher it wraps, or as soon as
+// cancel is closed
+type delayedWatcher struct {
+	types.CompletionWatcher
+	delay  time.Duration
+	cancel <-chan struct{}
+}
+
+func (w *delayedWatcher) Wait() error {
+	err := w.CompletionWatcher.Wait()
+	select {
+	case <-time.After(w.delay):
+		return err
+	case <-w.cancel:
+		return runner.ErrJobCancelled
+	}
+}
+
+func testMaterializeWithinGracePeriod(addr string) error {
+	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register copy to online runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.COPY_TO_ONLINE))
+	slowMaterializeFactory := func(config runner.Config) (types.Runner, error) {
+		materializeRunner, err := runner.MaterializeRunnerFactory(config)
+		if err != nil {
+			return nil, err
+		}
+		return &delayedRunner{Runner: materializeRunner, delay: time.Second}, nil
+	}
+	if err := runner.RegisterFactory(string(runner.MATERIALIZE), slowMaterializeFactory); err != nil {
+		return fmt.Errorf("Failed to register materialize runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.MATERIALIZE))
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
+	}
+	defer coord.Metadata.Close()
+	defer coord.EtcdClient.Close()
+	// The materialization finishes after its timeout, but well within the grace period
+	coord.RunnerTimeout = 100 * time.Millisecond
+	coord.RunnerGracePeriod = 30 * time.Second
+	redisConfig := &pc.RedisConfig{
+		Addr: fmt.Sprintf("%s:%s", redisHost, redisPort),
+	}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, originalTableName, ""); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+		return fmt.Errorf("expected materialization finishing within grace period to succeed: %v", err)
+	}
+	feature, err := coord.Metadata.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: featureName, Variant: ""})
+	if err != nil {
+		return err
+	}
+	if status := feature.Status(); status != metadata.READY {
+		return fmt.Errorf("expected feature to be READY, got %s", status)
+	}
+	return nil
+}
+
+// interruptedRunner finishes its wrapped runner's job, then reports that it
+// failed, like a runner that lost its connection after creating its tables.
+type interruptedRunner struct {
+	types.Runner
+}
+
+func (r *interruptedRunner) Run() (types.CompletionWatcher, error) {
+	watcher, err := r.Runner.Run()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Wait(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("lost connection to runner")
+}
+
+func testMaterializeRetry(addr string) error {
+	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register chunk runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.COPY_TO_ONLINE))
+	attempts := 0
+	interruptedOnceFactory := func(config runner.Config) (types.Runner, error) {
+		materializeRunner, err := runner.MaterializeRunnerFactory(config)
+		if err != nil {
+			return nil, err
+		}
+		attempts++
+		if attempts == 1 {
+			return &interruptedRunner{Runner: materializeRunner}, nil
+		}
+		return materializeRunner, nil
+	}
+	if err := runner.RegisterFactory(string(runner.MATERIALIZE), interruptedOnceFactory); err != nil {
+		return fmt.Errorf("Failed to register materialize runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.MATERIALIZE))
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
+	}
+	defer coord.Metadata.Close()
+	defer coord.EtcdClient.Close()
+	coord.JobRetries = map[metadata.ResourceType]JobRetryPolicy{
+		metadata.FEATURE_VARIANT: {Attempts: 2, Delay: time.Millisecond},
+	}
+	redisConfig := &pc.RedisConfig{
+		Addr: fmt.Sprintf("%s:%s", redisHost, redisPort),
+	}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, originalTableName, ""); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	// The first attempt leaves the materialization and the online table behind
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+		return fmt.Errorf("expected retried materialization to succeed: %v", err)
+	}
+	if attempts != 2 {
+		return fmt.Errorf("expected 2 attempts, got %d", attempts)
+	}
+	feature, err := coord.Metadata.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: featureName, Variant: ""})
+	if err != nil {
+		return err
+	}
+	if status := feature.Status(); status != metadata.READY {
+		return fmt.Errorf("expected feature to be READY, got %s", status)
+	}
+	return nil
+}
+
+func CreateOriginalPostgresTable(tableName string) error {
+	url := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", postgresConfig.Username, postgresConfig.Password, postgresConfig.Host, postgresConfig.Port, postgresConfig.Database)
+	ctx := context.Background()
+	conn, err := pgxpool.Connect(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	createTableQuery := fmt.Sprintf("CREATE TABLE %s (entity VARCHAR, value INT, ts TIMESTAMPTZ)", sanitize(tableName))
+	if _, err := conn.Exec(ctx, createTableQuery); err != nil {
+		return err
+	}
+	rows := make([][]interface{}, len(testOfflineTableValues))
+	for i, record := range testOfflineTableValues {
+		rows[i] = []interface{}{record.Entity, record.Value, record.TS}
+	}
+	_, err = conn.CopyFrom(ctx, pgx.Identifier{tableName}, []string{"entity", "value", "ts"}, pgx.CopyFromRows(rows))
+	return err
+}
+
+func TestLockJobKey(t *testing.T) {
+	jobKey := metadata.GetJobKey(metadata.ResourceID{Name: "name", Variant: "variant", Type: metadata.SOURCE_VARIANT})
+	lockKey := fmt.Sprintf("%s/694d8a5f2b3c1e07", GetLockKey(jobKey))
+	if !strings.HasPrefix(lockKey, jobLockPrefix) {
+		t.Fatalf("expected lock key %s to have prefix %s", lockKey, jobLockPrefix)
+	}
+	if actual := lockJobKey(lockKey); actual != jobKey {
+		t.Fatalf("expected job key %s, got %s", jobKey, actual)
+	}
+}
+
+// Simulates a coordinator dying while it runs a job, by taking the job's lock
+// and setting its resource to PENDING with a session whose lease is then left
+// to expire, and checks that another coordinator finishes the job.
+func testReclaimAbandonedJob(addr string) error {
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
+	}
+	defer coord.Close()
+	tableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(tableName); err != nil {
+		return fmt.Errorf("Could not create non-featureform source table: %v", err)
+	}
+	sourceName := createSafeUUID()
+	if err := createSourceWithProvider(coord.Metadata, postgresConfig.Serialize(), sourceName, tableName); err != nil {
+		return fmt.Errorf("could not register source in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	jobKey := metadata.GetJobKey(sourceID)
+
+	deadCli, err := clientv3.New(clientv3.Config{Endpoints: []string{fmt.Sprintf("%s:%s", etcdHost, etcdPort)}})
+	if err != nil {
+		return err
+	}
+	session, err := concurrency.NewSession(deadCli, concurrency.WithTTL(1))
+	if err != nil {
+		return fmt.Errorf("new session: %v", err)
+	}
+	if err := concurrency.NewMutex(session, GetLockKey(jobKey)).Lock(context.Background()); err != nil {
+		return fmt.Errorf("could not lock job: %v", err)
+	}
+	if err := coord.Metadata.SetStatus(context.Background(), sourceID, metadata.PENDING, ""); err != nil {
+		return fmt.Errorf("could not set source to pending: %v", err)
+	}
+
+	go coord.WatchForAbandonedJobs()
+	// Give the watch time to start before the lock is released
+	time.Sleep(time.Second)
+	// The dying coordinator stops keeping its lease alive without unlocking
+	session.Orphan()
+	deadCli.Close()
+
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		source, err := coord.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{Name: sourceName, Variant: ""})
+		if err != nil {
+			return fmt.Errorf("could not get source: %v", err)
+		}
+		has, err := coord.hasJob(sourceID)
+		if err != nil {
+			return err
+		}
+		if source.Status() == metadata.READY && !has {
+			return nil
+		}
+		if source.Status() == metadata.FAILED {
+			return fmt.Errorf("reclaimed job failed: %s", source.Error())
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for abandoned job to be reclaimed, status %s", source.Status())
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// Checks that a job isn't reclaimed when a coordinator gives up waiting for its
+// lock while another still holds it, and that it is once the holder releases
+// the lock without finishing the job.
+func testReclaimSkipsHeldJob(addr string) error {
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
+	}
+	defer coord.Close()
+	tableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(tableName); err != nil {
+		return fmt.Errorf("Could not create non-featureform source table: %v", err)
+	}
+	sourceName := createSafeUUID()
+	if err := createSourceWithProvider(coord.Metadata, postgresConfig.Serialize(), sourceName, tableName); err != nil {
+		return fmt.Errorf("could not register source in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	jobKey := metadata.GetJobKey(sourceID)
+	if err := coord.Metadata.SetStatus(context.Background(), sourceID, metadata.PENDING, ""); err != nil {
+		return fmt.Errorf("could not set source to pending: %v", err)
+	}
+	holder, err := concurrency.NewSession(coord.EtcdClient, concurrency.WithTTL(1))
+	if err != nil {
+		return fmt.Errorf("new session: %v", err)
+	}
+	defer holder.Close()
+	holderLock := concurrency.NewMutex(holder, GetLockKey(jobKey))
+	if err := holderLock.Lock(context.Background()); err != nil {
+		return fmt.Errorf("could not lock job: %v", err)
+	}
+
+	go coord.WatchForAbandonedJobs()
+	// Give the watch time to start before the waiter's key is deleted
+	time.Sleep(time.Second)
+	waiter, err := concurrency.NewSession(coord.EtcdClient, concurrency.WithTTL(1))
+	if err != nil {
+		return fmt.Errorf("new session: %v", err)
+	}
+	defer waiter.Close()
+	if err := concurrency.NewMutex(waiter, GetLockKey(jobKey)).TryLock(context.Background()); !errors.Is(err, concurrency.ErrLocked) {
+		return fmt.Errorf("expected held job lock to be locked, got %v", err)
+	}
+	time.Sleep(2 * time.Second)
+	source, err := coord.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{Name: sourceName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get source: %v", err)
+	}
+	if source.Status() != metadata.PENDING {
+		return fmt.Errorf("expected held job to be left to its holder, got status %s", source.Status())
+	}
+
+	if err := holderLock.Unlock(context.Background()); err != nil {
+		return fmt.Errorf("could not unlock job: %v", err)
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		source, err := coord.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{Name: sourceName, Variant: ""})
+		if err != nil {
+			return fmt.Errorf("could not get source: %v", err)
+		}
+		if source.Status() == metadata.READY {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for released job to be reclaimed, status %s", source.Status())
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// Registers a source before the table it reads exists, so that its job fails,
+// and then retries it once the table is created.
+func testRetryFailedJob(addr string) error {
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
+	}
+	defer coord.Close()
+	tableName := createSafeUUID()
+	sourceName := createSafeUUID()
+	if err := createSourceWithProvider(coord.Metadata, postgresConfig.Serialize(), sourceName, tableName); err != nil {
+		return fmt.Errorf("could not register source in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err == nil {
+		return fmt.Errorf("expected source over a missing table to fail")
+	}
+	failed, err := findFailedJob(coord, sourceID)
+	if err != nil {
+		return err
+	}
+	if failed == nil {
+		return fmt.Errorf("expected failed job to be recorded")
+	}
+	if failed.Error == "" || failed.Attempts != 1 {
+		return fmt.Errorf("expected failed job with an error after 1 attempt, got %+v", failed)
+	}
+
+	if err := CreateOriginalPostgresTable(tableName); err != nil {
+		return fmt.Errorf("Could not create non-featureform source table: %v", err)
+	}
+	if err := coord.RetryFailedJob(sourceID); err != nil {
+		return fmt.Errorf("could not retry failed job: %v", err)
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return fmt.Errorf("retried job failed: %v", err)
+	}
+	source, err := coord.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{Name: sourceName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get source: %v", err)
+	}
+	if source.Status() != metadata.READY {
+		return fmt.Errorf("expected retried source to be READY, got %s", source.Status())
+	}
+	if failed, err := findFailedJob(coord, sourceID); err != nil {
+		return err
+	} else if failed != nil {
+		return fmt.Errorf("expected failed job to be cleared once its retry succeeded, got %+v", failed)
+	}
+	if err := coord.RetryFailedJob(sourceID); err == nil {
+		return fmt.Errorf("expected retrying a job that hasn't failed to fail")
+	}
+	return nil
+}
+
+func findFailedJob(coord *Coordinator, resID metadata.ResourceID) (*FailedJob, error) {
+	jobs, err := coord.ListFailedJobs()
+	if err != nil {
+		return nil, fmt.Errorf("could not list failed jobs: %v", err)
+	}
+	for _, job := range jobs {
+		if job.Resource == resID {
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+
+// Materializes a feature, overwrites one of its online values, and checks that
+// materializing it again with MaterializeOptions.Force restores the value.
+func testForceMaterialize(addr string) error {
+	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register copy to online runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.COPY_TO_ONLINE))
+	if err := runner.RegisterFactory(string(runner.MATERIALIZE), runner.MaterializeRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register materialize runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.MATERIALIZE))
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
+	}
+	defer coord.Close()
+	redisConfig := &pc.RedisConfig{Addr: fmt.Sprintf("%s:%s", redisHost, redisPort)}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, originalTableName, ""); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+		return err
+	}
+	if err := coord.runFeatureMaterializeJob(context.Background(), featureID, ""); err == nil {
+		return fmt.Errorf("expected materializing a READY feature without force to fail")
+	}
+
+	p, err := provider.Get(pt.RedisOnline, redisConfig.Serialized())
+	if err != nil {
+		return fmt.Errorf("could not get online provider: %v", err)
+	}
+	onlineStore, err := p.AsOnlineStore()
+	if err != nil {
+		return fmt.Errorf("could not get provider as online store: %v", err)
+	}
+	table, err := onlineStore.GetTable(featureName, "")
+	if err != nil {
+		return err
+	}
+	stale := testOfflineTableValues[0]
+	if err := table.Set(stale.Entity, 100); err != nil {
+		return fmt.Errorf("could not overwrite online value: %v", err)
+	}
+	coord.MaterializeOptions.Force = true
+	if err := coord.runFeatureMaterializeJob(context.Background(), featureID, ""); err != nil {
+		return fmt.Errorf("could not materialize READY feature again: %v", err)
+	}
+	feature, err := coord.Metadata.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: featureName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get feature variant: %v", err)
+	}
+	if feature.Status() != metadata.READY {
+		return fmt.Errorf("expected feature to be READY again, got %s", feature.Status())
+	}
+	value, err := table.Get(stale.Entity)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(value, stale.Value) {
+		return fmt.Errorf("expected %s to be materialized as %v again, got %v", stale.Entity, stale.Value, value)
+	}
+	return nil
+}
+
+func testParallelMaterialize(addr string) error {
+	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register copy to online runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.COPY_TO_ONLINE))
+	if err := runner.RegisterFactory(string(runner.MATERIALIZE), runner.MaterializeRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register materialize runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.MATERIALIZE))
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
+	}
+	defer coord.Close()
+	// One chunk per row, with more workers than there are chunks
+	coord.MaterializeChunkRows = 1
+	coord.MaterializeParallelism = len(testOfflineTableValues) + 3
+	redisConfig := &pc.RedisConfig{Addr: fmt.Sprintf("%s:%s", redisHost, redisPort)}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, originalTableName, ""); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+		return err
+	}
+	feature, err := coord.Metadata.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: featureName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get feature variant: %v", err)
+	}
+	if feature.Status() != metadata.READY {
+		return fmt.Errorf("expected feature to be READY, got %s", feature.Status())
+	}
+	p, err := provider.Get(pt.RedisOnline, redisConfig.Serialized())
+	if err != nil {
+		return fmt.Errorf("could not get online provider: %v", err)
+	}
+	onlineStore, err := p.AsOnlineStore()
+	if err != nil {
+		return fmt.Errorf("could not get provider as online store: %v", err)
+	}
+	table, err := onlineStore.GetTable(featureName, "")
+	if err != nil {
+		return err
+	}
+	for _, record := range testOfflineTableValues {
+		value, err := table.Get(record.Entity)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(value, record.Value) {
+			return fmt.Errorf("expected %s to be materialized as %v, got %v", record.Entity, record.Value, value)
+		}
+	}
+	return nil
+}
+
+func testIncrementalMaterialize(addr string) error {
+	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register copy to online runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.COPY_TO_ONLINE))
+	if err := runner.RegisterFactory(string(runner.MATERIALIZE), runner.MaterializeRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register materialize runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.MATERIALIZE))
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
+	}
+	defer coord.Close()
+	coord.MaterializeOptions.Incremental = true
+	redisConfig := &pc.RedisConfig{Addr: fmt.Sprintf("%s:%s", redisHost, redisPort)}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, originalTableName, ""); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+		return err
+	}
+	watermark, err := coord.getMaterializeWatermark(featureID)
+	if err != nil {
+		return err
+	}
+	if !watermark.Equal(time.UnixMilli(0).UTC()) {
+		return fmt.Errorf("expected the first run to record a watermark of %v, got %v", time.UnixMilli(0).UTC(), watermark)
+	}
+
+	p, err := provider.Get(pt.RedisOnline, redisConfig.Serialized())
+	if err != nil {
+		return fmt.Errorf("could not get online provider: %v", err)
+	}
+	onlineStore, err := p.AsOnlineStore()
+	if err != nil {
+		return fmt.Errorf("could not get provider as online store: %v", err)
+	}
+	table, err := onlineStore.GetTable(featureName, "")
+	if err != nil {
+		return err
+	}
+	// A row that isn't part of the delta keeps whatever the online store has
+	untouched := testOfflineTableValues[1]
+	if err := table.Set(untouched.Entity, 100); err != nil {
+		return fmt.Errorf("could not overwrite online value: %v", err)
+	}
+	appended := []provider.ResourceRecord{
+		{Entity: "a", Value: 6, TS: time.UnixMilli(1).UTC()},
+		{Entity: "f", Value: 7, TS: time.UnixMilli(2).UTC()},
+	}
+	offline, err := provider.Get(pt.PostgresOffline, postgresConfig.Serialize())
+	if err != nil {
+		return err
+	}
+	offlineStore, err := offline.AsOfflineStore()
+	if err != nil {
+		return err
+	}
+	defer offlineStore.Close()
+	originalTable, err := offlineStore.GetResourceTable(provider.ResourceID{Name: originalTableName, Variant: "", Type: provider.Feature})
+	if err != nil {
+		return fmt.Errorf("could not get original table: %v", err)
+	}
+	if err := originalTable.WriteBatch(appended); err != nil {
+		return fmt.Errorf("could not append rows: %v", err)
+	}
+	if err := coord.runFeatureMaterializeJob(context.Background(), featureID, ""); err != nil {
+		return fmt.Errorf("could not materialize feature incrementally: %v", err)
+	}
+	for _, record := range appended {
+		value, err := table.Get(record.Entity)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(value, record.Value) {
+			return fmt.Errorf("expected appended %s to be materialized as %v, got %v", record.Entity, record.Value, value)
+		}
+	}
+	value, err := table.Get(untouched.Entity)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(value, 100) {
+		return fmt.Errorf("expected %s to be skipped by the incremental run, got %v", untouched.Entity, value)
+	}
+	watermark, err = coord.getMaterializeWatermark(featureID)
+	if err != nil {
+		return err
+	}
+	if !watermark.Equal(time.UnixMilli(2).UTC()) {
+		return fmt.Errorf("expected a watermark of %v, got %v", time.UnixMilli(2).UTC(), watermark)
+	}
+	return nil
+}
+
+func testJobMetrics(addr string) error {
+	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register copy to online runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.COPY_TO_ONLINE))
+	if err := runner.RegisterFactory(string(runner.MATERIALIZE), runner.MaterializeRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register materialize runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.MATERIALIZE))
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
+	}
+	defer coord.Close()
+	if err := coord.RegisterMetrics(prometheus.NewRegistry()); err != nil {
+		return fmt.Errorf("could not register metrics: %v", err)
+	}
+	redisConfig := &pc.RedisConfig{Addr: fmt.Sprintf("%s:%s", redisHost, redisPort)}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, originalTableName, ""); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+		return err
+	}
+	read := func(vec *prometheus.CounterVec, resType metadata.ResourceType) float64 {
+		m := &dto.Metric{}
+		vec.WithLabelValues(resType.String()).Write(m)
+		return m.GetCounter().GetValue()
+	}
+	for _, resType := range []metadata.ResourceType{metadata.SOURCE_VARIANT, metadata.FEATURE_VARIANT} {
+		if started := read(coord.metrics.started, resType); started != 1 {
+			return fmt.Errorf("expected 1 %s job to be started, got %v", resType, started)
+		}
+		if succeeded := read(coord.metrics.succeeded, resType); succeeded != 1 {
+			return fmt.Errorf("expected 1 %s job to succeed, got %v", resType, succeeded)
+		}
+	}
+	m := &dto.Metric{}
+	coord.metrics.duration.WithLabelValues(materializeJobType).(prometheus.Histogram).Write(m)
+	if count := m.GetHistogram().GetSampleCount(); count != 1 {
+		return fmt.Errorf("expected 1 materialize duration, got %d", count)
+	}
+	return nil
+}
+
+func testDeterministicPrimaryTableName(addr string) error {
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator")
+	}
+	defer coord.Close()
+	// Metadata outlives a test run, so the sequence is scoped to this run
+	run := createSafeUUID()
+	names := provider.NewSequenceNameGenerator(run)
+	tableName := names.NewName()
+	sourceName := names.NewName()
+	if err := CreateOriginalPostgresTable(tableName); err != nil {
+		return fmt.Errorf("Could not create non-featureform source table: %v", err)
+	}
+	serialPGConfig := postgresConfig.Serialize()
+	if err := createSourceWithProvider(coord.Metadata, pc.SerializedConfig(serialPGConfig), sourceName, tableName); err != nil {
+		return fmt.Errorf("could not register source in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	myProvider, err := provider.Get(pt.PostgresOffline, serialPGConfig)
+	if err != nil {
+		return fmt.Errorf("could not get provider: %v", err)
+	}
+	myOffline, err := myProvider.AsOfflineStore()
+	if err != nil {
+		return fmt.Errorf("could not get provider as offline store: %v", err)
+	}
+	primaryTable, err := myOffline.GetPrimaryTable(provider.ResourceID{Name: sourceName, Variant: "", Type: provider.Primary})
+	if err != nil {
+		return fmt.Errorf("Coordinator did not create primary table: %v", err)
+	}
+	if expected := fmt.Sprintf("featureform_primary__%s_2__", run); primaryTable.GetName() != expected {
+		return fmt.Errorf("expected primary table %s, got %s", expected, primaryTable.GetName())
+	}
+	return nil
+}
+
+func testPrimaryTableOverwriteGuard(addr string) error {
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator")
+	}
+	defer coord.Close()
+	tableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(tableName); err != nil {
+		return fmt.Errorf("Could not create non-featureform source table: %v", err)
+	}
+	serialPGConfig := postgresConfig.Serialize()
+	sourceName := createSafeUUID()
+	if err := createSourceWithProvider(coord.Metadata, pc.SerializedConfig(serialPGConfig), sourceName, tableName); err != nil {
+		return fmt.Errorf("could not register source in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	if err := coord.runRegisterSourceJob(context.Background(), sourceID, ""); !errors.Is(err, ErrTableExists) {
+		return fmt.Errorf("expected re-registering a primary table with data to fail with ErrTableExists, got %v", err)
+	}
+	coord.OverwriteSourceTables = true
+	if err := coord.runRegisterSourceJob(context.Background(), sourceID, ""); err != nil {
+		return fmt.Errorf("could not overwrite primary table: %v", err)
+	}
+	myProvider, err := provider.Get(pt.PostgresOffline, serialPGConfig)
+	if err != nil {
+		return fmt.Errorf("could not get provider: %v", err)
+	}
+	myOffline, err := myProvider.AsOfflineStore()
+	if err != nil {
+		return fmt.Errorf("could not get provider as offline store: %v", err)
+	}
+	primaryTable, err := myOffline.GetPrimaryTable(provider.ResourceID{Name: sourceName, Variant: "", Type: provider.Primary})
+	if err != nil {
+		return fmt.Errorf("could not get overwritten primary table: %v", err)
+	}
+	numRows, err := primaryTable.NumRows()
+	if err != nil {
+		return fmt.Errorf("could not get number of rows: %v", err)
+	}
+	if int(numRows) != len(testOfflineTableValues) {
+		return fmt.Errorf("expected overwritten primary table to have %d rows, got %d", len(testOfflineTableValues), numRows)
+	}
+	return nil
+}
+
+func testTransformationOverwriteGuard(addr string) error {
+	if err := runner.RegisterFactory(string(runner.CREATE_TRANSFORMATION), runner.CreateTransformationRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register transformation runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.CREATE_TRANSFORMATION))
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator")
+	}
+	defer coord.Close()
+	tableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(tableName); err != nil {
+		return fmt.Errorf("Could not create non-featureform source table: %v", err)
+	}
+	serialPGConfig := postgresConfig.Serialize()
+	sourceName := strings.Replace(createSafeUUID(), "-", "", -1)
+	if err := createSourceWithProvider(coord.Metadata, pc.SerializedConfig(serialPGConfig), sourceName, tableName); err != nil {
+		return fmt.Errorf("could not register source in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	transformationQuery := fmt.Sprintf("SELECT * FROM {{%s.}}", sourceName)
+	transformationName := strings.Replace(createSafeUUID(), "-", "", -1)
+	sourceNameVariants := []metadata.NameVariant{{Name: sourceName, Variant: ""}}
+	if err := createTransformationWithProvider(coord.Metadata, serialPGConfig, transformationName, transformationQuery, sourceNameVariants, ""); err != nil {
+		return err
+	}
+	transformationID := metadata.ResourceID{Name: transformationName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(transformationID)); err != nil {
+		return err
+	}
+	// READY transformations aren't run again, so the job is made to look unfinished
+	if err := coord.setStatus(transformationID, metadata.PENDING, ""); err != nil {
+		return fmt.Errorf("could not reset transformation status: %v", err)
+	}
+	if err := coord.runRegisterSourceJob(context.Background(), transformationID, ""); !errors.Is(err, ErrTableExists) {
+		return fmt.Errorf("expected re-running a transformation with data to fail with ErrTableExists, got %v", err)
+	}
+	coord.OverwriteSourceTables = true
+	if err := coord.setStatus(transformationID, metadata.PENDING, ""); err != nil {
+		return fmt.Errorf("could not reset transformation status: %v", err)
+	}
+	if err := coord.runRegisterSourceJob(context.Background(), transformationID, ""); err != nil {
+		return fmt.Errorf("could not overwrite transformation table: %v", err)
+	}
+	myProvider, err := provider.Get(pt.PostgresOffline, serialPGConfig)
+	if err != nil {
+		return fmt.Errorf("could not get provider: %v", err)
+	}
+	myOffline, err := myProvider.AsOfflineStore()
+	if err != nil {
+		return fmt.Errorf("could not get provider as offline store: %v", err)
+	}
+	table, err := myOffline.GetTransformationTable(provider.ResourceID{Name: transformationName, Variant: "", Type: provider.Transformation})
+	if err != nil {
+		return fmt.Errorf("could not get overwritten transformation table: %v", err)
+	}
+	numRows, err := table.NumRows()
+	if err != nil {
+		return fmt.Errorf("could not get number of rows: %v", err)
+	}
+	if int(numRows) != len(testOfflineTableValues) {
+		return fmt.Errorf("expected overwritten transformation table to have %d rows, got %d", len(testOfflineTableValues), numRows)
+	}
+	return nil
+}
+
+func testSourceSchemaCompatibility(addr string) error {
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator")
+	}
+	defer coord.Close()
+	redisConfig := &pc.RedisConfig{Addr: fmt.Sprintf("%s:%s", redisHost, redisPort)}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return err
+	}
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, originalTableName, ""); err != nil {
+		return fmt.Errorf("could not create feature in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return fmt.Errorf("could not register source: %v", err)
+	}
+	source, err := coord.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{Name: sourceName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get source: %v", err)
+	}
+	// The new variant's table no longer has the value column the feature reads
+	reducedTableName := createSafeUUID()
+	url := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", postgresConfig.Username, postgresConfig.Password, postgresConfig.Host, postgresConfig.Port, postgresConfig.Database)
+	conn, err := pgxpool.Connect(context.Background(), url)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	createTableQuery := fmt.Sprintf("CREATE TABLE %s (entity VARCHAR, ts TIMESTAMPTZ)", sanitize(reducedTableName))
+	if _, err := conn.Exec(context.Background(), createTableQuery); err != nil {
+		return err
+	}
+	defs := []metadata.ResourceDef{
+		metadata.SourceDef{
+			Name:     sourceName,
+			Variant:  "reduced",
+			Owner:    source.Owner(),
+			Provider: source.Provider(),
+			Definition: metadata.PrimaryDataSource{
+				Location: metadata.SQLTable{
+					Name: reducedTableName,
+				},
+			},
+		},
+	}
+	if err := coord.Metadata.CreateAll(context.Background(), defs); err != nil {
+		return fmt.Errorf("could not register new source variant: %v", err)
+	}
+	reducedID := metadata.ResourceID{Name: sourceName, Variant: "reduced", Type: metadata.SOURCE_VARIANT}
+	err = coord.ExecuteJob(metadata.GetJobKey(reducedID))
+	var compatErr IncompatibleSourceSchemaError
+	if !errors.As(err, &compatErr) {
+		return fmt.Errorf("expected incompatible schema error, got %v", err)
+	}
+	if !strings.Contains(compatErr.Error(), "value (used by feature "+featureName) {
+		return fmt.Errorf("expected error to name the value column, got %v", compatErr)
+	}
+	p, err := provider.Get(pt.PostgresOffline, postgresConfig.Serialize())
+	if err != nil {
+		return fmt.Errorf("could not get provider: %v", err)
+	}
+	store, err := p.AsOfflineStore()
+	if err != nil {
+		return fmt.Errorf("could not get offline store: %v", err)
+	}
+	// The check fails before a primary table is created for the new variant
+	reducedPrimaryID := provider.ResourceID{Name: sourceName, Variant: "reduced", Type: provider.Primary}
+	if _, err := store.GetPrimaryTable(reducedPrimaryID); err == nil {
+		return fmt.Errorf("expected no primary table for the incompatible variant")
+	}
+	return nil
+}
+
+func testListVariants(addr string) error {
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator")
+	}
+	defer coord.Close()
+	sourceName := createSafeUUID()
+	if err := createSourceWithProvider(coord.Metadata, postgresConfig.Serialize(), sourceName, createSafeUUID()); err != nil {
+		return fmt.Errorf("could not create source: %v", err)
+	}
+	source, err := coord.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{Name: sourceName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get source: %v", err)
+	}
+	defs := []metadata.ResourceDef{
+		metadata.SourceDef{
+			Name:     sourceName,
+			Variant:  "second",
+			Owner:    source.Owner(),
+			Provider: source.Provider(),
+			Definition: metadata.PrimaryDataSource{
+				Location: metadata.SQLTable{
+					Name: createSafeUUID(),
+				},
+			},
+		},
+	}
+	if err := coord.Metadata.CreateAll(context.Background(), defs); err != nil {
+		return fmt.Errorf("could not register second source variant: %v", err)
+	}
+	firstID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := coord.setStatus(firstID, metadata.READY, ""); err != nil {
+		return fmt.Errorf("could not set first variant ready: %v", err)
+	}
+	secondID := metadata.ResourceID{Name: sourceName, Variant: "second", Type: metadata.SOURCE_VARIANT}
+	if err := coord.setStatus(secondID, metadata.FAILED, "failed for test"); err != nil {
+		return fmt.Errorf("could not set second variant failed: %v", err)
+	}
+	variants, err := coord.ListVariants(sourceName, metadata.SOURCE)
+	if err != nil {
+		return fmt.Errorf("could not list variants: %v", err)
+	}
+	statuses := make(map[string]metadata.ResourceStatus)
+	for _, variant := range variants {
+		statuses[variant.Variant] = variant.Status
+	}
+	expected := map[string]metadata.ResourceStatus{"": metadata.READY, "second": metadata.FAILED}
+	if !reflect.DeepEqual(expected, statuses) {
+		return fmt.Errorf("expected variant statuses %v, got %v", expected, statuses)
+	}
+	if _, err := coord.ListVariants(sourceName, metadata.PROVIDER); err == nil {
+		return fmt.Errorf("expected listing variants of a provider to fail")
+	}
+	return nil
+}
+
+func testRegisterPrimaryTableFromSource(addr string) error {
+	logger := zap.NewExample().Sugar()
+	client, err := metadata.NewClient(addr, logger)
+	if err != nil {
+		return fmt.Errorf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	etcdConnect := fmt.Sprintf("%s:%s", etcdHost, etcdPort)
+	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{etcdConnect}})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	tableName := createSafeUUID()
+	serialPGConfig := postgresConfig.Serialize()
+	myProvider, err := provider.Get(pt.PostgresOffline, serialPGConfig)
+	if err != nil {
+		return fmt.Errorf("could not get provider: %v", err)
+	}
+	myOffline, err := myProvider.AsOfflineStore()
+	if err != nil {
+		return fmt.Errorf("could not get provider as offline store: %v", err)
+	}
+	if err := CreateOriginalPostgresTable(tableName); err != nil {
+		return fmt.Errorf("Could not create non-featureform source table: %v", err)
+	}
+	sourceName := createSafeUUID()
+	if err := createSourceWithProvider(client, pc.SerializedConfig(serialPGConfig), sourceName, tableName); err != nil {
+		return fmt.Errorf("could not register source in metadata: %v", err)
+	}
+	sourceCreated, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: sourceName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get source: %v", err)
+	}
+	if sourceCreated.Status() != metadata.CREATED {
+		return fmt.Errorf("Source not set to created with no coordinator running")
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	memJobSpawner := MemoryJobSpawner{}
+	coord, err := NewCoordinator(client, logger, cli, &memJobSpawner)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator")
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	startWaitDelete := time.Now()
+	elapsed := time.Since(startWaitDelete)
+	for has, _ := coord.hasJob(sourceID); has && elapsed < time.Duration(10)*time.Second; has, _ = coord.hasJob(sourceID) {
+		time.Sleep(1 * time.Second)
+		elapsed = time.Since(startWaitDelete)
+		fmt.Printf("waiting for job %v to be deleted\n", sourceID)
+	}
+	if elapsed >= time.Duration(10)*time.Second {
+		return fmt.Errorf("timed out waiting for job to delete")
+	}
+	sourceComplete, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: sourceName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get source variant")
+	}
+	if metadata.READY != sourceComplete.Status() {
+		return fmt.Errorf("source variant not set to ready once job completes")
+	}
+	providerSourceID := provider.ResourceID{Name: sourceName, Variant: "", Type: provider.Primary}
+	primaryTable, err := myOffline.GetPrimaryTable(providerSourceID)
+	if err != nil {
+		return fmt.Errorf("Coordinator did not create primary table")
+	}
+	primaryTableName, err := provider.GetPrimaryTableName(providerSourceID)
+	if err != nil {
+		return fmt.Errorf("invalid table name: %v", err)
+	}
+	if primaryTable.GetName() != primaryTableName {
+		return fmt.Errorf("Primary table did not copy name")
+	}
+	numRows, err := primaryTable.NumRows()
+	if err != nil {
+		return fmt.Errorf("Could not get num rows from primary table")
+	}
+	if int(numRows) != len(testOfflineTableValues) {
+		return fmt.Errorf("primary table did not copy correct number of rows")
+	}
+	primaryTableIterator, err := primaryTable.IterateSegment(int64(len(testOfflineTableValues)))
+	if err != nil {
+		return err
+	}
+	i := 0
+	for ; primaryTableIterator.Next(); i++ {
+		if primaryTableIterator.Err() != nil {
+			return err
+		}
+		primaryTableRow := primaryTableIterator.Values()
+		values := reflect.ValueOf(testOfflineTableValues[i])
+		for j := 0; j < values.NumField(); j++ {
+			if primaryTableRow[j] != values.Field(j).Interface() {
+				return fmt.Errorf("Primary table value does not match original value")
+			}
+		}
+	}
+	if i != len(testOfflineTableValues) {
+		return fmt.Errorf("primary table did not copy all rows")
+	}
+	return nil
+}
+
+// testMixedCaseTransformationToken runs a transformation whose query names its
+// source in a different case than it was registered with, which Postgres
+// doesn't distinguish
+func testMixedCaseTransformationToken(addr string) error {
+	if err := runner.RegisterFactory(string(runner.CREATE_TRANSFORMATION), runner.CreateTransformationRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register transformation runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.CREATE_TRANSFORMATION))
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator")
+	}
+	defer coord.Close()
+	tableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(tableName); err != nil {
+		return fmt.Errorf("Could not create non-featureform source table: %v", err)
+	}
+	serialPGConfig := postgresConfig.Serialize()
+	sourceName := "Mixed" + strings.Replace(createSafeUUID(), "-", "", -1)
+	if err := createSourceWithProvider(coord.Metadata, pc.SerializedConfig(serialPGConfig), sourceName, tableName); err != nil {
+		return fmt.Errorf("could not register source in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	transformationQuery := fmt.Sprintf("SELECT * FROM {{%s.}}", strings.ToLower(sourceName))
+	transformationName := strings.Replace(createSafeUUID(), "-", "", -1)
+	sourceNameVariants := []metadata.NameVariant{{Name: sourceName, Variant: ""}}
+	if err := createTransformationWithProvider(coord.Metadata, serialPGConfig, transformationName, transformationQuery, sourceNameVariants, ""); err != nil {
+		return err
+	}
+	transformationID := metadata.ResourceID{Name: transformationName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(transformationID)); err != nil {
+		return err
+	}
+	transformation, err := coord.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{Name: transformationName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get transformation: %v", err)
+	}
+	if transformation.Status() != metadata.READY {
+		return fmt.Errorf("expected transformation to be READY, got %s: %s", transformation.Status(), transformation.Error())
+	}
+	p, err := provider.Get(pt.PostgresOffline, serialPGConfig)
+	if err != nil {
+		return fmt.Errorf("could not get provider: %v", err)
+	}
+	store, err := p.AsOfflineStore()
+	if err != nil {
+		return fmt.Errorf("could not get provider as offline store: %v", err)
+	}
+	table, err := store.GetTransformationTable(provider.ResourceID{Name: transformationName, Variant: "", Type: provider.Transformation})
+	if err != nil {
+		return fmt.Errorf("could not get transformation table: %v", err)
+	}
+	numRows, err := table.NumRows()
+	if err != nil {
+		return fmt.Errorf("could not get number of rows: %v", err)
+	}
+	if int(numRows) != len(testOfflineTableValues) {
+		return fmt.Errorf("expected %d rows in transformation table, got %d", len(testOfflineTableValues), numRows)
+	}
+	return nil
+}
+
+func testTransformationQueryFailureStatus(addr string) error {
+	if err := runner.RegisterFactory(string(runner.CREATE_TRANSFORMATION), runner.CreateTransformationRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register transformation runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.CREATE_TRANSFORMATION))
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator")
+	}
+	defer coord.Close()
+	tableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(tableName); err != nil {
+		return fmt.Errorf("Could not create non-featureform source table: %v", err)
+	}
+	serialPGConfig := postgresConfig.Serialize()
+	sourceName := strings.Replace(createSafeUUID(), "-", "", -1)
+	if err := createSourceWithProvider(coord.Metadata, pc.SerializedConfig(serialPGConfig), sourceName, tableName); err != nil {
+		return fmt.Errorf("could not register source in metadata: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	// The source exists, so the query is rendered before the database rejects it
+	transformationQuery := fmt.Sprintf("SELECT no_such_column FROM {{%s.}}", sourceName)
+	transformationName := strings.Replace(createSafeUUID(), "-", "", -1)
+	sourceNameVariants := []metadata.NameVariant{{Name: sourceName, Variant: ""}}
+	if err := createTransformationWithProvider(coord.Metadata, serialPGConfig, transformationName, transformationQuery, sourceNameVariants, ""); err != nil {
+		return err
+	}
+	transformationID := metadata.ResourceID{Name: transformationName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(transformationID)); err == nil {
+		return fmt.Errorf("expected transformation selecting a missing column to fail")
+	}
+	transformation, err := coord.Metadata.GetSourceVariant(context.Background(), metadata.NameVariant{Name: transformationName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get transformation: %v", err)
+	}
+	if transformation.Status() != metadata.FAILED {
+		return fmt.Errorf("expected transformation to be FAILED, got %s", transformation.Status())
+	}
+	message := transformation.Error()
+	if !strings.Contains(message, "\nquery: SELECT no_such_column FROM ") || strings.Contains(message, "{{") {
+		return fmt.Errorf("expected status message to contain the rendered query, got %s", message)
+	}
+	return nil
+}
+
+func testRegisterTransformationFromSource(addr string) error {
+	if err := runner.RegisterFactory(string(runner.CREATE_TRANSFORMATION), runner.CreateTransformationRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
+	}
+	defer runner.UnregisterFactory(string(runner.CREATE_TRANSFORMATION))
+	logger := zap.NewExample().Sugar()
+	client, err := metadata.NewClient(addr, logger)
+	if err != nil {
+		return fmt.Errorf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	etcdConnect := fmt.Sprintf("%s:%s", etcdHost, etcdPort)
+	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{etcdConnect}})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	tableName := createSafeUUID()
+	serialPGConfig := postgresConfig.Serialize()
+	myProvider, err := provider.Get(pt.PostgresOffline, serialPGConfig)
+	if err != nil {
+		return fmt.Errorf("could not get provider: %v", err)
+	}
+	myOffline, err := myProvider.AsOfflineStore()
+	if err != nil {
+		return fmt.Errorf("could not get provider as offline store: %v", err)
+	}
+	if err := CreateOriginalPostgresTable(tableName); err != nil {
+		return fmt.Errorf("Could not create non-featureform source table: %v", err)
+	}
+	sourceName := strings.Replace(createSafeUUID(), "-", "", -1)
+	if err := createSourceWithProvider(client, pc.SerializedConfig(serialPGConfig), sourceName, tableName); err != nil {
+		return fmt.Errorf("could not register source in metadata: %v", err)
+	}
+	sourceCreated, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: sourceName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get source: %v", err)
+	}
+	if sourceCreated.Status() != metadata.CREATED {
+		return fmt.Errorf("Source not set to created with no coordinator running")
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	memJobSpawner := MemoryJobSpawner{}
+	coord, err := NewCoordinator(client, logger, cli, &memJobSpawner)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator")
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return err
+	}
+	sourceComplete, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: sourceName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get source variant")
+	}
+	if metadata.READY != sourceComplete.Status() {
+		return fmt.Errorf("source variant not set to ready once job completes")
+	}
+	transformationQuery := fmt.Sprintf("SELECT * FROM {{%s.}}", sourceName)
+	transformationName := strings.Replace(createSafeUUID(), "-", "", -1)
+	transformationID := metadata.ResourceID{Name: transformationName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	sourceNameVariants := []metadata.NameVariant{{Name: sourceName, Variant: ""}}
+	if err := createTransformationWithProvider(client, serialPGConfig, transformationName, transformationQuery, sourceNameVariants, ""); err != nil {
+		return err
+	}
+	transformationCreated, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: transformationName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get transformation: %v", err)
+	}
+	if transformationCreated.Status() != metadata.CREATED {
+		return fmt.Errorf("Transformation not set to created with no coordinator running")
+	}
+	if err := coord.ExecuteJob(metadata.GetJobKey(transformationID)); err != nil {
+		return err
+	}
+	transformationComplete, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: transformationName, Variant: ""})
+	if err != nil {
+		return fmt.Errorf("could not get source variant")
+	}
+	if metadata.READY != transformationComplete.Status() {
+		return fmt.Errorf("transformation variant not set to ready once job completes")
+	}
+	providerTransformationID := provider.ResourceID{Name: transformationName, Variant: "", Type: provider.Transformation}
+	transformationTable, err := myOffline.GetTransformationTable(providerTransformationID)
+	if err != nil {
+		return err
+	}
+	transformationTableName, err := provider.GetPrimaryTableName(providerTransformationID)
+	if err != nil {
+		return fmt.Errorf("invalid transformation table name: %v", err)
+	}
+	if transformationTable.GetName() != transformationTableName {
+		return fmt.Errorf("Transformation table did not copy name")
+	}
+	numRows, err := transformationTable.NumRows()
+	if err != nil {
+		return fmt.Errorf("Could not get num rows from transformation table")
+	}
+	if int(numRows) != len(testOfflineTableValues) {
+		return fmt.Errorf("transformation table did not copy correct number of rows")
+	}
+	for _, id := range []metadata.ResourceID{sourceID, transformationID} {
+		source, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: id.Name, Variant: id.Variant})
+		if err != nil {
+			return fmt.Errorf("could not get source variant %s: %v", id.Name, err)
+		}
+		stats, has := source.Stats()
+		if !has {
+			return fmt.Errorf("stats of %s were not recorded", id.Name)
+		}
+		if int(stats.RowCount) != len(testOfflineTableValues) {
+			return fmt.Errorf("expected %s to record %d rows, got %d", id.Name, len(testOfflineTableValues), stats.RowCount)
+		}
+		// Postgres transformations are tables, so they take up space of their own
+		if id == transformationID && stats.SizeBytes <= 0 {
+			return fmt.Errorf("expected %s to record its size, got %d", id.Name, stats.SizeBytes)
+		}
+	}
+	transformationIterator, err := transformationTable.IterateSegment(int64(len(testOfflineTableValues)))
+	if err != nil {
 		return err
 	}
-	startWaitDelete := time.Now()
-	elapsed := time.Since(startWaitDelete)
-	for has, _ := coord.hasJob(tsID); has && elapsed < time.Duration(10)*time.Second; has, _ = coord.hasJob(tsID) {
-		time.Sleep(1 * time.Second)
-		elapsed = time.Since(startWaitDelete)
-		fmt.Printf("waiting for job %v to be deleted\n", tsID)
+	i := 0
+	for ; transformationIterator.Next(); i++ {
+		if transformationIterator.Err() != nil {
+			return err
+		}
+		transformationTableRow := transformationIterator.Values()
+		values := reflect.ValueOf(testOfflineTableValues[i])
+		for j := 0; j < values.NumField(); j++ {
+			if transformationTableRow[j] != values.Field(j).Interface() {
+				return fmt.Errorf("Transformation table value does not match original value")
+			}
+		}
 	}
-	if elapsed >= time.Duration(10)*time.Second {
-		return fmt.Errorf("timed out waiting for job to delete")
+	if i != len(testOfflineTableValues) {
+		return fmt.Errorf("transformation table did not copy all rows")
 	}
-	ts_complete, err := client.GetTrainingSetVariant(ctx, metadata.NameVariant{Name: tsName, Variant: ""})
+
+	joinTransformationQuery := fmt.Sprintf("SELECT {{%s.}}.entity, {{%s.}}.value, {{%s.}}.ts FROM {{%s.}} INNER JOIN {{%s.}} ON {{%s.}}.entity = {{%s.}}.entity", sourceName, sourceName, sourceName, sourceName, transformationName, sourceName, transformationName)
+	joinTransformationName := strings.Replace(createSafeUUID(), "-", "", -1)
+	joinTransformationID := metadata.ResourceID{Name: joinTransformationName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	joinSourceNameVariants := []metadata.NameVariant{{Name: sourceName, Variant: ""}, {Name: transformationName, Variant: ""}}
+	if err := createTransformationWithProvider(client, serialPGConfig, joinTransformationName, joinTransformationQuery, joinSourceNameVariants, ""); err != nil {
+		return err
+	}
+	joinTransformationCreated, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: joinTransformationName, Variant: ""})
 	if err != nil {
-		return fmt.Errorf("could not get training set variant")
+		return fmt.Errorf("could not get transformation: %v", err)
 	}
-	if metadata.READY != ts_complete.Status() {
-		return fmt.Errorf("Training set not set to ready once job completes")
+	if joinTransformationCreated.Status() != metadata.CREATED {
+		return fmt.Errorf("Transformation not set to created with no coordinator running")
 	}
-	if err := coord.runTrainingSetJob(tsID, ""); err == nil {
-		return fmt.Errorf("run training set job did not trigger error when tried to create training set that already exists")
+	if err := coord.ExecuteJob(metadata.GetJobKey(joinTransformationID)); err != nil {
+		return err
 	}
-	providerTsID := provider.ResourceID{Name: tsID.Name, Variant: tsID.Variant, Type: provider.TrainingSet}
-	tsIterator, err := my_offline.GetTrainingSet(providerTsID)
+	joinTransformationComplete, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: joinTransformationName, Variant: ""})
 	if err != nil {
-		return fmt.Errorf("Coordinator did not create training set")
+		return fmt.Errorf("could not get source variant")
 	}
-
-	for i := 0; tsIterator.Next(); i++ {
-		retrievedFeatures := tsIterator.Features()
-		retrievedLabel := tsIterator.Label()
-		if !reflect.DeepEqual(retrievedFeatures[0], testOfflineTableValues[i].Value) {
-			return fmt.Errorf("Features not copied into training set")
+	if metadata.READY != joinTransformationComplete.Status() {
+		return fmt.Errorf("transformation variant not set to ready once job completes")
+	}
+	providerJoinTransformationID := provider.ResourceID{Name: transformationName, Variant: "", Type: provider.Transformation}
+	joinTransformationTable, err := myOffline.GetTransformationTable(providerJoinTransformationID)
+	if err != nil {
+		return err
+	}
+	transformationJoinName, err := provider.GetPrimaryTableName(providerJoinTransformationID)
+	if err != nil {
+		return fmt.Errorf("invalid transformation table name: %v", err)
+	}
+	if joinTransformationTable.GetName() != transformationJoinName {
+		return fmt.Errorf("Transformation table did not copy name")
+	}
+	numRows, err = joinTransformationTable.NumRows()
+	if err != nil {
+		return fmt.Errorf("Could not get num rows from transformation table")
+	}
+	if int(numRows) != len(testOfflineTableValues) {
+		return fmt.Errorf("transformation table did not copy correct number of rows")
+	}
+	joinTransformationIterator, err := joinTransformationTable.IterateSegment(int64(len(testOfflineTableValues)))
+	if err != nil {
+		return err
+	}
+	i = 0
+	for ; joinTransformationIterator.Next(); i++ {
+		if joinTransformationIterator.Err() != nil {
+			return err
 		}
-		if !reflect.DeepEqual(retrievedLabel, testOfflineTableValues[i].Value) {
-			return fmt.Errorf("Label not copied into training set")
+		joinTransformationTableRow := joinTransformationIterator.Values()
+		values := reflect.ValueOf(testOfflineTableValues[i])
+		for j := 0; j < values.NumField(); j++ {
+			if joinTransformationTableRow[j] != values.Field(j).Interface() {
+				return fmt.Errorf("Transformation table value does not match original value")
+			}
 		}
+	}
+	if i != len(testOfflineTableValues) {
+		return fmt.Errorf("transformation table did not copy all rows")
+	}
+
+	return nil
+}
+
+func TestGetSourceMapping(t *testing.T) {
+	templateString := "Some example text {{name1.variant1}} and more {{name2.variant2}}"
+	replacements := map[string]string{"name1.variant1": "replacement1", "name2.variant2": "replacement2"}
+	expectedSourceMap := []provider.SourceMapping{
+		provider.SourceMapping{
+			Template: "\"replacement1\"",
+			Source:   "replacement1",
+		},
+		provider.SourceMapping{
+			Template: "\"replacement2\"",
+			Source:   "replacement2",
+		},
+	}
+
+	sourceMap, err := getSourceMapping(templateString, replacements, provider.CaseSensitiveIdentifiers, false)
+	if err != nil {
+		t.Fatalf("Could not retrieve the source mapping: %v", err)
+	}
+
+	if !reflect.DeepEqual(sourceMap, expectedSourceMap) {
+		t.Fatalf("source mapping did not generate the SourceMapping correctly. Expected %v, got %v", sourceMap, expectedSourceMap)
+	}
+}
+
+func TestGetSourceMappingEscapes(t *testing.T) {
+	templateString := `SELECT '{{{{"a": 1}}' FROM {{name1.variant1}} JOIN {{unknown.variant}}`
+	replacements := map[string]string{"name1.variant1": "replacement1"}
+	if _, err := getSourceMapping(templateString, replacements, provider.CaseSensitiveIdentifiers, false); err == nil {
+		t.Fatalf("getSourceMapping did not catch unmatched token in %s", templateString)
+	}
+	sourceMap, err := getSourceMapping(templateString, replacements, provider.CaseSensitiveIdentifiers, true)
+	if err != nil {
+		t.Fatalf("Could not retrieve the source mapping: %v", err)
+	}
+	expectedSourceMap := []provider.SourceMapping{{Template: "\"replacement1\"", Source: "replacement1"}}
+	if !reflect.DeepEqual(sourceMap, expectedSourceMap) {
+		t.Fatalf("source mapping did not skip escaped and unmatched tokens. Expected %v, got %v", expectedSourceMap, sourceMap)
+	}
+}
+
+func TestGetSourceMappingMixedCase(t *testing.T) {
+	templateString := "SELECT * FROM {{transactions.Daily}}"
+	replacements := map[string]string{"Transactions.Daily": "featureform_primary__Transactions__Daily"}
+	if _, err := getSourceMapping(templateString, replacements, provider.CaseSensitiveIdentifiers, false); err == nil {
+		t.Fatalf("expected a token in a different case not to match on a case sensitive store")
+	}
+	sourceMap, err := getSourceMapping(templateString, replacements, provider.LowerCaseIdentifiers, false)
+	if err != nil {
+		t.Fatalf("Could not retrieve the source mapping: %v", err)
+	}
+	expectedSourceMap := []provider.SourceMapping{{Template: "\"featureform_primary__Transactions__Daily\"", Source: "featureform_primary__Transactions__Daily"}}
+	if !reflect.DeepEqual(sourceMap, expectedSourceMap) {
+		t.Fatalf("source mapping did not match the token ignoring case. Expected %v, got %v", expectedSourceMap, sourceMap)
+	}
+}
+
+func TestGetSourceMappingError(t *testing.T) {
+	templateString := "Some example text {{name1.variant1}} and more {{name2.variant2}}"
+	wrongReplacements := map[string]string{"name1.variant1": "replacement1", "name3.variant3": "replacement2"}
+	_, err := getSourceMapping(templateString, wrongReplacements, provider.CaseSensitiveIdentifiers, false)
+	if err == nil {
+		t.Fatalf("getSourceMapping did not catch error: templateString {%v} and wrongReplacement {%v}", templateString, wrongReplacements)
+	}
+}
+
+func TestGetOrderedSourceMappings(t *testing.T) {
+	type testCase struct {
+		name              string
+		sources           []metadata.NameVariant
+		sourceMap         map[string]string
+		expectedSourceMap []provider.SourceMapping
+		expectError       bool
+	}
+
+	testCases := []testCase{
+		{
+			name: "test ordered source mappings",
+			sources: []metadata.NameVariant{
+				{Name: "name1", Variant: "variant1"},
+				{Name: "name2", Variant: "variant2"},
+			},
+			sourceMap: map[string]string{
+				"name1.variant1": "tableA",
+				"name2.variant2": "tableB",
+			},
+			expectedSourceMap: []provider.SourceMapping{
+				{
+					Template: "name1.variant1",
+					Source:   "tableA",
+				},
+				{
+					Template: "name2.variant2",
+					Source:   "tableB",
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "test unordered source mappings",
+			sources: []metadata.NameVariant{
+				{Name: "name1", Variant: "variant1"},
+				{Name: "name2", Variant: "variant2"},
+				{Name: "name3", Variant: "variant3"},
+				{Name: "name4", Variant: "variant4"},
+			},
+			sourceMap: map[string]string{
+				"name2.variant2": "tableB",
+				"name4.variant4": "tableD",
+				"name1.variant1": "tableA",
+				"name3.variant3": "tableC",
+			},
+			expectedSourceMap: []provider.SourceMapping{
+				{
+					Template: "name1.variant1",
+					Source:   "tableA",
+				},
+				{
+					Template: "name2.variant2",
+					Source:   "tableB",
+				},
+				{
+					Template: "name3.variant3",
+					Source:   "tableC",
+				},
+				{
+					Template: "name4.variant4",
+					Source:   "tableD",
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "test missing key in source map",
+			sources: []metadata.NameVariant{
+				{Name: "name1", Variant: "variant1"},
+				{Name: "name2", Variant: "variant2"},
+			},
+			sourceMap: map[string]string{
+				"name1.variant1": "tableA",
+			},
+			expectedSourceMap: nil,
+			expectError:       true,
+		},
+		{
+			name: "test duplicate source",
+			sources: []metadata.NameVariant{
+				{Name: "name1", Variant: "variant1"},
+				{Name: "name2", Variant: "variant2"},
+				{Name: "name1", Variant: "variant1"},
+			},
+			sourceMap: map[string]string{
+				"name1.variant1": "tableA",
+				"name2.variant2": "tableB",
+			},
+			expectedSourceMap: nil,
+			expectError:       true,
+		},
+	}
 
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sourceMap, err := getOrderedSourceMappings(tc.sources, tc.sourceMap)
+			if tc.expectError && err == nil {
+				t.Fatalf("Expected error, but did not get one")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(sourceMap, tc.expectedSourceMap) {
+				t.Fatalf("source mapping did not generate the SourceMapping correctly. Expected %v, got %v", sourceMap, tc.expectedSourceMap)
+			}
+		})
 	}
-	return nil
 }
 
-func testCoordinatorMaterializeFeature(addr string) error {
+func testJobRowQuota(addr string) error {
 	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
 		return fmt.Errorf("Failed to register training set runner factory: %v", err)
 	}
@@ -1370,80 +4579,91 @@ func testCoordinatorMaterializeFeature(addr string) error {
 		return fmt.Errorf("Failed to register training set runner factory: %v", err)
 	}
 	defer runner.UnregisterFactory(string(runner.MATERIALIZE))
-	logger := zap.NewExample().Sugar()
-	client, err := metadata.NewClient(addr, logger)
+	coord, err := createNewCoordinator(addr)
 	if err != nil {
-		return fmt.Errorf("Failed to connect: %v", err)
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
 	}
-	defer client.Close()
-	etcdConnect := fmt.Sprintf("%s:%s", etcdHost, etcdPort)
-	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{etcdConnect}})
-	if err != nil {
+	defer coord.Metadata.Close()
+	defer coord.EtcdClient.Close()
+	redisConfig := &pc.RedisConfig{
+		Addr: fmt.Sprintf("%s:%s", redisHost, redisPort),
+	}
+	tableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(tableName); err != nil {
 		return err
 	}
-	defer cli.Close()
-	serialPGConfig := postgresConfig.Serialize()
-	liveAddr := fmt.Sprintf("%s:%s", redisHost, redisPort)
-	redisConfig := &pc.RedisConfig{
-		Addr: liveAddr,
+	sourceRows := int64(len(testOfflineTableValues))
+	coord.JobRowQuotas = map[metadata.ResourceType]int64{metadata.FEATURE_VARIANT: sourceRows - 1}
+
+	oversizedFeature := createSafeUUID()
+	oversizedSource := createSafeUUID()
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), oversizedFeature, oversizedSource, tableName, ""); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
 	}
-	serialRedisConfig := redisConfig.Serialized()
-	p, err := provider.Get(pt.RedisOnline, serialRedisConfig)
-	if err != nil {
-		return fmt.Errorf("could not get online provider: %v", err)
+	if err := coord.ExecuteJob(metadata.GetJobKey(metadata.ResourceID{Name: oversizedSource, Variant: "", Type: metadata.SOURCE_VARIANT})); err != nil {
+		return fmt.Errorf("source job should not be limited by a feature quota: %v", err)
 	}
-	onlineStore, err := p.AsOnlineStore()
-	if err != nil {
-		return fmt.Errorf("could not get provider as online store")
+	oversizedID := metadata.ResourceID{Name: oversizedFeature, Variant: "", Type: metadata.FEATURE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(oversizedID)); !errors.Is(err, ErrQuotaExceeded) {
+		return fmt.Errorf("expected feature over its quota to fail with ErrQuotaExceeded, got %v", err)
 	}
-	featureName := createSafeUUID()
-	sourceName := createSafeUUID()
-	originalTableName := createSafeUUID()
-	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+	feature, err := coord.Metadata.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: oversizedFeature, Variant: ""})
+	if err != nil {
 		return err
 	}
-	if err := materializeFeatureWithProvider(client, serialPGConfig, serialRedisConfig, featureName, sourceName, originalTableName, ""); err != nil {
+	if feature.Status() != metadata.FAILED {
+		return fmt.Errorf("expected feature over its quota to be FAILED, got %s", feature.Status())
+	}
+
+	coord.JobRowQuotas[metadata.FEATURE_VARIANT] = sourceRows
+	smallFeature := createSafeUUID()
+	smallSource := createSafeUUID()
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), smallFeature, smallSource, tableName, ""); err != nil {
 		return fmt.Errorf("could not create online feature in metadata: %v", err)
 	}
-	if err := client.SetStatus(context.Background(), metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}, metadata.READY, ""); err != nil {
+	if err := coord.ExecuteJob(metadata.GetJobKey(metadata.ResourceID{Name: smallSource, Variant: "", Type: metadata.SOURCE_VARIANT})); err != nil {
 		return err
 	}
-	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
-	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
-	featureCreated, err := client.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: featureName, Variant: ""})
-	if err != nil {
-		return fmt.Errorf("could not get feature: %v", err)
-	}
-	if featureCreated.Status() != metadata.CREATED {
-		return fmt.Errorf("Feature not set to created with no coordinator running")
+	if err := coord.ExecuteJob(metadata.GetJobKey(metadata.ResourceID{Name: smallFeature, Variant: "", Type: metadata.FEATURE_VARIANT})); err != nil {
+		return fmt.Errorf("feature within its quota should materialize: %v", err)
 	}
-	memJobSpawner := MemoryJobSpawner{}
-	coord, err := NewCoordinator(client, logger, cli, &memJobSpawner)
+	return nil
+}
+
+func testStreamPrimaryMaterialization(addr string) error {
+	// No materialize runner is registered, so the job fails unless it's streamed
+	coord, err := createNewCoordinator(addr)
 	if err != nil {
-		return fmt.Errorf("Failed to set up coordinator")
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
 	}
-	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
-		return err
+	defer coord.Metadata.Close()
+	defer coord.EtcdClient.Close()
+	coord.StreamPrimaryMaterializations = true
+	redisConfig := &pc.RedisConfig{
+		Addr: fmt.Sprintf("%s:%s", redisHost, redisPort),
 	}
-	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); err != nil {
+	tableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(tableName); err != nil {
 		return err
 	}
-	startWaitDelete := time.Now()
-	elapsed := time.Since(startWaitDelete)
-	for has, _ := coord.hasJob(featureID); has && elapsed < time.Duration(10)*time.Second; has, _ = coord.hasJob(featureID) {
-		time.Sleep(1 * time.Second)
-		elapsed = time.Since(startWaitDelete)
-		fmt.Printf("waiting for job %v to be deleted\n", featureID)
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, tableName, ""); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
 	}
-	if elapsed >= time.Duration(10)*time.Second {
-		return fmt.Errorf("timed out waiting for job to delete")
+	if err := coord.ExecuteJob(metadata.GetJobKey(metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT})); err != nil {
+		return err
 	}
-	featureComplete, err := client.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: featureName, Variant: ""})
+	if err := coord.ExecuteJob(metadata.GetJobKey(metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT})); err != nil {
+		return fmt.Errorf("could not stream feature: %v", err)
+	}
+	p, err := provider.Get(pt.RedisOnline, redisConfig.Serialized())
 	if err != nil {
-		return fmt.Errorf("could not get feature variant")
+		return fmt.Errorf("could not get online provider: %v", err)
 	}
-	if metadata.READY != featureComplete.Status() {
-		return fmt.Errorf("Feature not set to ready once job completes")
+	onlineStore, err := p.AsOnlineStore()
+	if err != nil {
+		return fmt.Errorf("could not get provider as online store")
 	}
 	resourceTable, err := onlineStore.GetTable(featureName, "")
 	if err != nil {
@@ -1455,449 +4675,467 @@ func testCoordinatorMaterializeFeature(addr string) error {
 			return err
 		}
 		if !reflect.DeepEqual(value, record.Value) {
-			return fmt.Errorf("Feature value did not materialize")
+			return fmt.Errorf("expected %s to be %v online, got %v", record.Entity, record.Value, value)
 		}
 	}
-	return nil
-}
-
-func CreateOriginalPostgresTable(tableName string) error {
-	url := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", postgresConfig.Username, postgresConfig.Password, postgresConfig.Host, postgresConfig.Port, postgresConfig.Database)
-	ctx := context.Background()
-	conn, err := pgxpool.Connect(ctx, url)
+	offline, err := provider.Get(pt.PostgresOffline, postgresConfig.Serialize())
 	if err != nil {
 		return err
 	}
-	createTableQuery := fmt.Sprintf("CREATE TABLE %s (entity VARCHAR, value INT, ts TIMESTAMPTZ)", sanitize(tableName))
-	if _, err := conn.Exec(context.Background(), createTableQuery); err != nil {
+	offlineStore, err := offline.AsOfflineStore()
+	if err != nil {
 		return err
 	}
-	for _, record := range testOfflineTableValues {
-		upsertQuery := fmt.Sprintf("INSERT INTO %s (entity, value, ts) VALUES ($1, $2, $3)", sanitize(tableName))
-		if _, err := conn.Exec(context.Background(), upsertQuery, record.Entity, record.Value, record.TS); err != nil {
-			return err
-		}
+	if _, err := offlineStore.GetMaterialization(provider.MaterializationID(featureName)); err == nil {
+		return fmt.Errorf("expected streamed feature to not stage a materialization")
+	}
+	if _, err := offlineStore.GetResourceTable(provider.ResourceID{Name: featureName, Variant: "", Type: provider.Feature}); err == nil {
+		return fmt.Errorf("expected streamed feature to not register a resource table")
 	}
 	return nil
 }
 
-func testRegisterPrimaryTableFromSource(addr string) error {
-	logger := zap.NewExample().Sugar()
-	client, err := metadata.NewClient(addr, logger)
+func testExecuteJobTimeout(addr string) error {
+	coord, err := createNewCoordinator(addr)
 	if err != nil {
-		return fmt.Errorf("Failed to connect: %v", err)
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
 	}
-	defer client.Close()
-	etcdConnect := fmt.Sprintf("%s:%s", etcdHost, etcdPort)
-	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{etcdConnect}})
-	if err != nil {
-		return err
+	defer coord.Metadata.Close()
+	defer coord.EtcdClient.Close()
+	// The retry is streamed, so it doesn't need a materialize runner
+	coord.StreamPrimaryMaterializations = true
+	redisConfig := &pc.RedisConfig{
+		Addr: fmt.Sprintf("%s:%s", redisHost, redisPort),
 	}
-	defer cli.Close()
 	tableName := createSafeUUID()
-	serialPGConfig := postgresConfig.Serialize()
-	myProvider, err := provider.Get(pt.PostgresOffline, serialPGConfig)
-	if err != nil {
-		return fmt.Errorf("could not get provider: %v", err)
-	}
-	myOffline, err := myProvider.AsOfflineStore()
-	if err != nil {
-		return fmt.Errorf("could not get provider as offline store: %v", err)
-	}
 	if err := CreateOriginalPostgresTable(tableName); err != nil {
-		return fmt.Errorf("Could not create non-featureform source table: %v", err)
+		return err
 	}
+	featureName := createSafeUUID()
 	sourceName := createSafeUUID()
-	if err := createSourceWithProvider(client, pc.SerializedConfig(serialPGConfig), sourceName, tableName); err != nil {
-		return fmt.Errorf("could not register source in metadata: %v", err)
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, tableName, ""); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
 	}
-	sourceCreated, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: sourceName, Variant: ""})
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+
+	// The source's job isn't run, so the feature waits on it until it times out
+	err = coord.ExecuteJobWithTimeout(metadata.GetJobKey(featureID), 2*time.Second)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("expected job to time out, got %v", err)
+	}
+	feature, err := coord.Metadata.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: featureName, Variant: ""})
 	if err != nil {
-		return fmt.Errorf("could not get source: %v", err)
+		return err
 	}
-	if sourceCreated.Status() != metadata.CREATED {
-		return fmt.Errorf("Source not set to created with no coordinator running")
+	if feature.Status() != metadata.FAILED {
+		return fmt.Errorf("expected timed out feature to be FAILED, got %s", feature.Status())
 	}
-	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
-	memJobSpawner := MemoryJobSpawner{}
-	coord, err := NewCoordinator(client, logger, cli, &memJobSpawner)
-	if err != nil {
-		return fmt.Errorf("Failed to set up coordinator")
+	if has, err := coord.hasJob(featureID); err != nil {
+		return err
+	} else if has {
+		return fmt.Errorf("expected timed out job to be deleted")
 	}
+
 	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
 		return err
 	}
-	startWaitDelete := time.Now()
-	elapsed := time.Since(startWaitDelete)
-	for has, _ := coord.hasJob(sourceID); has && elapsed < time.Duration(10)*time.Second; has, _ = coord.hasJob(sourceID) {
-		time.Sleep(1 * time.Second)
-		elapsed = time.Since(startWaitDelete)
-		fmt.Printf("waiting for job %v to be deleted\n", sourceID)
-	}
-	if elapsed >= time.Duration(10)*time.Second {
-		return fmt.Errorf("timed out waiting for job to delete")
+	if err := coord.Metadata.SetStatus(context.Background(), featureID, metadata.PENDING, ""); err != nil {
+		return fmt.Errorf("could not reset feature status: %v", err)
 	}
-	sourceComplete, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: sourceName, Variant: ""})
+	job := metadata.CoordinatorJob{Resource: featureID}
+	serialized, err := job.Serialize()
 	if err != nil {
-		return fmt.Errorf("could not get source variant")
-	}
-	if metadata.READY != sourceComplete.Status() {
-		return fmt.Errorf("source variant not set to ready once job completes")
+		return err
 	}
-	providerSourceID := provider.ResourceID{Name: sourceName, Variant: "", Type: provider.Primary}
-	primaryTable, err := myOffline.GetPrimaryTable(providerSourceID)
-	if err != nil {
-		return fmt.Errorf("Coordinator did not create primary table")
+	if _, err := (*coord.KVClient).Put(context.Background(), metadata.GetJobKey(featureID), string(serialized)); err != nil {
+		return fmt.Errorf("could not recreate job: %v", err)
 	}
-	primaryTableName, err := provider.GetPrimaryTableName(providerSourceID)
-	if err != nil {
-		return fmt.Errorf("invalid table name: %v", err)
+	if err := coord.ExecuteJobWithTimeout(metadata.GetJobKey(featureID), time.Minute); err != nil {
+		return fmt.Errorf("could not retry timed out job: %v", err)
 	}
-	if primaryTable.GetName() != primaryTableName {
-		return fmt.Errorf("Primary table did not copy name")
+	return nil
+}
+
+func testTrainingSetJobTimeout(addr string) error {
+	if err := runner.RegisterFactory(string(runner.CREATE_TRAINING_SET), runner.TrainingSetRunnerFactory); err != nil {
+		return fmt.Errorf("Failed to register training set runner factory: %v", err)
 	}
-	numRows, err := primaryTable.NumRows()
+	defer runner.UnregisterFactory(string(runner.CREATE_TRAINING_SET))
+	coord, err := createNewCoordinator(addr)
 	if err != nil {
-		return fmt.Errorf("Could not get num rows from primary table")
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
 	}
-	if int(numRows) != len(testOfflineTableValues) {
-		return fmt.Errorf("primary table did not copy correct number of rows")
+	defer coord.Close()
+	tableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(tableName); err != nil {
+		return err
 	}
-	primaryTableIterator, err := primaryTable.IterateSegment(int64(len(testOfflineTableValues)))
-	if err != nil {
+	sourceName := createSafeUUID()
+	tsName := createSafeUUID()
+	if err := createTrainingSetWithProvider(coord.Metadata, postgresConfig.Serialize(), sourceName, createSafeUUID(), createSafeUUID(), tsName, tableName, ""); err != nil {
+		return fmt.Errorf("could not create training set: %v", err)
+	}
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
 		return err
 	}
-	i := 0
-	for ; primaryTableIterator.Next(); i++ {
-		if primaryTableIterator.Err() != nil {
-			return err
-		}
-		primaryTableRow := primaryTableIterator.Values()
-		values := reflect.ValueOf(testOfflineTableValues[i])
-		for j := 0; j < values.NumField(); j++ {
-			if primaryTableRow[j] != values.Field(j).Interface() {
-				return fmt.Errorf("Primary table value does not match original value")
-			}
-		}
+	// The feature's job isn't run, so the training set waits on it until it
+	// times out
+	tsID := metadata.ResourceID{Name: tsName, Variant: "", Type: metadata.TRAINING_SET_VARIANT}
+	err = coord.ExecuteJobWithTimeout(metadata.GetJobKey(tsID), 2*time.Second)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("expected job to time out, got %v", err)
 	}
-	if i != len(testOfflineTableValues) {
-		return fmt.Errorf("primary table did not copy all rows")
+	// The wait stops with the job, so there's no work left to drain
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := coord.Shutdown(ctx); err != nil {
+		return fmt.Errorf("expected timed out job's work to have stopped: %v", err)
 	}
 	return nil
 }
 
-func testRegisterTransformationFromSource(addr string) error {
-	if err := runner.RegisterFactory(string(runner.CREATE_TRANSFORMATION), runner.CreateTransformationRunnerFactory); err != nil {
-		return fmt.Errorf("Failed to register training set runner factory: %v", err)
-	}
-	defer runner.UnregisterFactory(string(runner.CREATE_TRANSFORMATION))
-	logger := zap.NewExample().Sugar()
-	client, err := metadata.NewClient(addr, logger)
+func testPoolSkipsLockedJob(addr string) error {
+	coord, err := createNewCoordinator(addr)
 	if err != nil {
-		return fmt.Errorf("Failed to connect: %v", err)
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
 	}
-	defer client.Close()
-	etcdConnect := fmt.Sprintf("%s:%s", etcdHost, etcdPort)
-	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{etcdConnect}})
+	defer coord.Metadata.Close()
+	defer coord.EtcdClient.Close()
+	resID := metadata.ResourceID{Name: createSafeUUID(), Variant: "", Type: metadata.SOURCE_VARIANT}
+	jobKey := metadata.GetJobKey(resID)
+	serialized, err := (&metadata.CoordinatorJob{Resource: resID}).Serialize()
 	if err != nil {
 		return err
 	}
-	defer cli.Close()
-	tableName := createSafeUUID()
-	serialPGConfig := postgresConfig.Serialize()
-	myProvider, err := provider.Get(pt.PostgresOffline, serialPGConfig)
+	if _, err := (*coord.KVClient).Put(context.Background(), jobKey, string(serialized)); err != nil {
+		return fmt.Errorf("could not create job: %v", err)
+	}
+	defer (*coord.KVClient).Delete(context.Background(), jobKey)
+	// Stands in for another coordinator running the job
+	s, err := concurrency.NewSession(coord.EtcdClient, concurrency.WithTTL(5))
 	if err != nil {
-		return fmt.Errorf("could not get provider: %v", err)
+		return err
 	}
-	myOffline, err := myProvider.AsOfflineStore()
+	defer s.Close()
+	mtx := concurrency.NewMutex(s, GetLockKey(jobKey))
+	if err := mtx.Lock(context.Background()); err != nil {
+		return err
+	}
+	defer mtx.Unlock(context.Background())
+	if err := coord.executeJob(context.Background(), jobKey, false); !errors.Is(err, ErrJobLocked) {
+		return fmt.Errorf("expected locked job to be skipped, got %v", err)
+	}
+	resp, err := (*coord.KVClient).Get(context.Background(), jobKey)
 	if err != nil {
-		return fmt.Errorf("could not get provider as offline store: %v", err)
+		return err
 	}
-	if err := CreateOriginalPostgresTable(tableName); err != nil {
-		return fmt.Errorf("Could not create non-featureform source table: %v", err)
+	if len(resp.Kvs) != 1 {
+		return fmt.Errorf("expected skipped job to be left for its lock holder")
 	}
-	sourceName := strings.Replace(createSafeUUID(), "-", "", -1)
-	if err := createSourceWithProvider(client, pc.SerializedConfig(serialPGConfig), sourceName, tableName); err != nil {
-		return fmt.Errorf("could not register source in metadata: %v", err)
+	if job := (&metadata.CoordinatorJob{}); job.Deserialize(resp.Kvs[0].Value) != nil || job.Attempts != 0 {
+		return fmt.Errorf("expected skipped job to be left unattempted")
 	}
-	sourceCreated, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: sourceName, Variant: ""})
+	return nil
+}
+
+func testCancelJob(addr string) error {
+	coord, err := createNewCoordinator(addr)
 	if err != nil {
-		return fmt.Errorf("could not get source: %v", err)
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
 	}
-	if sourceCreated.Status() != metadata.CREATED {
-		return fmt.Errorf("Source not set to created with no coordinator running")
+	defer coord.Metadata.Close()
+	defer coord.EtcdClient.Close()
+	redisConfig := &pc.RedisConfig{
+		Addr: fmt.Sprintf("%s:%s", redisHost, redisPort),
 	}
-	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
-	memJobSpawner := MemoryJobSpawner{}
-	coord, err := NewCoordinator(client, logger, cli, &memJobSpawner)
+	tableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(tableName); err != nil {
+		return err
+	}
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, tableName, ""); err != nil {
+		return fmt.Errorf("could not create online feature in metadata: %v", err)
+	}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+
+	// The source's job isn't run, so the feature waits on it until it's cancelled
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- coord.ExecuteJobWithTimeout(metadata.GetJobKey(featureID), time.Minute)
+	}()
+	time.Sleep(2 * time.Second)
+	if err := coord.CancelJob(featureID); err != nil {
+		return fmt.Errorf("could not cancel job: %v", err)
+	}
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, ErrJobCancelled) {
+			return fmt.Errorf("expected job to be cancelled, got %v", err)
+		}
+	case <-time.After(30 * time.Second):
+		return fmt.Errorf("cancelled job did not stop")
+	}
+	// The job's coordinator releases its lock once the job has stopped
+	lockResp, err := (*coord.KVClient).Get(context.Background(), GetLockKey(metadata.GetJobKey(featureID))+"/", clientv3.WithPrefix(), clientv3.WithCountOnly())
 	if err != nil {
-		return fmt.Errorf("Failed to set up coordinator")
+		return err
 	}
-	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+	if lockResp.Count != 0 {
+		return fmt.Errorf("expected cancelled job's lock to be released once it stopped")
+	}
+	if has, err := coord.hasJob(featureID); err != nil {
 		return err
+	} else if has {
+		return fmt.Errorf("expected cancelled job to be deleted")
 	}
-	sourceComplete, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: sourceName, Variant: ""})
+	feature, err := coord.Metadata.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: featureName, Variant: ""})
 	if err != nil {
-		return fmt.Errorf("could not get source variant")
+		return err
 	}
-	if metadata.READY != sourceComplete.Status() {
-		return fmt.Errorf("source variant not set to ready once job completes")
+	if feature.Status() != metadata.FAILED {
+		return fmt.Errorf("expected cancelled feature to be FAILED, got %s", feature.Status())
 	}
-	transformationQuery := fmt.Sprintf("SELECT * FROM {{%s.}}", sourceName)
-	transformationName := strings.Replace(createSafeUUID(), "-", "", -1)
-	transformationID := metadata.ResourceID{Name: transformationName, Variant: "", Type: metadata.SOURCE_VARIANT}
-	sourceNameVariants := []metadata.NameVariant{{Name: sourceName, Variant: ""}}
-	if err := createTransformationWithProvider(client, serialPGConfig, transformationName, transformationQuery, sourceNameVariants, ""); err != nil {
-		return err
+	if feature.Error() != ErrJobCancelled.Error() {
+		return fmt.Errorf("expected cancellation message, got %q", feature.Error())
 	}
-	transformationCreated, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: transformationName, Variant: ""})
-	if err != nil {
-		return fmt.Errorf("could not get transformation: %v", err)
+	err = coord.CancelJob(featureID)
+	var notExist *JobDoesNotExistError
+	if !errors.As(err, &notExist) {
+		return fmt.Errorf("expected cancelling a deleted job to fail, got %v", err)
 	}
-	if transformationCreated.Status() != metadata.CREATED {
-		return fmt.Errorf("Transformation not set to created with no coordinator running")
+	return nil
+}
+
+// registerSlowMaterializeRunner makes materializations take delay longer to
+// report they're done. The returned function unregisters it.
+func registerSlowMaterializeRunner(delay time.Duration) (func(), error) {
+	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
+		return nil, fmt.Errorf("Failed to register chunk runner factory: %v", err)
 	}
-	if err := coord.ExecuteJob(metadata.GetJobKey(transformationID)); err != nil {
-		return err
+	slowMaterializeFactory := func(config runner.Config) (types.Runner, error) {
+		materializeRunner, err := runner.MaterializeRunnerFactory(config)
+		if err != nil {
+			return nil, err
+		}
+		return &delayedRunner{Runner: materializeRunner, delay: delay}, nil
 	}
-	transformationComplete, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: transformationName, Variant: ""})
-	if err != nil {
-		return fmt.Errorf("could not get source variant")
+	if err := runner.RegisterFactory(string(runner.MATERIALIZE), slowMaterializeFactory); err != nil {
+		runner.UnregisterFactory(string(runner.COPY_TO_ONLINE))
+		return nil, fmt.Errorf("Failed to register materialize runner factory: %v", err)
 	}
-	if metadata.READY != transformationComplete.Status() {
-		return fmt.Errorf("transformation variant not set to ready once job completes")
+	return func() {
+		runner.UnregisterFactory(string(runner.COPY_TO_ONLINE))
+		runner.UnregisterFactory(string(runner.MATERIALIZE))
+	}, nil
+}
+
+// startSlowMaterialization runs a feature's source job, then starts its
+// materialization job, which is still running when it returns
+func startSlowMaterialization(coord *Coordinator) (metadata.ResourceID, chan error, error) {
+	redisConfig := &pc.RedisConfig{
+		Addr: fmt.Sprintf("%s:%s", redisHost, redisPort),
 	}
-	providerTransformationID := provider.ResourceID{Name: transformationName, Variant: "", Type: provider.Transformation}
-	transformationTable, err := myOffline.GetTransformationTable(providerTransformationID)
-	if err != nil {
-		return err
+	featureName := createSafeUUID()
+	sourceName := createSafeUUID()
+	originalTableName := createSafeUUID()
+	if err := CreateOriginalPostgresTable(originalTableName); err != nil {
+		return metadata.ResourceID{}, nil, err
 	}
-	transformationTableName, err := provider.GetPrimaryTableName(providerTransformationID)
-	if err != nil {
-		return fmt.Errorf("invalid transformation table name: %v", err)
+	if err := materializeFeatureWithProvider(coord.Metadata, postgresConfig.Serialize(), redisConfig.Serialized(), featureName, sourceName, originalTableName, ""); err != nil {
+		return metadata.ResourceID{}, nil, fmt.Errorf("could not create online feature in metadata: %v", err)
 	}
-	if transformationTable.GetName() != transformationTableName {
-		return fmt.Errorf("Transformation table did not copy name")
+	sourceID := metadata.ResourceID{Name: sourceName, Variant: "", Type: metadata.SOURCE_VARIANT}
+	featureID := metadata.ResourceID{Name: featureName, Variant: "", Type: metadata.FEATURE_VARIANT}
+	if err := coord.ExecuteJob(metadata.GetJobKey(sourceID)); err != nil {
+		return metadata.ResourceID{}, nil, err
 	}
-	numRows, err := transformationTable.NumRows()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- coord.ExecuteJob(metadata.GetJobKey(featureID))
+	}()
+	time.Sleep(time.Second)
+	return featureID, errCh, nil
+}
+
+func testShutdownWaitsForJobs(addr string) error {
+	unregister, err := registerSlowMaterializeRunner(3 * time.Second)
 	if err != nil {
-		return fmt.Errorf("Could not get num rows from transformation table")
+		return err
 	}
-	if int(numRows) != len(testOfflineTableValues) {
-		return fmt.Errorf("transformation table did not copy correct number of rows")
+	defer unregister()
+	coord, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
 	}
-	transformationIterator, err := transformationTable.IterateSegment(int64(len(testOfflineTableValues)))
+	defer coord.Close()
+	featureID, errCh, err := startSlowMaterialization(coord)
 	if err != nil {
 		return err
 	}
-	i := 0
-	for ; transformationIterator.Next(); i++ {
-		if transformationIterator.Err() != nil {
-			return err
-		}
-		transformationTableRow := transformationIterator.Values()
-		values := reflect.ValueOf(testOfflineTableValues[i])
-		for j := 0; j < values.NumField(); j++ {
-			if transformationTableRow[j] != values.Field(j).Interface() {
-				return fmt.Errorf("Transformation table value does not match original value")
-			}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := coord.Shutdown(ctx); err != nil {
+		return fmt.Errorf("expected shutdown to finish cleanly: %v", err)
+	}
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("expected running job to finish during shutdown: %v", err)
 		}
+	default:
+		return fmt.Errorf("shutdown returned before the running job finished")
 	}
-	if i != len(testOfflineTableValues) {
-		return fmt.Errorf("transformation table did not copy all rows")
+	if err := coord.ExecuteJob(metadata.GetJobKey(featureID)); !errors.As(err, &CoordinatorClosedError{}) {
+		return fmt.Errorf("expected jobs after shutdown to be rejected, got %v", err)
 	}
+	return nil
+}
 
-	joinTransformationQuery := fmt.Sprintf("SELECT {{%s.}}.entity, {{%s.}}.value, {{%s.}}.ts FROM {{%s.}} INNER JOIN {{%s.}} ON {{%s.}}.entity = {{%s.}}.entity", sourceName, sourceName, sourceName, sourceName, transformationName, sourceName, transformationName)
-	joinTransformationName := strings.Replace(createSafeUUID(), "-", "", -1)
-	joinTransformationID := metadata.ResourceID{Name: joinTransformationName, Variant: "", Type: metadata.SOURCE_VARIANT}
-	joinSourceNameVariants := []metadata.NameVariant{{Name: sourceName, Variant: ""}, {Name: transformationName, Variant: ""}}
-	if err := createTransformationWithProvider(client, serialPGConfig, joinTransformationName, joinTransformationQuery, joinSourceNameVariants, ""); err != nil {
+func testShutdownReleasesJobs(addr string) error {
+	unregister, err := registerSlowMaterializeRunner(time.Minute)
+	if err != nil {
 		return err
 	}
-	joinTransformationCreated, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: joinTransformationName, Variant: ""})
+	defer unregister()
+	coord, err := createNewCoordinator(addr)
 	if err != nil {
-		return fmt.Errorf("could not get transformation: %v", err)
-	}
-	if joinTransformationCreated.Status() != metadata.CREATED {
-		return fmt.Errorf("Transformation not set to created with no coordinator running")
-	}
-	if err := coord.ExecuteJob(metadata.GetJobKey(joinTransformationID)); err != nil {
-		return err
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
 	}
-	joinTransformationComplete, err := client.GetSourceVariant(context.Background(), metadata.NameVariant{Name: joinTransformationName, Variant: ""})
+	defer coord.Close()
+	featureID, errCh, err := startSlowMaterialization(coord)
 	if err != nil {
-		return fmt.Errorf("could not get source variant")
+		return err
 	}
-	if metadata.READY != joinTransformationComplete.Status() {
-		return fmt.Errorf("transformation variant not set to ready once job completes")
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := coord.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("expected shutdown to run out of time, got %v", err)
+	}
+	// Shutdown doesn't wait past its deadline, but the job returns as soon as
+	// it's stopped
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, ErrShutdown) {
+			return fmt.Errorf("expected running job to be stopped by shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		return fmt.Errorf("running job wasn't stopped by shutdown")
 	}
-	providerJoinTransformationID := provider.ResourceID{Name: transformationName, Variant: "", Type: provider.Transformation}
-	joinTransformationTable, err := myOffline.GetTransformationTable(providerJoinTransformationID)
+	other, err := createNewCoordinator(addr)
 	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
+	}
+	defer other.Close()
+	if has, err := other.hasJob(featureID); err != nil {
 		return err
+	} else if !has {
+		return fmt.Errorf("expected stopped job to be left for another coordinator")
 	}
-	transformationJoinName, err := provider.GetPrimaryTableName(providerJoinTransformationID)
+	feature, err := other.Metadata.GetFeatureVariant(context.Background(), metadata.NameVariant{Name: featureID.Name, Variant: ""})
 	if err != nil {
-		return fmt.Errorf("invalid transformation table name: %v", err)
+		return err
 	}
-	if joinTransformationTable.GetName() != transformationJoinName {
-		return fmt.Errorf("Transformation table did not copy name")
+	if feature.Status() != metadata.PENDING {
+		return fmt.Errorf("expected stopped feature to be left PENDING, got %s", feature.Status())
 	}
-	numRows, err = joinTransformationTable.NumRows()
+	// The lock was released, so another coordinator can claim the job
+	s, err := concurrency.NewSession(other.EtcdClient, concurrency.WithTTL(1))
 	if err != nil {
-		return fmt.Errorf("Could not get num rows from transformation table")
+		return err
 	}
-	if int(numRows) != len(testOfflineTableValues) {
-		return fmt.Errorf("transformation table did not copy correct number of rows")
+	defer s.Close()
+	mtx := concurrency.NewMutex(s, GetLockKey(metadata.GetJobKey(featureID)))
+	if err := mtx.TryLock(context.Background()); err != nil {
+		return fmt.Errorf("expected stopped job's lock to be released: %v", err)
 	}
-	joinTransformationIterator, err := joinTransformationTable.IterateSegment(int64(len(testOfflineTableValues)))
+	return mtx.Unlock(context.Background())
+}
+
+func testRecurringJobTickClaimedOnce(addr string) error {
+	coord, err := createNewCoordinator(addr)
 	if err != nil {
-		return err
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
 	}
-	i = 0
-	for ; joinTransformationIterator.Next(); i++ {
-		if joinTransformationIterator.Err() != nil {
+	defer coord.Close()
+	other, err := createNewCoordinator(addr)
+	if err != nil {
+		return fmt.Errorf("Failed to set up coordinator: %v", err)
+	}
+	defer other.Close()
+	resID := metadata.ResourceID{Name: createSafeUUID(), Variant: "", Type: metadata.TRAINING_SET_VARIANT}
+	if err := coord.scheduleJob(resID, "every minute"); err == nil {
+		return fmt.Errorf("expected invalid schedule to be rejected")
+	}
+	key := recurringJobKey(resID)
+	tick := time.Now().Truncate(time.Minute)
+	claims := 0
+	for _, c := range []*Coordinator{coord, other, coord} {
+		claimed, err := c.claimRecurringTick(context.Background(), key, tick)
+		if err != nil {
 			return err
 		}
-		joinTransformationTableRow := joinTransformationIterator.Values()
-		values := reflect.ValueOf(testOfflineTableValues[i])
-		for j := 0; j < values.NumField(); j++ {
-			if joinTransformationTableRow[j] != values.Field(j).Interface() {
-				return fmt.Errorf("Transformation table value does not match original value")
-			}
+		if claimed {
+			claims++
 		}
 	}
-	if i != len(testOfflineTableValues) {
-		return fmt.Errorf("transformation table did not copy all rows")
-	}
-
-	return nil
-}
-
-func TestGetSourceMapping(t *testing.T) {
-	templateString := "Some example text {{name1.variant1}} and more {{name2.variant2}}"
-	replacements := map[string]string{"name1.variant1": "replacement1", "name2.variant2": "replacement2"}
-	expectedSourceMap := []provider.SourceMapping{
-		provider.SourceMapping{
-			Template: "\"replacement1\"",
-			Source:   "replacement1",
-		},
-		provider.SourceMapping{
-			Template: "\"replacement2\"",
-			Source:   "replacement2",
-		},
+	if claims != 1 {
+		return fmt.Errorf("expected tick to be claimed once, got %d claims", claims)
 	}
-
-	sourceMap, err := getSourceMapping(templateString, replacements)
+	claimed, err := other.claimRecurringTick(context.Background(), key, tick.Add(time.Minute))
 	if err != nil {
-		t.Fatalf("Could not retrieve the source mapping: %v", err)
-	}
-
-	if !reflect.DeepEqual(sourceMap, expectedSourceMap) {
-		t.Fatalf("source mapping did not generate the SourceMapping correctly. Expected %v, got %v", sourceMap, expectedSourceMap)
+		return err
 	}
-}
-
-func TestGetSourceMappingError(t *testing.T) {
-	templateString := "Some example text {{name1.variant1}} and more {{name2.variant2}}"
-	wrongReplacements := map[string]string{"name1.variant1": "replacement1", "name3.variant3": "replacement2"}
-	_, err := getSourceMapping(templateString, wrongReplacements)
-	if err == nil {
-		t.Fatalf("getSourceMapping did not catch error: templateString {%v} and wrongReplacement {%v}", templateString, wrongReplacements)
+	if !claimed {
+		return fmt.Errorf("expected next tick to be claimable")
 	}
+	return nil
 }
 
-func TestGetOrderedSourceMappings(t *testing.T) {
-	type testCase struct {
-		name              string
-		sources           []metadata.NameVariant
-		sourceMap         map[string]string
-		expectedSourceMap []provider.SourceMapping
-		expectError       bool
-	}
-
-	testCases := []testCase{
-		{
-			name: "test ordered source mappings",
-			sources: []metadata.NameVariant{
-				{Name: "name1", Variant: "variant1"},
-				{Name: "name2", Variant: "variant2"},
-			},
-			sourceMap: map[string]string{
-				"name1.variant1": "tableA",
-				"name2.variant2": "tableB",
-			},
-			expectedSourceMap: []provider.SourceMapping{
-				{
-					Template: "name1.variant1",
-					Source:   "tableA",
-				},
-				{
-					Template: "name2.variant2",
-					Source:   "tableB",
-				},
-			},
-			expectError: false,
+func TestFailureStatusMessage(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		contains string
+	}{
+		"Unknown Type": {
+			fmt.Errorf("get provider: %w", &provider.UnknownProviderTypeError{Type: "INVALID_PROVIDER"}),
+			"INVALID_PROVIDER is not a supported provider type",
 		},
-		{
-			name: "test unordered source mappings",
-			sources: []metadata.NameVariant{
-				{Name: "name1", Variant: "variant1"},
-				{Name: "name2", Variant: "variant2"},
-				{Name: "name3", Variant: "variant3"},
-				{Name: "name4", Variant: "variant4"},
-			},
-			sourceMap: map[string]string{
-				"name2.variant2": "tableB",
-				"name4.variant4": "tableD",
-				"name1.variant1": "tableA",
-				"name3.variant3": "tableC",
-			},
-			expectedSourceMap: []provider.SourceMapping{
-				{
-					Template: "name1.variant1",
-					Source:   "tableA",
-				},
-				{
-					Template: "name2.variant2",
-					Source:   "tableB",
-				},
-				{
-					Template: "name3.variant3",
-					Source:   "tableC",
-				},
-				{
-					Template: "name4.variant4",
-					Source:   "tableD",
-				},
-			},
-			expectError: false,
+		"Not Offline": {
+			fmt.Errorf("convert provider: %w", &provider.NotOfflineStoreError{Type: pt.RedisOnline}),
+			"REDIS_ONLINE is not an offline store",
 		},
-		{
-			name: "test missing key in source map",
-			sources: []metadata.NameVariant{
-				{Name: "name1", Variant: "variant1"},
-				{Name: "name2", Variant: "variant2"},
-			},
-			sourceMap: map[string]string{
-				"name1.variant1": "tableA",
-			},
-			expectedSourceMap: nil,
-			expectError:       true,
+		"Not Online": {
+			fmt.Errorf("materialize: %w", &provider.NotOnlineStoreError{Type: pt.PostgresOffline}),
+			"POSTGRES_OFFLINE is not an online store",
+		},
+		"Transformation Query": {
+			fmt.Errorf("sql transformation: %w", TransformationQueryError{
+				query:    `SELECT entity FROM "featureform_source__transactions__default"`,
+				rendered: true,
+				err:      fmt.Errorf("relation does not exist"),
+			}),
+			`query: SELECT entity FROM "featureform_source__transactions__default"`,
+		},
+		"Transformation Query Template": {
+			TransformationQueryError{query: "{{ghost_source.}}", err: fmt.Errorf("the sources were not completed")},
+			"query template: {{ghost_source.}}",
+		},
+		"Other": {
+			fmt.Errorf("something else"),
+			"something else",
 		},
 	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			sourceMap, err := getOrderedSourceMappings(tc.sources, tc.sourceMap)
-			if tc.expectError && err == nil {
-				t.Fatalf("Expected error, but did not get one")
-			}
-			if !tc.expectError && err != nil {
-				t.Fatalf("Unexpected error: %v", err)
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			message := failureStatusMessage(test.err)
+			if !strings.HasPrefix(message, test.err.Error()) {
+				t.Fatalf("expected message to start with the error, got %s", message)
 			}
-			if !reflect.DeepEqual(sourceMap, tc.expectedSourceMap) {
-				t.Fatalf("source mapping did not generate the SourceMapping correctly. Expected %v, got %v", sourceMap, tc.expectedSourceMap)
+			if !strings.Contains(message, test.contains) {
+				t.Fatalf("expected message to contain %q, got %s", test.contains, message)
 			}
 		})
 	}
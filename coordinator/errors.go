@@ -1,10 +1,67 @@
 package coordinator
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/featureform/metadata"
+	"github.com/featureform/provider"
+	"github.com/featureform/runner"
 )
 
+// ErrTableExists is returned when a source is registered again but its primary
+// or transformation table already has data, and
+// Coordinator.OverwriteSourceTables isn't set.
+var ErrTableExists = errors.New("source table already exists with data")
+
+// ErrQuotaExceeded is returned when a job's sources have more rows than
+// Coordinator.JobRowQuotas allows for its type.
+var ErrQuotaExceeded = errors.New("job exceeds its resource quota")
+
+// ErrNoJobProgress is returned when a resource's job hasn't recorded any
+// progress, such as when it has already finished.
+var ErrNoJobProgress = errors.New("no progress recorded for job")
+
+// ErrJobCancelled is returned by jobs stopped with Coordinator.CancelJob. It's
+// the same error as runner.ErrJobCancelled, which runners stop with.
+var ErrJobCancelled = runner.ErrJobCancelled
+
+// ErrShutdown is returned by jobs that were still running when
+// Coordinator.Shutdown stopped waiting for them. They're left to be run again.
+var ErrShutdown = errors.New("coordinator shut down before job finished")
+
+// ErrJobLocked is returned when a job from the coordinator's pool is skipped
+// because its lock is already held.
+var ErrJobLocked = errors.New("job is locked by another run")
+
+// failureStatusMessage is the error message a failed resource's status is set
+// to. Misconfigured providers are explained, since the job can't succeed until
+// the resource is registered with a different provider. Failed SQL
+// transformations include the query they ran.
+func failureStatusMessage(err error) string {
+	var unknownType *provider.UnknownProviderTypeError
+	var notOffline *provider.NotOfflineStoreError
+	var notOnline *provider.NotOnlineStoreError
+	var queryErr TransformationQueryError
+	switch {
+	case errors.As(err, &unknownType):
+		return fmt.Sprintf("%v: %s is not a supported provider type", err, unknownType.Type)
+	case errors.As(err, &notOffline):
+		return fmt.Sprintf("%v: %s is not an offline store, so it can't hold sources, transformations or training sets", err, notOffline.Type)
+	case errors.As(err, &notOnline):
+		return fmt.Sprintf("%v: %s is not an online store, so features can't be materialized to it", err, notOnline.Type)
+	case errors.As(err, &queryErr) && queryErr.rendered:
+		return fmt.Sprintf("%v\nquery: %s", err, queryErr.query)
+	case errors.As(err, &queryErr):
+		return fmt.Sprintf("%v\nquery template: %s", err, queryErr.query)
+	default:
+		return err.Error()
+	}
+}
+
 type JobDoesNotExistError struct {
 	key string
 }
@@ -28,3 +85,83 @@ type ResourceAlreadyFailedError struct {
 func (m ResourceAlreadyFailedError) Error() string {
 	return fmt.Sprintf("resource failed in a previous run: %s %s %s", m.resourceID.Type, m.resourceID.Name, m.resourceID.Variant)
 }
+
+type CoordinatorClosedError struct{}
+
+func (m CoordinatorClosedError) Error() string {
+	return "coordinator closed"
+}
+
+type DependencyFailedError struct {
+	resourceID metadata.ResourceID
+	dependency metadata.ResourceID
+}
+
+func (m DependencyFailedError) Error() string {
+	return fmt.Sprintf("dependency %s %s %s of %s %s %s failed", m.dependency.Type, m.dependency.Name, m.dependency.Variant, m.resourceID.Type, m.resourceID.Name, m.resourceID.Variant)
+}
+
+type MaterializationMismatchError struct {
+	resourceID metadata.ResourceID
+	mismatches int
+	sampled    int
+}
+
+func (m MaterializationMismatchError) Error() string {
+	return fmt.Sprintf("%d of %d sampled entities of feature %s %s don't match its source", m.mismatches, m.sampled, m.resourceID.Name, m.resourceID.Variant)
+}
+
+type IncompatibleSourceSchemaError struct {
+	resourceID metadata.ResourceID
+	previous   metadata.NameVariant
+	// Each missing column and the features and labels of the previous variant that read it
+	missing map[string][]string
+}
+
+func (m IncompatibleSourceSchemaError) Error() string {
+	columns := make([]string, 0, len(m.missing))
+	for column := range m.missing {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	descriptions := make([]string, len(columns))
+	for i, column := range columns {
+		descriptions[i] = fmt.Sprintf("%s (used by %s)", column, strings.Join(m.missing[column], ", "))
+	}
+	return fmt.Sprintf("source %s %s is missing columns used with variant %s: %s", m.resourceID.Name, m.resourceID.Variant, m.previous.Variant, strings.Join(descriptions, "; "))
+}
+
+type JobTimeoutError struct {
+	resourceID metadata.ResourceID
+	timeout    time.Duration
+}
+
+func (m JobTimeoutError) Error() string {
+	return fmt.Sprintf("%s %s %s did not finish within %s", m.resourceID.Type, m.resourceID.Name, m.resourceID.Variant, m.timeout)
+}
+
+type NonSelectQueryError struct {
+	resourceID metadata.ResourceID
+	statement  string
+}
+
+func (m NonSelectQueryError) Error() string {
+	return fmt.Sprintf("transformation %s %s runs %s, but SQL transformations must be read-only SELECT queries; set its %s property to \"true\" to run it anyway", m.resourceID.Name, m.resourceID.Variant, m.statement, AllowNonSelectQueryProperty)
+}
+
+// TransformationQueryError is returned when a SQL transformation fails, with the
+// query it ran. Failures before its sources were templated in have the
+// template instead.
+type TransformationQueryError struct {
+	query    string
+	rendered bool
+	err      error
+}
+
+func (m TransformationQueryError) Error() string {
+	return m.err.Error()
+}
+
+func (m TransformationQueryError) Unwrap() error {
+	return m.err
+}
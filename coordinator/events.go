@@ -0,0 +1,136 @@
+package coordinator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/featureform/metadata"
+)
+
+type ResourceStatusEvent struct {
+	Name      string
+	Variant   string
+	Type      string
+	Status    string
+	Timestamp time.Time
+}
+
+func NewResourceStatusEvent(resID metadata.ResourceID, status metadata.ResourceStatus) ResourceStatusEvent {
+	return ResourceStatusEvent{
+		Name:      resID.Name,
+		Variant:   resID.Variant,
+		Type:      resID.Type.String(),
+		Status:    status.String(),
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+func (e *ResourceStatusEvent) Serialize() (Config, error) {
+	config, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("serialize resource status event: %v", err)
+	}
+	return config, nil
+}
+
+func (e *ResourceStatusEvent) Deserialize(config Config) error {
+	err := json.Unmarshal(config, e)
+	if err != nil {
+		return fmt.Errorf("deserialize resource status event: %v", err)
+	}
+	return nil
+}
+
+// EventSink receives a ResourceStatusEvent every time the coordinator changes
+// the status of a resource while running a job. Implementations are expected
+// to be safe for concurrent use, since jobs are executed in separate goroutines.
+type EventSink interface {
+	Publish(event ResourceStatusEvent) error
+	Close() error
+}
+
+type NoopEventSink struct{}
+
+func (s *NoopEventSink) Publish(event ResourceStatusEvent) error {
+	return nil
+}
+
+func (s *NoopEventSink) Close() error {
+	return nil
+}
+
+type KafkaEventSinkConfig struct {
+	// Base URL of a Kafka REST Proxy (e.g. http://kafka-rest:8082)
+	RestProxyURL string
+	Topic        string
+	Timeout      time.Duration
+}
+
+// KafkaEventSink publishes events to a Kafka topic through the Kafka REST Proxy
+// so the coordinator doesn't have to hold a broker connection of its own. Each
+// event is keyed by its resource so all transitions for a resource land in the
+// same partition and stay ordered.
+type KafkaEventSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewKafkaEventSink(config KafkaEventSinkConfig) (*KafkaEventSink, error) {
+	if config.RestProxyURL == "" {
+		return nil, fmt.Errorf("kafka event sink requires a rest proxy url")
+	}
+	if config.Topic == "" {
+		return nil, fmt.Errorf("kafka event sink requires a topic")
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	return &KafkaEventSink{
+		url:    fmt.Sprintf("%s/topics/%s", strings.TrimSuffix(config.RestProxyURL, "/"), url.PathEscape(config.Topic)),
+		client: &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+type kafkaRecord struct {
+	Key   string              `json:"key"`
+	Value ResourceStatusEvent `json:"value"`
+}
+
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+func (s *KafkaEventSink) Publish(event ResourceStatusEvent) error {
+	body, err := json.Marshal(kafkaRecords{
+		Records: []kafkaRecord{{Key: fmt.Sprintf("%s.%s.%s", event.Type, event.Name, event.Variant), Value: event}},
+	})
+	if err != nil {
+		return fmt.Errorf("serialize kafka records: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create kafka publish request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("publish event to kafka: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("publish event to kafka: status %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+func (s *KafkaEventSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
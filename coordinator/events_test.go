@@ -0,0 +1,133 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/featureform/metadata"
+)
+
+type memoryEventSink struct {
+	mu     sync.Mutex
+	events []ResourceStatusEvent
+}
+
+func (s *memoryEventSink) Publish(event ResourceStatusEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *memoryEventSink) Close() error {
+	return nil
+}
+
+func (s *memoryEventSink) hasEvent(resID metadata.ResourceID, status metadata.ResourceStatus) bool {
+	return s.eventIndex(resID, status) != -1
+}
+
+// eventIndex returns the position of the first matching event in the order they
+// were published, or -1 if there isn't one.
+func (s *memoryEventSink) eventIndex(resID metadata.ResourceID, status metadata.ResourceStatus) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, event := range s.events {
+		if event.Name == resID.Name && event.Variant == resID.Variant && event.Type == resID.Type.String() && event.Status == status.String() {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestResourceStatusEventSerialize(t *testing.T) {
+	resID := metadata.ResourceID{Name: "name", Variant: "variant", Type: metadata.TRAINING_SET_VARIANT}
+	event := NewResourceStatusEvent(resID, metadata.READY)
+	serialized, err := event.Serialize()
+	if err != nil {
+		t.Fatalf("could not serialize event: %v", err)
+	}
+	deserialized := ResourceStatusEvent{}
+	if err := deserialized.Deserialize(serialized); err != nil {
+		t.Fatalf("could not deserialize event: %v", err)
+	}
+	if deserialized.Name != event.Name || deserialized.Status != event.Status || !deserialized.Timestamp.Equal(event.Timestamp) {
+		t.Fatalf("expected %v, got %v", event, deserialized)
+	}
+}
+
+func TestKafkaEventSinkPublish(t *testing.T) {
+	var received kafkaRecords
+	var path, contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	sink, err := NewKafkaEventSink(KafkaEventSinkConfig{RestProxyURL: server.URL, Topic: "status"})
+	if err != nil {
+		t.Fatalf("could not create kafka sink: %v", err)
+	}
+	defer sink.Close()
+	resID := metadata.ResourceID{Name: "name", Variant: "variant", Type: metadata.TRAINING_SET_VARIANT}
+	if err := sink.Publish(NewResourceStatusEvent(resID, metadata.READY)); err != nil {
+		t.Fatalf("could not publish event: %v", err)
+	}
+	if path != "/topics/status" {
+		t.Fatalf("expected event to be posted to /topics/status, got %s", path)
+	}
+	if contentType != "application/vnd.kafka.json.v2+json" {
+		t.Fatalf("unexpected content type: %s", contentType)
+	}
+	if len(received.Records) != 1 || received.Records[0].Value.Status != metadata.READY.String() {
+		t.Fatalf("unexpected records received: %v", received)
+	}
+}
+
+func TestKafkaEventSinkEscapesTopic(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	sink, err := NewKafkaEventSink(KafkaEventSinkConfig{RestProxyURL: server.URL, Topic: "status/events?v=1"})
+	if err != nil {
+		t.Fatalf("could not create kafka sink: %v", err)
+	}
+	defer sink.Close()
+	if err := sink.Publish(ResourceStatusEvent{}); err != nil {
+		t.Fatalf("could not publish event: %v", err)
+	}
+	if path != "/topics/status%2Fevents%3Fv=1" {
+		t.Fatalf("expected the topic to be escaped in the path, got %s", path)
+	}
+}
+
+func TestKafkaEventSinkErrors(t *testing.T) {
+	if _, err := NewKafkaEventSink(KafkaEventSinkConfig{Topic: "status"}); err == nil {
+		t.Fatalf("expected error creating sink without a rest proxy url")
+	}
+	if _, err := NewKafkaEventSink(KafkaEventSinkConfig{RestProxyURL: "http://localhost"}); err == nil {
+		t.Fatalf("expected error creating sink without a topic")
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	sink, err := NewKafkaEventSink(KafkaEventSinkConfig{RestProxyURL: server.URL, Topic: "status"})
+	if err != nil {
+		t.Fatalf("could not create kafka sink: %v", err)
+	}
+	if err := sink.Publish(ResourceStatusEvent{}); err == nil {
+		t.Fatalf("expected error publishing to failing rest proxy")
+	}
+}
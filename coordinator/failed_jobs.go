@@ -0,0 +1,128 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/featureform/metadata"
+)
+
+// Prefix for the jobs that failed for good. Like jobHistoryPrefix, it must not
+// start with "JOB_".
+const failedJobPrefix = "failed-jobs/"
+
+// FailedJob records why a resource's job failed once it ran out of attempts,
+// so that it can be looked into and retried with RetryFailedJob.
+type FailedJob struct {
+	Resource metadata.ResourceID
+	FailedAt time.Time
+	// Times the coordinator ran the job, each of which attempts its runner as
+	// many times as its JobRetryPolicy allows
+	Attempts int
+	Schedule string
+	Error    string
+}
+
+func (j *FailedJob) Serialize() (Config, error) {
+	config, err := json.Marshal(j)
+	if err != nil {
+		return nil, fmt.Errorf("serialize failed job: %v", err)
+	}
+	return config, nil
+}
+
+func (j *FailedJob) Deserialize(config Config) error {
+	err := json.Unmarshal(config, j)
+	if err != nil {
+		return fmt.Errorf("deserialize failed job: %v", err)
+	}
+	return nil
+}
+
+func failedJobKey(resID metadata.ResourceID) string {
+	return fmt.Sprintf("%s%s__%s__%s", failedJobPrefix, resID.Type, resID.Name, resID.Variant)
+}
+
+// recordFailedJob keeps the job's final error in etcd. Failing to write it is
+// logged rather than returned, since the job has failed either way.
+func (c *Coordinator) recordFailedJob(job *metadata.CoordinatorJob, jobErr error) {
+	failed := FailedJob{
+		Resource: job.Resource,
+		FailedAt: time.Now().UTC(),
+		Attempts: job.Attempts,
+		Schedule: job.Schedule,
+		Error:    jobErr.Error(),
+	}
+	serialized, err := failed.Serialize()
+	if err != nil {
+		c.Logger.Errorw("Could not serialize failed job", "resource", job.Resource, "error", err)
+		return
+	}
+	if _, err := (*c.KVClient).Put(context.Background(), failedJobKey(job.Resource), string(serialized)); err != nil {
+		c.Logger.Errorw("Could not record failed job", "resource", job.Resource, "error", err)
+	}
+}
+
+func (c *Coordinator) clearFailedJob(resID metadata.ResourceID) {
+	if _, err := (*c.KVClient).Delete(context.Background(), failedJobKey(resID)); err != nil {
+		c.Logger.Errorw("Could not clear failed job", "resource", resID, "error", err)
+	}
+}
+
+// ListFailedJobs returns the jobs that failed and haven't succeeded since, on
+// any coordinator, most recent first.
+func (c *Coordinator) ListFailedJobs() ([]FailedJob, error) {
+	resp, err := (*c.KVClient).Get(context.Background(), failedJobPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("get failed jobs: %w", err)
+	}
+	jobs := make([]FailedJob, len(resp.Kvs))
+	for i, kv := range resp.Kvs {
+		if err := jobs[i].Deserialize(kv.Value); err != nil {
+			return nil, fmt.Errorf("%s: %w", kv.Key, err)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].FailedAt.After(jobs[j].FailedAt)
+	})
+	return jobs, nil
+}
+
+// RetryFailedJob sets a failed resource back to PENDING and queues its job
+// again with no attempts used, to be picked up by WatchForNewJobs. The failed
+// job is kept until the retry succeeds.
+func (c *Coordinator) RetryFailedJob(resID metadata.ResourceID) error {
+	resp, err := (*c.KVClient).Get(context.Background(), failedJobKey(resID))
+	if err != nil {
+		return fmt.Errorf("get failed job: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return &JobDoesNotExistError{key: failedJobKey(resID)}
+	}
+	failed := FailedJob{}
+	if err := failed.Deserialize(resp.Kvs[0].Value); err != nil {
+		return err
+	}
+	job := metadata.CoordinatorJob{
+		Resource: resID,
+		Schedule: failed.Schedule,
+	}
+	serialized, err := job.Serialize()
+	if err != nil {
+		return fmt.Errorf("serialize job: %w", err)
+	}
+	c.Logger.Infow("Retrying failed job", "resource", resID, "error", failed.Error)
+	// The job has to see its resource as PENDING, rather than FAILED, to run
+	if err := c.setStatus(resID, metadata.PENDING, ""); err != nil {
+		return fmt.Errorf("set pending status: %w", err)
+	}
+	if _, err := (*c.KVClient).Put(context.Background(), metadata.GetJobKey(resID), string(serialized)); err != nil {
+		return fmt.Errorf("queue job: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package coordinator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/featureform/metadata"
+)
+
+func TestFailedJobSerialization(t *testing.T) {
+	resID := metadata.ResourceID{Name: "name", Variant: "variant", Type: metadata.TRAINING_SET_VARIANT}
+	failed := FailedJob{
+		Resource: resID,
+		FailedAt: time.UnixMilli(1000).UTC(),
+		Attempts: 2,
+		Schedule: "*/5 * * * *",
+		Error:    "training set job failed",
+	}
+	serialized, err := failed.Serialize()
+	if err != nil {
+		t.Fatalf("could not serialize failed job: %v", err)
+	}
+	deserialized := FailedJob{}
+	if err := deserialized.Deserialize(serialized); err != nil {
+		t.Fatalf("could not deserialize failed job: %v", err)
+	}
+	if !reflect.DeepEqual(failed, deserialized) {
+		t.Fatalf("expected %+v, got %+v", failed, deserialized)
+	}
+	// Failed jobs mustn't be mistaken for jobs to run
+	if key := failedJobKey(resID); strings.HasPrefix(key, "JOB_") || !strings.HasPrefix(key, failedJobPrefix) {
+		t.Fatalf("unexpected failed job key %s", key)
+	}
+}
@@ -0,0 +1,116 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/featureform/metadata"
+)
+
+// Prefix of the etcd mutexes ExecuteJob holds while it runs a job
+var jobLockPrefix = GetLockKey("JOB_")
+
+// WatchForAbandonedJobs reclaims the jobs of coordinators that die part way
+// through running them. A job's lock is held with its coordinator's lease, so
+// etcd releases the lock once the lease expires. When a lock is released but
+// its job still exists and its resource is still PENDING, the job wasn't
+// finished, so it's queued to run again from the start by this coordinator.
+func (c *Coordinator) WatchForAbandonedJobs() error {
+	c.Logger.Info("Watching for abandoned jobs")
+	c.watchPrefix(jobLockPrefix, 0, func(ev *clientv3.Event) {
+		if ev.Type != mvccpb.DELETE {
+			return
+		}
+		jobKey := lockJobKey(string(ev.Kv.Key))
+		go func() {
+			if err := c.reclaimJob(jobKey); err != nil {
+				c.checkError(err, jobKey)
+			}
+		}()
+	})
+	return nil
+}
+
+// lockJobKey returns the key of the job that a key of its lock belongs to. Each
+// coordinator waiting for or holding the lock writes the lock's key followed by
+// its lease ID.
+func lockJobKey(lockKey string) string {
+	jobKey := strings.TrimPrefix(lockKey, GetLockKey(""))
+	if i := strings.LastIndex(jobKey, "/"); i != -1 {
+		jobKey = jobKey[:i]
+	}
+	return jobKey
+}
+
+// reclaimJob queues a job whose lock was released if it was left unfinished.
+// Jobs that succeeded are already deleted, and ones that failed have a FAILED
+// resource, so neither is run again. A key is also deleted when a coordinator
+// stops waiting for the lock, so the job is only reclaimed once no coordinator
+// holds or waits for it.
+func (c *Coordinator) reclaimJob(jobKey string) error {
+	lockKeys, err := (*c.KVClient).Get(context.Background(), GetLockKey(jobKey)+"/", clientv3.WithPrefix(), clientv3.WithCountOnly())
+	if err != nil {
+		return fmt.Errorf("get lock of job %s: %v", jobKey, err)
+	}
+	if lockKeys.Count > 0 {
+		return nil
+	}
+	resp, err := (*c.KVClient).Get(context.Background(), jobKey)
+	if err != nil {
+		return fmt.Errorf("get job %s: %v", jobKey, err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil
+	}
+	job := &metadata.CoordinatorJob{}
+	if err := job.Deserialize(resp.Kvs[0].Value); err != nil {
+		return fmt.Errorf("could not deserialize coordinator job: %v", err)
+	}
+	status, err := c.resourceStatus(job.Resource)
+	if err != nil {
+		return fmt.Errorf("get status of %v: %v", job.Resource, err)
+	}
+	if status != metadata.PENDING {
+		return nil
+	}
+	c.Logger.Warnw("Reclaiming abandoned job", "key", jobKey, "resource", job.Resource, "attempts", job.Attempts)
+	c.queueJob(jobKey)
+	return nil
+}
+
+func (c *Coordinator) resourceStatus(resID metadata.ResourceID) (metadata.ResourceStatus, error) {
+	ctx := context.Background()
+	nameVariant := metadata.NameVariant{Name: resID.Name, Variant: resID.Variant}
+	switch resID.Type {
+	case metadata.SOURCE_VARIANT:
+		source, err := c.Metadata.GetSourceVariant(ctx, nameVariant)
+		if err != nil {
+			return metadata.NO_STATUS, err
+		}
+		return source.Status(), nil
+	case metadata.FEATURE_VARIANT:
+		feature, err := c.Metadata.GetFeatureVariant(ctx, nameVariant)
+		if err != nil {
+			return metadata.NO_STATUS, err
+		}
+		return feature.Status(), nil
+	case metadata.LABEL_VARIANT:
+		label, err := c.Metadata.GetLabelVariant(ctx, nameVariant)
+		if err != nil {
+			return metadata.NO_STATUS, err
+		}
+		return label.Status(), nil
+	case metadata.TRAINING_SET_VARIANT:
+		ts, err := c.Metadata.GetTrainingSetVariant(ctx, nameVariant)
+		if err != nil {
+			return metadata.NO_STATUS, err
+		}
+		return ts.Status(), nil
+	default:
+		return metadata.NO_STATUS, fmt.Errorf("not a valid resource type for running jobs: %s", resID.Type)
+	}
+}
@@ -0,0 +1,129 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// How long a probe waits on etcd before reporting it as down
+const etcdHealthTimeout = 2 * time.Second
+
+// States of the job watch loop, stored in Coordinator.watchState
+const (
+	watchNotStarted int32 = iota
+	watchRunning
+	watchStopped
+)
+
+type CoordinatorOption func(*Coordinator)
+
+// WithHealthServer serves /healthz and /readyz on addr, such as ":8081", for
+// Kubernetes liveness and readiness probes.
+//
+// /readyz returns 503 unless the metadata and etcd connections are up and the
+// job watch loop is running. /healthz only returns 503 if the coordinator has
+// been closed or its watch loop has exited, since restarting the coordinator
+// won't bring a dependency back up.
+func WithHealthServer(addr string) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.healthAddr = addr
+	}
+}
+
+// HealthStatus is the body of /healthz and /readyz responses. Each check is
+// "ok" or a description of why it failed.
+type HealthStatus struct {
+	Metadata string `json:"metadata"`
+	Etcd     string `json:"etcd"`
+	Watch    string `json:"watch"`
+}
+
+func (s HealthStatus) ready() bool {
+	return s.Metadata == "ok" && s.Etcd == "ok" && s.Watch == "ok"
+}
+
+func (c *Coordinator) startHealthServer() error {
+	lis, err := net.Listen("tcp", c.healthAddr)
+	if err != nil {
+		return fmt.Errorf("listen for health checks: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", c.serveHealthz)
+	mux.HandleFunc("/readyz", c.serveReadyz)
+	c.healthServer = &http.Server{Handler: mux}
+	c.healthListener = lis
+	go func() {
+		if err := c.healthServer.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			c.Logger.Errorw("Health server stopped", "error", err)
+		}
+	}()
+	c.Logger.Infow("Serving health checks", "address", lis.Addr().String())
+	return nil
+}
+
+func (c *Coordinator) healthStatus() HealthStatus {
+	status := HealthStatus{Metadata: "ok", Etcd: "ok", Watch: "ok"}
+	if c.Metadata == nil || !c.Metadata.Connected() {
+		status.Metadata = "not connected"
+	}
+	if err := c.checkEtcd(); err != nil {
+		status.Etcd = err.Error()
+	}
+	switch atomic.LoadInt32(&c.watchState) {
+	case watchNotStarted:
+		status.Watch = "not started"
+	case watchStopped:
+		status.Watch = "stopped"
+	}
+	return status
+}
+
+func (c *Coordinator) checkEtcd() error {
+	if c.EtcdClient == nil {
+		return fmt.Errorf("no etcd client")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), etcdHealthTimeout)
+	defer cancel()
+	if _, err := c.EtcdClient.Get(ctx, "JOB_", clientv3.WithPrefix(), clientv3.WithCountOnly()); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Coordinator) serveHealthz(w http.ResponseWriter, r *http.Request) {
+	status := c.healthStatus()
+	alive := !c.isClosed() && atomic.LoadInt32(&c.watchState) != watchStopped
+	writeHealthStatus(w, status, alive)
+}
+
+func (c *Coordinator) serveReadyz(w http.ResponseWriter, r *http.Request) {
+	status := c.healthStatus()
+	writeHealthStatus(w, status, !c.isClosed() && status.ready())
+}
+
+func writeHealthStatus(w http.ResponseWriter, status HealthStatus, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if ok {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+func (c *Coordinator) closeHealthServer() error {
+	if c.healthServer == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return c.healthServer.Shutdown(ctx)
+}
@@ -0,0 +1,82 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/featureform/metadata"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+func getHealth(t *testing.T, c *Coordinator, path string) (int, HealthStatus) {
+	resp, err := http.Get(fmt.Sprintf("http://%s%s", c.healthListener.Addr().String(), path))
+	if err != nil {
+		t.Fatalf("could not get %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	var status HealthStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("could not decode %s response: %v", path, err)
+	}
+	return resp.StatusCode, status
+}
+
+func TestHealthServer(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	serv, addr := startServ(t)
+	defer serv.Stop()
+	logger := zap.NewExample().Sugar()
+	client, err := metadata.NewClient(addr, logger)
+	if err != nil {
+		t.Fatalf("could not set up metadata client: %v", err)
+	}
+	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{fmt.Sprintf("%s:%s", etcdHost, etcdPort)}})
+	if err != nil {
+		t.Fatalf("could not connect to etcd: %v", err)
+	}
+	coord, err := NewCoordinator(client, logger, cli, &MemoryJobSpawner{}, WithHealthServer("127.0.0.1:0"))
+	if err != nil {
+		t.Fatalf("could not create coordinator: %v", err)
+	}
+	defer coord.Close()
+
+	if code, status := getHealth(t, coord, "/readyz"); code != http.StatusServiceUnavailable || status.Watch != "not started" {
+		t.Fatalf("expected coordinator not to be ready before watching for jobs, got %d %v", code, status)
+	}
+	go coord.WatchForNewJobs()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		code, status := getHealth(t, coord, "/readyz")
+		if code == http.StatusOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("coordinator did not become ready: %v", status)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if code, _ := getHealth(t, coord, "/healthz"); code != http.StatusOK {
+		t.Fatalf("expected healthy coordinator, got %d", code)
+	}
+
+	if err := cli.Close(); err != nil {
+		t.Fatalf("could not close etcd client: %v", err)
+	}
+	code, status := getHealth(t, coord, "/readyz")
+	if code != http.StatusServiceUnavailable || status.Etcd == "ok" {
+		t.Fatalf("expected coordinator not to be ready without etcd, got %d %v", code, status)
+	}
+	if status.Metadata != "ok" {
+		t.Fatalf("expected metadata to still be connected, got %v", status)
+	}
+	// Losing etcd makes the coordinator unready, but restarting it wouldn't help
+	if code, status := getHealth(t, coord, "/healthz"); code != http.StatusOK || status.Etcd == "ok" {
+		t.Fatalf("expected coordinator to stay live and report etcd as down, got %d %v", code, status)
+	}
+}
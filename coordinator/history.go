@@ -0,0 +1,126 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/featureform/metadata"
+)
+
+// Prefix for persisted job history. It must not start with "JOB_", which is
+// watched for new jobs.
+const jobHistoryPrefix = "HISTORY_"
+
+// JobRecord is the outcome of the most recent run of a resource's job on this coordinator.
+type JobRecord struct {
+	Resource metadata.ResourceID
+	LastRun  time.Time
+	Attempts int
+	Status   metadata.ResourceStatus
+	Error    string
+}
+
+func (r *JobRecord) Serialize() (Config, error) {
+	config, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("serialize job record: %v", err)
+	}
+	return config, nil
+}
+
+func (r *JobRecord) Deserialize(config Config) error {
+	err := json.Unmarshal(config, r)
+	if err != nil {
+		return fmt.Errorf("deserialize job record: %v", err)
+	}
+	return nil
+}
+
+type jobHistory struct {
+	mtx     sync.RWMutex
+	records map[metadata.ResourceID]JobRecord
+}
+
+func newJobHistory() *jobHistory {
+	return &jobHistory{records: make(map[metadata.ResourceID]JobRecord)}
+}
+
+func (h *jobHistory) record(record JobRecord) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.records[record.Resource] = record
+}
+
+// list returns the records with the most recently run first. A limit of zero or
+// less returns every record.
+func (h *jobHistory) list(limit int) []JobRecord {
+	h.mtx.RLock()
+	records := make([]JobRecord, 0, len(h.records))
+	for _, record := range h.records {
+		records = append(records, record)
+	}
+	h.mtx.RUnlock()
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].LastRun.After(records[j].LastRun)
+	})
+	if limit > 0 && limit < len(records) {
+		records = records[:limit]
+	}
+	return records
+}
+
+func jobHistoryKey(resID metadata.ResourceID) string {
+	return fmt.Sprintf("%s%s__%s__%s", jobHistoryPrefix, resID.Type, resID.Name, resID.Variant)
+}
+
+// History returns the outcome of the jobs this coordinator has run since it
+// started, most recent first, with at most limit entries. A limit of zero or
+// less returns all of them.
+func (c *Coordinator) History(limit int) []JobRecord {
+	if c.history == nil {
+		return []JobRecord{}
+	}
+	return c.history.list(limit)
+}
+
+// jobSucceeded reports whether a job that returned err counts as a success.
+// Jobs for resources that were already complete have nothing left to do.
+func jobSucceeded(err error) bool {
+	switch err.(type) {
+	case nil, ResourceAlreadyCompleteError:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Coordinator) recordJob(job *metadata.CoordinatorJob, jobErr error) {
+	record := JobRecord{
+		Resource: job.Resource,
+		LastRun:  time.Now().UTC(),
+		Attempts: job.Attempts,
+		Status:   metadata.READY,
+	}
+	if !jobSucceeded(jobErr) {
+		record.Status = metadata.FAILED
+		record.Error = jobErr.Error()
+	}
+	if c.history != nil {
+		c.history.record(record)
+	}
+	if !c.PersistHistory {
+		return
+	}
+	serialized, err := record.Serialize()
+	if err != nil {
+		c.Logger.Errorw("Could not serialize job record", "resource", job.Resource, "error", err)
+		return
+	}
+	if _, err := (*c.KVClient).Put(context.Background(), jobHistoryKey(job.Resource), string(serialized)); err != nil {
+		c.Logger.Errorw("Could not persist job record", "resource", job.Resource, "error", err)
+	}
+}
@@ -0,0 +1,33 @@
+package coordinator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/featureform/metadata"
+)
+
+func TestJobHistoryList(t *testing.T) {
+	history := newJobHistory()
+	first := metadata.ResourceID{Name: "first", Type: metadata.FEATURE_VARIANT}
+	second := metadata.ResourceID{Name: "second", Type: metadata.LABEL_VARIANT}
+	now := time.Now()
+	history.record(JobRecord{Resource: first, LastRun: now, Attempts: 1, Status: metadata.FAILED})
+	history.record(JobRecord{Resource: second, LastRun: now.Add(time.Second), Attempts: 1, Status: metadata.READY})
+	// A later run of the same resource replaces its earlier record
+	history.record(JobRecord{Resource: first, LastRun: now.Add(2 * time.Second), Attempts: 2, Status: metadata.READY})
+
+	records := history.list(0)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if records[0].Resource != first || records[0].Attempts != 2 || records[0].Status != metadata.READY {
+		t.Fatalf("expected latest run of first resource, got %v", records[0])
+	}
+	if records[1].Resource != second {
+		t.Fatalf("expected second resource, got %v", records[1])
+	}
+	if limited := history.list(1); len(limited) != 1 || limited[0].Resource != first {
+		t.Fatalf("expected only the most recent record, got %v", limited)
+	}
+}
@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/featureform/config"
 	"github.com/featureform/coordinator"
 	help "github.com/featureform/helpers"
 	"github.com/featureform/logging"
 	"github.com/featureform/metadata"
 	"github.com/featureform/runner"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	corev1 "k8s.io/api/core/v1"
 )
 
 func main() {
@@ -33,12 +43,6 @@ func main() {
 		panic(err)
 	}
 	fmt.Println("connected to etcd")
-	defer func(cli *clientv3.Client) {
-		err := cli.Close()
-		if err != nil {
-			panic(fmt.Errorf("failed to close etcd client: %w", err))
-		}
-	}(cli)
 	if err := runner.RegisterFactory(string(runner.COPY_TO_ONLINE), runner.MaterializedChunkRunnerFactory); err != nil {
 		panic(fmt.Errorf("failed to register 'Copy to Online' runner factory: %w", err))
 	}
@@ -64,17 +68,100 @@ func main() {
 	if useK8sRunner == "false" {
 		spawner = &coordinator.MemoryJobSpawner{}
 	} else {
-		spawner = &coordinator.KubernetesJobSpawner{EtcdConfig: etcdConfig}
+		var tolerations []corev1.Toleration
+		if raw := config.GetJobTolerations(); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &tolerations); err != nil {
+				logger.Errorw("Failed to parse JOB_TOLERATIONS", "error", err)
+				panic(err)
+			}
+		}
+		spawner = &coordinator.KubernetesJobSpawner{
+			EtcdConfig: etcdConfig,
+			Specs: metadata.KubernetesResourceSpecs{
+				CPURequest:    config.GetJobCPURequest(),
+				CPULimit:      config.GetJobCPULimit(),
+				MemoryRequest: config.GetJobMemoryRequest(),
+				MemoryLimit:   config.GetJobMemoryLimit(),
+			},
+			NodeSelector: config.GetJobNodeSelector(),
+			Tolerations:  tolerations,
+		}
 	}
-	coord, err := coordinator.NewCoordinator(client, logger, cli, spawner)
+	var opts []coordinator.CoordinatorOption
+	if healthPort := help.GetEnv("HEALTH_PORT", ""); healthPort != "" {
+		opts = append(opts, coordinator.WithHealthServer(fmt.Sprintf(":%s", healthPort)))
+	}
+	coord, err := coordinator.NewCoordinator(client, logger, cli, spawner, opts...)
 	if err != nil {
 		logger.Errorw("Failed to set up coordinator: %v", err)
 		panic(err)
 	}
+	coord.RunnerTimeout = config.GetRunnerTimeout()
+	coord.RunnerGracePeriod = config.GetRunnerGracePeriod()
+	defer func() {
+		if err := coord.Close(); err != nil {
+			logger.Errorw("Failed to close coordinator", "error", err)
+		}
+	}()
+	if metricsPort := help.GetEnv("METRICS_PORT", ""); metricsPort != "" {
+		registry := prometheus.NewRegistry()
+		if err := coord.RegisterMetrics(registry); err != nil {
+			logger.Errorw("Failed to register coordinator metrics", "error", err)
+			panic(err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		go func() {
+			if err := http.ListenAndServe(fmt.Sprintf(":%s", metricsPort), mux); err != nil {
+				logger.Errorw("Metrics server stopped", "error", err)
+			}
+		}()
+	}
+	if kafkaURL := help.GetEnv("KAFKA_REST_PROXY_URL", ""); kafkaURL != "" {
+		sink, err := coordinator.NewKafkaEventSink(coordinator.KafkaEventSinkConfig{
+			RestProxyURL: kafkaURL,
+			Topic:        help.GetEnv("KAFKA_EVENT_TOPIC", "featureform-resource-status"),
+		})
+		if err != nil {
+			logger.Errorw("Failed to set up kafka event sink: %v", err)
+			panic(err)
+		}
+		coord.EventSink = sink
+		logger.Debug("Publishing resource status events to kafka")
+	}
+	go func() {
+		if err := coord.WatchForAbandonedJobs(); err != nil {
+			logger.Errorw("Failed to watch for abandoned jobs", "error", err)
+		}
+	}()
+	go func() {
+		if err := coord.WatchForRecurringJobs(); err != nil {
+			logger.Errorw("Failed to watch for recurring jobs", "error", err)
+		}
+	}()
+	// On SIGTERM, jobs that are running are given SHUTDOWN_TIMEOUT_SECONDS to
+	// finish before they're left for another coordinator
+	shutdownTimeout := time.Duration(help.GetEnvInt("SHUTDOWN_TIMEOUT_SECONDS", 30)) * time.Second
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+		sig := <-signals
+		logger.Infow("Shutting down coordinator", "signal", sig.String(), "timeout", shutdownTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := coord.Shutdown(ctx); err != nil {
+			logger.Errorw("Failed to shut down coordinator cleanly", "error", err)
+		}
+	}()
 	logger.Debug("Begin Job Watch")
 	if err := coord.WatchForNewJobs(); err != nil {
 		logger.Errorw(err.Error())
 		panic(err)
 		return
 	}
+	// The watch only stops once Shutdown has begun, which is waited for so
+	// that running jobs can finish
+	<-shutdownDone
 }
@@ -0,0 +1,105 @@
+package coordinator
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/featureform/metadata"
+)
+
+// The job types that durations are observed for, as the job_type label
+const (
+	trainingSetJobType       = "training_set"
+	materializeJobType       = "materialize"
+	sqlTransformationJobType = "sql_transformation"
+)
+
+// jobMetrics counts the jobs a coordinator runs. Each coordinator has its own,
+// rather than using prometheus's default registry, so that coordinators in the
+// same process report separately. A nil jobMetrics records nothing.
+type jobMetrics struct {
+	// Labeled by the type of resource each job is for
+	started   *prometheus.CounterVec
+	succeeded *prometheus.CounterVec
+	failed    *prometheus.CounterVec
+	running   prometheus.Gauge
+	// Labeled by job type, such as materializeJobType
+	duration *prometheus.HistogramVec
+}
+
+func newJobMetrics() *jobMetrics {
+	return &jobMetrics{
+		started: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "featureform_coordinator_jobs_started_total",
+			Help: "Jobs the coordinator has started, labeled by resource type",
+		}, []string{"resource_type"}),
+		succeeded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "featureform_coordinator_jobs_succeeded_total",
+			Help: "Jobs the coordinator has finished successfully, labeled by resource type",
+		}, []string{"resource_type"}),
+		failed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "featureform_coordinator_jobs_failed_total",
+			Help: "Jobs the coordinator has failed, labeled by resource type",
+		}, []string{"resource_type"}),
+		running: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "featureform_coordinator_jobs_running",
+			Help: "Jobs the coordinator is running",
+		}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "featureform_coordinator_job_duration_seconds",
+			Help:    "How long the coordinator's jobs took, labeled by job type",
+			Buckets: prometheus.ExponentialBuckets(0.1, 4, 10),
+		}, []string{"job_type"}),
+	}
+}
+
+func (m *jobMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.started, m.succeeded, m.failed, m.running, m.duration}
+}
+
+// jobStarted counts a job for a resource of resType as started and running.
+// The returned function is called with the job's error once it's finished.
+func (m *jobMetrics) jobStarted(resType metadata.ResourceType) func(error) {
+	if m == nil {
+		return func(error) {}
+	}
+	label := resType.String()
+	m.started.WithLabelValues(label).Inc()
+	m.running.Inc()
+	return func(err error) {
+		m.running.Dec()
+		if jobSucceeded(err) {
+			m.succeeded.WithLabelValues(label).Inc()
+		} else {
+			m.failed.WithLabelValues(label).Inc()
+		}
+	}
+}
+
+// timeJob starts timing a job of jobType. The returned function observes how
+// long it took once it's finished.
+func (m *jobMetrics) timeJob(jobType string) func() {
+	if m == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		m.duration.WithLabelValues(jobType).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterMetrics registers the coordinator's job metrics with registry, so
+// they're exported wherever it's served. The metrics are recorded whether or
+// not they're registered.
+func (c *Coordinator) RegisterMetrics(registry *prometheus.Registry) error {
+	if c.metrics == nil {
+		c.metrics = newJobMetrics()
+	}
+	for _, collector := range c.metrics.collectors() {
+		if err := registry.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package coordinator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/featureform/metadata"
+)
+
+func counterValue(t *testing.T, vec *prometheus.CounterVec, label string) float64 {
+	m := &dto.Metric{}
+	if err := vec.WithLabelValues(label).Write(m); err != nil {
+		t.Fatalf("could not read counter: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func gaugeValue(t *testing.T, gauge prometheus.Gauge) float64 {
+	m := &dto.Metric{}
+	if err := gauge.Write(m); err != nil {
+		t.Fatalf("could not read gauge: %v", err)
+	}
+	return m.GetGauge().GetValue()
+}
+
+func histogramCount(t *testing.T, vec *prometheus.HistogramVec, label string) uint64 {
+	m := &dto.Metric{}
+	if err := vec.WithLabelValues(label).(prometheus.Histogram).Write(m); err != nil {
+		t.Fatalf("could not read histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestJobMetrics(t *testing.T) {
+	c := &Coordinator{}
+	if err := c.RegisterMetrics(prometheus.NewRegistry()); err != nil {
+		t.Fatalf("could not register metrics: %v", err)
+	}
+	feature := metadata.FEATURE_VARIANT.String()
+
+	succeeded := c.metrics.jobStarted(metadata.FEATURE_VARIANT)
+	failed := c.metrics.jobStarted(metadata.FEATURE_VARIANT)
+	if running := gaugeValue(t, c.metrics.running); running != 2 {
+		t.Fatalf("expected 2 running jobs, got %v", running)
+	}
+	succeeded(nil)
+	failed(errors.New("job failed"))
+	c.metrics.jobStarted(metadata.FEATURE_VARIANT)(ResourceAlreadyCompleteError{})
+	if running := gaugeValue(t, c.metrics.running); running != 0 {
+		t.Fatalf("expected no running jobs, got %v", running)
+	}
+	if started := counterValue(t, c.metrics.started, feature); started != 3 {
+		t.Fatalf("expected 3 started jobs, got %v", started)
+	}
+	if succeeded := counterValue(t, c.metrics.succeeded, feature); succeeded != 2 {
+		t.Fatalf("expected 2 succeeded jobs, got %v", succeeded)
+	}
+	if failed := counterValue(t, c.metrics.failed, feature); failed != 1 {
+		t.Fatalf("expected 1 failed job, got %v", failed)
+	}
+	c.metrics.timeJob(materializeJobType)()
+	if count := histogramCount(t, c.metrics.duration, materializeJobType); count != 1 {
+		t.Fatalf("expected 1 materialize duration, got %d", count)
+	}
+
+	// Another coordinator reports separately, even to the same registry
+	registry := prometheus.NewRegistry()
+	other := &Coordinator{}
+	if err := other.RegisterMetrics(registry); err != nil {
+		t.Fatalf("could not register other coordinator's metrics: %v", err)
+	}
+	if started := counterValue(t, other.metrics.started, feature); started != 0 {
+		t.Fatalf("expected the other coordinator to have started no jobs, got %v", started)
+	}
+	if err := c.RegisterMetrics(registry); err == nil {
+		t.Fatalf("expected registering a second coordinator's metrics with the same registry to fail")
+	}
+
+	// Coordinators that weren't built by NewCoordinator record nothing
+	var unregistered *jobMetrics
+	unregistered.jobStarted(metadata.FEATURE_VARIANT)(nil)
+	unregistered.timeJob(materializeJobType)()
+}
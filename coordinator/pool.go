@@ -0,0 +1,57 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/featureform/metadata"
+)
+
+// The order jobs found when WatchForNewJobs starts are queued in, so that the
+// resources others wait on get a slot first
+var jobQueueOrder = []metadata.ResourceType{
+	metadata.SOURCE_VARIANT,
+	metadata.LABEL_VARIANT,
+	metadata.FEATURE_VARIANT,
+	metadata.TRAINING_SET_VARIANT,
+}
+
+func jobQueuePriority(jobKey string) int {
+	for i, resType := range jobQueueOrder {
+		if strings.HasPrefix(jobKey, fmt.Sprintf("JOB__%s__", resType)) {
+			return i
+		}
+	}
+	return len(jobQueueOrder)
+}
+
+// sortJobQueue orders job keys by jobQueueOrder, keeping the order of jobs of
+// the same type
+func sortJobQueue(jobKeys []string) {
+	sort.SliceStable(jobKeys, func(i, j int) bool {
+		return jobQueuePriority(jobKeys[i]) < jobQueuePriority(jobKeys[j])
+	})
+}
+
+// queueJob runs a job watched for by WatchForNewJobs once one of the
+// coordinator's job slots is free. Jobs that another coordinator, or another of
+// this coordinator's slots, holds the lock for are skipped rather than waited
+// on, since whoever holds the lock runs them, and WatchForAbandonedJobs reruns
+// them if it dies. Coordinators created without a pool run every job at once.
+func (c *Coordinator) queueJob(jobKey string) {
+	go func() {
+		if c.jobSlots != nil {
+			select {
+			case c.jobSlots <- struct{}{}:
+			case <-c.watchContext().Done():
+				return
+			}
+			defer func() { <-c.jobSlots }()
+		}
+		if err := c.executeJob(context.Background(), jobKey, false); err != nil {
+			c.checkError(err, jobKey)
+		}
+	}()
+}
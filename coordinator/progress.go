@@ -0,0 +1,72 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/featureform/metadata"
+	"github.com/featureform/runner"
+	"github.com/featureform/types"
+)
+
+func jobProgressKey(id metadata.ResourceID) string {
+	return fmt.Sprintf("PROGRESS__%s__%s__%s", id.Type, id.Name, id.Variant)
+}
+
+type etcdProgressRecorder struct {
+	kv  clientv3.KV
+	key string
+}
+
+// NewEtcdProgressRecorder returns a recorder that saves the progress of a
+// resource's job in etcd, where GetJobProgress reads it from.
+func NewEtcdProgressRecorder(kv clientv3.KV, resID metadata.ResourceID) runner.ProgressRecorder {
+	return etcdProgressRecorder{kv: kv, key: jobProgressKey(resID)}
+}
+
+func (r etcdProgressRecorder) RecordProgress(progress runner.JobProgress) error {
+	serialized, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("serialize job progress: %w", err)
+	}
+	if _, err := r.kv.Put(context.Background(), r.key, string(serialized)); err != nil {
+		return fmt.Errorf("save job progress: %w", err)
+	}
+	return nil
+}
+
+// GetJobProgress returns the progress recorded by a resource's running job. It
+// returns ErrNoJobProgress if the job isn't running or doesn't record its
+// progress.
+func (c *Coordinator) GetJobProgress(resID metadata.ResourceID) (runner.JobProgress, error) {
+	resp, err := (*c.KVClient).Get(context.Background(), jobProgressKey(resID))
+	if err != nil {
+		return runner.JobProgress{}, fmt.Errorf("get job progress: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return runner.JobProgress{}, fmt.Errorf("%w: %s %s (%s)", ErrNoJobProgress, resID.Type, resID.Name, resID.Variant)
+	}
+	progress := runner.JobProgress{}
+	if err := json.Unmarshal(resp.Kvs[0].Value, &progress); err != nil {
+		return runner.JobProgress{}, fmt.Errorf("deserialize job progress: %w", err)
+	}
+	return progress, nil
+}
+
+// recordJobProgress has jobRunner record its progress if it can. The returned
+// function deletes whatever was recorded, and is called once the job finishes.
+// Runners started in worker pods record their progress themselves, so it's
+// deleted even if jobRunner can't record it.
+func (c *Coordinator) recordJobProgress(resID metadata.ResourceID, jobRunner types.Runner) func() {
+	if recording, ok := jobRunner.(runner.ProgressRecordingRunner); ok {
+		recording.SetProgressRecorder(NewEtcdProgressRecorder(*c.KVClient, resID))
+	}
+	return func() {
+		if _, err := (*c.KVClient).Delete(context.Background(), jobProgressKey(resID)); err != nil {
+			c.Logger.Warnw("Could not delete job progress", "resource", resID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/featureform/metadata"
+	"github.com/featureform/provider"
+	pt "github.com/featureform/provider/provider_type"
+)
+
+// checkQuota rejects a job whose sources have more rows in total than the
+// quota for its type in JobRowQuotas. A job whose size can't be estimated, such
+// as one reading a source that isn't ready yet, is let through. Resources that
+// are already READY or FAILED are left for the job itself to handle, so that
+// a ready resource isn't failed by a quota added after it was created.
+func (c *Coordinator) checkQuota(resID metadata.ResourceID) error {
+	quota, has := c.JobRowQuotas[resID.Type]
+	if !has || quota <= 0 {
+		return nil
+	}
+	if status, err := c.resourceStatus(resID); err == nil && (status == metadata.READY || status == metadata.FAILED) {
+		return nil
+	}
+	rows, err := c.estimateJobRows(resID)
+	if err != nil {
+		c.Logger.Warnw("Could not estimate job size, skipping quota check", "resource", resID, "error", err)
+		return nil
+	}
+	if rows > quota {
+		return fmt.Errorf("%w: %s %s (%s) reads an estimated %d rows, more than its quota of %d", ErrQuotaExceeded, resID.Type, resID.Name, resID.Variant, rows, quota)
+	}
+	return nil
+}
+
+// estimateJobRows returns the total number of rows in the sources a job reads
+func (c *Coordinator) estimateJobRows(resID metadata.ResourceID) (int64, error) {
+	sources, err := c.jobSources(resID)
+	if err != nil {
+		return 0, err
+	}
+	total := int64(0)
+	for _, nameVariant := range sources {
+		rows, err := c.sourceRows(nameVariant)
+		if err != nil {
+			return 0, fmt.Errorf("count rows of source %s (%s): %w", nameVariant.Name, nameVariant.Variant, err)
+		}
+		total += rows
+	}
+	return total, nil
+}
+
+// jobSources returns the sources a job reads. Registering a primary source
+// doesn't read another source, so it has none.
+func (c *Coordinator) jobSources(resID metadata.ResourceID) ([]metadata.NameVariant, error) {
+	ctx := context.Background()
+	nameVariant := metadata.NameVariant{Name: resID.Name, Variant: resID.Variant}
+	switch resID.Type {
+	case metadata.SOURCE_VARIANT:
+		source, err := c.Metadata.GetSourceVariant(ctx, nameVariant)
+		if err != nil {
+			return nil, err
+		}
+		if source.IsSQLTransformation() {
+			return source.SQLTransformationSources(), nil
+		}
+		if source.IsDFTransformation() {
+			return source.DFTransformationSources(), nil
+		}
+		return nil, nil
+	case metadata.FEATURE_VARIANT:
+		feature, err := c.Metadata.GetFeatureVariant(ctx, nameVariant)
+		if err != nil {
+			return nil, err
+		}
+		return []metadata.NameVariant{feature.Source()}, nil
+	case metadata.LABEL_VARIANT:
+		label, err := c.Metadata.GetLabelVariant(ctx, nameVariant)
+		if err != nil {
+			return nil, err
+		}
+		return []metadata.NameVariant{label.Source()}, nil
+	case metadata.TRAINING_SET_VARIANT:
+		ts, err := c.Metadata.GetTrainingSetVariant(ctx, nameVariant)
+		if err != nil {
+			return nil, err
+		}
+		label, err := c.Metadata.GetLabelVariant(ctx, ts.Label())
+		if err != nil {
+			return nil, err
+		}
+		sources := []metadata.NameVariant{label.Source()}
+		for _, featureNameVariant := range ts.Features() {
+			feature, err := c.Metadata.GetFeatureVariant(ctx, featureNameVariant)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, feature.Source())
+		}
+		return sources, nil
+	default:
+		return nil, fmt.Errorf("not a valid resource type for running jobs")
+	}
+}
+
+func (c *Coordinator) sourceRows(nameVariant metadata.NameVariant) (int64, error) {
+	ctx := context.Background()
+	source, err := c.Metadata.GetSourceVariant(ctx, nameVariant)
+	if err != nil {
+		return 0, err
+	}
+	sourceProvider, err := source.FetchProvider(c.Metadata, ctx)
+	if err != nil {
+		return 0, fmt.Errorf("fetch provider: %w", err)
+	}
+	p, err := provider.Get(pt.Type(sourceProvider.Type()), sourceProvider.SerializedConfig())
+	if err != nil {
+		return 0, fmt.Errorf("get provider: %w", err)
+	}
+	store, err := p.AsOfflineStore()
+	if err != nil {
+		return 0, fmt.Errorf("convert provider to offline store: %w", err)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			c.Logger.Errorf("could not close offline store: %v", err)
+		}
+	}()
+	table, err := featureSourceTable(store, source)
+	if err != nil {
+		return 0, fmt.Errorf("get table: %w", err)
+	}
+	return table.NumRows()
+}
@@ -0,0 +1,213 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gorhill/cronexpr"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/featureform/metadata"
+	"github.com/featureform/provider"
+	pt "github.com/featureform/provider/provider_type"
+	"github.com/featureform/runner"
+)
+
+// Prefixes of the keys holding the schedules of recurring jobs, and the keys
+// coordinators race to create to run one of their ticks
+const (
+	recurringJobPrefix  = "RECURRINGJOB__"
+	recurringTickPrefix = "RECURRINGTICK__"
+)
+
+// How long the key claiming a tick is kept. It only has to outlive the
+// difference between coordinators' clocks, so that a coordinator running late
+// still sees that the tick was claimed.
+const recurringTickTTL = 5 * 60
+
+func recurringJobKey(id metadata.ResourceID) string {
+	return fmt.Sprintf("%s%s__%s__%s", recurringJobPrefix, id.Type, id.Name, id.Variant)
+}
+
+// scheduleJob registers a recurring job that reruns a resource's job on each
+// tick of cronExpr. The schedule is stored in etcd, so that every coordinator
+// watching for recurring jobs keeps it running. On each tick, they race to
+// create a key held by a lease, and only the one that creates it reruns the job.
+func (c *Coordinator) scheduleJob(resID metadata.ResourceID, cronExpr string) error {
+	if _, err := cronexpr.Parse(cronExpr); err != nil {
+		return fmt.Errorf("invalid schedule %q: %v", cronExpr, err)
+	}
+	job := &metadata.CoordinatorScheduleJob{Resource: resID, Schedule: cronExpr}
+	serialized, err := job.Serialize()
+	if err != nil {
+		return fmt.Errorf("serialize recurring job: %v", err)
+	}
+	key := recurringJobKey(resID)
+	if _, err := (*c.KVClient).Put(context.Background(), key, string(serialized)); err != nil {
+		return fmt.Errorf("save recurring job: %v", err)
+	}
+	c.Logger.Infow("Scheduled recurring job", "resource", resID, "schedule", cronExpr)
+	return c.startRecurringJob(key, serialized)
+}
+
+// WatchForRecurringJobs runs the recurring jobs registered by any coordinator
+// until the coordinator is closed.
+func (c *Coordinator) WatchForRecurringJobs() error {
+	c.Logger.Info("Watching for recurring jobs")
+	getResp, err := (*c.KVClient).Get(context.Background(), recurringJobPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return fmt.Errorf("get existing recurring jobs: %v", err)
+	}
+	for _, kv := range getResp.Kvs {
+		if err := c.startRecurringJob(string(kv.Key), kv.Value); err != nil {
+			c.Logger.Errorw("Could not start recurring job", "key", string(kv.Key), "error", err)
+		}
+	}
+	c.watchPrefix(recurringJobPrefix, getResp.Header.Revision+1, func(ev *clientv3.Event) {
+		key := string(ev.Kv.Key)
+		if ev.Type == mvccpb.DELETE {
+			c.stopRecurringJob(key)
+			return
+		}
+		if err := c.startRecurringJob(key, ev.Kv.Value); err != nil {
+			c.Logger.Errorw("Could not start recurring job", "key", key, "error", err)
+		}
+	})
+	return nil
+}
+
+// startRecurringJob starts the loop of a recurring job, replacing the one
+// already running for its key, if any.
+func (c *Coordinator) startRecurringJob(key string, serialized []byte) error {
+	job := &metadata.CoordinatorScheduleJob{}
+	if err := job.Deserialize(serialized); err != nil {
+		return fmt.Errorf("deserialize recurring job: %v", err)
+	}
+	expr, err := cronexpr.Parse(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q: %v", job.Schedule, err)
+	}
+	ctx, cancel := context.WithCancel(c.watchContext())
+	c.recurringMtx.Lock()
+	if c.recurring == nil {
+		c.recurring = make(map[string]context.CancelFunc)
+	}
+	if running, has := c.recurring[key]; has {
+		running()
+	}
+	c.recurring[key] = cancel
+	c.recurringMtx.Unlock()
+	go c.runRecurringJob(ctx, key, job.Resource, expr)
+	return nil
+}
+
+func (c *Coordinator) stopRecurringJob(key string) {
+	c.recurringMtx.Lock()
+	defer c.recurringMtx.Unlock()
+	if cancel, has := c.recurring[key]; has {
+		cancel()
+		delete(c.recurring, key)
+	}
+}
+
+func (c *Coordinator) runRecurringJob(ctx context.Context, key string, resID metadata.ResourceID, expr *cronexpr.Expression) {
+	for {
+		tick := expr.Next(time.Now())
+		if tick.IsZero() {
+			c.Logger.Infow("Recurring job has no more ticks", "resource", resID)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(tick)):
+		}
+		claimed, err := c.claimRecurringTick(ctx, key, tick)
+		if err != nil {
+			c.Logger.Errorw("Could not claim recurring job tick", "resource", resID, "tick", tick, "error", err)
+			continue
+		}
+		if !claimed {
+			c.Logger.Debugw("Recurring job tick claimed by another coordinator", "resource", resID, "tick", tick)
+			continue
+		}
+		c.Logger.Infow("Rerunning recurring job", "resource", resID, "tick", tick)
+		if err := c.rerunJob(ctx, resID); err != nil {
+			c.Logger.Errorw("Recurring job failed", "resource", resID, "tick", tick, "error", err)
+		}
+	}
+}
+
+// claimRecurringTick reports whether this coordinator is the first to claim a
+// tick of a recurring job.
+func (c *Coordinator) claimRecurringTick(ctx context.Context, key string, tick time.Time) (bool, error) {
+	lease, err := c.EtcdClient.Grant(ctx, recurringTickTTL)
+	if err != nil {
+		return false, fmt.Errorf("grant lease: %v", err)
+	}
+	tickKey := fmt.Sprintf("%s%s__%d", recurringTickPrefix, strings.TrimPrefix(key, recurringJobPrefix), tick.Unix())
+	resp, err := (*c.KVClient).Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(tickKey), "=", 0)).
+		Then(clientv3.OpPut(tickKey, "", clientv3.WithLease(lease.ID))).
+		Commit()
+	if err != nil {
+		return false, fmt.Errorf("claim tick: %v", err)
+	}
+	return resp.Succeeded, nil
+}
+
+// rerunJob runs a recurring job's resource again, updating it in place
+func (c *Coordinator) rerunJob(ctx context.Context, resID metadata.ResourceID) error {
+	switch resID.Type {
+	case metadata.TRAINING_SET_VARIANT:
+		return c.rerunTrainingSetJob(ctx, resID)
+	default:
+		return fmt.Errorf("%s jobs can't be rerun on a schedule", resID.Type)
+	}
+}
+
+func (c *Coordinator) rerunTrainingSetJob(ctx context.Context, resID metadata.ResourceID) error {
+	ts, err := c.Metadata.GetTrainingSetVariant(ctx, metadata.NameVariant{Name: resID.Name, Variant: resID.Variant})
+	if err != nil {
+		return fmt.Errorf("fetch training set variant from metadata: %v", err)
+	}
+	providerEntry, err := ts.FetchProvider(c.Metadata, ctx)
+	if err != nil {
+		return fmt.Errorf("fetch training set variant offline provider: %v", err)
+	}
+	p, err := provider.Get(pt.Type(providerEntry.Type()), providerEntry.SerializedConfig())
+	if err != nil {
+		return fmt.Errorf("fetch offline store interface of training set provider: %w", err)
+	}
+	store, err := p.AsOfflineStore()
+	if err != nil {
+		return fmt.Errorf("convert training set provider to offline store interface: %w", err)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			c.Logger.Errorf("could not close offline store: %v", err)
+		}
+	}()
+	providerResID := provider.ResourceID{Name: resID.Name, Variant: resID.Variant, Type: provider.TrainingSet}
+	trainingSetDef, err := c.trainingSetDef(ctx, ts, providerResID)
+	if err != nil {
+		return err
+	}
+	config := runner.TrainingSetRunnerConfig{
+		OfflineType:   pt.Type(providerEntry.Type()),
+		OfflineConfig: providerEntry.SerializedConfig(),
+		Def:           trainingSetDef,
+		IsUpdate:      true,
+	}
+	if err := c.runTrainingSetRunner(ctx, resID, config, ts.KubernetesArgs(), store); err != nil {
+		return err
+	}
+	// Setting the status again marks when the training set was last updated
+	if err := c.setReady(ctx, resID); err != nil {
+		return fmt.Errorf("set training set update status: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/featureform/metadata"
+	"github.com/featureform/provider"
+	pt "github.com/featureform/provider/provider_type"
+)
+
+// ServeFeature returns a materialized feature's value for an entity, read from
+// the online store the feature was materialized to. If the entity has no value,
+// the error is a *provider.EntityNotFound.
+func (c *Coordinator) ServeFeature(ctx context.Context, featureVariant metadata.NameVariant, entity string) (interface{}, error) {
+	feature, err := c.Metadata.GetFeatureVariant(ctx, featureVariant)
+	if err != nil {
+		return nil, fmt.Errorf("get feature variant: %w", err)
+	}
+	onlineStore, err := c.featureOnlineStore(ctx, feature)
+	if err != nil {
+		return nil, err
+	}
+	defer onlineStore.Close()
+	table, err := onlineStore.GetTable(featureVariant.Name, featureVariant.Variant)
+	if err != nil {
+		return nil, fmt.Errorf("get online table: %w", err)
+	}
+	return table.Get(entity)
+}
+
+// featureOnlineStore connects to the online store a feature is materialized to.
+// The caller is responsible for closing it.
+func (c *Coordinator) featureOnlineStore(ctx context.Context, feature *metadata.FeatureVariant) (provider.OnlineStore, error) {
+	featureProvider, err := feature.FetchProvider(c.Metadata, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetch online provider: %w", err)
+	}
+	if !strings.HasSuffix(featureProvider.Type(), "_ONLINE") {
+		return nil, fmt.Errorf("feature provider %s is not an online store", featureProvider.Name())
+	}
+	online, err := provider.Get(pt.Type(featureProvider.Type()), featureProvider.SerializedConfig())
+	if err != nil {
+		return nil, err
+	}
+	return online.AsOnlineStore()
+}
@@ -0,0 +1,106 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// startJob counts a job as in flight until the returned function is called.
+// Once the coordinator is shutting down or closed, no more jobs are started.
+func (c *Coordinator) startJob() (func(), error) {
+	c.closedMtx.RLock()
+	defer c.closedMtx.RUnlock()
+	if c.closed || c.draining {
+		return nil, CoordinatorClosedError{}
+	}
+	c.inFlight.Add(1)
+	return c.inFlight.Done, nil
+}
+
+// stopJobsChan is closed when Shutdown stops waiting for jobs to finish
+func (c *Coordinator) stopJobsChan() chan struct{} {
+	c.stopJobsInit.Do(func() {
+		c.stopJobs = make(chan struct{})
+	})
+	return c.stopJobs
+}
+
+// jobContext returns a context for executing a job that's also cancelled if
+// Shutdown stops waiting for it
+func (c *Coordinator) jobContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := c.stopJobsChan()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+func (c *Coordinator) jobsStopped() bool {
+	select {
+	case <-c.stopJobsChan():
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown stops the coordinator from starting any more jobs and waits for the
+// ones it's executing to finish before closing it. If ctx is done first, the
+// jobs still running are stopped and their locks released, but they're left
+// in etcd, with their resources PENDING, for another coordinator to pick up.
+// Shutdown returns ctx's error straight away rather than waiting on their
+// work, which is cancelled but may not notice; the coordinator is closed in
+// the background once it has stopped.
+func (c *Coordinator) Shutdown(ctx context.Context) error {
+	c.closedMtx.Lock()
+	c.draining = true
+	c.closedMtx.Unlock()
+	// Stops the watch loops, so that no more jobs are queued
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.Logger.Info("Shutting down coordinator, waiting for running jobs to finish")
+	drained := make(chan struct{})
+	go func() {
+		c.inFlight.Wait()
+		c.jobWork.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return c.Close()
+	case <-ctx.Done():
+	}
+	c.Logger.Warnw("Stopping jobs that haven't finished so that another coordinator can run them", "error", ctx.Err(), "jobs", c.runningJobKeys())
+	c.stopJobsOnce.Do(func() {
+		close(c.stopJobsChan())
+	})
+	// Jobs return as soon as they're stopped, releasing their locks, but
+	// their work still uses the coordinator's clients until it notices it's
+	// been cancelled
+	go func() {
+		<-drained
+		if err := c.Close(); err != nil {
+			c.Logger.Errorw("Failed to close coordinator after its stopped jobs finished", "error", err)
+		}
+	}()
+	return fmt.Errorf("shutdown stopped running jobs: %w", ctx.Err())
+}
+
+// runningJobKeys returns the keys of the jobs this coordinator is executing
+func (c *Coordinator) runningJobKeys() []string {
+	c.runningMtx.Lock()
+	defer c.runningMtx.Unlock()
+	keys := make([]string, 0, len(c.running))
+	for key := range c.running {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
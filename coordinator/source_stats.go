@@ -0,0 +1,37 @@
+package coordinator
+
+import (
+	"context"
+	"time"
+
+	"github.com/featureform/metadata"
+	"github.com/featureform/provider"
+)
+
+// recordSourceStats counts the rows of a source's table, and sums its size if
+// the store can, and sets them on the source variant. Like recordFailedJob,
+// failing to is logged rather than returned, since the job has succeeded
+// either way.
+func (c *Coordinator) recordSourceStats(resID metadata.ResourceID, table provider.PrimaryTable) {
+	rows, err := table.NumRows()
+	if err != nil {
+		c.Logger.Errorw("Could not count rows of source table", "resource", resID, "error", err)
+		return
+	}
+	stats := metadata.SourceStats{
+		RowCount:   rows,
+		RecordedAt: time.Now().UTC(),
+	}
+	if sized, ok := table.(provider.SizedTable); ok {
+		size, err := sized.SizeBytes()
+		if err != nil {
+			c.Logger.Warnw("Could not get size of source table", "resource", resID, "error", err)
+		}
+		stats.SizeBytes = size
+	}
+	c.Logger.Infow("Source table stats", "resource", resID, "rows", stats.RowCount, "bytes", stats.SizeBytes)
+	nameVariant := metadata.NameVariant{Name: resID.Name, Variant: resID.Variant}
+	if err := c.Metadata.SetSourceStats(context.Background(), nameVariant, stats); err != nil {
+		c.Logger.Errorw("Could not record source stats", "resource", resID, "error", err)
+	}
+}
@@ -0,0 +1,169 @@
+package coordinator
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/featureform/metadata"
+)
+
+// The source property that lets a SQL transformation run statements other than
+// SELECT, such as DDL, when set to "true"
+const AllowNonSelectQueryProperty = "allow_non_select_query"
+
+func allowsNonSelectQuery(source *metadata.SourceVariant) bool {
+	return strings.EqualFold(source.Properties()[AllowNonSelectQueryProperty], "true")
+}
+
+// Statements that change data or schema, which read-only queries can't run
+var writeStatementKeywords = map[string]bool{
+	"INSERT":   true,
+	"UPDATE":   true,
+	"DELETE":   true,
+	"MERGE":    true,
+	"UPSERT":   true,
+	"DROP":     true,
+	"CREATE":   true,
+	"ALTER":    true,
+	"TRUNCATE": true,
+	"GRANT":    true,
+	"REVOKE":   true,
+}
+
+// nonSelectStatement returns the keyword of the first statement in query that
+// isn't read-only, or "" if every statement is a SELECT. Only each statement's
+// top level is checked: the keyword it starts with, the statement a WITH
+// clause's CTEs are followed by, and SELECT ... INTO, which creates a table.
+// Strings, quoted identifiers, comments and {{ }} template tokens are skipped.
+func nonSelectStatement(query string) string {
+	for _, statement := range sqlStatements(query) {
+		if keyword := statementKeyword(statement); keyword != "" {
+			return keyword
+		}
+	}
+	return ""
+}
+
+// statementKeyword returns the keyword that makes a statement's tokens not
+// read-only, if any
+func statementKeyword(tokens []sqlToken) string {
+	depth := 0
+	statement := ""
+	for _, token := range tokens {
+		switch token.text {
+		case "(":
+			depth++
+			continue
+		case ")":
+			depth--
+			continue
+		}
+		if !token.word {
+			continue
+		}
+		keyword := strings.ToUpper(token.text)
+		switch {
+		case statement == "":
+			// Parenthesized queries, such as (SELECT ...) UNION (SELECT ...),
+			// are whichever statement their first word starts
+			statement = keyword
+			if statement != "SELECT" && statement != "WITH" && statement != "VALUES" {
+				return statement
+			}
+		case depth > 0:
+		case statement == "WITH":
+			// The CTEs are followed by the statement that uses them
+			if writeStatementKeywords[keyword] {
+				return keyword
+			}
+			if keyword == "SELECT" || keyword == "VALUES" {
+				statement = keyword
+			}
+		case keyword == "INTO":
+			return "SELECT INTO"
+		}
+	}
+	return ""
+}
+
+type sqlToken struct {
+	text string
+	// Whether the token is an unquoted word, such as a keyword or name
+	word bool
+}
+
+// sqlStatements splits query into the tokens of each of its statements
+func sqlStatements(query string) [][]sqlToken {
+	statements := make([][]sqlToken, 0)
+	current := make([]sqlToken, 0)
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+		case r == ';':
+			if len(current) > 0 {
+				statements = append(statements, current)
+			}
+			current = make([]sqlToken, 0)
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			i = skipUntil(runes, i+2, "\n")
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i = skipUntil(runes, i+2, "*/")
+		case r == '{' && i+1 < len(runes) && runes[i+1] == '{':
+			// Template tokens name a source's table
+			i = skipUntil(runes, i+2, "}}")
+			current = append(current, sqlToken{text: "{{}}"})
+		case r == '\'' || r == '"' || r == '`':
+			i = skipQuoted(runes, i)
+			current = append(current, sqlToken{text: string(r)})
+		case r == '(' || r == ')':
+			current = append(current, sqlToken{text: string(r)})
+		case isWordRune(r):
+			start := i
+			for i+1 < len(runes) && isWordRune(runes[i+1]) {
+				i++
+			}
+			current = append(current, sqlToken{text: string(runes[start : i+1]), word: true})
+		default:
+			current = append(current, sqlToken{text: string(r)})
+		}
+	}
+	if len(current) > 0 {
+		statements = append(statements, current)
+	}
+	return statements
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// skipUntil returns the index of the last rune of the first end at or after
+// start, or of the last rune if there isn't one
+func skipUntil(runes []rune, start int, end string) int {
+	endRunes := []rune(end)
+	for i := start; i+len(endRunes) <= len(runes); i++ {
+		if string(runes[i:i+len(endRunes)]) == end {
+			return i + len(endRunes) - 1
+		}
+	}
+	return len(runes) - 1
+}
+
+// skipQuoted returns the index of the quote that closes the one at start. A
+// doubled quote inside is an escaped one.
+func skipQuoted(runes []rune, start int) int {
+	quote := runes[start]
+	for i := start + 1; i < len(runes); i++ {
+		if runes[i] != quote {
+			continue
+		}
+		if i+1 < len(runes) && runes[i+1] == quote {
+			i++
+			continue
+		}
+		return i
+	}
+	return len(runes) - 1
+}
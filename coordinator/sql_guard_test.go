@@ -0,0 +1,32 @@
+package coordinator
+
+import "testing"
+
+func TestNonSelectStatement(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"select", "SELECT entity, value FROM {{ transactions.default }} WHERE value > 10", ""},
+		{"lowercase select", "select * from {{transactions.default}};", ""},
+		{"cte", "WITH t AS (SELECT * FROM {{ transactions.default }}) SELECT entity FROM t", ""},
+		{"union of parenthesized queries", "(SELECT 1) UNION ALL (SELECT 2)", ""},
+		{"keywords in strings and comments", "-- DROP TABLE users\nSELECT 'update' AS \"delete\" /* INSERT */ FROM {{ t.v }}", ""},
+		{"keyword as a column", "SELECT updated_at, created FROM {{ t.v }}", ""},
+		{"update", "UPDATE {{ transactions.default }} SET value = 0", "UPDATE"},
+		{"update after comment", "/* fix */ -- values\n update {{ transactions.default }} SET value = 0", "UPDATE"},
+		{"second statement", "SELECT * FROM {{ t.v }}; DROP TABLE users", "DROP"},
+		{"create table as", "CREATE TABLE copy AS SELECT * FROM {{ t.v }}", "CREATE"},
+		{"insert after cte", "WITH t AS (SELECT 1) INSERT INTO users SELECT * FROM t", "INSERT"},
+		{"select into", "SELECT * INTO copy FROM {{ t.v }}", "SELECT INTO"},
+		{"escaped quote", "SELECT 'it''s' FROM {{ t.v }}; DELETE FROM users", "DELETE"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if statement := nonSelectStatement(test.query); statement != test.expected {
+				t.Fatalf("expected %q for %q, got %q", test.expected, test.query, statement)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cfg "github.com/featureform/config"
+	"github.com/featureform/metadata"
+	"github.com/featureform/provider"
+	"github.com/featureform/runner"
+)
+
+// canStreamMaterialization reports whether a feature can skip the staged
+// materialization and be streamed straight from its source table. Only
+// one-off, non-historical scalar features over primary sources qualify, since
+// filters, schedules, TTLs, historical tables and vector indexes all rely on
+// the materialize runner.
+func (c *Coordinator) canStreamMaterialization(source *metadata.SourceVariant, feature *metadata.FeatureVariant, schedule string) bool {
+	if !c.StreamPrimaryMaterializations || schedule != "" || c.VerifyMaterializations {
+		return false
+	}
+	if !(source.IsPrimaryDataSQLTable() || source.IsPrimaryDataQuery() || source.IsPrimaryDataIcebergTable()) {
+		return false
+	}
+	return feature.Filter() == "" && feature.TTL() == 0 && !feature.IsEmbedding() && feature.Properties()[FeatureOnlineHistoryProperty] != "true"
+}
+
+// streamMaterialization writes the newest value of each entity in a feature's
+// source table to the online store, reading the table once rather than
+// registering it in the offline store and copying it into a materialization
+// first. Rows are written in batches of up to the materialize buffer size, so
+// only one batch is held in memory. Timestamped rows are read oldest first, so
+// that each entity's newest value is written last, which needs a table the
+// offline store can sort. It returns false without writing anything if the
+// table can't be sorted or its columns don't line up with the feature's, so the
+// caller can fall back to the staged materialization.
+func (c *Coordinator) streamMaterialization(resID metadata.ResourceID, feature *metadata.FeatureVariant, table provider.PrimaryTable, columns metadata.ResourceVariantColumns, vType provider.ValueType) (bool, error) {
+	var iter provider.GenericTableIterator
+	var err error
+	if columns.TS == "" {
+		// Without timestamps the last row read for an entity wins
+		iter, err = table.IterateSegment(-1)
+	} else if ordered, ok := table.(provider.OrderedPrimaryTable); ok {
+		iter, err = ordered.IterateOrdered(columns.TS)
+	} else {
+		c.Logger.Infow("Source table can't be read in timestamp order, staging materialization", "resource", resID)
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("iterate source table: %w", err)
+	}
+	defer iter.Close()
+	entityIdx, valueIdx := -1, -1
+	for i, column := range iter.Columns() {
+		// SQL tables return their column names quoted
+		column = strings.Trim(column, "\"`")
+		switch {
+		case strings.EqualFold(column, columns.Entity):
+			entityIdx = i
+		case strings.EqualFold(column, columns.Value):
+			valueIdx = i
+		}
+	}
+	if entityIdx == -1 || valueIdx == -1 {
+		c.Logger.Infow("Source columns don't match feature, staging materialization", "resource", resID, "columns", iter.Columns())
+		return false, nil
+	}
+	c.Logger.Infow("Streaming materialization from source table", "resource", resID, "table", table.GetName())
+	onlineStore, err := c.featureOnlineStore(context.Background(), feature)
+	if err != nil {
+		return false, err
+	}
+	defer onlineStore.Close()
+	onlineTable, err := onlineStore.CreateTable(resID.Name, resID.Variant, vType)
+	if err != nil {
+		return false, fmt.Errorf("create online table: %w", err)
+	}
+	progress := runner.MaterializeProgress{TotalChunks: 1}
+	if numRows, err := table.NumRows(); err == nil {
+		progress.TotalRows = numRows
+	}
+	batchSize := cfg.GetMaterializeBufferSize()
+	// Rows read later replace earlier ones of the same entity within a batch,
+	// and batches are written in the order they're read
+	batch := make(map[string]interface{}, batchSize)
+	rows := int64(0)
+	flush := func() error {
+		for entity, value := range batch {
+			if err := onlineTable.Set(entity, value); err != nil {
+				return fmt.Errorf("set %s: %w", entity, err)
+			}
+		}
+		batch = make(map[string]interface{}, batchSize)
+		progress.RowsWritten = rows
+		c.reportMaterializeProgress(resID, progress)
+		return nil
+	}
+	for iter.Next() {
+		row := iter.Values()
+		rows++
+		if row[entityIdx] == nil {
+			continue
+		}
+		entity := fmt.Sprintf("%v", row[entityIdx])
+		batch[entity] = row[valueIdx]
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return false, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return false, fmt.Errorf("read source table: %w", err)
+	}
+	progress.CompletedChunks = 1
+	if err := flush(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,62 @@
+package coordinator
+
+import (
+	"time"
+
+	"github.com/featureform/metadata"
+)
+
+// How often a timed out job's work is probed during its grace period
+const gracePeriodProbeInterval = 500 * time.Millisecond
+
+// completionProbe reports whether a job's work has landed, such as its output
+// table existing, regardless of whether its runner has finished.
+type completionProbe func() bool
+
+// awaitRunner waits for a job's runner to finish. If it takes longer than
+// RunnerTimeout, the job is given RunnerGracePeriod more to finish, during which
+// probe is checked in case the work landed but the runner hasn't reported it.
+// The job only fails with a JobTimeoutError if neither happens by the end of the
+// grace period. probe may be nil for jobs whose output can't be checked.
+//
+// If awaitRunner returns before the runner finishes, it calls stop so that the
+// runner, and the goroutine waiting on it, end as soon as the runner can stop.
+func (c *Coordinator) awaitRunner(resID metadata.ResourceID, wait func() error, probe completionProbe, stop func()) error {
+	if c.RunnerTimeout <= 0 {
+		return wait()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- wait()
+	}()
+	timeout := time.NewTimer(c.RunnerTimeout)
+	defer timeout.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timeout.C:
+	}
+	c.Logger.Warnw("Job runner timed out, waiting for grace period", "resource", resID, "timeout", c.RunnerTimeout, "grace_period", c.RunnerGracePeriod)
+	grace := time.NewTimer(c.RunnerGracePeriod)
+	defer grace.Stop()
+	ticker := time.NewTicker(gracePeriodProbeInterval)
+	defer ticker.Stop()
+	for {
+		if probe != nil && probe() {
+			c.Logger.Infow("Timed out job's work completed within grace period", "resource", resID)
+			stop()
+			return nil
+		}
+		select {
+		case err := <-done:
+			if err == nil {
+				c.Logger.Infow("Timed out job finished within grace period", "resource", resID)
+			}
+			return err
+		case <-grace.C:
+			stop()
+			return JobTimeoutError{resourceID: resID, timeout: c.RunnerTimeout + c.RunnerGracePeriod}
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/featureform/metadata"
+)
+
+// VariantStatus is a variant of a resource along with its current status
+type VariantStatus struct {
+	Variant string
+	Status  metadata.ResourceStatus
+	Created time.Time
+}
+
+// ListVariants returns every variant of the named feature, label, source or
+// training set with its status, oldest first. resType can be either the
+// resource's type, such as metadata.SOURCE, or its variants' type, such as
+// metadata.SOURCE_VARIANT.
+func (c *Coordinator) ListVariants(name string, resType metadata.ResourceType) ([]VariantStatus, error) {
+	ctx := context.Background()
+	var statuses []VariantStatus
+	add := func(variant string, status metadata.ResourceStatus, created time.Time) {
+		statuses = append(statuses, VariantStatus{Variant: variant, Status: status, Created: created})
+	}
+	switch resType {
+	case metadata.FEATURE, metadata.FEATURE_VARIANT:
+		feature, err := c.Metadata.GetFeature(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("get feature: %w", err)
+		}
+		variants, err := feature.FetchVariants(c.Metadata, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("fetch feature variants: %w", err)
+		}
+		for _, variant := range variants {
+			add(variant.Variant(), variant.Status(), variant.Created())
+		}
+	case metadata.LABEL, metadata.LABEL_VARIANT:
+		label, err := c.Metadata.GetLabel(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("get label: %w", err)
+		}
+		variants, err := label.FetchVariants(c.Metadata, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("fetch label variants: %w", err)
+		}
+		for _, variant := range variants {
+			add(variant.Variant(), variant.Status(), variant.Created())
+		}
+	case metadata.SOURCE, metadata.SOURCE_VARIANT:
+		source, err := c.Metadata.GetSource(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("get source: %w", err)
+		}
+		variants, err := source.FetchVariants(c.Metadata, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("fetch source variants: %w", err)
+		}
+		for _, variant := range variants {
+			add(variant.Variant(), variant.Status(), variant.Created())
+		}
+	case metadata.TRAINING_SET, metadata.TRAINING_SET_VARIANT:
+		trainingSet, err := c.Metadata.GetTrainingSet(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("get training set: %w", err)
+		}
+		variants, err := trainingSet.FetchVariants(c.Metadata, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("fetch training set variants: %w", err)
+		}
+		for _, variant := range variants {
+			add(variant.Variant(), variant.Status(), variant.Created())
+		}
+	default:
+		return nil, fmt.Errorf("resources of type %s have no variants", resType)
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].Created.Before(statuses[j].Created)
+	})
+	return statuses, nil
+}
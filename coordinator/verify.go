@@ -0,0 +1,132 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/featureform/metadata"
+	"github.com/featureform/provider"
+	pt "github.com/featureform/provider/provider_type"
+)
+
+// How many of a feature's entities VerifyMaterialization compares
+const materializationSampleSize = 1000
+
+// VerifyMaterialization compares the online values of a random sample of a
+// materialized feature's entities with their latest values in its source, and
+// returns how many of them are missing from the online store or have a
+// different value. The offline store is only read, so values that changed since
+// the feature was materialized count as mismatches.
+func (c *Coordinator) VerifyMaterialization(featureVariant metadata.NameVariant) (int, error) {
+	mismatches, _, err := c.verifyMaterialization(featureVariant)
+	return mismatches, err
+}
+
+func (c *Coordinator) verifyMaterialization(featureVariant metadata.NameVariant) (int, int, error) {
+	ctx := context.Background()
+	feature, err := c.Metadata.GetFeatureVariant(ctx, featureVariant)
+	if err != nil {
+		return 0, 0, fmt.Errorf("get feature variant: %w", err)
+	}
+	source, err := c.Metadata.GetSourceVariant(ctx, feature.Source())
+	if err != nil {
+		return 0, 0, fmt.Errorf("get source variant: %w", err)
+	}
+	sourceProvider, err := source.FetchProvider(c.Metadata, ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("fetch offline provider: %w", err)
+	}
+	offline, err := provider.Get(pt.Type(sourceProvider.Type()), sourceProvider.SerializedConfig())
+	if err != nil {
+		return 0, 0, err
+	}
+	offlineStore, err := offline.AsOfflineStore()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer offlineStore.Close()
+	onlineStore, err := c.featureOnlineStore(ctx, feature)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer onlineStore.Close()
+	table, err := onlineStore.GetTable(featureVariant.Name, featureVariant.Variant)
+	if err != nil {
+		return 0, 0, fmt.Errorf("get online table: %w", err)
+	}
+	sampled, ok := offlineStore.(provider.SampledFeatureOfflineStore)
+	if !ok {
+		return 0, 0, fmt.Errorf("offline store %s cannot sample feature values", offlineStore.Type())
+	}
+	featureID := provider.ResourceID{Name: featureVariant.Name, Variant: featureVariant.Variant, Type: provider.Feature}
+	records, err := sampled.SampleLatestFeatureValues(featureID, materializationSampleSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("sample feature values: %w", err)
+	}
+	mismatches := 0
+	for _, record := range records {
+		value, err := table.Get(record.Entity)
+		var notFound *provider.EntityNotFound
+		if errors.As(err, &notFound) {
+			c.Logger.Debugw("Materialized entity missing from online store", "feature", featureVariant, "entity", record.Entity)
+			mismatches++
+			continue
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("get entity %s: %w", record.Entity, err)
+		}
+		if !featureValuesEqual(record.Value, value) {
+			c.Logger.Debugw("Materialized value differs from source", "feature", featureVariant, "entity", record.Entity, "source", record.Value, "online", value)
+			mismatches++
+		}
+	}
+	return mismatches, len(records), nil
+}
+
+// checkMaterialization fails a feature's materialization job if more of its
+// sampled values differ from the source than MaxMaterializationMismatches.
+func (c *Coordinator) checkMaterialization(resID metadata.ResourceID) error {
+	mismatches, sampled, err := c.verifyMaterialization(metadata.NameVariant{Name: resID.Name, Variant: resID.Variant})
+	if err != nil {
+		return fmt.Errorf("verify materialization: %w", err)
+	}
+	c.Logger.Infow("Verified materialization", "resource", resID, "sampled", sampled, "mismatches", mismatches)
+	if mismatches > c.MaxMaterializationMismatches {
+		return MaterializationMismatchError{resourceID: resID, mismatches: mismatches, sampled: sampled}
+	}
+	return nil
+}
+
+// featureValuesEqual compares a value read from an offline store with one read
+// from an online store. Stores don't agree on the width of numeric types, so
+// numbers are compared by value.
+func featureValuesEqual(offline, online interface{}) bool {
+	if reflect.DeepEqual(offline, online) {
+		return true
+	}
+	offlineNum, offlineIsNum := numericValue(offline)
+	onlineNum, onlineIsNum := numericValue(online)
+	if offlineIsNum && onlineIsNum {
+		return offlineNum == onlineNum
+	}
+	return fmt.Sprintf("%v", offline) == fmt.Sprintf("%v", online)
+}
+
+func numericValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,82 @@
+package coordinator
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Bounds of the delay before a watch loop subscribes again after losing its
+// etcd watch
+const (
+	watchReconnectMinDelay = 500 * time.Millisecond
+	watchReconnectMaxDelay = 30 * time.Second
+)
+
+// watchReconnectDelay returns how long to wait before the attempt'th reconnect
+// in a row. The delay doubles with each attempt up to watchReconnectMaxDelay,
+// and a random half of it is dropped so that coordinators that lost etcd at the
+// same time don't all reconnect at once.
+func watchReconnectDelay(attempt int) time.Duration {
+	delay := watchReconnectMinDelay
+	for i := 1; i < attempt && delay < watchReconnectMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > watchReconnectMaxDelay {
+		delay = watchReconnectMaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// watchPrefix calls handle for each change to a key under prefix, starting at
+// revision startRev, or at the current revision if startRev is 0. If the watch
+// ends, such as when the etcd connection drops, it subscribes again from the
+// revision after the last event it handled, so no changes are skipped or
+// handled twice, backing off between attempts with watchReconnectDelay. It only
+// returns once the coordinator is closed.
+func (c *Coordinator) watchPrefix(prefix string, startRev int64, handle func(*clientv3.Event)) {
+	nextRev := startRev
+	attempt := 0
+	for {
+		ctx := c.watchContext()
+		if ctx.Err() != nil {
+			return
+		}
+		opts := []clientv3.OpOption{clientv3.WithPrefix()}
+		if nextRev > 0 {
+			opts = append(opts, clientv3.WithRev(nextRev))
+		}
+		watchCtx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+		for wresp := range c.EtcdClient.Watch(watchCtx, prefix, opts...) {
+			if wresp.CompactRevision != 0 {
+				// The revisions we missed are gone, so pick up from the oldest one left
+				c.Logger.Warnw("Watch revision was compacted, changes may have been missed", "prefix", prefix, "revision", nextRev, "compact_revision", wresp.CompactRevision)
+				nextRev = wresp.CompactRevision
+				break
+			}
+			if err := wresp.Err(); err != nil {
+				c.Logger.Warnw("Watch failed", "prefix", prefix, "error", err)
+				break
+			}
+			attempt = 0
+			for _, ev := range wresp.Events {
+				handle(ev)
+				nextRev = ev.Kv.ModRevision + 1
+			}
+		}
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		attempt++
+		delay := watchReconnectDelay(attempt)
+		c.Logger.Warnw("Watch disconnected, reconnecting", "prefix", prefix, "attempt", attempt, "delay", delay, "revision", nextRev)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
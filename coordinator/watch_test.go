@@ -0,0 +1,124 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+func TestWatchReconnectDelay(t *testing.T) {
+	for attempt := 1; attempt <= 20; attempt++ {
+		for i := 0; i < 100; i++ {
+			delay := watchReconnectDelay(attempt)
+			if delay < watchReconnectMinDelay/2 || delay > watchReconnectMaxDelay {
+				t.Fatalf("attempt %d: delay %s out of bounds", attempt, delay)
+			}
+		}
+	}
+	if delay := watchReconnectDelay(1); delay > watchReconnectMinDelay {
+		t.Fatalf("expected first reconnect to wait at most %s, got %s", watchReconnectMinDelay, delay)
+	}
+	if delay := watchReconnectDelay(20); delay < watchReconnectMaxDelay/2 {
+		t.Fatalf("expected repeated reconnects to back off to at least %s, got %s", watchReconnectMaxDelay/2, delay)
+	}
+}
+
+// droppingWatcher ends the first watch after its first response, the way a
+// dropped etcd connection would
+type droppingWatcher struct {
+	clientv3.Watcher
+	dropped int32
+}
+
+func (w *droppingWatcher) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	if !atomic.CompareAndSwapInt32(&w.dropped, 0, 1) {
+		return w.Watcher.Watch(ctx, key, opts...)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	upstream := w.Watcher.Watch(ctx, key, opts...)
+	ch := make(chan clientv3.WatchResponse)
+	go func() {
+		defer close(ch)
+		defer cancel()
+		if resp, ok := <-upstream; ok {
+			ch <- resp
+		}
+	}()
+	return ch
+}
+
+func TestWatchReconnect(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{fmt.Sprintf("%s:%s", etcdHost, etcdPort)}})
+	if err != nil {
+		t.Fatalf("could not connect to etcd: %v", err)
+	}
+	cli.Watcher = &droppingWatcher{Watcher: cli.Watcher}
+	coord, err := NewCoordinator(nil, zap.NewExample().Sugar(), cli, &MemoryJobSpawner{})
+	if err != nil {
+		t.Fatalf("could not create coordinator: %v", err)
+	}
+	defer coord.Close()
+
+	prefix := fmt.Sprintf("TEST_WATCH_%s_", uuid.NewString())
+	getResp, err := cli.Get(context.Background(), prefix, clientv3.WithPrefix())
+	if err != nil {
+		t.Fatalf("could not get current revision: %v", err)
+	}
+	keys := make(chan string, 10)
+	stopped := make(chan struct{})
+	go func() {
+		coord.watchPrefix(prefix, getResp.Header.Revision+1, func(ev *clientv3.Event) {
+			keys <- string(ev.Kv.Key)
+		})
+		close(stopped)
+	}()
+	receive := func() string {
+		select {
+		case key := <-keys:
+			return key
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for watch event")
+			return ""
+		}
+	}
+
+	expected := []string{prefix + "before_drop", prefix + "while_reconnecting", prefix + "after_reconnect"}
+	if _, err := cli.Put(context.Background(), expected[0], ""); err != nil {
+		t.Fatalf("could not put key: %v", err)
+	}
+	received := []string{receive()}
+	// The watch has now been dropped, so this is written before it reconnects
+	if _, err := cli.Put(context.Background(), expected[1], ""); err != nil {
+		t.Fatalf("could not put key: %v", err)
+	}
+	received = append(received, receive())
+	if _, err := cli.Put(context.Background(), expected[2], ""); err != nil {
+		t.Fatalf("could not put key: %v", err)
+	}
+	received = append(received, receive())
+	if !reflect.DeepEqual(expected, received) {
+		t.Fatalf("expected events %v, got %v", expected, received)
+	}
+	select {
+	case key := <-keys:
+		t.Fatalf("expected each event to be handled once, got %s again", key)
+	case <-time.After(time.Second):
+	}
+
+	coord.Close()
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("watch did not stop after the coordinator was closed")
+	}
+}
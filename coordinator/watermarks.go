@@ -0,0 +1,42 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/featureform/metadata"
+)
+
+// Prefix for the newest timestamp each feature has been incrementally
+// materialized up to. It must not start with "JOB_".
+const materializeWatermarkPrefix = "materialize-watermarks/"
+
+func materializeWatermarkKey(resID metadata.ResourceID) string {
+	return fmt.Sprintf("%s%s__%s", materializeWatermarkPrefix, resID.Name, resID.Variant)
+}
+
+// getMaterializeWatermark returns the newest timestamp that a feature's
+// materializations have written, or the zero time if there isn't one yet.
+func (c *Coordinator) getMaterializeWatermark(resID metadata.ResourceID) (time.Time, error) {
+	resp, err := (*c.KVClient).Get(context.Background(), materializeWatermarkKey(resID))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get materialize watermark: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return time.Time{}, nil
+	}
+	watermark, err := time.Parse(time.RFC3339Nano, string(resp.Kvs[0].Value))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse materialize watermark: %w", err)
+	}
+	return watermark, nil
+}
+
+func (c *Coordinator) setMaterializeWatermark(resID metadata.ResourceID, watermark time.Time) error {
+	value := watermark.UTC().Format(time.RFC3339Nano)
+	if _, err := (*c.KVClient).Put(context.Background(), materializeWatermarkKey(resID), value); err != nil {
+		return fmt.Errorf("set materialize watermark: %w", err)
+	}
+	return nil
+}
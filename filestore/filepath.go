@@ -31,6 +31,10 @@ const (
 	Parquet FileType = "parquet"
 	CSV     FileType = "csv"
 	DB      FileType = "db"
+	Avro    FileType = "avro"
+	ORC     FileType = "orc"
+	// Newline delimited JSON, with one object per row
+	JSONL FileType = "jsonl"
 )
 
 const (
@@ -41,19 +45,22 @@ const (
 	AzureBlobPrefix  = "abfss://"
 	HDFSPrefix       = "hdfs://"
 	FileSystemPrefix = "file://"
+	MemoryPrefix     = "mem://"
 )
 
 var ValidSchemes = []string{
-	GSPrefix, S3Prefix, S3APrefix, S3NPrefix, AzureBlobPrefix, HDFSPrefix, FileSystemPrefix,
+	GSPrefix, S3Prefix, S3APrefix, S3NPrefix, AzureBlobPrefix, HDFSPrefix, FileSystemPrefix, MemoryPrefix,
 }
 
+// Matches reports whether file has the extension ft. Compound extensions work
+// both ways: "part.snappy.parquet" is a Parquet file, and it also matches
+// FileType("snappy.parquet").
 func (ft FileType) Matches(file string) bool {
-	ext := GetFileExtension(file)
-	return FileType(ext) == ft
+	return strings.HasSuffix(file, "."+string(ft))
 }
 
 func IsValidFileType(file string) bool {
-	for _, fileType := range []FileType{Parquet, CSV, DB} {
+	for _, fileType := range []FileType{Parquet, CSV, DB, Avro, ORC, JSONL} {
 		if fileType.Matches(file) {
 			return true
 		}
@@ -115,7 +122,7 @@ func NewEmptyFilepath(storeType FileStoreType) (Filepath, error) {
 	case GCS:
 		return &GCSFilepath{FilePath{isDir: false}}, nil
 	case Memory:
-		return nil, fmt.Errorf("currently unsupported file store type '%s'", storeType)
+		return &MemoryFilepath{FilePath{isDir: false}}, nil
 	case FileSystem:
 		return &LocalFilepath{FilePath{isDir: false}}, nil
 	//case DB:
@@ -136,7 +143,7 @@ func NewEmptyDirpath(storeType FileStoreType) (Filepath, error) {
 	case GCS:
 		return &GCSFilepath{FilePath{isDir: true}}, nil
 	case Memory:
-		return nil, fmt.Errorf("currently unsupported file store type '%s'", storeType)
+		return &MemoryFilepath{FilePath{isDir: true}}, nil
 	case FileSystem:
 		return nil, fmt.Errorf("currently unsupported file store type '%s'", storeType)
 	//case DB:
@@ -466,6 +473,29 @@ func (local *LocalFilepath) Validate() error {
 	return nil
 }
 
+// MemoryFilepath is a path in an in-memory file store, such as
+// mem:///path/to/file.parquet. Like LocalFilepath, it has no bucket.
+type MemoryFilepath struct {
+	FilePath
+}
+
+func (mem *MemoryFilepath) SetBucket(bucket string) error {
+	return nil
+}
+
+func (mem *MemoryFilepath) Validate() error {
+	if mem.scheme != MemoryPrefix {
+		return fmt.Errorf("invalid scheme '%s', must be '%s'", mem.scheme, MemoryPrefix)
+	}
+	if mem.key == "" {
+		return fmt.Errorf("key cannot be empty")
+	} else {
+		mem.key = strings.Trim(mem.key, "/")
+	}
+	mem.isValid = true
+	return nil
+}
+
 type FilePathGroupingType string
 
 const (
@@ -534,6 +564,13 @@ func groupByDateTimeDirectory(files []Filepath) (FilePathGroup, error) {
 			groups[datetime] = append(groups[datetime], file)
 		}
 	}
+	// Each group's files, such as the part files Spark writes, are sorted by name
+	// so that they're always served, and their rows read, in the same order
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			return filepath.Base(group[i].Key()) < filepath.Base(group[j].Key())
+		})
+	}
 	keys := make([]string, 0)
 	for key := range groups {
 		keys = append(keys, key)
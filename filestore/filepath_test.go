@@ -174,6 +174,26 @@ func TestValidateFilepaths(t *testing.T) {
 				},
 			}, true,
 		},
+		{
+			"Memory Valid",
+			Memory,
+			&MemoryFilepath{
+				FilePath: FilePath{
+					scheme: "mem://",
+					key:    "my/path/",
+				},
+			}, false,
+		},
+		{
+			"Memory Invalid Scheme",
+			Memory,
+			&MemoryFilepath{
+				FilePath: FilePath{
+					scheme: "file://",
+					key:    "my/path/",
+				},
+			}, true,
+		},
 	}
 	for _, tt := range testCases {
 		t.Run(tt.name, func(t *testing.T) {
@@ -404,3 +424,58 @@ func TestNewEmptyPath(t *testing.T) {
 		})
 	}
 }
+
+func TestMemoryFilepathRoundTrip(t *testing.T) {
+	path, err := NewEmptyFilepath(Memory)
+	if err != nil {
+		t.Fatalf("could not create memory filepath: %v", err)
+	}
+	if err := path.ParseFilePath("mem:///my/path/file.parquet"); err != nil {
+		t.Fatalf("could not parse memory filepath: %v", err)
+	}
+	if err := path.Validate(); err != nil {
+		t.Fatalf("invalid memory filepath: %v", err)
+	}
+	if path.Key() != "my/path/file.parquet" || path.IsDir() {
+		t.Fatalf("expected file my/path/file.parquet, got %s (dir: %v)", path.Key(), path.IsDir())
+	}
+	if path.ToURI() != "mem:///my/path/file.parquet" {
+		t.Fatalf("expected mem:///my/path/file.parquet, got %s", path.ToURI())
+	}
+}
+
+func TestFilePathGroupSortsFilesByName(t *testing.T) {
+	newer := "featureform/Transformation/name/variant/2024-01-02-03-04-05-123456"
+	older := "featureform/Transformation/name/variant/2023-01-02-03-04-05-123456"
+	keys := []string{
+		newer + "/part-00002.parquet",
+		older + "/part-00000.parquet",
+		newer + "/part-00000.parquet",
+		newer + "/part-00001.parquet",
+	}
+	files := make([]Filepath, len(keys))
+	for i, key := range keys {
+		path := MemoryFilepath{}
+		if err := path.SetKey(key); err != nil {
+			t.Fatalf("could not set key %s: %v", key, err)
+		}
+		files[i] = &path
+	}
+	group, err := NewFilePathGroup(files, DateTimeDirectoryGrouping)
+	if err != nil {
+		t.Fatalf("could not group files: %v", err)
+	}
+	newest, err := group.GetFirst()
+	if err != nil {
+		t.Fatalf("could not get newest files: %v", err)
+	}
+	expected := []string{newer + "/part-00000.parquet", newer + "/part-00001.parquet", newer + "/part-00002.parquet"}
+	if len(newest) != len(expected) {
+		t.Fatalf("expected %d files, got %d", len(expected), len(newest))
+	}
+	for i, file := range newest {
+		if file.Key() != expected[i] {
+			t.Fatalf("expected file %d to be %s, got %s", i, expected[i], file.Key())
+		}
+	}
+}
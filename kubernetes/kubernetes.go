@@ -115,33 +115,47 @@ func validateJobLimits(specs metadata.KubernetesResourceSpecs) (v1.ResourceRequi
 		Requests: make(v1.ResourceList),
 		Limits:   make(v1.ResourceList),
 	}
-	var parseErr error
 	if specs.CPURequest != "" {
 		qty, err := resource.ParseQuantity(specs.CPURequest)
+		if err != nil {
+			return rsrcReq, fmt.Errorf("invalid cpu request %q: %w", specs.CPURequest, err)
+		}
 		rsrcReq.Requests[v1.ResourceCPU] = qty
-		parseErr = err
 	}
 	if specs.CPULimit != "" {
 		qty, err := resource.ParseQuantity(specs.CPULimit)
+		if err != nil {
+			return rsrcReq, fmt.Errorf("invalid cpu limit %q: %w", specs.CPULimit, err)
+		}
 		rsrcReq.Limits[v1.ResourceCPU] = qty
-		parseErr = err
 	}
 	if specs.MemoryRequest != "" {
 		qty, err := resource.ParseQuantity(specs.MemoryRequest)
+		if err != nil {
+			return rsrcReq, fmt.Errorf("invalid memory request %q: %w", specs.MemoryRequest, err)
+		}
 		rsrcReq.Requests[v1.ResourceMemory] = qty
-		parseErr = err
 	}
 	if specs.MemoryLimit != "" {
 		qty, err := resource.ParseQuantity(specs.MemoryLimit)
+		if err != nil {
+			return rsrcReq, fmt.Errorf("invalid memory limit %q: %w", specs.MemoryLimit, err)
+		}
 		rsrcReq.Limits[v1.ResourceMemory] = qty
-		parseErr = err
-	}
-	if parseErr != nil {
-		return rsrcReq, parseErr
 	}
 	return rsrcReq, nil
 }
 
+// NewJobSpec creates the spec of the job that runs config, failing if its
+// resource requests or limits can't be parsed
+func NewJobSpec(config KubernetesRunnerConfig) (batchv1.JobSpec, error) {
+	rsrcReqs, err := validateJobLimits(config.Specs)
+	if err != nil {
+		return batchv1.JobSpec{}, err
+	}
+	return newJobSpec(config, rsrcReqs), nil
+}
+
 func newJobSpec(config KubernetesRunnerConfig, rsrcReqs v1.ResourceRequirements) batchv1.JobSpec {
 	containerID := uuid.New().String()
 	envVars := generateKubernetesEnvVars(config.EnvVars)
@@ -181,6 +195,8 @@ func newJobSpec(config KubernetesRunnerConfig, rsrcReqs v1.ResourceRequirements)
 					},
 				},
 				RestartPolicy: v1.RestartPolicyNever,
+				NodeSelector:  config.NodeSelector,
+				Tolerations:   config.Tolerations,
 			},
 		},
 	}
@@ -193,7 +209,13 @@ type KubernetesRunnerConfig struct {
 	Resource  metadata.ResourceID
 	Image     string
 	NumTasks  int32
-	Specs     metadata.KubernetesResourceSpecs
+	// CPU and memory requests and limits of the job's container. Empty ones
+	// aren't set.
+	Specs metadata.KubernetesResourceSpecs
+	// Labels the nodes the job's pods are scheduled on must have
+	NodeSelector map[string]string
+	// Taints of the nodes the job's pods may be scheduled on anyway
+	Tolerations []v1.Toleration
 }
 
 type JobClient interface {
@@ -349,11 +371,10 @@ func generateCleanRandomJobName() string {
 }
 
 func NewKubernetesRunner(config KubernetesRunnerConfig) (CronRunner, error) {
-	rsrcReqs, err := validateJobLimits(config.Specs)
+	jobSpec, err := NewJobSpec(config)
 	if err != nil {
 		return nil, err
 	}
-	jobSpec := newJobSpec(config, rsrcReqs)
 	var jobName string
 	if config.Resource.Name != "" {
 		jobName = CreateJobName(config.Resource, config.JobPrefix)
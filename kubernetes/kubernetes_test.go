@@ -13,11 +13,10 @@ import (
 )
 
 func NewMockKubernetesRunner(config KubernetesRunnerConfig) (CronRunner, error) {
-	rsrcReqs, err := validateJobLimits(config.Specs)
+	jobSpec, err := NewJobSpec(config)
 	if err != nil {
 		return nil, err
 	}
-	jobSpec := newJobSpec(config, rsrcReqs)
 	jobName := uuid.New().String()
 	namespace := "default"
 	jobClient := MockJobClient{
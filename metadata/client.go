@@ -14,9 +14,11 @@ import (
 	pb "github.com/featureform/metadata/proto"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
+	durpb "google.golang.org/protobuf/types/known/durationpb"
 	tspb "google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -113,6 +115,28 @@ func (client *Client) SetStatus(ctx context.Context, resID ResourceID, status Re
 	return err
 }
 
+// SourceStats is how big a source variant's table was when its transformation
+// or primary table job last finished
+type SourceStats struct {
+	RowCount int64
+	// Zero if the offline store can't report the size of its tables
+	SizeBytes  int64
+	RecordedAt time.Time
+}
+
+func (client *Client) SetSourceStats(ctx context.Context, id NameVariant, stats SourceStats) error {
+	req := pb.SetSourceStatsRequest{
+		Source: id.Serialize(),
+		Stats: &pb.SourceStats{
+			RowCount:   stats.RowCount,
+			SizeBytes:  stats.SizeBytes,
+			RecordedAt: tspb.New(stats.RecordedAt),
+		},
+	}
+	_, err := client.GrpcConn.SetSourceStats(ctx, &req)
+	return err
+}
+
 func (client *Client) CreateAll(ctx context.Context, defs []ResourceDef) error {
 	for _, def := range defs {
 		if err := client.Create(ctx, def); err != nil {
@@ -223,6 +247,14 @@ type FeatureDef struct {
 	Mode        ComputationMode
 	IsOnDemand  bool
 	IsEmbedding bool
+	// Optional SQL boolean expression over the source's columns that limits
+	// which rows are materialized.
+	Filter string
+	// How long materialized values stay in the online store. Zero keeps them
+	// until they're overwritten.
+	TTL time.Duration
+	// Used by the pods of the feature's materialization jobs on Kubernetes
+	KubernetesArgs KubernetesArgs
 }
 
 type ResourceVariantColumns struct {
@@ -284,6 +316,13 @@ func (client *Client) CreateFeatureVariant(ctx context.Context, def FeatureDef)
 		Properties:  def.Properties.Serialize(),
 		Mode:        pb.ComputationMode(def.Mode),
 		IsEmbedding: def.IsEmbedding,
+		Filter:      def.Filter,
+	}
+	if def.TTL > 0 {
+		serialized.Ttl = durpb.New(def.TTL)
+	}
+	if def.KubernetesArgs != (KubernetesArgs{}) {
+		serialized.KubernetesArgs = def.KubernetesArgs.Serialize()
 	}
 	switch x := def.Location.(type) {
 	case ResourceVariantColumns:
@@ -517,6 +556,8 @@ type TrainingSetDef struct {
 	Features    NameVariants
 	Tags        Tags
 	Properties  Properties
+	// Used by the pods of the training set's jobs on Kubernetes
+	KubernetesArgs KubernetesArgs
 }
 
 func (def TrainingSetDef) ResourceType() ResourceType {
@@ -537,6 +578,9 @@ func (client *Client) CreateTrainingSetVariant(ctx context.Context, def Training
 		Tags:        &pb.Tags{Tag: def.Tags},
 		Properties:  def.Properties.Serialize(),
 	}
+	if def.KubernetesArgs != (KubernetesArgs{}) {
+		serialized.KubernetesArgs = def.KubernetesArgs.Serialize()
+	}
 	_, err := client.GrpcConn.CreateTrainingSetVariant(ctx, serialized)
 	return err
 }
@@ -664,6 +708,12 @@ func (t SQLTransformationType) IsTransformationType() bool {
 func (t SQLTable) isPrimaryData() bool {
 	return true
 }
+func (t QueryDataSource) isPrimaryData() bool {
+	return true
+}
+func (t IcebergTable) isPrimaryData() bool {
+	return true
+}
 
 type TransformationSource struct {
 	TransformationType TransformationType
@@ -690,6 +740,19 @@ type SQLTable struct {
 	Name string
 }
 
+// QueryDataSource is primary data defined by a read-only SQL query rather than
+// an existing table. Unlike a SQL transformation it can't depend on other
+// sources; the query is run once when the source is registered.
+type QueryDataSource struct {
+	Query string
+}
+
+// IcebergTable is primary data stored as an Iceberg table in the provider's
+// file store. Location is the URI of the table's root directory.
+type IcebergTable struct {
+	Location string
+}
+
 type TransformationSourceDef struct {
 	Def interface{}
 }
@@ -727,6 +790,22 @@ func (s PrimaryDataSource) Serialize() (*pb.SourceVariant_PrimaryData, error) {
 				},
 			},
 		}
+	case QueryDataSource:
+		primaryData = &pb.PrimaryData{
+			Location: &pb.PrimaryData_Query{
+				Query: &pb.PrimarySQLQuery{
+					Query: x.Query,
+				},
+			},
+		}
+	case IcebergTable:
+		primaryData = &pb.PrimaryData{
+			Location: &pb.PrimaryData_Iceberg{
+				Iceberg: &pb.PrimaryIcebergTable{
+					Location: x.Location,
+				},
+			},
+		}
 	case nil:
 		return nil, fmt.Errorf("PrimaryDataSource Type not set")
 	default:
@@ -742,6 +821,9 @@ func (def SourceDef) ResourceType() ResourceType {
 }
 
 func (client *Client) CreateSourceVariant(ctx context.Context, def SourceDef) error {
+	if err := ValidateSourceDef(def); err != nil {
+		return err
+	}
 	serialized := &pb.SourceVariant{
 		Name:        def.Name,
 		Variant:     def.Variant,
@@ -1465,6 +1547,22 @@ func (variant *FeatureVariant) Location() interface{} {
 	return variant.serialized.GetLocation()
 }
 
+func (variant *FeatureVariant) Filter() string {
+	return variant.serialized.GetFilter()
+}
+
+// TTL is how long the variant's materialized values stay in the online store,
+// or zero if they don't expire.
+func (variant *FeatureVariant) TTL() time.Duration {
+	return variant.serialized.GetTtl().AsDuration()
+}
+
+// KubernetesArgs are used by the pods of the variant's materialization jobs on
+// Kubernetes. They're empty if the variant doesn't set any.
+func (variant *FeatureVariant) KubernetesArgs() KubernetesArgs {
+	return parseKubernetesArgs(variant.serialized.GetKubernetesArgs())
+}
+
 func (variant *FeatureVariant) isTable() bool {
 	return reflect.TypeOf(variant.serialized.GetLocation()) == reflect.TypeOf(&pb.FeatureVariant_Columns{})
 }
@@ -1905,6 +2003,12 @@ func (variant *TrainingSetVariant) Properties() Properties {
 	return variant.fetchPropertiesFn.Properties()
 }
 
+// KubernetesArgs are used by the pods of the variant's jobs on Kubernetes.
+// They're empty if the variant doesn't set any.
+func (variant *TrainingSetVariant) KubernetesArgs() KubernetesArgs {
+	return parseKubernetesArgs(variant.serialized.GetKubernetesArgs())
+}
+
 type Source struct {
 	serialized *pb.Source
 	variantsFns
@@ -1974,8 +2078,23 @@ func (arg KubernetesArgs) Type() TransformationArgType {
 	return K8sArgs
 }
 
+func (arg KubernetesArgs) Serialize() *pb.KubernetesArgs {
+	return &pb.KubernetesArgs{
+		DockerImage: arg.DockerImage,
+		Specs: &pb.KubernetesResourceSpecs{
+			CpuRequest:    arg.Specs.CPURequest,
+			CpuLimit:      arg.Specs.CPULimit,
+			MemoryRequest: arg.Specs.MemoryRequest,
+			MemoryLimit:   arg.Specs.MemoryLimit,
+		},
+	}
+}
+
 func (variant *SourceVariant) parseKubernetesArgs() KubernetesArgs {
-	args := variant.serialized.GetTransformation().GetKubernetesArgs()
+	return parseKubernetesArgs(variant.serialized.GetTransformation().GetKubernetesArgs())
+}
+
+func parseKubernetesArgs(args *pb.KubernetesArgs) KubernetesArgs {
 	specs := args.GetSpecs()
 	return KubernetesArgs{
 		DockerImage: args.GetDockerImage(),
@@ -2138,6 +2257,34 @@ func (variant *SourceVariant) PrimaryDataSQLTableName() string {
 	return variant.serialized.GetPrimaryData().GetTable().GetName()
 }
 
+func (variant *SourceVariant) IsPrimaryDataQuery() bool {
+	if !variant.isPrimaryData() {
+		return false
+	}
+	return reflect.TypeOf(variant.serialized.GetPrimaryData().GetLocation()) == reflect.TypeOf(&pb.PrimaryData_Query{})
+}
+
+func (variant *SourceVariant) PrimaryDataQuery() string {
+	if !variant.IsPrimaryDataQuery() {
+		return ""
+	}
+	return variant.serialized.GetPrimaryData().GetQuery().GetQuery()
+}
+
+func (variant *SourceVariant) IsPrimaryDataIcebergTable() bool {
+	if !variant.isPrimaryData() {
+		return false
+	}
+	return reflect.TypeOf(variant.serialized.GetPrimaryData().GetLocation()) == reflect.TypeOf(&pb.PrimaryData_Iceberg{})
+}
+
+func (variant *SourceVariant) PrimaryDataIcebergTableLocation() string {
+	if !variant.IsPrimaryDataIcebergTable() {
+		return ""
+	}
+	return variant.serialized.GetPrimaryData().GetIceberg().GetLocation()
+}
+
 func (variant *SourceVariant) Tags() Tags {
 	return variant.fetchTagsFn.Tags()
 }
@@ -2146,6 +2293,20 @@ func (variant *SourceVariant) Properties() Properties {
 	return variant.fetchPropertiesFn.Properties()
 }
 
+// Stats returns what the source variant's last job recorded, and false if
+// none has been
+func (variant *SourceVariant) Stats() (SourceStats, bool) {
+	stats := variant.serialized.GetStats()
+	if stats == nil {
+		return SourceStats{}, false
+	}
+	return SourceStats{
+		RowCount:   stats.RowCount,
+		SizeBytes:  stats.SizeBytes,
+		RecordedAt: stats.RecordedAt.AsTime(),
+	}, true
+}
+
 type Entity struct {
 	serialized *pb.Entity
 	fetchTrainingSetsFns
@@ -2218,6 +2379,17 @@ func NewClient(host string, logger *zap.SugaredLogger) (*Client, error) {
 	}, nil
 }
 
+// Connected reports whether the client's connection to the metadata server is
+// usable. An idle connection is asked to reconnect and counts as usable until
+// the attempt fails.
+func (client *Client) Connected() bool {
+	state := client.conn.GetState()
+	if state == connectivity.Idle {
+		client.conn.Connect()
+	}
+	return state != connectivity.TransientFailure && state != connectivity.Shutdown
+}
+
 func (client *Client) Close() {
 	client.conn.Close()
 }
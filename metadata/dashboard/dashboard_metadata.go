@@ -289,6 +289,10 @@ func getSourceString(variant *metadata.SourceVariant) string {
 		return variant.SQLTransformationQuery()
 	} else if variant.IsDFTransformation() {
 		return variant.DFTransformationQuerySource()
+	} else if variant.IsPrimaryDataQuery() {
+		return variant.PrimaryDataQuery()
+	} else if variant.IsPrimaryDataIcebergTable() {
+		return variant.PrimaryDataIcebergTableLocation()
 	} else {
 		return variant.PrimaryDataSQLTableName()
 	}
@@ -299,6 +303,10 @@ func getSourceType(variant *metadata.SourceVariant) string {
 		return "SQL Transformation"
 	} else if variant.IsDFTransformation() {
 		return "Dataframe Transformation"
+	} else if variant.IsPrimaryDataQuery() {
+		return "Query Source"
+	} else if variant.IsPrimaryDataIcebergTable() {
+		return "Iceberg Table"
 	} else {
 		return "Primary Table"
 	}
@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gorhill/cronexpr"
 	"github.com/pkg/errors"
 
 	pb "github.com/featureform/metadata/proto"
@@ -172,6 +173,19 @@ func resourceNamedSafely(id ResourceID) error {
 	return nil
 }
 
+// resourceScheduleValid rejects a schedule that isn't a cron expression when
+// its resource is created, rather than when its job is run.
+func resourceScheduleValid(res Resource) error {
+	schedule := res.Schedule()
+	if schedule == "" {
+		return nil
+	}
+	if _, err := cronexpr.Parse(schedule); err != nil {
+		return fmt.Errorf("resource %s %s has invalid schedule %q: %v", res.ID().Name, res.ID().Variant, schedule, err)
+	}
+	return nil
+}
+
 type ResourceNotFound struct {
 	ID ResourceID
 	E  error
@@ -1089,6 +1103,10 @@ func (resource *providerResource) isValidConfigUpdate(configUpdate pc.Serialized
 		return isValidMongoConfigUpdate(resource.serialized.SerializedConfig, configUpdate)
 	case pt.PostgresOffline:
 		return isValidPostgresConfigUpdate(resource.serialized.SerializedConfig, configUpdate)
+	case pt.MySQLOffline:
+		return isValidMySQLConfigUpdate(resource.serialized.SerializedConfig, configUpdate)
+	case pt.SQLiteOffline:
+		return isValidSQLiteConfigUpdate(resource.serialized.SerializedConfig, configUpdate)
 	case pt.RedisOnline:
 		return isValidRedisConfigUpdate(resource.serialized.SerializedConfig, configUpdate)
 	case pt.SnowflakeOffline:
@@ -1292,6 +1310,25 @@ func (serv *MetadataServer) SetResourceStatus(ctx context.Context, req *pb.SetSt
 	return &pb.Empty{}, err
 }
 
+// SetSourceStats replaces the stats recorded for a source variant
+func (serv *MetadataServer) SetSourceStats(ctx context.Context, req *pb.SetSourceStatsRequest) (*pb.Empty, error) {
+	resID := ResourceID{Name: req.Source.Name, Variant: req.Source.Variant, Type: SOURCE_VARIANT}
+	res, err := serv.lookup.Lookup(resID)
+	if err != nil {
+		return nil, err
+	}
+	variant, ok := res.(*sourceVariantResource)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a source variant", resID)
+	}
+	variant.serialized.Stats = req.Stats
+	if err := serv.lookup.Set(resID, variant); err != nil {
+		serv.Logger.Errorw("Could not set source stats", "error", err.Error())
+		return nil, err
+	}
+	return &pb.Empty{}, nil
+}
+
 func (serv *MetadataServer) ListFeatures(_ *pb.Empty, stream pb.Metadata_ListFeaturesServer) error {
 	return serv.genericList(FEATURE, func(msg proto.Message) error {
 		return stream.Send(msg.(*pb.Feature))
@@ -1503,6 +1540,9 @@ func (serv *MetadataServer) genericCreate(ctx context.Context, res Resource, ini
 	if err := resourceNamedSafely(id); err != nil {
 		return nil, err
 	}
+	if err := resourceScheduleValid(res); err != nil {
+		return nil, err
+	}
 	existing, err := serv.lookup.Lookup(id)
 	if _, isResourceError := err.(*ResourceNotFound); err != nil && !isResourceError {
 		return nil, err
@@ -1711,6 +1751,10 @@ type SourceVariantResource struct {
 func getSourceString(variant *SourceVariant) string {
 	if variant.IsSQLTransformation() {
 		return variant.SQLTransformationQuery()
+	} else if variant.IsPrimaryDataQuery() {
+		return variant.PrimaryDataQuery()
+	} else if variant.IsPrimaryDataIcebergTable() {
+		return variant.PrimaryDataIcebergTableLocation()
 	} else {
 		return variant.PrimaryDataSQLTableName()
 	}
@@ -1721,6 +1765,10 @@ func getSourceType(variant *SourceVariant) string {
 		return "SQL Transformation"
 	} else if variant.IsDFTransformation() {
 		return "Dataframe Transformation"
+	} else if variant.IsPrimaryDataQuery() {
+		return "Query Source"
+	} else if variant.IsPrimaryDataIcebergTable() {
+		return "Iceberg Table"
 	} else {
 		return "Primary Table"
 	}
@@ -104,7 +104,7 @@ func filledResourceDefs() []ResourceDef {
 			Description: "A CSV source",
 			Definition: TransformationSource{
 				TransformationType: SQLTransformationType{
-					Query: "SELECT * FROM dummy",
+					Query: "SELECT * FROM {{mockName.mockVariant}}",
 					Sources: []NameVariant{{
 						Name:    "mockName",
 						Variant: "mockVariant"},
@@ -167,6 +167,10 @@ func filledResourceDefs() []ResourceDef {
 			Properties: Properties{},
 			Mode:       PRECOMPUTED,
 			IsOnDemand: false,
+			KubernetesArgs: KubernetesArgs{
+				DockerImage: "my_image:latest",
+				Specs:       KubernetesResourceSpecs{MemoryLimit: "8Gi"},
+			},
 		},
 		FeatureDef{
 			Name:        "feature2",
@@ -244,6 +248,9 @@ func filledResourceDefs() []ResourceDef {
 			Owner:      "Featureform",
 			Tags:       Tags{},
 			Properties: Properties{},
+			KubernetesArgs: KubernetesArgs{
+				Specs: KubernetesResourceSpecs{CPURequest: "1", CPULimit: "2"},
+			},
 		},
 		ModelDef{
 			Name:         "fraud",
@@ -1147,6 +1154,8 @@ type FeatureVariantTest struct {
 	IsTable      bool
 	Mode         ComputationMode
 	IsOnDemand   bool
+	// Left empty on variants that don't set any
+	KubernetesArgs KubernetesArgs
 }
 
 func (test FeatureVariantTest) NameVariant() NameVariant {
@@ -1179,6 +1188,7 @@ func (test FeatureVariantTest) Test(t *testing.T, client *Client, res interface{
 	assertEqual(t, feature.Mode(), test.Mode)
 	assertEqual(t, feature.Mode(), test.Mode)
 	assertEqual(t, feature.IsOnDemand(), test.IsOnDemand)
+	assertEqual(t, feature.KubernetesArgs(), test.KubernetesArgs)
 	if tm := feature.Created(); tm == (time.Time{}) {
 		t.Fatalf("Created time not set")
 	}
@@ -1224,6 +1234,10 @@ func expectedFeatureVariants() ResourceTests {
 				TS:     "col3",
 			},
 			IsTable: true,
+			KubernetesArgs: KubernetesArgs{
+				DockerImage: "my_image:latest",
+				Specs:       KubernetesResourceSpecs{MemoryLimit: "8Gi"},
+			},
 		},
 		FeatureVariantTest{
 			Name:        "feature2",
@@ -1408,6 +1422,8 @@ type TrainingSetVariantTest struct {
 	Provider    string
 	Label       NameVariant
 	Features    []NameVariant
+	// Left empty on variants that don't set any
+	KubernetesArgs KubernetesArgs
 }
 
 func (test TrainingSetVariantTest) NameVariant() NameVariant {
@@ -1424,6 +1440,7 @@ func (test TrainingSetVariantTest) Test(t *testing.T, client *Client, resource i
 	assertEqual(t, trainingSet.Provider(), test.Provider)
 	assertEqual(t, trainingSet.Label(), test.Label)
 	assertEquivalentNameVariants(t, trainingSet.Features(), test.Features)
+	assertEqual(t, trainingSet.KubernetesArgs(), test.KubernetesArgs)
 	if shouldFetch {
 		testFetchProvider(t, client, trainingSet)
 		testFetchLabel(t, client, trainingSet)
@@ -1456,6 +1473,9 @@ func expectedTrainingSetVariants() ResourceTests {
 				{"feature", "variant2"},
 			},
 			Owner: "Featureform",
+			KubernetesArgs: KubernetesArgs{
+				Specs: KubernetesResourceSpecs{CPURequest: "1", CPULimit: "2"},
+			},
 		},
 	}
 }
@@ -1955,6 +1975,21 @@ func TestBannedStrings(t *testing.T) {
 	}
 }
 
+func TestResourceScheduleValid(t *testing.T) {
+	for _, schedule := range []string{"", "* * * * *", "*/5 * * * *", "@daily"} {
+		res := &trainingSetVariantResource{&pb.TrainingSetVariant{Name: "name", Variant: "variant", Schedule: schedule}}
+		if err := resourceScheduleValid(res); err != nil {
+			t.Fatalf("valid schedule %q triggered an error: %v", schedule, err)
+		}
+	}
+	for _, schedule := range []string{"every minute", "* * *"} {
+		res := &trainingSetVariantResource{&pb.TrainingSetVariant{Name: "name", Variant: "variant", Schedule: schedule}}
+		if err := resourceScheduleValid(res); err == nil {
+			t.Fatalf("testing didn't catch invalid schedule %q", schedule)
+		}
+	}
+}
+
 func TestIsValidConfigUpdate(t *testing.T) {
 
 	for _, providerType := range pt.AllProviderTypes {
@@ -98,6 +98,38 @@ func isValidPostgresConfigUpdate(sa, sb pc.SerializedConfig) (bool, error) {
 	return a.MutableFields().Contains(diff), nil
 }
 
+func isValidMySQLConfigUpdate(sa, sb pc.SerializedConfig) (bool, error) {
+	a := pc.MySQLConfig{}
+	b := pc.MySQLConfig{}
+	if err := a.Deserialize(sa); err != nil {
+		return false, err
+	}
+	if err := b.Deserialize(sb); err != nil {
+		return false, err
+	}
+	diff, err := a.DifferingFields(b)
+	if err != nil {
+		return false, err
+	}
+	return a.MutableFields().Contains(diff), nil
+}
+
+func isValidSQLiteConfigUpdate(sa, sb pc.SerializedConfig) (bool, error) {
+	a := pc.SQLiteConfig{}
+	b := pc.SQLiteConfig{}
+	if err := a.Deserialize(sa); err != nil {
+		return false, err
+	}
+	if err := b.Deserialize(sb); err != nil {
+		return false, err
+	}
+	diff, err := a.DifferingFields(b)
+	if err != nil {
+		return false, err
+	}
+	return a.MutableFields().Contains(diff), nil
+}
+
 func isValidRedisConfigUpdate(sa, sb pc.SerializedConfig) (bool, error) {
 	a := pc.RedisConfig{}
 	b := pc.RedisConfig{}
@@ -100,6 +100,26 @@ func TestProviderConfigUpdates(t *testing.T) {
 			valid:        false,
 			providerType: pt.SnowflakeOffline,
 		},
+		{
+			name:         "Valid MySQL Configuration Update",
+			valid:        true,
+			providerType: pt.MySQLOffline,
+		},
+		{
+			name:         "Invalid MySQL Configuration Update",
+			valid:        false,
+			providerType: pt.MySQLOffline,
+		},
+		{
+			name:         "Valid SQLite Configuration Update",
+			valid:        true,
+			providerType: pt.SQLiteOffline,
+		},
+		{
+			name:         "Invalid SQLite Configuration Update",
+			valid:        false,
+			providerType: pt.SQLiteOffline,
+		},
 		{
 			name:         "Valid Redshift Configuration Update",
 			valid:        true,
@@ -146,6 +166,10 @@ func TestProviderConfigUpdates(t *testing.T) {
 				testMongoConfigUpdates(t, c.providerType, c.valid)
 			case pt.PostgresOffline:
 				testPostgresConfigUpdates(t, c.providerType, c.valid)
+			case pt.MySQLOffline:
+				testMySQLConfigUpdates(t, c.providerType, c.valid)
+			case pt.SQLiteOffline:
+				testSQLiteConfigUpdates(t, c.providerType, c.valid)
 			case pt.RedisOnline:
 				testRedisConfigUpdates(t, c.providerType, c.valid)
 			case pt.SnowflakeOffline:
@@ -381,6 +405,70 @@ func testPostgresConfigUpdates(t *testing.T, providerType pt.Type, valid bool) {
 	assertConfigUpdateResult(t, valid, actual, err, providerType)
 }
 
+func testMySQLConfigUpdates(t *testing.T, providerType pt.Type, valid bool) {
+	host := "0.0.0.0"
+	port := "3306"
+	username := "mysql"
+	password := "password"
+	database := "mysql"
+	tlsMode := "false"
+
+	configA := pc.MySQLConfig{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		Database: database,
+		TLSMode:  tlsMode,
+	}
+	a := configA.Serialize()
+
+	if valid {
+		username += updateSuffix
+		password += updateSuffix
+		port = "3307"
+		tlsMode = "true"
+	} else {
+		host = "127.0.0.1"
+		database += updateSuffix
+	}
+
+	configB := pc.MySQLConfig{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		Database: database,
+		TLSMode:  tlsMode,
+	}
+	b := configB.Serialize()
+
+	actual, err := isValidMySQLConfigUpdate(a, b)
+	assertConfigUpdateResult(t, valid, actual, err, providerType)
+}
+
+// No SQLite fields can change, so the only valid update is the same config
+func testSQLiteConfigUpdates(t *testing.T, providerType pt.Type, valid bool) {
+	path := "featureform.db"
+
+	configA := pc.SQLiteConfig{
+		Path: path,
+	}
+	a := configA.Serialize()
+
+	if !valid {
+		path = pc.SQLiteMemoryPath
+	}
+
+	configB := pc.SQLiteConfig{
+		Path: path,
+	}
+	b := configB.Serialize()
+
+	actual, err := isValidSQLiteConfigUpdate(a, b)
+	assertConfigUpdateResult(t, valid, actual, err, providerType)
+}
+
 func testRedisConfigUpdates(t *testing.T, providerType pt.Type, valid bool) {
 	addr := "0.0.0.0 :=6379"
 	password := "password"
@@ -0,0 +1,134 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package metadata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TemplatePart is either literal text from a SQL transformation's query or the
+// contents of one of its {{name.variant}} tokens.
+type TemplatePart struct {
+	Text    string
+	Token   string
+	IsToken bool
+}
+
+// SplitTemplate splits a transformation query into its text and tokens. An
+// escaped {{{{ is read as a literal {{ rather than the start of a token, so
+// that queries can contain double braces, such as in JSON string literals.
+func SplitTemplate(template string) ([]TemplatePart, error) {
+	parts := make([]TemplatePart, 0)
+	text := ""
+	for {
+		start := strings.Index(template, "{{")
+		if start == -1 {
+			text += template
+			break
+		}
+		if strings.HasPrefix(template[start:], "{{{{") {
+			text += template[:start] + "{{"
+			template = template[start+len("{{{{"):]
+			continue
+		}
+		text += template[:start]
+		afterSplit := strings.SplitN(template[start+len("{{"):], "}}", 2)
+		if len(afterSplit) < 2 {
+			return nil, fmt.Errorf("unterminated template token: %s", template[start:])
+		}
+		if text != "" {
+			parts = append(parts, TemplatePart{Text: text})
+			text = ""
+		}
+		parts = append(parts, TemplatePart{Token: afterSplit[0], IsToken: true})
+		template = afterSplit[1]
+	}
+	if text != "" {
+		parts = append(parts, TemplatePart{Text: text})
+	}
+	return parts, nil
+}
+
+// ValidateSourceDef checks that a SQL transformation's query and its Sources
+// agree, so that a typo in either fails when the source is registered rather
+// than when its job runs. Each {{name.variant}} token in the query must name
+// one of the Sources, and each of the Sources must be used by the query.
+// Tokens are matched to sources ignoring case, as they are on offline stores
+// that don't distinguish it. Other kinds of sources are always valid.
+func ValidateSourceDef(def SourceDef) error {
+	transformation, ok := def.Definition.(TransformationSource)
+	if !ok {
+		return nil
+	}
+	sql, ok := transformation.TransformationType.(SQLTransformationType)
+	if !ok {
+		return nil
+	}
+	parts, err := SplitTemplate(sql.Query)
+	if err != nil {
+		return fmt.Errorf("source %s (%s): %w", def.Name, def.Variant, err)
+	}
+	used := make([]bool, len(sql.Sources))
+	undeclared := make([]string, 0)
+	for _, part := range parts {
+		if !part.IsToken {
+			continue
+		}
+		key := strings.TrimSpace(part.Token)
+		i := findTemplateSource(sql.Sources, key)
+		if i == -1 {
+			undeclared = append(undeclared, key)
+			continue
+		}
+		used[i] = true
+	}
+	if len(undeclared) > 0 {
+		return fmt.Errorf("source %s (%s): query uses sources that aren't declared: %s", def.Name, def.Variant, strings.Join(undeclared, ", "))
+	}
+	unused := make([]string, 0)
+	for i, source := range sql.Sources {
+		if !used[i] {
+			unused = append(unused, source.ClientString())
+		}
+	}
+	if len(unused) > 0 {
+		return fmt.Errorf("source %s (%s): declared sources aren't used in its query: %s", def.Name, def.Variant, strings.Join(unused, ", "))
+	}
+	return nil
+}
+
+// findTemplateSource returns the index of the source a token names, or -1 if
+// there's none. Case is ignored, as it is on the offline stores that don't
+// distinguish it.
+func findTemplateSource(sources NameVariants, key string) int {
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = source.ClientString()
+	}
+	return MatchTemplateSource(names, key, true)
+}
+
+// MatchTemplateSource returns the index of the name that a transformation
+// query's token refers to, or -1 if there's none. An exact match is preferred.
+// Otherwise, if foldCase is set, a name that only differs from the token in
+// case matches it, as long as no other name does.
+func MatchTemplateSource(names []string, key string, foldCase bool) int {
+	match := -1
+	matches := 0
+	for i, name := range names {
+		if name == key {
+			return i
+		}
+		if foldCase && strings.EqualFold(name, key) {
+			match = i
+			matches++
+		}
+	}
+	if matches != 1 {
+		return -1
+	}
+	return match
+}
@@ -0,0 +1,110 @@
+package metadata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitTemplate(t *testing.T) {
+	parts, err := SplitTemplate("SELECT '{{{{\"a\": 1}}' FROM {{ source.v1 }}")
+	if err != nil {
+		t.Fatalf("could not split template: %v", err)
+	}
+	expected := []TemplatePart{
+		{Text: "SELECT '{{\"a\": 1}}' FROM "},
+		{Token: " source.v1 ", IsToken: true},
+	}
+	if !reflect.DeepEqual(parts, expected) {
+		t.Fatalf("expected %v, got %v", expected, parts)
+	}
+	if _, err := SplitTemplate("SELECT * FROM {{source.v1"); err == nil {
+		t.Fatalf("expected an unterminated token to fail")
+	}
+}
+
+func TestValidateSourceDef(t *testing.T) {
+	sqlSource := func(query string, sources ...NameVariant) SourceDef {
+		return SourceDef{
+			Name:    "transformation",
+			Variant: "v1",
+			Definition: TransformationSource{
+				TransformationType: SQLTransformationType{Query: query, Sources: sources},
+			},
+		}
+	}
+	tests := map[string]struct {
+		def     SourceDef
+		isValid bool
+	}{
+		"Matching": {
+			sqlSource("SELECT * FROM {{a.v1}} JOIN {{ b.v1 }} USING (id)", NameVariant{"a", "v1"}, NameVariant{"b", "v1"}),
+			true,
+		},
+		"Repeated": {
+			sqlSource("SELECT {{a.}}.x FROM {{a.}}", NameVariant{"a", ""}),
+			true,
+		},
+		"DifferentCase": {
+			sqlSource("SELECT * FROM {{Transactions.default}}", NameVariant{"transactions", "default"}),
+			true,
+		},
+		"AmbiguousCase": {
+			sqlSource("SELECT * FROM {{Transactions.default}}", NameVariant{"transactions", "default"}, NameVariant{"TRANSACTIONS", "default"}),
+			false,
+		},
+		"Escaped": {
+			sqlSource("SELECT '{{{{x}}' FROM {{a.v1}}", NameVariant{"a", "v1"}),
+			true,
+		},
+		"Undeclared": {
+			sqlSource("SELECT * FROM {{a.v1}} JOIN {{typo.v1}} USING (id)", NameVariant{"a", "v1"}),
+			false,
+		},
+		"Unused": {
+			sqlSource("SELECT * FROM {{a.v1}}", NameVariant{"a", "v1"}, NameVariant{"b", "v1"}),
+			false,
+		},
+		"Unterminated": {
+			sqlSource("SELECT * FROM {{a.v1", NameVariant{"a", "v1"}),
+			false,
+		},
+		"PrimaryData": {
+			SourceDef{Name: "primary", Definition: PrimaryDataSource{Location: SQLTable{Name: "table"}}},
+			true,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateSourceDef(test.def)
+			if test.isValid && err != nil {
+				t.Fatalf("expected source to be valid: %v", err)
+			}
+			if !test.isValid && err == nil {
+				t.Fatalf("expected source to be invalid")
+			}
+		})
+	}
+}
+
+func TestMatchTemplateSource(t *testing.T) {
+	names := []string{"transactions.", "Transactions.", "users.v1"}
+	tests := map[string]struct {
+		key      string
+		foldCase bool
+		expected int
+	}{
+		"Exact":               {"Transactions.", false, 1},
+		"ExactPreferred":      {"transactions.", true, 0},
+		"FoldedCase":          {"USERS.v1", true, 2},
+		"CaseSensitive":       {"USERS.v1", false, -1},
+		"AmbiguousFoldedCase": {"TRANSACTIONS.", true, -1},
+		"Missing":             {"items.", true, -1},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if i := MatchTemplateSource(names, test.key, test.foldCase); i != test.expected {
+				t.Fatalf("expected %s to match %d, got %d", test.key, test.expected, i)
+			}
+		})
+	}
+}
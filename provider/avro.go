@@ -0,0 +1,475 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"time"
+)
+
+// AVRO
+// Avro object container files (https://avro.apache.org/docs/1.11.1/specification/#object-container-files)
+// start with a header holding the writer's schema and codec, followed by blocks of
+// encoded records. Records are decoded against the embedded schema, so no schema
+// needs to be registered ahead of time.
+
+var avroMagic = []byte{'O', 'b', 'j', 1}
+
+const avroSyncSize = 16
+
+type AvroIterator struct {
+	reader         *avroReader
+	schema         map[string]interface{}
+	names          map[string]interface{}
+	codec          string
+	sync           []byte
+	block          *avroReader
+	blockRemaining int64
+	featureColumns []string
+	labelColumn    string
+}
+
+func avroIteratorFromBytes(b []byte) (Iterator, error) {
+	r := &avroReader{buf: b}
+	magic, err := r.readFixed(len(avroMagic))
+	if err != nil || !bytes.Equal(magic, avroMagic) {
+		return nil, fmt.Errorf("not an avro object container file")
+	}
+	meta, err := r.readMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("could not read avro header: %w", err)
+	}
+	sync, err := r.readFixed(avroSyncSize)
+	if err != nil {
+		return nil, fmt.Errorf("could not read avro sync marker: %w", err)
+	}
+	codec := string(meta["avro.codec"])
+	if codec == "" {
+		codec = "null"
+	}
+	if codec != "null" && codec != "deflate" {
+		return nil, fmt.Errorf("unsupported avro codec: %s", codec)
+	}
+	var parsed interface{}
+	if err := json.Unmarshal(meta["avro.schema"], &parsed); err != nil {
+		return nil, fmt.Errorf("could not parse avro schema: %w", err)
+	}
+	record, isRecord := parsed.(map[string]interface{})
+	if !isRecord || record["type"] != "record" {
+		return nil, fmt.Errorf("avro schema must be a record")
+	}
+	names := make(map[string]interface{})
+	registerAvroNames(parsed, names)
+	iter := &AvroIterator{
+		reader: r,
+		schema: record,
+		names:  names,
+		codec:  codec,
+		sync:   sync,
+	}
+	schema := parquetSchema{}
+	for _, field := range avroFields(record) {
+		name, _ := field["name"].(string)
+		schema.setColumn(schema.getColumnType(name), name)
+	}
+	iter.featureColumns = schema.featureColumns
+	iter.labelColumn = schema.labelColumn
+	return iter, nil
+}
+
+func (a *AvroIterator) Next() (map[string]interface{}, error) {
+	for a.blockRemaining == 0 {
+		if a.reader.done() {
+			return nil, nil
+		}
+		if err := a.nextBlock(); err != nil {
+			return nil, err
+		}
+	}
+	row, err := a.block.readValue(a.schema, a.names)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode avro record: %w", err)
+	}
+	a.blockRemaining -= 1
+	return row.(map[string]interface{}), nil
+}
+
+func (a *AvroIterator) nextBlock() error {
+	count, err := a.reader.readLong()
+	if err != nil {
+		return fmt.Errorf("could not read avro block count: %w", err)
+	}
+	data, err := a.reader.readBytes()
+	if err != nil {
+		return fmt.Errorf("could not read avro block: %w", err)
+	}
+	sync, err := a.reader.readFixed(avroSyncSize)
+	if err != nil || !bytes.Equal(sync, a.sync) {
+		return fmt.Errorf("avro block is not followed by the file's sync marker")
+	}
+	if a.codec == "deflate" {
+		data, err = io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			return fmt.Errorf("could not inflate avro block: %w", err)
+		}
+	}
+	a.block = &avroReader{buf: data}
+	a.blockRemaining = count
+	return nil
+}
+
+func (a *AvroIterator) FeatureColumns() []string {
+	return a.featureColumns
+}
+
+func (a *AvroIterator) LabelColumn() string {
+	return a.labelColumn
+}
+
+func getAvroNumRows(b []byte) (int64, error) {
+	return countAvroRows(bytes.NewReader(b))
+}
+
+// countAvroRows sums the record counts of a file's blocks, skipping over the
+// header and the blocks' data without decoding them
+func countAvroRows(r io.Reader) (int64, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(avroMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || !bytes.Equal(magic, avroMagic) {
+		return 0, fmt.Errorf("not an avro object container file")
+	}
+	for {
+		count, err := binary.ReadVarint(br)
+		if err != nil {
+			return 0, fmt.Errorf("could not read avro header: %w", err)
+		}
+		if count == 0 {
+			break
+		}
+		if count < 0 {
+			if _, err := binary.ReadVarint(br); err != nil {
+				return 0, fmt.Errorf("could not read avro header: %w", err)
+			}
+			count = -count
+		}
+		// Each entry of the metadata map is a key and a value
+		for i := int64(0); i < 2*count; i++ {
+			if err := skipAvroBytes(br); err != nil {
+				return 0, fmt.Errorf("could not read avro header: %w", err)
+			}
+		}
+	}
+	if _, err := br.Discard(avroSyncSize); err != nil {
+		return 0, fmt.Errorf("could not read avro sync marker: %w", err)
+	}
+	numRows := int64(0)
+	for {
+		count, err := binary.ReadVarint(br)
+		if err == io.EOF {
+			return numRows, nil
+		} else if err != nil {
+			return 0, fmt.Errorf("could not read avro block count: %w", err)
+		}
+		if err := skipAvroBytes(br); err != nil {
+			return 0, fmt.Errorf("could not read avro block: %w", err)
+		}
+		if _, err := br.Discard(avroSyncSize); err != nil {
+			return 0, fmt.Errorf("avro block is not followed by the file's sync marker: %w", err)
+		}
+		numRows += count
+	}
+}
+
+func skipAvroBytes(r *bufio.Reader) error {
+	length, err := binary.ReadVarint(r)
+	if err != nil {
+		return err
+	}
+	if length < 0 {
+		return fmt.Errorf("negative length %d", length)
+	}
+	_, err = r.Discard(int(length))
+	return err
+}
+
+func avroFields(record map[string]interface{}) []map[string]interface{} {
+	raw, _ := record["fields"].([]interface{})
+	fields := make([]map[string]interface{}, 0, len(raw))
+	for _, f := range raw {
+		if field, ok := f.(map[string]interface{}); ok {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// registerAvroNames records every named type (record, enum, fixed) in the schema
+// so later references to it by name can be resolved while decoding.
+func registerAvroNames(schema interface{}, names map[string]interface{}) {
+	switch s := schema.(type) {
+	case []interface{}:
+		for _, branch := range s {
+			registerAvroNames(branch, names)
+		}
+	case map[string]interface{}:
+		if name, ok := s["name"].(string); ok {
+			switch s["type"] {
+			case "record", "enum", "fixed":
+				names[name] = s
+				if namespace, ok := s["namespace"].(string); ok && namespace != "" {
+					names[fmt.Sprintf("%s.%s", namespace, name)] = s
+				}
+			}
+		}
+		for _, field := range avroFields(s) {
+			registerAvroNames(field["type"], names)
+		}
+		registerAvroNames(s["items"], names)
+		registerAvroNames(s["values"], names)
+	}
+}
+
+type avroReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *avroReader) done() bool {
+	return r.pos >= len(r.buf)
+}
+
+func (r *avroReader) readFixed(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+// Avro ints and longs are zig-zag encoded variable length integers
+func (r *avroReader) readLong() (int64, error) {
+	val, n := binary.Varint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	r.pos += n
+	return val, nil
+}
+
+func (r *avroReader) readBytes() ([]byte, error) {
+	length, err := r.readLong()
+	if err != nil {
+		return nil, err
+	}
+	return r.readFixed(int(length))
+}
+
+func (r *avroReader) readMetadata() (map[string][]byte, error) {
+	meta := make(map[string][]byte)
+	err := r.readBlocks(func() error {
+		key, err := r.readBytes()
+		if err != nil {
+			return err
+		}
+		val, err := r.readBytes()
+		if err != nil {
+			return err
+		}
+		meta[string(key)] = val
+		return nil
+	})
+	return meta, err
+}
+
+// Arrays and maps are written as a series of blocks, each prefixed with its item
+// count, and terminated by an empty block. A negative count is followed by the
+// block's size in bytes.
+func (r *avroReader) readBlocks(readItem func() error) error {
+	for {
+		count, err := r.readLong()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			count = -count
+			if _, err := r.readLong(); err != nil {
+				return err
+			}
+		}
+		for i := int64(0); i < count; i++ {
+			if err := readItem(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (r *avroReader) readValue(schema interface{}, names map[string]interface{}) (interface{}, error) {
+	switch s := schema.(type) {
+	case string:
+		if named, ok := names[s]; ok {
+			return r.readValue(named, names)
+		}
+		return r.readPrimitive(s, "", nil)
+	case []interface{}:
+		branch, err := r.readLong()
+		if err != nil {
+			return nil, err
+		}
+		if branch < 0 || branch >= int64(len(s)) {
+			return nil, fmt.Errorf("union branch %d out of range", branch)
+		}
+		return r.readValue(s[branch], names)
+	case map[string]interface{}:
+		return r.readComplex(s, names)
+	default:
+		return nil, fmt.Errorf("invalid avro schema: %v", schema)
+	}
+}
+
+func (r *avroReader) readComplex(schema map[string]interface{}, names map[string]interface{}) (interface{}, error) {
+	typ, _ := schema["type"].(string)
+	logicalType, _ := schema["logicalType"].(string)
+	switch typ {
+	case "record":
+		row := make(map[string]interface{})
+		for _, field := range avroFields(schema) {
+			name, _ := field["name"].(string)
+			val, err := r.readValue(field["type"], names)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", name, err)
+			}
+			row[name] = val
+		}
+		return row, nil
+	case "enum":
+		idx, err := r.readLong()
+		if err != nil {
+			return nil, err
+		}
+		symbols, _ := schema["symbols"].([]interface{})
+		if idx < 0 || idx >= int64(len(symbols)) {
+			return nil, fmt.Errorf("enum symbol %d out of range", idx)
+		}
+		return symbols[idx], nil
+	case "array":
+		items := make([]interface{}, 0)
+		err := r.readBlocks(func() error {
+			item, err := r.readValue(schema["items"], names)
+			items = append(items, item)
+			return err
+		})
+		return items, err
+	case "map":
+		values := make(map[string]interface{})
+		err := r.readBlocks(func() error {
+			key, err := r.readBytes()
+			if err != nil {
+				return err
+			}
+			values[string(key)], err = r.readValue(schema["values"], names)
+			return err
+		})
+		return values, err
+	case "fixed":
+		size, _ := schema["size"].(float64)
+		b, err := r.readFixed(int(size))
+		if err != nil {
+			return nil, err
+		}
+		if logicalType == "decimal" {
+			return avroDecimal(b, schema), nil
+		}
+		return b, nil
+	default:
+		return r.readPrimitive(typ, logicalType, schema)
+	}
+}
+
+// readPrimitive decodes a primitive value. Logical types are converted to the same
+// Go types the parquet iterator produces: integers become int, timestamps become
+// UTC time.Time values and decimals become float64.
+func (r *avroReader) readPrimitive(typ, logicalType string, schema map[string]interface{}) (interface{}, error) {
+	switch typ {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := r.readFixed(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case "int", "long":
+		val, err := r.readLong()
+		if err != nil {
+			return nil, err
+		}
+		switch logicalType {
+		case "timestamp-millis", "local-timestamp-millis":
+			return time.UnixMilli(val).UTC(), nil
+		case "timestamp-micros", "local-timestamp-micros":
+			return time.UnixMicro(val).UTC(), nil
+		case "date":
+			return time.Unix(val*24*60*60, 0).UTC(), nil
+		default:
+			return int(val), nil
+		}
+	case "float":
+		b, err := r.readFixed(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+	case "double":
+		b, err := r.readFixed(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case "bytes":
+		b, err := r.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		if logicalType == "decimal" {
+			return avroDecimal(b, schema), nil
+		}
+		return b, nil
+	case "string":
+		b, err := r.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	default:
+		return nil, fmt.Errorf("unknown avro type: %s", typ)
+	}
+}
+
+// Decimals are stored as big-endian two's complement unscaled integers
+func avroDecimal(b []byte, schema map[string]interface{}) float64 {
+	unscaled := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	scale, _ := schema["scale"].(float64)
+	val, _ := new(big.Float).Quo(
+		new(big.Float).SetInt(unscaled),
+		new(big.Float).SetFloat64(math.Pow10(int(scale))),
+	).Float64()
+	return val
+}
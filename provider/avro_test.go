@@ -0,0 +1,199 @@
+package provider
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testAvroSync = []byte("featureformsync!")
+
+func writeAvroLong(buf *bytes.Buffer, v int64) {
+	b := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutVarint(b, v)
+	buf.Write(b[:n])
+}
+
+func writeAvroBytes(buf *bytes.Buffer, b []byte) {
+	writeAvroLong(buf, int64(len(b)))
+	buf.Write(b)
+}
+
+// writeAvroContainer wraps already encoded records in an object container file
+// with a single block.
+func writeAvroContainer(schema string, codec string, numRecords int, records []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.Write(avroMagic)
+	writeAvroLong(buf, 2)
+	writeAvroBytes(buf, []byte("avro.schema"))
+	writeAvroBytes(buf, []byte(schema))
+	writeAvroBytes(buf, []byte("avro.codec"))
+	writeAvroBytes(buf, []byte(codec))
+	writeAvroLong(buf, 0)
+	buf.Write(testAvroSync)
+	if codec == "deflate" {
+		compressed := new(bytes.Buffer)
+		w, err := flate.NewWriter(compressed, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(records); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		records = compressed.Bytes()
+	}
+	writeAvroLong(buf, int64(numRecords))
+	writeAvroBytes(buf, records)
+	buf.Write(testAvroSync)
+	return buf.Bytes(), nil
+}
+
+func convertToAvroBytes(schema TableSchema, list []GenericRecord) ([]byte, error) {
+	fields := make([]map[string]interface{}, len(schema.Columns))
+	for i, col := range schema.Columns {
+		var avroType interface{}
+		switch col.ValueType {
+		case Int, Int64:
+			avroType = "long"
+		case Int32:
+			avroType = "int"
+		case String:
+			avroType = "string"
+		case Float32:
+			avroType = "float"
+		case Float64:
+			avroType = "double"
+		case Bool:
+			avroType = "boolean"
+		case Timestamp:
+			avroType = map[string]string{"type": "long", "logicalType": "timestamp-millis"}
+		default:
+			return nil, fmt.Errorf("unsupported value type for avro: %v", col.ValueType)
+		}
+		fields[i] = map[string]interface{}{"name": col.Name, "type": avroType}
+	}
+	avroSchema, err := json.Marshal(map[string]interface{}{"type": "record", "name": "Record", "fields": fields})
+	if err != nil {
+		return nil, err
+	}
+	records := new(bytes.Buffer)
+	for _, record := range list {
+		for _, val := range record {
+			switch v := val.(type) {
+			case int:
+				writeAvroLong(records, int64(v))
+			case int32:
+				writeAvroLong(records, int64(v))
+			case int64:
+				writeAvroLong(records, v)
+			case string:
+				writeAvroBytes(records, []byte(v))
+			case float32:
+				b := make([]byte, 4)
+				binary.LittleEndian.PutUint32(b, math.Float32bits(v))
+				records.Write(b)
+			case float64:
+				b := make([]byte, 8)
+				binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+				records.Write(b)
+			case bool:
+				if v {
+					records.WriteByte(1)
+				} else {
+					records.WriteByte(0)
+				}
+			case time.Time:
+				writeAvroLong(records, v.UnixMilli())
+			default:
+				return nil, fmt.Errorf("unsupported avro value: %T", val)
+			}
+		}
+	}
+	return writeAvroContainer(string(avroSchema), "null", len(list), records.Bytes())
+}
+
+func TestAvroIteratorLogicalTypes(t *testing.T) {
+	schema := `{"type": "record", "name": "Row", "fields": [
+		{"name": "entity", "type": "string"},
+		{"name": "Feature__value", "type": ["null", "long"]},
+		{"name": "price", "type": {"type": "bytes", "logicalType": "decimal", "precision": 6, "scale": 2}},
+		{"name": "ts", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+		{"name": "Label__label", "type": {"type": "enum", "name": "Label", "symbols": ["no", "yes"]}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}}
+	]}`
+	ts := time.Date(2023, 1, 2, 3, 4, 5, 6000, time.UTC)
+	records := new(bytes.Buffer)
+	// entity "a", value 7, price 12.34, label "yes", tags ["x"]
+	writeAvroBytes(records, []byte("a"))
+	writeAvroLong(records, 1)
+	writeAvroLong(records, 7)
+	writeAvroBytes(records, []byte{0x04, 0xd2})
+	writeAvroLong(records, ts.UnixMicro())
+	writeAvroLong(records, 1)
+	writeAvroLong(records, 1)
+	writeAvroBytes(records, []byte("x"))
+	writeAvroLong(records, 0)
+	// entity "b", null value, price -1.00, label "no", no tags
+	writeAvroBytes(records, []byte("b"))
+	writeAvroLong(records, 0)
+	writeAvroBytes(records, []byte{0xff, 0x9c})
+	writeAvroLong(records, ts.UnixMicro())
+	writeAvroLong(records, 0)
+	writeAvroLong(records, 0)
+
+	for _, codec := range []string{"null", "deflate"} {
+		b, err := writeAvroContainer(schema, codec, 2, records.Bytes())
+		if err != nil {
+			t.Fatalf("could not write avro file: %v", err)
+		}
+		iter, err := avroIteratorFromBytes(b)
+		if err != nil {
+			t.Fatalf("could not create avro iterator: %v", err)
+		}
+		if !reflect.DeepEqual(iter.FeatureColumns(), []string{"Feature__value"}) || iter.LabelColumn() != "Label__label" {
+			t.Fatalf("unexpected columns: %v %s", iter.FeatureColumns(), iter.LabelColumn())
+		}
+		expected := []map[string]interface{}{
+			{"entity": "a", "Feature__value": 7, "price": 12.34, "ts": ts, "Label__label": "yes", "tags": []interface{}{"x"}},
+			{"entity": "b", "Feature__value": nil, "price": -1.0, "ts": ts, "Label__label": "no", "tags": []interface{}{}},
+		}
+		for _, exp := range expected {
+			row, err := iter.Next()
+			if err != nil {
+				t.Fatalf("could not read avro row: %v", err)
+			}
+			if !reflect.DeepEqual(row, exp) {
+				t.Fatalf("%s codec: expected %v, got %v", codec, exp, row)
+			}
+		}
+		if row, err := iter.Next(); row != nil || err != nil {
+			t.Fatalf("expected end of file, got %v %v", row, err)
+		}
+		numRows, err := getAvroNumRows(b)
+		if err != nil || numRows != 2 {
+			t.Fatalf("expected 2 rows, got %d: %v", numRows, err)
+		}
+	}
+}
+
+func TestAvroIteratorInvalidFile(t *testing.T) {
+	if _, err := avroIteratorFromBytes([]byte("PAR1")); err == nil {
+		t.Fatalf("expected error reading a non-avro file")
+	}
+	b, err := writeAvroContainer(`{"type": "record", "name": "Row", "fields": []}`, "snappy", 0, nil)
+	if err != nil {
+		t.Fatalf("could not write avro file: %v", err)
+	}
+	if _, err := avroIteratorFromBytes(b); err == nil {
+		t.Fatalf("expected error for unsupported codec")
+	}
+}
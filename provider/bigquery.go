@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -50,6 +51,8 @@ type BQOfflineTableQueries interface {
 	newBQOfflineTable(name string, columnType string) string
 	materializationCreate(tableName string, resultName string) string
 	materializationIterateSegment(tableName string, start int64, end int64) string
+	materializationIterateSegmentSince(tableName string, start int64, end int64, since time.Time) string
+	sampleLatestValues(tableName string, n int64) string
 	getNumRowsQuery(tableName string) string
 	getTablePrefix() string
 	setTablePrefix(prefix string)
@@ -131,6 +134,27 @@ func (pt *bqPrimaryTable) NumRows() (int64, error) {
 	return n[0].(int64), nil
 }
 
+// SizeBytes reads the size BigQuery bills the table's storage by. Views take up
+// no storage, so their size is unknown.
+func (table *bqPrimaryTable) SizeBytes() (int64, error) {
+	query := fmt.Sprintf("SELECT size_bytes FROM `%s.__TABLES__` WHERE table_id=%s", escapeBigQueryIdentifier(table.query.getTablePrefix()), quoteLiteral(pt.BigQueryOffline, table.name))
+	it, err := table.client.Query(query).Read(table.query.getContext())
+	if err != nil {
+		return 0, err
+	}
+	var row []bigquery.Value
+	if err := it.Next(&row); err == iterator.Done {
+		return 0, fmt.Errorf("size of %s is unknown", table.name)
+	} else if err != nil {
+		return 0, err
+	}
+	size, ok := row[0].(int64)
+	if !ok {
+		return 0, fmt.Errorf("size of %s is unknown", table.name)
+	}
+	return size, nil
+}
+
 func (pt *bqPrimaryTable) Write(rec GenericRecord) error {
 	tb := pt.name
 	recordsParameter, columns, columnsString := pt.getNonNullRecords(rec)
@@ -230,11 +254,12 @@ func (q defaultBQQueries) registerResources(client *bigquery.Client, tableName s
 	var query string
 	if timestamp {
 		query = fmt.Sprintf("CREATE VIEW `%s` AS SELECT `%s` as entity, `%s` as value, `%s` as ts, CURRENT_TIMESTAMP() as insert_ts FROM `%s`", q.getTableName(tableName),
-			schema.Entity, schema.Value, schema.TS, q.getTableName(schema.SourceTable))
+			escapeBigQueryIdentifier(schema.Entity), escapeBigQueryIdentifier(schema.Value), escapeBigQueryIdentifier(schema.TS), q.getTableName(schema.SourceTable))
 	} else {
 		query = fmt.Sprintf("CREATE VIEW `%s` AS SELECT `%s` as entity, `%s` as value, PARSE_TIMESTAMP('%%Y-%%m-%%d %%H:%%M:%%S +0000 UTC', '%s') as ts, CURRENT_TIMESTAMP() as insert_ts FROM `%s`", q.getTableName(tableName),
-			schema.Entity, schema.Value, time.UnixMilli(0).UTC(), q.getTableName(schema.SourceTable))
+			escapeBigQueryIdentifier(schema.Entity), escapeBigQueryIdentifier(schema.Value), time.UnixMilli(0).UTC(), q.getTableName(schema.SourceTable))
 	}
+	query += filterClause(schema.Filter)
 
 	bqQ := client.Query(query)
 	_, err := bqQ.Read(q.getContext())
@@ -253,11 +278,11 @@ func (q defaultBQQueries) writeExists(table string) string {
 }
 
 func (q defaultBQQueries) tableExists(tableName string) string {
-	return fmt.Sprintf("SELECT COUNT(*) AS total FROM `%s.INFORMATION_SCHEMA.TABLES` WHERE table_type='BASE TABLE' AND table_name='%s'", q.getTablePrefix(), tableName)
+	return fmt.Sprintf("SELECT COUNT(*) AS total FROM `%s.INFORMATION_SCHEMA.TABLES` WHERE table_type='BASE TABLE' AND table_name=%s", escapeBigQueryIdentifier(q.getTablePrefix()), quoteLiteral(pt.BigQueryOffline, tableName))
 }
 
 func (q defaultBQQueries) viewExists(viewName string) string {
-	return fmt.Sprintf("SELECT COUNT(*) AS total FROM `%s.INFORMATION_SCHEMA.TABLES` WHERE table_type='VIEW' AND table_name='%s'", q.getTablePrefix(), viewName)
+	return fmt.Sprintf("SELECT COUNT(*) AS total FROM `%s.INFORMATION_SCHEMA.TABLES` WHERE table_type='VIEW' AND table_name=%s", escapeBigQueryIdentifier(q.getTablePrefix()), quoteLiteral(pt.BigQueryOffline, viewName))
 }
 
 func (q defaultBQQueries) determineColumnType(valueType ValueType) (string, error) {
@@ -311,10 +336,20 @@ func (q defaultBQQueries) materializationCreate(tableName string, resultName str
 	return query
 }
 
+// sampleLatestValues picks n of a resource table's entities at random, with
+// their latest values
+func (q defaultBQQueries) sampleLatestValues(tableName string, n int64) string {
+	return fmt.Sprintf("SELECT entity, value, ts FROM (SELECT entity, value, ts, row_number() OVER (PARTITION BY entity ORDER BY ts DESC, insert_ts DESC) AS rn FROM `%s`) t WHERE rn=1 ORDER BY RAND() LIMIT %d", q.getTableName(tableName), n)
+}
+
 func (q defaultBQQueries) materializationIterateSegment(tableName string, start int64, end int64) string {
 	return fmt.Sprintf("SELECT entity, value, ts FROM ( SELECT * FROM `%s` WHERE row_number > %v AND row_number <= %v)", q.getTableName(tableName), start, end)
 }
 
+func (q defaultBQQueries) materializationIterateSegmentSince(tableName string, start int64, end int64, since time.Time) string {
+	return fmt.Sprintf("SELECT entity, value, ts FROM ( SELECT * FROM `%s` WHERE row_number > %v AND row_number <= %v AND ts > TIMESTAMP('%s'))", q.getTableName(tableName), start, end, since.UTC().Format(time.RFC3339Nano))
+}
+
 func (q defaultBQQueries) getNumRowsQuery(tableName string) string {
 	return fmt.Sprintf("SELECT COUNT(*) FROM `%s`", q.getTableName(tableName))
 }
@@ -361,7 +396,7 @@ func (q defaultBQQueries) castTableItemType(v interface{}, t interface{}) interf
 }
 
 func (q defaultBQQueries) materializationExists(tableName string) string {
-	return fmt.Sprintf("SELECT DISTINCT(table_name) FROM `%s.INFORMATION_SCHEMA.TABLES` WHERE table_type='BASE TABLE' AND table_name='%s'", q.getTablePrefix(), tableName)
+	return fmt.Sprintf("SELECT DISTINCT(table_name) FROM `%s.INFORMATION_SCHEMA.TABLES` WHERE table_type='BASE TABLE' AND table_name=%s", escapeBigQueryIdentifier(q.getTablePrefix()), quoteLiteral(pt.BigQueryOffline, tableName))
 }
 
 func (q defaultBQQueries) materializationDrop(tableName string) string {
@@ -420,7 +455,7 @@ func (q defaultBQQueries) transformationCreate(name string, query string) string
 }
 
 func (q defaultBQQueries) getColumns(client *bigquery.Client, name string) ([]TableColumn, error) {
-	qry := fmt.Sprintf("SELECT column_name FROM `%s.INFORMATION_SCHEMA.COLUMNS` WHERE table_name=\"%s\" ORDER BY ordinal_position", q.getTablePrefix(), name)
+	qry := fmt.Sprintf("SELECT column_name FROM `%s.INFORMATION_SCHEMA.COLUMNS` WHERE table_name=%s ORDER BY ordinal_position", escapeBigQueryIdentifier(q.getTablePrefix()), quoteLiteral(pt.BigQueryOffline, name))
 
 	bqQ := client.Query(qry)
 	it, err := bqQ.Read(q.getContext())
@@ -495,8 +530,10 @@ func (q defaultBQQueries) trainingSetQuery(store *bqOfflineStore, def TrainingSe
 		tableJoinAlias := fmt.Sprintf("t%d", i+1)
 		selectColumns = append(selectColumns, fmt.Sprintf("%s_rnk", tableJoinAlias))
 		columns = append(columns, santizedName)
-		query = fmt.Sprintf("%s LEFT OUTER JOIN (SELECT entity, value AS `%s`, ts, RANK() OVER (ORDER BY ts DESC, insert_ts DESC) AS %s_rnk FROM `%s` ORDER BY ts desc) AS %s ON (%s.entity=t0.entity AND %s.ts <= t0.ts)",
-			query, santizedName, tableJoinAlias, q.getTableName(tableName), tableJoinAlias, tableJoinAlias, tableJoinAlias)
+		// Ranks count up from the feature's newest value, so the joined row with
+		// the lowest rank has the latest value the join strategy allows
+		query = fmt.Sprintf("%s %s (SELECT entity, value AS `%s`, ts, RANK() OVER (ORDER BY ts DESC, insert_ts DESC) AS %s_rnk FROM `%s` ORDER BY ts desc) AS %s ON (%s.entity=t0.entity%s)",
+			query, def.JoinPolicy.featureJoin(), santizedName, tableJoinAlias, q.getTableName(tableName), tableJoinAlias, tableJoinAlias, def.JoinStrategy.featureTimeCondition(tableJoinAlias+".ts", "t0.ts"))
 		if i == len(def.Features)-1 {
 			query = fmt.Sprintf("%s )) WHERE rn=1", query)
 		}
@@ -507,7 +544,7 @@ func (q defaultBQQueries) trainingSetQuery(store *bqOfflineStore, def TrainingSe
 	if !isUpdate {
 		fullQuery := fmt.Sprintf(
 			"CREATE TABLE `%s` AS (SELECT %s, label FROM ("+
-				"SELECT *, row_number() over(PARTITION BY e, label, time ORDER BY \"time\", %s DESC) AS rn FROM ( "+
+				"SELECT *, row_number() over(PARTITION BY e, label, time ORDER BY \"time\", %s) AS rn FROM ( "+
 				"SELECT t0.entity AS e, t0.value AS label, t0.ts AS time, %s, %s FROM `%s` AS t0 %s )",
 			q.getTableName(tableName), columnStr, selectColumnStr, columnStr, selectColumnStr, q.getTableName(labelName), query)
 
@@ -523,7 +560,7 @@ func (q defaultBQQueries) trainingSetQuery(store *bqOfflineStore, def TrainingSe
 		tempTable := fmt.Sprintf("tmp_%s", tableName)
 		fullQuery := fmt.Sprintf(
 			"CREATE TABLE `%s` AS (SELECT %s, label FROM ("+
-				"SELECT *, row_number() over(PARTITION BY e, label, time ORDER BY \"time\", %s desc) AS rn FROM ( "+
+				"SELECT *, row_number() over(PARTITION BY e, label, time ORDER BY \"time\", %s) AS rn FROM ( "+
 				"SELECT t0.entity AS e, t0.value AS label, t0.ts AS time, %s, %s FROM `%s` AS t0 %s )",
 			q.getTableName(tempTable), columnStr, selectColumnStr, columnStr, selectColumnStr, q.getTableName(labelName), query)
 		err := q.atomicUpdate(store.client, tableName, tempTable, fullQuery)
@@ -539,8 +576,10 @@ func (q defaultBQQueries) primaryTableRegister(tableName string, sourceName stri
 	return fmt.Sprintf("CREATE VIEW `%s` AS SELECT * FROM `%s`", q.getTableName(tableName), q.getTableName(sourceName))
 }
 
+// getTableName returns the full name of a table, escaped to be put between
+// backticks
 func (q defaultBQQueries) getTableName(tableName string) string {
-	return fmt.Sprintf("%s.%s", q.getTablePrefix(), tableName)
+	return escapeBigQueryIdentifier(fmt.Sprintf("%s.%s", q.getTablePrefix(), tableName))
 }
 
 type bqMaterialization struct {
@@ -586,6 +625,17 @@ func (mat *bqMaterialization) IterateSegment(start, end int64) (FeatureIterator,
 	return newbqFeatureIterator(it, mat.query), nil
 }
 
+// IterateSegmentSince reads the rows of a segment with a timestamp after since,
+// leaving BigQuery to skip the others
+func (mat *bqMaterialization) IterateSegmentSince(start, end int64, since time.Time) (FeatureIterator, error) {
+	query := mat.query.materializationIterateSegmentSince(mat.tableName, start, end, since)
+	it, err := mat.client.Query(query).Read(mat.query.getContext())
+	if err != nil {
+		return nil, err
+	}
+	return newbqFeatureIterator(it, mat.query), nil
+}
+
 type bqFeatureIterator struct {
 	iter         *bigquery.RowIterator
 	currentValue ResourceRecord
@@ -693,13 +743,81 @@ func (table *bqOfflineTable) Write(rec ResourceRecord) error {
 	return err
 }
 
+// WriteBatch loads recs with a single load job if the table is empty, such as
+// when it's being seeded. Otherwise each record is upserted with Write, since it
+// has to replace any existing value for the same entity and timestamp.
 func (table *bqOfflineTable) WriteBatch(recs []ResourceRecord) error {
+	if len(recs) == 0 {
+		return nil
+	}
+	empty, err := table.isEmpty()
+	if err != nil {
+		return err
+	}
+	if !empty {
+		for _, rec := range recs {
+			if err := table.Write(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	latest, err := latestResourceRecords(recs)
+	if err != nil {
+		return err
+	}
+	return table.load(latest)
+}
+
+func (table *bqOfflineTable) isEmpty() (bool, error) {
+	bqQ := table.client.Query(fmt.Sprintf("SELECT 1 FROM `%s` LIMIT 1", table.query.getTableName(table.name)))
+	it, err := bqQ.Read(table.query.getContext())
+	if err != nil {
+		return false, err
+	}
+	var row []bigquery.Value
+	if err := it.Next(&row); err == iterator.Done {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// load appends recs to the table with a load job of newline delimited JSON
+func (table *bqOfflineTable) load(recs []ResourceRecord) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	insertTS := time.Now().UTC().Format(time.RFC3339Nano)
 	for _, rec := range recs {
-		if err := table.Write(rec); err != nil {
-			return err
+		row := map[string]interface{}{
+			"entity":    rec.Entity,
+			"value":     rec.Value,
+			"ts":        rec.TS.UTC().Format(time.RFC3339Nano),
+			"insert_ts": insertTS,
+		}
+		if err := encoder.Encode(row); err != nil {
+			return fmt.Errorf("encode record for entity %s: %w", rec.Entity, err)
 		}
 	}
-	return nil
+	prefix := strings.SplitN(table.query.getTablePrefix(), ".", 2)
+	if len(prefix) != 2 {
+		return fmt.Errorf("table prefix %s is not a project and dataset", table.query.getTablePrefix())
+	}
+	source := bigquery.NewReaderSource(&buf)
+	source.SourceFormat = bigquery.JSON
+	loader := table.client.DatasetInProject(prefix[0], prefix[1]).Table(table.name).LoaderFrom(source)
+	loader.WriteDisposition = bigquery.WriteAppend
+	ctx := table.query.getContext()
+	job, err := loader.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("start load job: %w", err)
+	}
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return fmt.Errorf("wait for load job: %w", err)
+	}
+	return status.Err()
 }
 
 type bqOfflineStore struct {
@@ -770,6 +888,15 @@ func (store *bqOfflineStore) RegisterResourceFromSourceTable(id ResourceID, sche
 	if schema.Entity == "" || schema.Value == "" {
 		return nil, fmt.Errorf("non-empty entity and value columns required")
 	}
+	if schema.Filter != "" {
+		columns, err := store.query.getColumns(store.client, schema.SourceTable)
+		if err != nil {
+			return nil, fmt.Errorf("get source columns: %w", err)
+		}
+		if err := validateFilter(schema.Filter, columns); err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+	}
 	tableName, err := store.getResourceTableName(id)
 	if err != nil {
 		return nil, fmt.Errorf("get name: %w", err)
@@ -866,24 +993,11 @@ func (store *bqOfflineStore) GetTransformationTable(id ResourceID) (Transformati
 	if err != nil {
 		return nil, err
 	}
-
-	existsQuery := store.query.tableExists(name)
-	bqQ := store.client.Query(existsQuery)
-	it, err := bqQ.Read(store.query.getContext())
-	if err != nil {
-		return nil, err
-	}
-
-	var row []bigquery.Value
-	err = it.Next(&row)
-
-	if err != nil {
+	if exists, err := store.tableExists(id); err != nil {
 		return nil, err
+	} else if !exists {
+		return nil, &TableNotFound{id.Name, id.Variant}
 	}
-	if len(row) == 0 {
-		return nil, fmt.Errorf("transformation not found: %v", name)
-	}
-
 	columnNames, err := store.query.getColumns(store.client, name)
 	if err != nil {
 		return nil, err
@@ -1015,6 +1129,23 @@ func (store *bqOfflineStore) GetResourceTable(id ResourceID) (OfflineTable, erro
 	return store.getbqResourceTable(id)
 }
 
+func (store *bqOfflineStore) SampleLatestFeatureValues(id ResourceID, n int64) ([]ResourceRecord, error) {
+	table, err := store.getbqResourceTable(id)
+	if err != nil {
+		return nil, err
+	}
+	it, err := store.client.Query(store.query.sampleLatestValues(table.name, n)).Read(store.query.getContext())
+	if err != nil {
+		return nil, err
+	}
+	iter := newbqFeatureIterator(it, store.query)
+	records := make([]ResourceRecord, 0, n)
+	for iter.Next() {
+		records = append(records, iter.Value())
+	}
+	return records, iter.Err()
+}
+
 func (store *bqOfflineStore) CreateMaterialization(id ResourceID) (Materialization, error) {
 	if id.Type != Feature {
 		return nil, errors.New("only features can be materialized")
@@ -1294,12 +1425,39 @@ func (store *bqOfflineStore) AsOfflineStore() (OfflineStore, error) {
 	return store, nil
 }
 
+// TagTable sets labels on a resource's table or view. Label values are
+// lowercased and have characters BigQuery doesn't allow replaced.
+func (store *bqOfflineStore) TagTable(id ResourceID, tags ResourceTags) error {
+	tableName, err := store.tableName(id)
+	if err != nil {
+		return err
+	}
+	sc := pc.BigQueryConfig{}
+	if err := sc.Deserialize(store.parent.Config); err != nil {
+		return errors.New("invalid bigquery config")
+	}
+	ctx := store.query.getContext()
+	table := store.client.Dataset(sc.DatasetId).Table(tableName)
+	md, err := table.Metadata(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get metadata of %s: %w", tableName, err)
+	}
+	var update bigquery.TableMetadataToUpdate
+	for key, value := range tableTags(id, tags) {
+		update.SetLabel(key, labelValue(value))
+	}
+	if _, err := table.Update(ctx, update, md.ETag); err != nil {
+		return fmt.Errorf("could not label %s: %w", tableName, err)
+	}
+	return nil
+}
+
 func (store *bqOfflineStore) Close() error {
 	return store.client.Close()
 }
 
-func (store *bqOfflineStore) tableExists(id ResourceID) (bool, error) {
-	var n []bigquery.Value
+// tableName returns the name of the table or view a resource is stored in
+func (store *bqOfflineStore) tableName(id ResourceID) (string, error) {
 	var tableName string
 	var err error
 	if id.check(Feature, Label) == nil {
@@ -1309,6 +1467,12 @@ func (store *bqOfflineStore) tableExists(id ResourceID) (bool, error) {
 	} else if id.check(Primary) == nil || id.check(Transformation) == nil {
 		tableName, err = GetPrimaryTableName(id)
 	}
+	return tableName, err
+}
+
+func (store *bqOfflineStore) tableExists(id ResourceID) (bool, error) {
+	var n []bigquery.Value
+	tableName, err := store.tableName(id)
 	if err != nil {
 		return false, err
 	}
@@ -168,6 +168,13 @@ func (table OnlineFileStoreTable) setEntityValue(feature, variant, entity string
 		return err
 	}
 	valueBytes := []byte(fmt.Sprintf("%v", value.(interface{})))
+	if table.valueType == JSON {
+		text, err := jsonText(value)
+		if err != nil {
+			return err
+		}
+		valueBytes = []byte(text)
+	}
 	return table.store.Write(&filepath, valueBytes)
 }
 
@@ -227,6 +234,8 @@ func castBytesToValue(value []byte, valueType ValueType) (interface{}, error) {
 		return bool(val.(bool)), err
 	case Timestamp:
 		return time.Parse(time.ANSIC, valueString)
+	case JSON:
+		return parseJSON(value)
 	default:
 		return nil, fmt.Errorf("undefined value type: %v", valueType)
 	}
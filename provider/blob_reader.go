@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"gocloud.dev/blob"
+)
+
+// The parquet reader reads pages a few KB at a time, so blobReaderAt fetches
+// files in larger chunks and keeps the most recently used ones. Columns are
+// stored apart, so reading a row group jumps between several chunks.
+const (
+	blobReaderChunkSize = 4 << 20
+	blobReaderMaxChunks = 8
+)
+
+// blobReaderAt reads a file from a bucket in ranges. It holds at most
+// blobReaderMaxChunks chunks at once, whatever the size of the file.
+type blobReaderAt struct {
+	bucket *blob.Bucket
+	key    string
+	size   int64
+	mtx    sync.Mutex
+	chunks map[int64][]byte
+	// Indexes of the cached chunks, least recently used first
+	used []int64
+}
+
+func newBlobReaderAt(bucket *blob.Bucket, key string, size int64) *blobReaderAt {
+	return &blobReaderAt{
+		bucket: bucket,
+		key:    key,
+		size:   size,
+		chunks: make(map[int64][]byte),
+	}
+}
+
+func (r *blobReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= r.size {
+			return n, io.EOF
+		}
+		idx := pos / blobReaderChunkSize
+		chunk, err := r.chunk(idx)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], chunk[pos-idx*blobReaderChunkSize:])
+	}
+	return n, nil
+}
+
+func (r *blobReaderAt) chunk(idx int64) ([]byte, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if chunk, has := r.chunks[idx]; has {
+		r.markUsed(idx)
+		return chunk, nil
+	}
+	start := idx * blobReaderChunkSize
+	length := int64(blobReaderChunkSize)
+	if start+length > r.size {
+		length = r.size - start
+	}
+	reader, err := r.bucket.NewRangeReader(context.TODO(), r.key, start, length, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s from %d: %w", r.key, start, err)
+	}
+	defer reader.Close()
+	chunk, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s from %d: %w", r.key, start, err)
+	}
+	if len(r.used) == blobReaderMaxChunks {
+		delete(r.chunks, r.used[0])
+		r.used = r.used[1:]
+	}
+	r.chunks[idx] = chunk
+	r.used = append(r.used, idx)
+	return chunk, nil
+}
+
+func (r *blobReaderAt) markUsed(idx int64) {
+	for i, used := range r.used {
+		if used == idx {
+			r.used = append(r.used[:i], r.used[i+1:]...)
+			break
+		}
+	}
+	r.used = append(r.used, idx)
+}
+
+func (r *blobReaderAt) Size() int64 {
+	return r.size
+}
@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/featureform/filestore"
+	"gocloud.dev/blob/memblob"
+)
+
+func TestBlobReaderAt(t *testing.T) {
+	bucket := memblob.OpenBucket(nil)
+	defer bucket.Close()
+	data := make([]byte, blobReaderChunkSize*(blobReaderMaxChunks+2)+123)
+	rand.New(rand.NewSource(0)).Read(data)
+	if err := bucket.WriteAll(context.Background(), "file", data, nil); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+	reader := newBlobReaderAt(bucket, "file", int64(len(data)))
+
+	// Reads across a chunk boundary
+	p := make([]byte, 100)
+	off := int64(blobReaderChunkSize - 50)
+	if n, err := reader.ReadAt(p, off); n != len(p) || err != nil {
+		t.Fatalf("expected %d bytes, got %d: %v", len(p), n, err)
+	}
+	if !bytes.Equal(p, data[off:off+int64(len(p))]) {
+		t.Fatalf("read wrong bytes across chunks")
+	}
+
+	// Reads past the end of the file
+	off = int64(len(data) - 10)
+	n, err := reader.ReadAt(p, off)
+	if n != 10 || err != io.EOF {
+		t.Fatalf("expected 10 bytes and EOF at the end of the file, got %d: %v", n, err)
+	}
+	if !bytes.Equal(p[:n], data[off:]) {
+		t.Fatalf("read wrong bytes at the end of the file")
+	}
+
+	// Reading the whole file never holds more than the chunk limit
+	all := make([]byte, 0, len(data))
+	for off := int64(0); off < int64(len(data)); off += int64(len(p)) * 1000 {
+		buf := make([]byte, len(p)*1000)
+		n, err := reader.ReadAt(buf, off)
+		if err != nil && err != io.EOF {
+			t.Fatalf("could not read at %d: %v", off, err)
+		}
+		all = append(all, buf[:n]...)
+		if len(reader.chunks) > blobReaderMaxChunks {
+			t.Fatalf("expected at most %d chunks, got %d", blobReaderMaxChunks, len(reader.chunks))
+		}
+	}
+	if !bytes.Equal(all, data) {
+		t.Fatalf("read wrong bytes reading the whole file")
+	}
+}
+
+func TestServeStreamsParquet(t *testing.T) {
+	bucket := memblob.OpenBucket(nil)
+	store := &genericFileStore{bucket: bucket}
+	defer store.Close()
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+		},
+	}
+	numRows := 1000
+	records := make([]GenericRecord, numRows)
+	for i := range records {
+		records[i] = GenericRecord{fmt.Sprintf("entity_%d", i), i}
+	}
+	b, err := schema.ToParquetBytes(records, ParquetWriteConfig{})
+	if err != nil {
+		t.Fatalf("could not write parquet file: %v", err)
+	}
+	path := &filestore.LocalFilepath{}
+	if err := path.SetKey("part-0000.parquet"); err != nil {
+		t.Fatalf("could not set key: %v", err)
+	}
+	if err := store.Write(path, b); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+	iter, err := store.Serve([]filestore.Filepath{path})
+	if err != nil {
+		t.Fatalf("could not serve file: %v", err)
+	}
+	parquetIter, ok := iter.(*ParquetIterator)
+	if !ok {
+		t.Fatalf("expected a parquet iterator, got %T", iter)
+	}
+	if _, isBlob := parquetIter.file.reader.(*blobReaderAt); !isBlob {
+		t.Fatalf("expected file to be streamed from the bucket, got %T", parquetIter.file.reader)
+	}
+	for i := 0; i < numRows; i++ {
+		row, err := iter.Next()
+		if err != nil {
+			t.Fatalf("could not read row %d: %v", i, err)
+		}
+		if row["entity"] != fmt.Sprintf("entity_%d", i) {
+			t.Fatalf("row %d: expected entity_%d, got %v", i, i, row["entity"])
+		}
+	}
+	if row, err := iter.Next(); row != nil || err != nil {
+		t.Fatalf("expected end of file, got %v %v", row, err)
+	}
+}
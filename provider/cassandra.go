@@ -39,6 +39,14 @@ type cassandraOnlineTable struct {
 	valueType ValueType
 }
 
+func newCassandraOnlineTable(session *gocql.Session, key cassandraTableKey, valueType ValueType) *cassandraOnlineTable {
+	return &cassandraOnlineTable{
+		session:   session,
+		key:       key,
+		valueType: valueType,
+	}
+}
+
 func cassandraOnlineStoreFactory(serialized pc.SerializedConfig) (Provider, error) {
 	cassandraConfig := &pc.CassandraConfig{}
 	if err := cassandraConfig.Deserialize(serialized); err != nil {
@@ -52,14 +60,21 @@ func cassandraOnlineStoreFactory(serialized pc.SerializedConfig) (Provider, erro
 }
 
 func NewCassandraOnlineStore(options *pc.CassandraConfig) (*cassandraOnlineStore, error) {
-	cassandraCluster := gocql.NewCluster(options.Addr)
+	hosts := options.Hosts()
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("cassandra config has no Addr or ContactPoints to connect to")
+	}
+	cassandraCluster := gocql.NewCluster(hosts...)
 	cassandraCluster.Authenticator = gocql.PasswordAuthenticator{
 		Username: options.Username,
 		Password: options.Password,
 	}
-	err := cassandraCluster.Consistency.UnmarshalText([]byte(options.Consistency))
-	if err != nil {
-		return nil, err
+	// The session's consistency applies to every query it makes
+	cassandraCluster.Consistency = gocql.Quorum
+	if options.Consistency != "" {
+		if err := cassandraCluster.Consistency.UnmarshalText([]byte(options.Consistency)); err != nil {
+			return nil, fmt.Errorf("invalid cassandra consistency %q: %w", options.Consistency, err)
+		}
 	}
 	newSession, err := cassandraCluster.CreateSession()
 	if err != nil {
@@ -110,34 +125,33 @@ func GetMetadataTableName(keyspace string) string {
 
 func (store *cassandraOnlineStore) CreateTable(feature, variant string, valueType ValueType) (OnlineStoreTable, error) {
 	tableName := GetTableName(store.keyspace, feature, variant)
-	vType := cassandraTypeMap[string(valueType.Scalar())]
+	vType, has := cassandraTypeMap[string(valueType.Scalar())]
+	if !has {
+		return nil, fmt.Errorf("cassandra does not support value type %s", valueType.Scalar())
+	}
 	key := cassandraTableKey{store.keyspace, feature, variant}
 	getTable, _ := store.GetTable(feature, variant)
 	if getTable != nil {
 		return nil, &TableAlreadyExists{feature, variant}
 	}
 
-	metadataTableName := GetMetadataTableName(store.keyspace)
-	query := fmt.Sprintf("INSERT INTO %s (tableName, tableType) VALUES (?, ?)", metadataTableName)
-	err := store.session.Query(query, tableName, string(valueType.Scalar())).WithContext(context.TODO()).Exec()
+	// The table is created before it's recorded in the metadata table, so
+	// that if creating it fails part way it can be created again. IF NOT
+	// EXISTS keeps that from failing on a table left by the last attempt.
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (entity text PRIMARY KEY, value %s)", tableName, vType)
+	err := store.session.Query(query).WithContext(context.TODO()).Exec()
 	if err != nil {
 		return nil, err
 	}
 
-	query = fmt.Sprintf("CREATE TABLE %s (entity text PRIMARY KEY, value %s)", tableName, vType)
-	err = store.session.Query(query).WithContext(context.TODO()).Exec()
+	metadataTableName := GetMetadataTableName(store.keyspace)
+	query = fmt.Sprintf("INSERT INTO %s (tableName, tableType) VALUES (?, ?)", metadataTableName)
+	err = store.session.Query(query, tableName, string(valueType.Scalar())).WithContext(context.TODO()).Exec()
 	if err != nil {
 		return nil, err
 	}
 
-	table := &cassandraOnlineTable{
-		session:   store.session,
-		key:       key,
-		valueType: valueType,
-	}
-
-	return table, nil
-
+	return newCassandraOnlineTable(store.session, key, valueType), nil
 }
 
 func (store *cassandraOnlineStore) GetTable(feature, variant string) (OnlineStoreTable, error) {
@@ -146,8 +160,8 @@ func (store *cassandraOnlineStore) GetTable(feature, variant string) (OnlineStor
 
 	var vType string
 	metadataTableName := GetMetadataTableName(store.keyspace)
-	query := fmt.Sprintf("SELECT tableType FROM %s WHERE tableName = '%s'", metadataTableName, tableName)
-	err := store.session.Query(query).WithContext(context.TODO()).Scan(&vType)
+	query := fmt.Sprintf("SELECT tableType FROM %s WHERE tableName = ?", metadataTableName)
+	err := store.session.Query(query, tableName).WithContext(context.TODO()).Scan(&vType)
 	if err == gocql.ErrNotFound {
 		return nil, &TableNotFound{feature, variant}
 	}
@@ -155,24 +169,18 @@ func (store *cassandraOnlineStore) GetTable(feature, variant string) (OnlineStor
 		return nil, err
 	}
 
-	table := &cassandraOnlineTable{
-		session:   store.session,
-		key:       key,
-		valueType: ScalarType(vType),
-	}
-
-	return table, nil
+	return newCassandraOnlineTable(store.session, key, ScalarType(vType)), nil
 }
 
 func (store *cassandraOnlineStore) DeleteTable(feature, variant string) error {
 	tableName := GetTableName(store.keyspace, feature, variant)
 	metadataTableName := GetMetadataTableName(store.keyspace)
-	query := fmt.Sprintf("DELETE FROM %s WHERE tableName = '%s' IF EXISTS", metadataTableName, tableName)
-	err := store.session.Query(query).WithContext(context.TODO()).Exec()
+	query := fmt.Sprintf("DELETE FROM %s WHERE tableName = ? IF EXISTS", metadataTableName)
+	err := store.session.Query(query, tableName).WithContext(context.TODO()).Exec()
 	if err != nil {
 		return err
 	}
-	query = fmt.Sprintf("DROP TABLE [IF EXISTS] %s", tableName)
+	query = fmt.Sprintf("DROP TABLE IF EXISTS %s", tableName)
 	err = store.session.Query(query).WithContext(context.TODO()).Exec()
 	if err != nil {
 		return err
@@ -181,10 +189,20 @@ func (store *cassandraOnlineStore) DeleteTable(feature, variant string) error {
 	return nil
 }
 
+// Set and Get bind their values rather than formatting them into the query,
+// so that gocql prepares each query once per session and reuses it.
 func (table cassandraOnlineTable) Set(entity string, value interface{}) error {
 	key := table.key
 	tableName := GetTableName(key.Keyspace, key.Feature, key.Variant)
 
+	if table.valueType == JSON {
+		text, err := jsonText(value)
+		if err != nil {
+			return err
+		}
+		value = text
+	}
+
 	query := fmt.Sprintf("INSERT INTO %s (entity, value) VALUES (?, ?)", tableName)
 	err := table.session.Query(query, entity, value).WithContext(context.TODO()).Exec()
 	if err != nil {
@@ -211,14 +229,14 @@ func (table cassandraOnlineTable) Get(entity string) (interface{}, error) {
 		ptr = new(float64)
 	case Bool:
 		ptr = new(bool)
-	case String, NilType:
+	case String, NilType, JSON:
 		ptr = new(string)
 	default:
 		return nil, fmt.Errorf("data type not recognized")
 	}
 
-	query := fmt.Sprintf("SELECT value FROM %s WHERE entity = '%s'", tableName, entity)
-	err := table.session.Query(query).WithContext(context.TODO()).Scan(ptr)
+	query := fmt.Sprintf("SELECT value FROM %s WHERE entity = ?", tableName)
+	err := table.session.Query(query, entity).WithContext(context.TODO()).Scan(ptr)
 	if err == gocql.ErrNotFound {
 		return nil, &EntityNotFound{entity}
 	}
@@ -239,6 +257,9 @@ func (table cassandraOnlineTable) Get(entity string) (interface{}, error) {
 	case *bool:
 		val = *casted
 	case *string:
+		if table.valueType == JSON {
+			return parseJSON([]byte(*casted))
+		}
 		val = *casted
 	default:
 		return nil, fmt.Errorf("data type not recognized")
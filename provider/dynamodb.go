@@ -260,10 +260,18 @@ func (store *dynamodbOnlineStore) DeleteTable(feature, variant string) error {
 }
 
 func (table dynamodbOnlineTable) Set(entity string, value interface{}) error {
+	serialized := fmt.Sprintf("%v", value)
+	if table.valueType == JSON {
+		text, err := jsonText(value)
+		if err != nil {
+			return err
+		}
+		serialized = text
+	}
 	input := &dynamodb.UpdateItemInput{
 		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
 			":val": {
-				S: aws.String(fmt.Sprintf("%v", value)),
+				S: aws.String(serialized),
 			},
 		},
 		TableName: aws.String(GetTablename(table.key.Prefix, table.key.Feature, table.key.Variant)),
@@ -315,6 +323,8 @@ func (table dynamodbOnlineTable) Get(entity string) (interface{}, error) {
 		result, err = strconv.ParseFloat(dynamodb_item.Value, 64)
 	case Bool:
 		result, err = strconv.ParseBool(dynamodb_item.Value)
+	case JSON:
+		result, err = parseJSON([]byte(dynamodb_item.Value))
 	}
 	if err != nil {
 		return nil, err
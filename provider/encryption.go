@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// Written at the start of every file encrypted by an envelopeEncrypter
+var encryptedFileMagic = []byte("FFENC1")
+
+// Size of the random key each file's contents are encrypted with
+const dataKeySize = 32
+
+// envelopeEncrypter encrypts each file with its own random data key, and stores
+// that key alongside the file encrypted with the configured key. An encrypted
+// file is laid out as:
+//
+//	magic | nonce | encrypted data key | nonce | encrypted contents
+//
+// Both keys use AES-GCM, so tampered files fail to decrypt rather than
+// returning corrupted data.
+type envelopeEncrypter struct {
+	keyEncryption cipher.AEAD
+}
+
+// newEnvelopeEncrypter creates an encrypter from a base64 encoded 16, 24 or 32
+// byte AES key. It returns nil if the key is empty, which leaves files
+// unencrypted.
+func newEnvelopeEncrypter(encodedKey string) (*envelopeEncrypter, error) {
+	if encodedKey == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key is not valid base64: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return &envelopeEncrypter{keyEncryption: aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (e *envelopeEncrypter) Encrypt(data []byte) ([]byte, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, fmt.Errorf("could not generate data key: %w", err)
+	}
+	dataEncryption, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	out := append([]byte{}, encryptedFileMagic...)
+	out, err = sealValue(e.keyEncryption, out, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return sealValue(dataEncryption, out, data)
+}
+
+// Decrypt returns the contents of a file written by Encrypt. Files that weren't
+// encrypted, such as those written before a key was configured, are returned
+// unchanged.
+func (e *envelopeEncrypter) Decrypt(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, encryptedFileMagic) {
+		return data, nil
+	}
+	dataKey, rest, err := openValue(e.keyEncryption, data[len(encryptedFileMagic):], dataKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt data key: %w", err)
+	}
+	dataEncryption, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, _, err := openValue(dataEncryption, rest, len(rest)-dataEncryption.NonceSize()-dataEncryption.Overhead())
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt file: %w", err)
+	}
+	return plaintext, nil
+}
+
+// sealValue appends a random nonce and the encrypted plaintext to dst
+func sealValue(aead cipher.AEAD, dst, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("could not generate nonce: %w", err)
+	}
+	dst = append(dst, nonce...)
+	return aead.Seal(dst, nonce, plaintext, encryptedFileMagic), nil
+}
+
+// openValue decrypts a value of plaintextSize bytes written by sealValue from the
+// start of data, and returns it along with the rest of data
+func openValue(aead cipher.AEAD, data []byte, plaintextSize int) ([]byte, []byte, error) {
+	size := aead.NonceSize() + plaintextSize + aead.Overhead()
+	if plaintextSize < 0 || len(data) < size {
+		return nil, nil, fmt.Errorf("encrypted file is truncated")
+	}
+	nonce := data[:aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, data[aead.NonceSize():size], encryptedFileMagic)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plaintext, data[size:], nil
+}
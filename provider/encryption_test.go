@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/featureform/filestore"
+	pc "github.com/featureform/provider/provider_config"
+)
+
+func newEncryptedLocalFileStore(t *testing.T, dir string, key []byte) FileStore {
+	config := pc.LocalFileStoreConfig{
+		DirPath:       fmt.Sprintf("file:///%s", dir),
+		EncryptionKey: base64.StdEncoding.EncodeToString(key),
+	}
+	serialized, err := config.Serialize()
+	if err != nil {
+		t.Fatalf("could not serialize file store config: %v", err)
+	}
+	store, err := NewLocalFileStore(serialized)
+	if err != nil {
+		t.Fatalf("could not create local file store: %v", err)
+	}
+	return store
+}
+
+func TestFileStoreEncryption(t *testing.T) {
+	dir := t.TempDir()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	store := newEncryptedLocalFileStore(t, dir, key)
+	defer store.Close()
+
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+		},
+	}
+	records := []GenericRecord{
+		{"plaintext_entity_a", 1},
+		{"plaintext_entity_b", 2},
+	}
+	content, err := schema.ToParquetBytes(records, ParquetWriteConfig{})
+	if err != nil {
+		t.Fatalf("could not write parquet file: %v", err)
+	}
+	path, err := store.CreateFilePath("encrypted/part-0000.parquet")
+	if err != nil {
+		t.Fatalf("could not create file path: %v", err)
+	}
+	if err := store.Write(path, content); err != nil {
+		t.Fatalf("could not write encrypted file: %v", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(dir, "encrypted", "part-0000.parquet"))
+	if err != nil {
+		t.Fatalf("could not read raw file: %v", err)
+	}
+	if bytes.Equal(raw, content) || bytes.Contains(raw, []byte("plaintext_entity")) || bytes.HasPrefix(raw, []byte("PAR1")) {
+		t.Fatalf("expected file to be encrypted on disk")
+	}
+	read, err := store.Read(path)
+	if err != nil {
+		t.Fatalf("could not read encrypted file: %v", err)
+	}
+	if !bytes.Equal(read, content) {
+		t.Fatalf("expected Read to return the original content")
+	}
+	iter, err := store.Serve([]filestore.Filepath{path})
+	if err != nil {
+		t.Fatalf("could not serve encrypted file: %v", err)
+	}
+	for i := range records {
+		row, err := iter.Next()
+		if err != nil || row == nil {
+			t.Fatalf("expected row %d, got %v: %v", i, row, err)
+		}
+		if row["entity"] != records[i][0] {
+			t.Fatalf("row %d: expected entity %v, got %v", i, records[i][0], row["entity"])
+		}
+	}
+
+	otherKey := make([]byte, 32)
+	if _, err := rand.Read(otherKey); err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	otherStore := newEncryptedLocalFileStore(t, dir, otherKey)
+	defer otherStore.Close()
+	if _, err := otherStore.Read(path); err == nil {
+		t.Fatalf("expected reading with a different key to fail")
+	}
+}
+
+func TestEnvelopeEncrypterInvalidKey(t *testing.T) {
+	if _, err := newEnvelopeEncrypter("not base64!"); err == nil {
+		t.Fatalf("expected invalid base64 key to fail")
+	}
+	if _, err := newEnvelopeEncrypter(base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Fatalf("expected key of invalid length to fail")
+	}
+	if encrypter, err := newEnvelopeEncrypter(""); encrypter != nil || err != nil {
+		t.Fatalf("expected no encrypter without a key, got %v %v", encrypter, err)
+	}
+}
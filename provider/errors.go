@@ -1,5 +1,11 @@
 package provider
 
+import (
+	"fmt"
+
+	pt "github.com/featureform/provider/provider_type"
+)
+
 type InvalidQueryError struct {
 	error string
 }
@@ -29,3 +35,33 @@ type EmptyParquetFileError struct{}
 func (e EmptyParquetFileError) Error() string {
 	return "could not read empty parquet file"
 }
+
+// UnknownProviderTypeError is returned by Get when no provider of Type is
+// registered.
+type UnknownProviderTypeError struct {
+	Type pt.Type
+}
+
+func (e *UnknownProviderTypeError) Error() string {
+	return fmt.Sprintf("no provider of type: %s", e.Type)
+}
+
+// NotOfflineStoreError is returned by AsOfflineStore when a provider can't be
+// used as an offline store, such as when it's an online store.
+type NotOfflineStoreError struct {
+	Type pt.Type
+}
+
+func (e *NotOfflineStoreError) Error() string {
+	return fmt.Sprintf("%s cannot be used as an OfflineStore", e.Type)
+}
+
+// NotOnlineStoreError is returned by AsOnlineStore when a provider can't be
+// used as an online store, such as when it's an offline store.
+type NotOnlineStoreError struct {
+	Type pt.Type
+}
+
+func (e *NotOnlineStoreError) Error() string {
+	return fmt.Sprintf("%s cannot be used as an OnlineStore", e.Type)
+}
@@ -8,7 +8,9 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 
 	re "github.com/avast/retry-go/v4"
 
@@ -17,8 +19,13 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	s3v2 "github.com/aws/aws-sdk-go-v2/service/s3"
 	hdfs "github.com/colinmarc/hdfs/v2"
+	cfg "github.com/featureform/config"
 	filestore "github.com/featureform/filestore"
 	pc "github.com/featureform/provider/provider_config"
+	krb "github.com/jcmturner/gokrb5/v8/client"
+	krbconfig "github.com/jcmturner/gokrb5/v8/config"
+	krbcreds "github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
 
 	"io/fs"
 	"time"
@@ -34,16 +41,30 @@ import (
 type FileStore interface {
 	Write(key filestore.Filepath, data []byte) error
 	Read(key filestore.Filepath) ([]byte, error)
+	// Serve iterates over the files in the order they're given, and over each
+	// file's rows in the order they were written, as a single table
 	Serve(keys []filestore.Filepath) (Iterator, error)
 	Exists(key filestore.Filepath) (bool, error)
 	Delete(key filestore.Filepath) error
 	DeleteAll(dir filestore.Filepath) error
 	NewestFileOfType(prefix filestore.Filepath, fileType filestore.FileType) (filestore.Filepath, error)
+	// NewestFileOfTypes returns the most recently modified file under prefix that
+	// matches any of fileTypes, such as both Parquet and "snappy.parquet". Of
+	// files modified at the same time, the one with the greatest key is returned.
+	NewestFileOfTypes(prefix filestore.Filepath, fileTypes ...filestore.FileType) (filestore.Filepath, error)
+	// NewestNFilesOfType returns the n most recently modified files of fileType
+	// under prefix, newest first. Fewer are returned if there aren't n of them.
+	NewestNFilesOfType(prefix filestore.Filepath, fileType filestore.FileType, n int) ([]filestore.Filepath, error)
 	List(dirPath filestore.Filepath, fileType filestore.FileType) ([]filestore.Filepath, error)
 	NumRows(key filestore.Filepath) (int64, error)
 	Close() error
 	Upload(sourcePath filestore.Filepath, destPath filestore.Filepath) error
 	Download(sourcePath filestore.Filepath, destPath filestore.Filepath) error
+	// UploadWithProgress and DownloadWithProgress are Upload and Download that
+	// report how much of the file has been transferred to progress, which can
+	// be nil
+	UploadWithProgress(sourcePath filestore.Filepath, destPath filestore.Filepath, progress TransferProgress) error
+	DownloadWithProgress(sourcePath filestore.Filepath, destPath filestore.Filepath, progress TransferProgress) error
 	FilestoreType() filestore.FileStoreType
 	AddEnvVars(envVars map[string]string) map[string]string
 	// CreateFilePath creates a new filepath object with the bucket and scheme from a Key
@@ -51,6 +72,162 @@ type FileStore interface {
 	CreateDirPath(key string) (filestore.Filepath, error)
 }
 
+// TransferProgress is called as a file is uploaded or downloaded with how many
+// of its totalBytes have been transferred. It's called with 0 before the
+// transfer starts, and with totalBytes once it has finished. Stores that can't
+// stream a file report nothing in between.
+type TransferProgress func(bytesTransferred, totalBytes int64)
+
+func (progress TransferProgress) report(bytesTransferred, totalBytes int64) {
+	if progress != nil {
+		progress(bytesTransferred, totalBytes)
+	}
+}
+
+// progressWriter reports the bytes written through it as they're written
+type progressWriter struct {
+	writer   io.Writer
+	written  int64
+	total    int64
+	progress TransferProgress
+}
+
+func newProgressWriter(writer io.Writer, total int64, progress TransferProgress) *progressWriter {
+	progress.report(0, total)
+	return &progressWriter{writer: writer, total: total, progress: progress}
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.written += int64(n)
+	w.progress.report(w.written, w.total)
+	return n, err
+}
+
+// ServeNewestFile iterates over only the most recently written file of the given
+// type under dir, such as the latest snapshot in a directory of snapshots.
+func ServeNewestFile(store FileStore, dir filestore.Filepath, fileType filestore.FileType) (Iterator, error) {
+	newest, err := store.NewestFileOfType(dir, fileType)
+	if err != nil {
+		return nil, fmt.Errorf("could not get newest %s file in %s: %w", fileType, dir.ToURI(), err)
+	}
+	if newest == nil || newest.Key() == "" {
+		return nil, fmt.Errorf("no %s files in %s", fileType, dir.ToURI())
+	}
+	return store.Serve([]filestore.Filepath{newest})
+}
+
+// ServeNewestFiles iterates over the n most recently written files of the given
+// type under dir as a single table, such as the last week of daily snapshots.
+func ServeNewestFiles(store FileStore, dir filestore.Filepath, fileType filestore.FileType, n int) (Iterator, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("number of files to serve must be positive, got %d", n)
+	}
+	newest, err := store.NewestNFilesOfType(dir, fileType, n)
+	if err != nil {
+		return nil, fmt.Errorf("could not get newest %s files in %s: %w", fileType, dir.ToURI(), err)
+	}
+	if len(newest) == 0 {
+		return nil, fmt.Errorf("no %s files in %s", fileType, dir.ToURI())
+	}
+	return store.Serve(newest)
+}
+
+// RowMapper transforms each row served from a file, such as to rename or cast a
+// column. Returning an error stops the iteration.
+type RowMapper func(row map[string]interface{}) (map[string]interface{}, error)
+
+// ServeMapped serves files like FileStore.Serve, passing each row through mapper
+// before it's returned. A nil mapper serves the rows unchanged.
+func ServeMapped(store FileStore, files []filestore.Filepath, mapper RowMapper) (Iterator, error) {
+	iter, err := store.Serve(files)
+	if err != nil {
+		return nil, err
+	}
+	if mapper == nil {
+		return iter, nil
+	}
+	return &mappedIterator{iter: iter, mapper: mapper}, nil
+}
+
+// ServeCSV serves CSV files like FileStore.Serve, reading them with config
+// rather than the defaults, such as to read tab-separated files. The Timestamp
+// columns of schema are read with config's layout; the types of other columns
+// are inferred. Every file must be CSV; mixing in files of other formats is an
+// error.
+func ServeCSV(store FileStore, files []filestore.Filepath, schema TableSchema, config CSVConfig) (Iterator, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files to serve")
+	}
+	for _, file := range files {
+		if file.Ext() != filestore.CSV {
+			return nil, fmt.Errorf("cannot serve %s as CSV: it is a %s file", file.Key(), file.Ext())
+		}
+	}
+	return newCSVFilesIterator(files, store, schema, config)
+}
+
+// NumRowsOfFiles returns the total number of rows in files, such as the parts of
+// a directory being served as one table. Counting a row usually means fetching
+// the file to read its footer, so up to parallelism files are counted at once;
+// a parallelism below 1 uses config.GetNumRowsParallelism. If any file can't be
+// counted, the error for the first such file in files is returned.
+func NumRowsOfFiles(store FileStore, files []filestore.Filepath, parallelism int) (int64, error) {
+	if parallelism < 1 {
+		parallelism = cfg.GetNumRowsParallelism()
+	}
+	if parallelism > len(files) {
+		parallelism = len(files)
+	}
+	counts := make([]int64, len(files))
+	errs := make([]error, len(files))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				counts[i], errs[i] = store.NumRows(files[i])
+			}
+		}()
+	}
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	var total int64
+	for i, count := range counts {
+		if errs[i] != nil {
+			return 0, fmt.Errorf("could not count rows of %s: %w", files[i].ToURI(), errs[i])
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// datedFile is a file found while searching for the newest files in a directory
+type datedFile struct {
+	key     string
+	modTime time.Time
+}
+
+// newestDatedFiles sorts files newest first and returns up to n of them. Files
+// modified at the same time are ordered by key so the result is deterministic.
+func newestDatedFiles(files []datedFile, n int) []datedFile {
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].modTime.Equal(files[j].modTime) {
+			return files[i].modTime.After(files[j].modTime)
+		}
+		return files[i].key > files[j].key
+	})
+	if len(files) > n {
+		files = files[:n]
+	}
+	return files
+}
+
 type Iterator interface {
 	Next() (map[string]interface{}, error)
 	FeatureColumns() []string
@@ -59,7 +236,11 @@ type Iterator interface {
 
 type LocalFileStore struct {
 	DirPath string
+	TempDir string
 	genericFileStore
+
+	stagingMtx  sync.Mutex
+	stagingDirs []string
 }
 
 func NewLocalFileStore(config Config) (FileStore, error) {
@@ -79,12 +260,18 @@ func NewLocalFileStore(config Config) (FileStore, error) {
 	if err != nil {
 		return nil, err
 	}
+	encrypter, err := newEnvelopeEncrypter(fileStoreConfig.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
 	return &LocalFileStore{
 		DirPath: fileStoreConfig.DirPath[len("file:///"):],
+		TempDir: fileStoreConfig.TempDir,
 		genericFileStore: genericFileStore{
 			bucket:    bucket,
 			path:      filepath,
 			storeType: filestore.FileSystem,
+			encrypter: encrypter,
 		},
 	}, nil
 }
@@ -123,6 +310,34 @@ func (fs *LocalFileStore) CreateDirPath(key string) (filestore.Filepath, error)
 	return fp, nil
 }
 
+// StagingDir creates a new directory under TempDir for staging files before
+// they're uploaded or after they're downloaded. Each call returns a different
+// directory, so concurrent callers can't collide, and all of them are removed
+// when the store is closed.
+func (fs *LocalFileStore) StagingDir() (string, error) {
+	dir, err := os.MkdirTemp(fs.TempDir, "featureform-staging-")
+	if err != nil {
+		return "", fmt.Errorf("could not create staging directory: %w", err)
+	}
+	fs.stagingMtx.Lock()
+	fs.stagingDirs = append(fs.stagingDirs, dir)
+	fs.stagingMtx.Unlock()
+	return dir, nil
+}
+
+func (fs *LocalFileStore) Close() error {
+	fs.stagingMtx.Lock()
+	dirs := fs.stagingDirs
+	fs.stagingDirs = nil
+	fs.stagingMtx.Unlock()
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("could not remove staging directory %s: %w", dir, err)
+		}
+	}
+	return fs.genericFileStore.Close()
+}
+
 type AzureFileStore struct {
 	AccountName      string
 	AccountKey       string
@@ -213,6 +428,10 @@ func NewAzureFileStore(config Config) (FileStore, error) {
 		return nil, fmt.Errorf("could not open azure bucket: %v", err)
 	}
 	connectionString := fmt.Sprintf("DefaultEndpointsProtocol=https;AccountName=%s;AccountKey=%s", azureStoreConfig.AccountName, azureStoreConfig.AccountKey)
+	encrypter, err := newEnvelopeEncrypter(azureStoreConfig.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
 	return &AzureFileStore{
 		AccountName:      azureStoreConfig.AccountName,
 		AccountKey:       azureStoreConfig.AccountKey,
@@ -222,6 +441,8 @@ func NewAzureFileStore(config Config) (FileStore, error) {
 		genericFileStore: genericFileStore{
 			bucket:    bucket,
 			storeType: filestore.Azure,
+			encrypter: encrypter,
+			multipart: azureMultipartUploader(client, azureStoreConfig.ContainerName),
 		},
 	}, nil
 }
@@ -258,6 +479,10 @@ func NewS3FileStore(config Config) (FileStore, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not create connection to s3 bucket: config: %v, name: %s, %v", s3StoreConfig, s3StoreConfig.BucketPath, err)
 	}
+	encrypter, err := newEnvelopeEncrypter(s3StoreConfig.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
 	return &S3FileStore{
 		Bucket:       s3StoreConfig.BucketPath,
 		BucketRegion: s3StoreConfig.BucketRegion,
@@ -266,6 +491,8 @@ func NewS3FileStore(config Config) (FileStore, error) {
 		genericFileStore: genericFileStore{
 			bucket:    bucket,
 			storeType: filestore.S3,
+			encrypter: encrypter,
+			multipart: s3MultipartUploader(clientV2, s3StoreConfig.BucketPath),
 		},
 	}, nil
 }
@@ -320,7 +547,7 @@ func (s3 *S3FileStore) AddEnvVars(envVars map[string]string) map[string]string {
 }
 
 func (s3 *S3FileStore) Read(path filestore.Filepath) ([]byte, error) {
-	data, err := s3.bucket.ReadAll(context.TODO(), path.Key())
+	data, err := s3.readAll(path)
 	if err != nil {
 		return nil, err
 	}
@@ -431,6 +658,10 @@ func NewGCSFileStore(config Config) (FileStore, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not open bucket: %v", err)
 	}
+	encrypter, err := newEnvelopeEncrypter(GCSConfig.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
 	return &GCSFileStore{
 		Bucket:      GCSConfig.BucketName,
 		Path:        GCSConfig.BucketPath,
@@ -438,6 +669,7 @@ func NewGCSFileStore(config Config) (FileStore, error) {
 		genericFileStore: genericFileStore{
 			bucket:    bucket,
 			storeType: filestore.GCS,
+			encrypter: encrypter,
 		},
 	}, nil
 }
@@ -452,9 +684,10 @@ func NewHDFSFileStore(config Config) (FileStore, error) {
 
 	address := fmt.Sprintf("%s:%s", HDFSConfig.Host, HDFSConfig.Port)
 	var username string
-	if HDFSConfig.Username == "" {
+	if HDFSConfig.Username == "" && HDFSConfig.Kerberos == nil {
 		username = "hduser"
 	} else {
+		// Kerberized clients act as their principal unless a user is given
 		username = HDFSConfig.Username
 	}
 
@@ -463,8 +696,18 @@ func NewHDFSFileStore(config Config) (FileStore, error) {
 		User:                username,
 		UseDatanodeHostname: true,
 	}
+	if HDFSConfig.Kerberos != nil {
+		krbClient, err := newKerberosClient(*HDFSConfig.Kerberos)
+		if err != nil {
+			return nil, err
+		}
+		ops.KerberosClient = krbClient
+		ops.KerberosServicePrincipleName = HDFSConfig.Kerberos.NamenodeSPN
+	}
 	client, err := hdfs.NewClient(ops)
-	if err != nil {
+	if err != nil && HDFSConfig.Kerberos != nil && isHDFSAuthError(err) {
+		return nil, &HDFSAuthError{Err: fmt.Errorf("namenode %s rejected kerberos credentials: %w", address, err)}
+	} else if err != nil {
 		return nil, fmt.Errorf("could not create hdfs client: %v", err)
 	}
 
@@ -475,6 +718,64 @@ func NewHDFSFileStore(config Config) (FileStore, error) {
 	}, nil
 }
 
+// HDFSAuthError is returned when an HDFS file store can't authenticate with
+// Kerberos, as opposed to when it can't reach the cluster at all.
+type HDFSAuthError struct {
+	Err error
+}
+
+func (e *HDFSAuthError) Error() string {
+	return fmt.Sprintf("hdfs kerberos authentication failed: %v", e.Err)
+}
+
+func (e *HDFSAuthError) Unwrap() error {
+	return e.Err
+}
+
+// newKerberosClient logs in to the KDC, so that bad credentials fail before
+// connecting to the namenode.
+func newKerberosClient(config pc.HDFSKerberosConfig) (*krb.Client, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid kerberos config: %w", err)
+	}
+	confPath := config.Krb5ConfPath
+	if confPath == "" {
+		confPath = "/etc/krb5.conf"
+	}
+	krbConf, err := krbconfig.Load(confPath)
+	if err != nil {
+		return nil, &HDFSAuthError{Err: fmt.Errorf("load %s: %w", confPath, err)}
+	}
+	var client *krb.Client
+	if config.KeytabPath != "" {
+		kt, err := keytab.Load(config.KeytabPath)
+		if err != nil {
+			return nil, &HDFSAuthError{Err: fmt.Errorf("load keytab %s: %w", config.KeytabPath, err)}
+		}
+		client = krb.NewWithKeytab(config.Principal, config.Realm, kt, krbConf, krb.DisablePAFXFAST(true))
+	} else {
+		ccache, err := krbcreds.LoadCCache(config.CCachePath)
+		if err != nil {
+			return nil, &HDFSAuthError{Err: fmt.Errorf("load credentials cache %s: %w", config.CCachePath, err)}
+		}
+		client, err = krb.NewFromCCache(ccache, krbConf, krb.DisablePAFXFAST(true))
+		if err != nil {
+			return nil, &HDFSAuthError{Err: fmt.Errorf("read credentials cache %s: %w", config.CCachePath, err)}
+		}
+	}
+	if err := client.Login(); err != nil {
+		return nil, &HDFSAuthError{Err: fmt.Errorf("login as %s: %w", config.Principal, err)}
+	}
+	return client, nil
+}
+
+// isHDFSAuthError reports whether the namenode refused a connection during its
+// SASL handshake, rather than not being reachable.
+func isHDFSAuthError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "SASL handshake") || strings.Contains(msg, "kerberos")
+}
+
 type HDFSFileStore struct {
 	Client *hdfs.Client
 	Host   string
@@ -550,8 +851,7 @@ func (fs *HDFSFileStore) Read(path filestore.Filepath) ([]byte, error) {
 }
 
 func (fs *HDFSFileStore) ServeDirectory(files []filestore.Filepath) (Iterator, error) {
-	// assume file type is parquet
-	return parquetIteratorOverMultipleFiles(files, fs)
+	return directoryIterator(files, fs)
 }
 
 func (fs *HDFSFileStore) Serve(files []filestore.Filepath) (Iterator, error) {
@@ -566,14 +866,7 @@ func (fs *HDFSFileStore) Serve(files []filestore.Filepath) (Iterator, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not read file: %w", err)
 	}
-	switch file.Ext() {
-	case filestore.Parquet:
-		return parquetIteratorFromBytes(b)
-	case filestore.CSV:
-		return nil, fmt.Errorf("could not find CSV reader")
-	default:
-		return nil, fmt.Errorf("unsupported file type")
-	}
+	return fileIteratorFromBytes(file.Ext(), b)
 }
 
 func (fs *HDFSFileStore) Exists(path filestore.Filepath) (bool, error) {
@@ -630,20 +923,40 @@ func (fs *HDFSFileStore) containsPrefix(prefix, path string) bool {
 	return strings.Contains(path, prefix)
 }
 
-func (fs *HDFSFileStore) isMoreRecentFile(newFileTime, oldFileTime time.Time, fileType filestore.FileType, path string) bool {
-	return (newFileTime.After(oldFileTime) || newFileTime.Equal(oldFileTime)) && fileType.Matches(path)
+// isNewerFile reports whether a file replaces the newest one found so far. Files
+// modified at the same time are ordered by key, as in newestDatedFiles, so the
+// newest file doesn't depend on the order they're listed in.
+func isNewerFile(modTime time.Time, key string, newestTime time.Time, newestKey string) bool {
+	if !modTime.Equal(newestTime) {
+		return modTime.After(newestTime)
+	}
+	return key > newestKey
+}
+
+func matchesFileType(path string, fileTypes []filestore.FileType) bool {
+	for _, fileType := range fileTypes {
+		if fileType.Matches(path) {
+			return true
+		}
+	}
+	return false
 }
 
 func (hdfs *HDFSFileStore) NewestFileOfType(rootpath filestore.Filepath, fileType filestore.FileType) (filestore.Filepath, error) {
+	return hdfs.NewestFileOfTypes(rootpath, fileType)
+}
+
+func (hdfs *HDFSFileStore) NewestFileOfTypes(rootpath filestore.Filepath, fileTypes ...filestore.FileType) (filestore.Filepath, error) {
 	var lastModTime time.Time
 	var lastModName string
 	err := hdfs.Client.Walk("/", func(path string, info fs.FileInfo, err error) error {
 		if hdfs.isPartialPath(rootpath.Key(), path) {
 			return nil
 		}
-		if hdfs.containsPrefix(rootpath.Key(), path) && hdfs.isMoreRecentFile(info.ModTime(), lastModTime, fileType, path) {
+		name := strings.TrimPrefix(path, "/")
+		if hdfs.containsPrefix(rootpath.Key(), path) && matchesFileType(path, fileTypes) && isNewerFile(info.ModTime(), name, lastModTime, lastModName) {
 			lastModTime = info.ModTime()
-			lastModName = strings.TrimPrefix(path, "/")
+			lastModName = name
 		}
 		return nil
 	})
@@ -662,6 +975,32 @@ func (hdfs *HDFSFileStore) NewestFileOfType(rootpath filestore.Filepath, fileTyp
 	return filepath, nil
 }
 
+func (hdfs *HDFSFileStore) NewestNFilesOfType(rootpath filestore.Filepath, fileType filestore.FileType, n int) ([]filestore.Filepath, error) {
+	files := make([]datedFile, 0)
+	err := hdfs.Client.Walk("/", func(path string, info fs.FileInfo, err error) error {
+		if hdfs.isPartialPath(rootpath.Key(), path) {
+			return nil
+		}
+		if hdfs.containsPrefix(rootpath.Key(), path) && !info.IsDir() && fileType.Matches(path) {
+			files = append(files, datedFile{key: strings.TrimPrefix(path, "/"), modTime: info.ModTime()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	newest := newestDatedFiles(files, n)
+	paths := make([]filestore.Filepath, len(newest))
+	for i, file := range newest {
+		filepath, err := hdfs.CreateFilePath(file.key)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = filepath
+	}
+	return paths, nil
+}
+
 func (fs *HDFSFileStore) List(dirPath filestore.Filepath, fileType filestore.FileType) ([]filestore.Filepath, error) {
 	return nil, fmt.Errorf("not implemented")
 }
@@ -681,10 +1020,38 @@ func (fs *HDFSFileStore) Close() error {
 	return fs.Client.Close()
 }
 func (fs *HDFSFileStore) Upload(sourcePath filestore.Filepath, destPath filestore.Filepath) error {
-	return fs.Client.CopyToRemote(sourcePath.Key(), destPath.Key())
+	return fs.UploadWithProgress(sourcePath, destPath, nil)
 }
 func (fs *HDFSFileStore) Download(sourcePath filestore.Filepath, destPath filestore.Filepath) error {
-	return fs.Client.CopyToLocal(sourcePath.Key(), destPath.Key())
+	return fs.DownloadWithProgress(sourcePath, destPath, nil)
+}
+
+// UploadWithProgress reports only the start and end of the copy, which the
+// HDFS client makes in one call
+func (fs *HDFSFileStore) UploadWithProgress(sourcePath filestore.Filepath, destPath filestore.Filepath, progress TransferProgress) error {
+	info, err := os.Stat(sourcePath.Key())
+	if err != nil {
+		return fmt.Errorf("cannot read %s file: %v", sourcePath, err)
+	}
+	progress.report(0, info.Size())
+	if err := fs.Client.CopyToRemote(sourcePath.Key(), destPath.Key()); err != nil {
+		return err
+	}
+	progress.report(info.Size(), info.Size())
+	return nil
+}
+
+func (fs *HDFSFileStore) DownloadWithProgress(sourcePath filestore.Filepath, destPath filestore.Filepath, progress TransferProgress) error {
+	info, err := fs.Client.Stat(sourcePath.Key())
+	if err != nil {
+		return fmt.Errorf("cannot read %s file: %v", sourcePath, err)
+	}
+	progress.report(0, info.Size())
+	if err := fs.Client.CopyToLocal(sourcePath.Key(), destPath.Key()); err != nil {
+		return err
+	}
+	progress.report(info.Size(), info.Size())
+	return nil
 }
 func (fs *HDFSFileStore) AsAzureStore() *AzureFileStore {
 	return nil
@@ -732,54 +1099,129 @@ type genericFileStore struct {
 	bucket    *blob.Bucket
 	path      filestore.Filepath
 	storeType filestore.FileStoreType
+	// Encrypts files as they're written and decrypts them as they're read. Nil
+	// if files are stored as is.
+	encrypter *envelopeEncrypter
+	// Files larger than this many bytes are uploaded in parts of this size.
+	// Defaults to config.GetUploadPartSize.
+	partSize int64
+	// Starts uploads that send each part in a request of its own, so that a
+	// failed part can be sent again by itself. Nil if the bucket's writer
+	// streams the parts instead.
+	multipart multipartUploader
 }
 
 // TODO: deprecate this in favor of List
 func (store *genericFileStore) NewestFileOfType(searchPath filestore.Filepath, fileType filestore.FileType) (filestore.Filepath, error) {
+	return store.NewestFileOfTypes(searchPath, fileType)
+}
+
+func (store *genericFileStore) NewestFileOfTypes(searchPath filestore.Filepath, fileTypes ...filestore.FileType) (filestore.Filepath, error) {
+	mostRecentKey, err := store.newestKeyOfTypes(context.TODO(), store.bucket, searchPath.Key(), fileTypes)
+	if err != nil {
+		return nil, err
+	}
+	path, err := filestore.NewEmptyFilepath(store.FilestoreType())
+	if err != nil {
+		return nil, err
+	}
+	// Prior to adding this guard clause, the call to path.ParseFilePath would fail
+	// with the following error if mostRecentKey is empty:
+	// invalid scheme '://', must be one of [gs:// s3:// s3a:// abfss:// hdfs://]
+	if mostRecentKey == "" {
+		return path, nil
+	}
+	// **NOTE:** this is a hack to address the fact that genericFileStore is ignorant of the scheme, bucket, etc.
+	// which means we're forced to use everything up to the path from the searchPath and replace its key with
+	// the latest key found at the prefix. The long-term fix could/should be to implement all Filepath methods on
+	// each implementation and call into the genericFileStore with additional parameters for the scheme, bucket, etc.
+	err = path.ParseFilePath(searchPath.ToURI())
+	if err != nil {
+		return nil, err
+	}
+	if err := path.SetKey(mostRecentKey); err != nil {
+		return nil, err
+	}
+	// TODO: consider reevaluating whether a path is a directory or file path when setting the key
+	path.SetIsDir(false)
+	return path, nil
+}
+
+// Number of objects requested at a time when paging through a listing
+const listPageSize = 1000
+
+// objectLister lists a bucket's objects a page at a time. It's implemented by
+// *blob.Bucket, which pages through S3, GCS and Azure listings with their
+// continuation tokens.
+type objectLister interface {
+	ListPage(ctx context.Context, pageToken []byte, pageSize int, opts *blob.ListOptions) ([]*blob.ListObject, []byte, error)
+}
+
+// newestKeyOfTypes returns the key of the most recently modified file under
+// prefix with one of fileTypes, or "" if there isn't one. It pages through the
+// whole listing, but only holds one page of objects in memory at a time.
+func (store *genericFileStore) newestKeyOfTypes(ctx context.Context, lister objectLister, prefix string, fileTypes []filestore.FileType) (string, error) {
 	opts := blob.ListOptions{
-		Prefix: searchPath.Key(),
+		Prefix: prefix,
 	}
-	listIterator := store.bucket.List(&opts)
 	mostRecentTime := time.UnixMilli(0)
 	mostRecentKey := ""
+	pageToken := blob.FirstPageToken
 	for {
-		if newObj, err := listIterator.Next(context.TODO()); err == nil {
-			mostRecentTime, mostRecentKey = store.getMoreRecentFile(newObj, fileType, mostRecentTime, mostRecentKey)
-		} else if err == io.EOF {
-			path, err := filestore.NewEmptyFilepath(store.FilestoreType())
-			if err != nil {
-				return nil, err
-			}
-			// Prior to adding this guard clause, the call to path.ParseFilePath would fail
-			// with the following error if mostRecentKey is empty:
-			// invalid scheme '://', must be one of [gs:// s3:// s3a:// abfss:// hdfs://]
-			if mostRecentKey == "" {
-				return path, nil
-			}
-			// **NOTE:** this is a hack to address the fact that genericFileStore is ignorant of the scheme, bucket, etc.
-			// which means we're forced to use everything up to the path from the searchPath and replace its key with
-			// the latest key found at the prefix. The long-term fix could/should be to implement all Filepath methods on
-			// each implementation and call into the genericFileStore with additional parameters for the scheme, bucket, etc.
-			err = path.ParseFilePath(searchPath.ToURI())
-			if err != nil {
-				return nil, err
-			}
-			if err := path.SetKey(mostRecentKey); err != nil {
-				return nil, err
-			}
-			// TODO: consider reevaluating whether a path is a directory or file path when setting the key
-			path.SetIsDir(false)
-			return path, nil
-		} else {
+		objs, nextPageToken, err := lister.ListPage(ctx, pageToken, listPageSize, &opts)
+		if err != nil {
+			return "", fmt.Errorf("could not list objects under %s: %w", prefix, err)
+		}
+		for _, obj := range objs {
+			mostRecentTime, mostRecentKey = store.getMoreRecentFile(obj, fileTypes, mostRecentTime, mostRecentKey)
+		}
+		if len(nextPageToken) == 0 {
+			return mostRecentKey, nil
+		}
+		pageToken = nextPageToken
+	}
+}
+
+func (store *genericFileStore) NewestNFilesOfType(searchPath filestore.Filepath, fileType filestore.FileType, n int) ([]filestore.Filepath, error) {
+	opts := blob.ListOptions{
+		Prefix: searchPath.Key(),
+	}
+	listIterator := store.bucket.List(&opts)
+	files := make([]datedFile, 0)
+	for {
+		obj, err := listIterator.Next(context.TODO())
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
 			return nil, err
 		}
+		if !obj.IsDir && fileType.Matches(obj.Key) {
+			files = append(files, datedFile{key: obj.Key, modTime: obj.ModTime})
+		}
 	}
+	newest := newestDatedFiles(files, n)
+	paths := make([]filestore.Filepath, len(newest))
+	for i, file := range newest {
+		// Same as in NewestFileOfTypes, the scheme and bucket come from searchPath
+		path, err := filestore.NewEmptyFilepath(store.FilestoreType())
+		if err != nil {
+			return nil, err
+		}
+		if err := path.ParseFilePath(searchPath.ToURI()); err != nil {
+			return nil, err
+		}
+		if err := path.SetKey(file.key); err != nil {
+			return nil, err
+		}
+		path.SetIsDir(false)
+		paths[i] = path
+	}
+	return paths, nil
 }
 
-func (store *genericFileStore) getMoreRecentFile(newObj *blob.ListObject, expectedFileType filestore.FileType, oldTime time.Time, oldKey string) (time.Time, string) {
-	pathParts := strings.Split(newObj.Key, ".")
-	fileType := pathParts[len(pathParts)-1]
-	if fileType == string(expectedFileType) && !newObj.IsDir && store.isMostRecentFile(newObj, oldTime) {
+func (store *genericFileStore) getMoreRecentFile(newObj *blob.ListObject, expectedFileTypes []filestore.FileType, oldTime time.Time, oldKey string) (time.Time, string) {
+	if matchesFileType(newObj.Key, expectedFileTypes) && !newObj.IsDir && isNewerFile(newObj.ModTime, newObj.Key, oldTime, oldKey) {
 		return newObj.ModTime, newObj.Key
 	}
 	return oldTime, oldKey
@@ -835,13 +1277,15 @@ func (store *genericFileStore) List(searchPath filestore.Filepath, fileType file
 	return files, iterError
 }
 
-func (store *genericFileStore) isMostRecentFile(listObj *blob.ListObject, time time.Time) bool {
-	return listObj.ModTime.After(time) || listObj.ModTime.Equal(time)
-}
-
 func (store *genericFileStore) DeleteAll(path filestore.Filepath) error {
+	prefix := path.Key()
+	// Only match keys inside the directory, not its siblings that share a
+	// prefix with it, such as another variant whose name starts with this one's
+	if path.IsDir() {
+		prefix += "/"
+	}
 	opts := blob.ListOptions{
-		Prefix: path.Key(),
+		Prefix: prefix,
 	}
 	listIterator := store.bucket.List(&opts)
 	for listObj, err := listIterator.Next(context.TODO()); err == nil; listObj, err = listIterator.Next(context.TODO()) {
@@ -856,6 +1300,13 @@ func (store *genericFileStore) DeleteAll(path filestore.Filepath) error {
 
 func (store *genericFileStore) Write(path filestore.Filepath, data []byte) error {
 	ctx := context.TODO()
+	if store.encrypter != nil {
+		encrypted, err := store.encrypter.Encrypt(data)
+		if err != nil {
+			return fmt.Errorf("could not encrypt %s: %w", path.Key(), err)
+		}
+		data = encrypted
+	}
 	err := store.bucket.WriteAll(ctx, path.Key(), data, nil)
 	if err != nil {
 		return err
@@ -880,7 +1331,7 @@ func (store *genericFileStore) Write(path filestore.Filepath, data []byte) error
 }
 
 func (store *genericFileStore) Read(path filestore.Filepath) ([]byte, error) {
-	data, err := store.bucket.ReadAll(context.TODO(), path.Key())
+	data, err := store.readAll(path)
 	if err != nil {
 		return nil, err
 	}
@@ -888,29 +1339,183 @@ func (store *genericFileStore) Read(path filestore.Filepath) ([]byte, error) {
 	return data, nil
 }
 
+// readAll reads a whole file, decrypting it if the store encrypts files
+func (store *genericFileStore) readAll(path filestore.Filepath) ([]byte, error) {
+	data, err := store.bucket.ReadAll(context.TODO(), path.Key())
+	if err != nil {
+		return nil, err
+	}
+	if store.encrypter == nil {
+		return data, nil
+	}
+	decrypted, err := store.encrypter.Decrypt(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt %s: %w", path.Key(), err)
+	}
+	return decrypted, nil
+}
+
+// openReaderAt opens a file to be read in ranges. Encrypted files can only be
+// decrypted whole, so they're read into memory.
+func (store *genericFileStore) openReaderAt(path filestore.Filepath) (io.ReaderAt, int64, error) {
+	if store.encrypter != nil {
+		data, err := store.readAll(path)
+		if err != nil {
+			return nil, 0, err
+		}
+		return bytes.NewReader(data), int64(len(data)), nil
+	}
+	attrs, err := store.bucket.Attributes(context.TODO(), path.Key())
+	if err != nil {
+		return nil, 0, err
+	}
+	return newBlobReaderAt(store.bucket, path.Key(), attrs.Size), attrs.Size, nil
+}
+
 func (store *genericFileStore) ServeDirectory(files []filestore.Filepath) (Iterator, error) {
-	// assume file type is parquet
-	return parquetIteratorOverMultipleFiles(files, store)
+	return directoryIterator(files, store)
 }
 
+// Upload copies a local file to the store. Files larger than the store's part
+// size are sent in parts, since blob stores such as Azure reject single
+// requests past a size. Stores that can upload the parts themselves, such as S3
+// and Azure, attempt each part up to config.GetUploadAttempts times, so a part
+// that fails is sent again without the ones before it. Other stores stream the
+// parts through their bucket's writer, whose client retries each of them.
+// Smaller files, and every file of a store that encrypts them, are written in a
+// single request.
 func (store *genericFileStore) Upload(sourcePath filestore.Filepath, destPath filestore.Filepath) error {
-	content, err := ioutil.ReadFile(sourcePath.Key())
+	return store.UploadWithProgress(sourcePath, destPath, nil)
+}
+
+// UploadWithProgress reports each part of a file uploaded in parts as it's
+// written.
+func (store *genericFileStore) UploadWithProgress(sourcePath filestore.Filepath, destPath filestore.Filepath, progress TransferProgress) error {
+	info, err := os.Stat(sourcePath.Key())
 	if err != nil {
 		return fmt.Errorf("cannot read %s file: %v", sourcePath, err)
 	}
-
-	err = store.Write(destPath, content)
+	partSize := store.partSize
+	if partSize <= 0 {
+		partSize = int64(cfg.GetUploadPartSize())
+	}
+	// Files are encrypted whole, so they can't be streamed
+	if info.Size() <= partSize || store.encrypter != nil {
+		content, err := ioutil.ReadFile(sourcePath.Key())
+		if err != nil {
+			return fmt.Errorf("cannot read %s file: %v", sourcePath, err)
+		}
+		progress.report(0, info.Size())
+		if err := store.Write(destPath, content); err != nil {
+			return fmt.Errorf("cannot upload %s file to %s destination: %v", sourcePath, destPath, err)
+		}
+		progress.report(info.Size(), info.Size())
+		return nil
+	}
+	if store.multipart != nil {
+		err = store.uploadParts(sourcePath.Key(), destPath.Key(), partSize, info.Size(), progress)
+	} else {
+		err = store.streamParts(sourcePath.Key(), destPath.Key(), partSize, info.Size(), progress)
+	}
 	if err != nil {
-		return fmt.Errorf("cannot upload %s file to %s destination: %v", sourcePath, destPath, err)
+		return fmt.Errorf("cannot upload %s file to %s destination in parts: %v", sourcePath, destPath, err)
 	}
+	return nil
+}
 
+// uploadParts sends a local file to key in parts of partSize bytes, attempting
+// each part up to config.GetUploadAttempts times. If a part still fails, the
+// upload is aborted and nothing is written to key.
+func (store *genericFileStore) uploadParts(source, key string, partSize, size int64, progress TransferProgress) error {
+	f, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	ctx := context.TODO()
+	upload, err := store.multipart(ctx, key)
+	if err != nil {
+		return fmt.Errorf("could not start upload: %w", err)
+	}
+	buf := make([]byte, partSize)
+	written := int64(0)
+	for number := 1; written < size; number++ {
+		n, err := io.ReadFull(f, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			upload.Abort(ctx)
+			return fmt.Errorf("could not read part %d: %w", number, err)
+		}
+		part := buf[:n]
+		err = re.Do(
+			func() error {
+				return upload.UploadPart(ctx, number, part)
+			},
+			re.DelayType(func(n uint, err error, config *re.Config) time.Duration {
+				return re.BackOffDelay(n, err, config)
+			}),
+			re.Attempts(uint(cfg.GetUploadAttempts())),
+			re.LastErrorOnly(true),
+		)
+		if err != nil {
+			upload.Abort(ctx)
+			return fmt.Errorf("could not upload part %d: %w", number, err)
+		}
+		written += int64(n)
+		progress.report(written, size)
+	}
+	if err := upload.Complete(ctx); err != nil {
+		upload.Abort(ctx)
+		return fmt.Errorf("could not complete upload: %w", err)
+	}
 	return nil
 }
 
-func (store *genericFileStore) Download(sourcePath filestore.Filepath, destPath filestore.Filepath) error {
-	content, err := store.Read(sourcePath)
+// streamParts streams a local file to key through the bucket's writer, which
+// sends it in parts of partSize bytes. Nothing is written to key unless every
+// part is.
+func (store *genericFileStore) streamParts(source, key string, partSize, size int64, progress TransferProgress) error {
+	f, err := os.Open(source)
 	if err != nil {
-		return fmt.Errorf("cannot read %s file: %v", sourcePath, err)
+		return err
+	}
+	defer f.Close()
+	// Cancelling the writer's context before it's closed aborts the upload
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	w, err := store.bucket.NewWriter(ctx, key, &blob.WriterOptions{BufferSize: int(partSize)})
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(newProgressWriter(w, size, progress), f); err != nil {
+		cancel()
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (store *genericFileStore) Download(sourcePath filestore.Filepath, destPath filestore.Filepath) error {
+	return store.DownloadWithProgress(sourcePath, destPath, nil)
+}
+
+// DownloadWithProgress streams a file to the local file system, reporting each
+// buffer as it's written. Encrypted files are decrypted whole first.
+func (store *genericFileStore) DownloadWithProgress(sourcePath filestore.Filepath, destPath filestore.Filepath, progress TransferProgress) error {
+	var source io.Reader
+	var size int64
+	if store.encrypter != nil {
+		content, err := store.readAll(sourcePath)
+		if err != nil {
+			return fmt.Errorf("cannot read %s file: %v", sourcePath, err)
+		}
+		source, size = bytes.NewReader(content), int64(len(content))
+	} else {
+		reader, err := store.bucket.NewReader(context.TODO(), sourcePath.Key(), nil)
+		if err != nil {
+			return fmt.Errorf("cannot read %s file: %v", sourcePath, err)
+		}
+		defer reader.Close()
+		source, size = reader, reader.Size()
 	}
 
 	f, err := os.Create(destPath.Key())
@@ -919,8 +1524,9 @@ func (store *genericFileStore) Download(sourcePath filestore.Filepath, destPath
 	}
 	defer f.Close()
 
-	f.Write(content)
-
+	if _, err := io.Copy(newProgressWriter(f, size, progress), source); err != nil {
+		return fmt.Errorf("cannot write %s file: %v", destPath, err)
+	}
 	return nil
 }
 
@@ -969,18 +1575,14 @@ func (store *genericFileStore) Close() error {
 }
 
 func (store *genericFileStore) ServeFile(path filestore.Filepath) (Iterator, error) {
-	b, err := store.bucket.ReadAll(context.TODO(), path.Key())
+	if path.Ext() == filestore.Parquet {
+		return openParquetFile(store, path)
+	}
+	b, err := store.readAll(path)
 	if err != nil {
 		return nil, fmt.Errorf("could not read file: %w", err)
 	}
-	switch path.Ext() {
-	case filestore.Parquet:
-		return parquetIteratorFromBytes(b)
-	case filestore.CSV:
-		return nil, fmt.Errorf("csv iterator not implemented")
-	default:
-		return nil, fmt.Errorf("unsupported file type")
-	}
+	return fileIteratorFromBytes(path.Ext(), b)
 }
 
 func (store *genericFileStore) Serve(files []filestore.Filepath) (Iterator, error) {
@@ -991,19 +1593,79 @@ func (store *genericFileStore) Serve(files []filestore.Filepath) (Iterator, erro
 	}
 }
 
+// NumRows reads only the footers of parquet and ORC files, and streams other
+// files rather than holding them in memory. Encrypted files are decrypted whole.
 func (store *genericFileStore) NumRows(path filestore.Filepath) (int64, error) {
-	b, err := store.bucket.ReadAll(context.TODO(), path.Key())
+	if store.encrypter != nil {
+		b, err := store.readAll(path)
+		if err != nil {
+			return 0, err
+		}
+		return numRowsFromBytes(path.Ext(), b)
+	}
+	switch path.Ext() {
+	case filestore.Parquet:
+		reader, size, err := store.openReaderAt(path)
+		if err != nil {
+			return 0, err
+		}
+		return getParquetNumRowsAt(reader, size), nil
+	case filestore.ORC:
+		reader, size, err := store.openReaderAt(path)
+		if err != nil {
+			return 0, err
+		}
+		tail, err := readORCTailAt(reader, size)
+		if err != nil {
+			return 0, err
+		}
+		return int64(tail.numRows), nil
+	}
+	reader, err := store.bucket.NewReader(context.TODO(), path.Key(), nil)
 	if err != nil {
 		return 0, err
 	}
-	switch path.Ext() {
+	defer reader.Close()
+	return numRowsFromReader(path.Ext(), reader)
+}
+
+// numRowsFromBytes counts the rows of a whole file of the given type
+func numRowsFromBytes(fileType filestore.FileType, b []byte) (int64, error) {
+	switch fileType {
 	case filestore.Parquet:
 		return getParquetNumRows(b)
+	case filestore.Avro:
+		return getAvroNumRows(b)
+	case filestore.ORC:
+		return getORCNumRows(b)
+	case filestore.CSV:
+		return getCSVNumRows(b)
+	case filestore.JSONL:
+		return getJSONLNumRows(b)
 	default:
 		return 0, fmt.Errorf("unsupported file type")
 	}
 }
 
+// numRowsFromReader counts the rows of a file of the given type as it's
+// streamed. Parquet and ORC files need to be read from their ends.
+func numRowsFromReader(fileType filestore.FileType, r io.Reader) (int64, error) {
+	switch fileType {
+	case filestore.Avro:
+		return countAvroRows(r)
+	case filestore.CSV:
+		return countCSVRows(r)
+	case filestore.JSONL:
+		return countJSONLRows(r)
+	default:
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return 0, err
+		}
+		return numRowsFromBytes(fileType, b)
+	}
+}
+
 func (store *genericFileStore) CreateDirPath(key string) (filestore.Filepath, error) {
 	fp, err := store.CreateFilePath(key)
 	if err != nil {
@@ -0,0 +1,861 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	cfg "github.com/featureform/config"
+	"github.com/featureform/filestore"
+	pc "github.com/featureform/provider/provider_config"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+)
+
+// writePartFiles writes numFiles parquet files of rowsPerFile rows each to a
+// local file store, and returns the store and the files' paths.
+func writePartFiles(tb testing.TB, numFiles, rowsPerFile int) (FileStore, []filestore.Filepath) {
+	config := pc.LocalFileStoreConfig{DirPath: fmt.Sprintf("file:///%s", tb.TempDir())}
+	serialized, err := config.Serialize()
+	if err != nil {
+		tb.Fatalf("could not serialize file store config: %v", err)
+	}
+	store, err := NewLocalFileStore(serialized)
+	if err != nil {
+		tb.Fatalf("could not create local file store: %v", err)
+	}
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+		},
+	}
+	files := make([]filestore.Filepath, numFiles)
+	for i := range files {
+		records := make([]GenericRecord, rowsPerFile+i)
+		for j := range records {
+			records[j] = GenericRecord{fmt.Sprintf("entity_%d_%d", i, j), j}
+		}
+		content, err := schema.ToParquetBytes(records, ParquetWriteConfig{})
+		if err != nil {
+			tb.Fatalf("could not write parquet file: %v", err)
+		}
+		path, err := store.CreateFilePath(fmt.Sprintf("parts/part-%04d.parquet", i))
+		if err != nil {
+			tb.Fatalf("could not create file path: %v", err)
+		}
+		if err := store.Write(path, content); err != nil {
+			tb.Fatalf("could not write part file: %v", err)
+		}
+		files[i] = path
+	}
+	return store, files
+}
+
+func TestNumRowsOfFiles(t *testing.T) {
+	store, files := writePartFiles(t, 50, 10)
+	defer store.Close()
+	var sequential int64
+	for _, file := range files {
+		rows, err := store.NumRows(file)
+		if err != nil {
+			t.Fatalf("could not count rows of %s: %v", file.Key(), err)
+		}
+		sequential += rows
+	}
+	for _, parallelism := range []int{0, 1, 4, 100} {
+		total, err := NumRowsOfFiles(store, files, parallelism)
+		if err != nil {
+			t.Fatalf("parallelism %d: could not count rows: %v", parallelism, err)
+		}
+		if total != sequential {
+			t.Fatalf("parallelism %d: expected %d rows, got %d", parallelism, sequential, total)
+		}
+	}
+
+	missing, err := store.CreateFilePath("parts/missing.parquet")
+	if err != nil {
+		t.Fatalf("could not create file path: %v", err)
+	}
+	if _, err := NumRowsOfFiles(store, append(files, missing), 4); err == nil {
+		t.Fatalf("expected counting a missing file to fail")
+	}
+}
+
+func TestNumRowsOfFileTypes(t *testing.T) {
+	config := pc.LocalFileStoreConfig{DirPath: fmt.Sprintf("file:///%s", t.TempDir())}
+	serialized, err := config.Serialize()
+	if err != nil {
+		t.Fatalf("could not serialize file store config: %v", err)
+	}
+	store, err := NewLocalFileStore(serialized)
+	if err != nil {
+		t.Fatalf("could not create local file store: %v", err)
+	}
+	defer store.Close()
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+		},
+	}
+	numRows := 25
+	records := make([]GenericRecord, numRows)
+	jsonl := bytes.Buffer{}
+	for i := range records {
+		records[i] = GenericRecord{fmt.Sprintf("entity_%d", i), i}
+		fmt.Fprintf(&jsonl, "{\"entity\": \"entity_%d\", \"value\": %d}\n", i, i)
+	}
+	orc, err := convertToORCBytes(schema, records, orcCompressionZlib)
+	if err != nil {
+		t.Fatalf("could not write orc file: %v", err)
+	}
+	avro, err := convertToAvroBytes(schema, records)
+	if err != nil {
+		t.Fatalf("could not write avro file: %v", err)
+	}
+	csv, err := schema.ToCSVBytes(records, CSVConfig{})
+	if err != nil {
+		t.Fatalf("could not write csv file: %v", err)
+	}
+	files := map[string][]byte{
+		"part.orc":   orc,
+		"part.avro":  avro,
+		"part.csv":   csv,
+		"part.jsonl": jsonl.Bytes(),
+	}
+	for name, content := range files {
+		path, err := store.CreateFilePath(fmt.Sprintf("types/%s", name))
+		if err != nil {
+			t.Fatalf("could not create file path: %v", err)
+		}
+		if err := store.Write(path, content); err != nil {
+			t.Fatalf("could not write %s: %v", name, err)
+		}
+		rows, err := store.NumRows(path)
+		if err != nil {
+			t.Fatalf("could not count rows of %s: %v", name, err)
+		}
+		if rows != int64(numRows) {
+			t.Fatalf("expected %d rows in %s, got %d", numRows, name, rows)
+		}
+	}
+}
+
+func BenchmarkNumRowsOfFiles(b *testing.B) {
+	store, files := writePartFiles(b, 200, 1000)
+	defer store.Close()
+	for _, parallelism := range []int{1, cfg.NumRowsParallelism} {
+		b.Run(fmt.Sprintf("parallelism_%d", parallelism), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := NumRowsOfFiles(store, files, parallelism); err != nil {
+					b.Fatalf("could not count rows: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestServeMapped(t *testing.T) {
+	store, files := writePartFiles(t, 3, 5)
+	defer store.Close()
+	rename := func(row map[string]interface{}) (map[string]interface{}, error) {
+		row["id"] = row["entity"]
+		delete(row, "entity")
+		return row, nil
+	}
+	iter, err := ServeMapped(store, files, rename)
+	if err != nil {
+		t.Fatalf("could not serve files: %v", err)
+	}
+	rows := 0
+	for {
+		row, err := iter.Next()
+		if err != nil {
+			t.Fatalf("could not read row: %v", err)
+		}
+		if row == nil {
+			break
+		}
+		if _, has := row["entity"]; has {
+			t.Fatalf("expected entity column to be renamed, got %v", row)
+		}
+		if id, ok := row["id"].(string); !ok || id == "" {
+			t.Fatalf("expected renamed id column, got %v", row)
+		}
+		rows++
+	}
+	// writePartFiles writes one more row to each file than the last
+	if expected := 5 + 6 + 7; rows != expected {
+		t.Fatalf("expected %d rows, got %d", expected, rows)
+	}
+
+	failing := func(row map[string]interface{}) (map[string]interface{}, error) {
+		return nil, fmt.Errorf("bad row")
+	}
+	iter, err = ServeMapped(store, files, failing)
+	if err != nil {
+		t.Fatalf("could not serve files: %v", err)
+	}
+	if _, err := iter.Next(); err == nil {
+		t.Fatalf("expected mapper error to stop iteration")
+	}
+	if _, err := iter.Next(); err == nil {
+		t.Fatalf("expected iteration to stay stopped after a mapper error")
+	}
+}
+
+func TestServeCSV(t *testing.T) {
+	store, parquetFiles := writePartFiles(t, 1, 1)
+	defer store.Close()
+	ts := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	contents := []string{
+		fmt.Sprintf("entity\tvalue\tscore\tactive\tts\na\t1\t0.5\ttrue\t%s\n", ts.Format(time.RFC3339)),
+		"entity\tvalue\tscore\tactive\tts\nb\t2\t1.5\tFALSE\tnot a time\n",
+	}
+	files := make([]filestore.Filepath, len(contents))
+	for i, content := range contents {
+		path, err := store.CreateFilePath(fmt.Sprintf("csv/part-%d.csv", i))
+		if err != nil {
+			t.Fatalf("could not create file path: %v", err)
+		}
+		if err := store.Write(path, []byte(content)); err != nil {
+			t.Fatalf("could not write csv file: %v", err)
+		}
+		files[i] = path
+	}
+	iter, err := ServeCSV(store, files, TableSchema{}, CSVConfig{Delimiter: '\t'})
+	if err != nil {
+		t.Fatalf("could not serve csv files: %v", err)
+	}
+	expected := []map[string]interface{}{
+		{"entity": "a", "value": 1, "score": 0.5, "active": true, "ts": ts},
+		{"entity": "b", "value": 2, "score": 1.5, "active": false, "ts": "not a time"},
+	}
+	for i, exp := range expected {
+		row, err := iter.Next()
+		if err != nil {
+			t.Fatalf("could not read row %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(row, exp) {
+			t.Fatalf("row %d: expected %v, got %v", i, exp, row)
+		}
+	}
+	if row, err := iter.Next(); row != nil || err != nil {
+		t.Fatalf("expected end of rows, got %v %v", row, err)
+	}
+
+	if _, err := ServeCSV(store, append(files, parquetFiles...), TableSchema{}, CSVConfig{}); err == nil || !strings.Contains(err.Error(), parquetFiles[0].Key()) {
+		t.Fatalf("expected error naming the parquet file, got %v", err)
+	}
+
+	// Timestamp columns of the schema are read with the configured layout
+	layout := "02/01/2006 15:04"
+	path, err := store.CreateFilePath("csv/layout.csv")
+	if err != nil {
+		t.Fatalf("could not create file path: %v", err)
+	}
+	if err := store.Write(path, []byte("entity,ts\na,02/01/2023 03:04\n")); err != nil {
+		t.Fatalf("could not write csv file: %v", err)
+	}
+	schema := TableSchema{Columns: []TableColumn{{Name: "entity", ValueType: String}, {Name: "ts", ValueType: Timestamp}}}
+	iter, err = ServeCSV(store, []filestore.Filepath{path}, schema, CSVConfig{TimestampLayout: layout})
+	if err != nil {
+		t.Fatalf("could not serve csv file: %v", err)
+	}
+	row, err := iter.Next()
+	if err != nil {
+		t.Fatalf("could not read row: %v", err)
+	}
+	if expected := time.Date(2023, time.January, 2, 3, 4, 0, 0, time.UTC); !reflect.DeepEqual(row["ts"], expected) {
+		t.Fatalf("expected ts %v, got %#v", expected, row["ts"])
+	}
+}
+
+// pagedLister is a stub listing API that returns its pages one at a time, with
+// the index of the next page as the continuation token.
+type pagedLister struct {
+	pages  [][]*blob.ListObject
+	tokens [][]byte
+}
+
+func (l *pagedLister) ListPage(ctx context.Context, pageToken []byte, pageSize int, opts *blob.ListOptions) ([]*blob.ListObject, []byte, error) {
+	l.tokens = append(l.tokens, pageToken)
+	page := 0
+	if !bytes.Equal(pageToken, blob.FirstPageToken) {
+		if len(pageToken) != 1 {
+			return nil, nil, fmt.Errorf("invalid page token %v", pageToken)
+		}
+		page = int(pageToken[0])
+	}
+	if page >= len(l.pages) {
+		return nil, nil, fmt.Errorf("invalid page token %v", pageToken)
+	}
+	if page == len(l.pages)-1 {
+		return l.pages[page], nil, nil
+	}
+	return l.pages[page], []byte{byte(page + 1)}, nil
+}
+
+func TestNewestKeyOfTypesPagesThroughListing(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	object := func(key string, minutes int) *blob.ListObject {
+		return &blob.ListObject{Key: key, ModTime: start.Add(time.Duration(minutes) * time.Minute)}
+	}
+	lister := &pagedLister{
+		pages: [][]*blob.ListObject{
+			{object("prefix/a.parquet", 2), object("prefix/b.csv", 50)},
+			{object("prefix/c.parquet", 1), {Key: "prefix/dir.parquet", IsDir: true, ModTime: start.Add(time.Hour)}},
+			{object("prefix/d.parquet", 10), object("prefix/e.parquet", 3)},
+		},
+	}
+	store := &genericFileStore{}
+	key, err := store.newestKeyOfTypes(context.Background(), lister, "prefix/", []filestore.FileType{filestore.Parquet})
+	if err != nil {
+		t.Fatalf("could not get newest key: %v", err)
+	}
+	if key != "prefix/d.parquet" {
+		t.Fatalf("expected newest parquet file on the last page, got %q", key)
+	}
+	if len(lister.tokens) != len(lister.pages) {
+		t.Fatalf("expected all %d pages to be listed, got %d", len(lister.pages), len(lister.tokens))
+	}
+	if !bytes.Equal(lister.tokens[0], blob.FirstPageToken) {
+		t.Fatalf("expected listing to start from the first page, got token %v", lister.tokens[0])
+	}
+
+	key, err = store.newestKeyOfTypes(context.Background(), &pagedLister{pages: [][]*blob.ListObject{{}}}, "prefix/", []filestore.FileType{filestore.Parquet})
+	if err != nil || key != "" {
+		t.Fatalf("expected no key for an empty listing, got %q: %v", key, err)
+	}
+}
+
+func TestNewestKeyOfTypesBreaksTiesByKey(t *testing.T) {
+	modTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	object := func(key string) *blob.ListObject {
+		return &blob.ListObject{Key: key, ModTime: modTime}
+	}
+	// Both orders of the same listing must pick the same file
+	listings := [][]*blob.ListObject{
+		{object("prefix/b.parquet"), object("prefix/c.snappy.parquet"), object("prefix/a.parquet")},
+		{object("prefix/a.parquet"), object("prefix/c.snappy.parquet"), object("prefix/b.parquet")},
+	}
+	store := &genericFileStore{}
+	for i, listing := range listings {
+		lister := &pagedLister{pages: [][]*blob.ListObject{listing}}
+		key, err := store.newestKeyOfTypes(context.Background(), lister, "prefix/", []filestore.FileType{filestore.Parquet})
+		if err != nil {
+			t.Fatalf("listing %d: could not get newest key: %v", i, err)
+		}
+		if key != "prefix/c.snappy.parquet" {
+			t.Fatalf("listing %d: expected the last key of the tied files, got %q", i, key)
+		}
+		lister = &pagedLister{pages: [][]*blob.ListObject{listing}}
+		key, err = store.newestKeyOfTypes(context.Background(), lister, "prefix/", []filestore.FileType{"snappy.parquet", filestore.CSV})
+		if err != nil || key != "prefix/c.snappy.parquet" {
+			t.Fatalf("listing %d: expected the only snappy parquet file, got %q: %v", i, key, err)
+		}
+	}
+}
+
+func TestUploadInParts(t *testing.T) {
+	bucket := memblob.OpenBucket(nil)
+	defer bucket.Close()
+	partSize := int64(1024)
+	store := &genericFileStore{bucket: bucket, storeType: filestore.Memory, partSize: partSize}
+	stagingDir := t.TempDir()
+	sizes := map[string]int64{
+		"Single Put":     partSize / 2,
+		"Exact Part":     partSize,
+		"Multiple Parts": 5*partSize + 7,
+	}
+	for name, size := range sizes {
+		t.Run(name, func(t *testing.T) {
+			data := make([]byte, size)
+			rand.New(rand.NewSource(size)).Read(data)
+			source := filestore.LocalFilepath{}
+			if err := source.SetKey(filepath.Join(stagingDir, fmt.Sprintf("upload_%d", size))); err != nil {
+				t.Fatalf("could not set source path: %v", err)
+			}
+			if err := os.WriteFile(source.Key(), data, 0644); err != nil {
+				t.Fatalf("could not write file to upload: %v", err)
+			}
+			dest := filestore.MemoryFilepath{}
+			if err := dest.SetKey(fmt.Sprintf("uploads/%d", size)); err != nil {
+				t.Fatalf("could not set destination path: %v", err)
+			}
+			if err := store.Upload(&source, &dest); err != nil {
+				t.Fatalf("could not upload file: %v", err)
+			}
+			download := filestore.LocalFilepath{}
+			if err := download.SetKey(filepath.Join(stagingDir, fmt.Sprintf("download_%d", size))); err != nil {
+				t.Fatalf("could not set download path: %v", err)
+			}
+			if err := store.Download(&dest, &download); err != nil {
+				t.Fatalf("could not download file: %v", err)
+			}
+			downloaded, err := os.ReadFile(download.Key())
+			if err != nil {
+				t.Fatalf("could not read downloaded file: %v", err)
+			}
+			if !bytes.Equal(downloaded, data) {
+				t.Fatalf("expected the %d bytes uploaded to be downloaded, got %d different bytes", len(data), len(downloaded))
+			}
+		})
+	}
+
+	missing := filestore.LocalFilepath{}
+	if err := missing.SetKey(filepath.Join(stagingDir, "missing")); err != nil {
+		t.Fatalf("could not set missing path: %v", err)
+	}
+	dest := filestore.MemoryFilepath{}
+	if err := dest.SetKey("uploads/missing"); err != nil {
+		t.Fatalf("could not set destination path: %v", err)
+	}
+	if err := store.Upload(&missing, &dest); err == nil {
+		t.Fatalf("expected uploading a missing file to fail")
+	}
+}
+
+func TestServeOrder(t *testing.T) {
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "file", ValueType: Int},
+			{Name: "row", ValueType: Int},
+		},
+	}
+	numFiles, rowsPerFile := 8, 4
+	for _, fileType := range []filestore.FileType{filestore.Parquet, filestore.CSV} {
+		t.Run(string(fileType), func(t *testing.T) {
+			store := NewMemoryFileStore()
+			files := make([]filestore.Filepath, numFiles)
+			for i := range files {
+				records := make([]GenericRecord, rowsPerFile)
+				for j := range records {
+					records[j] = GenericRecord{i, j}
+				}
+				var b []byte
+				var err error
+				if fileType == filestore.CSV {
+					b, err = schema.ToCSVBytes(records, CSVConfig{})
+				} else {
+					b, err = schema.ToParquetBytes(records, ParquetWriteConfig{})
+				}
+				if err != nil {
+					t.Fatalf("could not write file %d: %v", i, err)
+				}
+				path, err := store.CreateFilePath(fmt.Sprintf("served/part-%05d.%s", i, fileType))
+				if err != nil {
+					t.Fatalf("could not create file path: %v", err)
+				}
+				if err := store.Write(path, b); err != nil {
+					t.Fatalf("could not write file %d: %v", i, err)
+				}
+				files[i] = path
+			}
+			rand.New(rand.NewSource(int64(numFiles))).Shuffle(len(files), func(i, j int) {
+				files[i], files[j] = files[j], files[i]
+			})
+			iter, err := store.Serve(files)
+			if err != nil {
+				t.Fatalf("could not serve files: %v", err)
+			}
+			for _, file := range files {
+				var expectedFile int
+				if _, err := fmt.Sscanf(filepath.Base(file.Key()), "part-%05d.", &expectedFile); err != nil {
+					t.Fatalf("could not parse file number of %s: %v", file.Key(), err)
+				}
+				for expectedRow := 0; expectedRow < rowsPerFile; expectedRow++ {
+					row, err := iter.Next()
+					if err != nil || row == nil {
+						t.Fatalf("expected row %d of file %d: %v", expectedRow, expectedFile, err)
+					}
+					if fmt.Sprint(row["file"]) != fmt.Sprint(expectedFile) || fmt.Sprint(row["row"]) != fmt.Sprint(expectedRow) {
+						t.Fatalf("expected row %d of file %d, got %v", expectedRow, expectedFile, row)
+					}
+				}
+			}
+			if row, err := iter.Next(); row != nil || err != nil {
+				t.Fatalf("expected end of rows, got %v %v", row, err)
+			}
+		})
+	}
+}
+
+// progressRecorder checks a transfer's progress only increases, and keeps the
+// last progress reported
+type progressRecorder struct {
+	t           *testing.T
+	calls       int
+	transferred int64
+	total       int64
+}
+
+func (r *progressRecorder) record(bytesTransferred, totalBytes int64) {
+	if bytesTransferred < r.transferred {
+		r.t.Fatalf("progress went back from %d to %d bytes", r.transferred, bytesTransferred)
+	}
+	r.calls++
+	r.transferred, r.total = bytesTransferred, totalBytes
+}
+
+func TestTransferProgress(t *testing.T) {
+	bucket := memblob.OpenBucket(nil)
+	defer bucket.Close()
+	partSize := int64(1024)
+	stores := map[string]FileStore{
+		"Blob":   &genericFileStore{bucket: bucket, storeType: filestore.Memory, partSize: partSize},
+		"Memory": NewMemoryFileStore(),
+	}
+	stagingDir := t.TempDir()
+	for storeName, store := range stores {
+		for _, size := range []int64{0, partSize / 2, 5*partSize + 7} {
+			t.Run(fmt.Sprintf("%s %d", storeName, size), func(t *testing.T) {
+				data := make([]byte, size)
+				rand.New(rand.NewSource(size)).Read(data)
+				source := filestore.LocalFilepath{}
+				if err := source.SetKey(filepath.Join(stagingDir, fmt.Sprintf("%s_upload_%d", storeName, size))); err != nil {
+					t.Fatalf("could not set source path: %v", err)
+				}
+				if err := os.WriteFile(source.Key(), data, 0644); err != nil {
+					t.Fatalf("could not write file to upload: %v", err)
+				}
+				dest := filestore.MemoryFilepath{}
+				if err := dest.SetKey(fmt.Sprintf("progress/%d", size)); err != nil {
+					t.Fatalf("could not set destination path: %v", err)
+				}
+				uploaded := &progressRecorder{t: t}
+				if err := store.UploadWithProgress(&source, &dest, uploaded.record); err != nil {
+					t.Fatalf("could not upload file: %v", err)
+				}
+				if uploaded.calls < 2 || uploaded.transferred != size || uploaded.total != size {
+					t.Fatalf("expected upload to finish with %d of %d bytes, got %d of %d after %d calls", size, size, uploaded.transferred, uploaded.total, uploaded.calls)
+				}
+
+				download := filestore.LocalFilepath{}
+				if err := download.SetKey(filepath.Join(stagingDir, fmt.Sprintf("%s_download_%d", storeName, size))); err != nil {
+					t.Fatalf("could not set download path: %v", err)
+				}
+				downloaded := &progressRecorder{t: t}
+				if err := store.DownloadWithProgress(&dest, &download, downloaded.record); err != nil {
+					t.Fatalf("could not download file: %v", err)
+				}
+				if downloaded.transferred != size || downloaded.total != size {
+					t.Fatalf("expected download to finish with %d of %d bytes, got %d of %d", size, size, downloaded.transferred, downloaded.total)
+				}
+				if content, err := os.ReadFile(download.Key()); err != nil || !bytes.Equal(content, data) {
+					t.Fatalf("downloaded file doesn't match uploaded file: %v", err)
+				}
+			})
+		}
+	}
+}
+
+// flakyMultipartUpload fails the first attempt at each part, and writes the
+// parts to its bucket once the upload is completed
+type flakyMultipartUpload struct {
+	bucket   *blob.Bucket
+	key      string
+	attempts map[int]int
+	parts    map[int][]byte
+	failPart int
+	aborted  bool
+}
+
+func (u *flakyMultipartUpload) UploadPart(ctx context.Context, number int, data []byte) error {
+	u.attempts[number]++
+	if u.attempts[number] == 1 || number == u.failPart {
+		return fmt.Errorf("part %d failed", number)
+	}
+	u.parts[number] = append([]byte{}, data...)
+	return nil
+}
+
+func (u *flakyMultipartUpload) Complete(ctx context.Context) error {
+	content := make([]byte, 0)
+	for number := 1; number <= len(u.parts); number++ {
+		content = append(content, u.parts[number]...)
+	}
+	return u.bucket.WriteAll(ctx, u.key, content, nil)
+}
+
+func (u *flakyMultipartUpload) Abort(ctx context.Context) error {
+	u.aborted = true
+	return nil
+}
+
+func TestUploadRetriesEachPart(t *testing.T) {
+	bucket := memblob.OpenBucket(nil)
+	defer bucket.Close()
+	partSize := int64(1024)
+	var upload *flakyMultipartUpload
+	store := &genericFileStore{
+		bucket:    bucket,
+		storeType: filestore.Memory,
+		partSize:  partSize,
+		multipart: func(ctx context.Context, key string) (multipartUpload, error) {
+			upload = &flakyMultipartUpload{bucket: bucket, key: key, attempts: make(map[int]int), parts: make(map[int][]byte)}
+			return upload, nil
+		},
+	}
+	data := make([]byte, 3*partSize+7)
+	rand.New(rand.NewSource(int64(len(data)))).Read(data)
+	source := filestore.LocalFilepath{}
+	if err := source.SetKey(filepath.Join(t.TempDir(), "upload")); err != nil {
+		t.Fatalf("could not set source path: %v", err)
+	}
+	if err := os.WriteFile(source.Key(), data, 0644); err != nil {
+		t.Fatalf("could not write file to upload: %v", err)
+	}
+	dest := filestore.MemoryFilepath{}
+	if err := dest.SetKey("uploads/retried"); err != nil {
+		t.Fatalf("could not set destination path: %v", err)
+	}
+	if err := store.Upload(&source, &dest); err != nil {
+		t.Fatalf("could not upload file: %v", err)
+	}
+	expectedAttempts := map[int]int{1: 2, 2: 2, 3: 2, 4: 2}
+	if !reflect.DeepEqual(expectedAttempts, upload.attempts) {
+		t.Fatalf("expected each part to be attempted twice, got %v", upload.attempts)
+	}
+	uploaded, err := bucket.ReadAll(context.Background(), dest.Key())
+	if err != nil {
+		t.Fatalf("could not read uploaded file: %v", err)
+	}
+	if !bytes.Equal(uploaded, data) {
+		t.Fatalf("expected the %d bytes uploaded to be stored, got %d different bytes", len(data), len(uploaded))
+	}
+
+	// A part that fails every attempt aborts the upload
+	store.multipart = func(ctx context.Context, key string) (multipartUpload, error) {
+		upload = &flakyMultipartUpload{bucket: bucket, key: key, attempts: make(map[int]int), parts: make(map[int][]byte), failPart: 2}
+		return upload, nil
+	}
+	failedDest := filestore.MemoryFilepath{}
+	if err := failedDest.SetKey("uploads/failed"); err != nil {
+		t.Fatalf("could not set destination path: %v", err)
+	}
+	if err := store.Upload(&source, &failedDest); err == nil {
+		t.Fatalf("expected an upload with a failing part to fail")
+	}
+	if !upload.aborted {
+		t.Fatalf("expected the failed upload to be aborted")
+	}
+	if upload.attempts[3] != 0 {
+		t.Fatalf("expected no parts to be sent after the failed part, got %d attempts at part 3", upload.attempts[3])
+	}
+	if exists, err := bucket.Exists(context.Background(), failedDest.Key()); err != nil || exists {
+		t.Fatalf("expected nothing to be written for the failed upload: exists %v, err %v", exists, err)
+	}
+}
+
+func TestHDFSKerberosAuthError(t *testing.T) {
+	dir := t.TempDir()
+	krb5Conf := filepath.Join(dir, "krb5.conf")
+	if err := os.WriteFile(krb5Conf, []byte("[libdefaults]\n  default_realm = EXAMPLE.COM\n"), 0644); err != nil {
+		t.Fatalf("could not write krb5.conf: %v", err)
+	}
+	newStore := func(kerberos *pc.HDFSKerberosConfig) error {
+		config := pc.HDFSFileStoreConfig{Host: "localhost", Port: "9000", Path: "/", Kerberos: kerberos}
+		serialized, err := config.Serialize()
+		if err != nil {
+			t.Fatalf("could not serialize config: %v", err)
+		}
+		_, err = NewHDFSFileStore(serialized)
+		return err
+	}
+
+	// Bad credentials fail before the namenode is contacted
+	err := newStore(&pc.HDFSKerberosConfig{
+		Principal:    "featureform",
+		Realm:        "EXAMPLE.COM",
+		KeytabPath:   filepath.Join(dir, "missing.keytab"),
+		NamenodeSPN:  "nn/localhost",
+		Krb5ConfPath: krb5Conf,
+	})
+	var authErr *HDFSAuthError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("expected an auth error for a missing keytab, got %v", err)
+	}
+
+	err = newStore(&pc.HDFSKerberosConfig{CCachePath: filepath.Join(dir, "krb5cc"), Krb5ConfPath: krb5Conf})
+	if err == nil || errors.As(err, &authErr) {
+		t.Fatalf("expected a config error for a missing namenode SPN, got %v", err)
+	}
+}
+
+func TestMemoryFileStore(t *testing.T) {
+	var store FileStore = NewMemoryFileStore()
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+		},
+	}
+	records := []GenericRecord{{"a", 1}, {"b", 2}, {"c", 3}}
+	content, err := schema.ToParquetBytes(records, ParquetWriteConfig{})
+	if err != nil {
+		t.Fatalf("could not write parquet file: %v", err)
+	}
+	dir, err := store.CreateDirPath("tables/feature")
+	if err != nil {
+		t.Fatalf("could not create dir path: %v", err)
+	}
+	path, err := store.CreateFilePath("tables/feature/part-0000.parquet")
+	if err != nil {
+		t.Fatalf("could not create file path: %v", err)
+	}
+	if path.ToURI() != "mem:///tables/feature/part-0000.parquet" {
+		t.Fatalf("unexpected uri %s", path.ToURI())
+	}
+	if exists, err := store.Exists(dir); err != nil || exists {
+		t.Fatalf("expected empty store to have no directory, got %v %v", exists, err)
+	}
+	if err := store.Write(path, content); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+	if exists, err := store.Exists(dir); err != nil || !exists {
+		t.Fatalf("expected directory to exist, got %v %v", exists, err)
+	}
+	read, err := store.Read(path)
+	if err != nil {
+		t.Fatalf("could not read file: %v", err)
+	}
+	if !bytes.Equal(read, content) {
+		t.Fatalf("read file doesn't match written file")
+	}
+	if rows, err := store.NumRows(path); err != nil || rows != int64(len(records)) {
+		t.Fatalf("expected %d rows, got %d %v", len(records), rows, err)
+	}
+	iter, err := store.Serve([]filestore.Filepath{path})
+	if err != nil {
+		t.Fatalf("could not serve file: %v", err)
+	}
+	for i, record := range records {
+		row, err := iter.Next()
+		if err != nil {
+			t.Fatalf("could not read row %d: %v", i, err)
+		}
+		if row["entity"] != record[0] || row["value"] != record[1] {
+			t.Fatalf("row %d: expected %v, got %v", i, record, row)
+		}
+	}
+	if row, err := iter.Next(); row != nil || err != nil {
+		t.Fatalf("expected end of rows, got %v %v", row, err)
+	}
+	files, err := store.List(dir, filestore.Parquet)
+	if err != nil || len(files) != 1 || files[0].Key() != path.Key() {
+		t.Fatalf("expected to list %s, got %v %v", path.Key(), files, err)
+	}
+
+	// Files round trip through the local file system
+	local := filestore.LocalFilepath{}
+	if err := local.SetKey(filepath.Join(t.TempDir(), "part.parquet")); err != nil {
+		t.Fatalf("could not set local path: %v", err)
+	}
+	if err := store.Download(path, &local); err != nil {
+		t.Fatalf("could not download file: %v", err)
+	}
+	uploaded, err := store.CreateFilePath("uploaded/part.parquet")
+	if err != nil {
+		t.Fatalf("could not create file path: %v", err)
+	}
+	if err := store.Upload(&local, uploaded); err != nil {
+		t.Fatalf("could not upload file: %v", err)
+	}
+	if read, err := store.Read(uploaded); err != nil || !bytes.Equal(read, content) {
+		t.Fatalf("uploaded file doesn't match downloaded file: %v", err)
+	}
+
+	// A sibling whose name starts with the directory's isn't deleted with it
+	sibling, err := store.CreateFilePath("tables/feature_v2/part-0000.parquet")
+	if err != nil {
+		t.Fatalf("could not create file path: %v", err)
+	}
+	if err := store.Write(sibling, content); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+	if err := store.DeleteAll(dir); err != nil {
+		t.Fatalf("could not delete directory: %v", err)
+	}
+	if _, err := store.Read(path); err == nil {
+		t.Fatalf("expected file to be deleted with its directory")
+	}
+	if err := store.Delete(sibling); err != nil {
+		t.Fatalf("could not delete sibling: %v", err)
+	}
+	if err := store.Delete(sibling); err == nil {
+		t.Fatalf("expected deleting a missing file to fail")
+	}
+}
+
+func TestMemoryFileStoreNewestFile(t *testing.T) {
+	store := NewMemoryFileStore()
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	store.Now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	dir, err := store.CreateDirPath("snapshots")
+	if err != nil {
+		t.Fatalf("could not create dir path: %v", err)
+	}
+	// Keys are written in the opposite of their sort order, so that the newest
+	// file can only be found by its modification time
+	keys := []string{"snapshots/c.parquet", "snapshots/b.parquet", "snapshots/a.parquet", "snapshots/z.csv"}
+	paths := make([]filestore.Filepath, len(keys))
+	for i, key := range keys {
+		path, err := store.CreateFilePath(key)
+		if err != nil {
+			t.Fatalf("could not create file path: %v", err)
+		}
+		if err := store.Write(path, []byte(key)); err != nil {
+			t.Fatalf("could not write %s: %v", key, err)
+		}
+		paths[i] = path
+	}
+	newest, err := store.NewestFileOfType(dir, filestore.Parquet)
+	if err != nil {
+		t.Fatalf("could not get newest file: %v", err)
+	}
+	if newest.Key() != "snapshots/a.parquet" {
+		t.Fatalf("expected snapshots/a.parquet, got %s", newest.Key())
+	}
+	newest, err = store.NewestFileOfTypes(dir, filestore.Parquet, filestore.CSV)
+	if err != nil || newest.Key() != "snapshots/z.csv" {
+		t.Fatalf("expected snapshots/z.csv, got %v %v", newest, err)
+	}
+
+	// Moving a file's modification time makes it the newest, and files modified
+	// at the same time are ordered by key
+	if err := store.SetModTime(paths[0], start.Add(time.Hour)); err != nil {
+		t.Fatalf("could not set modification time: %v", err)
+	}
+	if err := store.SetModTime(paths[1], start.Add(time.Hour)); err != nil {
+		t.Fatalf("could not set modification time: %v", err)
+	}
+	newestN, err := store.NewestNFilesOfType(dir, filestore.Parquet, 2)
+	if err != nil {
+		t.Fatalf("could not get newest files: %v", err)
+	}
+	if len(newestN) != 2 || newestN[0].Key() != "snapshots/c.parquet" || newestN[1].Key() != "snapshots/b.parquet" {
+		t.Fatalf("expected c and b to be newest, got %v", newestN)
+	}
+
+	empty, err := store.CreateDirPath("missing")
+	if err != nil {
+		t.Fatalf("could not create dir path: %v", err)
+	}
+	if newest, err := store.NewestFileOfType(empty, filestore.Parquet); err != nil || newest.Key() != "" {
+		t.Fatalf("expected no newest file, got %v %v", newest, err)
+	}
+}
@@ -0,0 +1,101 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Words that may appear in a filter expression without referring to a column.
+var filterKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IN": true, "IS": true, "NULL": true,
+	"LIKE": true, "ILIKE": true, "BETWEEN": true, "TRUE": true, "FALSE": true,
+}
+
+// filterIdentifiers tokenizes a SQL boolean expression and returns the column
+// identifiers it references. Quoted string literals are skipped, double-quoted and
+// backtick-quoted identifiers are unquoted, and keywords and function names are
+// ignored. Statement separators and comments are rejected so that the filter can
+// only ever extend the WHERE clause it's placed in.
+func filterIdentifiers(filter string) ([]string, error) {
+	if strings.Contains(filter, ";") || strings.Contains(filter, "--") || strings.Contains(filter, "/*") {
+		return nil, fmt.Errorf("filter %q cannot contain statement separators or comments", filter)
+	}
+	identifiers := make([]string, 0)
+	runes := []rune(filter)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("filter %q has an unterminated string literal", filter)
+			}
+			i = end + 1
+		case r == '"' || r == '`':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("filter %q has an unterminated quoted identifier", filter)
+			}
+			identifiers = append(identifiers, string(runes[i+1:end]))
+			i = end + 1
+		case unicode.IsLetter(r) || r == '_':
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+				end++
+			}
+			word := string(runes[i:end])
+			next := end
+			for next < len(runes) && unicode.IsSpace(runes[next]) {
+				next++
+			}
+			isFunction := next < len(runes) && runes[next] == '('
+			if !filterKeywords[strings.ToUpper(word)] && !isFunction {
+				identifiers = append(identifiers, word)
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	return identifiers, nil
+}
+
+// validateFilter checks that a filter is well formed and, if the source's columns
+// are known, that every identifier it references is a column in the source.
+func validateFilter(filter string, columns []TableColumn) error {
+	identifiers, err := filterIdentifiers(filter)
+	if err != nil {
+		return err
+	}
+	if columns == nil {
+		return nil
+	}
+	sourceColumns := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		sourceColumns[strings.ToLower(col.Name)] = true
+	}
+	for _, identifier := range identifiers {
+		if !sourceColumns[strings.ToLower(identifier)] {
+			return fmt.Errorf("filter %q references column %s which does not exist in the source", filter, identifier)
+		}
+	}
+	return nil
+}
+
+func filterClause(filter string) string {
+	if filter == "" {
+		return ""
+	}
+	return fmt.Sprintf(" WHERE %s", filter)
+}
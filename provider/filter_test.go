@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFilterIdentifiers(t *testing.T) {
+	type testCase struct {
+		filter   string
+		expected []string
+	}
+
+	cases := []testCase{
+		{filter: "value > 2", expected: []string{"value"}},
+		{filter: "status = 'active' AND age >= 18", expected: []string{"status", "age"}},
+		{filter: `"Status" IS NOT NULL OR lower(name) LIKE 'a%'`, expected: []string{"Status", "name"}},
+		{filter: "`ts` BETWEEN '2020-01-01' AND '2021-01-01'", expected: []string{"ts"}},
+	}
+
+	for _, c := range cases {
+		identifiers, err := filterIdentifiers(c.filter)
+		if err != nil {
+			t.Fatalf("unexpected error parsing filter %q: %v", c.filter, err)
+		}
+		if !reflect.DeepEqual(identifiers, c.expected) {
+			t.Errorf("expected %v, got %v", c.expected, identifiers)
+		}
+	}
+}
+
+func TestValidateFilter(t *testing.T) {
+	columns := []TableColumn{{Name: "entity"}, {Name: "value"}, {Name: "ts"}}
+	valid := []string{"value > 2", "VALUE > 2 AND entity != 'a'", "ts IS NOT NULL"}
+	for _, filter := range valid {
+		if err := validateFilter(filter, columns); err != nil {
+			t.Errorf("expected filter %q to be valid: %v", filter, err)
+		}
+	}
+	invalid := []string{
+		"missing > 2",
+		"value > 2; DROP TABLE users",
+		"value > 2 -- comment",
+		"entity = 'a",
+	}
+	for _, filter := range invalid {
+		if err := validateFilter(filter, columns); err == nil {
+			t.Errorf("expected filter %q to be invalid", filter)
+		}
+	}
+	if err := validateFilter("missing > 2", nil); err != nil {
+		t.Errorf("expected unknown columns to be allowed when source columns are unknown: %v", err)
+	}
+}
+
+func TestFilteredMaterializationQuery(t *testing.T) {
+	q := defaultPythonOfflineQueries{}
+	schema := ResourceSchema{Entity: "entity", Value: "value", TS: "ts", Filter: "value > 2"}
+	query := q.materializationCreate(schema)
+	if !strings.Contains(query, "(SELECT * FROM source_0 WHERE value > 2) AS filtered_source") {
+		t.Fatalf("expected filter to be pushed into the source read: %s", query)
+	}
+	schema.Filter = ""
+	if query := q.materializationCreate(schema); strings.Contains(query, "WHERE value") {
+		t.Fatalf("unexpected filter in unfiltered query: %s", query)
+	}
+}
@@ -160,6 +160,15 @@ func (store *firestoreOnlineStore) DeleteTable(feature, variant string) error {
 }
 
 func (table firestoreOnlineTable) Set(entity string, value interface{}) error {
+	// JSON values are stored as their text, since Firestore would read numbers
+	// in them back as int64 rather than float64
+	if table.valueType == JSON {
+		text, err := jsonText(value)
+		if err != nil {
+			return err
+		}
+		value = text
+	}
 	_, err := table.document.Set(context.TODO(), map[string]interface{}{
 		entity: value,
 	}, firestore.MergeAll)
@@ -184,6 +193,12 @@ func (table firestoreOnlineTable) Get(entity string) (interface{}, error) {
 	case Float32:
 		var floatVal float64 = value.(float64)
 		return float32(floatVal), nil
+	case JSON:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected JSON text, got %T", value)
+		}
+		return parseJSON([]byte(text))
 	}
 
 	return value, nil
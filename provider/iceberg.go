@@ -0,0 +1,547 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+
+	filestore "github.com/featureform/filestore"
+)
+
+// ICEBERG
+// An Iceberg table (https://iceberg.apache.org/spec/) is a directory holding its
+// data files and a metadata/ directory. Each commit to the table writes a new
+// metadata/v<N>.metadata.json naming the table's current snapshot and schemas.
+// A snapshot's manifest list is an Avro file naming its manifests, which are Avro
+// files listing its data files. Only parquet data files are supported, and
+// snapshots with delete files are rejected since their rows can't be filtered.
+
+// The TableSchema.SourceFormat of primary tables registered from Iceberg tables
+const icebergSourceFormat = "iceberg"
+
+// IcebergPrimaryOfflineStore is implemented by offline stores that can register
+// an Iceberg table as a primary table. The table is read from its current
+// snapshot each time it's iterated, so later commits to it are picked up.
+type IcebergPrimaryOfflineStore interface {
+	OfflineStore
+	RegisterPrimaryFromIcebergTable(id ResourceID, location string) (PrimaryTable, error)
+}
+
+const (
+	icebergContentData       = 0
+	icebergStatusDeleted     = 2
+	icebergNameMappingProp   = "schema.name-mapping.default"
+	icebergVersionHintFile   = "version-hint.text"
+	icebergMetadataDirectory = "metadata"
+)
+
+type icebergField struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type icebergSchema struct {
+	SchemaID int            `json:"schema-id"`
+	Fields   []icebergField `json:"fields"`
+}
+
+type icebergSnapshot struct {
+	SnapshotID   int64  `json:"snapshot-id"`
+	ManifestList string `json:"manifest-list"`
+	// Format version 1 tables may list manifests in the metadata instead
+	Manifests []string `json:"manifests"`
+}
+
+type icebergMetadata struct {
+	Location        string `json:"location"`
+	CurrentSchemaID int    `json:"current-schema-id"`
+	// Format version 1 tables only have a single schema
+	Schema            *icebergSchema    `json:"schema"`
+	Schemas           []icebergSchema   `json:"schemas"`
+	CurrentSnapshotID *int64            `json:"current-snapshot-id"`
+	Snapshots         []icebergSnapshot `json:"snapshots"`
+	Properties        map[string]string `json:"properties"`
+}
+
+// An entry of the table's name mapping, which assigns field IDs to the columns
+// of data files that were written without them, such as imported files
+type icebergMappedField struct {
+	FieldID int      `json:"field-id"`
+	Names   []string `json:"names"`
+}
+
+type icebergDataFile struct {
+	path        filestore.Filepath
+	recordCount int64
+}
+
+type icebergTable struct {
+	store FileStore
+	// Key of the table's root directory in store
+	key      string
+	metadata icebergMetadata
+}
+
+// loadIcebergTable reads the newest metadata of the table at location, which
+// is either the table's directory or one of its metadata.json files.
+func loadIcebergTable(store FileStore, location filestore.Filepath) (*icebergTable, error) {
+	metadataPath := location
+	tableKey := strings.Trim(location.Key(), "/")
+	if strings.HasSuffix(tableKey, ".metadata.json") {
+		tableKey = path.Dir(path.Dir(tableKey))
+	} else {
+		var err error
+		if metadataPath, err = newestIcebergMetadata(store, tableKey); err != nil {
+			return nil, err
+		}
+	}
+	b, err := store.Read(metadataPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read iceberg metadata %s: %w", metadataPath.Key(), err)
+	}
+	table := &icebergTable{store: store, key: tableKey}
+	if err := json.Unmarshal(b, &table.metadata); err != nil {
+		return nil, fmt.Errorf("could not parse iceberg metadata %s: %w", metadataPath.Key(), err)
+	}
+	return table, nil
+}
+
+// newestIcebergMetadata finds the newest metadata file of a table, starting
+// from the version in its version hint, which may lag behind the newest commit.
+func newestIcebergMetadata(store FileStore, tableKey string) (filestore.Filepath, error) {
+	version := 0
+	hint, err := store.CreateFilePath(path.Join(tableKey, icebergMetadataDirectory, icebergVersionHintFile))
+	if err != nil {
+		return nil, fmt.Errorf("could not create version hint path: %w", err)
+	}
+	if exists, err := store.Exists(hint); err != nil {
+		return nil, fmt.Errorf("could not check for iceberg version hint: %w", err)
+	} else if exists {
+		b, err := store.Read(hint)
+		if err != nil {
+			return nil, fmt.Errorf("could not read iceberg version hint: %w", err)
+		}
+		if version, err = strconv.Atoi(strings.TrimSpace(string(b))); err != nil {
+			return nil, fmt.Errorf("invalid iceberg version hint %q: %w", b, err)
+		}
+	}
+	for {
+		next, err := store.CreateFilePath(icebergMetadataKey(tableKey, version+1))
+		if err != nil {
+			return nil, fmt.Errorf("could not create metadata path: %w", err)
+		}
+		if exists, err := store.Exists(next); err != nil {
+			return nil, fmt.Errorf("could not check for iceberg metadata: %w", err)
+		} else if !exists {
+			break
+		}
+		version++
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("no iceberg metadata found under %s", tableKey)
+	}
+	return store.CreateFilePath(icebergMetadataKey(tableKey, version))
+}
+
+func icebergMetadataKey(tableKey string, version int) string {
+	return path.Join(tableKey, icebergMetadataDirectory, fmt.Sprintf("v%d.metadata.json", version))
+}
+
+// resolve returns the path of a file referenced by the table's metadata. Files
+// under the table's location are resolved relative to where the table was
+// loaded from, so tables that were copied or moved can still be read.
+func (t *icebergTable) resolve(file string) (filestore.Filepath, error) {
+	location := strings.TrimSuffix(t.metadata.Location, "/")
+	if location != "" && strings.HasPrefix(file, location+"/") {
+		return t.store.CreateFilePath(path.Join(t.key, strings.TrimPrefix(file, location+"/")))
+	}
+	fp, err := filestore.NewEmptyFilepath(t.store.FilestoreType())
+	if err != nil {
+		return nil, fmt.Errorf("could not create file path: %w", err)
+	}
+	if err := fp.ParseFilePath(file); err != nil {
+		return nil, fmt.Errorf("could not parse path %s: %w", file, err)
+	}
+	return fp, nil
+}
+
+func (t *icebergTable) currentSchema() (icebergSchema, error) {
+	for _, schema := range t.metadata.Schemas {
+		if schema.SchemaID == t.metadata.CurrentSchemaID {
+			return schema, nil
+		}
+	}
+	if t.metadata.Schema != nil {
+		return *t.metadata.Schema, nil
+	}
+	return icebergSchema{}, fmt.Errorf("iceberg metadata has no schema with id %d", t.metadata.CurrentSchemaID)
+}
+
+func (t *icebergTable) currentSnapshot() (*icebergSnapshot, error) {
+	id := t.metadata.CurrentSnapshotID
+	if id == nil || *id == -1 {
+		return nil, nil
+	}
+	for i := range t.metadata.Snapshots {
+		if t.metadata.Snapshots[i].SnapshotID == *id {
+			return &t.metadata.Snapshots[i], nil
+		}
+	}
+	return nil, fmt.Errorf("iceberg metadata has no snapshot with id %d", *id)
+}
+
+// readAvro reads every record of an Avro file referenced by the metadata
+func (t *icebergTable) readAvro(file string) ([]map[string]interface{}, error) {
+	fp, err := t.resolve(file)
+	if err != nil {
+		return nil, err
+	}
+	b, err := t.store.Read(fp)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", file, err)
+	}
+	iter, err := avroIteratorFromBytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %w", file, err)
+	}
+	records := make([]map[string]interface{}, 0)
+	for {
+		record, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", file, err)
+		}
+		if record == nil {
+			return records, nil
+		}
+		records = append(records, record)
+	}
+}
+
+// dataFiles returns the data files of the table's current snapshot. A table
+// without a snapshot has no data files.
+func (t *icebergTable) dataFiles() ([]icebergDataFile, error) {
+	snapshot, err := t.currentSnapshot()
+	if err != nil || snapshot == nil {
+		return nil, err
+	}
+	manifests := snapshot.Manifests
+	if snapshot.ManifestList != "" {
+		entries, err := t.readAvro(snapshot.ManifestList)
+		if err != nil {
+			return nil, fmt.Errorf("could not read manifest list: %w", err)
+		}
+		manifests = make([]string, 0, len(entries))
+		for _, entry := range entries {
+			if content, _ := entry["content"].(int); content != icebergContentData {
+				return nil, fmt.Errorf("iceberg tables with delete files are not supported")
+			}
+			manifest, _ := entry["manifest_path"].(string)
+			manifests = append(manifests, manifest)
+		}
+	}
+	files := make([]icebergDataFile, 0)
+	for _, manifest := range manifests {
+		entries, err := t.readAvro(manifest)
+		if err != nil {
+			return nil, fmt.Errorf("could not read manifest: %w", err)
+		}
+		for _, entry := range entries {
+			if status, _ := entry["status"].(int); status == icebergStatusDeleted {
+				continue
+			}
+			dataFile, _ := entry["data_file"].(map[string]interface{})
+			if content, _ := dataFile["content"].(int); content != icebergContentData {
+				return nil, fmt.Errorf("iceberg tables with delete files are not supported")
+			}
+			filePath, _ := dataFile["file_path"].(string)
+			if fileFormat, _ := dataFile["file_format"].(string); !strings.EqualFold(fileFormat, string(filestore.Parquet)) {
+				return nil, fmt.Errorf("unsupported iceberg data file format %s: %s", fileFormat, filePath)
+			}
+			fp, err := t.resolve(filePath)
+			if err != nil {
+				return nil, err
+			}
+			recordCount, _ := dataFile["record_count"].(int)
+			files = append(files, icebergDataFile{path: fp, recordCount: int64(recordCount)})
+		}
+	}
+	return files, nil
+}
+
+func (t *icebergTable) nameMapping() ([]icebergMappedField, error) {
+	raw, has := t.metadata.Properties[icebergNameMappingProp]
+	if !has {
+		return nil, nil
+	}
+	mapping := make([]icebergMappedField, 0)
+	if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+		return nil, fmt.Errorf("could not parse iceberg name mapping: %w", err)
+	}
+	return mapping, nil
+}
+
+// parquetTopLevelColumns returns the schema elements of a parquet file's top
+// level columns. The schema is stored flattened, with the root first and each
+// group followed by its descendants.
+func parquetTopLevelColumns(b []byte) ([]format.SchemaElement, error) {
+	file, err := parquet.OpenFile(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, fmt.Errorf("could not open parquet file: %w", err)
+	}
+	elements := file.Metadata().Schema
+	if len(elements) == 0 {
+		return nil, nil
+	}
+	columns := make([]format.SchemaElement, 0, elements[0].NumChildren)
+	for i, remaining := 1, int(elements[0].NumChildren); remaining > 0 && i < len(elements); remaining-- {
+		columns = append(columns, elements[i])
+		i = skipSchemaElement(elements, i)
+	}
+	return columns, nil
+}
+
+// skipSchemaElement returns the index of the element after elements[i] and its
+// descendants.
+func skipSchemaElement(elements []format.SchemaElement, i int) int {
+	children := int(elements[i].NumChildren)
+	i++
+	for ; children > 0 && i < len(elements); children-- {
+		i = skipSchemaElement(elements, i)
+	}
+	return i
+}
+
+// icebergColumnNames maps field IDs to the names of a data file's columns.
+// Iceberg writes each column's field ID into the file, so columns that were
+// renamed after the file was written are still found. Files without field IDs
+// fall back to the table's name mapping, and then to the current column names.
+func icebergColumnNames(columns []format.SchemaElement, schema icebergSchema, mapping []icebergMappedField) map[int]string {
+	names := make(map[int]string)
+	for _, column := range columns {
+		if column.FieldID != 0 {
+			names[int(column.FieldID)] = column.Name
+		}
+	}
+	if len(names) > 0 {
+		return names
+	}
+	inFile := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		inFile[column.Name] = true
+	}
+	if mapping == nil {
+		mapping = make([]icebergMappedField, len(schema.Fields))
+		for i, field := range schema.Fields {
+			mapping[i] = icebergMappedField{FieldID: field.ID, Names: []string{field.Name}}
+		}
+	}
+	for _, field := range mapping {
+		for _, name := range field.Names {
+			if inFile[name] {
+				names[field.FieldID] = name
+				break
+			}
+		}
+	}
+	return names
+}
+
+// ServeIcebergTable iterates over the rows of the current snapshot of the
+// Iceberg table at location. Rows have the columns of the table's current
+// schema; columns added after a data file was written are nil in its rows.
+func ServeIcebergTable(store FileStore, location filestore.Filepath) (Iterator, error) {
+	table, err := loadIcebergTable(store, location)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := table.currentSchema()
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := table.nameMapping()
+	if err != nil {
+		return nil, err
+	}
+	files, err := table.dataFiles()
+	if err != nil {
+		return nil, err
+	}
+	columns := parquetSchema{}
+	for _, field := range schema.Fields {
+		columns.setColumn(columns.getColumnType(field.Name), field.Name)
+	}
+	return &icebergIterator{
+		store:          store,
+		files:          files,
+		schema:         schema,
+		mapping:        mapping,
+		featureColumns: columns.featureColumns,
+		labelColumn:    columns.labelColumn,
+	}, nil
+}
+
+// icebergNumRows returns the number of rows in the current snapshot of the
+// Iceberg table at location, as recorded in its manifests.
+func icebergNumRows(store FileStore, location filestore.Filepath) (int64, error) {
+	table, err := loadIcebergTable(store, location)
+	if err != nil {
+		return 0, err
+	}
+	files, err := table.dataFiles()
+	if err != nil {
+		return 0, err
+	}
+	numRows := int64(0)
+	for _, file := range files {
+		numRows += file.recordCount
+	}
+	return numRows, nil
+}
+
+type icebergIterator struct {
+	store   FileStore
+	files   []icebergDataFile
+	schema  icebergSchema
+	mapping []icebergMappedField
+	// Index of the next file to open
+	fileIdx int
+	file    Iterator
+	// Names of the current file's columns, by field ID
+	fileColumns    map[int]string
+	featureColumns []string
+	labelColumn    string
+}
+
+func (it *icebergIterator) Next() (map[string]interface{}, error) {
+	for {
+		if it.file != nil {
+			row, err := it.file.Next()
+			if err != nil {
+				return nil, err
+			}
+			if row != nil {
+				return it.project(row), nil
+			}
+		}
+		if it.fileIdx >= len(it.files) {
+			return nil, nil
+		}
+		if err := it.openFile(it.files[it.fileIdx].path); err != nil {
+			return nil, err
+		}
+		it.fileIdx++
+	}
+}
+
+func (it *icebergIterator) openFile(fp filestore.Filepath) error {
+	b, err := it.store.Read(fp)
+	if err != nil {
+		return fmt.Errorf("could not read iceberg data file %s: %w", fp.Key(), err)
+	}
+	columns, err := parquetTopLevelColumns(b)
+	if err != nil {
+		return fmt.Errorf("could not read schema of iceberg data file %s: %w", fp.Key(), err)
+	}
+	file, err := parquetIteratorFromBytes(b)
+	if err != nil {
+		return fmt.Errorf("could not open iceberg data file %s: %w", fp.Key(), err)
+	}
+	it.file = file
+	it.fileColumns = icebergColumnNames(columns, it.schema, it.mapping)
+	return nil
+}
+
+// project converts a row of the current data file to the table's current schema
+func (it *icebergIterator) project(row map[string]interface{}) map[string]interface{} {
+	projected := make(map[string]interface{}, len(it.schema.Fields))
+	for _, field := range it.schema.Fields {
+		if name, has := it.fileColumns[field.ID]; has {
+			projected[field.Name] = row[name]
+		} else {
+			projected[field.Name] = nil
+		}
+	}
+	return projected
+}
+
+func (it *icebergIterator) FeatureColumns() []string {
+	return it.featureColumns
+}
+
+func (it *icebergIterator) LabelColumn() string {
+	return it.labelColumn
+}
+
+// icebergTableIterator iterates over up to limit rows of an Iceberg table as
+// records with the columns of its current schema, in order.
+type icebergTableIterator struct {
+	iter    *icebergIterator
+	columns []string
+	record  GenericRecord
+	err     error
+	limit   int64
+	idx     int64
+}
+
+func newIcebergTableIterator(store FileStore, location filestore.Filepath, limit int64) (GenericTableIterator, error) {
+	iter, err := ServeIcebergTable(store, location)
+	if err != nil {
+		return nil, err
+	}
+	icebergIter := iter.(*icebergIterator)
+	columns := make([]string, len(icebergIter.schema.Fields))
+	for i, field := range icebergIter.schema.Fields {
+		columns[i] = field.Name
+	}
+	if limit == -1 {
+		limit = math.MaxInt64
+	}
+	return &icebergTableIterator{iter: icebergIter, columns: columns, limit: limit}, nil
+}
+
+func (it *icebergTableIterator) Next() bool {
+	if it.err != nil || it.idx >= it.limit {
+		return false
+	}
+	row, err := it.iter.Next()
+	if err != nil {
+		it.err = err
+		return false
+	}
+	if row == nil {
+		return false
+	}
+	it.record = make(GenericRecord, len(it.columns))
+	for i, column := range it.columns {
+		it.record[i] = row[column]
+	}
+	it.idx++
+	return true
+}
+
+func (it *icebergTableIterator) Values() GenericRecord {
+	return it.record
+}
+
+func (it *icebergTableIterator) Columns() []string {
+	return it.columns
+}
+
+func (it *icebergTableIterator) Err() error {
+	return it.err
+}
+
+func (it *icebergTableIterator) Close() error {
+	return nil
+}
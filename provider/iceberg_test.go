@@ -0,0 +1,211 @@
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/parquet-go/parquet-go/format"
+
+	pc "github.com/featureform/provider/provider_config"
+)
+
+const (
+	testIcebergLocation = "s3://warehouse/db/events"
+	testIcebergKey      = "iceberg/events"
+)
+
+const testManifestListSchema = `{"type": "record", "name": "manifest_file", "fields": [
+	{"name": "manifest_path", "type": "string"},
+	{"name": "content", "type": "int"}
+]}`
+
+const testManifestSchema = `{"type": "record", "name": "manifest_entry", "fields": [
+	{"name": "status", "type": "int"},
+	{"name": "data_file", "type": {"type": "record", "name": "r2", "fields": [
+		{"name": "content", "type": "int"},
+		{"name": "file_path", "type": "string"},
+		{"name": "file_format", "type": "string"},
+		{"name": "record_count", "type": "long"}
+	]}}
+]}`
+
+type testManifestEntry struct {
+	status      int64
+	file        string
+	recordCount int64
+}
+
+func writeTestIcebergFile(t *testing.T, store FileStore, name string, content []byte) {
+	path, err := store.CreateFilePath(fmt.Sprintf("%s/%s", testIcebergKey, name))
+	if err != nil {
+		t.Fatalf("could not create file path: %v", err)
+	}
+	if err := store.Write(path, content); err != nil {
+		t.Fatalf("could not write %s: %v", name, err)
+	}
+}
+
+func writeTestManifestList(t *testing.T, store FileStore, name string, manifests ...string) {
+	records := bytes.Buffer{}
+	for _, manifest := range manifests {
+		writeAvroBytes(&records, []byte(fmt.Sprintf("%s/metadata/%s", testIcebergLocation, manifest)))
+		writeAvroLong(&records, icebergContentData)
+	}
+	content, err := writeAvroContainer(testManifestListSchema, "null", len(manifests), records.Bytes())
+	if err != nil {
+		t.Fatalf("could not write manifest list: %v", err)
+	}
+	writeTestIcebergFile(t, store, "metadata/"+name, content)
+}
+
+func writeTestManifest(t *testing.T, store FileStore, name string, entries ...testManifestEntry) {
+	records := bytes.Buffer{}
+	for _, entry := range entries {
+		writeAvroLong(&records, entry.status)
+		writeAvroLong(&records, icebergContentData)
+		writeAvroBytes(&records, []byte(fmt.Sprintf("%s/data/%s", testIcebergLocation, entry.file)))
+		writeAvroBytes(&records, []byte("PARQUET"))
+		writeAvroLong(&records, entry.recordCount)
+	}
+	content, err := writeAvroContainer(testManifestSchema, "null", len(entries), records.Bytes())
+	if err != nil {
+		t.Fatalf("could not write manifest: %v", err)
+	}
+	writeTestIcebergFile(t, store, "metadata/"+name, content)
+}
+
+func writeTestDataFile(t *testing.T, store FileStore, name string, schema TableSchema, records []GenericRecord) {
+	content, err := schema.ToParquetBytes(records, ParquetWriteConfig{})
+	if err != nil {
+		t.Fatalf("could not write parquet file: %v", err)
+	}
+	writeTestIcebergFile(t, store, "data/"+name, content)
+}
+
+// writeTestIcebergTable writes a table with two snapshots. Between them, the
+// "points" column was renamed to "value" and a "weight" column was added. The
+// second snapshot adds a file and deletes one of the first snapshot's files.
+// The data files are written without field IDs, so columns are matched to the
+// schema with the table's name mapping.
+func writeTestIcebergTable(t *testing.T, store FileStore) {
+	oldSchema := TableSchema{Columns: []TableColumn{
+		{Name: "entity", ValueType: String},
+		{Name: "points", ValueType: Int},
+	}}
+	writeTestDataFile(t, store, "a.parquet", oldSchema, []GenericRecord{{"a", 1}, {"b", 2}})
+	writeTestDataFile(t, store, "removed.parquet", oldSchema, []GenericRecord{{"removed", 0}})
+	newSchema := TableSchema{Columns: []TableColumn{
+		{Name: "entity", ValueType: String},
+		{Name: "value", ValueType: Int},
+		{Name: "weight", ValueType: Float64},
+	}}
+	writeTestDataFile(t, store, "c.parquet", newSchema, []GenericRecord{{"c", 3, 0.5}})
+
+	writeTestManifest(t, store, "m1.avro",
+		testManifestEntry{status: 1, file: "a.parquet", recordCount: 2},
+		testManifestEntry{status: 1, file: "removed.parquet", recordCount: 1},
+	)
+	writeTestManifestList(t, store, "snap-1.avro", "m1.avro")
+	writeTestManifest(t, store, "m2.avro",
+		testManifestEntry{status: 0, file: "a.parquet", recordCount: 2},
+		testManifestEntry{status: 2, file: "removed.parquet", recordCount: 1},
+	)
+	writeTestManifest(t, store, "m3.avro", testManifestEntry{status: 1, file: "c.parquet", recordCount: 1})
+	writeTestManifestList(t, store, "snap-2.avro", "m2.avro", "m3.avro")
+
+	v1 := fmt.Sprintf(`{
+		"format-version": 2,
+		"location": %q,
+		"current-schema-id": 0,
+		"schemas": [{"schema-id": 0, "fields": [{"id": 1, "name": "entity"}, {"id": 2, "name": "points"}]}],
+		"current-snapshot-id": 1,
+		"snapshots": [{"snapshot-id": 1, "manifest-list": "%s/metadata/snap-1.avro"}]
+	}`, testIcebergLocation, testIcebergLocation)
+	v2 := fmt.Sprintf(`{
+		"format-version": 2,
+		"location": %q,
+		"current-schema-id": 1,
+		"schemas": [
+			{"schema-id": 0, "fields": [{"id": 1, "name": "entity"}, {"id": 2, "name": "points"}]},
+			{"schema-id": 1, "fields": [{"id": 1, "name": "entity"}, {"id": 2, "name": "value"}, {"id": 3, "name": "weight"}]}
+		],
+		"current-snapshot-id": 2,
+		"snapshots": [
+			{"snapshot-id": 1, "manifest-list": "%s/metadata/snap-1.avro"},
+			{"snapshot-id": 2, "manifest-list": "%s/metadata/snap-2.avro"}
+		],
+		"properties": {
+			"schema.name-mapping.default": "[{\"field-id\": 1, \"names\": [\"entity\"]}, {\"field-id\": 2, \"names\": [\"value\", \"points\"]}, {\"field-id\": 3, \"names\": [\"weight\"]}]"
+		}
+	}`, testIcebergLocation, testIcebergLocation, testIcebergLocation)
+	writeTestIcebergFile(t, store, "metadata/v1.metadata.json", []byte(v1))
+	writeTestIcebergFile(t, store, "metadata/v2.metadata.json", []byte(v2))
+	writeTestIcebergFile(t, store, "metadata/version-hint.text", []byte("1"))
+}
+
+func TestServeIcebergTable(t *testing.T) {
+	config := pc.LocalFileStoreConfig{DirPath: fmt.Sprintf("file:///%s", t.TempDir())}
+	serialized, err := config.Serialize()
+	if err != nil {
+		t.Fatalf("could not serialize file store config: %v", err)
+	}
+	store, err := NewLocalFileStore(serialized)
+	if err != nil {
+		t.Fatalf("could not create local file store: %v", err)
+	}
+	defer store.Close()
+	writeTestIcebergTable(t, store)
+
+	table, err := store.CreateDirPath(testIcebergKey)
+	if err != nil {
+		t.Fatalf("could not create table path: %v", err)
+	}
+	iter, err := ServeIcebergTable(store, table)
+	if err != nil {
+		t.Fatalf("could not serve iceberg table: %v", err)
+	}
+	rows := make([]map[string]interface{}, 0)
+	for {
+		row, err := iter.Next()
+		if err != nil {
+			t.Fatalf("could not iterate over iceberg table: %v", err)
+		}
+		if row == nil {
+			break
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i]["entity"].(string) < rows[j]["entity"].(string)
+	})
+	expected := []map[string]interface{}{
+		{"entity": "a", "value": 1, "weight": nil},
+		{"entity": "b", "value": 2, "weight": nil},
+		{"entity": "c", "value": 3, "weight": 0.5},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("expected current snapshot rows %v, got %v", expected, rows)
+	}
+
+	numRows, err := icebergNumRows(store, table)
+	if err != nil {
+		t.Fatalf("could not count rows: %v", err)
+	}
+	if numRows != int64(len(expected)) {
+		t.Fatalf("expected %d rows, got %d", len(expected), numRows)
+	}
+}
+
+func TestIcebergColumnNamesUsesFieldIDs(t *testing.T) {
+	schema := icebergSchema{Fields: []icebergField{{ID: 1, Name: "entity"}, {ID: 2, Name: "value"}, {ID: 3, Name: "weight"}}}
+	// A mapping that disagrees with the file's field IDs, which take precedence
+	mapping := []icebergMappedField{{FieldID: 3, Names: []string{"points"}}}
+	columns := []format.SchemaElement{{Name: "entity", FieldID: 1}, {Name: "points", FieldID: 2}}
+	expected := map[int]string{1: "entity", 2: "points"}
+	if actual := icebergColumnNames(columns, schema, mapping); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected column names %v, got %v", expected, actual)
+	}
+}
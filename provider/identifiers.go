@@ -0,0 +1,110 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	pt "github.com/featureform/provider/provider_type"
+)
+
+// IdentifierCase is how an offline store folds the case of unquoted
+// identifiers. The tables Featureform creates are always quoted, so they keep
+// the case they were created with, but names typed by users are resolved the
+// way the backend would resolve them.
+type IdentifierCase string
+
+const (
+	// CaseSensitiveIdentifiers are matched exactly, as in BigQuery and file
+	// stores.
+	CaseSensitiveIdentifiers IdentifierCase = ""
+	LowerCaseIdentifiers     IdentifierCase = "lower"
+	UpperCaseIdentifiers     IdentifierCase = "upper"
+)
+
+// IdentifierCasing returns how offline stores of type t fold unquoted
+// identifiers.
+func IdentifierCasing(t pt.Type) IdentifierCase {
+	switch t {
+	case pt.PostgresOffline, pt.RedshiftOffline:
+		return LowerCaseIdentifiers
+	case pt.SQLiteOffline:
+		// SQLite keeps the case of names, but matches them regardless of
+		// it, quoted or not
+		return LowerCaseIdentifiers
+	case pt.SnowflakeOffline:
+		return UpperCaseIdentifiers
+	default:
+		return CaseSensitiveIdentifiers
+	}
+}
+
+// Equal reports whether two unquoted identifiers resolve to the same name.
+func (c IdentifierCase) Equal(a, b string) bool {
+	if c == CaseSensitiveIdentifiers {
+		return a == b
+	}
+	return strings.EqualFold(a, b)
+}
+
+// UnsafeIdentifierError is returned for names that no offline store can quote
+// as an identifier.
+type UnsafeIdentifierError struct {
+	Name string
+}
+
+func (e *UnsafeIdentifierError) Error() string {
+	return fmt.Sprintf("identifier %q contains characters that cannot be quoted", e.Name)
+}
+
+// SanitizeIdentifier quotes a name that may come from a user as an identifier
+// for an offline store of type t. Quotes, semicolons, backslashes and anything
+// else in it are escaped by the store's rules, so that they're part of the
+// name rather than ending it. Names with NUL bytes, or that aren't valid UTF-8,
+// are rejected with an UnsafeIdentifierError.
+func SanitizeIdentifier(t pt.Type, name string) (string, error) {
+	if strings.ContainsRune(name, 0) || !utf8.ValidString(name) {
+		return "", &UnsafeIdentifierError{Name: name}
+	}
+	return QuoteIdentifier(t, name), nil
+}
+
+// QuoteIdentifier quotes name for an offline store of type t so that it's
+// resolved exactly as it's cased. BigQuery names can include the project and
+// dataset, separated by dots. MySQL names are shortened the same way as the
+// tables the MySQL store creates, so that they resolve to them. Names from
+// users should go through SanitizeIdentifier instead.
+func QuoteIdentifier(t pt.Type, name string) string {
+	switch t {
+	case pt.BigQueryOffline:
+		return "`" + escapeBigQueryIdentifier(name) + "`"
+	case pt.MySQLOffline:
+		return "`" + strings.ReplaceAll(shortenIdentifier(name, mysqlMaxIdentifierLength), "`", "``") + "`"
+	default:
+		return sanitize(name)
+	}
+}
+
+// escapeBigQueryIdentifier escapes name to be put between backticks. Quoted
+// BigQuery identifiers read backslashes as escapes, so a trailing one would
+// otherwise escape the closing backtick.
+func escapeBigQueryIdentifier(name string) string {
+	return strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(name)
+}
+
+// quoteLiteral quotes s as a string literal for an offline store of type t.
+// BigQuery and Snowflake also read backslashes in literals as escapes.
+func quoteLiteral(t pt.Type, s string) string {
+	switch t {
+	case pt.BigQueryOffline:
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+	case pt.SnowflakeOffline:
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(s) + "'"
+	default:
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+}
@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"errors"
+	"testing"
+
+	pt "github.com/featureform/provider/provider_type"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	cases := []struct {
+		providerType pt.Type
+		name         string
+		expected     string
+	}{
+		{pt.PostgresOffline, "Transactions", `"Transactions"`},
+		{pt.SnowflakeOffline, "Transactions", `"Transactions"`},
+		{pt.RedshiftOffline, `Odd"Name`, `"Odd""Name"`},
+		{pt.BigQueryOffline, "project.dataset.Transactions", "`project.dataset.Transactions`"},
+		{pt.MySQLOffline, "Odd`Name", "`Odd``Name`"},
+		{pt.SQLiteOffline, `Odd"Name`, `"Odd""Name"`},
+	}
+	for _, c := range cases {
+		if quoted := QuoteIdentifier(c.providerType, c.name); quoted != c.expected {
+			t.Fatalf("%s: expected %s, got %s", c.providerType, c.expected, quoted)
+		}
+	}
+}
+
+func TestIdentifierCasing(t *testing.T) {
+	if !IdentifierCasing(pt.PostgresOffline).Equal("Transactions", "transactions") {
+		t.Fatalf("expected postgres identifiers to be case insensitive")
+	}
+	if !IdentifierCasing(pt.SnowflakeOffline).Equal("Transactions", "TRANSACTIONS") {
+		t.Fatalf("expected snowflake identifiers to be case insensitive")
+	}
+	if !IdentifierCasing(pt.SQLiteOffline).Equal("Transactions", "TRANSACTIONS") {
+		t.Fatalf("expected sqlite identifiers to be case insensitive")
+	}
+	if IdentifierCasing(pt.BigQueryOffline).Equal("Transactions", "transactions") {
+		t.Fatalf("expected bigquery identifiers to be case sensitive")
+	}
+}
+
+func TestShortenIdentifier(t *testing.T) {
+	if name := shortenIdentifier("featureform_primary__short__v1", 64); name != "featureform_primary__short__v1" {
+		t.Fatalf("expected short name to be kept, got %s", name)
+	}
+	long := "featureform_resource_feature__transactions__4ad7f5b2-0d4e-4b7e-8f51-3f9a8f0c2d11"
+	first := shortenIdentifier(long, 64)
+	if len(first) != 64 {
+		t.Fatalf("expected name of length 64, got %d: %s", len(first), first)
+	}
+	if first != shortenIdentifier(long, 64) {
+		t.Fatalf("expected shortened names to be deterministic")
+	}
+	if first == shortenIdentifier(long+"0", 64) {
+		t.Fatalf("expected names with the same prefix to be shortened differently")
+	}
+}
+
+var adversarialNames = []string{
+	"transactions",
+	`a"; DROP TABLE users; --`,
+	`a'; DROP TABLE users; --`,
+	"a`; DROP TABLE users; --",
+	`trailing\`,
+	`\"`,
+	`\'`,
+	"\\`",
+	`O'Brien`,
+	`"`,
+}
+
+// unquote reads a quoted identifier or literal the way a store's parser would.
+// It returns the name it reads and whatever follows the closing quote, which is
+// empty unless the name broke out of the quotes.
+func unquote(quoted string, quote byte, doubled, backslashes bool) (string, string) {
+	if len(quoted) == 0 || quoted[0] != quote {
+		return "", quoted
+	}
+	name := ""
+	for i := 1; i < len(quoted); i++ {
+		c := quoted[i]
+		switch {
+		case backslashes && c == '\\' && i+1 < len(quoted):
+			name += string(quoted[i+1])
+			i++
+		case doubled && c == quote && i+1 < len(quoted) && quoted[i+1] == quote:
+			name += string(quote)
+			i++
+		case c == quote:
+			return name, quoted[i+1:]
+		default:
+			name += string(c)
+		}
+	}
+	return name, "unterminated"
+}
+
+func TestSanitizeIdentifier(t *testing.T) {
+	cases := []struct {
+		providerType pt.Type
+		quote        byte
+		doubled      bool
+		backslashes  bool
+	}{
+		{pt.PostgresOffline, '"', true, false},
+		{pt.RedshiftOffline, '"', true, false},
+		{pt.SnowflakeOffline, '"', true, false},
+		{pt.BigQueryOffline, '`', false, true},
+		{pt.MySQLOffline, '`', true, false},
+		{pt.SQLiteOffline, '"', true, false},
+	}
+	for _, c := range cases {
+		for _, name := range adversarialNames {
+			quoted, err := SanitizeIdentifier(c.providerType, name)
+			if err != nil {
+				t.Fatalf("%s: could not sanitize %s: %v", c.providerType, name, err)
+			}
+			unquoted, rest := unquote(quoted, c.quote, c.doubled, c.backslashes)
+			if rest != "" {
+				t.Fatalf("%s: %s broke out of its identifier as %s, leaving %s", c.providerType, name, quoted, rest)
+			}
+			if unquoted != name {
+				t.Fatalf("%s: expected %s to name %s, got %s", c.providerType, quoted, name, unquoted)
+			}
+		}
+		for _, name := range []string{"nul\x00byte", "invalid\xffutf8"} {
+			var unsafe *UnsafeIdentifierError
+			if _, err := SanitizeIdentifier(c.providerType, name); !errors.As(err, &unsafe) {
+				t.Fatalf("%s: expected %q to be rejected, got %v", c.providerType, name, err)
+			}
+		}
+	}
+	if quoted := sanitize("nul\x00byte"); quoted != `"nulbyte"` {
+		t.Fatalf("expected NUL bytes to be dropped, got %q", quoted)
+	}
+}
+
+func TestQuoteLiteral(t *testing.T) {
+	cases := []struct {
+		providerType pt.Type
+		doubled      bool
+		backslashes  bool
+	}{
+		{pt.PostgresOffline, true, false},
+		{pt.SnowflakeOffline, true, true},
+		{pt.BigQueryOffline, false, true},
+		{pt.SQLiteOffline, true, false},
+	}
+	for _, c := range cases {
+		for _, name := range adversarialNames {
+			quoted := quoteLiteral(c.providerType, name)
+			unquoted, rest := unquote(quoted, '\'', c.doubled, c.backslashes)
+			if rest != "" {
+				t.Fatalf("%s: %s broke out of its literal as %s, leaving %s", c.providerType, name, quoted, rest)
+			}
+			if unquoted != name {
+				t.Fatalf("%s: expected %s to read as %s, got %s", c.providerType, quoted, name, unquoted)
+			}
+		}
+	}
+}
@@ -3,12 +3,15 @@ package provider
 import (
 	"bytes"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
 	"math"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/parquet-go/parquet-go"
@@ -19,9 +22,50 @@ import (
 	filestore "github.com/featureform/filestore"
 )
 
+// IteratorStats is how much data an iterator has read so far.
+type IteratorStats struct {
+	RowsRead int64
+	// Bytes read from the underlying files. This includes file metadata, so it
+	// can be more than the size of the rows read.
+	BytesRead int64
+}
+
+// StatsIterator is implemented by iterators that count what they've read, so
+// callers can report the I/O of a job.
+type StatsIterator interface {
+	Stats() IteratorStats
+}
+
+// countingReaderAt counts the bytes the parquet reader pulls from a file.
+type countingReaderAt struct {
+	reader io.ReaderAt
+	size   int64
+	read   int64
+}
+
+func newCountingReaderAt(b []byte) *countingReaderAt {
+	return &countingReaderAt{reader: bytes.NewReader(b), size: int64(len(b))}
+}
+
+func (r *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.reader.ReadAt(p, off)
+	atomic.AddInt64(&r.read, int64(n))
+	return n, err
+}
+
+// Size lets the parquet reader find the footer without seeking
+func (r *countingReaderAt) Size() int64 {
+	return r.size
+}
+
+func (r *countingReaderAt) bytesRead() int64 {
+	return atomic.LoadInt64(&r.read)
+}
+
 // PARQUET
 type parquetIterator struct {
 	reader        *parquet.Reader
+	file          *countingReaderAt
 	currentValues GenericRecord
 	err           error
 	// Using fields instead of column names gives us access to
@@ -48,6 +92,24 @@ func (p *parquetIterator) Next() bool {
 	}
 	records := make(GenericRecord, 0)
 	for _, f := range p.fields {
+		if decimal, isDecimal := parquetDecimal(f); isDecimal && row[f.Name()] != nil {
+			value, err := decimal.fromParquet(row[f.Name()])
+			if err != nil {
+				p.err = fmt.Errorf("column %s: %w", f.Name(), err)
+				return false
+			}
+			records = append(records, value)
+			continue
+		}
+		if raw, isRaw := row[f.Name()].([]byte); isRaw && parquetJSON(f) {
+			value, err := parseJSON(raw)
+			if err != nil {
+				p.err = fmt.Errorf("column %s: %w", f.Name(), err)
+				return false
+			}
+			records = append(records, value)
+			continue
+		}
 		var recordVal interface{}
 		switch assertedVal := row[f.Name()].(type) {
 		// We're currently converting int32 to int to decrease/simplify the number of
@@ -95,14 +157,19 @@ func (p *parquetIterator) Close() error {
 	return p.reader.Close()
 }
 
+func (p *parquetIterator) Stats() IteratorStats {
+	return IteratorStats{RowsRead: p.idx, BytesRead: p.file.bytesRead()}
+}
+
 func newParquetIterator(b []byte, limit int64) (GenericTableIterator, error) {
-	file := bytes.NewReader(b)
+	file := newCountingReaderAt(b)
 	reader := parquet.NewReader(file)
 	if limit == -1 {
 		limit = math.MaxInt64
 	}
 	return &parquetIterator{
 		reader: reader,
+		file:   file,
 		fields: reader.Schema().Fields(),
 		limit:  limit,
 		idx:    0,
@@ -119,6 +186,8 @@ type multipleFileParquetIterator struct {
 	idx           int64
 	files         []filestore.Filepath
 	fileIdx       int64
+	// Bytes read from the files before the current one
+	bytesRead int64
 }
 
 func (p *multipleFileParquetIterator) Next() bool {
@@ -155,6 +224,7 @@ func (p *multipleFileParquetIterator) Next() bool {
 		p.err = fmt.Errorf("iterator is not a parquet iterator")
 		return false
 	}
+	p.bytesRead += p.iterator.file.bytesRead()
 	p.iterator = parquetIterator
 	p.fileIdx += 1
 	return p.Next()
@@ -176,6 +246,10 @@ func (p *multipleFileParquetIterator) Close() error {
 	return p.iterator.reader.Close()
 }
 
+func (p *multipleFileParquetIterator) Stats() IteratorStats {
+	return IteratorStats{RowsRead: p.idx, BytesRead: p.bytesRead + p.iterator.file.bytesRead()}
+}
+
 func newMultipleFileParquetIterator(files []filestore.Filepath, store FileStore, limit int64) (GenericTableIterator, error) {
 	if len(files) == 0 {
 		return nil, fmt.Errorf("no files to read")
@@ -219,14 +293,12 @@ type ParquetIteratorMultipleFiles struct {
 	featureColumns []string
 	labelColumn    string
 	store          FileStore
+	// What was read from the files before the current one
+	stats IteratorStats
 }
 
 func parquetIteratorOverMultipleFiles(fileParts []filestore.Filepath, store FileStore) (Iterator, error) {
-	b, err := store.Read(fileParts[0])
-	if err != nil {
-		return nil, fmt.Errorf("could not read bucket: %w", err)
-	}
-	iterator, err := parquetIteratorFromBytes(b)
+	iterator, err := openParquetFile(store, fileParts[0])
 	if err != nil {
 		return nil, fmt.Errorf("could not open first parquet file: %w", err)
 	}
@@ -258,22 +330,30 @@ func (p *ParquetIteratorMultipleFiles) Next() (map[string]interface{}, error) {
 			return nil, nil
 		}
 		p.currentIndex += 1
-		b, err := p.store.Read(p.fileList[p.currentIndex])
-		if err != nil {
-			return nil, err
-		}
-		iterator, err := parquetIteratorFromBytes(b)
+		iterator, err := openParquetFile(p.store, p.fileList[p.currentIndex])
 		if err != nil {
 			return nil, err
 		}
+		p.stats = p.Stats()
 		p.fileIterator = iterator
 		return p.fileIterator.Next()
 	}
 	return nextRow, nil
 }
 
+func (p *ParquetIteratorMultipleFiles) Stats() IteratorStats {
+	stats := p.stats
+	if current, ok := p.fileIterator.(StatsIterator); ok {
+		currentStats := current.Stats()
+		stats.RowsRead += currentStats.RowsRead
+		stats.BytesRead += currentStats.BytesRead
+	}
+	return stats
+}
+
 type ParquetIterator struct {
 	reader         *parquet.Reader
+	file           *countingReaderAt
 	index          int64
 	featureColumns []string
 	labelColumn    string
@@ -290,8 +370,30 @@ func (p *ParquetIterator) Next() (map[string]interface{}, error) {
 			return nil, err
 		}
 	}
+	p.index++
 	for _, f := range p.fields {
+		if decimal, isDecimal := parquetDecimal(f); isDecimal && row[f.Name()] != nil {
+			value, err := decimal.fromParquet(row[f.Name()])
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", f.Name(), err)
+			}
+			row[f.Name()] = value
+			continue
+		}
+		if raw, isRaw := row[f.Name()].([]byte); isRaw && parquetJSON(f) {
+			value, err := parseJSON(raw)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", f.Name(), err)
+			}
+			row[f.Name()] = value
+			continue
+		}
 		switch assertedVal := row[f.Name()].(type) {
+		case nil:
+			// Null values are kept under their column, even if the reader left
+			// it out, so that they can be told apart from columns the file
+			// doesn't have
+			row[f.Name()] = nil
 		case int32:
 			row[f.Name()] = int(assertedVal)
 		case int64:
@@ -315,6 +417,10 @@ func (p *ParquetIterator) Next() (map[string]interface{}, error) {
 	return row, nil
 }
 
+func (p *ParquetIterator) Stats() IteratorStats {
+	return IteratorStats{RowsRead: p.index, BytesRead: p.file.bytesRead()}
+}
+
 func (p *ParquetIterator) FeatureColumns() []string {
 	return p.featureColumns
 }
@@ -323,12 +429,35 @@ func (p *ParquetIterator) LabelColumn() string {
 	return p.labelColumn
 }
 
+// parquetDecimal returns the decimal type a parquet field is annotated with, if
+// any. Its values are read as *big.Rat rather than their unscaled integers.
+func parquetDecimal(f parquet.Field) (DecimalType, bool) {
+	logical := f.Type().LogicalType()
+	if logical == nil || logical.Decimal == nil {
+		return DecimalType{}, false
+	}
+	return DecimalType{Precision: logical.Decimal.Precision, Scale: logical.Decimal.Scale}, true
+}
+
+// parquetJSON reports whether a parquet field is annotated as JSON. parquet-go
+// decodes its values when it reads rows into maps; any it leaves as []byte are
+// decoded by the iterators instead.
+func parquetJSON(f parquet.Field) bool {
+	logical := f.Type().LogicalType()
+	return logical != nil && logical.Json != nil
+}
+
 func getParquetNumRows(b []byte) (int64, error) {
 	file := bytes.NewReader(b)
 	r := parquet.NewReader(file)
 	return r.NumRows(), nil
 }
 
+// getParquetNumRowsAt reads the row count from a parquet file's footer
+func getParquetNumRowsAt(reader io.ReaderAt, size int64) int64 {
+	return parquet.NewReader(&countingReaderAt{reader: reader, size: size}).NumRows()
+}
+
 type columnType string
 
 const (
@@ -365,12 +494,19 @@ func (s *parquetSchema) setColumn(colType columnType, name string) {
 }
 
 func parquetIteratorFromBytes(b []byte) (Iterator, error) {
-	file := bytes.NewReader(b)
+	return parquetIteratorFromReader(bytes.NewReader(b), int64(len(b)))
+}
+
+// parquetIteratorFromReader reads a parquet file of the given size a row group
+// at a time, so only the pages being read are held in memory.
+func parquetIteratorFromReader(reader io.ReaderAt, size int64) (Iterator, error) {
+	file := &countingReaderAt{reader: reader, size: size}
 	r := parquet.NewReader(file)
 	schema := parquetSchema{}
 	schema.parseParquetColumnName(r)
 	return &ParquetIterator{
 		reader:         r,
+		file:           file,
 		index:          int64(0),
 		featureColumns: schema.featureColumns,
 		labelColumn:    schema.labelColumn,
@@ -378,6 +514,29 @@ func parquetIteratorFromBytes(b []byte) (Iterator, error) {
 	}, nil
 }
 
+// rangeReadableStore is implemented by file stores that can read parts of a
+// file without downloading all of it.
+type rangeReadableStore interface {
+	openReaderAt(path filestore.Filepath) (io.ReaderAt, int64, error)
+}
+
+// openParquetFile streams a parquet file from store if the store can read it in
+// ranges, and otherwise reads the whole file.
+func openParquetFile(store FileStore, path filestore.Filepath) (Iterator, error) {
+	if ranged, ok := store.(rangeReadableStore); ok {
+		reader, size, err := ranged.openReaderAt(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not open %s: %w", path.Key(), err)
+		}
+		return parquetIteratorFromReader(reader, size)
+	}
+	b, err := store.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", path.Key(), err)
+	}
+	return parquetIteratorFromBytes(b)
+}
+
 /// CSV
 type csvIterator struct {
 	reader        *csv.Reader
@@ -386,6 +545,8 @@ type csvIterator struct {
 	columnNames   []string
 	idx           int64
 	limit         int64
+	// Layouts of the columns that are parsed as timestamps, by column index
+	timestampLayouts map[int]string
 }
 
 func (c *csvIterator) Next() bool {
@@ -401,7 +562,12 @@ func (c *csvIterator) Next() bool {
 			return false
 		}
 	}
-	c.currentValues = c.ParseRow(row)
+	values, err := c.ParseRow(row)
+	if err != nil {
+		c.err = fmt.Errorf("row %d: %w", c.idx+1, err)
+		return false
+	}
+	c.currentValues = values
 	c.idx += 1
 	return true
 }
@@ -422,9 +588,20 @@ func (c *csvIterator) Close() error {
 	return nil
 }
 
-func (c *csvIterator) ParseRow(row []string) GenericRecord {
+func (c *csvIterator) ParseRow(row []string) (GenericRecord, error) {
 	records := make(GenericRecord, len(row))
 	for i, value := range row {
+		if layout, isTimestamp := c.timestampLayouts[i]; isTimestamp {
+			if value == "" {
+				continue
+			}
+			ts, err := time.Parse(layout, value)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: timestamp %q does not match layout %q: %w", c.columnNames[i], value, layout, err)
+			}
+			records[i] = ts
+			continue
+		}
 		if integer, err := strconv.Atoi(value); err == nil {
 			records[i] = integer
 			continue
@@ -433,13 +610,29 @@ func (c *csvIterator) ParseRow(row []string) GenericRecord {
 			records[i] = float
 			continue
 		}
+		if strings.EqualFold(value, "true") || strings.EqualFold(value, "false") {
+			records[i] = strings.EqualFold(value, "true")
+			continue
+		}
+		if ts, err := time.Parse(time.RFC3339, value); err == nil {
+			records[i] = ts
+			continue
+		}
 		records[i] = value
 	}
-	return records
+	return records, nil
 }
 
 func newCSVIterator(b []byte, limit int64) (GenericTableIterator, error) {
+	return newCSVIteratorWithSchema(b, limit, TableSchema{}, CSVConfig{})
+}
+
+// newCSVIteratorWithSchema reads the Timestamp columns of schema as time.Time,
+// using the layout in config. Other columns are inferred from their values as
+// ints, floats, bools or RFC3339 timestamps, falling back to strings.
+func newCSVIteratorWithSchema(b []byte, limit int64, schema TableSchema, config CSVConfig) (GenericTableIterator, error) {
 	reader := csv.NewReader(bytes.NewReader(b))
+	reader.Comma = config.delimiter()
 	headers, err := reader.Read()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CSV reader: %w", err)
@@ -447,10 +640,564 @@ func newCSVIterator(b []byte, limit int64) (GenericTableIterator, error) {
 	if limit == -1 {
 		limit = math.MaxInt64
 	}
+	timestampLayouts := make(map[int]string)
+	for _, col := range schema.Columns {
+		if col.ValueType != Timestamp {
+			continue
+		}
+		for i, header := range headers {
+			if header == col.Name {
+				timestampLayouts[i] = config.timestampLayout()
+			}
+		}
+	}
 	return &csvIterator{
-		reader:      reader,
-		columnNames: headers,
-		limit:       limit,
-		idx:         0,
+		reader:           reader,
+		columnNames:      headers,
+		limit:            limit,
+		idx:              0,
+		timestampLayouts: timestampLayouts,
+	}, nil
+}
+
+// csvFileIterator serves the rows of a CSV file, keyed by the names in its header
+type csvFileIterator struct {
+	iter           *csvIterator
+	featureColumns []string
+	labelColumn    string
+}
+
+func csvIteratorFromBytes(b []byte) (Iterator, error) {
+	return csvIteratorFromBytesWithSchema(b, TableSchema{}, CSVConfig{})
+}
+
+func csvIteratorFromBytesWithSchema(b []byte, schema TableSchema, config CSVConfig) (Iterator, error) {
+	iter, err := newCSVIteratorWithSchema(b, -1, schema, config)
+	if err != nil {
+		return nil, err
+	}
+	csvIter := iter.(*csvIterator)
+	columns := parquetSchema{}
+	for _, name := range csvIter.Columns() {
+		columns.setColumn(columns.getColumnType(name), name)
+	}
+	return &csvFileIterator{
+		iter:           csvIter,
+		featureColumns: columns.featureColumns,
+		labelColumn:    columns.labelColumn,
+	}, nil
+}
+
+func (c *csvFileIterator) Next() (map[string]interface{}, error) {
+	if !c.iter.Next() {
+		return nil, c.iter.Err()
+	}
+	values := c.iter.Values()
+	columns := c.iter.Columns()
+	row := make(map[string]interface{}, len(columns))
+	for i, name := range columns {
+		if i < len(values) {
+			row[name] = values[i]
+		}
+	}
+	return row, nil
+}
+
+func (c *csvFileIterator) FeatureColumns() []string {
+	return c.featureColumns
+}
+
+func (c *csvFileIterator) LabelColumn() string {
+	return c.labelColumn
+}
+
+// csvFilesIterator serves the rows of several CSV files in turn. Their feature
+// and label columns are taken from the first file.
+type csvFilesIterator struct {
+	files   []filestore.Filepath
+	store   FileStore
+	schema  TableSchema
+	config  CSVConfig
+	fileIdx int
+	current Iterator
+	first   Iterator
+}
+
+func newCSVFilesIterator(files []filestore.Filepath, store FileStore, schema TableSchema, config CSVConfig) (Iterator, error) {
+	iter := &csvFilesIterator{files: files, store: store, schema: schema, config: config}
+	first, err := iter.open(0)
+	if err != nil {
+		return nil, err
+	}
+	iter.first = first
+	iter.current = first
+	return iter, nil
+}
+
+func (c *csvFilesIterator) open(idx int) (Iterator, error) {
+	file := c.files[idx]
+	b, err := c.store.Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", file.Key(), err)
+	}
+	iter, err := csvIteratorFromBytesWithSchema(b, c.schema, c.config)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %w", file.Key(), err)
+	}
+	return iter, nil
+}
+
+func (c *csvFilesIterator) Next() (map[string]interface{}, error) {
+	for {
+		row, err := c.current.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", c.files[c.fileIdx].Key(), err)
+		}
+		if row != nil {
+			return row, nil
+		}
+		if c.fileIdx+1 >= len(c.files) {
+			return nil, nil
+		}
+		c.fileIdx++
+		if c.current, err = c.open(c.fileIdx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *csvFilesIterator) FeatureColumns() []string {
+	return c.first.FeatureColumns()
+}
+
+func (c *csvFilesIterator) LabelColumn() string {
+	return c.first.LabelColumn()
+}
+
+func getCSVNumRows(b []byte) (int64, error) {
+	return countCSVRows(bytes.NewReader(b))
+}
+
+// countCSVRows counts the rows after a CSV file's header
+func countCSVRows(r io.Reader) (int64, error) {
+	reader := csv.NewReader(r)
+	if _, err := reader.Read(); err != nil {
+		return 0, fmt.Errorf("failed to create CSV reader: %w", err)
+	}
+	numRows := int64(0)
+	for {
+		if _, err := reader.Read(); err == io.EOF {
+			return numRows, nil
+		} else if err != nil {
+			return 0, err
+		}
+		numRows++
+	}
+}
+
+// fileIteratorFromBytes reads a whole file of the given type
+func fileIteratorFromBytes(fileType filestore.FileType, b []byte) (Iterator, error) {
+	switch fileType {
+	case filestore.Parquet:
+		return parquetIteratorFromBytes(b)
+	case filestore.Avro:
+		return avroIteratorFromBytes(b)
+	case filestore.ORC:
+		return orcIteratorFromBytes(b)
+	case filestore.CSV:
+		return csvIteratorFromBytes(b)
+	case filestore.JSONL:
+		return jsonlIteratorFromBytes(b)
+	default:
+		return nil, fmt.Errorf("unsupported file type")
+	}
+}
+
+// JSONL
+// Each line holds one JSON object. Integral numbers are read as int and other
+// numbers as float64, matching the CSV iterator.
+type jsonlIterator struct {
+	decoder        *json.Decoder
+	featureColumns []string
+	labelColumn    string
+}
+
+func newJSONLDecoder(b []byte) *json.Decoder {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	return decoder
+}
+
+func jsonlIteratorFromBytes(b []byte) (Iterator, error) {
+	// Object keys are unordered, so the columns are taken from the first row in
+	// sorted order
+	first := make(map[string]interface{})
+	if err := newJSONLDecoder(b).Decode(&first); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("could not decode first jsonl row: %w", err)
+	}
+	names := make([]string, 0, len(first))
+	for name := range first {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	schema := parquetSchema{}
+	for _, name := range names {
+		schema.setColumn(schema.getColumnType(name), name)
+	}
+	return &jsonlIterator{
+		decoder:        newJSONLDecoder(b),
+		featureColumns: schema.featureColumns,
+		labelColumn:    schema.labelColumn,
 	}, nil
 }
+
+func (j *jsonlIterator) Next() (map[string]interface{}, error) {
+	row := make(map[string]interface{})
+	if err := j.decoder.Decode(&row); err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not decode jsonl row: %w", err)
+	}
+	for name, val := range row {
+		if num, isNumber := val.(json.Number); isNumber {
+			if integer, err := num.Int64(); err == nil {
+				row[name] = int(integer)
+			} else if float, err := num.Float64(); err == nil {
+				row[name] = float
+			}
+		}
+	}
+	return row, nil
+}
+
+func (j *jsonlIterator) FeatureColumns() []string {
+	return j.featureColumns
+}
+
+func (j *jsonlIterator) LabelColumn() string {
+	return j.labelColumn
+}
+
+func getJSONLNumRows(b []byte) (int64, error) {
+	return countJSONLRows(bytes.NewReader(b))
+}
+
+func countJSONLRows(r io.Reader) (int64, error) {
+	decoder := json.NewDecoder(r)
+	numRows := int64(0)
+	for {
+		var row map[string]json.RawMessage
+		if err := decoder.Decode(&row); err == io.EOF {
+			return numRows, nil
+		} else if err != nil {
+			return 0, fmt.Errorf("could not decode jsonl row: %w", err)
+		}
+		numRows++
+	}
+}
+
+// mappedIterator applies a RowMapper to each row of another iterator. Its
+// columns are those of the underlying rows, before they're mapped.
+type mappedIterator struct {
+	iter   Iterator
+	mapper RowMapper
+	err    error
+}
+
+func (it *mappedIterator) Next() (map[string]interface{}, error) {
+	if it.err != nil {
+		return nil, it.err
+	}
+	row, err := it.iter.Next()
+	if err != nil || row == nil {
+		return row, err
+	}
+	mapped, err := it.mapper(row)
+	if err != nil {
+		it.err = fmt.Errorf("could not map row: %w", err)
+		return nil, it.err
+	}
+	return mapped, nil
+}
+
+func (it *mappedIterator) FeatureColumns() []string {
+	return it.iter.FeatureColumns()
+}
+
+func (it *mappedIterator) LabelColumn() string {
+	return it.iter.LabelColumn()
+}
+
+// servedTableIterator reads the rows of a served Iterator as a
+// GenericTableIterator, such as to read several files as one primary table.
+// Its columns are the ones it's given, or the served columns if it isn't given
+// any, and columns missing from a row are nil.
+type servedTableIterator struct {
+	iter    Iterator
+	columns []string
+	limit   int64
+	idx     int64
+	record  GenericRecord
+	err     error
+}
+
+func newServedTableIterator(iter Iterator, columns []string, limit int64) GenericTableIterator {
+	if len(columns) == 0 {
+		columns = append(columns, iter.FeatureColumns()...)
+		if label := iter.LabelColumn(); label != "" {
+			columns = append(columns, label)
+		}
+	}
+	if limit == -1 {
+		limit = math.MaxInt64
+	}
+	return &servedTableIterator{iter: iter, columns: columns, limit: limit}
+}
+
+func (it *servedTableIterator) Next() bool {
+	if it.err != nil || it.idx >= it.limit {
+		return false
+	}
+	row, err := it.iter.Next()
+	if err != nil {
+		it.err = err
+		return false
+	}
+	if row == nil {
+		return false
+	}
+	record := make(GenericRecord, len(it.columns))
+	for i, col := range it.columns {
+		record[i] = row[col]
+	}
+	it.record = record
+	it.idx++
+	return true
+}
+
+func (it *servedTableIterator) Values() GenericRecord {
+	return it.record
+}
+
+func (it *servedTableIterator) Columns() []string {
+	return it.columns
+}
+
+func (it *servedTableIterator) Err() error {
+	return it.err
+}
+
+func (it *servedTableIterator) Close() error {
+	return nil
+}
+
+// directoryIterator serves every file in a directory as one iterator. Directories
+// with only parquet files, the common case, are read without reconciling schemas.
+func directoryIterator(files []filestore.Filepath, store FileStore) (Iterator, error) {
+	for _, file := range files {
+		if file.Ext() != filestore.Parquet {
+			return newMixedFormatIterator(files, store)
+		}
+	}
+	return parquetIteratorOverMultipleFiles(files, store)
+}
+
+type valueKind string
+
+const (
+	numberKind    valueKind = "number"
+	stringKind    valueKind = "string"
+	boolKind      valueKind = "bool"
+	timestampKind valueKind = "timestamp"
+	bytesKind     valueKind = "bytes"
+)
+
+func kindOf(val interface{}) valueKind {
+	switch val.(type) {
+	case int, int32, int64, float32, float64:
+		return numberKind
+	case string:
+		return stringKind
+	case bool:
+		return boolKind
+	case time.Time:
+		return timestampKind
+	case []byte:
+		return bytesKind
+	default:
+		return valueKind(fmt.Sprintf("%T", val))
+	}
+}
+
+// CSV files have no types and JSON has no timestamps, so CSV values and JSON
+// strings are converted to whatever type the other formats use for their column
+var untypedTimestampLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02 15:04:05 -0700 MST", "2006-01-02"}
+
+func convertUntypedValue(val interface{}, kind valueKind) (interface{}, error) {
+	if kind == stringKind {
+		// CSV timestamps are parsed, so they're formatted back as they were read
+		if ts, isTimestamp := val.(time.Time); isTimestamp {
+			return ts.Format(time.RFC3339Nano), nil
+		}
+		return fmt.Sprint(val), nil
+	}
+	str, isString := val.(string)
+	if !isString {
+		return nil, fmt.Errorf("expected %s, got %s", kind, kindOf(val))
+	}
+	switch kind {
+	case numberKind:
+		return strconv.ParseFloat(str, 64)
+	case boolKind:
+		return strconv.ParseBool(str)
+	case timestampKind:
+		for _, layout := range untypedTimestampLayouts {
+			if ts, err := time.Parse(layout, str); err == nil {
+				return ts.UTC(), nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not a recognized timestamp", str)
+	case bytesKind:
+		return []byte(str), nil
+	default:
+		return nil, fmt.Errorf("cannot convert to %s", kind)
+	}
+}
+
+type mixedFormatColumn struct {
+	kind   valueKind
+	source filestore.FileType
+	// Set if the kind was only inferred from an untyped value
+	weak bool
+}
+
+// mixedFormatIterator unions the rows of files with different formats, such as a
+// CSV bootstrap file next to parquet increments. The schema is reconciled up
+// front from the first row of the first file of each format, and each value is
+// checked against it as it's read. A column with values of different types is an
+// error, unless the values are untyped and can be converted.
+type mixedFormatIterator struct {
+	files          []filestore.Filepath
+	store          FileStore
+	fileIdx        int
+	current        Iterator
+	columns        map[string]mixedFormatColumn
+	featureColumns []string
+	labelColumn    string
+}
+
+func newMixedFormatIterator(files []filestore.Filepath, store FileStore) (Iterator, error) {
+	iter := &mixedFormatIterator{
+		files:   files,
+		store:   store,
+		fileIdx: -1,
+		columns: make(map[string]mixedFormatColumn),
+	}
+	// Typed formats go first so that CSV values are converted to their types
+	firstOfFormat := make(map[filestore.FileType]filestore.Filepath)
+	formats := make([]filestore.FileType, 0)
+	for _, file := range files {
+		if _, has := firstOfFormat[file.Ext()]; !has {
+			firstOfFormat[file.Ext()] = file
+			formats = append(formats, file.Ext())
+		}
+	}
+	sort.SliceStable(formats, func(i, j int) bool {
+		return formats[i] != filestore.CSV && formats[j] == filestore.CSV
+	})
+	hasFeature := make(map[string]bool)
+	for _, format := range formats {
+		file := firstOfFormat[format]
+		fileIter, err := iter.open(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range fileIter.FeatureColumns() {
+			if !hasFeature[name] {
+				hasFeature[name] = true
+				iter.featureColumns = append(iter.featureColumns, name)
+			}
+		}
+		if iter.labelColumn == "" {
+			iter.labelColumn = fileIter.LabelColumn()
+		}
+		row, err := fileIter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", file.Key(), err)
+		}
+		if _, err := iter.reconcile(row, format); err != nil {
+			return nil, fmt.Errorf("could not reconcile schema of %s: %w", file.Key(), err)
+		}
+	}
+	return iter, nil
+}
+
+func (m *mixedFormatIterator) open(file filestore.Filepath) (Iterator, error) {
+	b, err := m.store.Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", file.Key(), err)
+	}
+	iter, err := fileIteratorFromBytes(file.Ext(), b)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %w", file.Key(), err)
+	}
+	return iter, nil
+}
+
+// reconcile checks each value in row against its column's type, converting
+// untyped values, and records the types of columns that haven't been seen yet.
+func (m *mixedFormatIterator) reconcile(row map[string]interface{}, format filestore.FileType) (map[string]interface{}, error) {
+	for name, val := range row {
+		if val == nil {
+			continue
+		}
+		kind := kindOf(val)
+		weak := format == filestore.CSV || (format == filestore.JSONL && kind == stringKind)
+		col, has := m.columns[name]
+		switch {
+		case !has, col.weak && !weak:
+			m.columns[name] = mixedFormatColumn{kind: kind, source: format, weak: weak}
+		case col.kind == kind, col.weak && weak:
+		case weak:
+			converted, err := convertUntypedValue(val, col.kind)
+			if err != nil {
+				return nil, fmt.Errorf("column %s is %s in %s files but could not convert %s value %v: %w", name, col.kind, col.source, format, val, err)
+			}
+			row[name] = converted
+		default:
+			return nil, fmt.Errorf("column %s has conflicting types: %s in %s files but %s in %s files", name, col.kind, col.source, kind, format)
+		}
+	}
+	return row, nil
+}
+
+func (m *mixedFormatIterator) Next() (map[string]interface{}, error) {
+	for {
+		if m.current != nil {
+			row, err := m.current.Next()
+			if err != nil {
+				return nil, fmt.Errorf("could not read %s: %w", m.files[m.fileIdx].Key(), err)
+			}
+			if row != nil {
+				return m.reconcile(row, m.files[m.fileIdx].Ext())
+			}
+		}
+		if m.fileIdx+1 >= len(m.files) {
+			return nil, nil
+		}
+		m.fileIdx++
+		iter, err := m.open(m.files[m.fileIdx])
+		if err != nil {
+			return nil, err
+		}
+		m.current = iter
+	}
+}
+
+func (m *mixedFormatIterator) FeatureColumns() []string {
+	return m.featureColumns
+}
+
+func (m *mixedFormatIterator) LabelColumn() string {
+	return m.labelColumn
+}
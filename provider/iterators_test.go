@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -61,7 +63,10 @@ func TestMultipleFileParquetIterator(t *testing.T) {
 	for _, record := range allRecords {
 		records = append(records, record)
 		if len(records) == 5 {
-			parquetRecords := tableSchema.ToParquetRecords(records)
+			parquetRecords, err := tableSchema.ToParquetRecords(records)
+			if err != nil {
+				t.Fatalf("error converting records: %v", err)
+			}
 			buf := new(bytes.Buffer)
 			if err := parquet.Write[any](buf, parquetRecords, schema); err != nil {
 				t.Fatalf("error writing parquet file: %v", err)
@@ -141,3 +146,468 @@ func TestMultipleFileParquetIterator(t *testing.T) {
 		})
 	}
 }
+
+func TestParquetVectorWriteConfig(t *testing.T) {
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "vector", ValueType: VectorType{ScalarType: Float32, Dimension: 128, IsEmbedding: true}},
+		},
+	}
+	numRows := 500
+	records := make([]GenericRecord, numRows)
+	for i := 0; i < numRows; i++ {
+		vector := make([]float32, 128)
+		for j := range vector {
+			// Quantized embeddings only take a handful of distinct values per element
+			vector[j] = float32((i*j)%16) / 16
+		}
+		records[i] = GenericRecord{fmt.Sprintf("entity_%d", i), vector}
+	}
+	plain, err := schema.ToParquetBytes(records, ParquetWriteConfig{})
+	if err != nil {
+		t.Fatalf("could not write parquet file with default config: %v", err)
+	}
+	dict, err := schema.ToParquetBytes(records, ParquetWriteConfig{PageBufferSize: 1024 * 1024, VectorDictionary: true})
+	if err != nil {
+		t.Fatalf("could not write parquet file with dictionary config: %v", err)
+	}
+	if len(dict) >= len(plain) {
+		t.Fatalf("expected dictionary encoded file (%d bytes) to be smaller than plain file (%d bytes)", len(dict), len(plain))
+	}
+	featureIter := &FileStoreFeatureIterator{}
+	for _, b := range [][]byte{plain, dict} {
+		iter, err := parquetIteratorFromBytes(b)
+		if err != nil {
+			t.Fatalf("could not create parquet iterator: %v", err)
+		}
+		for i := 0; i < numRows; i++ {
+			row, err := iter.Next()
+			if err != nil {
+				t.Fatalf("could not read row %d: %v", i, err)
+			}
+			vector, err := featureIter.parseValue(row["vector"])
+			if err != nil {
+				t.Fatalf("could not parse vector in row %d: %v", i, err)
+			}
+			if !reflect.DeepEqual(vector, records[i][1]) {
+				t.Fatalf("row %d: expected %v, got %v", i, records[i][1], vector)
+			}
+		}
+		if row, err := iter.Next(); row != nil || err != nil {
+			t.Fatalf("expected end of file, got %v %v", row, err)
+		}
+	}
+}
+
+func TestParquetIteratorNullValues(t *testing.T) {
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "Feature__score", ValueType: Float64},
+			{Name: "active", ValueType: Bool},
+			{Name: "ts", ValueType: Timestamp},
+		},
+	}
+	ts := time.UnixMilli(1000).UTC()
+	records := []GenericRecord{
+		{"a", nil, true, ts},
+		{"b", 0.5, nil, nil},
+		{"c", nil, nil, nil},
+	}
+	b, err := schema.ToParquetBytes(records, ParquetWriteConfig{})
+	if err != nil {
+		t.Fatalf("could not write parquet file: %v", err)
+	}
+	iter, err := parquetIteratorFromBytes(b)
+	if err != nil {
+		t.Fatalf("could not create parquet iterator: %v", err)
+	}
+	expected := []map[string]interface{}{
+		{"entity": "a", "Feature__score": nil, "active": true, "ts": ts},
+		{"entity": "b", "Feature__score": 0.5, "active": nil, "ts": nil},
+		{"entity": "c", "Feature__score": nil, "active": nil, "ts": nil},
+	}
+	for i, exp := range expected {
+		row, err := iter.Next()
+		if err != nil {
+			t.Fatalf("could not read row %d: %v", i, err)
+		}
+		for name := range exp {
+			if _, has := row[name]; !has {
+				t.Fatalf("row %d: expected column %s to be present, got %v", i, name, row)
+			}
+		}
+		if !reflect.DeepEqual(row, exp) {
+			t.Fatalf("row %d: expected %v, got %v", i, exp, row)
+		}
+	}
+	if row, err := iter.Next(); row != nil || err != nil {
+		t.Fatalf("expected end of file, got %v %v", row, err)
+	}
+}
+
+func TestParquetDecimalRoundTrip(t *testing.T) {
+	// One decimal of each physical type: int32, int64 and bytes
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "rate", ValueType: DecimalType{Precision: 5, Scale: 4}},
+			{Name: "price", ValueType: DecimalType{Precision: 12, Scale: 2}},
+			{Name: "balance", ValueType: DecimalType{Precision: 30, Scale: 6}},
+		},
+	}
+	records := []GenericRecord{
+		{"a", "0.0001", 0.1, "123456789012345678.123456"},
+		{"b", big.NewRat(-1, 8), "-9999999999.99", "-128"},
+		{"c", nil, 19.99, big.NewRat(-1, 1000000)},
+	}
+	b, err := schema.ToParquetBytes(records, ParquetWriteConfig{})
+	if err != nil {
+		t.Fatalf("could not write parquet file: %v", err)
+	}
+	expected := [][]string{
+		{"0.0001", "0.10", "123456789012345678.123456"},
+		{"-0.1250", "-9999999999.99", "-128.000000"},
+		{"", "19.99", "-0.000001"},
+	}
+	iter, err := parquetIteratorFromBytes(b)
+	if err != nil {
+		t.Fatalf("could not create parquet iterator: %v", err)
+	}
+	for i, exp := range expected {
+		row, err := iter.Next()
+		if err != nil {
+			t.Fatalf("could not read row %d: %v", i, err)
+		}
+		for j, name := range []string{"rate", "price", "balance"} {
+			if exp[j] == "" {
+				if row[name] != nil {
+					t.Fatalf("row %d: expected null %s, got %v", i, name, row[name])
+				}
+				continue
+			}
+			value, isRat := row[name].(*big.Rat)
+			if !isRat {
+				t.Fatalf("row %d: expected %s to be read as *big.Rat, got %T", i, name, row[name])
+			}
+			scale := int(schema.Columns[j+1].ValueType.(DecimalType).Scale)
+			if value.FloatString(scale) != exp[j] {
+				t.Fatalf("row %d: expected %s %s, got %s", i, name, exp[j], value.FloatString(scale))
+			}
+		}
+	}
+
+	tooPrecise := []GenericRecord{{"d", "0.00001", nil, nil}}
+	if _, err := schema.ToParquetBytes(tooPrecise, ParquetWriteConfig{}); err == nil || !strings.Contains(err.Error(), "column rate") {
+		t.Fatalf("expected error for a value with too many decimal places, got %v", err)
+	}
+	invalid := TableSchema{Columns: []TableColumn{{Name: "amount", ValueType: DecimalType{Precision: 2, Scale: 3}}}}
+	if _, err := invalid.ToParquetBytes(nil, ParquetWriteConfig{}); err == nil {
+		t.Fatalf("expected error for a scale larger than the precision")
+	}
+}
+
+func TestParquetBytesRoundTrip(t *testing.T) {
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "payload", ValueType: Bytes},
+		},
+	}
+	// Neither payload is valid UTF8
+	records := []GenericRecord{
+		{"a", []byte{0xff, 0xfe, 0x00, 0x80}},
+		{"b", []byte{0xc3, 0x28}},
+		{"c", nil},
+	}
+	b, err := schema.ToParquetBytes(records, ParquetWriteConfig{})
+	if err != nil {
+		t.Fatalf("could not write parquet file: %v", err)
+	}
+	iter, err := newParquetIterator(b, -1)
+	if err != nil {
+		t.Fatalf("could not create parquet iterator: %v", err)
+	}
+	for i, expected := range records {
+		if !iter.Next() {
+			t.Fatalf("expected row %d: %v", i, iter.Err())
+		}
+		row := iter.Values()
+		if _, isString := row[0].(string); !isString {
+			t.Fatalf("row %d: expected entity to be read as a string, got %T", i, row[0])
+		}
+		if expected[1] == nil {
+			if row[1] != nil {
+				t.Fatalf("row %d: expected null payload, got %v", i, row[1])
+			}
+			continue
+		}
+		payload, isBytes := row[1].([]byte)
+		if !isBytes {
+			t.Fatalf("row %d: expected payload to be read as []byte, got %T", i, row[1])
+		}
+		if !bytes.Equal(payload, expected[1].([]byte)) {
+			t.Fatalf("row %d: expected payload %v, got %v", i, expected[1], payload)
+		}
+	}
+	if iter.Next() {
+		t.Fatalf("expected end of file, got %v", iter.Values())
+	}
+}
+
+func TestParquetJSONRoundTrip(t *testing.T) {
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "profile", ValueType: JSON},
+		},
+	}
+	records := []GenericRecord{
+		{"a", map[string]interface{}{
+			"name": "alice",
+			"address": map[string]interface{}{
+				"city": "Oakland",
+				"geo":  []interface{}{37.8, -122.27},
+			},
+			"tags": []interface{}{"new", map[string]interface{}{"tier": 2.0}},
+		}},
+		{"b", []interface{}{1.0, []interface{}{2.0, 3.0}, nil}},
+		{"c", []byte(`{"raw":{"nested":true}}`)},
+		{"d", "just a string"},
+		{"e", nil},
+	}
+	expected := []interface{}{
+		records[0][1],
+		records[1][1],
+		map[string]interface{}{"raw": map[string]interface{}{"nested": true}},
+		"just a string",
+		nil,
+	}
+	b, err := schema.ToParquetBytes(records, ParquetWriteConfig{})
+	if err != nil {
+		t.Fatalf("could not write parquet file: %v", err)
+	}
+	iter, err := newParquetIterator(b, -1)
+	if err != nil {
+		t.Fatalf("could not create parquet iterator: %v", err)
+	}
+	for i := range records {
+		if !iter.Next() {
+			t.Fatalf("expected row %d: %v", i, iter.Err())
+		}
+		if profile := iter.Values()[1]; !reflect.DeepEqual(profile, expected[i]) {
+			t.Fatalf("row %d: expected profile %#v, got %#v", i, expected[i], profile)
+		}
+	}
+	if iter.Next() {
+		t.Fatalf("expected end of file, got %v", iter.Values())
+	}
+
+	rows, err := parquetIteratorFromBytes(b)
+	if err != nil {
+		t.Fatalf("could not create parquet iterator: %v", err)
+	}
+	for i := range records {
+		row, err := rows.Next()
+		if err != nil {
+			t.Fatalf("could not read row %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(row["profile"], expected[i]) {
+			t.Fatalf("row %d: expected profile %#v, got %#v", i, expected[i], row["profile"])
+		}
+	}
+}
+
+func TestFeatureIteratorJSONValues(t *testing.T) {
+	// A JSON object with just a list is stored the same way as a vector
+	value := map[string]interface{}{"list": []interface{}{map[string]interface{}{"element": 1.0}}}
+	jsonIter := &FileStoreFeatureIterator{valueType: JSON}
+	parsed, err := jsonIter.parseValue(value)
+	if err != nil {
+		t.Fatalf("could not parse JSON value: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, value) {
+		t.Fatalf("expected JSON value %#v to be kept, got %#v", value, parsed)
+	}
+	vectorIter := &FileStoreFeatureIterator{valueType: VectorType{ScalarType: Float32, Dimension: 1}}
+	parsed, err = vectorIter.parseValue(value)
+	if err != nil {
+		t.Fatalf("could not parse vector: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, []float32{1}) {
+		t.Fatalf("expected vector [1], got %#v", parsed)
+	}
+	if _, err := vectorIter.parseValue(map[string]interface{}{"name": "alice"}); err == nil {
+		t.Fatalf("expected a map without a list not to parse as a vector")
+	}
+}
+
+func TestParquetJSONInvalidText(t *testing.T) {
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "profile", ValueType: JSON},
+		},
+	}
+	records := []GenericRecord{{"a", []byte(`{"unterminated": `)}}
+	if _, err := schema.ToParquetBytes(records, ParquetWriteConfig{}); err == nil {
+		t.Fatalf("expected invalid JSON to fail to write")
+	}
+}
+
+func TestCSVFileIterator(t *testing.T) {
+	b := []byte("entity,Feature__value,Label__label,name\na,1,0.5,x\nb,2,1.5,y\n")
+	iter, err := csvIteratorFromBytes(b)
+	if err != nil {
+		t.Fatalf("could not create csv iterator: %v", err)
+	}
+	if !reflect.DeepEqual(iter.FeatureColumns(), []string{"Feature__value"}) || iter.LabelColumn() != "Label__label" {
+		t.Fatalf("unexpected columns: %v %s", iter.FeatureColumns(), iter.LabelColumn())
+	}
+	expected := []map[string]interface{}{
+		{"entity": "a", "Feature__value": 1, "Label__label": 0.5, "name": "x"},
+		{"entity": "b", "Feature__value": 2, "Label__label": 1.5, "name": "y"},
+	}
+	for _, exp := range expected {
+		row, err := iter.Next()
+		if err != nil {
+			t.Fatalf("could not read csv row: %v", err)
+		}
+		if !reflect.DeepEqual(row, exp) {
+			t.Fatalf("expected %v, got %v", exp, row)
+		}
+	}
+	if row, err := iter.Next(); row != nil || err != nil {
+		t.Fatalf("expected end of file, got %v %v", row, err)
+	}
+	numRows, err := getCSVNumRows(b)
+	if err != nil || numRows != 2 {
+		t.Fatalf("expected 2 rows, got %d: %v", numRows, err)
+	}
+}
+
+func TestCSVTypeInference(t *testing.T) {
+	ts := time.Date(2023, 1, 2, 3, 4, 5, 0, time.FixedZone("", 2*60*60))
+	b := []byte("int,float,bool,ts,str\n-3,2.5,True,2023-01-02T03:04:05+02:00,t\n")
+	iter, err := newCSVIterator(b, -1)
+	if err != nil {
+		t.Fatalf("could not create csv iterator: %v", err)
+	}
+	if !iter.Next() {
+		t.Fatalf("expected a row: %v", iter.Err())
+	}
+	values := iter.Values()
+	expected := GenericRecord{-3, 2.5, true, ts, "t"}
+	for i, exp := range expected {
+		if i == 3 {
+			if actual, ok := values[i].(time.Time); !ok || !actual.Equal(ts) {
+				t.Fatalf("expected timestamp %v, got %v", ts, values[i])
+			}
+			continue
+		}
+		if !reflect.DeepEqual(values[i], exp) {
+			t.Fatalf("column %d: expected %#v, got %#v", i, exp, values[i])
+		}
+	}
+}
+
+func TestCSVTimestampLayout(t *testing.T) {
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "ts", ValueType: Timestamp},
+		},
+	}
+	layout := "02/01/2006 15:04:05"
+	records := []GenericRecord{
+		{"a", time.Date(2023, time.March, 4, 5, 6, 7, 0, time.UTC)},
+		{"b", time.Date(2023, time.December, 31, 23, 59, 59, 0, time.UTC)},
+		{"c", nil},
+	}
+	b, err := schema.ToCSVBytes(records, CSVConfig{TimestampLayout: layout})
+	if err != nil {
+		t.Fatalf("could not write csv file: %v", err)
+	}
+	iter, err := newCSVIteratorWithSchema(b, -1, schema, CSVConfig{TimestampLayout: layout})
+	if err != nil {
+		t.Fatalf("could not create csv iterator: %v", err)
+	}
+	for i, expected := range records {
+		if !iter.Next() {
+			t.Fatalf("expected row %d: %v", i, iter.Err())
+		}
+		if !reflect.DeepEqual(iter.Values(), expected) {
+			t.Fatalf("row %d: expected %v, got %v", i, expected, iter.Values())
+		}
+	}
+	if iter.Next() {
+		t.Fatalf("expected end of file, got %v", iter.Values())
+	}
+
+	// The default RFC3339 layout doesn't match, so reading fails rather than
+	// returning the wrong time
+	iter, err = newCSVIteratorWithSchema(b, -1, schema, CSVConfig{})
+	if err != nil {
+		t.Fatalf("could not create csv iterator: %v", err)
+	}
+	if iter.Next() {
+		t.Fatalf("expected mismatched layout to fail, got %v", iter.Values())
+	}
+	if err := iter.Err(); err == nil || !strings.Contains(err.Error(), "column ts") || !strings.Contains(err.Error(), time.RFC3339) {
+		t.Fatalf("expected error naming the column and layout, got %v", err)
+	}
+}
+
+func TestParquetIteratorStats(t *testing.T) {
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+		},
+	}
+	numRows := 100
+	records := make([]GenericRecord, numRows)
+	for i := range records {
+		records[i] = GenericRecord{fmt.Sprintf("entity_%d", i), i}
+	}
+	b, err := schema.ToParquetBytes(records, ParquetWriteConfig{})
+	if err != nil {
+		t.Fatalf("could not write parquet file: %v", err)
+	}
+	checkStats := func(stats IteratorStats) {
+		if stats.RowsRead != int64(numRows) {
+			t.Fatalf("expected %d rows read, got %d", numRows, stats.RowsRead)
+		}
+		// The footer can be read more than once, but not the whole file
+		if stats.BytesRead <= 0 || stats.BytesRead > 2*int64(len(b)) {
+			t.Fatalf("expected between 1 and %d bytes read, got %d", 2*len(b), stats.BytesRead)
+		}
+	}
+
+	tableIter, err := newParquetIterator(b, -1)
+	if err != nil {
+		t.Fatalf("could not create parquet iterator: %v", err)
+	}
+	for tableIter.Next() {
+	}
+	if err := tableIter.Err(); err != nil {
+		t.Fatalf("could not iterate: %v", err)
+	}
+	checkStats(tableIter.(StatsIterator).Stats())
+
+	iter, err := parquetIteratorFromBytes(b)
+	if err != nil {
+		t.Fatalf("could not create parquet iterator: %v", err)
+	}
+	for {
+		row, err := iter.Next()
+		if err != nil {
+			t.Fatalf("could not read row: %v", err)
+		}
+		if row == nil {
+			break
+		}
+	}
+	checkStats(iter.(StatsIterator).Stats())
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -45,7 +47,7 @@ func (q pandasOfflineQueries) trainingSetCreate(def TrainingSetDef, featureSchem
 		} else {
 			featureWindowQuery = fmt.Sprintf("SELECT * FROM (SELECT %s as t%d_entity, %s as %s, %s as t%d_ts FROM source_%d) ORDER BY t%d_ts ASC", featureSchemas[i].Entity, i+1, featureSchemas[i].Value, featureColumnName, featureSchemas[i].TS, i+1, i+1, i+1)
 		}
-		featureJoinQuery := fmt.Sprintf("LEFT OUTER JOIN (%s) t%d ON (t%d_entity = entity AND t%d_ts <= label_ts)", featureWindowQuery, i+1, i+1, i+1)
+		featureJoinQuery := fmt.Sprintf("%s (%s) t%d ON (t%d_entity = entity%s)", def.JoinPolicy.featureJoin(), featureWindowQuery, i+1, i+1, def.JoinStrategy.featureTimeCondition(fmt.Sprintf("t%d_ts", i+1), "label_ts"))
 		joinQueries = append(joinQueries, featureJoinQuery)
 		featureTimestamps = append(featureTimestamps, fmt.Sprintf("t%d_ts", i+1))
 	}
@@ -95,9 +97,18 @@ type K8sOfflineStore struct {
 	store    FileStore
 	logger   *zap.SugaredLogger
 	query    *pandasOfflineQueries
+	// Defaults for the parquet outputs of transformations and training sets
+	parquetConfig ParquetWriteConfig
+	// Names the directories table data is written to. Defaults to the time
+	// each is created.
+	names NameGenerator
 	BaseProvider
 }
 
+func (k8s *K8sOfflineStore) SetNameGenerator(names NameGenerator) {
+	k8s.names = names
+}
+
 func (k8s *K8sOfflineStore) AsOfflineStore() (OfflineStore, error) {
 	return k8s, nil
 }
@@ -214,12 +225,17 @@ func k8sOfflineStoreFactory(config pc.SerializedConfig) (Provider, error) {
 		return nil, err
 	}
 	logger.Debugf("Store type: %s", k8.StoreType)
+	executor = stageLocalRuns(executor, store)
 	queries := pandasOfflineQueries{}
 	k8sOfflineStore := K8sOfflineStore{
 		executor: executor,
 		store:    store,
 		logger:   logger,
 		query:    &queries,
+		parquetConfig: ParquetWriteConfig{
+			Compression:  ParquetCompression(k8.ParquetCompression),
+			RowGroupSize: k8.ParquetRowGroupSize,
+		},
 		BaseProvider: BaseProvider{
 			ProviderType:   "K8S_OFFLINE",
 			ProviderConfig: config,
@@ -234,16 +250,51 @@ type Executor interface {
 
 type LocalExecutor struct {
 	scriptPath string
+	workingDir string
+	// Where each run's staging directory comes from, if not workingDir
+	staging stagingStore
+}
+
+// stagingStore is a file store that creates local directories for staging
+// files, such as LocalFileStore
+type stagingStore interface {
+	StagingDir() (string, error)
+}
+
+// stageLocalRuns has a local executor without a working directory of its own
+// stage its runs in store's staging directories, if it has them, so that they
+// go under the store's TempDir and are cleaned up with it. Other executors are
+// returned as they are.
+func stageLocalRuns(executor Executor, store FileStore) Executor {
+	local, ok := executor.(LocalExecutor)
+	if !ok || local.workingDir != "" {
+		return executor
+	}
+	staging, ok := store.(stagingStore)
+	if !ok {
+		return executor
+	}
+	local.staging = staging
+	return local
 }
 
+// ExecuteScript runs the script in a new staging directory, where it stages any
+// files it downloads or writes before uploading. The directory is removed once
+// the script exits, and the environment is only passed to the script, so
+// concurrent runs don't interfere with each other.
 func (local LocalExecutor) ExecuteScript(envVars map[string]string, args *metadata.KubernetesArgs) error {
 	envVars["MODE"] = "local"
-	for key, value := range envVars {
-		if err := os.Setenv(key, value); err != nil {
-			return fmt.Errorf("could not set env variable: %s: %w", key, err)
-		}
+	stagingDir, err := local.stagingDir()
+	if err != nil {
+		return err
 	}
+	defer os.RemoveAll(stagingDir)
 	cmd := exec.Command("python3", local.scriptPath)
+	cmd.Dir = stagingDir
+	cmd.Env = append(os.Environ(), fmt.Sprintf("LOCAL_DATA_PATH=%s", stagingDir))
+	for key, value := range envVars {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	}
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
@@ -252,8 +303,23 @@ func (local LocalExecutor) ExecuteScript(envVars map[string]string, args *metada
 	return nil
 }
 
+// stagingDir creates the directory a run is staged in, under the executor's
+// working directory unless it stages in a file store's directories
+func (local LocalExecutor) stagingDir() (string, error) {
+	if local.staging != nil {
+		return local.staging.StagingDir()
+	}
+	dir, err := os.MkdirTemp(local.workingDir, "featureform-local-")
+	if err != nil {
+		return "", fmt.Errorf("could not create staging directory: %w", err)
+	}
+	return dir, nil
+}
+
 type LocalExecutorConfig struct {
 	ScriptPath string
+	// Where each run's staging directory is created. Defaults to os.TempDir.
+	WorkingDir string
 }
 
 func (config *LocalExecutorConfig) Serialize() ([]byte, error) {
@@ -281,8 +347,15 @@ func NewLocalExecutor(config Config, logger *zap.SugaredLogger) (Executor, error
 	if err != nil {
 		return nil, fmt.Errorf("could not find script path: %v", err)
 	}
+	// The script runs from its staging directory, so a relative path would no
+	// longer point at it
+	scriptPath, err := filepath.Abs(localConfig.ScriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve script path: %v", err)
+	}
 	return LocalExecutor{
-		scriptPath: localConfig.ScriptPath,
+		scriptPath: scriptPath,
+		workingDir: localConfig.WorkingDir,
 	}, nil
 }
 
@@ -410,6 +483,12 @@ func (tbl *BlobOfflineTable) WriteBatch(records []ResourceRecord) error {
 			return fmt.Errorf("could not append records to existing file: %w", err)
 		}
 	}
+	// The whole file is rewritten, so a later record for the same entity and
+	// timestamp replaces the earlier one as it would with Write
+	records, err = latestResourceRecords(records)
+	if err != nil {
+		return err
+	}
 	data, err := tbl.writeRecordsToParquetBytes(records)
 	if err != nil {
 		return fmt.Errorf("could not write records to parquet bytes: %w", err)
@@ -502,6 +581,19 @@ func blobRegisterResourceFromSourceTable(id ResourceID, sourceSchema ResourceSch
 		logger.Errorw("Failure checking ID", "error", err)
 		return nil, fmt.Errorf("ID check failed: %v", err)
 	}
+	if sourceSchema.Filter != "" {
+		names, err := blobSourceTableColumns(sourceSchema.SourceTable, store)
+		if err != nil {
+			return nil, fmt.Errorf("get source columns: %w", err)
+		}
+		columns := make([]TableColumn, len(names))
+		for i, name := range names {
+			columns[i] = TableColumn{Name: name}
+		}
+		if err := validateFilter(sourceSchema.Filter, columns); err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+	}
 	destination, err := store.CreateFilePath(id.ToFilestorePath())
 	if err != nil {
 		return nil, fmt.Errorf("could not create file path: %w", err)
@@ -561,14 +653,11 @@ func (tbl *FileStorePrimaryTable) WriteBatch(records []GenericRecord) error {
 			return fmt.Errorf("could not append records to existing file: %w", err)
 		}
 	}
-	buf := new(bytes.Buffer)
-	schema := parquet.SchemaOf(tbl.schema.Interface())
-	parquetRecords := tbl.schema.ToParquetRecords(records)
-	err = parquet.Write[any](buf, parquetRecords, schema)
+	b, err := tbl.schema.ToParquetBytes(records, ParquetWriteConfig{})
 	if err != nil {
-		return fmt.Errorf("could not write parquet file to bytes: %v", err)
+		return err
 	}
-	return tbl.store.Write(destination, buf.Bytes())
+	return tbl.store.Write(destination, b)
 }
 
 // TODO: Add unit tests for this method
@@ -612,29 +701,15 @@ func (tbl *FileStorePrimaryTable) GetName() string {
 }
 
 func (tbl *FileStorePrimaryTable) IterateSegment(n int64) (GenericTableIterator, error) {
-	sources := []filestore.Filepath{tbl.source}
-	if tbl.source.IsDir() {
-		// The key should only be a directory in the case of transformations.
-		if !tbl.isTransformation {
-			return nil, fmt.Errorf("expected a file but got a directory: %s", tbl.source.Key())
-		}
-		// The file structure in cloud storage for transformations is /featureform/Transformation/<NAME>/<VARIANT>
-		// but there is an additional directory that's named using a timestamp that contains the transformation file
-		// we need to access. NewestFileOfType will recursively search for the newest file of the given type (i.e.
-		// parquet) given a path (i.e. `key`).
-		transformations, err := tbl.store.List(tbl.source, filestore.Parquet)
-		if err != nil {
-			return nil, fmt.Errorf("could not find newest file of type %s: %w", filestore.Parquet, err)
-		}
-		groups, err := filestore.NewFilePathGroup(transformations, filestore.DateTimeDirectoryGrouping)
-		if err != nil {
-			return nil, fmt.Errorf("could not group files by datetime: %w", err)
-		}
-		newestFiles, err := groups.GetFirst()
-		if err != nil {
-			return nil, fmt.Errorf("could not get newest files: %w", err)
-		}
-		sources = newestFiles
+	if tbl.schema.SourceFormat == icebergSourceFormat {
+		return newIcebergTableIterator(tbl.store, tbl.source, n)
+	}
+	if tbl.schema.NewestFiles > 0 {
+		return tbl.iterateNewestFiles(n)
+	}
+	sources, err := tbl.files()
+	if err != nil {
+		return nil, err
 	}
 	fmt.Printf("Sources: %d found\n", len(sources))
 	fmt.Printf("Source %s extension %s\n", sources[0].ToURI(), string(sources[0].Ext()))
@@ -650,18 +725,143 @@ func (tbl *FileStorePrimaryTable) IterateSegment(n int64) (GenericTableIterator,
 		if err != nil {
 			return nil, fmt.Errorf("could not read file: %w", err)
 		}
-		return newCSVIterator(b, n)
+		return newCSVIteratorWithSchema(b, n, tbl.schema, CSVConfig{})
 	default:
 		return nil, fmt.Errorf("unsupported file type: %s", sources[0].Ext())
 	}
 }
 
+// iterateNewestFiles serves the table's newest snapshots one after another as
+// a single table
+func (tbl *FileStorePrimaryTable) iterateNewestFiles(limit int64) (GenericTableIterator, error) {
+	fileType, err := tbl.snapshotFileType()
+	if err != nil {
+		return nil, err
+	}
+	iter, err := ServeNewestFiles(tbl.store, tbl.source, fileType, tbl.schema.NewestFiles)
+	if err != nil {
+		return nil, fmt.Errorf("could not serve newest snapshots: %w", err)
+	}
+	columns := make([]string, len(tbl.schema.Columns))
+	for i, col := range tbl.schema.Columns {
+		columns[i] = col.Name
+	}
+	return newServedTableIterator(iter, columns, limit), nil
+}
+
+// snapshotFileType returns the type of the snapshots in the table's directory,
+// which are parquet unless the directory only has CSV files
+func (tbl *FileStorePrimaryTable) snapshotFileType() (filestore.FileType, error) {
+	newest, err := tbl.store.NewestNFilesOfType(tbl.source, filestore.Parquet, 1)
+	if err != nil {
+		return "", fmt.Errorf("could not list snapshots in %s: %w", tbl.source.ToURI(), err)
+	}
+	if len(newest) == 0 {
+		return filestore.CSV, nil
+	}
+	return filestore.Parquet, nil
+}
+
+// files returns the files that hold the table's rows, which for a
+// transformation are the ones its newest run wrote, and for a directory of
+// snapshots are its newest ones.
+func (tbl *FileStorePrimaryTable) files() ([]filestore.Filepath, error) {
+	if tbl.schema.NewestFiles > 0 {
+		fileType, err := tbl.snapshotFileType()
+		if err != nil {
+			return nil, err
+		}
+		newest, err := tbl.store.NewestNFilesOfType(tbl.source, fileType, tbl.schema.NewestFiles)
+		if err != nil {
+			return nil, fmt.Errorf("could not get newest snapshots in %s: %w", tbl.source.ToURI(), err)
+		}
+		if len(newest) == 0 {
+			return nil, fmt.Errorf("no snapshots in %s", tbl.source.ToURI())
+		}
+		return newest, nil
+	}
+	if !tbl.source.IsDir() {
+		return []filestore.Filepath{tbl.source}, nil
+	}
+	// The key should only be a directory in the case of transformations.
+	if !tbl.isTransformation {
+		return nil, fmt.Errorf("expected a file but got a directory: %s", tbl.source.Key())
+	}
+	// The file structure in cloud storage for transformations is /featureform/Transformation/<NAME>/<VARIANT>
+	// but there is an additional directory that's named using a timestamp that contains the transformation file
+	// we need to access. NewestFileOfType will recursively search for the newest file of the given type (i.e.
+	// parquet) given a path (i.e. `key`).
+	transformations, err := tbl.store.List(tbl.source, filestore.Parquet)
+	if err != nil {
+		return nil, fmt.Errorf("could not find newest file of type %s: %w", filestore.Parquet, err)
+	}
+	// Transformations can be output as CSV instead of parquet
+	if len(transformations) == 0 {
+		transformations, err = tbl.store.List(tbl.source, filestore.CSV)
+		if err != nil {
+			return nil, fmt.Errorf("could not find newest file of type %s: %w", filestore.CSV, err)
+		}
+	}
+	groups, err := filestore.NewFilePathGroup(transformations, filestore.DateTimeDirectoryGrouping)
+	if err != nil {
+		return nil, fmt.Errorf("could not group files by datetime: %w", err)
+	}
+	newestFiles, err := groups.GetFirst()
+	if err != nil {
+		return nil, fmt.Errorf("could not get newest files: %w", err)
+	}
+	return newestFiles, nil
+}
+
+// NumRows counts the rows of each of the table's files, which for a
+// transformation may have been written as many parts.
 func (tbl *FileStorePrimaryTable) NumRows() (int64, error) {
-	src, err := tbl.GetSource()
+	if tbl.schema.SourceFormat == icebergSourceFormat {
+		src, err := tbl.GetSource()
+		if err != nil {
+			return 0, err
+		}
+		return icebergNumRows(tbl.store, src)
+	}
+	files, err := tbl.files()
 	if err != nil {
 		return 0, err
 	}
-	return tbl.store.NumRows(src)
+	return NumRowsOfFiles(tbl.store, files, cfg.GetNumRowsParallelism())
+}
+
+// SizeBytes sums the sizes of the table's files as they're stored
+func (tbl *FileStorePrimaryTable) SizeBytes() (int64, error) {
+	if tbl.schema.SourceFormat == icebergSourceFormat {
+		return 0, fmt.Errorf("size of iceberg tables is not supported")
+	}
+	files, err := tbl.files()
+	if err != nil {
+		return 0, err
+	}
+	total := int64(0)
+	for _, file := range files {
+		size, err := fileSize(tbl.store, file)
+		if err != nil {
+			return 0, fmt.Errorf("could not get size of %s: %w", file.Key(), err)
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// fileSize gets the size of a file without reading it if the store can read
+// files in ranges, and otherwise reads all of it
+func fileSize(store FileStore, path filestore.Filepath) (int64, error) {
+	if ranged, ok := store.(rangeReadableStore); ok {
+		_, size, err := ranged.openReaderAt(path)
+		return size, err
+	}
+	b, err := store.Read(path)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(b)), nil
 }
 
 func (tbl *FileStorePrimaryTable) GetSource() (filestore.Filepath, error) {
@@ -677,6 +877,30 @@ func (k8s *K8sOfflineStore) RegisterPrimaryFromSourceTable(id ResourceID, source
 	return blobRegisterPrimary(id, sourcePath, k8s.logger, k8s.store)
 }
 
+func (k8s *K8sOfflineStore) SourceTableColumns(sourcePath string) ([]string, error) {
+	return blobSourceTableColumns(sourcePath, k8s.store)
+}
+
+// blobSourceTableColumns reads the columns of the file at sourcePath, the way
+// they'd be read once it's registered as a primary table. A directory is read
+// as a transformation's output.
+func blobSourceTableColumns(sourcePath string, store FileStore) ([]string, error) {
+	sourceFilePath, err := filestore.NewEmptyFilepath(store.FilestoreType())
+	if err != nil {
+		return nil, fmt.Errorf("could not create empty filepath: %w", err)
+	}
+	if err := sourceFilePath.ParseFilePath(sourcePath); err != nil {
+		return nil, fmt.Errorf("could not parse source path: %w", err)
+	}
+	table := &FileStorePrimaryTable{store: store, source: sourceFilePath, schema: TableSchema{SourceTable: sourcePath}, isTransformation: sourceFilePath.IsDir()}
+	iter, err := table.IterateSegment(0)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	return iter.Columns(), nil
+}
+
 func blobRegisterPrimary(id ResourceID, sourcePath string, logger *zap.SugaredLogger, store FileStore) (PrimaryTable, error) {
 	sourceFilePath, err := filestore.NewEmptyFilepath(store.FilestoreType())
 	if err != nil {
@@ -733,6 +957,91 @@ func blobRegisterPrimary(id ResourceID, sourcePath string, logger *zap.SugaredLo
 	return &FileStorePrimaryTable{store, sourceFilePath, schema, false, id}, nil
 }
 
+// RegisterPrimaryFromNewestFiles registers the directory dir, which a snapshot
+// of the source is written to each day or run, as a primary table made up of
+// its n newest files.
+func (k8s *K8sOfflineStore) RegisterPrimaryFromNewestFiles(id ResourceID, dir string, n int) (PrimaryTable, error) {
+	return blobRegisterPrimaryFromNewestFiles(id, dir, n, k8s.logger, k8s.store)
+}
+
+func blobRegisterPrimaryFromNewestFiles(id ResourceID, dir string, n int, logger *zap.SugaredLogger, store FileStore) (PrimaryTable, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("number of newest files must be positive, got %d", n)
+	}
+	dirPath, err := filestore.NewEmptyFilepath(store.FilestoreType())
+	if err != nil {
+		return nil, fmt.Errorf("could not create empty filepath: %w", err)
+	}
+	if err := dirPath.ParseDirPath(dir); err != nil {
+		return nil, fmt.Errorf("could not parse snapshot directory: %w", err)
+	}
+	if exists, err := store.Exists(dirPath); err != nil {
+		return nil, fmt.Errorf("error checking if source exists: %v", err)
+	} else if !exists {
+		return nil, fmt.Errorf("source directory does not exist")
+	}
+	filepath, err := store.CreateFilePath(id.ToFilestorePath())
+	if err != nil {
+		return nil, fmt.Errorf("could not create file path: %w", err)
+	}
+	if exists, err := store.Exists(filepath); err != nil {
+		return nil, fmt.Errorf("error checking if primary exists: %v", err)
+	} else if exists {
+		return nil, fmt.Errorf("primary already exists")
+	}
+	schema := TableSchema{
+		SourceTable: dir,
+		NewestFiles: n,
+	}
+	data, err := schema.Serialize()
+	if err != nil {
+		return nil, fmt.Errorf("error serializing primary schema: %s: %s", schema, err)
+	}
+	if err := store.Write(filepath, data); err != nil {
+		return nil, err
+	}
+	logger.Debugw("Registered newest files as primary table", "id", id, "dir", dir, "files", n)
+	return &FileStorePrimaryTable{store, dirPath, schema, false, id}, nil
+}
+
+// RegisterPrimaryFromIcebergTable registers the Iceberg table at location, the
+// URI of its root directory, as a primary table. Only the reference to the table
+// is stored, and its current snapshot is resolved whenever it's read.
+func (k8s *K8sOfflineStore) RegisterPrimaryFromIcebergTable(id ResourceID, location string) (PrimaryTable, error) {
+	tablePath, err := filestore.NewEmptyFilepath(k8s.store.FilestoreType())
+	if err != nil {
+		return nil, fmt.Errorf("could not create empty filepath: %w", err)
+	}
+	if err := tablePath.ParseDirPath(location); err != nil {
+		return nil, fmt.Errorf("could not parse iceberg table location: %w", err)
+	}
+	if _, err := loadIcebergTable(k8s.store, tablePath); err != nil {
+		return nil, fmt.Errorf("could not load iceberg table: %w", err)
+	}
+	filepath, err := k8s.store.CreateFilePath(id.ToFilestorePath())
+	if err != nil {
+		return nil, fmt.Errorf("could not create file path: %w", err)
+	}
+	if exists, err := k8s.store.Exists(filepath); err != nil {
+		return nil, fmt.Errorf("error checking if primary exists: %v", err)
+	} else if exists {
+		return nil, fmt.Errorf("primary already exists")
+	}
+	schema := TableSchema{
+		SourceTable:  location,
+		SourceFormat: icebergSourceFormat,
+	}
+	data, err := schema.Serialize()
+	if err != nil {
+		return nil, fmt.Errorf("error serializing primary schema: %s: %s", schema, err)
+	}
+	if err := k8s.store.Write(filepath, data); err != nil {
+		return nil, err
+	}
+	k8s.logger.Debugw("Registered iceberg table as primary table", "id", id, "location", location)
+	return &FileStorePrimaryTable{k8s.store, tablePath, schema, false, id}, nil
+}
+
 func (k8s *K8sOfflineStore) CreateTransformation(config TransformationConfig) error {
 	return k8s.transformation(config, false)
 }
@@ -784,6 +1093,22 @@ func (k8s K8sOfflineStore) getDFArgs(outputURI string, code string, mapping []So
 	return envVars
 }
 
+// addParquetArgs sets how the pandas runner writes parquet outputs, from
+// config and then the store's defaults
+func (k8s *K8sOfflineStore) addParquetArgs(envVars map[string]string, config ParquetWriteConfig) (map[string]string, error) {
+	config = config.WithDefaults(k8s.parquetConfig)
+	if _, err := config.Compression.codec(); err != nil {
+		return nil, err
+	}
+	if config.Compression != "" {
+		envVars["PARQUET_COMPRESSION"] = string(config.Compression)
+	}
+	if config.RowGroupSize > 0 {
+		envVars["PARQUET_ROW_GROUP_SIZE"] = strconv.FormatInt(config.RowGroupSize, 10)
+	}
+	return envVars, nil
+}
+
 func addResourceID(envVars map[string]string, id ResourceID) map[string]string {
 	envVars["RESOURCE_NAME"] = id.Name
 	envVars["RESOURCE_VARIANT"] = id.Variant
@@ -797,12 +1122,16 @@ func (k8s *K8sOfflineStore) sqlTransformation(config TransformationConfig, isUpd
 		k8s.logger.Errorw("Could not generate updated query for k8s transformation", err)
 		return err
 	}
+	outputFormat, err := config.outputFormat()
+	if err != nil {
+		return err
+	}
 
 	filepath, err := k8s.store.CreateFilePath(fileStoreResourcePath(config.TargetTableID))
 	if err != nil {
 		return fmt.Errorf("could not create file path: %w", err)
 	}
-	transformationDestinationExactPath, err := k8s.store.NewestFileOfType(filepath, filestore.Parquet)
+	transformationDestinationExactPath, err := k8s.store.NewestFileOfType(filepath, outputFormat)
 	if err != nil {
 		k8s.logger.Errorw("Could not get newest blob", "location", filepath.Key(), "error", err)
 		return fmt.Errorf("could not get newest blob: %s: %v", filepath.Key(), err)
@@ -817,7 +1146,12 @@ func (k8s *K8sOfflineStore) sqlTransformation(config TransformationConfig, isUpd
 	}
 	k8s.logger.Debugw("Running SQL transformation", "target_table", config.TargetTableID, "query", config.Query)
 	runnerArgs := k8s.pandasRunnerArgs(filepath.ToURI(), updatedQuery, sources, Transform)
+	runnerArgs["OUTPUT_FORMAT"] = string(outputFormat)
 	runnerArgs = addResourceID(runnerArgs, config.TargetTableID)
+	runnerArgs, err = k8s.addParquetArgs(runnerArgs, config.ParquetConfig)
+	if err != nil {
+		return err
+	}
 
 	args, err := k8s.checkArgs(config.Args)
 	if err != nil {
@@ -845,6 +1179,10 @@ func (k8s *K8sOfflineStore) dfTransformation(config TransformationConfig, isUpda
 	if err != nil {
 		return err
 	}
+	outputFormat, err := config.outputFormat()
+	if err != nil {
+		return err
+	}
 	filepath, err := k8s.store.CreateFilePath(fileStoreResourcePath(config.TargetTableID))
 	if err != nil {
 		return fmt.Errorf("could not create file path: %w", err)
@@ -874,7 +1212,12 @@ func (k8s *K8sOfflineStore) dfTransformation(config TransformationConfig, isUpda
 	}
 
 	dfArgs := k8s.getDFArgs(filepath.ToURI(), transformationFilepath.Key(), config.SourceMapping, sources)
+	dfArgs["OUTPUT_FORMAT"] = string(outputFormat)
 	dfArgs = addResourceID(dfArgs, config.TargetTableID)
+	dfArgs, err = k8s.addParquetArgs(dfArgs, config.ParquetConfig)
+	if err != nil {
+		return err
+	}
 	k8s.logger.Debugw("Running DF transformation", "target_table", config.TargetTableID)
 	args, err := k8s.checkArgs(config.Args)
 	if err != nil {
@@ -1004,10 +1347,30 @@ func (k8s *K8sOfflineStore) GetTransformationTable(id ResourceID) (Transformatio
 		k8s.logger.Errorw("Could not create empty filepath", "error", err, "storeType", k8s.store.FilestoreType(), "transformationPath", transformationFilepath.ToURI())
 		return nil, err
 	}
+	if exists, err := fileStoreTransformationExists(k8s.store, transformationFilepath); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, &TableNotFound{id.Name, id.Variant}
+	}
 	// TODO: populate schema
 	return &FileStorePrimaryTable{k8s.store, transformationFilepath, TableSchema{}, true, id}, nil
 }
 
+// fileStoreTransformationExists reports whether a transformation has written
+// any output to dir, which it writes as Parquet or CSV.
+func fileStoreTransformationExists(store FileStore, dir filestore.Filepath) (bool, error) {
+	for _, fileType := range []filestore.FileType{filestore.Parquet, filestore.CSV} {
+		files, err := store.List(dir, fileType)
+		if err != nil {
+			return false, fmt.Errorf("could not list %s files in %s: %w", fileType, dir.ToURI(), err)
+		}
+		if len(files) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (k8s *K8sOfflineStore) UpdateTransformation(config TransformationConfig) error {
 	return k8s.transformation(config, true)
 }
@@ -1025,6 +1388,11 @@ func fileStoreGetPrimary(id ResourceID, store FileStore, logger *zap.SugaredLogg
 		return nil, fmt.Errorf("could not create file path: %w", err)
 	}
 	logger.Debugw("Getting primary table", "id", id, "resourceKey", filepath.Key())
+	if exists, err := store.Exists(filepath); err != nil {
+		return nil, fmt.Errorf("could not check if primary table exists: %w", err)
+	} else if !exists {
+		return nil, &TableNotFound{id.Name, id.Variant}
+	}
 	table, err := store.Read(filepath)
 	logger.Debugw("Read primary table", "table", string(table), "error", err)
 	if err != nil {
@@ -1046,7 +1414,55 @@ func fileStoreGetPrimary(id ResourceID, store FileStore, logger *zap.SugaredLogg
 }
 
 func (k8s *K8sOfflineStore) CreateResourceTable(id ResourceID, schema TableSchema) (OfflineTable, error) {
-	return nil, fmt.Errorf("not implemented")
+	return fileStoreCreateResourceTable(id, schema, k8s.store, k8s.names)
+}
+
+func fileStoreCreateResourceTable(id ResourceID, schema TableSchema, store FileStore, names NameGenerator) (OfflineTable, error) {
+	if err := id.check(Feature, Label); err != nil {
+		return nil, fmt.Errorf("ID check failed: %v", err)
+	}
+	resourceTableFilepath, err := store.CreateFilePath(id.ToFilestorePath())
+	if err != nil {
+		return nil, fmt.Errorf("could not create file path due to error %w (store type: %s; path: %s)", err, store.FilestoreType(), id.ToFilestorePath())
+	}
+	if exists, err := store.Exists(resourceTableFilepath); err != nil {
+		return nil, fmt.Errorf("could not check if table exists: %v", err)
+	} else if exists {
+		return nil, &TableAlreadyExists{id.Name, id.Variant}
+	}
+	table := BlobOfflineTable{
+		store: store,
+		schema: ResourceSchema{
+			// Create a URI in the same directory as the resource table that follows the naming convention <VARIANT>_src.parquet
+			SourceTable: fmt.Sprintf("%s/%s/src.parquet", resourceTableFilepath.ToURI(), namesOrDefault(names, dateTimeNameGenerator{}).NewName()),
+		},
+	}
+	for _, col := range schema.Columns {
+		switch col.Name {
+		case string(Entity):
+			table.schema.Entity = col.Name
+		case string(Value):
+			table.schema.Value = col.Name
+		case string(TS):
+			table.schema.TS = col.Name
+		default:
+			// TODO: verify the assumption that col.Name should be:
+			// * Entity ("entity")
+			// * Value ("value")
+			// * TS ("ts")
+			// makes sense in the context of the schema
+			return nil, fmt.Errorf("unexpected column name: %s", col.Name)
+		}
+	}
+	data, err := table.schema.Serialize()
+	if err != nil {
+		return nil, fmt.Errorf("could not serialize schema: %v", err)
+	}
+	err = store.Write(resourceTableFilepath, data)
+	if err != nil {
+		return nil, fmt.Errorf("could not write schema to file: %v", err)
+	}
+	return &table, nil
 }
 
 func (k8s *K8sOfflineStore) GetResourceTable(id ResourceID) (OfflineTable, error) {
@@ -1129,12 +1545,19 @@ func fileStoreGetMaterialization(id MaterializationID, store FileStore, logger *
 	materializationID := ResourceID{s[1], s[2], FeatureMaterialization}
 	logger.Debugw("Getting materialization", "id", id)
 	logger.Debugw("Successfully retrieved materialization", "id", id)
-	return &FileStoreMaterialization{materializationID, store}, nil
+	return &FileStoreMaterialization{id: materializationID, store: store}, nil
 }
 
 type FileStoreMaterialization struct {
 	id    ResourceID
 	store FileStore
+	// Unset if the feature's type isn't known, in which case lists are read as
+	// vectors
+	valueType ValueType
+}
+
+func (mat *FileStoreMaterialization) SetValueType(t ValueType) {
+	mat.valueType = t
 }
 
 func (mat FileStoreMaterialization) ID() MaterializationID {
@@ -1142,35 +1565,39 @@ func (mat FileStoreMaterialization) ID() MaterializationID {
 }
 
 func (mat FileStoreMaterialization) NumRows() (int64, error) {
-	materializationFilepath, err := mat.store.CreateFilePath(fileStoreResourcePath(mat.id))
-	if err != nil {
-		return 0, fmt.Errorf("could not create file path: %w", err)
-	}
-	latestMaterializationPath, err := mat.store.NewestFileOfType(materializationFilepath, filestore.Parquet)
+	newestFiles, err := mat.newestFiles()
 	if err != nil {
-		return 0, fmt.Errorf("could not get materialization num rows; %v", err)
+		return 0, fmt.Errorf("could not get materialization num rows: %w", err)
 	}
-	// TODO: convert NumRows to accept a list of files to get a total count
-	return mat.store.NumRows(latestMaterializationPath)
+	return NumRowsOfFiles(mat.store, newestFiles, cfg.GetNumRowsParallelism())
 }
 
-func (mat FileStoreMaterialization) IterateSegment(begin, end int64) (FeatureIterator, error) {
+// newestFiles returns the parts of the most recent run of the materialization
+func (mat FileStoreMaterialization) newestFiles() ([]filestore.Filepath, error) {
 	searchPath, err := mat.store.CreateFilePath(fileStoreResourcePath(mat.id))
 	if err != nil {
 		return nil, fmt.Errorf("could not create file path: %w", err)
 	}
 	files, err := mat.store.List(searchPath, filestore.Parquet)
 	if err != nil {
-		return nil, fmt.Errorf("could not get materialization iterate segment: %v", err)
+		return nil, fmt.Errorf("could not list materialization files: %v", err)
 	}
 	groups, err := filestore.NewFilePathGroup(files, filestore.DateTimeDirectoryGrouping)
 	if err != nil {
-		return nil, fmt.Errorf("could not groups files by datetime directory: %v", err)
+		return nil, fmt.Errorf("could not group files by datetime directory: %v", err)
 	}
 	newestFiles, err := groups.GetFirst()
 	if err != nil {
 		return nil, fmt.Errorf("could not get newest files: %v", err)
 	}
+	return newestFiles, nil
+}
+
+func (mat FileStoreMaterialization) IterateSegment(begin, end int64) (FeatureIterator, error) {
+	newestFiles, err := mat.newestFiles()
+	if err != nil {
+		return nil, fmt.Errorf("could not get materialization iterate segment: %w", err)
+	}
 	iter, err := mat.store.Serve(newestFiles)
 	if err != nil {
 		return nil, err
@@ -1180,18 +1607,20 @@ func (mat FileStoreMaterialization) IterateSegment(begin, end int64) (FeatureIte
 		_, _ = iter.Next()
 	}
 	return &FileStoreFeatureIterator{
-		iter:   iter,
-		curIdx: i,
-		maxIdx: end,
+		iter:      iter,
+		curIdx:    i,
+		maxIdx:    end,
+		valueType: mat.valueType,
 	}, nil
 }
 
 type FileStoreFeatureIterator struct {
-	iter   Iterator
-	err    error
-	cur    ResourceRecord
-	curIdx int64
-	maxIdx int64
+	iter      Iterator
+	err       error
+	cur       ResourceRecord
+	curIdx    int64
+	maxIdx    int64
+	valueType ValueType
 }
 
 func (iter *FileStoreFeatureIterator) Next() bool {
@@ -1233,7 +1662,12 @@ func (iter *FileStoreFeatureIterator) Next() bool {
 // Attempts to parse value in one of the following formats:
 // 1. a scalar value (string, int, float, bool)
 // 2. []float32 (i.e. vector32)
+// 3. a JSON value, if the feature is JSON, which the file iterator has already
+// decoded
 func (iter *FileStoreFeatureIterator) parseValue(value interface{}) (interface{}, error) {
+	if iter.valueType == JSON {
+		return value, nil
+	}
 	valueMap, ok := value.(map[string]interface{})
 	if !ok {
 		if value, ok := value.(int32); ok {
@@ -1345,7 +1779,7 @@ func (k8s *K8sOfflineStore) materialization(id ResourceID, isUpdate bool) (Mater
 	}
 
 	k8s.logger.Debugw("Successfully created materialization", "id", id)
-	return &FileStoreMaterialization{materializationID, k8s.store}, nil
+	return &FileStoreMaterialization{id: materializationID, store: k8s.store}, nil
 }
 
 func (k8s *K8sOfflineStore) DeleteMaterialization(id MaterializationID) error {
@@ -1373,6 +1807,55 @@ func fileStoreDeleteMaterialization(id MaterializationID, store FileStore, logge
 	return store.DeleteAll(materializationPath)
 }
 
+func (k8s *K8sOfflineStore) DeletePrimaryTable(id ResourceID) error {
+	if err := id.check(Primary); err != nil {
+		return fmt.Errorf("ID check failed: %w", err)
+	}
+	return fileStoreDeleteTable(id, k8s.store, k8s.logger)
+}
+
+func (k8s *K8sOfflineStore) DeleteResourceTable(id ResourceID) error {
+	if err := id.check(Feature, Label); err != nil {
+		return fmt.Errorf("ID check failed: %w", err)
+	}
+	return fileStoreDeleteTable(id, k8s.store, k8s.logger)
+}
+
+func (k8s *K8sOfflineStore) DeleteTransformationTable(id ResourceID) error {
+	if err := id.check(Transformation); err != nil {
+		return fmt.Errorf("ID check failed: %w", err)
+	}
+	return fileStoreDeleteTable(id, k8s.store, k8s.logger)
+}
+
+// fileStoreDeleteTable removes a table registered in a file store. Primary and
+// resource tables are a single file holding their schema, and transformations
+// are a directory of their outputs, both at the resource's path. It isn't an
+// error if the table doesn't exist.
+func fileStoreDeleteTable(id ResourceID, store FileStore, logger *zap.SugaredLogger) error {
+	if id.Type == Transformation {
+		dir, err := store.CreateDirPath(id.ToFilestorePath())
+		if err != nil {
+			return fmt.Errorf("could not create dir path: %w", err)
+		}
+		logger.Debugw("Deleting transformation table", "id", id, "resourceKey", dir.Key())
+		return store.DeleteAll(dir)
+	}
+	filepath, err := store.CreateFilePath(id.ToFilestorePath())
+	if err != nil {
+		return fmt.Errorf("could not create file path: %w", err)
+	}
+	exists, err := store.Exists(filepath)
+	if err != nil {
+		return fmt.Errorf("could not check if table exists: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+	logger.Debugw("Deleting table", "id", id, "resourceKey", filepath.Key())
+	return store.Delete(filepath)
+}
+
 func (k8s *K8sOfflineStore) CreateTrainingSet(def TrainingSetDef) error {
 	return k8s.trainingSet(def, false)
 }
@@ -1471,6 +1954,10 @@ func (k8s *K8sOfflineStore) trainingSet(def TrainingSetDef, isUpdate bool) error
 	k8s.logger.Debugw("Training Set Query", "list", trainingSetQuery)
 	pandasArgs := k8s.pandasRunnerArgs(destinationPath.ToURI(), trainingSetQuery, sourcePaths, CreateTrainingSet)
 	pandasArgs = addResourceID(pandasArgs, def.ID)
+	pandasArgs, err = k8s.addParquetArgs(pandasArgs, def.ParquetConfig)
+	if err != nil {
+		return err
+	}
 	k8s.logger.Debugw("Creating training set", "definition", def)
 
 	if err := k8s.executor.ExecuteScript(pandasArgs, nil); err != nil {
@@ -1541,13 +2028,36 @@ func (ts *FileStoreTrainingSet) Next() bool {
 	}
 	featureValues := make([]interface{}, len(ts.iter.FeatureColumns()))
 	for i, key := range ts.iter.FeatureColumns() {
-		featureValues[i] = row[key]
+		// A label row that was left outer joined to a missing feature has a null
+		// in that column. Keep it as nil rather than a zero value so it can't be
+		// mistaken for a real feature value.
+		if val, has := row[key]; has && !isNullFeatureValue(val) {
+			featureValues[i] = val
+		}
 	}
 	ts.features = featureValues
 	ts.label = row[ts.iter.LabelColumn()]
 	return true
 }
 
+// Parquet files written from pandas can hold a missing float as NaN rather than
+// null.
+func isNullFeatureValue(val interface{}) bool {
+	switch v := val.(type) {
+	case nil:
+		return true
+	case float64:
+		return math.IsNaN(v)
+	case float32:
+		return math.IsNaN(float64(v))
+	default:
+		return false
+	}
+}
+
+// Features returns the training row's feature values in the order of the
+// training set's features. A feature with no value at or before the label's
+// timestamp is nil.
 func (ts *FileStoreTrainingSet) Features() []interface{} {
 	return ts.features
 }
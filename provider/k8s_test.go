@@ -5,11 +5,13 @@ package provider
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -26,6 +28,7 @@ import (
 
 	filestore "github.com/featureform/filestore"
 	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 )
@@ -117,11 +120,18 @@ func TestBlobInterfaces(t *testing.T) {
 		"Test Not Exists":               testNotExists,
 		"Test Serve":                    testServe,
 		"Test Serve Directory":          testServeDirectory,
+		"Test Serve Avro":               testServeAvro,
+		"Test Serve ORC":                testServeORC,
+		"Test Serve Mixed Formats":      testServeMixedFormats,
 		"Test Delete":                   testDelete,
 		"Test Delete All":               testDeleteAll,
 		"Test Newest file":              testNewestFile,
+		"Test Newest File Of Types":     testNewestFileOfTypes,
+		"Test Serve Newest File":        testServeNewestFile,
+		"Test Newest N Files Of Type":   testNewestNFilesOfType,
 		"Test Num Rows":                 testNumRows,
 		"Test File Upload and Download": testFileUploadAndDownload,
+		"Test Delete Table":             testFileStoreDeleteTable,
 	}
 
 	err := godotenv.Load("../.env")
@@ -177,15 +187,16 @@ func TestBlobInterfaces(t *testing.T) {
 	}
 
 	blobProviders := map[string]FileStore{
-		"File":  fileFileStore,
-		"Azure": azureFileStore,
-		"HDFS":  hdfsFileStore,
+		"File":   fileFileStore,
+		"Azure":  azureFileStore,
+		"HDFS":   hdfsFileStore,
+		"Memory": NewMemoryFileStore(),
 	}
 	for testName, fileTest := range fileStoreTests {
 		fileTest = fileTest
 		testName = testName
 		for blobName, blobProvider := range blobProviders {
-			if blobName != "HDFS" {
+			if blobName != "HDFS" && blobName != "Memory" {
 				continue
 			}
 			blobName = blobName
@@ -201,18 +212,15 @@ func TestBlobInterfaces(t *testing.T) {
 }
 
 func testFileUploadAndDownload(t *testing.T, store FileStore) {
-	// Need to get the working directory because the LocalFilepath will use it to create the full path
-	// Currently, the LocalFilePath will only work with absolute paths
-	wd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("could not get working directory")
-	}
+	// The LocalFilepath only works with absolute paths. Local files are staged in
+	// a temporary directory so they don't end up in the working directory.
+	stagingDir := t.TempDir()
 
 	testId := uuidWithoutDashes()
 	fileContent := "testing file upload"
-	sourceFile := fmt.Sprintf("%s/fileUploadTest_%s.txt", wd, testId)
+	sourceFile := fmt.Sprintf("%s/fileUploadTest_%s.txt", stagingDir, testId)
 	destFile := fmt.Sprintf("fileUploadTest_%s.txt", testId)
-	localDestFile := fmt.Sprintf("%s/fileDownloadTest_%s.txt", wd, testId)
+	localDestFile := fmt.Sprintf("%s/fileDownloadTest_%s.txt", stagingDir, testId)
 
 	f, err := os.Create(sourceFile)
 	if err != nil {
@@ -298,6 +306,71 @@ func testFilestoreReadAndWrite(t *testing.T, store FileStore) {
 	}
 }
 
+func testFileStoreDeleteTable(t *testing.T, store FileStore) {
+	logger := zaptest.NewLogger(t).Sugar()
+	name := uuidWithoutDashes()
+	// A sibling variant whose name starts with the deleted one's must survive
+	id := ResourceID{Name: name, Variant: "v", Type: Feature}
+	sibling := ResourceID{Name: name, Variant: "v2", Type: Feature}
+	resourceSchema := ResourceSchema{Entity: "entity", Value: "value", SourceTable: "source"}
+	schema, err := resourceSchema.Serialize()
+	if err != nil {
+		t.Fatalf("could not serialize schema: %v", err)
+	}
+	for _, resID := range []ResourceID{id, sibling} {
+		path, err := store.CreateFilePath(resID.ToFilestorePath())
+		if err != nil {
+			t.Fatalf("could not create file path: %v", err)
+		}
+		if err := store.Write(path, schema); err != nil {
+			t.Fatalf("could not write resource table: %v", err)
+		}
+	}
+	transformation := ResourceID{Name: name, Variant: "v", Type: Transformation}
+	siblingTransformation := ResourceID{Name: name, Variant: "v2", Type: Transformation}
+	for _, resID := range []ResourceID{transformation, siblingTransformation} {
+		path, err := store.CreateFilePath(fmt.Sprintf("%s/2023-01-01-00-00-00/part-0000.parquet", resID.ToFilestorePath()))
+		if err != nil {
+			t.Fatalf("could not create file path: %v", err)
+		}
+		if err := store.Write(path, []byte("output")); err != nil {
+			t.Fatalf("could not write transformation output: %v", err)
+		}
+	}
+
+	for _, resID := range []ResourceID{id, transformation} {
+		if err := fileStoreDeleteTable(resID, store, logger); err != nil {
+			t.Fatalf("could not delete %s table: %v", resID.Type, err)
+		}
+		// Deleting it again is a no-op
+		if err := fileStoreDeleteTable(resID, store, logger); err != nil {
+			t.Fatalf("could not delete missing %s table: %v", resID.Type, err)
+		}
+	}
+	if _, err := fileStoreGetResourceTable(id, store, logger); err == nil {
+		t.Fatalf("expected deleted resource table to be missing")
+	} else if _, notFound := err.(*TableNotFound); !notFound {
+		t.Fatalf("expected table not found error, got %T %v", err, err)
+	}
+	if _, err := fileStoreGetResourceTable(sibling, store, logger); err != nil {
+		t.Fatalf("expected sibling resource table to survive: %v", err)
+	}
+	for resID, expected := range map[ResourceID]bool{transformation: false, siblingTransformation: true} {
+		path, err := store.CreateFilePath(fmt.Sprintf("%s/2023-01-01-00-00-00/part-0000.parquet", resID.ToFilestorePath()))
+		if err != nil {
+			t.Fatalf("could not create file path: %v", err)
+		}
+		if exists, err := store.Exists(path); err != nil || exists != expected {
+			t.Fatalf("expected output of %s to exist: %v, got %v (%v)", resID.Variant, expected, exists, err)
+		}
+	}
+	for _, resID := range []ResourceID{sibling, siblingTransformation} {
+		if err := fileStoreDeleteTable(resID, store, logger); err != nil {
+			t.Fatalf("could not clean up %s table: %v", resID.Type, err)
+		}
+	}
+}
+
 func TestExecutorRunLocal(t *testing.T) {
 	logger := zaptest.NewLogger(t).Sugar()
 	localConfig := LocalExecutorConfig{
@@ -328,6 +401,225 @@ func TestExecutorRunLocal(t *testing.T) {
 	}
 }
 
+func TestExecutorRunLocalConcurrently(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	mydir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("could not get working directory")
+	}
+	outputDir := t.TempDir()
+	type run struct {
+		workingDir string
+		output     string
+		limit      int
+	}
+	runs := []run{
+		{workingDir: t.TempDir(), output: fmt.Sprintf("%s/first", outputDir), limit: 1},
+		{workingDir: t.TempDir(), output: fmt.Sprintf("%s/second", outputDir), limit: 2},
+	}
+	errs := make(chan error, len(runs))
+	for _, r := range runs {
+		localConfig := LocalExecutorConfig{
+			ScriptPath: "./scripts/k8s/offline_store_pandas_runner.py",
+			WorkingDir: r.workingDir,
+		}
+		serialized, err := localConfig.Serialize()
+		if err != nil {
+			t.Fatalf("Error serializing local executor configuration: %v", err)
+		}
+		executor, err := NewLocalExecutor(Config(serialized), logger)
+		if err != nil {
+			t.Fatalf("Error creating new Local Executor: %v", err)
+		}
+		envVars := map[string]string{
+			"MODE":                "local",
+			"OUTPUT_URI":          r.output,
+			"OUTPUT_FORMAT":       "csv",
+			"SOURCES":             fmt.Sprintf("%s/scripts/k8s/tests/test_files/inputs/transaction_short/part-00000-9d3cb5a3-4b9c-4109-afa3-a75759bfcf89-c000.snappy.parquet", mydir),
+			"TRANSFORMATION_TYPE": "sql",
+			"TRANSFORMATION":      fmt.Sprintf("SELECT TransactionID FROM source_0 LIMIT %d", r.limit),
+		}
+		go func() {
+			errs <- executor.ExecuteScript(envVars, nil)
+		}()
+	}
+	for range runs {
+		if err := <-errs; err != nil {
+			t.Fatalf("Failed to execute pandas script: %v", err)
+		}
+	}
+	for _, r := range runs {
+		outputs, err := os.ReadDir(r.output)
+		if err != nil {
+			t.Fatalf("could not read output directory %s: %v", r.output, err)
+		}
+		if len(outputs) != 1 {
+			t.Fatalf("expected one output file in %s, got %d", r.output, len(outputs))
+		}
+		b, err := os.ReadFile(fmt.Sprintf("%s/%s", r.output, outputs[0].Name()))
+		if err != nil {
+			t.Fatalf("could not read output: %v", err)
+		}
+		rows, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+		if err != nil {
+			t.Fatalf("output is not valid csv: %v", err)
+		}
+		if len(rows) != r.limit+1 {
+			t.Fatalf("expected a header and %d rows in %s, got %d lines", r.limit, r.output, len(rows))
+		}
+		staged, err := os.ReadDir(r.workingDir)
+		if err != nil {
+			t.Fatalf("could not read working directory %s: %v", r.workingDir, err)
+		}
+		if len(staged) != 0 {
+			t.Fatalf("expected staging directory to be removed from %s, found %d entries", r.workingDir, len(staged))
+		}
+	}
+}
+
+func TestLocalExecutorStagesInFileStore(t *testing.T) {
+	tempDir := t.TempDir()
+	config := pc.LocalFileStoreConfig{DirPath: fmt.Sprintf("file:///%s", t.TempDir()), TempDir: tempDir}
+	serialized, err := config.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize file store config: %v", err)
+	}
+	store, err := NewLocalFileStore(serialized)
+	if err != nil {
+		t.Fatalf("could not create local file store: %v", err)
+	}
+	defer store.Close()
+	// The script records the directory it's run from
+	scriptDir := t.TempDir()
+	scriptPath := filepath.Join(scriptDir, "record_dir.py")
+	script := "import os\nopen(os.environ['RECORD_PATH'], 'w').write(os.getcwd())\n"
+	if err := os.WriteFile(scriptPath, []byte(script), 0644); err != nil {
+		t.Fatalf("could not write script: %v", err)
+	}
+	executor := stageLocalRuns(LocalExecutor{scriptPath: scriptPath}, store)
+	recordPath := filepath.Join(scriptDir, "dir")
+	if err := executor.ExecuteScript(map[string]string{"RECORD_PATH": recordPath}, nil); err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	recorded, err := os.ReadFile(recordPath)
+	if err != nil {
+		t.Fatalf("could not read recorded directory: %v", err)
+	}
+	if filepath.Dir(string(recorded)) != tempDir {
+		t.Fatalf("expected run to be staged in %s, got %s", tempDir, recorded)
+	}
+	if entries, err := os.ReadDir(tempDir); err != nil || len(entries) != 0 {
+		t.Fatalf("expected staging directory to be removed after the run, found %v (%v)", entries, err)
+	}
+}
+
+func TestLocalFileStoreStagingDir(t *testing.T) {
+	tempDir := t.TempDir()
+	config := pc.LocalFileStoreConfig{DirPath: fmt.Sprintf("file:///%s", t.TempDir()), TempDir: tempDir}
+	serialized, err := config.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize file store config: %v", err)
+	}
+	store, err := NewLocalFileStore(serialized)
+	if err != nil {
+		t.Fatalf("could not create local file store: %v", err)
+	}
+	localStore := store.(*LocalFileStore)
+	first, err := localStore.StagingDir()
+	if err != nil {
+		t.Fatalf("could not create staging directory: %v", err)
+	}
+	second, err := localStore.StagingDir()
+	if err != nil {
+		t.Fatalf("could not create staging directory: %v", err)
+	}
+	if first == second || filepath.Dir(first) != tempDir || filepath.Dir(second) != tempDir {
+		t.Fatalf("expected two distinct staging directories in %s, got %s and %s", tempDir, first, second)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("could not close store: %v", err)
+	}
+	if entries, err := os.ReadDir(tempDir); err != nil || len(entries) != 0 {
+		t.Fatalf("expected staging directories to be removed on close, found %v (%v)", entries, err)
+	}
+}
+
+func TestExecutorRunLocalCSVOutput(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	localConfig := LocalExecutorConfig{
+		ScriptPath: "./scripts/k8s/offline_store_pandas_runner.py",
+	}
+	serialized, err := localConfig.Serialize()
+	if err != nil {
+		t.Fatalf("Error serializing local executor configuration: %v", err)
+	}
+	executor, err := NewLocalExecutor(Config(serialized), logger)
+	if err != nil {
+		t.Fatalf("Error creating new Local Executor: %v", err)
+	}
+	mydir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("could not get working directory")
+	}
+	outputDir := uuid.New().String()
+	sqlEnvVars := map[string]string{
+		"MODE":                "local",
+		"OUTPUT_URI":          fmt.Sprintf(`%s/scripts/k8s/tests/test_files/output/%s`, mydir, outputDir),
+		"OUTPUT_FORMAT":       "csv",
+		"SOURCES":             fmt.Sprintf("%s/scripts/k8s/tests/test_files/inputs/transaction_short/part-00000-9d3cb5a3-4b9c-4109-afa3-a75759bfcf89-c000.snappy.parquet", mydir),
+		"TRANSFORMATION_TYPE": "sql",
+		"TRANSFORMATION":      "SELECT TransactionID, CustomerID FROM source_0 LIMIT 2",
+	}
+	if err := executor.ExecuteScript(sqlEnvVars, nil); err != nil {
+		t.Fatalf("Failed to execute pandas script: %v", err)
+	}
+	fileStoreConfig := pc.LocalFileStoreConfig{DirPath: fmt.Sprintf(`file:///%s/scripts/k8s/tests/test_files/output`, mydir)}
+	serializedFileConfig, err := fileStoreConfig.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize file store config: %v", err)
+	}
+	store, err := NewLocalFileStore(serializedFileConfig)
+	if err != nil {
+		t.Fatalf("could not create local file store: %v", err)
+	}
+	dir, err := store.CreateDirPath(outputDir)
+	if err != nil {
+		t.Fatalf("could not create output directory path: %v", err)
+	}
+	defer store.DeleteAll(dir)
+	output, err := store.NewestFileOfType(dir, filestore.CSV)
+	if err != nil {
+		t.Fatalf("could not find csv output: %v", err)
+	}
+	b, err := store.Read(output)
+	if err != nil {
+		t.Fatalf("could not read csv output: %v", err)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid csv: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %d lines", len(rows))
+	}
+	if !reflect.DeepEqual(rows[0], []string{"TransactionID", "CustomerID"}) {
+		t.Fatalf("unexpected header: %v", rows[0])
+	}
+	iter, err := store.Serve([]filestore.Filepath{output})
+	if err != nil {
+		t.Fatalf("could not serve csv output: %v", err)
+	}
+	for i := 1; i < len(rows); i++ {
+		row, err := iter.Next()
+		if err != nil {
+			t.Fatalf("could not read served row: %v", err)
+		}
+		if row["TransactionID"] != rows[i][0] {
+			t.Fatalf("expected served TransactionID %s, got %v", rows[i][0], row["TransactionID"])
+		}
+	}
+}
+
 func TestNewConfig(t *testing.T) {
 	err := godotenv.Load("../.env")
 
@@ -516,6 +808,112 @@ func testServe(t *testing.T, store FileStore) {
 	}
 }
 
+func testServeAvro(t *testing.T, store FileStore) {
+	avroNumRows := 5
+	schema, records := getMockSchemaAndRecords(avroNumRows)
+	avroBytes, err := convertToAvroBytes(schema, records)
+	if err != nil {
+		t.Fatalf("could not convert struct list to avro bytes: %v", err)
+	}
+	randomAvroKey := fmt.Sprintf("%s.avro", uuid.New().String())
+	randomAvroFilePath, err := store.CreateFilePath(randomAvroKey)
+	if err != nil {
+		t.Fatalf("Could not create random file path: %v", err)
+	}
+	if err := store.Write(randomAvroFilePath, avroBytes); err != nil {
+		t.Fatalf("Could not write avro bytes to random key: %v", err)
+	}
+	iterator, err := store.Serve([]filestore.Filepath{randomAvroFilePath})
+	if err != nil {
+		t.Fatalf("Could not get avro iterator: %v", err)
+	}
+	idx := 0
+	for {
+		avroRow, err := iterator.Next()
+		if err != nil {
+			t.Fatalf("Error iterating through avro file: %v", err)
+		}
+		if avroRow == nil {
+			if idx != avroNumRows {
+				t.Fatalf("Incorrect number of rows in avro file. Expected %d, got %d", avroNumRows, idx)
+			}
+			break
+		}
+		if idx >= avroNumRows {
+			t.Fatalf("iterating over more rows than given")
+		}
+		avroRecord := GenericRecord{avroRow["ID"], avroRow["Name"], avroRow["Points"], avroRow["Score"], avroRow["Registered"], avroRow["Created"]}
+		if !reflect.DeepEqual(records[idx], avroRecord) {
+			t.Fatalf("Submitted row and returned struct not identical. Got %v, expected %v", avroRecord, records[idx])
+		}
+		idx += 1
+	}
+	numRows, err := store.NumRows(randomAvroFilePath)
+	if err != nil {
+		t.Fatalf("Could not get number of rows in avro file: %v", err)
+	}
+	if numRows != int64(avroNumRows) {
+		t.Fatalf("Incorrect number of rows reported. Expected %d, got %d", avroNumRows, numRows)
+	}
+	// cleanup test
+	if err := store.Delete(randomAvroFilePath); err != nil {
+		t.Fatalf("Could not delete avro file: %v", err)
+	}
+}
+
+func testServeORC(t *testing.T, store FileStore) {
+	orcNumRows := 5
+	schema, records := getMockSchemaAndRecords(orcNumRows)
+	orcBytes, err := convertToORCBytes(schema, records, orcCompressionZlib)
+	if err != nil {
+		t.Fatalf("could not convert struct list to orc bytes: %v", err)
+	}
+	randomORCKey := fmt.Sprintf("%s.orc", uuid.New().String())
+	randomORCFilePath, err := store.CreateFilePath(randomORCKey)
+	if err != nil {
+		t.Fatalf("Could not create random file path: %v", err)
+	}
+	if err := store.Write(randomORCFilePath, orcBytes); err != nil {
+		t.Fatalf("Could not write orc bytes to random key: %v", err)
+	}
+	iterator, err := store.Serve([]filestore.Filepath{randomORCFilePath})
+	if err != nil {
+		t.Fatalf("Could not get orc iterator: %v", err)
+	}
+	idx := 0
+	for {
+		orcRow, err := iterator.Next()
+		if err != nil {
+			t.Fatalf("Error iterating through orc file: %v", err)
+		}
+		if orcRow == nil {
+			if idx != orcNumRows {
+				t.Fatalf("Incorrect number of rows in orc file. Expected %d, got %d", orcNumRows, idx)
+			}
+			break
+		}
+		if idx >= orcNumRows {
+			t.Fatalf("iterating over more rows than given")
+		}
+		orcRecord := GenericRecord{orcRow["ID"], orcRow["Name"], orcRow["Points"], orcRow["Score"], orcRow["Registered"], orcRow["Created"]}
+		if !reflect.DeepEqual(records[idx], orcRecord) {
+			t.Fatalf("Submitted row and returned struct not identical. Got %v, expected %v", orcRecord, records[idx])
+		}
+		idx += 1
+	}
+	numRows, err := store.NumRows(randomORCFilePath)
+	if err != nil {
+		t.Fatalf("Could not get number of rows in orc file: %v", err)
+	}
+	if numRows != int64(orcNumRows) {
+		t.Fatalf("Incorrect number of rows reported. Expected %d, got %d", orcNumRows, numRows)
+	}
+	// cleanup test
+	if err := store.Delete(randomORCFilePath); err != nil {
+		t.Fatalf("Could not delete orc file: %v", err)
+	}
+}
+
 func testServeDirectory(t *testing.T, store FileStore) {
 	parquetNumRows := 5
 	parquetNumFiles := 5
@@ -700,6 +1098,197 @@ func testNewestFile(t *testing.T, store FileStore) {
 	}
 }
 
+func testServeMixedFormats(t *testing.T, store FileStore) {
+	dirKey := uuid.New().String()
+	dir, err := store.CreateDirPath(dirKey)
+	if err != nil {
+		t.Fatalf("Could not create random directory: %v", err)
+	}
+	writeFile := func(ext string, b []byte) filestore.Filepath {
+		path, err := store.CreateFilePath(fmt.Sprintf("%s/%s.%s", dirKey, uuid.New().String(), ext))
+		if err != nil {
+			t.Fatalf("Could not create random file path: %v", err)
+		}
+		if err := store.Write(path, b); err != nil {
+			t.Fatalf("Could not write %s file: %v", ext, err)
+		}
+		return path
+	}
+	ts := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	csvFile := writeFile("csv", []byte(fmt.Sprintf("entity,value,ts\na,1,%s\nb,2,%s\n", ts.Format(time.RFC3339), ts.Format(time.RFC3339))))
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+			{Name: "ts", ValueType: Timestamp},
+		},
+	}
+	parquetBytes, err := convertToParquetBytes(schema, []GenericRecord{{"c", 3, ts}, {"d", 4, ts}, {"e", 5, ts}})
+	if err != nil {
+		t.Fatalf("could not convert records to parquet bytes: %v", err)
+	}
+	parquetFile := writeFile("parquet", parquetBytes)
+
+	iter, err := store.Serve([]filestore.Filepath{csvFile, parquetFile})
+	if err != nil {
+		t.Fatalf("Could not serve mixed format directory: %v", err)
+	}
+	expected := []map[string]interface{}{
+		{"entity": "a", "value": 1, "ts": ts},
+		{"entity": "b", "value": 2, "ts": ts},
+		{"entity": "c", "value": 3, "ts": ts},
+		{"entity": "d", "value": 4, "ts": ts},
+		{"entity": "e", "value": 5, "ts": ts},
+	}
+	for i, exp := range expected {
+		row, err := iter.Next()
+		if err != nil {
+			t.Fatalf("Could not read row %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(row, exp) {
+			t.Fatalf("Row %d: expected %v, got %v", i, exp, row)
+		}
+	}
+	if row, err := iter.Next(); row != nil || err != nil {
+		t.Fatalf("Expected end of rows, got %v %v", row, err)
+	}
+
+	// JSON timestamps are strings and can be converted, but "six" can't be a number
+	jsonlFile := writeFile("jsonl", []byte(`{"entity": "f", "value": "six", "ts": "2023-01-02T03:04:05Z"}`+"\n"))
+	if _, err := store.Serve([]filestore.Filepath{parquetFile, jsonlFile}); err == nil || !strings.Contains(err.Error(), "column value") {
+		t.Fatalf("Expected conflicting type error for column value, got %v", err)
+	}
+	if err := store.DeleteAll(dir); err != nil {
+		t.Fatalf("Could not delete directory: %v", err)
+	}
+}
+
+func testNewestFileOfTypes(t *testing.T, store FileStore) {
+	dirKey := uuid.New().String()
+	dir, err := store.CreateDirPath(dirKey)
+	if err != nil {
+		t.Fatalf("Could not create random directory: %v", err)
+	}
+	// Alternate extensions so the newest file of each type is never the newest overall
+	extensions := []string{"parquet", "csv", "parquet", "csv", "parquet", "json"}
+	keys := make([]string, len(extensions))
+	for i, ext := range extensions {
+		name := fmt.Sprintf("%s.%s", uuid.New().String(), ext)
+		keys[i] = fmt.Sprintf("%s/%s", dir.ToURI(), name)
+		path, err := store.CreateFilePath(fmt.Sprintf("%s/%s", dirKey, name))
+		if err != nil {
+			t.Fatalf("Could not create random file path: %v", err)
+		}
+		if err := store.Write(path, []byte(uuid.New().String())); err != nil {
+			t.Fatalf("Could not write key to filestore: %v", err)
+		}
+		time.Sleep(1 * time.Second) // To guarantee ordering of created in metadata follows write ordering
+	}
+	cases := []struct {
+		fileTypes []filestore.FileType
+		expected  string
+	}{
+		{[]filestore.FileType{filestore.Parquet, filestore.CSV}, keys[4]},
+		{[]filestore.FileType{filestore.CSV, filestore.Parquet}, keys[4]},
+		{[]filestore.FileType{filestore.CSV}, keys[3]},
+		{[]filestore.FileType{filestore.CSV, filestore.Avro}, keys[3]},
+	}
+	for _, c := range cases {
+		newest, err := store.NewestFileOfTypes(dir, c.fileTypes...)
+		if err != nil {
+			t.Fatalf("Error getting newest file of types %v: %v", c.fileTypes, err)
+		}
+		if newest.ToURI() != c.expected {
+			t.Fatalf("Newest file of types %v: expected '%s', got '%s'", c.fileTypes, c.expected, newest.ToURI())
+		}
+	}
+	if err := store.DeleteAll(dir); err != nil {
+		t.Fatalf("Could not delete directory: %v", err)
+	}
+}
+
+func testNewestNFilesOfType(t *testing.T, store FileStore) {
+	dirKey := uuid.New().String()
+	dir, err := store.CreateDirPath(dirKey)
+	if err != nil {
+		t.Fatalf("Could not create random directory: %v", err)
+	}
+	keys := make([]string, 10)
+	for i := range keys {
+		name := fmt.Sprintf("snapshot_%s.parquet", uuid.New().String())
+		keys[i] = fmt.Sprintf("%s/%s", dir.ToURI(), name)
+		path, err := store.CreateFilePath(fmt.Sprintf("%s/%s", dirKey, name))
+		if err != nil {
+			t.Fatalf("Could not create random file path: %v", err)
+		}
+		if err := store.Write(path, []byte(uuid.New().String())); err != nil {
+			t.Fatalf("Could not write key to filestore: %v", err)
+		}
+		time.Sleep(1 * time.Second) // To guarantee ordering of created in metadata follows write ordering
+	}
+	newest, err := store.NewestNFilesOfType(dir, filestore.Parquet, 3)
+	if err != nil {
+		t.Fatalf("Error getting newest files: %v", err)
+	}
+	expected := []string{keys[9], keys[8], keys[7]}
+	received := make([]string, len(newest))
+	for i, path := range newest {
+		received[i] = path.ToURI()
+	}
+	if !reflect.DeepEqual(expected, received) {
+		t.Fatalf("Expected newest files %v, got %v", expected, received)
+	}
+	if err := store.DeleteAll(dir); err != nil {
+		t.Fatalf("Could not delete directory: %v", err)
+	}
+}
+
+func testServeNewestFile(t *testing.T, store FileStore) {
+	dirKey := uuid.New().String()
+	dir, err := store.CreateDirPath(dirKey)
+	if err != nil {
+		t.Fatalf("Could not create random directory: %v", err)
+	}
+	var newestRecords []GenericRecord
+	for i := 1; i <= 3; i++ {
+		schema, records := getMockSchemaAndRecords(i)
+		parquetBytes, err := convertToParquetBytes(schema, records)
+		if err != nil {
+			t.Fatalf("could not convert struct list to parquet bytes: %v", err)
+		}
+		path, err := store.CreateFilePath(fmt.Sprintf("%s/%s.parquet", dirKey, uuid.New().String()))
+		if err != nil {
+			t.Fatalf("Could not create random file path: %v", err)
+		}
+		if err := store.Write(path, parquetBytes); err != nil {
+			t.Fatalf("Could not write parquet bytes to path: %v", err)
+		}
+		newestRecords = records
+		time.Sleep(1 * time.Second) // To guarantee ordering of created in metadata follows write ordering
+	}
+	iterator, err := ServeNewestFile(store, dir, filestore.Parquet)
+	if err != nil {
+		t.Fatalf("Could not serve newest file: %v", err)
+	}
+	served := make([]GenericRecord, 0)
+	for {
+		row, err := iterator.Next()
+		if err != nil {
+			t.Fatalf("Error iterating through newest file: %v", err)
+		}
+		if row == nil {
+			break
+		}
+		served = append(served, GenericRecord{row["ID"], row["Name"], row["Points"], row["Score"], row["Registered"], row["Created"]})
+	}
+	if !reflect.DeepEqual(newestRecords, served) {
+		t.Fatalf("Expected only the newest file's records %v, got %v", newestRecords, served)
+	}
+	if err := store.DeleteAll(dir); err != nil {
+		t.Fatalf("Could not delete directory: %v", err)
+	}
+}
+
 func testNumRows(t *testing.T, store FileStore) {
 	parquetNumRows := 5
 	schema, records := getMockSchemaAndRecords(parquetNumRows)
@@ -762,6 +1351,41 @@ func TestDatabricksInitialization(t *testing.T) {
 	}
 }
 
+func TestEMRInitialization(t *testing.T) {
+	credentials := pc.AWSCredentials{
+		AWSAccessKeyId: helpers.GetEnv("AWS_ACCESS_KEY_ID", ""),
+		AWSSecretKey:   helpers.GetEnv("AWS_SECRET_KEY", ""),
+	}
+	emrConfig := pc.EMRConfig{
+		Credentials:   credentials,
+		ClusterRegion: helpers.GetEnv("AWS_EMR_CLUSTER_REGION", ""),
+		ClusterName:   helpers.GetEnv("AWS_EMR_CLUSTER_ID", ""),
+	}
+	executor, err := NewEMRExecutor(emrConfig, zaptest.NewLogger(t).Sugar())
+	if err != nil {
+		t.Fatalf("Could not create new EMR executor: %v", err)
+	}
+
+	s3StoreConfig := &pc.S3FileStoreConfig{
+		Credentials:  credentials,
+		BucketRegion: helpers.GetEnv("S3_BUCKET_REGION", ""),
+		BucketPath:   helpers.GetEnv("S3_BUCKET_PATH", ""),
+		Path:         "emr_initialization_tests",
+	}
+	serializedS3Config, err := s3StoreConfig.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize s3 store config: %v", err)
+	}
+	s3FileStore, err := NewSparkS3FileStore(serializedS3Config)
+	if err != nil {
+		t.Fatalf("failed to create new s3 store: %v", err)
+	}
+
+	if err := executor.InitializeExecutor(s3FileStore); err != nil {
+		t.Fatalf("Error initializing executor: %v", err)
+	}
+}
+
 func TestKubernetesExecutor_isDefaultImage(t *testing.T) {
 	logger := zaptest.NewLogger(t).Sugar()
 	type fields struct {
@@ -987,6 +1611,41 @@ func TestTrainingSetOrder(t *testing.T) {
 	}
 }
 
+func TestTrainingSetNullFeature(t *testing.T) {
+	type RowType struct {
+		Feature__value *int64
+		Label__field   string
+	}
+	one := int64(1)
+	var buf bytes.Buffer
+	w := parquet.NewWriter(&buf)
+	w.Write(RowType{&one, "has feature"})
+	w.Write(RowType{nil, "missing feature"})
+	w.Close()
+
+	iter, err := parquetIteratorFromBytes(buf.Bytes())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	tsIterator := FileStoreTrainingSet{
+		iter: iter,
+	}
+	expected := []interface{}{1, nil}
+	i := 0
+	for tsIterator.Next() {
+		if !reflect.DeepEqual(tsIterator.Features(), []interface{}{expected[i]}) {
+			t.Errorf("Expected features %v for %v, got %v", []interface{}{expected[i]}, tsIterator.Label(), tsIterator.Features())
+		}
+		i++
+	}
+	if err := tsIterator.Err(); err != nil {
+		t.Fatalf("Failed to iterate training set: %v", err)
+	}
+	if i != len(expected) {
+		t.Fatalf("Expected %d rows, got %d", len(expected), i)
+	}
+}
+
 func TestParquetIterator_vector32(t *testing.T) {
 	data, err := ioutil.ReadFile("test_files/vector32.parquet")
 	if err != nil {
@@ -1035,20 +1694,346 @@ func TestParquetIterator_vector32(t *testing.T) {
 	}
 }
 
+func TestVectorRoundTrip(t *testing.T) {
+	vectorType := VectorType{ScalarType: Float32, Dimension: 4, IsEmbedding: true}
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: vectorType},
+			{Name: "ts", ValueType: Timestamp},
+		},
+	}
+	ts := time.UnixMilli(0).UTC()
+	records := []GenericRecord{
+		{"a", []float32{0.1, 0.2, 0.3, 0.4}, ts},
+		{"b", []float32{-1, 0, 1, 2}, ts},
+	}
+	parquetBytes, err := convertToParquetBytes(schema, records)
+	if err != nil {
+		t.Fatalf("could not convert records to parquet: %v", err)
+	}
+	iter, err := parquetIteratorFromBytes(parquetBytes)
+	if err != nil {
+		t.Fatalf("could not create parquet iterator: %v", err)
+	}
+	table, err := NewLocalOnlineStore().CreateTable("embedding", "default", vectorType)
+	if err != nil {
+		t.Fatalf("could not create online table: %v", err)
+	}
+	featureIter := &FileStoreFeatureIterator{}
+	for i := range records {
+		row, err := iter.Next()
+		if err != nil {
+			t.Fatalf("could not read row %d: %v", i, err)
+		}
+		vector, err := featureIter.parseValue(row["value"])
+		if err != nil {
+			t.Fatalf("could not parse vector in row %d: %v", i, err)
+		}
+		entity := row["entity"].(string)
+		if err := table.Set(entity, vector); err != nil {
+			t.Fatalf("could not set vector for %s: %v", entity, err)
+		}
+	}
+	if row, err := iter.Next(); row != nil || err != nil {
+		t.Fatalf("expected end of file, got %v %v", row, err)
+	}
+	for _, record := range records {
+		vector, err := table.Get(record[0].(string))
+		if err != nil {
+			t.Fatalf("could not get vector for %s: %v", record[0], err)
+		}
+		if !reflect.DeepEqual(vector, record[1]) {
+			t.Fatalf("%s: expected %v, got %v", record[0], record[1], vector)
+		}
+	}
+	if err := table.Set("c", []float32{1, 2}); err == nil {
+		t.Fatalf("expected a vector of the wrong dimension to be rejected")
+	}
+}
+
 func convertToParquetBytes(schema TableSchema, list []GenericRecord) ([]byte, error) {
 	if len(list) == 0 {
 		return nil, fmt.Errorf("list is empty")
 	}
-	parquetRecords := schema.ToParquetRecords(list)
-	parquetSchema := parquet.SchemaOf(schema.Interface())
-	buf := new(bytes.Buffer)
-	err := parquet.Write[any](
-		buf,
-		parquetRecords,
-		parquetSchema,
-	)
+	return schema.ToParquetBytes(list, ParquetWriteConfig{})
+}
+
+// recordingExecutor records the environment of each script it's asked to run
+// rather than running it
+type recordingExecutor struct {
+	envVars []map[string]string
+}
+
+func (e *recordingExecutor) ExecuteScript(envVars map[string]string, args *metadata.KubernetesArgs) error {
+	e.envVars = append(e.envVars, envVars)
+	return nil
+}
+
+func TestK8sParquetWriteConfig(t *testing.T) {
+	zstdStore := newLocalK8sOfflineStore(t)
+	zstdStore.parquetConfig = ParquetWriteConfig{Compression: ZstdCompression, RowGroupSize: 5}
+	defaultStore := newLocalK8sOfflineStore(t)
+	tests := map[string]struct {
+		store     *K8sOfflineStore
+		codec     format.CompressionCodec
+		rowGroups int
+	}{
+		"zstd":    {zstdStore, format.Zstd, 4},
+		"default": {defaultStore, format.Snappy, 1},
+	}
+	for name, test := range tests {
+		def := registerTrainingSetSources(t, test.store, 10)
+		def.ID = ResourceID{Name: uuidWithoutDashes(), Variant: name, Type: TrainingSet}
+		if err := test.store.CreateTrainingSetInMemory(def); err != nil {
+			t.Fatalf("%s: could not create training set: %v", name, err)
+		}
+		dir, err := test.store.store.CreateFilePath(fileStoreResourcePath(def.ID))
+		if err != nil {
+			t.Fatalf("%s: could not create file path: %v", name, err)
+		}
+		output, err := test.store.store.NewestFileOfType(dir, filestore.Parquet)
+		if err != nil {
+			t.Fatalf("%s: could not find training set output: %v", name, err)
+		}
+		b, err := test.store.store.Read(output)
+		if err != nil {
+			t.Fatalf("%s: could not read training set output: %v", name, err)
+		}
+		file, err := parquet.OpenFile(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			t.Fatalf("%s: could not open training set output: %v", name, err)
+		}
+		rowGroups := file.Metadata().RowGroups
+		if len(rowGroups) != test.rowGroups {
+			t.Fatalf("%s: expected %d row groups, got %d", name, test.rowGroups, len(rowGroups))
+		}
+		for _, rowGroup := range rowGroups {
+			for _, column := range rowGroup.Columns {
+				if column.MetaData.Codec != test.codec {
+					t.Fatalf("%s: expected column %v to be compressed with %s, got %s", name, column.MetaData.PathInSchema, test.codec, column.MetaData.Codec)
+				}
+			}
+		}
+		if rows := readTrainingRows(t, test.store, def.ID); len(rows) != 20 {
+			t.Fatalf("%s: expected 20 training rows, got %d", name, len(rows))
+		}
+	}
+
+	// A job's own options take precedence over the store's
+	executor := &recordingExecutor{}
+	zstdStore.executor = executor
+	def := registerTrainingSetSources(t, zstdStore, 1)
+	def.ID = ResourceID{Name: uuidWithoutDashes(), Variant: "gzip", Type: TrainingSet}
+	def.ParquetConfig = ParquetWriteConfig{Compression: GzipCompression}
+	if err := zstdStore.CreateTrainingSet(def); err != nil {
+		t.Fatalf("could not create training set: %v", err)
+	}
+	if len(executor.envVars) != 1 {
+		t.Fatalf("expected one training set job, got %d", len(executor.envVars))
+	}
+	if env := executor.envVars[0]; env["PARQUET_COMPRESSION"] != "gzip" || env["PARQUET_ROW_GROUP_SIZE"] != "5" {
+		t.Fatalf("expected job to write gzip with row groups of 5 rows, got %q and %q", env["PARQUET_COMPRESSION"], env["PARQUET_ROW_GROUP_SIZE"])
+	}
+	def.ParquetConfig = ParquetWriteConfig{Compression: "lzo"}
+	def.ID.Variant = "lzo"
+	if err := zstdStore.CreateTrainingSet(def); err == nil {
+		t.Fatalf("expected unsupported compression to fail")
+	}
+}
+
+func TestFileStorePrimaryTableStats(t *testing.T) {
+	store := NewMemoryFileStore()
+	id := ResourceID{Name: "stats", Variant: "v1", Type: Transformation}
+	dir := fmt.Sprintf("featureform/Transformation/%s/%s", id.Name, id.Variant)
+	newest := map[string]string{
+		"2024-01-02-00-00-00-000000/part-0.csv": "entity,value\na,1\nb,2\n",
+		"2024-01-02-00-00-00-000000/part-1.csv": "entity,value\nc,3\n",
+	}
+	// An older run's output shouldn't be counted
+	older := map[string]string{
+		"2024-01-01-00-00-00-000000/part-0.csv": "entity,value\na,1\nb,2\nc,3\nd,4\n",
+	}
+	expectedSize := int64(0)
+	for _, files := range []map[string]string{newest, older} {
+		for name, content := range files {
+			path, err := store.CreateFilePath(fmt.Sprintf("%s/%s", dir, name))
+			if err != nil {
+				t.Fatalf("could not create file path: %v", err)
+			}
+			if err := store.Write(path, []byte(content)); err != nil {
+				t.Fatalf("could not write file: %v", err)
+			}
+		}
+	}
+	for _, content := range newest {
+		expectedSize += int64(len(content))
+	}
+	dirPath, err := store.CreateDirPath(dir)
+	if err != nil {
+		t.Fatalf("could not create dir path: %v", err)
+	}
+	table := &FileStorePrimaryTable{store, dirPath, TableSchema{}, true, id}
+	rows, err := table.NumRows()
+	if err != nil {
+		t.Fatalf("could not count rows: %v", err)
+	}
+	if rows != 3 {
+		t.Fatalf("expected 3 rows, got %d", rows)
+	}
+	size, err := table.SizeBytes()
+	if err != nil {
+		t.Fatalf("could not get size: %v", err)
+	}
+	if size != expectedSize {
+		t.Fatalf("expected %d bytes, got %d", expectedSize, size)
+	}
+}
+
+func TestBlobRegisterResourceValidatesFilterColumns(t *testing.T) {
+	store := NewMemoryFileStore()
+	sourcePath, err := store.CreateFilePath("featureform/sources/users.csv")
+	if err != nil {
+		t.Fatalf("could not create file path: %v", err)
+	}
+	if err := store.Write(sourcePath, []byte("entity,value,ts\na,1,2024-01-01\nb,2,2024-01-02\n")); err != nil {
+		t.Fatalf("could not write source: %v", err)
+	}
+	logger := zaptest.NewLogger(t).Sugar()
+	schema := ResourceSchema{Entity: "entity", Value: "value", TS: "ts", SourceTable: sourcePath.ToURI(), Filter: "value > 1"}
+	valid := ResourceID{Name: "valid", Variant: "v1", Type: Feature}
+	if _, err := blobRegisterResourceFromSourceTable(valid, schema, logger, store); err != nil {
+		t.Fatalf("could not register resource with a valid filter: %v", err)
+	}
+	schema.Filter = "missing > 1"
+	invalid := ResourceID{Name: "invalid", Variant: "v1", Type: Feature}
+	if _, err := blobRegisterResourceFromSourceTable(invalid, schema, logger, store); err == nil {
+		t.Fatalf("expected a filter over a missing column to be rejected")
+	}
+}
+
+func TestBlobOfflineTableWriteBatch(t *testing.T) {
+	k8s := newLocalK8sOfflineStore(t)
+	id := ResourceID{Name: uuidWithoutDashes(), Variant: "v1", Type: Feature}
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+			{Name: "ts", ValueType: Timestamp},
+		},
+	}
+	if _, err := k8s.CreateResourceTable(id, schema); err != nil {
+		t.Fatalf("could not create resource table: %v", err)
+	}
+	if _, err := k8s.CreateResourceTable(id, schema); err == nil {
+		t.Fatalf("expected creating an existing resource table to fail")
+	}
+	table, err := k8s.GetResourceTable(id)
+	if err != nil {
+		t.Fatalf("could not get resource table: %v", err)
+	}
+	ts := time.UnixMilli(0).UTC()
+	if err := table.WriteBatch([]ResourceRecord{{Entity: "a", Value: 1, TS: ts}, {Entity: "b", Value: 2, TS: ts}}); err != nil {
+		t.Fatalf("could not write first batch: %v", err)
+	}
+	if err := table.WriteBatch([]ResourceRecord{{Entity: "a", Value: 3, TS: ts}, {Entity: "c", Value: 4, TS: ts}}); err != nil {
+		t.Fatalf("could not write second batch: %v", err)
+	}
+	source, err := filestore.NewEmptyFilepath(k8s.store.FilestoreType())
+	if err != nil {
+		t.Fatalf("could not create file path: %v", err)
+	}
+	if err := source.ParseFilePath(table.(*BlobOfflineTable).schema.SourceTable); err != nil {
+		t.Fatalf("could not parse source table: %v", err)
+	}
+	iter, err := k8s.store.Serve([]filestore.Filepath{source})
+	if err != nil {
+		t.Fatalf("could not serve source table: %v", err)
+	}
+	actual := make(map[string]interface{})
+	for {
+		row, err := iter.Next()
+		if err != nil {
+			t.Fatalf("could not read source table: %v", err)
+		}
+		if row == nil {
+			break
+		}
+		actual[row["Entity"].(string)] = row["Value"]
+	}
+	expected := map[string]interface{}{"a": int32(3), "b": int32(2), "c": int32(4)}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestRegisterPrimaryFromNewestFiles(t *testing.T) {
+	k8s := newLocalK8sOfflineStore(t)
+	dirKey := uuidWithoutDashes()
+	dir, err := k8s.store.CreateDirPath(dirKey)
 	if err != nil {
-		return nil, fmt.Errorf("could not write parquet file to bytes: %v", err)
+		t.Fatalf("could not create directory path: %v", err)
+	}
+	names := make([][]string, 3)
+	for i := range names {
+		schema, records := getMockSchemaAndRecords(2)
+		for _, record := range records {
+			names[i] = append(names[i], record[1].(string))
+		}
+		parquetBytes, err := convertToParquetBytes(schema, records)
+		if err != nil {
+			t.Fatalf("could not convert records to parquet: %v", err)
+		}
+		path, err := k8s.store.CreateFilePath(fmt.Sprintf("%s/snapshot_%d.parquet", dirKey, i))
+		if err != nil {
+			t.Fatalf("could not create file path: %v", err)
+		}
+		if err := k8s.store.Write(path, parquetBytes); err != nil {
+			t.Fatalf("could not write snapshot: %v", err)
+		}
+		time.Sleep(1 * time.Second) // To guarantee ordering of created in metadata follows write ordering
+	}
+	id := ResourceID{Name: uuidWithoutDashes(), Variant: "v1", Type: Primary}
+	if _, err := k8s.RegisterPrimaryFromNewestFiles(id, dir.ToURI(), 2); err != nil {
+		t.Fatalf("could not register newest files: %v", err)
+	}
+	table, err := k8s.GetPrimaryTable(id)
+	if err != nil {
+		t.Fatalf("could not get primary table: %v", err)
+	}
+	numRows, err := table.NumRows()
+	if err != nil {
+		t.Fatalf("could not get number of rows: %v", err)
+	}
+	if numRows != 4 {
+		t.Fatalf("expected 4 rows from the 2 newest snapshots, got %d", numRows)
+	}
+	iter, err := table.IterateSegment(-1)
+	if err != nil {
+		t.Fatalf("could not iterate primary table: %v", err)
+	}
+	defer iter.Close()
+	nameIdx := -1
+	for i, col := range iter.Columns() {
+		if col == "Name" {
+			nameIdx = i
+		}
+	}
+	if nameIdx == -1 {
+		t.Fatalf("expected a Name column, got %v", iter.Columns())
+	}
+	actual := make(map[string]bool)
+	for iter.Next() {
+		actual[iter.Values()[nameIdx].(string)] = true
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatalf("could not read primary table: %v", err)
+	}
+	expected := make(map[string]bool)
+	for _, name := range append(names[1], names[2]...) {
+		expected[name] = true
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected only the newest snapshots' rows %v, got %v", expected, actual)
 	}
-	return buf.Bytes(), nil
 }
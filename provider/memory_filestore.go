@@ -0,0 +1,276 @@
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	filestore "github.com/featureform/filestore"
+)
+
+// MemoryFileStore is a FileStore that keeps its files in a map, for tests that
+// shouldn't depend on a real file system or cloud storage. Its paths look like
+// mem:///path/to/file.parquet.
+type MemoryFileStore struct {
+	// The modification time given to files as they're written. Defaults to
+	// time.Now, and can be replaced to order files deterministically.
+	Now   func() time.Time
+	mtx   sync.RWMutex
+	files map[string]memoryFile
+}
+
+type memoryFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+func NewMemoryFileStore() *MemoryFileStore {
+	return &MemoryFileStore{
+		Now:   time.Now,
+		files: make(map[string]memoryFile),
+	}
+}
+
+// SetModTime changes when a file was last modified, as if it had been written
+// at modTime.
+func (store *MemoryFileStore) SetModTime(path filestore.Filepath, modTime time.Time) error {
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+	file, has := store.files[path.Key()]
+	if !has {
+		return fmt.Errorf("file %s does not exist", path.Key())
+	}
+	file.modTime = modTime
+	store.files[path.Key()] = file
+	return nil
+}
+
+func (store *MemoryFileStore) Write(path filestore.Filepath, data []byte) error {
+	copied := make([]byte, len(data))
+	copy(copied, data)
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+	store.files[path.Key()] = memoryFile{data: copied, modTime: store.Now()}
+	return nil
+}
+
+func (store *MemoryFileStore) Read(path filestore.Filepath) ([]byte, error) {
+	store.mtx.RLock()
+	defer store.mtx.RUnlock()
+	file, has := store.files[path.Key()]
+	if !has {
+		return nil, fmt.Errorf("file %s does not exist", path.Key())
+	}
+	copied := make([]byte, len(file.data))
+	copy(copied, file.data)
+	return copied, nil
+}
+
+// openReaderAt lets parquet files be served the same way as from blob stores
+func (store *MemoryFileStore) openReaderAt(path filestore.Filepath) (io.ReaderAt, int64, error) {
+	data, err := store.Read(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(data), int64(len(data)), nil
+}
+
+func (store *MemoryFileStore) Serve(files []filestore.Filepath) (Iterator, error) {
+	if len(files) > 1 {
+		return directoryIterator(files, store)
+	}
+	path := files[0]
+	if path.Ext() == filestore.Parquet {
+		return openParquetFile(store, path)
+	}
+	b, err := store.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file: %w", err)
+	}
+	return fileIteratorFromBytes(path.Ext(), b)
+}
+
+// Exists reports whether there's a file at path or under it, as blob stores do
+func (store *MemoryFileStore) Exists(path filestore.Filepath) (bool, error) {
+	store.mtx.RLock()
+	defer store.mtx.RUnlock()
+	for key := range store.files {
+		if strings.HasPrefix(key, path.Key()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (store *MemoryFileStore) Delete(path filestore.Filepath) error {
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+	if _, has := store.files[path.Key()]; !has {
+		return fmt.Errorf("file %s does not exist", path.Key())
+	}
+	delete(store.files, path.Key())
+	return nil
+}
+
+func (store *MemoryFileStore) DeleteAll(path filestore.Filepath) error {
+	prefix := path.Key()
+	if path.IsDir() {
+		prefix += "/"
+	}
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+	for key := range store.files {
+		if strings.HasPrefix(key, prefix) {
+			delete(store.files, key)
+		}
+	}
+	return nil
+}
+
+// datedFiles returns the files under prefix that match any of fileTypes
+func (store *MemoryFileStore) datedFiles(prefix string, fileTypes []filestore.FileType) []datedFile {
+	store.mtx.RLock()
+	defer store.mtx.RUnlock()
+	files := make([]datedFile, 0)
+	for key, file := range store.files {
+		if strings.HasPrefix(key, prefix) && matchesFileType(key, fileTypes) {
+			files = append(files, datedFile{key: key, modTime: file.modTime})
+		}
+	}
+	return files
+}
+
+func (store *MemoryFileStore) NewestFileOfType(prefix filestore.Filepath, fileType filestore.FileType) (filestore.Filepath, error) {
+	return store.NewestFileOfTypes(prefix, fileType)
+}
+
+func (store *MemoryFileStore) NewestFileOfTypes(prefix filestore.Filepath, fileTypes ...filestore.FileType) (filestore.Filepath, error) {
+	newest := newestDatedFiles(store.datedFiles(prefix.Key(), fileTypes), 1)
+	if len(newest) == 0 {
+		return filestore.NewEmptyFilepath(filestore.Memory)
+	}
+	return store.CreateFilePath(newest[0].key)
+}
+
+func (store *MemoryFileStore) NewestNFilesOfType(prefix filestore.Filepath, fileType filestore.FileType, n int) ([]filestore.Filepath, error) {
+	newest := newestDatedFiles(store.datedFiles(prefix.Key(), []filestore.FileType{fileType}), n)
+	paths := make([]filestore.Filepath, len(newest))
+	for i, file := range newest {
+		path, err := store.CreateFilePath(file.key)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = path
+	}
+	return paths, nil
+}
+
+// List returns the files of fileType under dirPath, ordered by key as blob
+// stores list them.
+func (store *MemoryFileStore) List(dirPath filestore.Filepath, fileType filestore.FileType) ([]filestore.Filepath, error) {
+	files := store.datedFiles(dirPath.Key(), []filestore.FileType{fileType})
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].key < files[j].key
+	})
+	paths := make([]filestore.Filepath, 0, len(files))
+	for _, file := range files {
+		path, err := store.CreateFilePath(file.key)
+		if err != nil {
+			return nil, err
+		}
+		if path.Ext() == fileType {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+func (store *MemoryFileStore) NumRows(path filestore.Filepath) (int64, error) {
+	b, err := store.Read(path)
+	if err != nil {
+		return 0, err
+	}
+	return numRowsFromBytes(path.Ext(), b)
+}
+
+func (store *MemoryFileStore) Close() error {
+	return nil
+}
+
+// Upload copies a file from the local file system into the store
+func (store *MemoryFileStore) Upload(sourcePath filestore.Filepath, destPath filestore.Filepath) error {
+	return store.UploadWithProgress(sourcePath, destPath, nil)
+}
+
+// Download copies a file from the store to the local file system
+func (store *MemoryFileStore) Download(sourcePath filestore.Filepath, destPath filestore.Filepath) error {
+	return store.DownloadWithProgress(sourcePath, destPath, nil)
+}
+
+func (store *MemoryFileStore) UploadWithProgress(sourcePath filestore.Filepath, destPath filestore.Filepath, progress TransferProgress) error {
+	content, err := ioutil.ReadFile(sourcePath.Key())
+	if err != nil {
+		return fmt.Errorf("cannot read %s file: %v", sourcePath, err)
+	}
+	size := int64(len(content))
+	progress.report(0, size)
+	if err := store.Write(destPath, content); err != nil {
+		return err
+	}
+	progress.report(size, size)
+	return nil
+}
+
+func (store *MemoryFileStore) DownloadWithProgress(sourcePath filestore.Filepath, destPath filestore.Filepath, progress TransferProgress) error {
+	content, err := store.Read(sourcePath)
+	if err != nil {
+		return fmt.Errorf("cannot read %s file: %v", sourcePath, err)
+	}
+	size := int64(len(content))
+	progress.report(0, size)
+	if err := os.WriteFile(destPath.Key(), content, 0644); err != nil {
+		return fmt.Errorf("cannot write %s file: %v", destPath, err)
+	}
+	progress.report(size, size)
+	return nil
+}
+
+func (store *MemoryFileStore) FilestoreType() filestore.FileStoreType {
+	return filestore.Memory
+}
+
+func (store *MemoryFileStore) AddEnvVars(envVars map[string]string) map[string]string {
+	return envVars
+}
+
+func (store *MemoryFileStore) CreateFilePath(key string) (filestore.Filepath, error) {
+	fp, err := filestore.NewEmptyFilepath(filestore.Memory)
+	if err != nil {
+		return nil, err
+	}
+	if err := fp.SetScheme(filestore.MemoryPrefix); err != nil {
+		return nil, err
+	}
+	if err := fp.SetKey(key); err != nil {
+		return nil, err
+	}
+	if err := fp.Validate(); err != nil {
+		return nil, err
+	}
+	return fp, nil
+}
+
+func (store *MemoryFileStore) CreateDirPath(key string) (filestore.Filepath, error) {
+	fp, err := store.CreateFilePath(key)
+	if err != nil {
+		return nil, err
+	}
+	fp.SetIsDir(true)
+	return fp, nil
+}
@@ -187,6 +187,13 @@ func (store *mongoDBOnlineStore) DeleteTable(feature, variant string) error {
 }
 
 func (table mongoDBOnlineTable) Set(entity string, value interface{}) error {
+	if table.valueType == JSON {
+		text, err := jsonText(value)
+		if err != nil {
+			return err
+		}
+		value = text
+	}
 	upsert := true
 	_, err := table.client.Database(table.database).
 		Collection(table.name).
@@ -234,6 +241,8 @@ func (table mongoDBOnlineTable) Get(entity string) (interface{}, error) {
 		return row.Value.(bool), nil
 	case String, NilType:
 		return row.Value.(string), nil
+	case JSON:
+		return parseJSON([]byte(row.Value.(string)))
 	default:
 		return nil, fmt.Errorf("given data type not recognized: %v", table.valueType)
 	}
@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3v2 "github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// multipartUpload sends an object in numbered parts, starting from 1, and
+// assembles them into the object once every part is sent. Each part is a
+// request of its own, so a part can be sent again if it fails. Nothing is
+// written to the object until the upload is completed.
+type multipartUpload interface {
+	UploadPart(ctx context.Context, number int, data []byte) error
+	Complete(ctx context.Context) error
+	Abort(ctx context.Context) error
+}
+
+// multipartUploader starts a multipart upload to key
+type multipartUploader func(ctx context.Context, key string) (multipartUpload, error)
+
+// s3MultipartUploader uploads to bucket with S3's multipart upload API
+func s3MultipartUploader(client *s3v2.Client, bucket string) multipartUploader {
+	return func(ctx context.Context, key string) (multipartUpload, error) {
+		out, err := client.CreateMultipartUpload(ctx, &s3v2.CreateMultipartUploadInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &s3MultipartUpload{client: client, bucket: bucket, key: key, uploadID: out.UploadId}, nil
+	}
+}
+
+type s3MultipartUpload struct {
+	client   *s3v2.Client
+	bucket   string
+	key      string
+	uploadID *string
+	parts    map[int]*string
+}
+
+func (u *s3MultipartUpload) UploadPart(ctx context.Context, number int, data []byte) error {
+	out, err := u.client.UploadPart(ctx, &s3v2.UploadPartInput{
+		Bucket:     aws.String(u.bucket),
+		Key:        aws.String(u.key),
+		UploadId:   u.uploadID,
+		PartNumber: int32(number),
+		Body:       bytes.NewReader(data),
+	})
+	if err != nil {
+		return err
+	}
+	if u.parts == nil {
+		u.parts = make(map[int]*string)
+	}
+	u.parts[number] = out.ETag
+	return nil
+}
+
+func (u *s3MultipartUpload) Complete(ctx context.Context) error {
+	parts := make([]s3types.CompletedPart, 0, len(u.parts))
+	for number, etag := range u.parts {
+		parts = append(parts, s3types.CompletedPart{ETag: etag, PartNumber: int32(number)})
+	}
+	// S3 requires the parts in ascending order
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+	_, err := u.client.CompleteMultipartUpload(ctx, &s3v2.CompleteMultipartUploadInput{
+		Bucket:          aws.String(u.bucket),
+		Key:             aws.String(u.key),
+		UploadId:        u.uploadID,
+		MultipartUpload: &s3types.CompletedMultipartUpload{Parts: parts},
+	})
+	return err
+}
+
+func (u *s3MultipartUpload) Abort(ctx context.Context) error {
+	_, err := u.client.AbortMultipartUpload(ctx, &s3v2.AbortMultipartUploadInput{
+		Bucket:   aws.String(u.bucket),
+		Key:      aws.String(u.key),
+		UploadId: u.uploadID,
+	})
+	return err
+}
+
+// azureMultipartUploader uploads to container by staging each part as a block
+// of a block blob, and committing the blocks once they're all staged
+func azureMultipartUploader(client *azblob.ServiceClient, container string) multipartUploader {
+	return func(ctx context.Context, key string) (multipartUpload, error) {
+		containerClient, err := client.NewContainerClient(container)
+		if err != nil {
+			return nil, err
+		}
+		blobClient, err := containerClient.NewBlockBlobClient(key)
+		if err != nil {
+			return nil, err
+		}
+		return &azureMultipartUpload{client: blobClient}, nil
+	}
+}
+
+type azureMultipartUpload struct {
+	client *azblob.BlockBlobClient
+	blocks map[int]string
+}
+
+// Block IDs have to be base64 and all the same length
+func azureBlockID(number int) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%010d", number)))
+}
+
+func (u *azureMultipartUpload) UploadPart(ctx context.Context, number int, data []byte) error {
+	id := azureBlockID(number)
+	if _, err := u.client.StageBlock(ctx, id, streaming.NopCloser(bytes.NewReader(data)), nil); err != nil {
+		return err
+	}
+	if u.blocks == nil {
+		u.blocks = make(map[int]string)
+	}
+	u.blocks[number] = id
+	return nil
+}
+
+func (u *azureMultipartUpload) Complete(ctx context.Context) error {
+	numbers := make([]int, 0, len(u.blocks))
+	for number := range u.blocks {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	ids := make([]string, len(numbers))
+	for i, number := range numbers {
+		ids[i] = u.blocks[number]
+	}
+	_, err := u.client.CommitBlockList(ctx, ids, nil)
+	return err
+}
+
+// Abort leaves the staged blocks, since Azure discards blocks that are never
+// committed after a week
+func (u *azureMultipartUpload) Abort(ctx context.Context) error {
+	return nil
+}
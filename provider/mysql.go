@@ -0,0 +1,383 @@
+package provider
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	pc "github.com/featureform/provider/provider_config"
+	pt "github.com/featureform/provider/provider_type"
+	"github.com/go-sql-driver/mysql"
+)
+
+// The longest table or column name MySQL accepts
+const mysqlMaxIdentifierLength = 64
+
+type mysqlColumnType string
+
+const (
+	myTinyInt   mysqlColumnType = "TINYINT"
+	myInt                       = "INT"
+	myBigInt                    = "BIGINT"
+	myFloat                     = "FLOAT"
+	myDouble                    = "DOUBLE"
+	myDecimal                   = "DECIMAL"
+	myString                    = "VARCHAR"
+	myDatetime                  = "DATETIME"
+	myTimestamp                 = "TIMESTAMP"
+)
+
+// MySQL has two types for timestamps. TIMESTAMP converts values from the
+// session's time zone to UTC when they're written and back when they're read,
+// and only covers 1970 to 2038, while DATETIME stores values as they're given.
+// Featureform's tables use DATETIME, and connections set their session and the
+// driver's location to UTC, so timestamps round trip in UTC like they do in
+// Postgres' TIMESTAMPTZ columns. TIMESTAMP columns in users' tables are read
+// in UTC too.
+const mysqlTimestampColumn = "DATETIME(6)"
+
+func mysqlOfflineStoreFactory(config pc.SerializedConfig) (Provider, error) {
+	sc := pc.MySQLConfig{}
+	if err := sc.Deserialize(config); err != nil {
+		return nil, fmt.Errorf("invalid mysql config: %v", config)
+	}
+	queries := mysqlSQLQueries{}
+	queries.setVariableBinding(MySQLBindingStyle)
+	sgConfig := SQLOfflineStoreConfig{
+		Config:        config,
+		ConnectionURL: mysqlConnectionURL(sc),
+		Driver:        "mysql",
+		ProviderType:  pt.MySQLOffline,
+		QueryImpl:     &queries,
+	}
+
+	store, err := NewSQLOfflineStore(sgConfig)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// mysqlConnectionURL builds the DSN for a MySQL config. Sessions quote
+// identifiers with double quotes, like the other SQL stores, on top of the
+// server's SQL mode, and use UTC.
+func mysqlConnectionURL(sc pc.MySQLConfig) string {
+	dsn := mysql.NewConfig()
+	dsn.User = sc.Username
+	dsn.Passwd = sc.Password
+	dsn.Net = "tcp"
+	dsn.Addr = net.JoinHostPort(sc.Host, sc.Port)
+	dsn.DBName = sc.Database
+	dsn.TLSConfig = sc.TLSMode
+	dsn.ParseTime = true
+	dsn.Loc = time.UTC
+	dsn.Params = map[string]string{
+		"sql_mode":  "CONCAT(@@sql_mode, ',ANSI_QUOTES')",
+		"time_zone": "'+00:00'",
+	}
+	return dsn.FormatDSN()
+}
+
+type mysqlSQLQueries struct {
+	defaultOfflineSQLQueries
+}
+
+func (q mysqlSQLQueries) maxIdentifierLength() int {
+	return mysqlMaxIdentifierLength
+}
+
+func (q mysqlSQLQueries) tableExists() string {
+	return "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE' AND table_name = ?"
+}
+
+func (q mysqlSQLQueries) viewExists() string {
+	return "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'VIEW' AND table_name = ?"
+}
+
+func (q mysqlSQLQueries) getTable() string {
+	return "SELECT DISTINCT (table_name) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+}
+
+func (q mysqlSQLQueries) registerResources(db *sql.DB, tableName string, schema ResourceSchema, timestamp bool) error {
+	var query string
+	if timestamp {
+		query = fmt.Sprintf("CREATE VIEW %s AS SELECT %s as entity, %s as value, %s as ts FROM %s", sanitize(tableName),
+			sanitize(schema.Entity), sanitize(schema.Value), sanitize(schema.TS), sanitize(schema.SourceTable))
+	} else {
+		query = fmt.Sprintf("CREATE VIEW %s AS SELECT %s as entity, %s as value, CAST('%s' AS %s) as ts FROM %s", sanitize(tableName),
+			sanitize(schema.Entity), sanitize(schema.Value), time.UnixMilli(0).UTC().Format("2006-01-02 15:04:05"), mysqlTimestampColumn, sanitize(schema.SourceTable))
+	}
+	query += filterClause(schema.Filter)
+	if _, err := db.Exec(query); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (q mysqlSQLQueries) primaryTableRegister(tableName string, sourceName string) string {
+	return fmt.Sprintf("CREATE VIEW %s AS SELECT * FROM %s", sanitize(tableName), sourceName)
+}
+
+func (q mysqlSQLQueries) getColumns(db *sql.DB, tableName string) ([]TableColumn, error) {
+	qry := "SELECT column_name FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? ORDER BY ordinal_position"
+	rows, err := db.Query(qry, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columnNames := make([]TableColumn, 0)
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columnNames = append(columnNames, TableColumn{Name: column})
+	}
+	return columnNames, nil
+}
+
+func (q mysqlSQLQueries) tableSize(db *sql.DB, tableName string) (int64, error) {
+	qry := "SELECT data_length + index_length FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	return scanTableSize(db.QueryRow(qry, tableName), tableName)
+}
+
+func (q mysqlSQLQueries) determineColumnType(valueType ValueType) (string, error) {
+	switch valueType {
+	case Int, Int64:
+		return "BIGINT", nil
+	case Int32:
+		return "INT", nil
+	case Float32:
+		return "FLOAT", nil
+	case Float64:
+		return "DOUBLE", nil
+	case String:
+		return "TEXT", nil
+	case Bool:
+		return "BOOLEAN", nil
+	case Timestamp:
+		return mysqlTimestampColumn, nil
+	case NilType:
+		return "TEXT", nil
+	default:
+		return "", fmt.Errorf("cannot find column type for value type: %s", valueType)
+	}
+}
+
+// Entities are VARCHAR rather than TEXT since MySQL can only index TEXT
+// columns by a prefix
+func (q mysqlSQLQueries) newSQLOfflineTable(name string, columnType string) string {
+	return fmt.Sprintf("CREATE TABLE %s (entity VARCHAR(255), value %s, ts %s, UNIQUE (entity, ts))", sanitize(name), columnType, mysqlTimestampColumn)
+}
+
+// MySQL doesn't have materialized views, so materializations are tables that
+// are rebuilt on update. row_number is a reserved word, so it's quoted.
+func (q mysqlSQLQueries) materializationCreate(tableName string, sourceName string) string {
+	return fmt.Sprintf(
+		"CREATE TABLE %s AS SELECT entity, value, ts, row_number() OVER (ORDER BY entity) AS \"row_number\" FROM "+
+			"(SELECT entity, ts, value, row_number() OVER (PARTITION BY entity ORDER BY ts DESC) "+
+			"AS rn FROM %s) t WHERE rn=1", sanitize(tableName), sanitize(sourceName))
+}
+
+func (q mysqlSQLQueries) materializationUpdate(db *sql.DB, tableName string, sourceName string) error {
+	tempName := mysqlTempTableName(tableName)
+	if _, err := db.Exec(q.materializationCreate(tempName, sourceName)); err != nil {
+		return err
+	}
+	return q.swapTable(db, tableName, tempName)
+}
+
+func (q mysqlSQLQueries) materializationExists() string {
+	return q.getTable()
+}
+
+func (q mysqlSQLQueries) materializationDrop(tableName string) string {
+	return fmt.Sprintf("DROP TABLE %s", sanitize(tableName))
+}
+
+func (q mysqlSQLQueries) materializationIterateSegment(tableName string) string {
+	return fmt.Sprintf("SELECT entity, value, ts FROM %s WHERE \"row_number\">? AND \"row_number\"<=?", sanitize(tableName))
+}
+
+func (q mysqlSQLQueries) sampleLatestValues(tableName string, n int64) string {
+	return fmt.Sprintf("SELECT entity, value, ts FROM (SELECT entity, value, ts, row_number() OVER (PARTITION BY entity ORDER BY ts DESC) AS rn FROM %s) t WHERE rn=1 ORDER BY RAND() LIMIT %d", sanitize(tableName), n)
+}
+
+func (q mysqlSQLQueries) materializationIterateSegmentSince(tableName string) string {
+	return fmt.Sprintf("SELECT entity, value, ts FROM %s WHERE \"row_number\">? AND \"row_number\"<=? AND ts>?", sanitize(tableName))
+}
+
+// MySQL only supports comments on tables, so tagging a view fails
+func (q mysqlSQLQueries) commentOn(tableName string, isView bool, comment string) string {
+	return fmt.Sprintf("ALTER TABLE %s COMMENT = '%s'", sanitize(tableName), strings.ReplaceAll(comment, "'", "''"))
+}
+
+func (q mysqlSQLQueries) writeExists(table string) string {
+	return fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE entity=? AND ts=?", table)
+}
+
+func (q mysqlSQLQueries) trainingSetCreate(store *sqlOfflineStore, def TrainingSetDef, tableName string, labelName string) error {
+	return q.trainingSetQuery(store, def, tableName, labelName, false)
+}
+
+func (q mysqlSQLQueries) trainingSetUpdate(store *sqlOfflineStore, def TrainingSetDef, tableName string, labelName string) error {
+	return q.trainingSetQuery(store, def, tableName, labelName, true)
+}
+
+// trainingSetQuery joins each label to the value of each feature picked by the
+// join strategy, like the Postgres training set query. Lateral joins need
+// MySQL 8.0.14 or later.
+func (q mysqlSQLQueries) trainingSetQuery(store *sqlOfflineStore, def TrainingSetDef, tableName string, labelName string, isUpdate bool) error {
+	columns := make([]string, 0)
+	query := fmt.Sprintf(" (SELECT entity, value, ts FROM %s) l ", sanitize(labelName))
+	for i, feature := range def.Features {
+		tableName, err := store.getResourceTableName(feature)
+		if err != nil {
+			return err
+		}
+		santizedName := sanitize(tableName)
+		tableJoinAlias := fmt.Sprintf("t%d", i)
+		columns = append(columns, santizedName)
+		query = fmt.Sprintf("%s %s LATERAL (SELECT entity, value AS %s, ts FROM %s WHERE entity=l.entity%s ORDER BY ts DESC LIMIT 1) %s ON %s.entity=l.entity ",
+			query, def.JoinPolicy.featureJoin(), santizedName, santizedName, def.JoinStrategy.featureTimeCondition("ts", "l.ts"), tableJoinAlias, tableJoinAlias)
+	}
+	columnStr := strings.Join(columns, ", ")
+
+	if !isUpdate {
+		fullQuery := fmt.Sprintf("CREATE TABLE %s AS SELECT %s, l.value AS label FROM %s", sanitize(tableName), columnStr, query)
+		if _, err := store.db.Exec(fullQuery); err != nil {
+			return err
+		}
+		return nil
+	}
+	tempName := mysqlTempTableName(tableName)
+	fullQuery := fmt.Sprintf("CREATE TABLE %s AS SELECT %s, l.value AS label FROM %s", sanitize(tempName), columnStr, query)
+	if _, err := store.db.Exec(fullQuery); err != nil {
+		return err
+	}
+	return q.swapTable(store.db, tableName, tempName)
+}
+
+func mysqlTempTableName(tableName string) string {
+	return shortenIdentifier(fmt.Sprintf("tmp_%s", tableName), mysqlMaxIdentifierLength)
+}
+
+// swapTable replaces tableName with tempName. MySQL commits implicitly before
+// each DDL statement, so rather than recreating the table in a transaction
+// both tables are renamed at once, which is atomic.
+func (q mysqlSQLQueries) swapTable(db *sql.DB, tableName string, tempName string) error {
+	oldName := shortenIdentifier(fmt.Sprintf("old_%s", tableName), mysqlMaxIdentifierLength)
+	rename := fmt.Sprintf("RENAME TABLE %s TO %s, %s TO %s", sanitize(tableName), sanitize(oldName), sanitize(tempName), sanitize(tableName))
+	if _, err := db.Exec(rename); err != nil {
+		return fmt.Errorf("swap %s with its update: %w", tableName, err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("DROP TABLE %s", sanitize(oldName))); err != nil {
+		return fmt.Errorf("drop previous %s: %w", tableName, err)
+	}
+	return nil
+}
+
+func (q mysqlSQLQueries) transformationCreate(name string, query string) string {
+	return fmt.Sprintf("CREATE TABLE %s AS %s", sanitize(name), query)
+}
+
+func (q mysqlSQLQueries) transformationUpdate(db *sql.DB, tableName string, query string) error {
+	tempName := mysqlTempTableName(tableName)
+	if _, err := db.Exec(q.transformationCreate(tempName, query)); err != nil {
+		return err
+	}
+	return q.swapTable(db, tableName, tempName)
+}
+
+func (q mysqlSQLQueries) transformationExists() string {
+	return q.getTable()
+}
+
+// castTableItemType converts a value read from MySQL to the Go type of its
+// column. Queries without arguments return every value but timestamps as
+// bytes, while the rest return them typed, so both are handled.
+func (q mysqlSQLQueries) castTableItemType(v interface{}, t interface{}) interface{} {
+	if v == nil {
+		return v
+	}
+	if b, ok := v.([]byte); ok {
+		v = string(b)
+	}
+	switch t {
+	case myTinyInt:
+		// BOOLEAN columns are TINYINT(1)
+		switch val := v.(type) {
+		case int64:
+			return val != 0
+		case string:
+			return val != "0"
+		}
+	case myInt:
+		if i, err := mysqlInt(v); err == nil {
+			return int32(i)
+		}
+	case myBigInt:
+		if i, err := mysqlInt(v); err == nil {
+			return int(i)
+		}
+	case myFloat, myDouble, myDecimal:
+		switch val := v.(type) {
+		case float32:
+			return float64(val)
+		case float64:
+			return val
+		case string:
+			if f, err := strconv.ParseFloat(val, 64); err == nil {
+				return f
+			}
+		}
+	case myDatetime, myTimestamp:
+		if ts, ok := v.(time.Time); ok {
+			return ts.UTC()
+		}
+	}
+	return v
+}
+
+func mysqlInt(v interface{}) (int64, error) {
+	switch val := v.(type) {
+	case int64:
+		return val, nil
+	case string:
+		return strconv.ParseInt(val, 10, 64)
+	default:
+		return 0, fmt.Errorf("not an integer: %v", v)
+	}
+}
+
+func (q mysqlSQLQueries) getValueColumnType(t *sql.ColumnType) interface{} {
+	switch strings.TrimPrefix(t.DatabaseTypeName(), "UNSIGNED ") {
+	case "TINYINT":
+		return myTinyInt
+	case "SMALLINT", "MEDIUMINT", "INT":
+		return myInt
+	case "BIGINT":
+		return myBigInt
+	case "FLOAT":
+		return myFloat
+	case "DOUBLE":
+		return myDouble
+	case "DECIMAL":
+		return myDecimal
+	case "DATETIME":
+		return myDatetime
+	case "TIMESTAMP":
+		return myTimestamp
+	}
+	return myString
+}
+
+func (q mysqlSQLQueries) numRows(n interface{}) (int64, error) {
+	if b, ok := n.([]byte); ok {
+		n = string(b)
+	}
+	return mysqlInt(n)
+}
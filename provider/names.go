@@ -0,0 +1,71 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NameGenerator creates unique names for what a store creates on its own
+// behalf, such as materializations and the files a table's data is written to.
+// Names are valid SQL identifiers without quoting.
+type NameGenerator interface {
+	NewName() string
+}
+
+// NamedOfflineStore is implemented by offline stores whose generated names
+// can be replaced, such as so tests can assert on them.
+type NamedOfflineStore interface {
+	OfflineStore
+	SetNameGenerator(names NameGenerator)
+}
+
+// UUIDNameGenerator creates random names.
+type UUIDNameGenerator struct{}
+
+func (UUIDNameGenerator) NewName() string {
+	return strings.ReplaceAll(fmt.Sprintf("a%sa", uuid.NewString()), "-", "")
+}
+
+// dateTimeNameGenerator names files by when they're created, so that the
+// newest sorts last. File stores use it unless a generator is injected.
+type dateTimeNameGenerator struct{}
+
+func (dateTimeNameGenerator) NewName() string {
+	return time.Now().Format("2006-01-02-15-04-05-999999")
+}
+
+// SequenceNameGenerator creates the names <prefix>_1, <prefix>_2 and so on, so
+// tests can assert on the exact names of what they create.
+type SequenceNameGenerator struct {
+	prefix string
+	next   int
+	mtx    sync.Mutex
+}
+
+func NewSequenceNameGenerator(prefix string) *SequenceNameGenerator {
+	return &SequenceNameGenerator{prefix: prefix, next: 1}
+}
+
+func (g *SequenceNameGenerator) NewName() string {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	name := fmt.Sprintf("%s_%d", g.prefix, g.next)
+	g.next++
+	return name
+}
+
+// namesOrDefault returns names, or fallback if none were injected
+func namesOrDefault(names, fallback NameGenerator) NameGenerator {
+	if names == nil {
+		return fallback
+	}
+	return names
+}
@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"go.uber.org/zap/zaptest"
+)
+
+func TestMemoryMaterializationNames(t *testing.T) {
+	var store NamedOfflineStore = NewMemoryOfflineStore()
+	store.SetNameGenerator(NewSequenceNameGenerator("materialization"))
+	id := ResourceID{Name: "feature", Variant: "variant", Type: Feature}
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+			{Name: "ts", ValueType: Timestamp},
+		},
+	}
+	table, err := store.CreateResourceTable(id, schema)
+	if err != nil {
+		t.Fatalf("could not create resource table: %v", err)
+	}
+	if err := table.Write(ResourceRecord{Entity: "a", Value: 1}); err != nil {
+		t.Fatalf("could not write record: %v", err)
+	}
+	for _, expected := range []MaterializationID{"materialization_1", "materialization_2"} {
+		mat, err := store.CreateMaterialization(id)
+		if err != nil {
+			t.Fatalf("could not create materialization: %v", err)
+		}
+		if mat.ID() != expected {
+			t.Fatalf("expected materialization %s, got %s", expected, mat.ID())
+		}
+		if _, err := store.GetMaterialization(expected); err != nil {
+			t.Fatalf("could not get materialization %s: %v", expected, err)
+		}
+	}
+}
+
+func TestFileStoreResourceTableNames(t *testing.T) {
+	fileStore := NewMemoryFileStore()
+	var store NamedOfflineStore = &K8sOfflineStore{
+		store:  fileStore,
+		logger: zaptest.NewLogger(t).Sugar(),
+	}
+	store.SetNameGenerator(NewSequenceNameGenerator("run"))
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+			{Name: "ts", ValueType: Timestamp},
+		},
+	}
+	for i, name := range []string{"first", "second"} {
+		id := ResourceID{Name: name, Variant: "variant", Type: Feature}
+		if _, err := store.CreateResourceTable(id, schema); err != nil {
+			t.Fatalf("could not create resource table: %v", err)
+		}
+		tablePath, err := fileStore.CreateFilePath(id.ToFilestorePath())
+		if err != nil {
+			t.Fatalf("could not create file path: %v", err)
+		}
+		data, err := fileStore.Read(tablePath)
+		if err != nil {
+			t.Fatalf("could not read resource table: %v", err)
+		}
+		resourceSchema := ResourceSchema{}
+		if err := resourceSchema.Deserialize(data); err != nil {
+			t.Fatalf("could not deserialize resource table: %v", err)
+		}
+		expected := fmt.Sprintf("%s/run_%d/src.parquet", tablePath.ToURI(), i+1)
+		if resourceSchema.SourceTable != expected {
+			t.Fatalf("expected %s to be written to %s, got %s", name, expected, resourceSchema.SourceTable)
+		}
+	}
+}
@@ -5,9 +5,13 @@
 package provider
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"go/token"
+	"math/big"
 	"reflect"
 	"sort"
 	"strings"
@@ -16,11 +20,13 @@ import (
 	"golang.org/x/sync/syncmap"
 
 	"github.com/mitchellh/mapstructure"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
 
+	"github.com/featureform/filestore"
 	"github.com/featureform/metadata"
 	pc "github.com/featureform/provider/provider_config"
 	pt "github.com/featureform/provider/provider_type"
-	"github.com/google/uuid"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
@@ -130,11 +136,78 @@ type LagFeatureDef struct {
 	LagDelta       time.Duration
 }
 
+// JoinPolicy determines what happens to a label row when one of the training
+// set's features has no value for its entity at or before the label's timestamp.
+type JoinPolicy string
+
+const (
+	// LeftOuterJoin keeps the label row with a nil value for the missing
+	// feature. It's the default.
+	LeftOuterJoin JoinPolicy = "left_outer"
+	// InnerJoin drops the label row.
+	InnerJoin JoinPolicy = "inner"
+)
+
+func (p JoinPolicy) check() error {
+	switch p {
+	case "", LeftOuterJoin, InnerJoin:
+		return nil
+	default:
+		return fmt.Errorf("unknown join policy: %s", p)
+	}
+}
+
+// featureJoin is the SQL join used to add a feature to the label rows. Lag
+// features are always left outer joined, since a label without enough history
+// for a lag is expected.
+func (p JoinPolicy) featureJoin() string {
+	if p == InnerJoin {
+		return "INNER JOIN"
+	}
+	return "LEFT OUTER JOIN"
+}
+
+// JoinStrategy determines which of a feature's values is joined to a label row.
+type JoinStrategy string
+
+const (
+	// PointInTimeJoin joins each label row to the feature's latest value at or
+	// before the label's timestamp, so that training sets don't include
+	// values from after the label was observed. It's the default.
+	PointInTimeJoin JoinStrategy = "point_in_time"
+	// LatestValueJoin joins each label row to the feature's latest value,
+	// whatever the label's timestamp.
+	LatestValueJoin JoinStrategy = "latest"
+)
+
+func (s JoinStrategy) check() error {
+	switch s {
+	case "", PointInTimeJoin, LatestValueJoin:
+		return nil
+	default:
+		return fmt.Errorf("unknown join strategy: %s", s)
+	}
+}
+
+// featureTimeCondition is the condition added to a feature's SQL join on
+// entity that limits it to values at or before the label's timestamp.
+func (s JoinStrategy) featureTimeCondition(featureTS, labelTS string) string {
+	if s == LatestValueJoin {
+		return ""
+	}
+	return fmt.Sprintf(" AND %s <= %s", featureTS, labelTS)
+}
+
 type TrainingSetDef struct {
-	ID          ResourceID
-	Label       ResourceID
-	Features    []ResourceID
-	LagFeatures []LagFeatureDef
+	ID           ResourceID
+	Label        ResourceID
+	Features     []ResourceID
+	LagFeatures  []LagFeatureDef
+	JoinPolicy   JoinPolicy
+	JoinStrategy JoinStrategy
+	// How the training set is written by offline stores that write it as
+	// parquet, overriding the store's defaults
+	ParquetConfig ParquetWriteConfig
 }
 
 func (def *TrainingSetDef) check() error {
@@ -147,6 +220,12 @@ func (def *TrainingSetDef) check() error {
 	if len(def.Features) == 0 {
 		return errors.New("training set must have atleast one feature")
 	}
+	if err := def.JoinPolicy.check(); err != nil {
+		return err
+	}
+	if err := def.JoinStrategy.check(); err != nil {
+		return err
+	}
 	for i := range def.Features {
 		// We use features[i] to make sure that the Type value is updated to
 		// Feature if it's unset.
@@ -178,6 +257,23 @@ type TransformationConfig struct {
 	SourceMapping []SourceMapping
 	Args          metadata.TransformationArgs
 	ArgType       metadata.TransformationArgType
+	// The file type the result is written as by offline stores backed by a
+	// file store. Defaults to parquet.
+	OutputFormat filestore.FileType
+	// How a parquet result is written, overriding the offline store's defaults
+	ParquetConfig ParquetWriteConfig
+}
+
+// outputFormat returns the file type the transformation's result is written as
+func (m *TransformationConfig) outputFormat() (filestore.FileType, error) {
+	switch m.OutputFormat {
+	case "", filestore.Parquet:
+		return filestore.Parquet, nil
+	case filestore.CSV:
+		return filestore.CSV, nil
+	default:
+		return "", fmt.Errorf("unsupported transformation output format: %s", m.OutputFormat)
+	}
 }
 
 func (m *TransformationConfig) MarshalJSON() ([]byte, error) {
@@ -208,6 +304,8 @@ func (m *TransformationConfig) UnmarshalJSON(data []byte) error {
 		SourceMapping []SourceMapping
 		Args          map[string]interface{}
 		ArgType       metadata.TransformationArgType
+		OutputFormat  filestore.FileType
+		ParquetConfig ParquetWriteConfig
 	}
 
 	var temp tempConfig
@@ -221,6 +319,8 @@ func (m *TransformationConfig) UnmarshalJSON(data []byte) error {
 	m.Query = temp.Query
 	m.Code = temp.Code
 	m.SourceMapping = temp.SourceMapping
+	m.OutputFormat = temp.OutputFormat
+	m.ParquetConfig = temp.ParquetConfig
 
 	err = m.decodeArgs(temp.ArgType, temp.Args)
 	if err != nil {
@@ -294,6 +394,31 @@ type Materialization interface {
 	IterateSegment(begin, end int64) (FeatureIterator, error)
 }
 
+// SampledFeatureOfflineStore is implemented by offline stores that can read the
+// latest values of a random sample of a feature's entities straight from its
+// resource table, without creating or refreshing its materialization.
+type SampledFeatureOfflineStore interface {
+	OfflineStore
+	SampleLatestFeatureValues(id ResourceID, n int64) ([]ResourceRecord, error)
+}
+
+// ValueTypedMaterialization is implemented by materializations that can't
+// tell every value type apart by how it's stored, such as those in file stores,
+// where a JSON object can be stored the same way as a vector. The feature's type
+// is set before its rows are read.
+type ValueTypedMaterialization interface {
+	Materialization
+	SetValueType(t ValueType)
+}
+
+// SinceMaterialization is implemented by materializations whose offline store
+// can skip the rows that aren't newer than a timestamp while reading a segment,
+// rather than returning every row of it. Rows without a timestamp are skipped.
+type SinceMaterialization interface {
+	Materialization
+	IterateSegmentSince(begin, end int64, since time.Time) (FeatureIterator, error)
+}
+
 type FeatureIterator interface {
 	Next() bool
 	Value() ResourceRecord
@@ -374,11 +499,71 @@ type TransformationTable interface {
 	PrimaryTable
 }
 
+// SizedTable is implemented by tables that can report how many bytes their
+// rows take up in storage
+type SizedTable interface {
+	SizeBytes() (int64, error)
+}
+
+// DeletablePrimaryOfflineStore is implemented by offline stores that can remove
+// a registered primary table, so that its source can be registered again. It
+// isn't an error to delete a table that doesn't exist.
+type DeletablePrimaryOfflineStore interface {
+	OfflineStore
+	DeletePrimaryTable(id ResourceID) error
+}
+
+// SourceColumnsOfflineStore is implemented by offline stores that can read the
+// columns of a source table before it's registered as a primary table, so that
+// a source can be checked without creating anything for it.
+type SourceColumnsOfflineStore interface {
+	OfflineStore
+	SourceTableColumns(sourceName string) ([]string, error)
+}
+
+// SnapshotPrimaryOfflineStore is implemented by offline stores that can register
+// a directory of snapshot files as a primary table made up of its n newest
+// files, such as the last week of daily snapshots. The newest files are picked
+// again each time the table is read, so later snapshots are picked up.
+type SnapshotPrimaryOfflineStore interface {
+	OfflineStore
+	RegisterPrimaryFromNewestFiles(id ResourceID, dir string, n int) (PrimaryTable, error)
+}
+
+// DeletableOfflineStore is implemented by offline stores that can also remove
+// a single resource or transformation table, such as when the resource is
+// deleted. Like DeletePrimaryTable, deleting a table that doesn't exist
+// succeeds.
+type DeletableOfflineStore interface {
+	DeletablePrimaryOfflineStore
+	DeleteResourceTable(id ResourceID) error
+	DeleteTransformationTable(id ResourceID) error
+}
+
+// SchemaPrimaryTable is implemented by primary tables that can list their
+// columns without reading any rows.
+type SchemaPrimaryTable interface {
+	PrimaryTable
+	GetSchema() (TableSchema, error)
+}
+
+// OrderedPrimaryTable is implemented by primary tables that the offline store
+// can sort by one of their columns as they're read, so that rows can be
+// processed in order without holding the table in memory. Rows without a
+// value in the column come first.
+type OrderedPrimaryTable interface {
+	PrimaryTable
+	IterateOrdered(column string) (GenericTableIterator, error)
+}
+
 type ResourceSchema struct {
 	Entity      string
 	Value       string
 	TS          string
 	SourceTable string
+	// Optional SQL boolean expression over the source table's columns. Only
+	// rows matching the filter are registered and materialized.
+	Filter string
 }
 
 func (schema *ResourceSchema) Serialize() ([]byte, error) {
@@ -401,11 +586,19 @@ type TableSchema struct {
 	Columns []TableColumn
 	// The complete URL that points to the location of the data file
 	SourceTable string
+	// Set when SourceTable is a table format rather than a single file, such as
+	// an Iceberg table
+	SourceFormat string
+	// Set when SourceTable is a directory of snapshots, to the number of its
+	// newest files that are served together as the table
+	NewestFiles int
 }
 
 type TableSchemaJSONWrapper struct {
-	Columns     []TableColumnJSONWrapper
-	SourceTable string
+	Columns      []TableColumnJSONWrapper
+	SourceTable  string
+	SourceFormat string `json:",omitempty"`
+	NewestFiles  int    `json:",omitempty"`
 }
 
 // This method converts the list of columns into a struct type that can be
@@ -417,15 +610,104 @@ func (schema *TableSchema) Interface() interface{} {
 }
 
 func (schema *TableSchema) Value() reflect.Value {
+	return schema.parquetValue(ParquetWriteConfig{})
+}
+
+// ParquetWriteConfig tunes how records are encoded when a TableSchema is written
+// to parquet. The zero value keeps parquet-go's defaults.
+type ParquetWriteConfig struct {
+	// Size in bytes of the buffer each page is written from. Larger pages fit more
+	// of each embedding and compress better, but use more memory when reading.
+	PageBufferSize int
+	// Dictionary-encode the elements of vector columns. This shrinks quantized
+	// embeddings, whose elements repeat, but rarely helps full precision ones.
+	VectorDictionary bool
+	// Codec pages are compressed with. Defaults to snappy.
+	Compression ParquetCompression
+	// Most rows written to each row group. Smaller row groups let readers skip
+	// more of a file, larger ones compress better. Defaults to parquet-go's
+	// default.
+	RowGroupSize int64
+}
+
+// WithDefaults returns the config with any of its unset options taken from
+// defaults, such as an offline store's defaults for a job's outputs.
+func (config ParquetWriteConfig) WithDefaults(defaults ParquetWriteConfig) ParquetWriteConfig {
+	if config.PageBufferSize == 0 {
+		config.PageBufferSize = defaults.PageBufferSize
+	}
+	if !config.VectorDictionary {
+		config.VectorDictionary = defaults.VectorDictionary
+	}
+	if config.Compression == "" {
+		config.Compression = defaults.Compression
+	}
+	if config.RowGroupSize == 0 {
+		config.RowGroupSize = defaults.RowGroupSize
+	}
+	return config
+}
+
+func (config ParquetWriteConfig) writerOptions() ([]parquet.WriterOption, error) {
+	codec, err := config.Compression.codec()
+	if err != nil {
+		return nil, err
+	}
+	options := []parquet.WriterOption{parquet.Compression(codec)}
+	if config.PageBufferSize > 0 {
+		options = append(options, parquet.PageBufferSize(config.PageBufferSize))
+	}
+	if config.RowGroupSize > 0 {
+		options = append(options, parquet.MaxRowsPerRowGroup(config.RowGroupSize))
+	}
+	return options, nil
+}
+
+// ParquetCompression is the codec a parquet file's pages are compressed with
+type ParquetCompression string
+
+const (
+	SnappyCompression ParquetCompression = "snappy"
+	ZstdCompression   ParquetCompression = "zstd"
+	GzipCompression   ParquetCompression = "gzip"
+	NoCompression     ParquetCompression = "none"
+)
+
+func (compression ParquetCompression) codec() (compress.Codec, error) {
+	switch compression {
+	case "", SnappyCompression:
+		return &parquet.Snappy, nil
+	case ZstdCompression:
+		return &parquet.Zstd, nil
+	case GzipCompression:
+		return &parquet.Gzip, nil
+	case NoCompression:
+		return &parquet.Uncompressed, nil
+	default:
+		return nil, fmt.Errorf("unsupported parquet compression: %s", compression)
+	}
+}
+
+func (schema *TableSchema) parquetValue(config ParquetWriteConfig) reflect.Value {
 	fields := make([]reflect.StructField, len(schema.Columns))
 	for i, col := range schema.Columns {
 		caser := cases.Title(language.English)
 		colType := col.Scalar().Type()
+		decimal, isDecimal := col.ValueType.(DecimalType)
+		if isDecimal {
+			colType = decimal.parquetType()
+		}
 
+		// We need to title case the column name to ensure the fields are public
+		// in the struct we create. Column names that still aren't valid Go
+		// identifiers, such as training set features with a dash in their name,
+		// are named after their position instead.
+		name := caser.String(col.Name)
+		if !token.IsIdentifier(name) || !token.IsExported(name) {
+			name = fmt.Sprintf("Column%d", i)
+		}
 		f := reflect.StructField{
-			// We need to title case the column name to ensure the fields are public
-			// in the struct we create.
-			Name: caser.String(col.Name),
+			Name: name,
 			Type: colType,
 			// At a minimum, we need to set the parquet tag to the column name so that when
 			// we read from the file, the field names match up with the column names as they
@@ -439,6 +721,31 @@ func (schema *TableSchema) Value() reflect.Value {
 			f.Tag = reflect.StructTag(fmt.Sprintf(`parquet:"%s,optional,timestamp"`, col.Name))
 		}
 
+		// Decimals are written as unscaled integers annotated with their
+		// precision and scale, so that they're read back exactly
+		if isDecimal {
+			f.Tag = reflect.StructTag(fmt.Sprintf(`parquet:"%s,optional,decimal(%d:%d)"`, col.Name, decimal.Scale, decimal.Precision))
+		}
+
+		if col.ValueType == JSON {
+			f.Tag = reflect.StructTag(fmt.Sprintf(`parquet:"%s,optional,json"`, col.Name))
+		}
+
+		// Vectors are written as parquet lists of non-nullable elements, which is
+		// the same layout Spark uses and the layout our iterators expect.
+		if col.IsVector() {
+			elemType := colType
+			if elemType.Kind() == reflect.Pointer {
+				elemType = elemType.Elem()
+			}
+			f.Type = reflect.SliceOf(elemType)
+			tag := fmt.Sprintf("%s,optional,list", col.Name)
+			if config.VectorDictionary {
+				tag += ",dict"
+			}
+			f.Tag = reflect.StructTag(fmt.Sprintf(`parquet:"%s"`, tag))
+		}
+
 		fields[i] = f
 	}
 	structType := reflect.StructOf(fields)
@@ -447,8 +754,10 @@ func (schema *TableSchema) Value() reflect.Value {
 
 func (schema *TableSchema) Serialize() ([]byte, error) {
 	wrapper := &TableSchemaJSONWrapper{
-		SourceTable: schema.SourceTable,
-		Columns:     make([]TableColumnJSONWrapper, len(schema.Columns)),
+		SourceTable:  schema.SourceTable,
+		SourceFormat: schema.SourceFormat,
+		NewestFiles:  schema.NewestFiles,
+		Columns:      make([]TableColumnJSONWrapper, len(schema.Columns)),
 	}
 	for i, col := range schema.Columns {
 		wrapper.Columns[i] = TableColumnJSONWrapper{
@@ -477,16 +786,28 @@ func (schema *TableSchema) Deserialize(config []byte) error {
 		}
 	}
 	schema.SourceTable = wrapper.SourceTable
+	schema.SourceFormat = wrapper.SourceFormat
+	schema.NewestFiles = wrapper.NewestFiles
 	return nil
 }
 
 // *NOTE:* pointer types are used for all the scalar types to ensure they
 // can be nullable in the parquet file.
-func (schema *TableSchema) ToParquetRecords(records []GenericRecord) []any {
+func (schema *TableSchema) ToParquetRecords(records []GenericRecord) ([]any, error) {
+	return schema.toParquetRecords(records, ParquetWriteConfig{})
+}
+
+func (schema *TableSchema) toParquetRecords(records []GenericRecord, config ParquetWriteConfig) ([]any, error) {
+	for _, col := range schema.Columns {
+		if decimal, isDecimal := col.ValueType.(DecimalType); isDecimal {
+			if err := decimal.validate(); err != nil {
+				return nil, fmt.Errorf("column %s: %w", col.Name, err)
+			}
+		}
+	}
 	parquetRecords := make([]any, len(records))
-	caser := cases.Title(language.English)
 	for i, record := range records {
-		parquetRecord := schema.Value()
+		parquetRecord := schema.parquetValue(config)
 		for j, value := range record {
 			// if a value is nil, we skip it so that the zero value for the pointer
 			// type is used instead, which will preserve the null value when the parquet
@@ -494,31 +815,140 @@ func (schema *TableSchema) ToParquetRecords(records []GenericRecord) []any {
 			if value == nil {
 				continue
 			}
-			// To ensure the struct fields are public and accessible to other methods,
-			// we need to title case them when setting them.
-			colName := caser.String(schema.Columns[j].Name)
+			field := parquetRecord.Elem().Field(j)
+			if decimal, isDecimal := schema.Columns[j].ValueType.(DecimalType); isDecimal {
+				converted, err := decimal.toParquet(value)
+				if err != nil {
+					return nil, fmt.Errorf("record %d: column %s: %w", i, schema.Columns[j].Name, err)
+				}
+				field.Set(converted)
+				continue
+			}
+			if schema.Columns[j].ValueType == JSON {
+				text, err := jsonText(value)
+				if err != nil {
+					return nil, fmt.Errorf("record %d: column %s: %w", i, schema.Columns[j].Name, err)
+				}
+				field.Set(reflect.ValueOf(&text))
+				continue
+			}
 			switch v := value.(type) {
 			case int:
-				parquetRecord.Elem().FieldByName(colName).Set(reflect.ValueOf(&v))
+				field.Set(reflect.ValueOf(&v))
 			case int32:
-				parquetRecord.Elem().FieldByName(colName).Set(reflect.ValueOf(&v))
+				field.Set(reflect.ValueOf(&v))
 			case int64:
-				parquetRecord.Elem().FieldByName(colName).Set(reflect.ValueOf(&v))
+				field.Set(reflect.ValueOf(&v))
 			case float32:
-				parquetRecord.Elem().FieldByName(colName).Set(reflect.ValueOf(&v))
+				field.Set(reflect.ValueOf(&v))
 			case float64:
-				parquetRecord.Elem().FieldByName(colName).Set(reflect.ValueOf(&v))
+				field.Set(reflect.ValueOf(&v))
 			case string:
-				parquetRecord.Elem().FieldByName(colName).Set(reflect.ValueOf(&v))
+				if schema.Columns[j].Scalar() == Bytes {
+					field.Set(reflect.ValueOf([]byte(v)))
+				} else {
+					field.Set(reflect.ValueOf(&v))
+				}
 			case bool:
-				parquetRecord.Elem().FieldByName(colName).Set(reflect.ValueOf(&v))
+				field.Set(reflect.ValueOf(&v))
 			default:
-				parquetRecord.Elem().FieldByName(colName).Set(reflect.ValueOf(value))
+				field.Set(reflect.ValueOf(value))
 			}
 		}
 		parquetRecords[i] = parquetRecord.Interface()
 	}
-	return parquetRecords
+	return parquetRecords, nil
+}
+
+// ToParquetBytes encodes records as a parquet file with one column per column in
+// the schema.
+func (schema *TableSchema) ToParquetBytes(records []GenericRecord, config ParquetWriteConfig) ([]byte, error) {
+	options, err := config.writerOptions()
+	if err != nil {
+		return nil, err
+	}
+	parquetRecords, err := schema.toParquetRecords(records, config)
+	if err != nil {
+		return nil, err
+	}
+	options = append(options, parquet.SchemaOf(schema.parquetValue(config).Interface()))
+	buf := new(bytes.Buffer)
+	if err := parquet.Write[any](buf, parquetRecords, options...); err != nil {
+		return nil, fmt.Errorf("could not write parquet file to bytes: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// CSVConfig sets how values that have no single text form are encoded when a
+// TableSchema is written to or read from CSV.
+type CSVConfig struct {
+	// Layout of Timestamp columns, as accepted by time.Parse. Defaults to
+	// time.RFC3339.
+	TimestampLayout string
+	// Separator between fields. Defaults to a comma.
+	Delimiter rune
+}
+
+func (config CSVConfig) delimiter() rune {
+	if config.Delimiter == 0 {
+		return ','
+	}
+	return config.Delimiter
+}
+
+func (config CSVConfig) timestampLayout() string {
+	if config.TimestampLayout == "" {
+		return time.RFC3339
+	}
+	return config.TimestampLayout
+}
+
+// ToCSVBytes encodes records as a CSV file with a header of the schema's column
+// names. Null values are written as empty fields.
+func (schema *TableSchema) ToCSVBytes(records []GenericRecord, config CSVConfig) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+	w.Comma = config.delimiter()
+	header := make([]string, len(schema.Columns))
+	for i, col := range schema.Columns {
+		header[i] = col.Name
+	}
+	if err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("could not write csv header: %w", err)
+	}
+	row := make([]string, len(schema.Columns))
+	for i, record := range records {
+		if len(record) != len(schema.Columns) {
+			return nil, fmt.Errorf("record %d has %d values, expected %d", i, len(record), len(schema.Columns))
+		}
+		for j, value := range record {
+			switch v := value.(type) {
+			case nil:
+				row[j] = ""
+			case *big.Rat:
+				decimal, isDecimal := schema.Columns[j].ValueType.(DecimalType)
+				if !isDecimal {
+					return nil, fmt.Errorf("record %d: column %s is not a decimal column", i, schema.Columns[j].Name)
+				}
+				row[j] = v.FloatString(int(decimal.Scale))
+			case time.Time:
+				if schema.Columns[j].ValueType != Timestamp {
+					return nil, fmt.Errorf("record %d: column %s is not a timestamp column", i, schema.Columns[j].Name)
+				}
+				row[j] = v.Format(config.timestampLayout())
+			default:
+				row[j] = fmt.Sprintf("%v", v)
+			}
+		}
+		if err := w.Write(row); err != nil {
+			return nil, fmt.Errorf("could not write csv record %d: %w", i, err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("could not write csv file to bytes: %w", err)
+	}
+	return buf.Bytes(), nil
 }
 
 type TableColumnJSONWrapper struct {
@@ -535,9 +965,15 @@ type memoryOfflineStore struct {
 	tables           syncmap.Map
 	materializations syncmap.Map
 	trainingSets     syncmap.Map
+	// Generates materialization IDs. Defaults to UUIDNameGenerator.
+	names NameGenerator
 	BaseProvider
 }
 
+func (store *memoryOfflineStore) SetNameGenerator(names NameGenerator) {
+	store.names = names
+}
+
 func memoryOfflineStoreFactory(serializedConfig pc.SerializedConfig) (Provider, error) {
 	return NewMemoryOfflineStore(), nil
 }
@@ -641,7 +1077,7 @@ func (store *memoryOfflineStore) CreateMaterialization(id ResourceID) (Materiali
 		return true
 	})
 	sort.Sort(matData)
-	matId := MaterializationID(uuid.NewString())
+	matId := MaterializationID(namesOrDefault(store.names, UUIDNameGenerator{}).NewName())
 	mat := &memoryMaterialization{
 		id:   matId,
 		data: matData,
@@ -704,21 +1140,39 @@ func (store *memoryOfflineStore) CreateTrainingSet(def TrainingSetDef) error {
 		}
 		features[i] = feature
 	}
-	labelRecs := label.records()
-	trainingData := make(trainingRows, len(labelRecs))
-	for i, rec := range labelRecs {
+	trainingData := joinTrainingRows(label.records(), features, def.JoinPolicy, def.JoinStrategy)
+	store.trainingSets.Store(def.ID, trainingData)
+	return nil
+}
+
+// joinTrainingRows builds a training row for each label record, in order, from
+// each feature's value picked by strategy.
+func joinTrainingRows(labelRecs []ResourceRecord, features []*memoryOfflineTable, policy JoinPolicy, strategy JoinStrategy) trainingRows {
+	trainingData := make(trainingRows, 0, len(labelRecs))
+	for _, rec := range labelRecs {
 		featureVals := make([]interface{}, len(features))
+		missing := false
 		for i, feature := range features {
-			featureVals[i] = feature.getLastValueBefore(rec.Entity, rec.TS)
+			var val interface{}
+			var has bool
+			if strategy == LatestValueJoin {
+				val, has = feature.getLastValue(rec.Entity)
+			} else {
+				val, has = feature.getLastValueBefore(rec.Entity, rec.TS)
+			}
+			featureVals[i] = val
+			missing = missing || !has
+		}
+		if missing && policy == InnerJoin {
+			continue
 		}
 		labelVal := rec.Value
-		trainingData[i] = trainingRow{
+		trainingData = append(trainingData, trainingRow{
 			Features: featureVals,
 			Label:    labelVal,
-		}
+		})
 	}
-	store.trainingSets.Store(def.ID, trainingData)
-	return nil
+	return trainingData
 }
 
 func (store *memoryOfflineStore) UpdateTrainingSet(def TrainingSetDef) error {
@@ -814,10 +1268,22 @@ func (table *memoryOfflineTable) records() []ResourceRecord {
 	return allRecs
 }
 
-func (table *memoryOfflineTable) getLastValueBefore(entity string, ts time.Time) interface{} {
+// getLastValue returns the entity's latest value regardless of its
+// timestamp, and whether it had one.
+func (table *memoryOfflineTable) getLastValue(entity string) (interface{}, bool) {
 	recs, has := table.entityMap.Load(entity)
 	if !has {
-		return nil
+		return nil, false
+	}
+	return latestRecord(recs.([]ResourceRecord)).Value, true
+}
+
+// getLastValueBefore returns the entity's latest value at or before ts, and
+// whether it had one.
+func (table *memoryOfflineTable) getLastValueBefore(entity string, ts time.Time) (interface{}, bool) {
+	recs, has := table.entityMap.Load(entity)
+	if !has {
+		return nil, false
 	}
 	sortedRecs := ResourceRecords(recs.([]ResourceRecord))
 	sort.Sort(sortedRecs)
@@ -826,13 +1292,13 @@ func (table *memoryOfflineTable) getLastValueBefore(entity string, ts time.Time)
 		if rec.TS.After(ts) {
 			// Entity was not yet set at timestamp, don't return a record.
 			if i == 0 {
-				return nil
+				return nil, false
 			}
 			// Use the record before this, since it would have been before TS.
-			return sortedRecs[i-1].Value
+			return sortedRecs[i-1].Value, true
 		} else if i == lastIdx {
 			// Every record happened before the TS, use the last record.
-			return rec.Value
+			return rec.Value, true
 		}
 	}
 	// This line should never be able to be reached.
@@ -931,6 +1397,32 @@ func checkTimestamp(rec ResourceRecord) ResourceRecord {
 	return rec
 }
 
+// latestResourceRecords checks recs and keeps only the last record written for
+// each entity and timestamp, which is what writing them one at a time would
+// leave behind. Records keep the order they were first seen in.
+func latestResourceRecords(recs []ResourceRecord) ([]ResourceRecord, error) {
+	type recordKey struct {
+		entity string
+		ts     time.Time
+	}
+	indexes := make(map[recordKey]int, len(recs))
+	latest := make([]ResourceRecord, 0, len(recs))
+	for _, rec := range recs {
+		rec = checkTimestamp(rec)
+		if err := rec.check(); err != nil {
+			return nil, err
+		}
+		key := recordKey{rec.Entity, rec.TS.UTC()}
+		if i, has := indexes[key]; has {
+			latest[i] = rec
+			continue
+		}
+		indexes[key] = len(latest)
+		latest = append(latest, rec)
+	}
+	return latest, nil
+}
+
 type sanitization func(string) string
 
 func replaceSourceName(query string, mapping []SourceMapping, sanitize sanitization) (string, error) {
@@ -18,7 +18,9 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -75,6 +77,28 @@ func TestOfflineStores(t *testing.T) {
 		return postgresConfig.Serialize()
 	}
 
+	mysqlInit := func() pc.SerializedConfig {
+		db := checkEnv("MYSQL_DB")
+		user := checkEnv("MYSQL_USER")
+		password := checkEnv("MYSQL_PASSWORD")
+		var mysqlConfig = pc.MySQLConfig{
+			Host:     "localhost",
+			Port:     "3306",
+			Database: db,
+			Username: user,
+			Password: password,
+			TLSMode:  "false",
+		}
+		return mysqlConfig.Serialize()
+	}
+
+	sqliteInit := func() pc.SerializedConfig {
+		sqliteConfig := pc.SQLiteConfig{
+			Path: filepath.Join(t.TempDir(), "featureform.db"),
+		}
+		return sqliteConfig.Serialize()
+	}
+
 	snowflakeInit := func() (pc.SerializedConfig, pc.SnowflakeConfig) {
 		snowFlakeDatabase := strings.ToUpper(uuid.NewString())
 		t.Log("Snowflake Database: ", snowFlakeDatabase)
@@ -246,6 +270,12 @@ func TestOfflineStores(t *testing.T) {
 	if *provider == "postgres" || *provider == "" {
 		testList = append(testList, testMember{pt.PostgresOffline, postgresInit(), true})
 	}
+	if *provider == "mysql" || *provider == "" {
+		testList = append(testList, testMember{pt.MySQLOffline, mysqlInit(), true})
+	}
+	if (*provider == "sqlite" || *provider == "") && SQLiteSupported {
+		testList = append(testList, testMember{pt.SQLiteOffline, sqliteInit(), false})
+	}
 	if *provider == "snowflake" || *provider == "" {
 		serialSFConfig, snowflakeConfig := snowflakeInit()
 		testList = append(testList, testMember{pt.SnowflakeOffline, serialSFConfig, true})
@@ -299,6 +329,8 @@ func TestOfflineStores(t *testing.T) {
 		"MaterializationNotFound": testMaterializationNotFound,
 		"TrainingSets":            testTrainingSet,
 		"TrainingSetUpdate":       testTrainingSetUpdate,
+		"TrainingSetJoinPolicy":   testTrainingSetJoinPolicy,
+		"TrainingSetPointInTime":  testTrainingSetJoinStrategy,
 		// "TrainingSetLag": testLagFeaturesTrainingSet,
 		"TrainingSetInvalidID":   testGetTrainingSetInvalidResourceID,
 		"GetUnknownTrainingSet":  testGetUnknownTrainingSet,
@@ -311,6 +343,7 @@ func TestOfflineStores(t *testing.T) {
 	testSQLFns := map[string]func(*testing.T, OfflineStore){
 		"PrimaryTableCreate":                 testPrimaryCreateTable,
 		"PrimaryTableWrite":                  testPrimaryTableWrite,
+		"WriteBatch":                         testWriteBatch,
 		"Transformation":                     testTransform,
 		"TransformationUpdate":               testTransformUpdate,
 		"TransformationUpdateWithFeature":    testTransformUpdateWithFeatures,
@@ -320,6 +353,9 @@ func TestOfflineStores(t *testing.T) {
 		"CreateResourceFromSourceNoTS":       testCreateResourceFromSourceNoTS,
 		"CreatePrimaryFromSource":            testCreatePrimaryFromSource,
 		"CreatePrimaryFromNonExistentSource": testCreatePrimaryFromNonExistentSource,
+		"CreatePrimaryFromQuery":             testCreatePrimaryFromQuery,
+		"TagPrimaryTable":                    testTagPrimaryTable,
+		"DeleteResourceTable":                testDeleteResourceTable,
 	}
 
 	psqlInfo := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", os.Getenv("POSTGRES_USER"), os.Getenv("POSTGRES_PASSWORD"), "localhost", "5432", os.Getenv("POSTGRES_DB"))
@@ -542,6 +578,39 @@ func testCreateGetOfflineTable(t *testing.T, store OfflineStore) {
 	}
 }
 
+func testDeleteResourceTable(t *testing.T, store OfflineStore) {
+	deletable, ok := store.(DeletableOfflineStore)
+	if !ok {
+		t.Skipf("%s can't delete tables", store.Type())
+	}
+	id := randomID(Feature, Label)
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+			{Name: "ts", ValueType: Timestamp},
+		},
+	}
+	if _, err := store.CreateResourceTable(id, schema); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if err := deletable.DeleteResourceTable(id); err != nil {
+		t.Fatalf("Failed to delete table: %v", err)
+	}
+	if _, err := store.GetResourceTable(id); err == nil {
+		t.Fatalf("Succeeded in getting deleted table")
+	} else if _, valid := err.(*TableNotFound); !valid {
+		t.Fatalf("Wrong error for deleted table: %T %v", err, err)
+	}
+	// Deleting a table that's already gone succeeds
+	if err := deletable.DeleteResourceTable(id); err != nil {
+		t.Fatalf("Failed to delete missing table: %v", err)
+	}
+	if err := deletable.DeleteTransformationTable(randomID(Transformation)); err != nil {
+		t.Fatalf("Failed to delete missing transformation table: %v", err)
+	}
+}
+
 func testOfflineTableAlreadyExists(t *testing.T, store OfflineStore) {
 	id := randomID(Feature, Label)
 	schema := TableSchema{
@@ -1491,6 +1560,152 @@ func testTrainingSet(t *testing.T, store OfflineStore) {
 	}
 }
 
+func testTrainingSetJoinPolicy(t *testing.T, store OfflineStore) {
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+		},
+	}
+	featureID := randomID(Feature)
+	featureTable, err := store.CreateResourceTable(featureID, schema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %s", err)
+	}
+	// Entity c has a label but no feature value
+	if err := featureTable.WriteBatch([]ResourceRecord{{Entity: "a", Value: 1}, {Entity: "b", Value: 2}}); err != nil {
+		t.Fatalf("Failed to write batch: %v", err)
+	}
+	labelID := randomID(Label)
+	labelTable, err := store.CreateResourceTable(labelID, schema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %s", err)
+	}
+	if err := labelTable.WriteBatch([]ResourceRecord{{Entity: "a", Value: 10}, {Entity: "b", Value: 20}, {Entity: "c", Value: 30}}); err != nil {
+		t.Fatalf("Failed to write batch: %v", err)
+	}
+	expected := map[JoinPolicy]map[interface{}]interface{}{
+		LeftOuterJoin: {10: 1, 20: 2, 30: nil},
+		InnerJoin:     {10: 1, 20: 2},
+	}
+	for policy, expectedRows := range expected {
+		def := TrainingSetDef{
+			ID:         randomID(TrainingSet),
+			Label:      labelID,
+			Features:   []ResourceID{featureID},
+			JoinPolicy: policy,
+		}
+		if err := store.CreateTrainingSet(def); err != nil {
+			t.Fatalf("Failed to create %s training set: %s", policy, err)
+		}
+		iter, err := store.GetTrainingSet(def.ID)
+		if err != nil {
+			t.Fatalf("Failed to get %s training set: %s", policy, err)
+		}
+		rows := make(map[interface{}]interface{})
+		for iter.Next() {
+			rows[iter.Label()] = iter.Features()[0]
+		}
+		if err := iter.Err(); err != nil {
+			t.Fatalf("Failed to iterate %s training set: %s", policy, err)
+		}
+		if !reflect.DeepEqual(rows, expectedRows) {
+			t.Fatalf("%s join: expected label to feature %v, got %v", policy, expectedRows, rows)
+		}
+	}
+	invalid := TrainingSetDef{
+		ID:         randomID(TrainingSet),
+		Label:      labelID,
+		Features:   []ResourceID{featureID},
+		JoinPolicy: "right",
+	}
+	if err := store.CreateTrainingSet(invalid); err == nil {
+		t.Fatalf("Expected unknown join policy to fail")
+	}
+}
+
+// Entity a has several feature values, and each of its labels should be joined
+// to the one that was set as of the label, or to the last one if the training
+// set ignores timestamps. Entity b's label is older than its only feature value.
+func testTrainingSetJoinStrategy(t *testing.T, store OfflineStore) {
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+			{Name: "ts", ValueType: Timestamp},
+		},
+	}
+	at := func(hour int) time.Time {
+		return time.Date(2023, time.January, 1, hour, 0, 0, 0, time.UTC)
+	}
+	featureID := randomID(Feature)
+	featureTable, err := store.CreateResourceTable(featureID, schema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %s", err)
+	}
+	featureRecs := []ResourceRecord{
+		{Entity: "a", Value: 1, TS: at(1)},
+		{Entity: "a", Value: 2, TS: at(3)},
+		{Entity: "a", Value: 3, TS: at(5)},
+		{Entity: "b", Value: 10, TS: at(2)},
+	}
+	if err := featureTable.WriteBatch(featureRecs); err != nil {
+		t.Fatalf("Failed to write batch: %v", err)
+	}
+	labelID := randomID(Label)
+	labelTable, err := store.CreateResourceTable(labelID, schema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %s", err)
+	}
+	labelRecs := []ResourceRecord{
+		{Entity: "a", Value: 100, TS: at(2)},
+		{Entity: "a", Value: 200, TS: at(3)},
+		{Entity: "a", Value: 300, TS: at(6)},
+		{Entity: "b", Value: 400, TS: at(1)},
+	}
+	if err := labelTable.WriteBatch(labelRecs); err != nil {
+		t.Fatalf("Failed to write batch: %v", err)
+	}
+	expected := map[JoinStrategy]map[interface{}]interface{}{
+		PointInTimeJoin: {100: 1, 200: 2, 300: 3, 400: nil},
+		LatestValueJoin: {100: 3, 200: 3, 300: 3, 400: 10},
+	}
+	for strategy, expectedRows := range expected {
+		def := TrainingSetDef{
+			ID:           randomID(TrainingSet),
+			Label:        labelID,
+			Features:     []ResourceID{featureID},
+			JoinStrategy: strategy,
+		}
+		if err := store.CreateTrainingSet(def); err != nil {
+			t.Fatalf("Failed to create %s training set: %s", strategy, err)
+		}
+		iter, err := store.GetTrainingSet(def.ID)
+		if err != nil {
+			t.Fatalf("Failed to get %s training set: %s", strategy, err)
+		}
+		rows := make(map[interface{}]interface{})
+		for iter.Next() {
+			rows[iter.Label()] = iter.Features()[0]
+		}
+		if err := iter.Err(); err != nil {
+			t.Fatalf("Failed to iterate %s training set: %s", strategy, err)
+		}
+		if !reflect.DeepEqual(rows, expectedRows) {
+			t.Fatalf("%s join: expected label to feature %v, got %v", strategy, expectedRows, rows)
+		}
+	}
+	invalid := TrainingSetDef{
+		ID:           randomID(TrainingSet),
+		Label:        labelID,
+		Features:     []ResourceID{featureID},
+		JoinStrategy: "nearest",
+	}
+	if err := store.CreateTrainingSet(invalid); err == nil {
+		t.Fatalf("Expected unknown join strategy to fail")
+	}
+}
+
 func testTrainingSetUpdate(t *testing.T, store OfflineStore) {
 	type expectedTrainingRow struct {
 		Features []interface{}
@@ -2155,6 +2370,109 @@ func testPrimaryCreateTable(t *testing.T, store OfflineStore) {
 	}
 }
 
+func testWriteBatch(t *testing.T, store OfflineStore) {
+	const numRecords = 3000
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+			{Name: "ts", ValueType: Timestamp},
+		},
+	}
+	resourceRecords := make([]ResourceRecord, numRecords)
+	primaryRecords := make([]GenericRecord, numRecords)
+	for i := 0; i < numRecords; i++ {
+		entity := fmt.Sprintf("entity_%d", i)
+		ts := time.UnixMilli(int64(i)).UTC()
+		resourceRecords[i] = ResourceRecord{Entity: entity, Value: i, TS: ts}
+		primaryRecords[i] = GenericRecord{entity, i, ts}
+	}
+	// A later record for the same entity and timestamp replaces the earlier one
+	resourceRecords = append(resourceRecords, ResourceRecord{Entity: "entity_0", Value: numRecords, TS: time.UnixMilli(0).UTC()})
+
+	featureID := randomID(Feature)
+	featureTable, err := store.CreateResourceTable(featureID, schema)
+	if err != nil {
+		t.Fatalf("Failed to create resource table: %s", err)
+	}
+	if err := featureTable.WriteBatch(resourceRecords); err != nil {
+		t.Fatalf("Failed to write batch of resource records: %s", err)
+	}
+	mat, err := store.CreateMaterialization(featureID)
+	if err != nil {
+		t.Fatalf("Failed to create materialization: %s", err)
+	}
+	defer store.DeleteMaterialization(mat.ID())
+	if rows, err := mat.NumRows(); err != nil {
+		t.Fatalf("Failed to get number of rows: %s", err)
+	} else if rows != numRecords {
+		t.Fatalf("Expected %d materialized rows, got %d", numRecords, rows)
+	}
+	expectedValues := make(map[string]interface{}, numRecords)
+	for _, rec := range resourceRecords {
+		expectedValues[rec.Entity] = rec.Value
+	}
+	featureIter, err := mat.IterateSegment(0, numRecords)
+	if err != nil {
+		t.Fatalf("Failed to iterate materialization: %s", err)
+	}
+	defer featureIter.Close()
+	read := 0
+	for featureIter.Next() {
+		rec := featureIter.Value()
+		if !reflect.DeepEqual(expectedValues[rec.Entity], rec.Value) {
+			t.Fatalf("Wrong value for %s: expected %v, got %v", rec.Entity, expectedValues[rec.Entity], rec.Value)
+		}
+		read++
+	}
+	if err := featureIter.Err(); err != nil {
+		t.Fatalf("Failed to read materialization: %s", err)
+	}
+	if read != numRecords {
+		t.Fatalf("Expected to read %d materialized rows, got %d", numRecords, read)
+	}
+
+	primaryID := ResourceID{Name: uuid.NewString(), Type: Primary}
+	primaryTable, err := store.CreatePrimaryTable(primaryID, schema)
+	if err != nil {
+		t.Fatalf("Failed to create primary table: %s", err)
+	}
+	if err := primaryTable.WriteBatch(primaryRecords); err != nil {
+		t.Fatalf("Failed to write batch of primary records: %s", err)
+	}
+	if rows, err := primaryTable.NumRows(); err != nil {
+		t.Fatalf("Failed to get number of rows: %s", err)
+	} else if rows != numRecords {
+		t.Fatalf("Expected %d primary rows, got %d", numRecords, rows)
+	}
+	primaryIter, err := primaryTable.IterateSegment(numRecords)
+	if err != nil {
+		t.Fatalf("Failed to iterate primary table: %s", err)
+	}
+	defer primaryIter.Close()
+	read = 0
+	for primaryIter.Next() {
+		values := primaryIter.Values()
+		i, err := strconv.Atoi(strings.TrimPrefix(values[0].(string), "entity_"))
+		if err != nil {
+			t.Fatalf("Unexpected entity %v: %s", values[0], err)
+		}
+		if !reflect.DeepEqual(primaryRecords[i][1], values[1]) {
+			t.Fatalf("Wrong value for %v: expected %v, got %v", values[0], primaryRecords[i][1], values[1])
+		}
+		if ts, ok := values[2].(time.Time); !ok || !ts.Equal(primaryRecords[i][2].(time.Time)) {
+			t.Fatalf("Wrong timestamp for %v: expected %v, got %v", values[0], primaryRecords[i][2], values[2])
+		}
+		read++
+	}
+	if err := primaryIter.Err(); err != nil {
+		t.Fatalf("Failed to read primary table: %s", err)
+	}
+	if read != numRecords {
+		t.Fatalf("Expected to read %d primary rows, got %d", numRecords, read)
+	}
+}
+
 func testPrimaryTableWrite(t *testing.T, store OfflineStore) {
 	type TestCase struct {
 		Rec         ResourceID
@@ -3572,6 +3890,123 @@ func testCreatePrimaryFromSource(t *testing.T, store OfflineStore) {
 	}
 }
 
+func testCreatePrimaryFromQuery(t *testing.T, store OfflineStore) {
+	queryStore, ok := store.(QueryPrimaryOfflineStore)
+	if !ok {
+		t.Skipf("%s does not support query sources", store.Type())
+	}
+	primaryID := ResourceID{
+		Name:    uuid.NewString(),
+		Variant: uuid.NewString(),
+		Type:    Primary,
+	}
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "col1", ValueType: String},
+			{Name: "col2", ValueType: Int},
+		},
+	}
+	table, err := store.CreatePrimaryTable(primaryID, schema)
+	if err != nil {
+		t.Fatalf("Could not create primary table: %v", err)
+	}
+	records := []GenericRecord{
+		{"a", 1},
+		{"b", 2},
+		{"c", 3},
+		{"d", 4},
+		{"e", 5},
+	}
+	if err := table.WriteBatch(records); err != nil {
+		t.Fatalf("Could not write batch: %v", err)
+	}
+	queryID := ResourceID{
+		Name:    uuid.NewString(),
+		Variant: uuid.NewString(),
+		Type:    Primary,
+	}
+	tableName := sanitizeTableName(string(store.Type()), table.GetName())
+	query := fmt.Sprintf("SELECT * FROM %s WHERE col2 > 2", tableName)
+	if _, err := queryStore.RegisterPrimaryFromQuery(queryID, query); err != nil {
+		t.Fatalf("Could not register primary from query: %v", err)
+	}
+	// The query is snapshotted, so rows written afterwards aren't included
+	if err := table.Write(GenericRecord{"f", 6}); err != nil {
+		t.Fatalf("Could not write record: %v", err)
+	}
+	snapshot, err := store.GetPrimaryTable(queryID)
+	if err != nil {
+		t.Fatalf("Could not get primary table: %v", err)
+	}
+	numRows, err := snapshot.NumRows()
+	if err != nil {
+		t.Fatalf("Could not get num rows: %v", err)
+	}
+	if numRows != 3 {
+		t.Fatalf("Expected 3 rows in query snapshot, got %d", numRows)
+	}
+	if _, err := queryStore.RegisterPrimaryFromQuery(ResourceID{uuid.NewString(), uuid.NewString(), Primary}, fmt.Sprintf("DELETE FROM %s", tableName)); err == nil {
+		t.Fatalf("Expected a query that writes to be rejected")
+	}
+}
+
+func testTagPrimaryTable(t *testing.T, store OfflineStore) {
+	taggable, ok := store.(TaggableOfflineStore)
+	if !ok {
+		t.Skipf("%s does not support tagging tables", store.Type())
+	}
+	sourceID := ResourceID{
+		Name:    uuid.NewString(),
+		Variant: uuid.NewString(),
+		Type:    Primary,
+	}
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+		},
+	}
+	table, err := store.CreatePrimaryTable(sourceID, schema)
+	if err != nil {
+		t.Fatalf("Could not create primary table: %v", err)
+	}
+	primaryID := ResourceID{
+		Name:    uuid.NewString(),
+		Variant: uuid.NewString(),
+		Type:    Primary,
+	}
+	if _, err := store.RegisterPrimaryFromSourceTable(primaryID, sanitizeTableName(string(store.Type()), table.GetName())); err != nil {
+		t.Fatalf("Could not register from source table: %v", err)
+	}
+	tags := ResourceTags{Owner: "owner@featureform.com", Team: "data-platform"}
+	if err := taggable.TagTable(primaryID, tags); err != nil {
+		t.Fatalf("Could not tag table: %v", err)
+	}
+	if store.Type() != pt.PostgresOffline {
+		return
+	}
+	tableName, err := GetPrimaryTableName(primaryID)
+	if err != nil {
+		t.Fatalf("Could not get primary table name: %v", err)
+	}
+	var comment string
+	if err := store.(*sqlOfflineStore).db.QueryRow("SELECT obj_description($1::regclass, 'pg_class')", sanitize(tableName)).Scan(&comment); err != nil {
+		t.Fatalf("Could not get table comment: %v", err)
+	}
+	var actual map[string]string
+	if err := json.Unmarshal([]byte(comment), &actual); err != nil {
+		t.Fatalf("Could not parse table comment %q: %v", comment, err)
+	}
+	expected := map[string]string{
+		"featureform_resource": fmt.Sprintf("Primary__%s__%s", primaryID.Name, primaryID.Variant),
+		"featureform_owner":    tags.Owner,
+		"featureform_team":     tags.Team,
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected table comment %v, got %v", expected, actual)
+	}
+}
+
 func Test_snowflakeOfflineTable_checkTimestamp(t *testing.T) {
 	type fields struct {
 		db   *sql.DB
@@ -3709,6 +4144,8 @@ func getTableName(testName string, tableName string) string {
 	if strings.Contains(testName, "BIGQUERY") {
 		prefix := fmt.Sprintf("%s.%s", os.Getenv("BIGQUERY_PROJECT_ID"), os.Getenv("BIGQUERY_DATASET_ID"))
 		tableName = fmt.Sprintf("`%s.%s`", prefix, tableName)
+	} else if strings.Contains(testName, "MYSQL") {
+		tableName = QuoteIdentifier(pt.MySQLOffline, tableName)
 	} else {
 		tableName = sanitize(tableName)
 	}
@@ -3728,7 +4165,7 @@ func modifyTransformationConfig(t *testing.T, testName, tableName string, provid
 		// In contrast to the SQL provider, that only needed change is the table name to perform the required transformation configuration,
 		// The Spark implementation needs to update the source mappings to ensure the source file is used in the transformation query.
 		config.SourceMapping[0].Source = tableName
-	case pt.MemoryOffline, pt.BigQueryOffline, pt.PostgresOffline, pt.SnowflakeOffline, pt.RedshiftOffline:
+	case pt.MemoryOffline, pt.BigQueryOffline, pt.PostgresOffline, pt.SnowflakeOffline, pt.RedshiftOffline, pt.MySQLOffline, pt.SQLiteOffline:
 		tableName := getTableName(testName, tableName)
 		config.Query = strings.Replace(config.Query, "tb", tableName, 1)
 	default:
@@ -4107,9 +4544,12 @@ func TestTableSchemaToParquetRecords(t *testing.T) {
 	testSchema := func(t *testing.T, test TableSchemaTest) {
 		testFilename := fmt.Sprintf("generic_records_%s.parquet", uuid.NewString())
 		schema := parquet.SchemaOf(test.Schema.Interface())
-		parquetRecords := test.Schema.ToParquetRecords(test.Records)
+		parquetRecords, err := test.Schema.ToParquetRecords(test.Records)
+		if err != nil {
+			t.Fatalf("could not convert records: %v", err)
+		}
 		buf := new(bytes.Buffer)
-		err := parquet.Write[any](buf, parquetRecords, schema)
+		err = parquet.Write[any](buf, parquetRecords, schema)
 		if err != nil {
 			t.Fatalf("Could not write parquet records: %v", err)
 		}
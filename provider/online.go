@@ -5,7 +5,10 @@
 package provider
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"time"
 
 	pc "github.com/featureform/provider/provider_config"
 	pt "github.com/featureform/provider/provider_type"
@@ -18,6 +21,7 @@ var cassandraTypeMap = map[string]string{
 	"float32": "float",
 	"float64": "double",
 	"bool":    "boolean",
+	"json":    "text",
 }
 
 type OnlineStore interface {
@@ -33,6 +37,111 @@ type OnlineStoreTable interface {
 	Get(entity string) (interface{}, error)
 }
 
+// MergeStrategy determines how a value being materialized is combined with the
+// value already stored for its entity.
+type MergeStrategy string
+
+const (
+	// OverwriteMerge replaces the existing value and is the default.
+	OverwriteMerge MergeStrategy = ""
+	SumMerge       MergeStrategy = "sum"
+	MaxMerge       MergeStrategy = "max"
+	MinMerge       MergeStrategy = "min"
+	// UnionMerge adds the elements of a JSON array that aren't already in the
+	// stored array, keeping the stored elements first.
+	UnionMerge MergeStrategy = "union"
+)
+
+// MergeableOnlineStoreTable is implemented by tables that can atomically combine
+// a new value with the one already stored, which makes it safe to merge from
+// multiple materialization chunks at once.
+type MergeableOnlineStoreTable interface {
+	OnlineStoreTable
+	Merge(entity string, value interface{}, strategy MergeStrategy) error
+}
+
+// DeletableOnlineStoreTable is implemented by tables that can remove a single
+// entity, such as to undo a write.
+type DeletableOnlineStoreTable interface {
+	OnlineStoreTable
+	Delete(entity string) error
+}
+
+// EnumerableOnlineStoreTable is implemented by tables that can list every
+// entity they hold, such as to save a table's values before it's truncated.
+type EnumerableOnlineStoreTable interface {
+	OnlineStoreTable
+	Entities() ([]string, error)
+}
+
+// TruncatableOnlineStoreTable is implemented by tables that can remove every
+// entity they hold while leaving the table itself in place.
+type TruncatableOnlineStoreTable interface {
+	OnlineStoreTable
+	Truncate() error
+}
+
+// ErrConflict is returned by a versioned write when the entity has been
+// written since the writer read its version.
+var ErrConflict = errors.New("entity was changed by another writer")
+
+// VersionedOnlineStoreTable is implemented by tables that can make a write
+// conditional on the version of the value currently stored, so that concurrent
+// writers don't silently overwrite each other. An entity that has never been
+// written with SetIfVersion has version 0. Set stays unconditional and doesn't
+// change the version, so only writers that all use SetIfVersion are protected.
+type VersionedOnlineStoreTable interface {
+	OnlineStoreTable
+	GetVersion(entity string) (int64, error)
+	// SetIfVersion sets the entity's value if its stored version is still
+	// version and returns the new version, or returns ErrConflict.
+	SetIfVersion(entity string, value interface{}, version int64) (int64, error)
+}
+
+// ExpiringOnlineStoreTable is implemented by tables whose values can be set to
+// expire, such as features that only matter for a session. Values written with
+// Set never expire.
+type ExpiringOnlineStoreTable interface {
+	OnlineStoreTable
+	// SetWithTTL sets the entity's value, which is removed once ttl has passed
+	SetWithTTL(entity string, value interface{}, ttl time.Duration) error
+}
+
+// ErrExpiryUnsupported is returned by SetWithTTL when the server behind a table
+// is too old to expire values
+var ErrExpiryUnsupported = errors.New("online store does not support expiring values")
+
+// HistoricalOnlineStore is implemented by online stores that can keep every
+// value written to a table rather than only the latest one. Keeping history
+// costs storage for every write, so it's only done for tables created with
+// CreateHistoricalTable; GetTable returns them as HistoricalOnlineStoreTables.
+type HistoricalOnlineStore interface {
+	OnlineStore
+	CreateHistoricalTable(feature, variant string, valueType ValueType) (HistoricalOnlineStoreTable, error)
+}
+
+// HistoricalOnlineStoreTable records a timestamped version of an entity's value
+// on every write. Set records the value as of the time it's called, and Get
+// returns the most recent version. Truncating one, where it's supported,
+// removes every version of every entity and not only the latest values.
+type HistoricalOnlineStoreTable interface {
+	OnlineStoreTable
+	// SetAt records value as the entity's value from ts onwards.
+	SetAt(entity string, value interface{}, ts time.Time) error
+	// GetAsOf returns the entity's value at ts, which is the latest version
+	// recorded at or before it, or EntityNotFound if there was none yet.
+	GetAsOf(entity string, ts time.Time) (interface{}, error)
+}
+
+// RevertibleHistoricalOnlineStoreTable is implemented by historical tables that
+// can remove the versions of an entity recorded at a time, such as to undo a
+// write. The entity's value goes back to its latest remaining version, and it's
+// removed if it has none left.
+type RevertibleHistoricalOnlineStoreTable interface {
+	HistoricalOnlineStoreTable
+	DeleteVersionsAt(entity string, ts time.Time) error
+}
+
 type VectorStore interface {
 	CreateIndex(feature, variant string, vectorType VectorType) (VectorStoreTable, error)
 	DeleteIndex(feature, variant string) error
@@ -85,13 +194,13 @@ func localOnlineStoreFactory(pc.SerializedConfig) (Provider, error) {
 }
 
 type localOnlineStore struct {
-	tables map[tableKey]localOnlineTable
+	tables map[tableKey]OnlineStoreTable
 	BaseProvider
 }
 
 func NewLocalOnlineStore() *localOnlineStore {
 	return &localOnlineStore{
-		make(map[tableKey]localOnlineTable),
+		make(map[tableKey]OnlineStoreTable),
 		BaseProvider{
 			ProviderType:   pt.LocalOnline,
 			ProviderConfig: []byte{},
@@ -116,7 +225,21 @@ func (store *localOnlineStore) CreateTable(feature, variant string, valueType Va
 	if _, has := store.tables[key]; has {
 		return nil, &TableAlreadyExists{feature, variant}
 	}
-	table := make(localOnlineTable)
+	table := localOnlineTable{
+		values:    make(map[string]interface{}),
+		versions:  make(map[string]int64),
+		valueType: valueType,
+	}
+	store.tables[key] = table
+	return table, nil
+}
+
+func (store *localOnlineStore) CreateHistoricalTable(feature, variant string, valueType ValueType) (HistoricalOnlineStoreTable, error) {
+	key := tableKey{feature, variant}
+	if _, has := store.tables[key]; has {
+		return nil, &TableAlreadyExists{feature, variant}
+	}
+	table := localHistoricalTable{history: make(map[string][]localVersion)}
 	store.tables[key] = table
 	return table, nil
 }
@@ -129,17 +252,145 @@ func (store *localOnlineStore) Close() error {
 	return nil
 }
 
-type localOnlineTable map[string]interface{}
+type localOnlineTable struct {
+	values    map[string]interface{}
+	versions  map[string]int64
+	valueType ValueType
+}
 
 func (table localOnlineTable) Set(entity string, value interface{}) error {
-	table[entity] = value
+	value, err := table.checkValue(value)
+	if err != nil {
+		return err
+	}
+	table.values[entity] = value
 	return nil
 }
 
+// checkValue returns the value to store for value. Vectors are checked against
+// the table's dimension and stored as []float32, as the other stores return
+// them. JSON values are stored decoded from their text, as they're returned.
+func (table localOnlineTable) checkValue(value interface{}) (interface{}, error) {
+	if table.valueType == JSON {
+		text, err := jsonText(value)
+		if err != nil {
+			return nil, err
+		}
+		return parseJSON([]byte(text))
+	}
+	vectorType, isVector := table.valueType.(VectorType)
+	if !isVector || value == nil {
+		return value, nil
+	}
+	vector, err := vectorType.vector32(value)
+	if err != nil {
+		return nil, err
+	}
+	return vector, nil
+}
+
 func (table localOnlineTable) Get(entity string) (interface{}, error) {
-	val, has := table[entity]
+	val, has := table.values[entity]
 	if !has {
 		return nil, &EntityNotFound{entity}
 	}
 	return val, nil
 }
+
+func (table localOnlineTable) GetVersion(entity string) (int64, error) {
+	return table.versions[entity], nil
+}
+
+func (table localOnlineTable) SetIfVersion(entity string, value interface{}, version int64) (int64, error) {
+	value, err := table.checkValue(value)
+	if err != nil {
+		return 0, err
+	}
+	current := table.versions[entity]
+	if current != version {
+		return 0, ErrConflict
+	}
+	table.values[entity] = value
+	table.versions[entity] = current + 1
+	return current + 1, nil
+}
+
+func (table localOnlineTable) Delete(entity string) error {
+	delete(table.values, entity)
+	delete(table.versions, entity)
+	return nil
+}
+
+func (table localOnlineTable) Entities() ([]string, error) {
+	entities := make([]string, 0, len(table.values))
+	for entity := range table.values {
+		entities = append(entities, entity)
+	}
+	return entities, nil
+}
+
+func (table localOnlineTable) Truncate() error {
+	for entity := range table.values {
+		delete(table.values, entity)
+	}
+	for entity := range table.versions {
+		delete(table.versions, entity)
+	}
+	return nil
+}
+
+type localVersion struct {
+	ts    time.Time
+	value interface{}
+}
+
+// localHistoricalTable keeps each entity's versions sorted by time
+type localHistoricalTable struct {
+	history map[string][]localVersion
+}
+
+func (table localHistoricalTable) Set(entity string, value interface{}) error {
+	return table.SetAt(entity, value, time.Now())
+}
+
+func (table localHistoricalTable) SetAt(entity string, value interface{}, ts time.Time) error {
+	versions := table.history[entity]
+	i := sort.Search(len(versions), func(i int) bool { return versions[i].ts.After(ts) })
+	versions = append(versions, localVersion{})
+	copy(versions[i+1:], versions[i:])
+	versions[i] = localVersion{ts: ts, value: value}
+	table.history[entity] = versions
+	return nil
+}
+
+func (table localHistoricalTable) Get(entity string) (interface{}, error) {
+	versions := table.history[entity]
+	if len(versions) == 0 {
+		return nil, &EntityNotFound{entity}
+	}
+	return versions[len(versions)-1].value, nil
+}
+
+func (table localHistoricalTable) DeleteVersionsAt(entity string, ts time.Time) error {
+	remaining := make([]localVersion, 0, len(table.history[entity]))
+	for _, version := range table.history[entity] {
+		if !version.ts.Equal(ts) {
+			remaining = append(remaining, version)
+		}
+	}
+	if len(remaining) == 0 {
+		delete(table.history, entity)
+	} else {
+		table.history[entity] = remaining
+	}
+	return nil
+}
+
+func (table localHistoricalTable) GetAsOf(entity string, ts time.Time) (interface{}, error) {
+	versions := table.history[entity]
+	i := sort.Search(len(versions), func(i int) bool { return versions[i].ts.After(ts) })
+	if i == 0 {
+		return nil, &EntityNotFound{entity}
+	}
+	return versions[i-1].value, nil
+}
@@ -0,0 +1,305 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package provider
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+// BatchOnlineStoreTable is implemented by tables that can read many entities in
+// a single round trip.
+type BatchOnlineStoreTable interface {
+	OnlineStoreTable
+	BatchGet(entities []string) (map[string]interface{}, error)
+}
+
+// BatchGet reads the values of entities from table, using the table's own
+// BatchGet if it has one. Entities that aren't in the table are left out of the
+// returned map rather than causing an error.
+func BatchGet(table OnlineStoreTable, entities []string) (map[string]interface{}, error) {
+	if batchTable, ok := table.(BatchOnlineStoreTable); ok {
+		return batchTable.BatchGet(entities)
+	}
+	values := make(map[string]interface{}, len(entities))
+	for _, entity := range entities {
+		val, err := table.Get(entity)
+		if _, notFound := err.(*EntityNotFound); notFound {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("get %s: %w", entity, err)
+		}
+		values[entity] = val
+	}
+	return values, nil
+}
+
+// DefaultOnlineCacheSize is how many values a CachingOnlineStore holds if it
+// isn't given a size.
+const DefaultOnlineCacheSize = 100000
+
+// CachingOnlineStore is a read-through cache in front of an online store. Values
+// are cached the first time they're read, or ahead of time with WarmCache, and
+// dropped when they're set. Once it holds its maximum number of values, the
+// least recently read value is dropped to make room for each new one.
+type CachingOnlineStore struct {
+	OnlineStore
+	mtx        sync.Mutex
+	maxEntries int
+	// Most recently read first
+	recency *list.List
+	values  map[tableKey]map[string]*list.Element
+	// The entities being read from the online store, so that a value that's
+	// set while it's being read isn't cached over
+	reads map[tableKey]map[string]*pendingRead
+}
+
+// pendingRead counts the reads of an entity in flight. Its generation is bumped
+// whenever the entity is set, so that reads that started before then don't
+// cache what they read.
+type pendingRead struct {
+	generation uint64
+	readers    int
+}
+
+type cachedValue struct {
+	key    tableKey
+	entity string
+	value  interface{}
+}
+
+// NewCachingOnlineStore caches up to maxEntries values across all of the
+// store's tables, or DefaultOnlineCacheSize if maxEntries isn't positive.
+func NewCachingOnlineStore(store OnlineStore, maxEntries int) *CachingOnlineStore {
+	if maxEntries <= 0 {
+		maxEntries = DefaultOnlineCacheSize
+	}
+	return &CachingOnlineStore{
+		OnlineStore: store,
+		maxEntries:  maxEntries,
+		recency:     list.New(),
+		values:      make(map[tableKey]map[string]*list.Element),
+		reads:       make(map[tableKey]map[string]*pendingRead),
+	}
+}
+
+func (store *CachingOnlineStore) AsOnlineStore() (OnlineStore, error) {
+	return store, nil
+}
+
+func (store *CachingOnlineStore) GetTable(feature, variant string) (OnlineStoreTable, error) {
+	table, err := store.OnlineStore.GetTable(feature, variant)
+	if err != nil {
+		return nil, err
+	}
+	return &cachingOnlineTable{table: table, store: store, key: tableKey{feature, variant}}, nil
+}
+
+func (store *CachingOnlineStore) CreateTable(feature, variant string, valueType ValueType) (OnlineStoreTable, error) {
+	table, err := store.OnlineStore.CreateTable(feature, variant, valueType)
+	if err != nil {
+		return nil, err
+	}
+	return &cachingOnlineTable{table: table, store: store, key: tableKey{feature, variant}}, nil
+}
+
+func (store *CachingOnlineStore) DeleteTable(feature, variant string) error {
+	store.evictTable(tableKey{feature, variant})
+	return store.OnlineStore.DeleteTable(feature, variant)
+}
+
+// WarmCache reads entities of a feature variant in one batch and caches them, so
+// the first requests for them don't have to go to the online store. It returns
+// the number of entities that were found and cached.
+func (store *CachingOnlineStore) WarmCache(feature, variant string, entities []string) (int, error) {
+	table, err := store.OnlineStore.GetTable(feature, variant)
+	if err != nil {
+		return 0, fmt.Errorf("get table: %w", err)
+	}
+	key := tableKey{feature, variant}
+	generations := make(map[string]uint64, len(entities))
+	for _, entity := range entities {
+		generations[entity] = store.startRead(key, entity)
+	}
+	values, err := BatchGet(table, entities)
+	for _, entity := range entities {
+		val, found := values[entity]
+		store.finishRead(key, entity, generations[entity], val, found && err == nil)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("batch get: %w", err)
+	}
+	return len(values), nil
+}
+
+func (store *CachingOnlineStore) cached(key tableKey, entity string) (interface{}, bool) {
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+	elem, has := store.values[key][entity]
+	if !has {
+		return nil, false
+	}
+	store.recency.MoveToFront(elem)
+	return elem.Value.(*cachedValue).value, true
+}
+
+// startRead records that entity is being read from the online store, and
+// returns the generation that finishRead checks before caching what was read.
+func (store *CachingOnlineStore) startRead(key tableKey, entity string) uint64 {
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+	if _, has := store.reads[key]; !has {
+		store.reads[key] = make(map[string]*pendingRead)
+	}
+	read, has := store.reads[key][entity]
+	if !has {
+		read = &pendingRead{}
+		store.reads[key][entity] = read
+	}
+	read.readers++
+	return read.generation
+}
+
+// finishRead caches val if it was found and entity hasn't been set since the
+// read started.
+func (store *CachingOnlineStore) finishRead(key tableKey, entity string, generation uint64, val interface{}, found bool) {
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+	read := store.reads[key][entity]
+	if found && read.generation == generation {
+		store.cache(key, entity, val)
+	}
+	read.readers--
+	if read.readers == 0 {
+		delete(store.reads[key], entity)
+		if len(store.reads[key]) == 0 {
+			delete(store.reads, key)
+		}
+	}
+}
+
+// cache stores a value. The caller must hold mtx.
+func (store *CachingOnlineStore) cache(key tableKey, entity string, val interface{}) {
+	if elem, has := store.values[key][entity]; has {
+		elem.Value.(*cachedValue).value = val
+		store.recency.MoveToFront(elem)
+		return
+	}
+	if _, has := store.values[key]; !has {
+		store.values[key] = make(map[string]*list.Element)
+	}
+	store.values[key][entity] = store.recency.PushFront(&cachedValue{key: key, entity: entity, value: val})
+	for store.recency.Len() > store.maxEntries {
+		oldest := store.recency.Back().Value.(*cachedValue)
+		store.remove(oldest.key, oldest.entity)
+	}
+}
+
+func (store *CachingOnlineStore) evictTable(key tableKey) {
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+	for _, elem := range store.values[key] {
+		store.recency.Remove(elem)
+	}
+	delete(store.values, key)
+	for _, read := range store.reads[key] {
+		read.generation++
+	}
+}
+
+func (store *CachingOnlineStore) evict(key tableKey, entity string) {
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+	store.remove(key, entity)
+	if read, has := store.reads[key][entity]; has {
+		read.generation++
+	}
+}
+
+// remove drops a cached value. The caller must hold mtx.
+func (store *CachingOnlineStore) remove(key tableKey, entity string) {
+	elem, has := store.values[key][entity]
+	if !has {
+		return
+	}
+	store.recency.Remove(elem)
+	delete(store.values[key], entity)
+	if len(store.values[key]) == 0 {
+		delete(store.values, key)
+	}
+}
+
+type cachingOnlineTable struct {
+	table OnlineStoreTable
+	store *CachingOnlineStore
+	key   tableKey
+}
+
+func (table *cachingOnlineTable) Set(entity string, value interface{}) error {
+	if err := table.table.Set(entity, value); err != nil {
+		return err
+	}
+	table.store.evict(table.key, entity)
+	return nil
+}
+
+func (table *cachingOnlineTable) Get(entity string) (interface{}, error) {
+	if val, has := table.store.cached(table.key, entity); has {
+		return val, nil
+	}
+	generation := table.store.startRead(table.key, entity)
+	val, err := table.table.Get(entity)
+	table.store.finishRead(table.key, entity, generation, val, err == nil)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// BatchGet serves the entities that are cached and reads the rest from the
+// online store in one batch, caching them.
+func (table *cachingOnlineTable) BatchGet(entities []string) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(entities))
+	misses := make([]string, 0)
+	for _, entity := range entities {
+		if val, has := table.store.cached(table.key, entity); has {
+			values[entity] = val
+		} else {
+			misses = append(misses, entity)
+		}
+	}
+	if len(misses) == 0 {
+		return values, nil
+	}
+	generations := make(map[string]uint64, len(misses))
+	for _, entity := range misses {
+		generations[entity] = table.store.startRead(table.key, entity)
+	}
+	read, err := BatchGet(table.table, misses)
+	for _, entity := range misses {
+		val, found := read[entity]
+		table.store.finishRead(table.key, entity, generations[entity], val, found && err == nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for entity, val := range read {
+		values[entity] = val
+	}
+	return values, nil
+}
+
+func (table *cachingOnlineTable) Truncate() error {
+	truncatable, ok := table.table.(TruncatableOnlineStoreTable)
+	if !ok {
+		return fmt.Errorf("table does not support truncation: %T", table.table)
+	}
+	if err := truncatable.Truncate(); err != nil {
+		return err
+	}
+	table.store.evictTable(table.key)
+	return nil
+}
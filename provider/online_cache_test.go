@@ -0,0 +1,257 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+// countingOnlineStore counts the reads that reach its tables
+type countingOnlineStore struct {
+	*localOnlineStore
+	gets, batchGets int
+}
+
+func (store *countingOnlineStore) GetTable(feature, variant string) (OnlineStoreTable, error) {
+	table, err := store.localOnlineStore.GetTable(feature, variant)
+	if err != nil {
+		return nil, err
+	}
+	return &countingOnlineTable{table, store}, nil
+}
+
+type countingOnlineTable struct {
+	OnlineStoreTable
+	store *countingOnlineStore
+}
+
+func (table *countingOnlineTable) Get(entity string) (interface{}, error) {
+	table.store.gets++
+	return table.OnlineStoreTable.Get(entity)
+}
+
+func (table *countingOnlineTable) BatchGet(entities []string) (map[string]interface{}, error) {
+	table.store.batchGets++
+	values := make(map[string]interface{})
+	for _, entity := range entities {
+		if val, err := table.OnlineStoreTable.Get(entity); err == nil {
+			values[entity] = val
+		}
+	}
+	return values, nil
+}
+
+func TestCachingOnlineStoreWarmCache(t *testing.T) {
+	offline := NewMemoryOfflineStore()
+	id := ResourceID{Name: "feature", Variant: "variant", Type: Feature}
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+			{Name: "ts", ValueType: Timestamp},
+		},
+	}
+	resourceTable, err := offline.CreateResourceTable(id, schema)
+	if err != nil {
+		t.Fatalf("could not create resource table: %v", err)
+	}
+	expected := map[string]interface{}{"a": 1, "b": 2, "c": 3, "d": 4}
+	for entity, val := range expected {
+		if err := resourceTable.Write(ResourceRecord{Entity: entity, Value: val}); err != nil {
+			t.Fatalf("could not write record: %v", err)
+		}
+	}
+	mat, err := offline.CreateMaterialization(id)
+	if err != nil {
+		t.Fatalf("could not create materialization: %v", err)
+	}
+	backend := &countingOnlineStore{localOnlineStore: NewLocalOnlineStore()}
+	onlineTable, err := backend.CreateTable(id.Name, id.Variant, Int)
+	if err != nil {
+		t.Fatalf("could not create online table: %v", err)
+	}
+	it, err := mat.IterateSegment(0, int64(len(expected)))
+	if err != nil {
+		t.Fatalf("could not iterate materialization: %v", err)
+	}
+	for it.Next() {
+		if err := onlineTable.Set(it.Value().Entity, it.Value().Value); err != nil {
+			t.Fatalf("could not set online value: %v", err)
+		}
+	}
+
+	store := NewCachingOnlineStore(backend, 0)
+	warmed, err := store.WarmCache(id.Name, id.Variant, []string{"a", "b", "missing"})
+	if err != nil {
+		t.Fatalf("could not warm cache: %v", err)
+	}
+	if warmed != 2 {
+		t.Fatalf("expected 2 entities to be warmed, got %d", warmed)
+	}
+	if backend.batchGets != 1 || backend.gets != 0 {
+		t.Fatalf("expected warming to make a single batch read, got %d batch reads and %d reads", backend.batchGets, backend.gets)
+	}
+	table, err := store.GetTable(id.Name, id.Variant)
+	if err != nil {
+		t.Fatalf("could not get table: %v", err)
+	}
+	for _, entity := range []string{"a", "b"} {
+		val, err := table.Get(entity)
+		if err != nil {
+			t.Fatalf("could not get %s: %v", entity, err)
+		}
+		if val != expected[entity] {
+			t.Fatalf("expected %v for %s, got %v", expected[entity], entity, val)
+		}
+	}
+	if backend.gets != 0 {
+		t.Fatalf("expected warmed entities to be served from the cache, got %d backend reads", backend.gets)
+	}
+	if val, err := table.Get("c"); err != nil || val != expected["c"] {
+		t.Fatalf("expected %v for c, got %v: %v", expected["c"], val, err)
+	}
+	if backend.gets != 1 {
+		t.Fatalf("expected an unwarmed entity to be read from the backend, got %d backend reads", backend.gets)
+	}
+	values, err := BatchGet(table, []string{"a", "d", "missing"})
+	if err != nil {
+		t.Fatalf("could not batch get: %v", err)
+	}
+	if !reflect.DeepEqual(values, map[string]interface{}{"a": 1, "d": 4}) {
+		t.Fatalf("expected cached and uncached values, got %v", values)
+	}
+	if backend.batchGets != 2 || backend.gets != 1 {
+		t.Fatalf("expected only the uncached entities to be read in one batch, got %d batch reads and %d reads", backend.batchGets, backend.gets)
+	}
+	if err := table.Set("a", 10); err != nil {
+		t.Fatalf("could not set a: %v", err)
+	}
+	if val, err := table.Get("a"); err != nil || val != 10 {
+		t.Fatalf("expected set to replace the cached value, got %v: %v", val, err)
+	}
+}
+
+func TestCachingOnlineStoreEvictsLeastRecentlyRead(t *testing.T) {
+	backend := &countingOnlineStore{localOnlineStore: NewLocalOnlineStore()}
+	onlineTable, err := backend.CreateTable("feature", "variant", Int)
+	if err != nil {
+		t.Fatalf("could not create online table: %v", err)
+	}
+	for i, entity := range []string{"a", "b", "c"} {
+		if err := onlineTable.Set(entity, i); err != nil {
+			t.Fatalf("could not set online value: %v", err)
+		}
+	}
+	store := NewCachingOnlineStore(backend, 2)
+	table, err := store.GetTable("feature", "variant")
+	if err != nil {
+		t.Fatalf("could not get table: %v", err)
+	}
+	// a is read again after b, so b is the least recently read once c is cached
+	for _, entity := range []string{"a", "b", "a", "c"} {
+		if _, err := table.Get(entity); err != nil {
+			t.Fatalf("could not get %s: %v", entity, err)
+		}
+	}
+	if backend.gets != 3 {
+		t.Fatalf("expected 3 backend reads, got %d", backend.gets)
+	}
+	for _, entity := range []string{"a", "c"} {
+		if _, err := table.Get(entity); err != nil {
+			t.Fatalf("could not get %s: %v", entity, err)
+		}
+	}
+	if backend.gets != 3 {
+		t.Fatalf("expected a and c to still be cached, got %d backend reads", backend.gets)
+	}
+	if _, err := table.Get("b"); err != nil {
+		t.Fatalf("could not get b: %v", err)
+	}
+	if backend.gets != 4 {
+		t.Fatalf("expected b to have been evicted, got %d backend reads", backend.gets)
+	}
+}
+
+// blockingOnlineTable holds each read after it's made until it's released, so
+// that a test can set the value in between
+type blockingOnlineTable struct {
+	OnlineStoreTable
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (table *blockingOnlineTable) Get(entity string) (interface{}, error) {
+	val, err := table.OnlineStoreTable.Get(entity)
+	table.read <- struct{}{}
+	<-table.release
+	return val, err
+}
+
+type blockingOnlineStore struct {
+	*localOnlineStore
+	table *blockingOnlineTable
+}
+
+func (store *blockingOnlineStore) GetTable(feature, variant string) (OnlineStoreTable, error) {
+	table, err := store.localOnlineStore.GetTable(feature, variant)
+	if err != nil {
+		return nil, err
+	}
+	store.table.OnlineStoreTable = table
+	return store.table, nil
+}
+
+func TestCachingOnlineStoreSetDuringRead(t *testing.T) {
+	reads := map[string]func(table OnlineStoreTable) (interface{}, error){
+		"Get": func(table OnlineStoreTable) (interface{}, error) {
+			return table.Get("a")
+		},
+		"BatchGet": func(table OnlineStoreTable) (interface{}, error) {
+			values, err := BatchGet(table, []string{"a"})
+			return values["a"], err
+		},
+	}
+	for name, read := range reads {
+		t.Run(name, func(t *testing.T) {
+			backend := &blockingOnlineStore{
+				localOnlineStore: NewLocalOnlineStore(),
+				table:            &blockingOnlineTable{read: make(chan struct{}), release: make(chan struct{})},
+			}
+			onlineTable, err := backend.CreateTable("feature", "variant", Int)
+			if err != nil {
+				t.Fatalf("could not create online table: %v", err)
+			}
+			if err := onlineTable.Set("a", 1); err != nil {
+				t.Fatalf("could not set online value: %v", err)
+			}
+			store := NewCachingOnlineStore(backend, 0)
+			table, err := store.GetTable("feature", "variant")
+			if err != nil {
+				t.Fatalf("could not get table: %v", err)
+			}
+			type result struct {
+				val interface{}
+				err error
+			}
+			results := make(chan result, 1)
+			go func() {
+				val, err := read(table)
+				results <- result{val, err}
+			}()
+			// The old value has been read, but not yet cached
+			<-backend.table.read
+			if err := table.Set("a", 2); err != nil {
+				t.Fatalf("could not set a: %v", err)
+			}
+			close(backend.table.release)
+			if res := <-results; res.err != nil || res.val != 1 {
+				t.Fatalf("expected the concurrent read to return 1, got %v: %v", res.val, res.err)
+			}
+			go func() {
+				<-backend.table.read
+			}()
+			if val, err := table.Get("a"); err != nil || val != 2 {
+				t.Fatalf("expected the value read before the set to not be cached, got %v: %v", val, err)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -67,6 +68,11 @@ func TestOnlineStores(t *testing.T) {
 		"EntityNotFound":     testEntityNotFound,
 		"MassTableWrite":     testMassTableWrite,
 		"TypeCasting":        testTypeCasting,
+		"VersionedSet":       testVersionedSet,
+		"ExpiringSet":        testExpiringSet,
+		"HistoricalTable":    testHistoricalTable,
+		"BatchGet":           testBatchGet,
+		"JSONValues":         testJSONValues,
 	}
 
 	// Redis (Mock)
@@ -235,6 +241,180 @@ func testCreateGetTable(t *testing.T, store OnlineStore) {
 	}
 }
 
+func testVersionedSet(t *testing.T, store OnlineStore) {
+	mockFeature, mockVariant := randomFeatureVariant()
+	defer store.DeleteTable(mockFeature, mockVariant)
+	table, err := store.CreateTable(mockFeature, mockVariant, String)
+	if err != nil {
+		t.Fatalf("Failed to create table: %s", err)
+	}
+	versioned, ok := table.(VersionedOnlineStoreTable)
+	if !ok {
+		t.Skipf("%s tables don't support versioned writes", store.Type())
+	}
+	// Both writers read the entity before either of them writes it
+	first, err := versioned.GetVersion("entity")
+	if err != nil {
+		t.Fatalf("Failed to get version: %s", err)
+	}
+	second, err := versioned.GetVersion("entity")
+	if err != nil {
+		t.Fatalf("Failed to get version: %s", err)
+	}
+	newVersion, err := versioned.SetIfVersion("entity", "first", first)
+	if err != nil {
+		t.Fatalf("Failed to set entity at its current version: %s", err)
+	}
+	if newVersion == first {
+		t.Fatalf("Expected version to change from %d", first)
+	}
+	if _, err := versioned.SetIfVersion("entity", "second", second); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected write with stale version to conflict, got %v", err)
+	}
+	if value, err := table.Get("entity"); err != nil || value != "first" {
+		t.Fatalf("Expected conflicting write to leave first value, got %v %v", value, err)
+	}
+	if version, err := versioned.GetVersion("entity"); err != nil || version != newVersion {
+		t.Fatalf("Expected version %d, got %d %v", newVersion, version, err)
+	}
+	// Set stays unconditional
+	if err := table.Set("entity", "third"); err != nil {
+		t.Fatalf("Failed to set entity: %s", err)
+	}
+}
+
+func testExpiringSet(t *testing.T, store OnlineStore) {
+	mockFeature, mockVariant := randomFeatureVariant()
+	defer store.DeleteTable(mockFeature, mockVariant)
+	table, err := store.CreateTable(mockFeature, mockVariant, String)
+	if err != nil {
+		t.Fatalf("Failed to create table: %s", err)
+	}
+	expiring, ok := table.(ExpiringOnlineStoreTable)
+	if !ok {
+		t.Skipf("%s tables don't support expiring values", store.Type())
+	}
+	ttl := time.Second
+	if err := table.Set("expiring", "previous"); err != nil {
+		t.Fatalf("Failed to set entity: %s", err)
+	}
+	if err := expiring.SetWithTTL("expiring", "value", ttl); errors.Is(err, ErrExpiryUnsupported) {
+		// Servers that can't expire values must not keep them without a ttl
+		if value, err := table.Get("expiring"); err != nil || value != "previous" {
+			t.Fatalf("Expected previous value to remain, got %v %v", value, err)
+		}
+		if err := expiring.SetWithTTL("new", "value", ttl); !errors.Is(err, ErrExpiryUnsupported) {
+			t.Fatalf("Expected expiry to be unsupported, got %v", err)
+		}
+		var notFound *EntityNotFound
+		if _, err := table.Get("new"); !errors.As(err, &notFound) {
+			t.Fatalf("Expected entity set with unsupported ttl not to be stored, got %v", err)
+		}
+		return
+	} else if err != nil {
+		t.Fatalf("Failed to set entity with ttl: %s", err)
+	}
+	if err := table.Set("persistent", "value"); err != nil {
+		t.Fatalf("Failed to set entity: %s", err)
+	}
+	if value, err := table.Get("expiring"); err != nil || value != "value" {
+		t.Fatalf("Expected value before ttl passed, got %v %v", value, err)
+	}
+	time.Sleep(ttl + 500*time.Millisecond)
+	var notFound *EntityNotFound
+	if _, err := table.Get("expiring"); !errors.As(err, &notFound) {
+		t.Fatalf("Expected entity to have expired, got %v", err)
+	}
+	if value, err := table.Get("persistent"); err != nil || value != "value" {
+		t.Fatalf("Expected entity without ttl to remain, got %v %v", value, err)
+	}
+}
+
+func testBatchGet(t *testing.T, store OnlineStore) {
+	mockFeature, mockVariant := randomFeatureVariant()
+	defer store.DeleteTable(mockFeature, mockVariant)
+	table, err := store.CreateTable(mockFeature, mockVariant, String)
+	if err != nil {
+		t.Fatalf("Failed to create table: %s", err)
+	}
+	expected := map[string]interface{}{"a": "first", "b": "second"}
+	for entity, value := range expected {
+		if err := table.Set(entity, value); err != nil {
+			t.Fatalf("Failed to set entity: %s", err)
+		}
+	}
+	values, err := BatchGet(table, []string{"a", "missing", "b"})
+	if err != nil {
+		t.Fatalf("Failed to batch get: %s", err)
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("Expected %v with missing entity left out, got %v", expected, values)
+	}
+}
+
+func testHistoricalTable(t *testing.T, store OnlineStore) {
+	historical, ok := store.(HistoricalOnlineStore)
+	if !ok {
+		t.Skipf("%s doesn't support historical tables", store.Type())
+	}
+	mockFeature, mockVariant := randomFeatureVariant()
+	defer store.DeleteTable(mockFeature, mockVariant)
+	if _, err := historical.CreateHistoricalTable(mockFeature, mockVariant, Int); err != nil {
+		t.Fatalf("Failed to create historical table: %s", err)
+	}
+	table, err := store.GetTable(mockFeature, mockVariant)
+	if err != nil {
+		t.Fatalf("Failed to get table: %s", err)
+	}
+	tab, ok := table.(HistoricalOnlineStoreTable)
+	if !ok {
+		t.Fatalf("Expected historical table, got %T", table)
+	}
+	first := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	// Written out of order to check the latest version still wins
+	if err := tab.SetAt("entity", 2, second); err != nil {
+		t.Fatalf("Failed to set entity: %s", err)
+	}
+	if err := tab.SetAt("entity", 1, first); err != nil {
+		t.Fatalf("Failed to set entity: %s", err)
+	}
+	if _, err := tab.GetAsOf("entity", first.Add(-time.Minute)); err == nil {
+		t.Fatalf("Expected entity to be missing before its first version")
+	} else if _, valid := err.(*EntityNotFound); !valid {
+		t.Fatalf("Wrong error for entity not found: %T", err)
+	}
+	asOf := map[time.Time]int{
+		first:                       1,
+		first.Add(30 * time.Minute): 1,
+		second:                      2,
+		second.Add(24 * time.Hour):  2,
+	}
+	for ts, expected := range asOf {
+		if value, err := tab.GetAsOf("entity", ts); err != nil || value != expected {
+			t.Fatalf("Expected %d as of %s, got %v %v", expected, ts, value, err)
+		}
+	}
+	if value, err := tab.Get("entity"); err != nil || value != 2 {
+		t.Fatalf("Expected latest value 2, got %v %v", value, err)
+	}
+	truncatable, ok := table.(TruncatableOnlineStoreTable)
+	if !ok {
+		return
+	}
+	if err := truncatable.Truncate(); err != nil {
+		t.Fatalf("Failed to truncate table: %s", err)
+	}
+	// Truncating removes the history too, not only the latest value
+	for ts := range asOf {
+		if _, err := tab.GetAsOf("entity", ts); err == nil {
+			t.Fatalf("Expected entity to be missing as of %s after truncating", ts)
+		} else if _, valid := err.(*EntityNotFound); !valid {
+			t.Fatalf("Wrong error for entity not found: %T", err)
+		}
+	}
+}
+
 func testTableAlreadyExists(t *testing.T, store OnlineStore) {
 	mockFeature, mockVariant := randomFeatureVariant()
 	defer store.DeleteTable(mockFeature, mockVariant)
@@ -282,6 +462,41 @@ func testSetGetEntity(t *testing.T, store OnlineStore) {
 	}
 }
 
+func testJSONValues(t *testing.T, store OnlineStore) {
+	mockFeature, mockVariant := randomFeatureVariant()
+	defer store.DeleteTable(mockFeature, mockVariant)
+	tab, err := store.CreateTable(mockFeature, mockVariant, JSON)
+	if err != nil {
+		t.Fatalf("Failed to create table: %s", err)
+	}
+	values := map[string]interface{}{
+		"object": map[string]interface{}{
+			"list": []interface{}{1.0, "two"},
+			"geo":  map[string]interface{}{"lat": 37.8, "known": true},
+		},
+		"array":  []interface{}{1.5, nil, []interface{}{"a"}},
+		"string": "not JSON text",
+	}
+	for entity, val := range values {
+		if err := tab.Set(entity, val); err != nil {
+			t.Fatalf("Failed to set entity %s: %s", entity, err)
+		}
+	}
+	tab, err = store.GetTable(mockFeature, mockVariant)
+	if err != nil {
+		t.Fatalf("Failed to get table: %s", err)
+	}
+	for entity, val := range values {
+		gotVal, err := tab.Get(entity)
+		if err != nil {
+			t.Fatalf("Failed to get entity %s: %s", entity, err)
+		}
+		if !reflect.DeepEqual(val, gotVal) {
+			t.Fatalf("Values of %s are not the same %#v %#v", entity, val, gotVal)
+		}
+	}
+}
+
 func testEntityNotFound(t *testing.T, store OnlineStore) {
 	mockFeature, mockVariant := uuid.NewString(), "v"
 	entity := "e"
@@ -0,0 +1,1181 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package provider
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ORC
+// ORC files (https://orc.apache.org/specification/ORCv1/) end with a postscript
+// that locates the file's footer, which holds its schema and the position of each
+// stripe. A stripe holds a block of rows, stored column by column as streams of
+// run length encoded values. Stripes are decoded one at a time, and only columns
+// of scalar types are supported.
+
+var orcMagic = []byte("ORC")
+
+// Compression codecs, as the postscript numbers them
+const (
+	orcCompressionNone   = 0
+	orcCompressionZlib   = 1
+	orcCompressionSnappy = 2
+	orcCompressionLZO    = 3
+	orcCompressionLZ4    = 4
+	orcCompressionZstd   = 5
+)
+
+// The size of the chunks a compressed file is split into, which the postscript
+// only records when it isn't the default
+const orcDefaultCompressionBlockSize = 256 * 1024
+
+// Decoders are safe for concurrent use, so every file shares one
+var orcZstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+
+type orcTypeKind uint64
+
+const (
+	orcBoolean orcTypeKind = iota
+	orcByte
+	orcShort
+	orcInt
+	orcLong
+	orcFloat
+	orcDouble
+	orcString
+	orcBinary
+	orcTimestamp
+	orcList
+	orcMap
+	orcStruct
+	orcUnion
+	orcDecimal
+	orcDate
+	orcVarchar
+	orcChar
+	orcTimestampInstant
+)
+
+// Kinds of the streams a stripe stores its columns in. Index and bloom filter
+// streams are skipped.
+const (
+	orcPresentStream        = 0
+	orcDataStream           = 1
+	orcLengthStream         = 2
+	orcDictionaryDataStream = 3
+	orcSecondaryStream      = 5
+)
+
+var orcReadStreams = map[uint64]bool{
+	orcPresentStream:        true,
+	orcDataStream:           true,
+	orcLengthStream:         true,
+	orcDictionaryDataStream: true,
+	orcSecondaryStream:      true,
+}
+
+// Column encodings. The _V2 encodings use version 2 of the integer run length
+// encoding.
+const (
+	orcDirect       = 0
+	orcDictionary   = 1
+	orcDirectV2     = 2
+	orcDictionaryV2 = 3
+)
+
+// Timestamps are stored as seconds since the start of 2015
+var orcTimestampEpoch = time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type orcType struct {
+	kind       orcTypeKind
+	subtypes   []uint64
+	fieldNames []string
+}
+
+type orcStripe struct {
+	offset       uint64
+	indexLength  uint64
+	dataLength   uint64
+	footerLength uint64
+	numRows      uint64
+}
+
+type orcColumn struct {
+	name string
+	id   uint64
+	typ  orcType
+}
+
+type ORCIterator struct {
+	file      []byte
+	codec     orcCodec
+	columns   []orcColumn
+	stripes   []orcStripe
+	stripeIdx int
+	// The values of the current stripe's rows, by column
+	values         [][]interface{}
+	rowIdx         int
+	stripeRows     int
+	featureColumns []string
+	labelColumn    string
+}
+
+type orcTail struct {
+	codec   orcCodec
+	types   []orcType
+	stripes []orcStripe
+	numRows uint64
+}
+
+func orcIteratorFromBytes(b []byte) (Iterator, error) {
+	tail, err := readORCTail(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(tail.types) == 0 || tail.types[0].kind != orcStruct {
+		return nil, fmt.Errorf("orc schema must be a struct")
+	}
+	root := tail.types[0]
+	if len(root.fieldNames) != len(root.subtypes) {
+		return nil, fmt.Errorf("orc schema has %d field names for %d fields", len(root.fieldNames), len(root.subtypes))
+	}
+	iter := &ORCIterator{
+		file:    b,
+		codec:   tail.codec,
+		stripes: tail.stripes,
+	}
+	schema := parquetSchema{}
+	for i, id := range root.subtypes {
+		if id >= uint64(len(tail.types)) {
+			return nil, fmt.Errorf("orc field %s has unknown type %d", root.fieldNames[i], id)
+		}
+		column := orcColumn{name: root.fieldNames[i], id: id, typ: tail.types[id]}
+		switch column.typ.kind {
+		case orcList, orcMap, orcStruct, orcUnion:
+			return nil, fmt.Errorf("orc column %s: nested types are not supported", column.name)
+		}
+		iter.columns = append(iter.columns, column)
+		schema.setColumn(schema.getColumnType(column.name), column.name)
+	}
+	iter.featureColumns = schema.featureColumns
+	iter.labelColumn = schema.labelColumn
+	return iter, nil
+}
+
+func (o *ORCIterator) Next() (map[string]interface{}, error) {
+	for o.rowIdx >= o.stripeRows {
+		if o.stripeIdx >= len(o.stripes) {
+			return nil, nil
+		}
+		if err := o.readStripe(o.stripes[o.stripeIdx]); err != nil {
+			return nil, fmt.Errorf("could not read orc stripe %d: %w", o.stripeIdx, err)
+		}
+		o.stripeIdx += 1
+	}
+	row := make(map[string]interface{}, len(o.columns))
+	for i, column := range o.columns {
+		row[column.name] = o.values[i][o.rowIdx]
+	}
+	o.rowIdx += 1
+	return row, nil
+}
+
+func (o *ORCIterator) FeatureColumns() []string {
+	return o.featureColumns
+}
+
+func (o *ORCIterator) LabelColumn() string {
+	return o.labelColumn
+}
+
+func getORCNumRows(b []byte) (int64, error) {
+	tail, err := readORCTail(b)
+	if err != nil {
+		return 0, err
+	}
+	return int64(tail.numRows), nil
+}
+
+// readORCTailAt reads only the postscript and footer at the end of a file of the
+// given size
+func readORCTailAt(r io.ReaderAt, size int64) (orcTail, error) {
+	if size <= int64(len(orcMagic)) {
+		return orcTail{}, fmt.Errorf("not an orc file")
+	}
+	last := make([]byte, 1)
+	if err := readFullAt(r, last, size-1); err != nil {
+		return orcTail{}, fmt.Errorf("could not read orc postscript: %w", err)
+	}
+	psLength := int64(last[0])
+	if psLength+1 > size-int64(len(orcMagic)) {
+		return orcTail{}, fmt.Errorf("could not read orc postscript: %w", io.ErrUnexpectedEOF)
+	}
+	ps := make([]byte, psLength)
+	if err := readFullAt(r, ps, size-1-psLength); err != nil {
+		return orcTail{}, fmt.Errorf("could not read orc postscript: %w", err)
+	}
+	postscript, err := orcFields(ps)
+	if err != nil {
+		return orcTail{}, fmt.Errorf("could not read orc postscript: %w", err)
+	}
+	var footerLength int64
+	for _, field := range postscript {
+		if field.num == 1 {
+			footerLength = int64(field.value)
+		}
+	}
+	tailLength := footerLength + psLength + 1
+	if footerLength < 0 || tailLength > size-int64(len(orcMagic)) {
+		return orcTail{}, fmt.Errorf("could not read orc footer: %w", io.ErrUnexpectedEOF)
+	}
+	// The footer's offsets are relative to the end of the file, so the tail can
+	// be parsed as if it directly followed the magic at the start
+	tail := make([]byte, int64(len(orcMagic))+tailLength)
+	copy(tail, orcMagic)
+	if err := readFullAt(r, tail[len(orcMagic):], size-tailLength); err != nil {
+		return orcTail{}, fmt.Errorf("could not read orc footer: %w", err)
+	}
+	return readORCTail(tail)
+}
+
+func readFullAt(r io.ReaderAt, p []byte, off int64) error {
+	n, err := r.ReadAt(p, off)
+	if n == len(p) {
+		return nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// readORCTail reads the postscript from the last bytes of the file, and the
+// footer it locates
+func readORCTail(b []byte) (orcTail, error) {
+	if len(b) <= len(orcMagic) || !bytes.HasPrefix(b, orcMagic) {
+		return orcTail{}, fmt.Errorf("not an orc file")
+	}
+	psEnd := len(b) - 1
+	psStart := psEnd - int(b[psEnd])
+	if psStart < len(orcMagic) {
+		return orcTail{}, fmt.Errorf("could not read orc postscript: %w", io.ErrUnexpectedEOF)
+	}
+	postscript, err := orcFields(b[psStart:psEnd])
+	if err != nil {
+		return orcTail{}, fmt.Errorf("could not read orc postscript: %w", err)
+	}
+	tail := orcTail{codec: orcCodec{blockSize: orcDefaultCompressionBlockSize}}
+	var footerLength uint64
+	for _, field := range postscript {
+		switch field.num {
+		case 1:
+			footerLength = field.value
+		case 2:
+			tail.codec.compression = field.value
+		case 3:
+			tail.codec.blockSize = field.value
+		}
+	}
+	switch tail.codec.compression {
+	case orcCompressionNone, orcCompressionZlib, orcCompressionSnappy, orcCompressionLZ4, orcCompressionZstd:
+	case orcCompressionLZO:
+		return orcTail{}, fmt.Errorf("unsupported orc compression: LZO")
+	default:
+		return orcTail{}, fmt.Errorf("unsupported orc compression: %d", tail.codec.compression)
+	}
+	if footerLength > uint64(psStart) {
+		return orcTail{}, fmt.Errorf("could not read orc footer: %w", io.ErrUnexpectedEOF)
+	}
+	footer, err := tail.codec.decompress(b[psStart-int(footerLength) : psStart])
+	if err != nil {
+		return orcTail{}, fmt.Errorf("could not decompress orc footer: %w", err)
+	}
+	fields, err := orcFields(footer)
+	if err != nil {
+		return orcTail{}, fmt.Errorf("could not read orc footer: %w", err)
+	}
+	for _, field := range fields {
+		switch field.num {
+		case 3:
+			stripe, err := parseORCStripe(field.bytes)
+			if err != nil {
+				return orcTail{}, fmt.Errorf("could not read orc stripe information: %w", err)
+			}
+			tail.stripes = append(tail.stripes, stripe)
+		case 4:
+			typ, err := parseORCType(field.bytes)
+			if err != nil {
+				return orcTail{}, fmt.Errorf("could not read orc type: %w", err)
+			}
+			tail.types = append(tail.types, typ)
+		case 6:
+			tail.numRows = field.value
+		}
+	}
+	return tail, nil
+}
+
+func parseORCStripe(b []byte) (orcStripe, error) {
+	fields, err := orcFields(b)
+	if err != nil {
+		return orcStripe{}, err
+	}
+	stripe := orcStripe{}
+	for _, field := range fields {
+		switch field.num {
+		case 1:
+			stripe.offset = field.value
+		case 2:
+			stripe.indexLength = field.value
+		case 3:
+			stripe.dataLength = field.value
+		case 4:
+			stripe.footerLength = field.value
+		case 5:
+			stripe.numRows = field.value
+		}
+	}
+	return stripe, nil
+}
+
+func parseORCType(b []byte) (orcType, error) {
+	fields, err := orcFields(b)
+	if err != nil {
+		return orcType{}, err
+	}
+	typ := orcType{}
+	for _, field := range fields {
+		switch field.num {
+		case 1:
+			typ.kind = orcTypeKind(field.value)
+		case 2:
+			subtypes, err := field.varints()
+			if err != nil {
+				return orcType{}, err
+			}
+			typ.subtypes = append(typ.subtypes, subtypes...)
+		case 3:
+			typ.fieldNames = append(typ.fieldNames, string(field.bytes))
+		}
+	}
+	return typ, nil
+}
+
+// orcColumnStreams holds the decompressed streams of one column of a stripe
+type orcColumnStreams struct {
+	streams  map[uint64][]byte
+	encoding uint64
+	// The number of entries in the column's dictionary, if it has one
+	dictionarySize uint64
+}
+
+func (s *orcColumnStreams) v2() bool {
+	return s.encoding == orcDirectV2 || s.encoding == orcDictionaryV2
+}
+
+func (s *orcColumnStreams) ints(kind uint64, signed bool) *orcIntReader {
+	return &orcIntReader{buf: s.streams[kind], signed: signed, v2: s.v2()}
+}
+
+func (o *ORCIterator) readStripe(stripe orcStripe) error {
+	footerStart := stripe.offset + stripe.indexLength + stripe.dataLength
+	if footerStart+stripe.footerLength > uint64(len(o.file)) {
+		return io.ErrUnexpectedEOF
+	}
+	footer, err := o.codec.decompress(o.file[footerStart : footerStart+stripe.footerLength])
+	if err != nil {
+		return fmt.Errorf("could not decompress stripe footer: %w", err)
+	}
+	fields, err := orcFields(footer)
+	if err != nil {
+		return fmt.Errorf("could not read stripe footer: %w", err)
+	}
+	columns := make(map[uint64]*orcColumnStreams)
+	column := func(id uint64) *orcColumnStreams {
+		if _, has := columns[id]; !has {
+			columns[id] = &orcColumnStreams{streams: make(map[uint64][]byte)}
+		}
+		return columns[id]
+	}
+	// Streams are stored one after another from the start of the stripe, in
+	// the order the footer lists them
+	offset := stripe.offset
+	var encodingID uint64
+	location := time.UTC
+	for _, field := range fields {
+		switch field.num {
+		case 1:
+			stream, err := orcFields(field.bytes)
+			if err != nil {
+				return fmt.Errorf("could not read stream: %w", err)
+			}
+			var kind, id, length uint64
+			for _, f := range stream {
+				switch f.num {
+				case 1:
+					kind = f.value
+				case 2:
+					id = f.value
+				case 3:
+					length = f.value
+				}
+			}
+			if offset+length > footerStart {
+				return fmt.Errorf("stream of column %d overruns stripe: %w", id, io.ErrUnexpectedEOF)
+			}
+			if orcReadStreams[kind] {
+				data, err := o.codec.decompress(o.file[offset : offset+length])
+				if err != nil {
+					return fmt.Errorf("could not decompress stream of column %d: %w", id, err)
+				}
+				column(id).streams[kind] = data
+			}
+			offset += length
+		case 2:
+			encoding, err := orcFields(field.bytes)
+			if err != nil {
+				return fmt.Errorf("could not read column encoding: %w", err)
+			}
+			for _, f := range encoding {
+				switch f.num {
+				case 1:
+					column(encodingID).encoding = f.value
+				case 2:
+					column(encodingID).dictionarySize = f.value
+				}
+			}
+			encodingID += 1
+		case 3:
+			location, err = time.LoadLocation(string(field.bytes))
+			if err != nil {
+				return fmt.Errorf("unknown writer timezone: %w", err)
+			}
+		}
+	}
+	numRows := int(stripe.numRows)
+	values := make([][]interface{}, len(o.columns))
+	for i, col := range o.columns {
+		values[i], err = readORCColumn(col.typ, column(col.id), numRows, location)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", col.name, err)
+		}
+	}
+	o.values = values
+	o.rowIdx = 0
+	o.stripeRows = numRows
+	return nil
+}
+
+// readORCColumn decodes a stripe's values of a column of a scalar type. The
+// values are the same Go types the parquet iterator produces: integers become
+// int, timestamps and dates become UTC time.Time values and decimals become
+// float64. Rows without a value are nil.
+func readORCColumn(typ orcType, column *orcColumnStreams, numRows int, location *time.Location) ([]interface{}, error) {
+	present := make([]bool, numRows)
+	numValues := numRows
+	if stream, has := column.streams[orcPresentStream]; has {
+		var err error
+		present, err = readORCBooleans(stream, numRows)
+		if err != nil {
+			return nil, fmt.Errorf("could not read present stream: %w", err)
+		}
+		numValues = 0
+		for _, isPresent := range present {
+			if isPresent {
+				numValues += 1
+			}
+		}
+	} else {
+		for i := range present {
+			present[i] = true
+		}
+	}
+	values, err := readORCValues(typ, column, numValues, location)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]interface{}, numRows)
+	next := 0
+	for i, isPresent := range present {
+		if isPresent {
+			rows[i] = values[next]
+			next += 1
+		}
+	}
+	return rows, nil
+}
+
+func readORCValues(typ orcType, column *orcColumnStreams, n int, location *time.Location) ([]interface{}, error) {
+	values := make([]interface{}, n)
+	data := column.streams[orcDataStream]
+	switch typ.kind {
+	case orcBoolean:
+		bools, err := readORCBooleans(data, n)
+		if err != nil {
+			return nil, err
+		}
+		for i, b := range bools {
+			values[i] = b
+		}
+	case orcByte:
+		packed, err := readORCByteRLE(data, n)
+		if err != nil {
+			return nil, err
+		}
+		for i, b := range packed {
+			values[i] = int(int8(b))
+		}
+	case orcShort, orcInt, orcLong, orcDate:
+		ints, err := column.ints(orcDataStream, true).read(n)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range ints {
+			if typ.kind == orcDate {
+				values[i] = time.Unix(v*24*60*60, 0).UTC()
+			} else {
+				values[i] = int(v)
+			}
+		}
+	case orcFloat:
+		if len(data) < 4*n {
+			return nil, io.ErrUnexpectedEOF
+		}
+		for i := range values {
+			values[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+		}
+	case orcDouble:
+		if len(data) < 8*n {
+			return nil, io.ErrUnexpectedEOF
+		}
+		for i := range values {
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[8*i:]))
+		}
+	case orcString, orcVarchar, orcChar, orcBinary:
+		strs, err := readORCStrings(column, n)
+		if err != nil {
+			return nil, err
+		}
+		for i, s := range strs {
+			if typ.kind == orcBinary {
+				values[i] = s
+			} else {
+				values[i] = string(s)
+			}
+		}
+	case orcTimestamp, orcTimestampInstant:
+		if typ.kind == orcTimestampInstant {
+			location = time.UTC
+		}
+		seconds, err := column.ints(orcDataStream, true).read(n)
+		if err != nil {
+			return nil, err
+		}
+		nanos, err := column.ints(orcSecondaryStream, false).read(n)
+		if err != nil {
+			return nil, err
+		}
+		epoch := time.Date(orcTimestampEpoch.Year(), orcTimestampEpoch.Month(), orcTimestampEpoch.Day(), 0, 0, 0, 0, location).Unix()
+		for i := range values {
+			values[i] = orcTimestampValue(epoch+seconds[i], nanos[i])
+		}
+	case orcDecimal:
+		scales, err := column.ints(orcSecondaryStream, true).read(n)
+		if err != nil {
+			return nil, err
+		}
+		r := &orcIntReader{buf: data}
+		for i := range values {
+			unscaled, err := r.readBigVarint()
+			if err != nil {
+				return nil, err
+			}
+			values[i], _ = new(big.Float).Quo(
+				new(big.Float).SetInt(unscaled),
+				new(big.Float).SetFloat64(math.Pow10(int(scales[i]))),
+			).Float64()
+		}
+	default:
+		return nil, fmt.Errorf("unsupported orc type: %d", typ.kind)
+	}
+	return values, nil
+}
+
+// Nanoseconds are stored with their trailing zeros removed. If there were more
+// than one, the low three bits hold how many, less one.
+func orcTimestampValue(seconds int64, encodedNanos int64) time.Time {
+	nanos := encodedNanos >> 3
+	if zeros := encodedNanos & 7; zeros != 0 {
+		for i := int64(0); i <= zeros; i++ {
+			nanos *= 10
+		}
+	}
+	// Writers truncate the seconds of times before 1970 towards zero
+	if seconds < 0 && nanos > 999999 {
+		seconds -= 1
+	}
+	return time.Unix(seconds, nanos).UTC()
+}
+
+// readORCStrings reads string and binary values, which are either stored one
+// after another with their lengths in a separate stream, or as indexes into a
+// dictionary of the column's distinct values
+func readORCStrings(column *orcColumnStreams, n int) ([][]byte, error) {
+	lengths := column.ints(orcLengthStream, false)
+	values := make([][]byte, n)
+	if column.encoding == orcDictionary || column.encoding == orcDictionaryV2 {
+		dictionaryLengths, err := lengths.read(int(column.dictionarySize))
+		if err != nil {
+			return nil, fmt.Errorf("could not read dictionary lengths: %w", err)
+		}
+		dictionary, err := splitORCBytes(column.streams[orcDictionaryDataStream], dictionaryLengths)
+		if err != nil {
+			return nil, fmt.Errorf("could not read dictionary: %w", err)
+		}
+		indexes, err := column.ints(orcDataStream, false).read(n)
+		if err != nil {
+			return nil, err
+		}
+		for i, idx := range indexes {
+			if idx < 0 || idx >= int64(len(dictionary)) {
+				return nil, fmt.Errorf("dictionary index %d out of range", idx)
+			}
+			values[i] = dictionary[idx]
+		}
+		return values, nil
+	}
+	valueLengths, err := lengths.read(n)
+	if err != nil {
+		return nil, fmt.Errorf("could not read lengths: %w", err)
+	}
+	return splitORCBytes(column.streams[orcDataStream], valueLengths)
+}
+
+func splitORCBytes(data []byte, lengths []int64) ([][]byte, error) {
+	values := make([][]byte, len(lengths))
+	pos := int64(0)
+	for i, length := range lengths {
+		if length < 0 || pos+length > int64(len(data)) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		values[i] = data[pos : pos+length]
+		pos += length
+	}
+	return values, nil
+}
+
+// orcCodec is how a file's streams and footers are compressed
+type orcCodec struct {
+	compression uint64
+	blockSize   uint64
+}
+
+// decompress decompresses a stream or footer. Compressed ones are split into
+// chunks, each with a three byte header holding its length and whether it was
+// stored as is because compressing it didn't make it smaller.
+func (c orcCodec) decompress(b []byte) ([]byte, error) {
+	if c.compression == orcCompressionNone {
+		return b, nil
+	}
+	out := make([]byte, 0, len(b))
+	for len(b) > 0 {
+		if len(b) < 3 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		header := int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+		length := header >> 1
+		b = b[3:]
+		if length > len(b) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		chunk := b[:length]
+		b = b[length:]
+		if header&1 == 1 {
+			out = append(out, chunk...)
+			continue
+		}
+		var decompressed []byte
+		var err error
+		switch c.compression {
+		case orcCompressionZlib:
+			decompressed, err = io.ReadAll(flate.NewReader(bytes.NewReader(chunk)))
+		case orcCompressionSnappy:
+			decompressed, err = snappy.Decode(nil, chunk)
+		case orcCompressionLZ4:
+			// Chunks are raw LZ4 blocks, which don't record their
+			// decompressed size
+			buf := make([]byte, c.blockSize)
+			var n int
+			if n, err = lz4.UncompressBlock(chunk, buf); err == nil {
+				decompressed = buf[:n]
+			}
+		case orcCompressionZstd:
+			decompressed, err = orcZstdDecoder.DecodeAll(chunk, nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, decompressed...)
+	}
+	return out, nil
+}
+
+// orcField is a field of one of the protobuf messages ORC stores its metadata
+// in. Varints are held in value, and length delimited fields in bytes.
+type orcField struct {
+	num   protowire.Number
+	typ   protowire.Type
+	value uint64
+	bytes []byte
+}
+
+func orcFields(b []byte) ([]orcField, error) {
+	fields := make([]orcField, 0)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		field := orcField{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			field.value, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			field.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// varints returns the values of a repeated varint field, which may be packed
+func (f orcField) varints() ([]uint64, error) {
+	if f.typ == protowire.VarintType {
+		return []uint64{f.value}, nil
+	}
+	values := make([]uint64, 0)
+	b := f.bytes
+	for len(b) > 0 {
+		v, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		values = append(values, v)
+		b = b[n:]
+	}
+	return values, nil
+}
+
+// readORCByteRLE decodes n bytes of ORC's byte run length encoding. Each run
+// starts with a control byte: zero or more is a run of that many plus three
+// copies of the next byte, and negative is that many literal bytes.
+func readORCByteRLE(buf []byte, n int) ([]byte, error) {
+	values := make([]byte, 0, n)
+	pos := 0
+	for len(values) < n {
+		if pos >= len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		control := int8(buf[pos])
+		pos += 1
+		if control >= 0 {
+			if pos >= len(buf) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			for i := 0; i < int(control)+3; i++ {
+				values = append(values, buf[pos])
+			}
+			pos += 1
+		} else {
+			length := -int(control)
+			if pos+length > len(buf) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			values = append(values, buf[pos:pos+length]...)
+			pos += length
+		}
+	}
+	return values[:n], nil
+}
+
+// Booleans are packed eight to a byte, most significant bit first, and the bytes
+// are run length encoded
+func readORCBooleans(buf []byte, n int) ([]bool, error) {
+	packed, err := readORCByteRLE(buf, (n+7)/8)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]bool, n)
+	for i := range values {
+		values[i] = packed[i/8]&(0x80>>(i%8)) != 0
+	}
+	return values, nil
+}
+
+// orcIntReader decodes ORC's run length encodings of integers. Version 1 is
+// used by the DIRECT and DICTIONARY column encodings, and version 2 by the _V2
+// ones. Signed values are zig-zag encoded.
+type orcIntReader struct {
+	buf    []byte
+	pos    int
+	signed bool
+	v2     bool
+	// Values of the current run that haven't been read yet
+	run []int64
+}
+
+func (r *orcIntReader) read(n int) ([]int64, error) {
+	values := make([]int64, 0, n)
+	for len(values) < n {
+		if len(r.run) == 0 {
+			var err error
+			if r.v2 {
+				err = r.readRunV2()
+			} else {
+				err = r.readRunV1()
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		take := n - len(values)
+		if take > len(r.run) {
+			take = len(r.run)
+		}
+		values = append(values, r.run[:take]...)
+		r.run = r.run[take:]
+	}
+	return values, nil
+}
+
+func (r *orcIntReader) readByte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b := r.buf[r.pos]
+	r.pos += 1
+	return b, nil
+}
+
+func (r *orcIntReader) readUvarint() (uint64, error) {
+	val, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	r.pos += n
+	return val, nil
+}
+
+func (r *orcIntReader) readVarint() (int64, error) {
+	val, n := binary.Varint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	r.pos += n
+	return val, nil
+}
+
+// readValue reads a varint that's zig-zag encoded if the reader is signed
+func (r *orcIntReader) readValue() (int64, error) {
+	if r.signed {
+		return r.readVarint()
+	}
+	val, err := r.readUvarint()
+	return int64(val), err
+}
+
+// readBigVarint reads a zig-zag encoded varint of any size, as decimals are
+// stored
+func (r *orcIntReader) readBigVarint() (*big.Int, error) {
+	val := new(big.Int)
+	for shift := uint(0); ; shift += 7 {
+		b, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		val.Or(val, new(big.Int).Lsh(big.NewInt(int64(b&0x7f)), shift))
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	negative := val.Bit(0) == 1
+	val.Rsh(val, 1)
+	if negative {
+		val.Neg(val).Sub(val, big.NewInt(1))
+	}
+	return val, nil
+}
+
+// readBigEndian reads an unsigned integer stored in n bytes, most significant
+// first
+func (r *orcIntReader) readBigEndian(n int) (uint64, error) {
+	if r.pos+n > len(r.buf) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	val := uint64(0)
+	for _, b := range r.buf[r.pos : r.pos+n] {
+		val = val<<8 | uint64(b)
+	}
+	r.pos += n
+	return val, nil
+}
+
+// readPacked reads n values of width bits each, packed most significant bit
+// first. The values start at a byte boundary and the rest of their last byte is
+// unused.
+func (r *orcIntReader) readPacked(n int, width int) ([]uint64, error) {
+	size := (n*width + 7) / 8
+	if r.pos+size > len(r.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := r.buf[r.pos : r.pos+size]
+	values := make([]uint64, n)
+	bit := 0
+	for i := range values {
+		for j := 0; j < width; j++ {
+			values[i] = values[i]<<1 | uint64(data[bit/8]>>(7-bit%8)&1)
+			bit += 1
+		}
+	}
+	r.pos += size
+	return values, nil
+}
+
+func (r *orcIntReader) decode(val uint64) int64 {
+	if r.signed {
+		return int64(val>>1) ^ -int64(val&1)
+	}
+	return int64(val)
+}
+
+// A version 1 run starts with a control byte: zero or more is a run of that many
+// plus three values that differ by a fixed delta, and negative is that many
+// literal varints.
+func (r *orcIntReader) readRunV1() error {
+	control, err := r.readByte()
+	if err != nil {
+		return err
+	}
+	if int8(control) >= 0 {
+		delta, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		base, err := r.readValue()
+		if err != nil {
+			return err
+		}
+		r.run = make([]int64, int(control)+3)
+		for i := range r.run {
+			r.run[i] = base + int64(i)*int64(int8(delta))
+		}
+		return nil
+	}
+	r.run = make([]int64, -int(int8(control)))
+	for i := range r.run {
+		if r.run[i], err = r.readValue(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Version 2 runs are one of four sub-encodings, chosen by the top two bits of
+// their first byte
+const (
+	orcShortRepeat = 0
+	orcDirectRun   = 1
+	orcPatchedBase = 2
+	orcDeltaRun    = 3
+)
+
+// The bit widths that the five bit width field of a version 2 run stands for
+func orcBitWidth(encoded byte) int {
+	switch {
+	case encoded < 24:
+		return int(encoded) + 1
+	case encoded < 28:
+		return 26 + 2*int(encoded-24)
+	default:
+		return 40 + 8*int(encoded-28)
+	}
+}
+
+// orcClosestFixedBits rounds a bit width up to one that version 2 runs can
+// hold
+func orcClosestFixedBits(width int) int {
+	switch {
+	case width == 0:
+		return 1
+	case width <= 24:
+		return width
+	case width <= 32:
+		return width + width%2
+	case width <= 64:
+		return (width + 7) / 8 * 8
+	default:
+		return 64
+	}
+}
+
+func (r *orcIntReader) readRunV2() error {
+	first, err := r.readByte()
+	if err != nil {
+		return err
+	}
+	switch first >> 6 {
+	case orcShortRepeat:
+		val, err := r.readBigEndian(int(first>>3&0x07) + 1)
+		if err != nil {
+			return err
+		}
+		r.run = make([]int64, int(first&0x07)+3)
+		for i := range r.run {
+			r.run[i] = r.decode(val)
+		}
+		return nil
+	case orcDirectRun:
+		second, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		length := (int(first&0x01)<<8 | int(second)) + 1
+		packed, err := r.readPacked(length, orcBitWidth(first>>1&0x1f))
+		if err != nil {
+			return err
+		}
+		r.run = make([]int64, length)
+		for i, val := range packed {
+			r.run[i] = r.decode(val)
+		}
+		return nil
+	case orcPatchedBase:
+		return r.readPatchedBase(first)
+	default:
+		return r.readDelta(first)
+	}
+}
+
+// A patched base run stores values as offsets from a base value, with the high
+// bits of the few offsets too wide for the rest kept in a list of patches
+func (r *orcIntReader) readPatchedBase(first byte) error {
+	header := make([]byte, 3)
+	for i := range header {
+		b, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		header[i] = b
+	}
+	width := orcBitWidth(first >> 1 & 0x1f)
+	length := (int(first&0x01)<<8 | int(header[0])) + 1
+	baseBytes := int(header[1]>>5&0x07) + 1
+	patchWidth := orcBitWidth(header[1] & 0x1f)
+	gapWidth := int(header[2]>>5&0x07) + 1
+	numPatches := int(header[2] & 0x1f)
+	if patchWidth+gapWidth > 64 {
+		return fmt.Errorf("orc patch is wider than 64 bits")
+	}
+	unsignedBase, err := r.readBigEndian(baseBytes)
+	if err != nil {
+		return err
+	}
+	// The base's most significant bit is its sign
+	signBit := uint64(1) << (baseBytes*8 - 1)
+	base := int64(unsignedBase &^ signBit)
+	if unsignedBase&signBit != 0 {
+		base = -base
+	}
+	offsets, err := r.readPacked(length, width)
+	if err != nil {
+		return err
+	}
+	patches, err := r.readPacked(numPatches, orcClosestFixedBits(patchWidth+gapWidth))
+	if err != nil {
+		return err
+	}
+	patchMask := uint64(1)<<patchWidth - 1
+	// Each patch is preceded by its distance from the previous one. Distances
+	// of more than 255 are split over patches of 255 with no bits to patch.
+	idx := 0
+	for _, patch := range patches {
+		idx += int(patch >> patchWidth)
+		if patch&patchMask == 0 && patch>>patchWidth == 255 {
+			continue
+		}
+		if idx >= len(offsets) {
+			return fmt.Errorf("orc patch out of range")
+		}
+		offsets[idx] |= (patch & patchMask) << width
+	}
+	r.run = make([]int64, length)
+	for i, offset := range offsets {
+		r.run[i] = base + int64(offset)
+	}
+	return nil
+}
+
+// A delta run stores its first value, then the difference to the second, then
+// the size of the rest of the differences, which all have the same sign as the
+// second. A run without a bit width has a fixed delta.
+func (r *orcIntReader) readDelta(first byte) error {
+	second, err := r.readByte()
+	if err != nil {
+		return err
+	}
+	length := (int(first&0x01)<<8 | int(second)) + 1
+	base, err := r.readValue()
+	if err != nil {
+		return err
+	}
+	delta, err := r.readVarint()
+	if err != nil {
+		return err
+	}
+	r.run = make([]int64, length)
+	r.run[0] = base
+	encodedWidth := first >> 1 & 0x1f
+	if encodedWidth == 0 {
+		for i := 1; i < length; i++ {
+			r.run[i] = r.run[i-1] + delta
+		}
+		return nil
+	}
+	if length < 2 {
+		return fmt.Errorf("orc delta run is too short")
+	}
+	r.run[1] = base + delta
+	deltas, err := r.readPacked(length-2, orcBitWidth(encodedWidth))
+	if err != nil {
+		return err
+	}
+	for i, d := range deltas {
+		if delta < 0 {
+			r.run[i+2] = r.run[i+1] - int64(d)
+		} else {
+			r.run[i+2] = r.run[i+1] + int64(d)
+		}
+	}
+	return nil
+}
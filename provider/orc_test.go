@@ -0,0 +1,508 @@
+package provider
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/featureform/filestore"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// testORCColumn is a column of a single stripe ORC file, with its streams
+// already encoded
+type testORCColumn struct {
+	name           string
+	kind           orcTypeKind
+	encoding       uint64
+	dictionarySize uint64
+	streams        map[uint64][]byte
+}
+
+// compressTestORC splits b into chunks, each compressed as ORC files store them
+func compressTestORC(compression uint64, b []byte) ([]byte, error) {
+	if compression == orcCompressionNone {
+		return b, nil
+	}
+	out := make([]byte, 0)
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > orcDefaultCompressionBlockSize {
+			chunk = chunk[:orcDefaultCompressionBlockSize]
+		}
+		b = b[len(chunk):]
+		var compressed []byte
+		switch compression {
+		case orcCompressionZlib:
+			buf := new(bytes.Buffer)
+			w, err := flate.NewWriter(buf, flate.DefaultCompression)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := w.Write(chunk); err != nil {
+				return nil, err
+			}
+			if err := w.Close(); err != nil {
+				return nil, err
+			}
+			compressed = buf.Bytes()
+		case orcCompressionSnappy:
+			compressed = snappy.Encode(nil, chunk)
+		case orcCompressionLZ4:
+			compressed = make([]byte, lz4.CompressBlockBound(len(chunk)))
+			n, err := lz4.CompressBlock(chunk, compressed, nil)
+			if err != nil {
+				return nil, err
+			}
+			compressed = compressed[:n]
+		case orcCompressionZstd:
+			encoder, err := zstd.NewWriter(nil)
+			if err != nil {
+				return nil, err
+			}
+			compressed = encoder.EncodeAll(chunk, nil)
+			encoder.Close()
+		default:
+			return nil, fmt.Errorf("unsupported test compression: %d", compression)
+		}
+		header := len(compressed) << 1
+		// Chunks that don't get smaller are stored as is
+		if len(compressed) == 0 || len(compressed) >= len(chunk) {
+			header = len(chunk)<<1 | 1
+			compressed = chunk
+		}
+		out = append(out, byte(header), byte(header>>8), byte(header>>16))
+		out = append(out, compressed...)
+	}
+	return out, nil
+}
+
+func appendORCVarintField(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendORCBytesField(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// writeORCFile writes columns as a file with one stripe
+func writeORCFile(columns []testORCColumn, numRows int, compression uint64) ([]byte, error) {
+	file := append([]byte{}, orcMagic...)
+	stripeOffset := len(file)
+	stripeFooter := make([]byte, 0)
+	// The root struct has no streams of its own
+	encodings := [][]byte{appendORCVarintField(nil, 1, orcDirect)}
+	for i, column := range columns {
+		for _, kind := range []uint64{orcPresentStream, orcDataStream, orcLengthStream, orcDictionaryDataStream, orcSecondaryStream} {
+			data, has := column.streams[kind]
+			if !has {
+				continue
+			}
+			compressed, err := compressTestORC(compression, data)
+			if err != nil {
+				return nil, err
+			}
+			file = append(file, compressed...)
+			stream := appendORCVarintField(nil, 1, kind)
+			stream = appendORCVarintField(stream, 2, uint64(i+1))
+			stream = appendORCVarintField(stream, 3, uint64(len(compressed)))
+			stripeFooter = appendORCBytesField(stripeFooter, 1, stream)
+		}
+		encoding := appendORCVarintField(nil, 1, column.encoding)
+		encoding = appendORCVarintField(encoding, 2, column.dictionarySize)
+		encodings = append(encodings, encoding)
+	}
+	for _, encoding := range encodings {
+		stripeFooter = appendORCBytesField(stripeFooter, 2, encoding)
+	}
+	dataLength := len(file) - stripeOffset
+	compressedStripeFooter, err := compressTestORC(compression, stripeFooter)
+	if err != nil {
+		return nil, err
+	}
+	file = append(file, compressedStripeFooter...)
+
+	stripe := appendORCVarintField(nil, 1, uint64(stripeOffset))
+	stripe = appendORCVarintField(stripe, 2, 0)
+	stripe = appendORCVarintField(stripe, 3, uint64(dataLength))
+	stripe = appendORCVarintField(stripe, 4, uint64(len(compressedStripeFooter)))
+	stripe = appendORCVarintField(stripe, 5, uint64(numRows))
+	root := appendORCVarintField(nil, 1, uint64(orcStruct))
+	subtypes := make([]byte, 0)
+	for i := range columns {
+		subtypes = protowire.AppendVarint(subtypes, uint64(i+1))
+	}
+	root = appendORCBytesField(root, 2, subtypes)
+	for _, column := range columns {
+		root = appendORCBytesField(root, 3, []byte(column.name))
+	}
+	footer := appendORCVarintField(nil, 1, uint64(len(orcMagic)))
+	footer = appendORCVarintField(footer, 2, uint64(len(file)-len(orcMagic)))
+	footer = appendORCBytesField(footer, 3, stripe)
+	footer = appendORCBytesField(footer, 4, root)
+	for _, column := range columns {
+		footer = appendORCBytesField(footer, 4, appendORCVarintField(nil, 1, uint64(column.kind)))
+	}
+	footer = appendORCVarintField(footer, 6, uint64(numRows))
+	compressedFooter, err := compressTestORC(compression, footer)
+	if err != nil {
+		return nil, err
+	}
+	file = append(file, compressedFooter...)
+
+	postscript := appendORCVarintField(nil, 1, uint64(len(compressedFooter)))
+	postscript = appendORCVarintField(postscript, 2, compression)
+	postscript = appendORCVarintField(postscript, 3, 256*1024)
+	postscript = appendORCBytesField(postscript, 8000, orcMagic)
+	file = append(file, postscript...)
+	return append(file, byte(len(postscript))), nil
+}
+
+// encodeTestORCInts writes values as version 2 direct runs of 64 bit values
+func encodeTestORCInts(values []int64, signed bool) []byte {
+	b := make([]byte, 0)
+	for len(values) > 0 {
+		n := len(values)
+		if n > 512 {
+			n = 512
+		}
+		b = append(b, byte(orcDirectRun<<6|31<<1|(n-1)>>8), byte(n-1))
+		for _, v := range values[:n] {
+			encoded := uint64(v)
+			if signed {
+				encoded = uint64(v<<1) ^ uint64(v>>63)
+			}
+			word := make([]byte, 8)
+			binary.BigEndian.PutUint64(word, encoded)
+			b = append(b, word...)
+		}
+		values = values[n:]
+	}
+	return b
+}
+
+// encodeTestORCBytes writes b as literal runs of the byte run length encoding
+func encodeTestORCBytes(b []byte) []byte {
+	encoded := make([]byte, 0)
+	for len(b) > 0 {
+		n := len(b)
+		if n > 128 {
+			n = 128
+		}
+		encoded = append(encoded, byte(-n))
+		encoded = append(encoded, b[:n]...)
+		b = b[n:]
+	}
+	return encoded
+}
+
+func encodeTestORCBooleans(values []bool) []byte {
+	packed := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			packed[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return encodeTestORCBytes(packed)
+}
+
+func convertToORCBytes(schema TableSchema, list []GenericRecord, compression uint64) ([]byte, error) {
+	columns := make([]testORCColumn, len(schema.Columns))
+	for i, col := range schema.Columns {
+		present := make([]bool, len(list))
+		hasNull := false
+		values := make([]interface{}, 0, len(list))
+		for j, record := range list {
+			present[j] = record[i] != nil
+			if record[i] == nil {
+				hasNull = true
+				continue
+			}
+			values = append(values, record[i])
+		}
+		column := testORCColumn{name: col.Name, encoding: orcDirectV2, streams: make(map[uint64][]byte)}
+		if hasNull {
+			column.streams[orcPresentStream] = encodeTestORCBooleans(present)
+		}
+		var data []byte
+		switch col.ValueType {
+		case Int, Int64:
+			column.kind = orcLong
+			ints := make([]int64, len(values))
+			for j, v := range values {
+				ints[j] = int64(v.(int))
+			}
+			data = encodeTestORCInts(ints, true)
+		case String:
+			column.kind = orcString
+			lengths := make([]int64, len(values))
+			for j, v := range values {
+				data = append(data, v.(string)...)
+				lengths[j] = int64(len(v.(string)))
+			}
+			column.streams[orcLengthStream] = encodeTestORCInts(lengths, false)
+		case Float32:
+			column.kind = orcFloat
+			data = make([]byte, 4*len(values))
+			for j, v := range values {
+				binary.LittleEndian.PutUint32(data[4*j:], math.Float32bits(v.(float32)))
+			}
+		case Float64:
+			column.kind = orcDouble
+			data = make([]byte, 8*len(values))
+			for j, v := range values {
+				binary.LittleEndian.PutUint64(data[8*j:], math.Float64bits(v.(float64)))
+			}
+		case Bool:
+			column.kind = orcBoolean
+			bools := make([]bool, len(values))
+			for j, v := range values {
+				bools[j] = v.(bool)
+			}
+			data = encodeTestORCBooleans(bools)
+		case Timestamp:
+			column.kind = orcTimestamp
+			seconds := make([]int64, len(values))
+			nanos := make([]int64, len(values))
+			for j, v := range values {
+				ts := v.(time.Time)
+				seconds[j] = ts.Unix() - orcTimestampEpoch.Unix()
+				nanos[j] = int64(ts.Nanosecond()) << 3
+			}
+			data = encodeTestORCInts(seconds, true)
+			column.streams[orcSecondaryStream] = encodeTestORCInts(nanos, false)
+		default:
+			return nil, fmt.Errorf("unsupported value type for orc: %v", col.ValueType)
+		}
+		column.streams[orcDataStream] = data
+		columns[i] = column
+	}
+	return writeORCFile(columns, len(list), compression)
+}
+
+func TestORCIntegerRunLengthEncodingV2(t *testing.T) {
+	// Examples from the ORC specification
+	tests := []struct {
+		name     string
+		encoded  []byte
+		signed   bool
+		expected []int64
+	}{
+		{"short repeat", []byte{0x0a, 0x27, 0x10}, false, []int64{10000, 10000, 10000, 10000, 10000}},
+		{"direct", []byte{0x5e, 0x03, 0x5c, 0xa1, 0xab, 0x1e, 0xde, 0xad, 0xbe, 0xef}, false, []int64{23713, 43806, 57005, 48879}},
+		{
+			"patched base",
+			[]byte{0x8e, 0x13, 0x2b, 0x21, 0x07, 0xd0, 0x1e, 0x00, 0x14, 0x70, 0x28, 0x32, 0x3c, 0x46, 0x50, 0x5a, 0x64, 0x6e, 0x78, 0x82, 0x8c, 0x96, 0xa0, 0xaa, 0xb4, 0xbe, 0xfc, 0xe8},
+			true,
+			[]int64{2030, 2000, 2020, 1000000, 2040, 2050, 2060, 2070, 2080, 2090, 2100, 2110, 2120, 2130, 2140, 2150, 2160, 2170, 2180, 2190},
+		},
+		{"delta", []byte{0xc6, 0x09, 0x02, 0x02, 0x22, 0x42, 0x42, 0x46}, false, []int64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29}},
+		{"fixed delta", []byte{0xc0, 0x03, 0x0a, 0x03}, true, []int64{5, 3, 1, -1}},
+		{"signed direct", encodeTestORCInts([]int64{-1, math.MinInt64, math.MaxInt64}, true), true, []int64{-1, math.MinInt64, math.MaxInt64}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &orcIntReader{buf: test.encoded, signed: test.signed, v2: true}
+			values, err := r.read(len(test.expected))
+			if err != nil {
+				t.Fatalf("could not decode: %v", err)
+			}
+			if !reflect.DeepEqual(values, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, values)
+			}
+			if _, err := r.read(1); err == nil {
+				t.Fatalf("expected error reading past the end of the stream")
+			}
+		})
+	}
+}
+
+func TestORCIteratorEncodings(t *testing.T) {
+	price1234 := protowire.AppendVarint(nil, 2*1234)
+	priceMinus100 := protowire.AppendVarint(nil, 2*100-1)
+	columns := []testORCColumn{
+		// Version 1 run of 5, 6, 7
+		{name: "id", kind: orcLong, encoding: orcDirect, streams: map[uint64][]byte{
+			orcDataStream: {0x00, 0x01, 0x0a},
+		}},
+		{name: "city", kind: orcString, encoding: orcDictionaryV2, dictionarySize: 2, streams: map[uint64][]byte{
+			orcDataStream:           encodeTestORCInts([]int64{1, 0, 1}, false),
+			orcDictionaryDataStream: []byte("abc"),
+			orcLengthStream:         encodeTestORCInts([]int64{2, 1}, false),
+		}},
+		{name: "Feature__price", kind: orcDecimal, encoding: orcDirectV2, streams: map[uint64][]byte{
+			orcPresentStream:   encodeTestORCBooleans([]bool{true, false, true}),
+			orcDataStream:      append(price1234, priceMinus100...),
+			orcSecondaryStream: encodeTestORCInts([]int64{2, 2}, true),
+		}},
+		{name: "day", kind: orcDate, encoding: orcDirectV2, streams: map[uint64][]byte{
+			orcDataStream: encodeTestORCInts([]int64{0, 1, -1}, true),
+		}},
+		// A run of three -1s
+		{name: "Label__flag", kind: orcByte, encoding: orcDirect, streams: map[uint64][]byte{
+			orcDataStream: {0x00, 0xff},
+		}},
+	}
+	b, err := writeORCFile(columns, 3, orcCompressionZlib)
+	if err != nil {
+		t.Fatalf("could not write orc file: %v", err)
+	}
+	iter, err := orcIteratorFromBytes(b)
+	if err != nil {
+		t.Fatalf("could not create orc iterator: %v", err)
+	}
+	if !reflect.DeepEqual(iter.FeatureColumns(), []string{"Feature__price"}) || iter.LabelColumn() != "Label__flag" {
+		t.Fatalf("unexpected columns: %v %s", iter.FeatureColumns(), iter.LabelColumn())
+	}
+	expected := []map[string]interface{}{
+		{"id": 5, "city": "c", "Feature__price": 12.34, "day": time.Unix(0, 0).UTC(), "Label__flag": -1},
+		{"id": 6, "city": "ab", "Feature__price": nil, "day": time.Unix(24*60*60, 0).UTC(), "Label__flag": -1},
+		{"id": 7, "city": "c", "Feature__price": -1.0, "day": time.Unix(-24*60*60, 0).UTC(), "Label__flag": -1},
+	}
+	for _, exp := range expected {
+		row, err := iter.Next()
+		if err != nil {
+			t.Fatalf("could not read orc row: %v", err)
+		}
+		if !reflect.DeepEqual(row, exp) {
+			t.Fatalf("expected %v, got %v", exp, row)
+		}
+	}
+	if row, err := iter.Next(); row != nil || err != nil {
+		t.Fatalf("expected end of file, got %v %v", row, err)
+	}
+}
+
+// testORCRecords are the rows of the test ORC files, and of the fixtures
+// test_files/orc/generate.py writes
+func testORCRecords() (TableSchema, []GenericRecord) {
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "ID", ValueType: Int},
+			{Name: "Name", ValueType: String},
+			{Name: "Points", ValueType: Float32},
+			{Name: "Score", ValueType: Float64},
+			{Name: "Registered", ValueType: Bool},
+			{Name: "Created", ValueType: Timestamp},
+		},
+	}
+	records := make([]GenericRecord, 600)
+	for i := range records {
+		records[i] = GenericRecord{i, fmt.Sprintf("name %d", i), float32(i) + 0.1, float64(i) + 0.1, i%3 == 0, time.UnixMilli(int64(i)).UTC()}
+	}
+	// Nulls are left out of the data streams
+	records[1] = GenericRecord{1, nil, nil, nil, nil, nil}
+	return schema, records
+}
+
+func checkORCRecords(t *testing.T, iter Iterator, records []GenericRecord) {
+	for i, record := range records {
+		row, err := iter.Next()
+		if err != nil {
+			t.Fatalf("could not read row %d: %v", i, err)
+		}
+		served := GenericRecord{row["ID"], row["Name"], row["Points"], row["Score"], row["Registered"], row["Created"]}
+		if !reflect.DeepEqual(served, record) {
+			t.Fatalf("row %d: expected %v, got %v", i, record, served)
+		}
+	}
+	if row, err := iter.Next(); row != nil || err != nil {
+		t.Fatalf("expected end of file, got %v %v", row, err)
+	}
+}
+
+func TestServeORC(t *testing.T) {
+	schema, records := testORCRecords()
+	numRows := len(records)
+	for _, compression := range []uint64{orcCompressionNone, orcCompressionZlib, orcCompressionSnappy, orcCompressionLZ4, orcCompressionZstd} {
+		t.Run(fmt.Sprint(compression), func(t *testing.T) {
+			b, err := convertToORCBytes(schema, records, compression)
+			if err != nil {
+				t.Fatalf("could not convert records to orc bytes: %v", err)
+			}
+			store := NewMemoryFileStore()
+			path, err := store.CreateFilePath(fmt.Sprintf("orc/%d/part-00000.orc", compression))
+			if err != nil {
+				t.Fatalf("could not create file path: %v", err)
+			}
+			if err := store.Write(path, b); err != nil {
+				t.Fatalf("could not write orc file: %v", err)
+			}
+			dir, err := store.CreateDirPath(fmt.Sprintf("orc/%d", compression))
+			if err != nil {
+				t.Fatalf("could not create dir path: %v", err)
+			}
+			newest, err := store.NewestFileOfType(dir, filestore.ORC)
+			if err != nil || newest.Key() != path.Key() {
+				t.Fatalf("expected newest orc file %s, got %v %v", path.Key(), newest, err)
+			}
+			iter, err := store.Serve([]filestore.Filepath{path})
+			if err != nil {
+				t.Fatalf("could not serve orc file: %v", err)
+			}
+			checkORCRecords(t, iter, records)
+			rows, err := store.NumRows(path)
+			if err != nil || rows != int64(numRows) {
+				t.Fatalf("expected %d rows, got %d: %v", numRows, rows, err)
+			}
+		})
+	}
+}
+
+// TestORCFixtures reads files written by pyarrow rather than by the test
+// encoder above, so the decoder is checked against another implementation
+func TestORCFixtures(t *testing.T) {
+	_, records := testORCRecords()
+	for _, compression := range []string{"uncompressed", "zlib", "snappy", "lz4", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			// The fixtures are written by pyarrow, so that the reader isn't only
+			// tested against the writer in this file. A missing one fails
+			// rather than skips, so the reader is never left untested.
+			b, err := os.ReadFile(fmt.Sprintf("test_files/orc/%s.orc", compression))
+			if errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("fixture missing; run test_files/orc/generate.py and commit its output")
+			} else if err != nil {
+				t.Fatalf("could not read fixture: %v", err)
+			}
+			iter, err := orcIteratorFromBytes(b)
+			if err != nil {
+				t.Fatalf("could not open fixture: %v", err)
+			}
+			checkORCRecords(t, iter, records)
+			tail, err := readORCTailAt(bytes.NewReader(b), int64(len(b)))
+			if err != nil || tail.numRows != uint64(len(records)) {
+				t.Fatalf("expected %d rows, got %d: %v", len(records), tail.numRows, err)
+			}
+		})
+	}
+}
+
+func TestORCIteratorInvalidFile(t *testing.T) {
+	if _, err := orcIteratorFromBytes([]byte("PAR1")); err == nil {
+		t.Fatalf("expected error reading a non-orc file")
+	}
+	b, err := writeORCFile(nil, 0, orcCompressionNone)
+	if err != nil {
+		t.Fatalf("could not write orc file: %v", err)
+	}
+	// Compression is the postscript's second field, right after the footer
+	// length
+	postscript := len(b) - 1 - int(b[len(b)-1])
+	lzo := append([]byte{}, b...)
+	lzo[postscript+bytes.IndexByte(b[postscript:], 2<<3)+1] = orcCompressionLZO
+	if _, err := orcIteratorFromBytes(lzo); err == nil {
+		t.Fatalf("expected error for unsupported compression")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -41,6 +42,8 @@ const (
 type pineconeOnlineStore struct {
 	client *pineconeAPI
 	prefix string
+	// How often CreateIndex checks whether a new index is ready
+	pollInterval time.Duration
 	BaseProvider
 }
 
@@ -54,8 +57,9 @@ func pineconeOnlineStoreFactory(serialized pc.SerializedConfig) (Provider, error
 
 func NewPineconeOnlineStore(options *pc.PineconeConfig) (*pineconeOnlineStore, error) {
 	return &pineconeOnlineStore{
-		client: NewPineconeAPI(options),
-		prefix: prefixTemplate,
+		client:       NewPineconeAPI(options),
+		prefix:       prefixTemplate,
+		pollInterval: 10 * time.Second,
 		BaseProvider: BaseProvider{
 			ProviderType:   pt.PineconeOnline,
 			ProviderConfig: options.Serialize(),
@@ -73,12 +77,22 @@ func (store *pineconeOnlineStore) Close() error {
 }
 
 func (store *pineconeOnlineStore) CreateTable(feature, variant string, valueType ValueType) (OnlineStoreTable, error) {
-	return &pineconeOnlineTable{
-		api:       store.client,
-		indexName: store.createIndexName(feature, variant),
-		namespace: fmt.Sprintf(namespaceTemplate, feature, variant),
-		valueType: valueType,
-	}, nil
+	return store.createTable(feature, variant, valueType)
+}
+
+func (store *pineconeOnlineStore) createTable(feature, variant string, valueType ValueType) (pineconeOnlineTable, error) {
+	vectorType, isVector := valueType.(VectorType)
+	if !isVector {
+		return pineconeOnlineTable{}, fmt.Errorf("pinecone can only store vector features, but %s (%s) has type %v", feature, variant, valueType)
+	}
+	table, err := store.getTable(feature, variant)
+	if err != nil {
+		return pineconeOnlineTable{}, err
+	}
+	if err := table.checkDimension(feature, variant, vectorType); err != nil {
+		return pineconeOnlineTable{}, err
+	}
+	return table, nil
 }
 
 func (store *pineconeOnlineStore) DeleteTable(feature, variant string) error {
@@ -87,18 +101,44 @@ func (store *pineconeOnlineStore) DeleteTable(feature, variant string) error {
 }
 
 func (store *pineconeOnlineStore) CreateIndex(feature, variant string, vectorType VectorType) (VectorStoreTable, error) {
-	indexName := store.createIndexName(feature, variant)
+	indexName := store.indexName(feature, variant)
+	// Indexes that already exist, such as when a feature is materialized again,
+	// are reused as long as they hold vectors of the feature's dimension.
+	_, state, err := store.client.describeIndex(indexName)
+	var requestErr *pineconeRequestError
+	isMissing := errors.As(err, &requestErr) && requestErr.StatusCode == http.StatusNotFound
+	if err != nil && !isMissing {
+		return nil, err
+	}
+	if !isMissing {
+		// An index that's still spinning up, such as one created by an
+		// earlier attempt, is waited on like a new one
+		if state != Ready {
+			if _, err := store.waitForReadyIndex(indexName, feature, variant); err != nil {
+				return nil, err
+			}
+		}
+		return store.createTable(feature, variant, vectorType)
+	}
+	if store.client.config.Index != "" {
+		return nil, fmt.Errorf("pinecone index %s does not exist", indexName)
+	}
 	if err := store.client.createIndex(indexName, vectorType.Dimension); err != nil {
 		return nil, err
 	}
-	// Given Pinecone indexes are cloud-based clusters of compute resources, they take
-	// some time to spin up and become fully available. To ensure users don't encounter
-	// unnecessary errors while registering a new index, we wait for the index to be
-	// ready.
+	return store.waitForReadyIndex(indexName, feature, variant)
+}
+
+// waitForReadyIndex polls the index until it's ready, and returns its table.
+// Given Pinecone indexes are cloud-based clusters of compute resources, they take
+// some time to spin up and become fully available. To ensure users don't encounter
+// unnecessary errors while registering a new index, we wait for the index to be
+// ready.
+func (store *pineconeOnlineStore) waitForReadyIndex(indexName, feature, variant string) (VectorStoreTable, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
 	defer cancel()
 
-	ticker := time.NewTicker(10 * time.Second)
+	ticker := time.NewTicker(store.pollInterval)
 	defer ticker.Stop()
 
 	for {
@@ -123,35 +163,39 @@ func (store *pineconeOnlineStore) getTableForReadyIndex(indexName, feature, vari
 		return nil, err
 	}
 	if state == Ready {
-		return pineconeOnlineTable{
-			api:       store.client,
-			indexName: indexName,
-			namespace: fmt.Sprintf(namespaceTemplate, feature, variant),
-			valueType: VectorType{
-				Dimension:   dimension,
-				ScalarType:  Float32,
-				IsEmbedding: true,
-			},
-		}, nil
+		return store.newTable(indexName, feature, variant, dimension), nil
 	} else {
 		return nil, nil
 	}
 }
 
+// DeleteIndex deletes the feature's index, or only its vectors if every
+// feature shares the index set in the config.
 func (store *pineconeOnlineStore) DeleteIndex(feature, variant string) error {
+	if store.client.config.Index != "" {
+		return store.client.deleteNamespace(store.client.config.Index, fmt.Sprintf(namespaceTemplate, feature, variant))
+	}
 	indexName := store.createIndexName(feature, variant)
 	return store.client.deleteIndex(indexName)
 }
 
 func (store *pineconeOnlineStore) GetTable(feature, variant string) (OnlineStoreTable, error) {
-	indexName := store.createIndexName(feature, variant)
+	return store.getTable(feature, variant)
+}
+
+func (store *pineconeOnlineStore) getTable(feature, variant string) (pineconeOnlineTable, error) {
+	indexName := store.indexName(feature, variant)
 	dimension, state, err := store.client.describeIndex(indexName)
 	if err != nil {
-		return nil, err
+		return pineconeOnlineTable{}, err
 	}
 	if state != Ready {
-		return nil, fmt.Errorf("pinecone index %s not ready; current state is %s", indexName, state)
+		return pineconeOnlineTable{}, fmt.Errorf("pinecone index %s not ready; current state is %s", indexName, state)
 	}
+	return store.newTable(indexName, feature, variant, dimension), nil
+}
+
+func (store *pineconeOnlineStore) newTable(indexName, feature, variant string, dimension int32) pineconeOnlineTable {
 	return pineconeOnlineTable{
 		api:       store.client,
 		indexName: indexName,
@@ -161,7 +205,15 @@ func (store *pineconeOnlineStore) GetTable(feature, variant string) (OnlineStore
 			ScalarType:  Float32,
 			IsEmbedding: true,
 		},
-	}, nil
+	}
+}
+
+// indexName returns the index the feature's vectors are stored in
+func (store *pineconeOnlineStore) indexName(feature, variant string) string {
+	if store.client.config.Index != "" {
+		return store.client.config.Index
+	}
+	return store.createIndexName(feature, variant)
 }
 
 func (store *pineconeOnlineStore) createIndexName(feature, variant string) string {
@@ -173,15 +225,25 @@ type pineconeOnlineTable struct {
 	api       *pineconeAPI
 	indexName string
 	namespace string
-	valueType ValueType
+	valueType VectorType
+}
+
+// checkDimension errors if the table's index holds vectors of a different
+// dimension than the feature's, which Pinecone would otherwise only reject once
+// the first vector is written.
+func (table pineconeOnlineTable) checkDimension(feature, variant string, vectorType VectorType) error {
+	if vectorType.Dimension != table.valueType.Dimension {
+		return fmt.Errorf("feature %s (%s) has vectors of dimension %d, but pinecone index %s has dimension %d", feature, variant, vectorType.Dimension, table.indexName, table.valueType.Dimension)
+	}
+	return nil
 }
 
 func (table pineconeOnlineTable) Set(entity string, value interface{}) error {
-	vector, isVector := value.([]float32)
-	if !isVector {
-		return fmt.Errorf("expected value to be of type []float32, got %T", value)
+	vector, err := table.valueType.vector32(value)
+	if err != nil {
+		return fmt.Errorf("could not set %s in pinecone index %s: %w", entity, table.indexName, err)
 	}
-	err := table.api.upsert(table.indexName, table.namespace, entity, vector)
+	err = table.api.upsert(table.indexName, table.namespace, entity, vector)
 	if err != nil {
 		return err
 	}
@@ -257,6 +319,17 @@ func (api pineconeAPI) deleteIndex(name string) error {
 	return err
 }
 
+// https://docs.pinecone.io/reference/delete_post
+func (api pineconeAPI) deleteNamespace(indexName, namespace string) error {
+	base := api.getVectorOperationURL(indexName, "vectors/delete")
+	payload := &deleteRequest{
+		DeleteAll: true,
+		Namespace: namespace,
+	}
+	_, err := api.request(http.MethodPost, base, payload, http.StatusOK)
+	return err
+}
+
 // https://docs.pinecone.io/reference/upsert
 func (api pineconeAPI) upsert(indexName, namespace, id string, vector []float32) error {
 	base := api.getVectorOperationURL(indexName, "vectors/upsert")
@@ -382,7 +455,7 @@ func (api pineconeAPI) request(method, url string, payload interface{}, expected
 		return nil, err
 	}
 	if resp.StatusCode != expectedStatus {
-		return nil, fmt.Errorf("request failed with status code %d: %s", resp.StatusCode, string(body))
+		return nil, &pineconeRequestError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 	return body, nil
 }
@@ -395,6 +468,15 @@ func (api pineconeAPI) generateDeterministicID(id string) string {
 	return uuid.String()
 }
 
+type pineconeRequestError struct {
+	StatusCode int
+	Body       string
+}
+
+func (err *pineconeRequestError) Error() string {
+	return fmt.Sprintf("request failed with status code %d: %s", err.StatusCode, err.Body)
+}
+
 type createIndexRequest struct {
 	Name      string `json:"name"`
 	Dimension int32  `json:"dimension"`
@@ -444,6 +526,11 @@ type upsertRequest struct {
 	Namespace string          `json:"namespace"`
 }
 
+type deleteRequest struct {
+	DeleteAll bool   `json:"deleteAll"`
+	Namespace string `json:"namespace"`
+}
+
 type upsertResponse struct {
 	UpsertedCount int64 `json:"upsertedCount"`
 }
@@ -0,0 +1,235 @@
+package provider
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pc "github.com/featureform/provider/provider_config"
+)
+
+// fakePinecone serves the parts of the Pinecone API the online store uses,
+// keeping indexes and vectors in memory
+type fakePinecone struct {
+	mtx       sync.Mutex
+	indexes   map[string]int32
+	vectors   map[string]map[string]vectorElement
+	creates   int
+	projectID string
+	env       string
+
+	// How many more times each index is described as initializing before
+	// it's ready
+	initializing map[string]int
+}
+
+func newFakePinecone(t *testing.T, config *pc.PineconeConfig) (*pineconeOnlineStore, *fakePinecone) {
+	fake := &fakePinecone{
+		indexes:      make(map[string]int32),
+		vectors:      make(map[string]map[string]vectorElement),
+		projectID:    config.ProjectID,
+		env:          config.Environment,
+		initializing: make(map[string]int),
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	store, err := NewPineconeOnlineStore(config)
+	if err != nil {
+		t.Fatalf("could not create pinecone store: %v", err)
+	}
+	store.client.baseURLTemplate = server.URL + "/%s/%s"
+	store.pollInterval = time.Millisecond
+	return store, fake
+}
+
+func (fake *fakePinecone) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fake.mtx.Lock()
+	defer fake.mtx.Unlock()
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	host, operation := parts[0], parts[1]
+	if host == "controller."+fake.env {
+		fake.serveIndexOperation(w, r, operation)
+		return
+	}
+	index := strings.TrimSuffix(host, "-"+fake.projectID+".svc."+fake.env)
+	if _, has := fake.indexes[index]; !has {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch operation {
+	case "vectors/upsert":
+		var req upsertRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		key := index + "/" + req.Namespace
+		if fake.vectors[key] == nil {
+			fake.vectors[key] = make(map[string]vectorElement)
+		}
+		for _, vector := range req.Vectors {
+			if int32(len(vector.Values)) != fake.indexes[index] {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fake.vectors[key][vector.ID] = vector
+		}
+		json.NewEncoder(w).Encode(upsertResponse{UpsertedCount: int64(len(req.Vectors))})
+	case "vectors/fetch":
+		query := r.URL.Query()
+		vectors := fake.vectors[index+"/"+query.Get("namespace")]
+		response := fetchResponse{Vectors: make(map[string]vectorElement)}
+		if vector, has := vectors[query.Get("ids")]; has {
+			response.Vectors[vector.ID] = vector
+		}
+		json.NewEncoder(w).Encode(response)
+	case "vectors/delete":
+		var req deleteRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		delete(fake.vectors, index+"/"+req.Namespace)
+		w.Write([]byte("{}"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (fake *fakePinecone) serveIndexOperation(w http.ResponseWriter, r *http.Request, operation string) {
+	if operation == "databases" && r.Method == http.MethodPost {
+		var req createIndexRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		fake.indexes[req.Name] = req.Dimension
+		fake.creates++
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	name := strings.TrimPrefix(operation, "databases/")
+	dimension, has := fake.indexes[name]
+	if !has {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		delete(fake.indexes, name)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	var response describeIndexResponse
+	response.Database.Name = name
+	response.Database.Dimension = int(dimension)
+	response.Status.State = Ready
+	if fake.initializing[name] > 0 {
+		fake.initializing[name]--
+		response.Status.State = Initializing
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func TestPineconeOnlineStore(t *testing.T) {
+	store, fake := newFakePinecone(t, &pc.PineconeConfig{ProjectID: "project", Environment: "env", ApiKey: "key"})
+	vectorType := VectorType{ScalarType: Float32, Dimension: 3, IsEmbedding: true}
+	if _, err := store.CreateIndex("embedding", "default", vectorType); err != nil {
+		t.Fatalf("could not create index: %v", err)
+	}
+	table, err := store.CreateTable("embedding", "default", vectorType)
+	if err != nil {
+		t.Fatalf("could not create table: %v", err)
+	}
+	if err := table.Set("a", []float64{1, 2, 3}); err != nil {
+		t.Fatalf("could not set vector: %v", err)
+	}
+	vector, err := table.Get("a")
+	if err != nil {
+		t.Fatalf("could not get vector: %v", err)
+	}
+	if !reflect.DeepEqual(vector, []float32{1, 2, 3}) {
+		t.Fatalf("expected [1 2 3], got %v", vector)
+	}
+	if err := table.Set("b", []float32{1, 2}); err == nil {
+		t.Fatalf("expected a vector of the wrong dimension to be rejected")
+	}
+
+	// Materializing the feature again reuses its index
+	if _, err := store.CreateIndex("embedding", "default", vectorType); err != nil {
+		t.Fatalf("could not create existing index: %v", err)
+	}
+	if fake.creates != 1 {
+		t.Fatalf("expected the index to be created once, got %d", fake.creates)
+	}
+
+	// Dimension mismatches fail before anything is written
+	wrongDimension := VectorType{ScalarType: Float32, Dimension: 4, IsEmbedding: true}
+	if _, err := store.CreateTable("embedding", "default", wrongDimension); err == nil {
+		t.Fatalf("expected a table with the wrong dimension to fail")
+	}
+	if _, err := store.CreateIndex("embedding", "default", wrongDimension); err == nil {
+		t.Fatalf("expected an index with the wrong dimension to fail")
+	}
+	if _, err := store.CreateTable("embedding", "default", Float32); err == nil {
+		t.Fatalf("expected a scalar table to fail")
+	}
+
+	if err := store.DeleteIndex("embedding", "default"); err != nil {
+		t.Fatalf("could not delete index: %v", err)
+	}
+	if len(fake.indexes) != 0 {
+		t.Fatalf("expected the index to be deleted, got %v", fake.indexes)
+	}
+}
+
+func TestPineconeCreateIndexWaitsForExistingIndex(t *testing.T) {
+	store, fake := newFakePinecone(t, &pc.PineconeConfig{ProjectID: "project", Environment: "env", ApiKey: "key"})
+	vectorType := VectorType{ScalarType: Float32, Dimension: 3, IsEmbedding: true}
+	// An earlier attempt created the index, which is still spinning up
+	indexName := store.indexName("embedding", "default")
+	fake.indexes[indexName] = 3
+	fake.initializing[indexName] = 3
+	table, err := store.CreateIndex("embedding", "default", vectorType)
+	if err != nil {
+		t.Fatalf("could not create index that's initializing: %v", err)
+	}
+	if fake.initializing[indexName] != 0 {
+		t.Fatalf("expected the index to be polled until it was ready")
+	}
+	if fake.creates != 0 {
+		t.Fatalf("expected the existing index to be reused, got %d creates", fake.creates)
+	}
+	if err := table.Set("a", []float32{1, 2, 3}); err != nil {
+		t.Fatalf("could not set vector: %v", err)
+	}
+}
+
+func TestPineconeOnlineStoreSharedIndex(t *testing.T) {
+	config := &pc.PineconeConfig{ProjectID: "project", Environment: "env", ApiKey: "key", Index: "embeddings"}
+	store, fake := newFakePinecone(t, config)
+	vectorType := VectorType{ScalarType: Float32, Dimension: 2, IsEmbedding: true}
+	if _, err := store.CreateIndex("embedding", "default", vectorType); err == nil {
+		t.Fatalf("expected a missing configured index to fail")
+	}
+	fake.indexes["embeddings"] = 2
+	for _, variant := range []string{"v1", "v2"} {
+		table, err := store.CreateIndex("embedding", variant, vectorType)
+		if err != nil {
+			t.Fatalf("could not create index for %s: %v", variant, err)
+		}
+		if err := table.Set("a", []float32{1, 2}); err != nil {
+			t.Fatalf("could not set vector for %s: %v", variant, err)
+		}
+	}
+	if fake.creates != 0 {
+		t.Fatalf("expected the configured index to be used, got %d creates", fake.creates)
+	}
+	if len(fake.vectors) != 2 {
+		t.Fatalf("expected each variant to have its own namespace, got %v", fake.vectors)
+	}
+	if err := store.DeleteIndex("embedding", "v1"); err != nil {
+		t.Fatalf("could not delete index: %v", err)
+	}
+	if _, has := fake.indexes["embeddings"]; !has {
+		t.Fatalf("expected the configured index to be kept")
+	}
+	if len(fake.vectors) != 1 {
+		t.Fatalf("expected only v1's vectors to be deleted, got %v", fake.vectors)
+	}
+}
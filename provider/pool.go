@@ -0,0 +1,269 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	pc "github.com/featureform/provider/provider_config"
+	pt "github.com/featureform/provider/provider_type"
+)
+
+// ProviderPool reuses providers, and the connections they hold, across the
+// jobs that use them rather than opening new ones for each job. Providers are
+// keyed by their type and serialized config, so a provider whose config
+// changes gets a provider of its own. A nil pool doesn't reuse anything: each
+// provider it returns is closed when it's released.
+type ProviderPool struct {
+	// The most providers kept open while no job is using them. The ones
+	// released longest ago are closed first. Zero keeps every one open.
+	MaxIdle int
+	// How long a provider is reused for. Older providers are closed once
+	// they're released and a new one is opened in their place. Zero reuses
+	// them indefinitely.
+	MaxLifetime time.Duration
+	// Given to each NamedOfflineStore the pool opens, if set, so that the
+	// names its stores generate can be asserted on
+	Names NameGenerator
+
+	mtx     sync.Mutex
+	entries map[poolKey]*pooledProvider
+	// Providers no job is using, released longest ago first
+	idle []*pooledProvider
+	// Set once the pool is closed, after which it doesn't hand out providers
+	closed bool
+}
+
+// ErrPoolClosed is returned by Get once the pool has been closed.
+var ErrPoolClosed = errors.New("provider pool is closed")
+
+type poolKey struct {
+	providerType pt.Type
+	config       string
+}
+
+type pooledProvider struct {
+	key      poolKey
+	provider Provider
+	created  time.Time
+	// How many jobs are using the provider
+	refs int
+	// Set once the provider has been replaced, so it's closed when released
+	retired bool
+}
+
+func NewProviderPool(maxIdle int, maxLifetime time.Duration) *ProviderPool {
+	return &ProviderPool{
+		MaxIdle:     maxIdle,
+		MaxLifetime: maxLifetime,
+		entries:     make(map[poolKey]*pooledProvider),
+	}
+}
+
+// Get returns the provider for t and config, opening one if the pool doesn't
+// have it. The returned function must be called once the provider is no
+// longer used, in place of closing its stores.
+func (pool *ProviderPool) Get(t pt.Type, config pc.SerializedConfig) (Provider, func() error, error) {
+	if pool == nil {
+		p, err := Get(t, config)
+		if err != nil {
+			return nil, nil, err
+		}
+		return p, releaseOnce(func() error { return closeProvider(p) }), nil
+	}
+	key := poolKey{providerType: t, config: string(config)}
+	entry, err := pool.acquire(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if entry == nil {
+		// Opening a provider can take as long as connecting to it, so it's
+		// done without holding the lock every other Get waits on
+		p, err := Get(t, config)
+		if err != nil {
+			return nil, nil, err
+		}
+		pool.injectNames(p)
+		if entry, err = pool.insert(key, p); err != nil {
+			return nil, nil, err
+		}
+	}
+	return entry.provider, releaseOnce(func() error { return pool.release(entry) }), nil
+}
+
+func (pool *ProviderPool) injectNames(p Provider) {
+	if pool.Names == nil {
+		return
+	}
+	store, err := p.AsOfflineStore()
+	if err != nil {
+		return
+	}
+	if named, ok := store.(NamedOfflineStore); ok {
+		named.SetNameGenerator(pool.Names)
+	}
+}
+
+// acquire returns the pool's provider for key with a reference added for the
+// caller, or nil if one has to be opened
+func (pool *ProviderPool) acquire(key poolKey) (*pooledProvider, error) {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+	if pool.closed {
+		return nil, ErrPoolClosed
+	}
+	entry, has := pool.entries[key]
+	if !has {
+		return nil, nil
+	}
+	if pool.expired(entry) {
+		return nil, pool.retire(entry)
+	}
+	pool.addRef(entry)
+	return entry, nil
+}
+
+// insert adds the newly opened p to the pool under key and returns it with a
+// reference added for the caller. If another Get opened a provider for key in
+// the meantime, that one is returned instead and p is closed.
+func (pool *ProviderPool) insert(key poolKey, p Provider) (*pooledProvider, error) {
+	entry, err := pool.addProvider(key, p)
+	if entry == nil || entry.provider == p {
+		if err != nil {
+			closeProvider(p)
+		}
+		return entry, err
+	}
+	if err := closeProvider(p); err != nil {
+		pool.release(entry)
+		return nil, err
+	}
+	return entry, nil
+}
+
+// addProvider is insert under the pool's lock. It returns the provider that
+// was already in the pool for key, if there is one.
+func (pool *ProviderPool) addProvider(key poolKey, p Provider) (*pooledProvider, error) {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+	if pool.closed {
+		return nil, ErrPoolClosed
+	}
+	if pool.entries == nil {
+		pool.entries = make(map[poolKey]*pooledProvider)
+	}
+	if entry, has := pool.entries[key]; has {
+		if !pool.expired(entry) {
+			pool.addRef(entry)
+			return entry, nil
+		}
+		if err := pool.retire(entry); err != nil {
+			return nil, err
+		}
+	}
+	entry := &pooledProvider{key: key, provider: p, created: time.Now()}
+	pool.entries[key] = entry
+	pool.addRef(entry)
+	return entry, nil
+}
+
+func (pool *ProviderPool) addRef(entry *pooledProvider) {
+	if entry.refs == 0 {
+		pool.removeIdle(entry)
+	}
+	entry.refs++
+}
+
+// releaseOnce returns a function that only calls release the first time it's
+// called, so a provider released twice isn't released for another job
+func releaseOnce(release func() error) func() error {
+	var once sync.Once
+	return func() error {
+		var err error
+		once.Do(func() {
+			err = release()
+		})
+		return err
+	}
+}
+
+func (pool *ProviderPool) expired(entry *pooledProvider) bool {
+	return pool.MaxLifetime > 0 && time.Since(entry.created) >= pool.MaxLifetime
+}
+
+// retire stops entry from being handed out, closing it now if no job is using
+// it
+func (pool *ProviderPool) retire(entry *pooledProvider) error {
+	delete(pool.entries, entry.key)
+	entry.retired = true
+	if entry.refs > 0 {
+		return nil
+	}
+	pool.removeIdle(entry)
+	return closeProvider(entry.provider)
+}
+
+func (pool *ProviderPool) release(entry *pooledProvider) error {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+	entry.refs--
+	if entry.refs > 0 {
+		return nil
+	}
+	if entry.retired {
+		return closeProvider(entry.provider)
+	}
+	if pool.expired(entry) {
+		return pool.retire(entry)
+	}
+	pool.idle = append(pool.idle, entry)
+	if pool.MaxIdle <= 0 || len(pool.idle) <= pool.MaxIdle {
+		return nil
+	}
+	return pool.retire(pool.idle[0])
+}
+
+func (pool *ProviderPool) removeIdle(entry *pooledProvider) {
+	for i, idle := range pool.idle {
+		if idle == entry {
+			pool.idle = append(pool.idle[:i], pool.idle[i+1:]...)
+			return
+		}
+	}
+}
+
+// Close closes every provider no job is using. Providers still in use are
+// closed when they're released. Get fails with ErrPoolClosed once the pool is
+// closed.
+func (pool *ProviderPool) Close() error {
+	if pool == nil {
+		return nil
+	}
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+	pool.closed = true
+	var errs []error
+	for _, entry := range pool.entries {
+		if err := pool.retire(entry); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("close provider pool: %v", errs)
+	}
+	return nil
+}
+
+// closeProvider closes the connections p holds, if it holds any
+func closeProvider(p Provider) error {
+	closer, ok := p.(interface{ Close() error })
+	if !ok {
+		return nil
+	}
+	return closer.Close()
+}
@@ -0,0 +1,211 @@
+package provider
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	pc "github.com/featureform/provider/provider_config"
+	pt "github.com/featureform/provider/provider_type"
+)
+
+const poolTestType pt.Type = "POOL_TEST"
+
+// closeCountingProvider records how many times it's been closed
+type closeCountingProvider struct {
+	BaseProvider
+	closes int
+}
+
+func (p *closeCountingProvider) Close() error {
+	p.closes++
+	return nil
+}
+
+// Opening a provider with the config "slow" signals poolTestSlowStarted and
+// waits for poolTestSlowOpen to be closed
+var (
+	poolTestSlowStarted = make(chan struct{}, 1)
+	poolTestSlowOpen    = make(chan struct{})
+)
+
+var poolTestOpened struct {
+	sync.Mutex
+	providers []*closeCountingProvider
+}
+
+func init() {
+	err := RegisterFactory(poolTestType, func(config pc.SerializedConfig) (Provider, error) {
+		if string(config) == "slow" {
+			poolTestSlowStarted <- struct{}{}
+			<-poolTestSlowOpen
+		}
+		p := &closeCountingProvider{BaseProvider: BaseProvider{ProviderType: poolTestType, ProviderConfig: config}}
+		poolTestOpened.Lock()
+		defer poolTestOpened.Unlock()
+		poolTestOpened.providers = append(poolTestOpened.providers, p)
+		return p, nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func getPooled(t *testing.T, pool *ProviderPool, config string) (*closeCountingProvider, func() error) {
+	p, release, err := pool.Get(poolTestType, pc.SerializedConfig(config))
+	if err != nil {
+		t.Fatalf("Failed to get provider: %s", err)
+	}
+	return p.(*closeCountingProvider), release
+}
+
+func TestProviderPoolReusesProviders(t *testing.T) {
+	pool := NewProviderPool(0, 0)
+	first, releaseFirst := getPooled(t, pool, "config")
+	second, releaseSecond := getPooled(t, pool, "config")
+	if first != second {
+		t.Fatalf("Expected providers with the same config to be shared")
+	}
+	other, releaseOther := getPooled(t, pool, "changed config")
+	if other == first {
+		t.Fatalf("Expected providers with different configs to be distinct")
+	}
+	for _, release := range []func() error{releaseFirst, releaseSecond, releaseOther, releaseOther} {
+		if err := release(); err != nil {
+			t.Fatalf("Failed to release provider: %s", err)
+		}
+	}
+	if first.closes != 0 {
+		t.Fatalf("Expected idle provider to stay open, closed %d times", first.closes)
+	}
+	if again, _ := getPooled(t, pool, "config"); again != first {
+		t.Fatalf("Expected idle provider to be reused")
+	}
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Failed to close pool: %s", err)
+	}
+	if other.closes != 1 {
+		t.Fatalf("Expected closing the pool to close idle provider once, closed %d times", other.closes)
+	}
+}
+
+func TestProviderPoolMaxIdle(t *testing.T) {
+	pool := NewProviderPool(1, 0)
+	first, releaseFirst := getPooled(t, pool, "first")
+	second, releaseSecond := getPooled(t, pool, "second")
+	releaseFirst()
+	releaseSecond()
+	if first.closes != 1 || second.closes != 0 {
+		t.Fatalf("Expected only the provider released first to be closed, got %d and %d closes", first.closes, second.closes)
+	}
+	if reopened, _ := getPooled(t, pool, "first"); reopened == first {
+		t.Fatalf("Expected closed provider to be replaced")
+	}
+}
+
+func TestProviderPoolMaxLifetime(t *testing.T) {
+	pool := NewProviderPool(0, 50*time.Millisecond)
+	old, releaseOld := getPooled(t, pool, "config")
+	time.Sleep(100 * time.Millisecond)
+	replacement, releaseReplacement := getPooled(t, pool, "config")
+	if replacement == old {
+		t.Fatalf("Expected provider past its lifetime to be replaced")
+	}
+	if old.closes != 0 {
+		t.Fatalf("Expected provider in use to stay open")
+	}
+	releaseOld()
+	if old.closes != 1 {
+		t.Fatalf("Expected replaced provider to be closed when released, closed %d times", old.closes)
+	}
+	releaseReplacement()
+	if replacement.closes != 0 {
+		t.Fatalf("Expected replacement to stay open")
+	}
+}
+
+func TestProviderPoolOpensWithoutBlockingGets(t *testing.T) {
+	pool := NewProviderPool(0, 0)
+	defer pool.Close()
+	slowErr := make(chan error, 1)
+	go func() {
+		_, _, err := pool.Get(poolTestType, pc.SerializedConfig("slow"))
+		slowErr <- err
+	}()
+	<-poolTestSlowStarted
+	fastErr := make(chan error, 1)
+	go func() {
+		_, _, err := pool.Get(poolTestType, pc.SerializedConfig("fast"))
+		fastErr <- err
+	}()
+	select {
+	case err := <-fastErr:
+		if err != nil {
+			t.Fatalf("Failed to get provider: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected Get to return while another provider is being opened")
+	}
+	close(poolTestSlowOpen)
+	if err := <-slowErr; err != nil {
+		t.Fatalf("Failed to get slow provider: %s", err)
+	}
+}
+
+func TestProviderPoolGetAfterClose(t *testing.T) {
+	pool := NewProviderPool(0, 0)
+	_, release := getPooled(t, pool, "config")
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Failed to close pool: %s", err)
+	}
+	if _, _, err := pool.Get(poolTestType, pc.SerializedConfig("config")); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("Expected ErrPoolClosed from closed pool, got %v", err)
+	}
+	if err := release(); err != nil {
+		t.Fatalf("Failed to release provider: %s", err)
+	}
+}
+
+func TestNilProviderPoolClosesOnRelease(t *testing.T) {
+	var pool *ProviderPool
+	first, release := getPooled(t, pool, "config")
+	if second, _ := getPooled(t, pool, "config"); second == first {
+		t.Fatalf("Expected nil pool not to reuse providers")
+	}
+	release()
+	release()
+	if first.closes != 1 {
+		t.Fatalf("Expected provider to be closed once, closed %d times", first.closes)
+	}
+}
+
+func TestProviderPoolInjectsNames(t *testing.T) {
+	pool := NewProviderPool(0, 0)
+	defer pool.Close()
+	pool.Names = NewSequenceNameGenerator("pooled")
+	p, release, err := pool.Get(pt.MemoryOffline, pc.SerializedConfig{})
+	if err != nil {
+		t.Fatalf("Failed to get provider: %s", err)
+	}
+	defer release()
+	store, err := p.AsOfflineStore()
+	if err != nil {
+		t.Fatalf("Failed to get offline store: %s", err)
+	}
+	id := ResourceID{Name: "feature", Variant: "variant", Type: Feature}
+	table, err := store.CreateResourceTable(id, TableSchema{})
+	if err != nil {
+		t.Fatalf("Failed to create resource table: %s", err)
+	}
+	if err := table.Write(ResourceRecord{Entity: "a", Value: 1}); err != nil {
+		t.Fatalf("Failed to write record: %s", err)
+	}
+	mat, err := store.CreateMaterialization(id)
+	if err != nil {
+		t.Fatalf("Failed to create materialization: %s", err)
+	}
+	if mat.ID() != "pooled_1" {
+		t.Fatalf("Expected materialization pooled_1, got %s", mat.ID())
+	}
+}
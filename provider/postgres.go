@@ -8,7 +8,7 @@ import (
 
 	pc "github.com/featureform/provider/provider_config"
 	pt "github.com/featureform/provider/provider_type"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type postgresColumnType string
@@ -65,6 +65,7 @@ func (q postgresSQLQueries) registerResources(db *sql.DB, tableName string, sche
 		query = fmt.Sprintf("CREATE VIEW %s AS SELECT %s as entity, %s as value, to_timestamp('%s', 'YYYY-DD-MM HH24:MI:SS +0000 UTC')::TIMESTAMPTZ as ts FROM %s", sanitize(tableName),
 			sanitize(schema.Entity), sanitize(schema.Value), time.UnixMilli(0).UTC(), sanitize(schema.SourceTable))
 	}
+	query += filterClause(schema.Filter)
 	fmt.Printf("Resource creation query: %s", query)
 	if _, err := db.Exec(query); err != nil {
 		return err
@@ -115,6 +116,33 @@ func (q postgresSQLQueries) newSQLOfflineTable(name string, columnType string) s
 	return fmt.Sprintf("CREATE TABLE %s (entity VARCHAR, value %s, ts TIMESTAMPTZ, UNIQUE (entity, ts))", sanitize(name), columnType)
 }
 
+// bulkInsert streams rows into table with COPY in a single transaction
+func (q postgresSQLQueries) bulkInsert(db *sql.DB, table string, columns []string, rows [][]interface{}) error {
+	txn, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+	stmt, err := txn.Prepare(pq.CopyIn(table, columns...))
+	if err != nil {
+		return fmt.Errorf("prepare copy: %w", err)
+	}
+	for _, row := range rows {
+		if _, err := stmt.Exec(row...); err != nil {
+			stmt.Close()
+			return fmt.Errorf("copy row: %w", err)
+		}
+	}
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return fmt.Errorf("flush copy: %w", err)
+	}
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+	return txn.Commit()
+}
+
 func (q postgresSQLQueries) createValuePlaceholderString(columns []TableColumn) string {
 	placeholders := make([]string, 0)
 	for i := range columns {
@@ -142,8 +170,8 @@ func (q postgresSQLQueries) trainingSetQuery(store *sqlOfflineStore, def Trainin
 		santizedName := sanitize(tableName)
 		tableJoinAlias := fmt.Sprintf("t%d", i)
 		columns = append(columns, santizedName)
-		query = fmt.Sprintf("%s LEFT JOIN LATERAL (SELECT entity , value as %s, ts  FROM %s WHERE entity=l.entity and ts <= l.ts ORDER BY ts desc LIMIT 1) %s on %s.entity=l.entity ",
-			query, santizedName, santizedName, tableJoinAlias, tableJoinAlias)
+		query = fmt.Sprintf("%s %s LATERAL (SELECT entity , value as %s, ts  FROM %s WHERE entity=l.entity%s ORDER BY ts desc LIMIT 1) %s on %s.entity=l.entity ",
+			query, def.JoinPolicy.featureJoin(), santizedName, santizedName, def.JoinStrategy.featureTimeCondition("ts", "l.ts"), tableJoinAlias, tableJoinAlias)
 		if i == len(def.Features)-1 {
 			query = fmt.Sprintf("%s )", query)
 		}
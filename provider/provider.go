@@ -23,6 +23,8 @@ func init() {
 		pt.PostgresOffline:  postgresOfflineStoreFactory,
 		pt.SnowflakeOffline: snowflakeOfflineStoreFactory,
 		pt.RedshiftOffline:  redshiftOfflineStoreFactory,
+		pt.MySQLOffline:     mysqlOfflineStoreFactory,
+		pt.SQLiteOffline:    sqliteOfflineStoreFactory,
 		pt.BigQueryOffline:  bigQueryOfflineStoreFactory,
 		pt.SparkOffline:     sparkOfflineStoreFactory,
 		pt.K8sOffline:       k8sOfflineStoreFactory,
@@ -52,11 +54,11 @@ type BaseProvider struct {
 }
 
 func (provider BaseProvider) AsOnlineStore() (OnlineStore, error) {
-	return nil, fmt.Errorf("%T cannot be used as an OnlineStore", provider)
+	return nil, &NotOnlineStoreError{Type: provider.ProviderType}
 }
 
 func (provider BaseProvider) AsOfflineStore() (OfflineStore, error) {
-	return nil, fmt.Errorf("%T cannot be used as an OfflineStore", provider)
+	return nil, &NotOfflineStoreError{Type: provider.ProviderType}
 }
 
 func (provider BaseProvider) Type() pt.Type {
@@ -82,7 +84,7 @@ func RegisterFactory(t pt.Type, f Factory) error {
 func Get(t pt.Type, config pc.SerializedConfig) (Provider, error) {
 	f, has := factories[t]
 	if !has {
-		return nil, fmt.Errorf("no provider of type: %s", t)
+		return nil, &UnknownProviderTypeError{Type: t}
 	}
 	return f(config)
 }
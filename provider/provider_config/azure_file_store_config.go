@@ -12,6 +12,8 @@ type AzureFileStoreConfig struct {
 	AccountKey    string
 	ContainerName string
 	Path          string
+	// Base64 encoded AES key to encrypt files with, as in S3FileStoreConfig
+	EncryptionKey string
 }
 
 func (store *AzureFileStoreConfig) IsFileStoreConfig() bool {
@@ -7,14 +7,28 @@ import (
 )
 
 type CassandraConfig struct {
-	Keyspace    string
-	Addr        string
-	Username    string
-	Password    string
+	Keyspace string
+	Addr     string
+	// More nodes to connect to, as host:port, for clusters where Addr alone
+	// might be down. Works the same for ScyllaDB.
+	ContactPoints []string `json:",omitempty"`
+	Username      string
+	Password      string
+	// The consistency level that every read and write is made at, such as
+	// ONE or LOCAL_QUORUM. Defaults to QUORUM.
 	Consistency string
 	Replication int
 }
 
+// Hosts returns the nodes the store connects to, starting with Addr
+func (cass CassandraConfig) Hosts() []string {
+	hosts := make([]string, 0, len(cass.ContactPoints)+1)
+	if cass.Addr != "" {
+		hosts = append(hosts, cass.Addr)
+	}
+	return append(hosts, cass.ContactPoints...)
+}
+
 func (cass CassandraConfig) Serialized() SerializedConfig {
 	config, err := json.Marshal(cass)
 	if err != nil {
@@ -30,6 +30,26 @@ func TestCassandraConfigMutableFields(t *testing.T) {
 	}
 }
 
+func TestCassandraConfigHosts(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   CassandraConfig
+		expected []string
+	}{
+		{"Addr Only", CassandraConfig{Addr: "0.0.0.0:9042"}, []string{"0.0.0.0:9042"}},
+		{"Contact Points Only", CassandraConfig{ContactPoints: []string{"10.0.0.1:9042", "10.0.0.2:9042"}}, []string{"10.0.0.1:9042", "10.0.0.2:9042"}},
+		{"Addr And Contact Points", CassandraConfig{Addr: "0.0.0.0:9042", ContactPoints: []string{"10.0.0.1:9042"}}, []string{"0.0.0.0:9042", "10.0.0.1:9042"}},
+		{"Neither", CassandraConfig{}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := tt.config.Hosts(); !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("Expected %v, but instead found %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
 func TestCassandraConfigDifferingFields(t *testing.T) {
 	type args struct {
 		a CassandraConfig
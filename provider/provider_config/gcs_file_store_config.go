@@ -10,6 +10,8 @@ type GCSFileStoreConfig struct {
 	BucketName  string
 	BucketPath  string
 	Credentials GCPCredentials
+	// Base64 encoded AES key to encrypt files with, as in S3FileStoreConfig
+	EncryptionKey string
 }
 
 func (s *GCSFileStoreConfig) Deserialize(config SerializedConfig) error {
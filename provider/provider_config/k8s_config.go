@@ -14,6 +14,11 @@ type K8sConfig struct {
 	ExecutorConfig interface{}
 	StoreType      filestore.FileStoreType
 	StoreConfig    FileStoreConfig
+	// Codec and rows per row group of the parquet files the store's jobs
+	// write, unless a job sets its own. Defaults to snappy and the writer's
+	// default row group size.
+	ParquetCompression  string
+	ParquetRowGroupSize int64
 }
 
 func (k8s *K8sConfig) Serialize() ([]byte, error) {
@@ -34,10 +39,12 @@ func (k8s *K8sConfig) Deserialize(data SerializedConfig) error {
 
 func (k8s *K8sConfig) UnmarshalJSON(data []byte) error {
 	type tempConfig struct {
-		ExecutorType   ExecutorType
-		ExecutorConfig interface{}
-		StoreType      filestore.FileStoreType
-		StoreConfig    map[string]interface{}
+		ExecutorType        ExecutorType
+		ExecutorConfig      interface{}
+		StoreType           filestore.FileStoreType
+		StoreConfig         map[string]interface{}
+		ParquetCompression  string
+		ParquetRowGroupSize int64
 	}
 
 	var temp tempConfig
@@ -48,6 +55,8 @@ func (k8s *K8sConfig) UnmarshalJSON(data []byte) error {
 
 	k8s.ExecutorType = temp.ExecutorType
 	k8s.StoreType = temp.StoreType
+	k8s.ParquetCompression = temp.ParquetCompression
+	k8s.ParquetRowGroupSize = temp.ParquetRowGroupSize
 
 	if temp.ExecutorConfig == "" {
 		k8s.ExecutorConfig = ExecutorConfig{}
@@ -7,6 +7,10 @@ import (
 
 type LocalFileStoreConfig struct {
 	DirPath string
+	// Where staging directories are created. Defaults to os.TempDir.
+	TempDir string
+	// Base64 encoded AES key to encrypt files with, as in S3FileStoreConfig
+	EncryptionKey string
 }
 
 func (config *LocalFileStoreConfig) Serialize() ([]byte, error) {
@@ -0,0 +1,47 @@
+package provider_config
+
+import (
+	"encoding/json"
+
+	ss "github.com/featureform/helpers/string_set"
+)
+
+type MySQLConfig struct {
+	Host     string `json:"Host"`
+	Port     string `json:"Port"`
+	Username string `json:"Username"`
+	Password string `json:"Password"`
+	Database string `json:"Database"`
+	// One of "true", "false", "skip-verify" or "preferred", as accepted by the
+	// MySQL driver's tls parameter. Defaults to "false".
+	TLSMode string `json:"TLSMode"`
+}
+
+func (my *MySQLConfig) Deserialize(config SerializedConfig) error {
+	err := json.Unmarshal(config, my)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (my *MySQLConfig) Serialize() []byte {
+	conf, err := json.Marshal(my)
+	if err != nil {
+		panic(err)
+	}
+	return conf
+}
+
+func (my MySQLConfig) MutableFields() ss.StringSet {
+	return ss.StringSet{
+		"Username": true,
+		"Password": true,
+		"Port":     true,
+		"TLSMode":  true,
+	}
+}
+
+func (a MySQLConfig) DifferingFields(b MySQLConfig) (ss.StringSet, error) {
+	return differingFields(a, b)
+}
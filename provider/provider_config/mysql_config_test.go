@@ -0,0 +1,102 @@
+package provider_config
+
+import (
+	"reflect"
+	"testing"
+
+	ss "github.com/featureform/helpers/string_set"
+)
+
+func TestMySQLConfigMutableFields(t *testing.T) {
+	expected := ss.StringSet{
+		"Username": true,
+		"Password": true,
+		"Port":     true,
+		"TLSMode":  true,
+	}
+
+	config := MySQLConfig{
+		Host:     "0.0.0.0",
+		Port:     "3306",
+		Username: "mysql",
+		Password: "password",
+		Database: "mysql",
+		TLSMode:  "false",
+	}
+	actual := config.MutableFields()
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v but received %v", expected, actual)
+	}
+}
+
+func TestMySQLConfigDifferingFields(t *testing.T) {
+	type args struct {
+		a MySQLConfig
+		b MySQLConfig
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		expected ss.StringSet
+	}{
+		{"No Differing Fields", args{
+			a: MySQLConfig{
+				Host:     "0.0.0.0",
+				Port:     "3306",
+				Username: "mysql",
+				Password: "password",
+				Database: "mysql",
+				TLSMode:  "false",
+			},
+			b: MySQLConfig{
+				Host:     "0.0.0.0",
+				Port:     "3306",
+				Username: "mysql",
+				Password: "password",
+				Database: "mysql",
+				TLSMode:  "false",
+			},
+		}, ss.StringSet{}},
+		{"Differing Fields", args{
+			a: MySQLConfig{
+				Host:     "0.0.0.0",
+				Port:     "3306",
+				Username: "mysql",
+				Password: "password",
+				Database: "mysql",
+				TLSMode:  "false",
+			},
+			b: MySQLConfig{
+				Host:     "127.0.0.1",
+				Port:     "3306",
+				Username: "root",
+				Password: "password",
+				Database: "transaction",
+				TLSMode:  "true",
+			},
+		}, ss.StringSet{
+			"Host":     true,
+			"Username": true,
+			"Database": true,
+			"TLSMode":  true,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := tt.args.a.DifferingFields(tt.args.b)
+
+			if err != nil {
+				t.Errorf("Failed to get differing fields due to error: %v", err)
+			}
+
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("Expected %v, but instead found %v", tt.expected, actual)
+			}
+
+		})
+	}
+
+}
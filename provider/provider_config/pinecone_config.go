@@ -15,6 +15,9 @@ type PineconeConfig struct {
 	ProjectID   string
 	Environment string
 	ApiKey      string
+	// Index is an existing index to store every feature in, each in its own
+	// namespace. If it's empty, an index is created for each feature.
+	Index string
 }
 
 func (pc PineconeConfig) Serialize() SerializedConfig {
@@ -61,6 +61,21 @@ func TestPineconeConfigDifferingFields(t *testing.T) {
 		}, ss.StringSet{
 			"ApiKey": true,
 		}},
+		{"Differing Index", args{
+			a: PineconeConfig{
+				ProjectID:   "default",
+				Environment: "us-west1-gcp-free",
+				ApiKey:      "pinecone-api-key",
+			},
+			b: PineconeConfig{
+				ProjectID:   "default",
+				Environment: "us-west1-gcp-free",
+				ApiKey:      "pinecone-api-key",
+				Index:       "embeddings",
+			},
+		}, ss.StringSet{
+			"Index": true,
+		}},
 	}
 
 	for _, tt := range tests {
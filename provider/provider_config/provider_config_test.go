@@ -23,6 +23,8 @@ var providerMap = map[string]string{
 	"POSTGRES_OFFLINE":  "PostgresConfig",
 	"SNOWFLAKE_OFFLINE": "SnowflakeConfig",
 	"REDSHIFT_OFFLINE":  "RedshiftConfig",
+	"MYSQL_OFFLINE":     "MySQLConfig",
+	"SQLITE_OFFLINE":    "SQLiteConfig",
 	"SPARK_OFFLINE":     "SparkConfig",
 	"BIGQUERY_OFFLINE":  "BigQueryConfig",
 	"K8S_OFFLINE":       "K8sConfig",
@@ -257,6 +259,53 @@ func TestPostgres(t *testing.T) {
 	assert.NotNil(t, instance)
 }
 
+func TestMySQL(t *testing.T) {
+	connectionConfigs, err := getConnectionConfigs()
+	if err != nil {
+		println(err)
+		t.FailNow()
+	}
+
+	var jsonDict map[string]interface{}
+	if err = json.Unmarshal(connectionConfigs, &jsonDict); err != nil {
+		println(err)
+		t.FailNow()
+	}
+
+	config := jsonDict["MySQLConfig"].(map[string]interface{})
+	instance := MySQLConfig{
+		Host:     config["Host"].(string),
+		Port:     config["Port"].(string),
+		Username: config["Username"].(string),
+		Password: config["Password"].(string),
+		Database: config["Database"].(string),
+		TLSMode:  config["TLSMode"].(string),
+	}
+
+	assert.NotNil(t, instance)
+}
+
+func TestSQLite(t *testing.T) {
+	connectionConfigs, err := getConnectionConfigs()
+	if err != nil {
+		println(err)
+		t.FailNow()
+	}
+
+	var jsonDict map[string]interface{}
+	if err = json.Unmarshal(connectionConfigs, &jsonDict); err != nil {
+		println(err)
+		t.FailNow()
+	}
+
+	config := jsonDict["SQLiteConfig"].(map[string]interface{})
+	instance := SQLiteConfig{
+		Path: config["Path"].(string),
+	}
+
+	assert.NotNil(t, instance)
+}
+
 func TestSnowflake(t *testing.T) {
 	connectionConfigs, err := getConnectionConfigs()
 	if err != nil {
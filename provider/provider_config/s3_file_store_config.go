@@ -2,6 +2,7 @@ package provider_config
 
 import (
 	"encoding/json"
+	"fmt"
 
 	ss "github.com/featureform/helpers/string_set"
 )
@@ -11,6 +12,10 @@ type S3FileStoreConfig struct {
 	BucketRegion string
 	BucketPath   string
 	Path         string
+	// Base64 encoded AES key that files written through the file store are
+	// encrypted with, on top of any encryption the storage applies. Executors
+	// that read the store's files directly can't read encrypted files.
+	EncryptionKey string
 }
 
 func (s *S3FileStoreConfig) Deserialize(config SerializedConfig) error {
@@ -48,6 +53,37 @@ type HDFSFileStoreConfig struct {
 	Port     string
 	Path     string
 	Username string
+	// Authenticates with Kerberos, rather than as Username, if set
+	Kerberos *HDFSKerberosConfig `json:",omitempty"`
+}
+
+// HDFSKerberosConfig is how an HDFS file store logs in to a kerberized
+// cluster. The principal logs in with the keytab at KeytabPath or, if it isn't
+// set, with the tickets in the credentials cache at CCachePath.
+type HDFSKerberosConfig struct {
+	Principal  string
+	Realm      string
+	KeytabPath string
+	CCachePath string
+	// The namenode's service principal name, as <SERVICE>/<FQDN>, such as
+	// nn/namenode.example.com. The same as dfs.namenode.kerberos.principal
+	// without its realm.
+	NamenodeSPN string
+	// Defaults to /etc/krb5.conf
+	Krb5ConfPath string
+}
+
+func (k HDFSKerberosConfig) Validate() error {
+	if k.NamenodeSPN == "" {
+		return fmt.Errorf("kerberos namenode service principal name is required")
+	}
+	if k.KeytabPath == "" && k.CCachePath == "" {
+		return fmt.Errorf("kerberos requires either a keytab or a credentials cache")
+	}
+	if k.KeytabPath != "" && (k.Principal == "" || k.Realm == "") {
+		return fmt.Errorf("kerberos keytab login requires a principal and realm")
+	}
+	return nil
 }
 
 func (s *HDFSFileStoreConfig) Deserialize(config SerializedConfig) error {
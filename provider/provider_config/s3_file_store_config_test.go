@@ -86,3 +86,61 @@ func TestS3ConfigDifferingFields(t *testing.T) {
 	}
 
 }
+
+func TestHDFSKerberosConfig(t *testing.T) {
+	// Configs without Kerberos serialize as they did before it was supported
+	plain := HDFSFileStoreConfig{Host: "localhost", Port: "9000", Path: "/", Username: "hduser"}
+	serialized, err := plain.Serialize()
+	if err != nil {
+		t.Fatalf("could not serialize config: %v", err)
+	}
+	expected := `{"Host":"localhost","Port":"9000","Path":"/","Username":"hduser"}`
+	if string(serialized) != expected {
+		t.Fatalf("expected %s, got %s", expected, serialized)
+	}
+
+	kerberized := HDFSFileStoreConfig{
+		Host: "localhost",
+		Port: "9000",
+		Path: "/",
+		Kerberos: &HDFSKerberosConfig{
+			Principal:   "featureform",
+			Realm:       "EXAMPLE.COM",
+			KeytabPath:  "/etc/security/featureform.keytab",
+			NamenodeSPN: "nn/namenode.example.com",
+		},
+	}
+	serialized, err = kerberized.Serialize()
+	if err != nil {
+		t.Fatalf("could not serialize config: %v", err)
+	}
+	deserialized := HDFSFileStoreConfig{}
+	if err := deserialized.Deserialize(serialized); err != nil {
+		t.Fatalf("could not deserialize config: %v", err)
+	}
+	if !reflect.DeepEqual(kerberized, deserialized) {
+		t.Fatalf("expected %v, got %v", kerberized, deserialized)
+	}
+
+	tests := map[string]struct {
+		config  HDFSKerberosConfig
+		isValid bool
+	}{
+		"Keytab":     {*kerberized.Kerberos, true},
+		"CCache":     {HDFSKerberosConfig{CCachePath: "/tmp/krb5cc_1000", NamenodeSPN: "nn/namenode.example.com"}, true},
+		"No SPN":     {HDFSKerberosConfig{CCachePath: "/tmp/krb5cc_1000"}, false},
+		"No Tickets": {HDFSKerberosConfig{Principal: "featureform", Realm: "EXAMPLE.COM", NamenodeSPN: "nn/namenode.example.com"}, false},
+		"No Realm":   {HDFSKerberosConfig{Principal: "featureform", KeytabPath: "/etc/security/featureform.keytab", NamenodeSPN: "nn/namenode.example.com"}, false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.config.Validate()
+			if test.isValid && err != nil {
+				t.Fatalf("expected config to be valid: %v", err)
+			}
+			if !test.isValid && err == nil {
+				t.Fatalf("expected config to be invalid")
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package provider_config
+
+import (
+	"encoding/json"
+
+	ss "github.com/featureform/helpers/string_set"
+)
+
+// SQLiteMemoryPath stores the database in memory rather than in a file. It's
+// shared by the providers open in the process, and dropped once none are.
+const SQLiteMemoryPath = ":memory:"
+
+type SQLiteConfig struct {
+	// The database file, which is created if it doesn't exist, or
+	// SQLiteMemoryPath
+	Path string `json:"Path"`
+}
+
+func (sq *SQLiteConfig) Deserialize(config SerializedConfig) error {
+	err := json.Unmarshal(config, sq)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (sq *SQLiteConfig) Serialize() []byte {
+	conf, err := json.Marshal(sq)
+	if err != nil {
+		panic(err)
+	}
+	return conf
+}
+
+// A different path is a different database, so nothing can be updated
+func (sq SQLiteConfig) MutableFields() ss.StringSet {
+	return ss.StringSet{}
+}
+
+func (a SQLiteConfig) DifferingFields(b SQLiteConfig) (ss.StringSet, error) {
+	return differingFields(a, b)
+}
@@ -0,0 +1,69 @@
+package provider_config
+
+import (
+	"reflect"
+	"testing"
+
+	ss "github.com/featureform/helpers/string_set"
+)
+
+func TestSQLiteConfigMutableFields(t *testing.T) {
+	expected := ss.StringSet{}
+
+	config := SQLiteConfig{
+		Path: "featureform.db",
+	}
+	actual := config.MutableFields()
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v but received %v", expected, actual)
+	}
+}
+
+func TestSQLiteConfigDifferingFields(t *testing.T) {
+	type args struct {
+		a SQLiteConfig
+		b SQLiteConfig
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		expected ss.StringSet
+	}{
+		{"No Differing Fields", args{
+			a: SQLiteConfig{
+				Path: "featureform.db",
+			},
+			b: SQLiteConfig{
+				Path: "featureform.db",
+			},
+		}, ss.StringSet{}},
+		{"Differing Fields", args{
+			a: SQLiteConfig{
+				Path: "featureform.db",
+			},
+			b: SQLiteConfig{
+				Path: SQLiteMemoryPath,
+			},
+		}, ss.StringSet{
+			"Path": true,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := tt.args.a.DifferingFields(tt.args.b)
+
+			if err != nil {
+				t.Errorf("Failed to get differing fields due to error: %v", err)
+			}
+
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("Expected %v, but instead found %v", tt.expected, actual)
+			}
+
+		})
+	}
+
+}
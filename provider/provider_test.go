@@ -7,6 +7,7 @@
 package provider
 
 import (
+	"errors"
 	"fmt"
 	pc "github.com/featureform/provider/provider_config"
 	pt "github.com/featureform/provider/provider_type"
@@ -45,9 +46,14 @@ func TestFactoryExists(t *testing.T) {
 }
 
 func TestFactoryDoesntExists(t *testing.T) {
-	if provider, err := Get(pt.Type("Doesnt exist"), mockConfig); err == nil {
+	provider, err := Get(pt.Type("Doesnt exist"), mockConfig)
+	if err == nil {
 		t.Fatalf("Succeeded in getting unregistered provider: %v", provider)
 	}
+	var unknown *UnknownProviderTypeError
+	if !errors.As(err, &unknown) || unknown.Type != pt.Type("Doesnt exist") {
+		t.Fatalf("Expected unknown provider type error, got %v", err)
+	}
 }
 
 func TestBaseProvider(t *testing.T) {
@@ -61,11 +67,16 @@ func TestBaseProvider(t *testing.T) {
 			ProviderConfig: mockConfig,
 		},
 	}
-	if _, err := mock.AsOnlineStore(); err == nil {
-		t.Fatalf("BaseProvider succeeded in OnlineStore cast")
+	var notOnline *NotOnlineStoreError
+	if _, err := mock.AsOnlineStore(); !errors.As(err, &notOnline) {
+		t.Fatalf("Expected BaseProvider OnlineStore cast to fail with NotOnlineStoreError, got %v", err)
+	}
+	var notOffline *NotOfflineStoreError
+	if _, err := mock.AsOfflineStore(); !errors.As(err, &notOffline) {
+		t.Fatalf("Expected BaseProvider OfflineStore cast to fail with NotOfflineStoreError, got %v", err)
 	}
-	if _, err := mock.AsOfflineStore(); err == nil {
-		t.Fatalf("BaseProvider succeeded in OfflineStore cast")
+	if notOffline.Type != mockType {
+		t.Fatalf("Expected error for provider type %s, got %s", mockType, notOffline.Type)
 	}
 	if !reflect.DeepEqual(mock.Type(), mockType) {
 		t.Fatalf("Type not passed down to provider")
@@ -22,6 +22,8 @@ const (
 	PostgresOffline  Type = "POSTGRES_OFFLINE"
 	SnowflakeOffline Type = "SNOWFLAKE_OFFLINE"
 	RedshiftOffline  Type = "REDSHIFT_OFFLINE"
+	MySQLOffline     Type = "MYSQL_OFFLINE"
+	SQLiteOffline    Type = "SQLITE_OFFLINE"
 	SparkOffline     Type = "SPARK_OFFLINE"
 	BigQueryOffline  Type = "BIGQUERY_OFFLINE"
 	K8sOffline       Type = "K8S_OFFLINE"
@@ -45,6 +47,8 @@ var AllProviderTypes = []Type{
 	PostgresOffline,
 	SnowflakeOffline,
 	RedshiftOffline,
+	MySQLOffline,
+	SQLiteOffline,
 	SparkOffline,
 	BigQueryOffline,
 	K8sOffline,
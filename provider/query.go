@@ -0,0 +1,86 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// QueryPrimaryOfflineStore is implemented by offline stores that can snapshot
+// the results of a SQL query into a primary table.
+type QueryPrimaryOfflineStore interface {
+	OfflineStore
+	RegisterPrimaryFromQuery(id ResourceID, query string) (PrimaryTable, error)
+}
+
+// Keywords that can only appear in a statement that writes data or changes the
+// schema. SELECT ... INTO is included since it creates a table.
+var writeKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true, "UPSERT": true,
+	"CREATE": true, "DROP": true, "ALTER": true, "TRUNCATE": true, "RENAME": true,
+	"GRANT": true, "REVOKE": true, "COPY": true, "CALL": true, "EXEC": true,
+	"EXECUTE": true, "INTO": true, "LOCK": true, "VACUUM": true,
+}
+
+// queryKeywords tokenizes a SQL query and returns its unquoted words in upper
+// case. String literals and quoted identifiers are skipped. Comments and
+// statement separators are rejected, except for a single trailing semicolon,
+// so that the query is always exactly one statement.
+func queryKeywords(query string) ([]string, error) {
+	trimmed := strings.TrimSuffix(strings.TrimSpace(query), ";")
+	words := make([]string, 0)
+	runes := []rune(trimmed)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\'' || r == '"' || r == '`':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("query has an unterminated quote")
+			}
+			i = end + 1
+		case r == ';':
+			return nil, fmt.Errorf("query must be a single statement")
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-', r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			return nil, fmt.Errorf("query cannot contain comments")
+		case unicode.IsLetter(r) || r == '_':
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+				end++
+			}
+			words = append(words, strings.ToUpper(string(runes[i:end])))
+			i = end
+		default:
+			i++
+		}
+	}
+	return words, nil
+}
+
+// ValidateReadOnlyQuery checks that query is a single SELECT (optionally with
+// common table expressions) that doesn't write data or change the schema.
+func ValidateReadOnlyQuery(query string) error {
+	if strings.Contains(query, "{{") {
+		return fmt.Errorf("query cannot reference other sources; register a SQL transformation instead")
+	}
+	words, err := queryKeywords(query)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 || (words[0] != "SELECT" && words[0] != "WITH") {
+		return fmt.Errorf("query must start with SELECT or WITH")
+	}
+	for _, word := range words {
+		if writeKeywords[word] {
+			return fmt.Errorf("query must be read-only but contains %s", word)
+		}
+	}
+	return nil
+}
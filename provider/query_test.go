@@ -0,0 +1,33 @@
+package provider
+
+import "testing"
+
+func TestValidateReadOnlyQuery(t *testing.T) {
+	valid := []string{
+		"SELECT * FROM users WHERE age > 18",
+		"select id, name from users;",
+		"WITH active AS (SELECT * FROM users WHERE status = 'active') SELECT * FROM active",
+		"SELECT * FROM users WHERE note = 'drop table; -- not a comment'",
+		`SELECT "insert" FROM events`,
+	}
+	for _, query := range valid {
+		if err := ValidateReadOnlyQuery(query); err != nil {
+			t.Errorf("expected query %q to be valid: %v", query, err)
+		}
+	}
+	invalid := []string{
+		"",
+		"DELETE FROM users",
+		"SELECT * FROM users; DROP TABLE users",
+		"SELECT * INTO backup FROM users",
+		"WITH deleted AS (DELETE FROM users RETURNING *) SELECT * FROM deleted",
+		"SELECT * FROM users -- comment",
+		"SELECT * FROM {{ users.v1 }}",
+		"SELECT * FROM users WHERE name = 'a",
+	}
+	for _, query := range invalid {
+		if err := ValidateReadOnlyQuery(query); err == nil {
+			t.Errorf("expected query %q to be invalid", query)
+		}
+	}
+}
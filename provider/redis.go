@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	pc "github.com/featureform/provider/provider_config"
@@ -23,6 +24,18 @@ func (t redisTableKey) String() string {
 	return string(marshalled)
 }
 
+// versionsKey is the hash holding the version of each entity written with
+// SetIfVersion.
+func (t redisTableKey) versionsKey() string {
+	return t.String() + ":versions"
+}
+
+// historyKey is the sorted set holding every version of an entity written to a
+// historical table, scored by the time it was written.
+func (t redisTableKey) historyKey(entity string) string {
+	return t.String() + ":history:" + entity
+}
+
 type redisOnlineStore struct {
 	client rueidis.Client
 	prefix string
@@ -95,11 +108,7 @@ func (store *redisOnlineStore) GetTable(feature, variant string) (OnlineStoreTab
 	// which wrote the scalar type string as the value to the field under the
 	// tables hash.
 	if _, isScalarString := ScalarTypes[ScalarType(vType)]; isScalarString {
-		return &redisOnlineTable{
-			client:    store.client,
-			key:       key,
-			valueType: ScalarType(vType),
-		}, nil
+		return store.scalarTable(key, ScalarType(vType))
 	}
 	valueTypeJSON := &ValueTypeJSONWrapper{}
 	err = json.Unmarshal([]byte(vType), valueTypeJSON)
@@ -118,17 +127,40 @@ func (store *redisOnlineStore) GetTable(feature, variant string) (OnlineStoreTab
 			valueType: valueTypeJSON.ValueType,
 		}
 	case ScalarType:
-		table = &redisOnlineTable{
-			client:    store.client,
-			key:       key,
-			valueType: valueTypeJSON.ValueType,
-		}
+		return store.scalarTable(key, valueTypeJSON.ValueType)
 	default:
 		return nil, fmt.Errorf("unknown value type: %T", valueTypeJSON.ValueType)
 	}
 	return table, nil
 }
 
+func (store *redisOnlineStore) historicalTablesKey() string {
+	return fmt.Sprintf("%s__historical_tables", store.prefix)
+}
+
+// scalarTable returns a historical table if the table was created with
+// CreateHistoricalTable, and a plain one otherwise.
+func (store *redisOnlineStore) scalarTable(key redisTableKey, valueType ValueType) (OnlineStoreTable, error) {
+	table := redisOnlineTable{
+		client:    store.client,
+		key:       key,
+		valueType: valueType,
+	}
+	cmd := store.client.B().
+		Sismember().
+		Key(store.historicalTablesKey()).
+		Member(key.String()).
+		Build()
+	historical, err := store.client.Do(context.TODO(), cmd).AsBool()
+	if err != nil {
+		return nil, err
+	}
+	if historical {
+		return &redisHistoricalTable{table}, nil
+	}
+	return &table, nil
+}
+
 func (store *redisOnlineStore) CreateTable(feature, variant string, valueType ValueType) (OnlineStoreTable, error) {
 	key := redisTableKey{store.prefix, feature, variant}
 	cmd := store.client.B().
@@ -180,6 +212,29 @@ func (store *redisOnlineStore) CreateTable(feature, variant string, valueType Va
 	return table, nil
 }
 
+func (store *redisOnlineStore) CreateHistoricalTable(feature, variant string, valueType ValueType) (HistoricalOnlineStoreTable, error) {
+	if valueType.IsVector() {
+		return nil, fmt.Errorf("historical tables do not support vector type %v", valueType)
+	}
+	if _, err := store.CreateTable(feature, variant, valueType); err != nil {
+		return nil, err
+	}
+	key := redisTableKey{store.prefix, feature, variant}
+	cmd := store.client.B().
+		Sadd().
+		Key(store.historicalTablesKey()).
+		Member(key.String()).
+		Build()
+	if err := store.client.Do(context.TODO(), cmd).Error(); err != nil {
+		return nil, err
+	}
+	return &redisHistoricalTable{redisOnlineTable{
+		client:    store.client,
+		key:       key,
+		valueType: valueType,
+	}}, nil
+}
+
 func (store *redisOnlineStore) DeleteTable(feature, variant string) error {
 	return nil
 }
@@ -228,6 +283,69 @@ type redisOnlineTable struct {
 }
 
 func (table redisOnlineTable) Set(entity string, value interface{}) error {
+	serialized, err := table.serialize(value)
+	if err != nil {
+		return err
+	}
+	cmd := table.client.B().
+		Hset().
+		Key(table.key.String()).
+		FieldValue().
+		FieldValue(entity, serialized).
+		Build()
+	res := table.client.Do(context.TODO(), cmd)
+	if res.Error() != nil {
+		return res.Error()
+	}
+	return nil
+}
+
+// Sets the value and has it expire after ARGV[3] milliseconds. Each entity is a
+// field of the table's hash, so this needs hash field expiry from Redis 7.4.
+// Overwriting a field clears its expiry, so it has to be set first; on older
+// servers the previous value is put back rather than keeping the new one
+// forever.
+var redisSetWithTTLScript = rueidis.NewLuaScript(`
+local previous = redis.call("HGET", KEYS[1], ARGV[1])
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+local expired = redis.pcall("HPEXPIRE", KEYS[1], ARGV[3], "FIELDS", 1, ARGV[1])
+if type(expired) == "table" and expired.err then
+	if previous then
+		redis.call("HSET", KEYS[1], ARGV[1], previous)
+	else
+		redis.call("HDEL", KEYS[1], ARGV[1])
+	end
+	return expired
+end
+return 1
+`)
+
+func (table redisOnlineTable) SetWithTTL(entity string, value interface{}, ttl time.Duration) error {
+	serialized, err := table.serialize(value)
+	if err != nil {
+		return err
+	}
+	keys := []string{table.key.String()}
+	args := []string{entity, serialized, strconv.FormatInt(ttl.Milliseconds(), 10)}
+	if err := redisSetWithTTLScript.Exec(context.TODO(), table.client, keys, args).Error(); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "unknown") {
+			return fmt.Errorf("%w: %v", ErrExpiryUnsupported, err)
+		}
+		return fmt.Errorf("set %s with ttl %s: %w", entity, ttl, err)
+	}
+	return nil
+}
+
+// serialize returns the string value is stored as. JSON values are stored as
+// their text, which parseValue decodes.
+func (table redisOnlineTable) serialize(value interface{}) (string, error) {
+	if table.valueType == JSON {
+		return jsonText(value)
+	}
+	return redisValueString(value)
+}
+
+func redisValueString(value interface{}) (string, error) {
 	switch v := value.(type) {
 	case nil:
 		value = "nil"
@@ -257,15 +375,168 @@ func (table redisOnlineTable) Set(entity string, value interface{}) error {
 	case []float32:
 		value = rueidis.VectorString32(v)
 	default:
-		return fmt.Errorf("type %T of value %v is unsupported", value, value)
+		return "", fmt.Errorf("type %T of value %v is unsupported", value, value)
 	}
+	return value.(string), nil
+}
+
+// Sets the value and bumps the entity's version only if the stored version is
+// still ARGV[3]. Returns the new version, or -1 if the versions don't match.
+var redisSetIfVersionScript = rueidis.NewLuaScript(`
+local current = tonumber(redis.call("HGET", KEYS[2], ARGV[1])) or 0
+if current ~= tonumber(ARGV[3]) then
+	return -1
+end
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+return redis.call("HINCRBY", KEYS[2], ARGV[1], 1)
+`)
+
+func (table redisOnlineTable) GetVersion(entity string) (int64, error) {
 	cmd := table.client.B().
-		Hset().
-		Key(table.key.String()).
-		FieldValue().
-		FieldValue(entity, value.(string)).
+		Hget().
+		Key(table.key.versionsKey()).
+		Field(entity).
 		Build()
-	res := table.client.Do(context.TODO(), cmd)
+	version, err := table.client.Do(context.TODO(), cmd).AsInt64()
+	if rueidis.IsRedisNil(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get version of %s: %w", entity, err)
+	}
+	return version, nil
+}
+
+func (table redisOnlineTable) SetIfVersion(entity string, value interface{}, version int64) (int64, error) {
+	serialized, err := table.serialize(value)
+	if err != nil {
+		return 0, err
+	}
+	keys := []string{table.key.String(), table.key.versionsKey()}
+	args := []string{entity, serialized, strconv.FormatInt(version, 10)}
+	newVersion, err := redisSetIfVersionScript.Exec(context.TODO(), table.client, keys, args).AsInt64()
+	if err != nil {
+		return 0, fmt.Errorf("set %s if version %d: %w", entity, version, err)
+	}
+	if newVersion < 0 {
+		return 0, ErrConflict
+	}
+	return newVersion, nil
+}
+
+// Keeps whichever of the stored and new values is larger (or smaller) in a single
+// round trip so concurrent merges can't interleave. ARGV[3] is 1 for max and -1 for min.
+var redisCompareAndSetScript = rueidis.NewLuaScript(`
+local current = tonumber(redis.call("HGET", KEYS[1], ARGV[1]))
+local value = tonumber(ARGV[2])
+if current == nil or (value - current) * tonumber(ARGV[3]) > 0 then
+	redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+end
+return 1
+`)
+
+// Appends the elements of the JSON array ARGV[2] that aren't already in the
+// stored array. Elements are compared by their JSON text, and cjson writes an
+// empty table as an object, so an empty result is written as [] directly.
+var redisUnionScript = rueidis.NewLuaScript(`
+local arrays = {cjson.decode(ARGV[2])}
+local current = redis.call("HGET", KEYS[1], ARGV[1])
+if current then
+	arrays = {cjson.decode(current), arrays[1]}
+end
+local merged = {}
+local seen = {}
+for _, array in ipairs(arrays) do
+	for _, element in ipairs(array) do
+		local key = cjson.encode(element)
+		if not seen[key] then
+			seen[key] = true
+			table.insert(merged, element)
+		end
+	end
+end
+if #merged == 0 then
+	redis.call("HSET", KEYS[1], ARGV[1], "[]")
+else
+	redis.call("HSET", KEYS[1], ARGV[1], cjson.encode(merged))
+end
+return 1
+`)
+
+func (table redisOnlineTable) Merge(entity string, value interface{}, strategy MergeStrategy) error {
+	if strategy == OverwriteMerge {
+		return table.Set(entity, value)
+	}
+	if strategy == UnionMerge {
+		return table.unionMerge(entity, value)
+	}
+	var isInt bool
+	switch table.valueType {
+	case Int, Int32, Int64:
+		isInt = true
+	case Float32, Float64:
+		isInt = false
+	default:
+		return fmt.Errorf("cannot %s merge values of type %v", strategy, table.valueType)
+	}
+	var intVal int64
+	var floatVal float64
+	switch v := value.(type) {
+	case int:
+		intVal, floatVal = int64(v), float64(v)
+	case int32:
+		intVal, floatVal = int64(v), float64(v)
+	case int64:
+		intVal, floatVal = v, float64(v)
+	case float32:
+		intVal, floatVal = int64(v), float64(v)
+	case float64:
+		intVal, floatVal = int64(v), v
+	default:
+		return fmt.Errorf("cannot %s merge value %v of type %T", strategy, value, value)
+	}
+	var serialized string
+	if isInt {
+		serialized = strconv.FormatInt(intVal, 10)
+	} else {
+		serialized = strconv.FormatFloat(floatVal, 'f', -1, 64)
+	}
+	var res rueidis.RedisResult
+	switch strategy {
+	case SumMerge:
+		var cmd rueidis.Completed
+		if isInt {
+			cmd = table.client.B().Hincrby().Key(table.key.String()).Field(entity).Increment(intVal).Build()
+		} else {
+			cmd = table.client.B().Hincrbyfloat().Key(table.key.String()).Field(entity).Increment(floatVal).Build()
+		}
+		res = table.client.Do(context.TODO(), cmd)
+	case MaxMerge:
+		res = redisCompareAndSetScript.Exec(context.TODO(), table.client, []string{table.key.String()}, []string{entity, serialized, "1"})
+	case MinMerge:
+		res = redisCompareAndSetScript.Exec(context.TODO(), table.client, []string{table.key.String()}, []string{entity, serialized, "-1"})
+	default:
+		return fmt.Errorf("unknown merge strategy: %s", strategy)
+	}
+	if res.Error() != nil {
+		return res.Error()
+	}
+	return nil
+}
+
+func (table redisOnlineTable) unionMerge(entity string, value interface{}) error {
+	if table.valueType != JSON {
+		return fmt.Errorf("cannot %s merge values of type %v", UnionMerge, table.valueType)
+	}
+	serialized, err := jsonText(value)
+	if err != nil {
+		return err
+	}
+	var elements []interface{}
+	if err := json.Unmarshal([]byte(serialized), &elements); err != nil || elements == nil {
+		return fmt.Errorf("cannot %s merge %s: value must be a JSON array", UnionMerge, serialized)
+	}
+	res := redisUnionScript.Exec(context.TODO(), table.client, []string{table.key.String()}, []string{entity, serialized})
 	if res.Error() != nil {
 		return res.Error()
 	}
@@ -282,12 +553,50 @@ func (table redisOnlineTable) Get(entity string) (interface{}, error) {
 	if resp.Error() != nil {
 		return nil, &EntityNotFound{entity}
 	}
-	var result interface{}
-	var err error
 	val, err := resp.ToString()
 	if err != nil {
 		return nil, err
 	}
+	return table.parseValue(val)
+}
+
+// BatchGet reads every entity's field of the table's hash with a single HMGET
+func (table redisOnlineTable) BatchGet(entities []string) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(entities))
+	if len(entities) == 0 {
+		return values, nil
+	}
+	cmd := table.client.B().
+		Hmget().
+		Key(table.key.String()).
+		Field(entities...).
+		Build()
+	msgs, err := table.client.Do(context.TODO(), cmd).ToArray()
+	if err != nil {
+		return nil, fmt.Errorf("batch get from %s: %w", table.key.String(), err)
+	}
+	for i, msg := range msgs {
+		if msg.IsNil() {
+			continue
+		}
+		val, err := msg.ToString()
+		if err != nil {
+			return nil, fmt.Errorf("get %s: %w", entities[i], err)
+		}
+		parsed, err := table.parseValue(val)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", entities[i], err)
+		}
+		values[entities[i]] = parsed
+	}
+	return values, nil
+}
+
+// parseValue converts a value stored by redisValueString back to the table's
+// value type.
+func (table redisOnlineTable) parseValue(val string) (interface{}, error) {
+	var result interface{}
+	var err error
 	if table.valueType.IsVector() {
 		return rueidis.ToVector32(val), nil
 	}
@@ -310,6 +619,8 @@ func (table redisOnlineTable) Get(entity string) (interface{}, error) {
 		result, err = strconv.ParseFloat(val, 64)
 	case Bool:
 		result, err = strconv.ParseBool(val)
+	case JSON:
+		result, err = parseJSON([]byte(val))
 	case Timestamp, Datetime: // Including `Datetime` here maintains compatibility with previously create timestamp tables
 		// Maintains compatibility with go-redis implementation:
 		// https://github.com/redis/go-redis/blob/v8.11.5/command.go#L939
@@ -318,11 +629,163 @@ func (table redisOnlineTable) Get(entity string) (interface{}, error) {
 		result, err = val, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("could not cast value: %v to %s: %w", resp, table.valueType, err)
+		return nil, fmt.Errorf("could not cast value: %v to %s: %w", val, table.valueType, err)
 	}
 	return result, nil
 }
 
+func (table redisOnlineTable) Delete(entity string) error {
+	for _, key := range []string{table.key.String(), table.key.versionsKey()} {
+		cmd := table.client.B().
+			Hdel().
+			Key(key).
+			Field(entity).
+			Build()
+		if err := table.client.Do(context.TODO(), cmd).Error(); err != nil {
+			return fmt.Errorf("delete %s: %w", entity, err)
+		}
+	}
+	return nil
+}
+
+func (table redisOnlineTable) Entities() ([]string, error) {
+	cmd := table.client.B().
+		Hkeys().
+		Key(table.key.String()).
+		Build()
+	entities, err := table.client.Do(context.TODO(), cmd).AsStrSlice()
+	if err != nil {
+		return nil, fmt.Errorf("list entities of %s: %w", table.key.String(), err)
+	}
+	return entities, nil
+}
+
+// Every entity of a feature variant is a field of the same hash, so deleting the
+// hash truncates the table without touching any other feature variant.
+func (table redisOnlineTable) Truncate() error {
+	cmd := table.client.B().
+		Del().
+		Key(table.key.String(), table.key.versionsKey()).
+		Build()
+	if err := table.client.Do(context.TODO(), cmd).Error(); err != nil {
+		return fmt.Errorf("truncate %s: %w", table.key.String(), err)
+	}
+	return nil
+}
+
+// redisHistoricalTable keeps the latest value of each entity in the table's hash,
+// like redisOnlineTable, and every version in a sorted set per entity. It wraps
+// rather than embeds redisOnlineTable so that writes that bypass the history,
+// such as Merge and SetIfVersion, aren't available.
+type redisHistoricalTable struct {
+	table redisOnlineTable
+}
+
+// Adds a version to the entity's history, and makes it the current value if no
+// later version has been written. Versions are members of the form
+// "<micros>:<value>" so that equal values written at different times are kept.
+var redisSetAtScript = rueidis.NewLuaScript(`
+redis.call("ZADD", KEYS[2], ARGV[3], ARGV[3] .. ":" .. ARGV[2])
+local newest = redis.call("ZREVRANGE", KEYS[2], 0, 0, "WITHSCORES")
+if tonumber(newest[2]) <= tonumber(ARGV[3]) then
+	redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+end
+return 1
+`)
+
+func (h redisHistoricalTable) Set(entity string, value interface{}) error {
+	return h.SetAt(entity, value, time.Now())
+}
+
+func (h redisHistoricalTable) SetAt(entity string, value interface{}, ts time.Time) error {
+	serialized, err := h.table.serialize(value)
+	if err != nil {
+		return err
+	}
+	keys := []string{h.table.key.String(), h.table.key.historyKey(entity)}
+	args := []string{entity, serialized, strconv.FormatInt(ts.UnixMicro(), 10)}
+	if err := redisSetAtScript.Exec(context.TODO(), h.table.client, keys, args).Error(); err != nil {
+		return fmt.Errorf("set %s at %s: %w", entity, ts, err)
+	}
+	return nil
+}
+
+func (h redisHistoricalTable) Get(entity string) (interface{}, error) {
+	return h.table.Get(entity)
+}
+
+func (h redisHistoricalTable) BatchGet(entities []string) (map[string]interface{}, error) {
+	return h.table.BatchGet(entities)
+}
+
+func (h redisHistoricalTable) GetAsOf(entity string, ts time.Time) (interface{}, error) {
+	cmd := h.table.client.B().
+		Zrevrangebyscore().
+		Key(h.table.key.historyKey(entity)).
+		Max(strconv.FormatInt(ts.UnixMicro(), 10)).
+		Min("-inf").
+		Limit(0, 1).
+		Build()
+	versions, err := h.table.client.Do(context.TODO(), cmd).AsStrSlice()
+	if err != nil {
+		return nil, fmt.Errorf("get %s as of %s: %w", entity, ts, err)
+	}
+	if len(versions) == 0 {
+		return nil, &EntityNotFound{entity}
+	}
+	_, serialized, found := strings.Cut(versions[0], ":")
+	if !found {
+		return nil, fmt.Errorf("malformed version %q of %s", versions[0], entity)
+	}
+	return h.table.parseValue(serialized)
+}
+
+// Removes the versions recorded at ARGV[2] and makes the newest remaining
+// version the current value, or removes the entity if there's none left.
+var redisDeleteVersionsAtScript = rueidis.NewLuaScript(`
+redis.call("ZREMRANGEBYSCORE", KEYS[2], ARGV[2], ARGV[2])
+local newest = redis.call("ZREVRANGE", KEYS[2], 0, 0)
+if #newest == 0 then
+	redis.call("HDEL", KEYS[1], ARGV[1])
+else
+	local sep = string.find(newest[1], ":", 1, true)
+	redis.call("HSET", KEYS[1], ARGV[1], string.sub(newest[1], sep + 1))
+end
+return 1
+`)
+
+func (h redisHistoricalTable) DeleteVersionsAt(entity string, ts time.Time) error {
+	keys := []string{h.table.key.String(), h.table.key.historyKey(entity)}
+	args := []string{entity, strconv.FormatInt(ts.UnixMicro(), 10)}
+	if err := redisDeleteVersionsAtScript.Exec(context.TODO(), h.table.client, keys, args).Error(); err != nil {
+		return fmt.Errorf("delete versions of %s at %s: %w", entity, ts, err)
+	}
+	return nil
+}
+
+// Truncate removes the history of every entity along with its latest value,
+// so the table can't be read as of any time until it's written again.
+func (h redisHistoricalTable) Truncate() error {
+	cmd := h.table.client.B().
+		Hkeys().
+		Key(h.table.key.String()).
+		Build()
+	entities, err := h.table.client.Do(context.TODO(), cmd).AsStrSlice()
+	if err != nil {
+		return fmt.Errorf("list entities of %s: %w", h.table.key.String(), err)
+	}
+	for _, entity := range entities {
+		cmd := h.table.client.B().
+			Del().
+			Key(h.table.key.historyKey(entity)).
+			Build()
+		if err := h.table.client.Do(context.TODO(), cmd).Error(); err != nil {
+			return fmt.Errorf("truncate history of %s: %w", entity, err)
+		}
+	}
+	return h.table.Truncate()
+}
+
 type redisOnlineIndex struct {
 	client    rueidis.Client
 	key       redisIndexKey
@@ -342,6 +805,20 @@ func (k *redisIndexKey) deserialize(key []byte) error {
 	return json.Unmarshal(key, &k)
 }
 
+// entityPattern returns a SCAN pattern that matches the key of every entity of
+// the feature variant and nothing else.
+func (k redisIndexKey) entityPattern() (string, error) {
+	serialized, err := k.serialize("")
+	if err != nil {
+		return "", err
+	}
+	// Drop the closing quote and brace of the empty entity so any entity matches
+	prefix := strings.TrimSuffix(string(serialized), `"}`)
+	return redisGlobEscaper.Replace(prefix) + `*"}`, nil
+}
+
+var redisGlobEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
 func (k redisIndexKey) getVectorField() string {
 	name_variant := fmt.Sprintf("%s_%s", k.Feature, k.Variant)
 	// RawStdEncoding is necessary given the padding character is considered
@@ -393,6 +870,73 @@ func (table redisOnlineIndex) Get(entity string) (interface{}, error) {
 	return rueidis.ToVector32(val), nil
 }
 
+// BatchGet pipelines a read of each entity's vector, since every entity of an
+// index is stored under its own key.
+func (table redisOnlineIndex) BatchGet(entities []string) (map[string]interface{}, error) {
+	cmds := make([]rueidis.Completed, len(entities))
+	for i, entity := range entities {
+		serializedKey, err := table.key.serialize(entity)
+		if err != nil {
+			return nil, err
+		}
+		cmds[i] = table.client.B().
+			Hget().
+			Key(string(serializedKey)).
+			Field(table.key.getVectorField()).
+			Build()
+	}
+	values := make(map[string]interface{}, len(entities))
+	if len(cmds) == 0 {
+		return values, nil
+	}
+	for i, resp := range table.client.DoMulti(context.TODO(), cmds...) {
+		val, err := resp.ToString()
+		if rueidis.IsRedisNil(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get %s: %w", entities[i], err)
+		}
+		values[entities[i]] = rueidis.ToVector32(val)
+	}
+	return values, nil
+}
+
+// Each entity of an index is stored under its own key, so truncating scans for
+// the keys of this feature variant and deletes them a page at a time.
+func (table redisOnlineIndex) Truncate() error {
+	pattern, err := table.key.entityPattern()
+	if err != nil {
+		return err
+	}
+	cursor := uint64(0)
+	for {
+		scanCmd := table.client.B().
+			Scan().
+			Cursor(cursor).
+			Match(pattern).
+			Count(1000).
+			Build()
+		entry, err := table.client.Do(context.TODO(), scanCmd).AsScanEntry()
+		if err != nil {
+			return fmt.Errorf("scan index keys: %w", err)
+		}
+		if len(entry.Elements) > 0 {
+			delCmd := table.client.B().
+				Del().
+				Key(entry.Elements...).
+				Build()
+			if err := table.client.Do(context.TODO(), delCmd).Error(); err != nil {
+				return fmt.Errorf("delete index keys: %w", err)
+			}
+		}
+		if entry.Cursor == 0 {
+			return nil
+		}
+		cursor = entry.Cursor
+	}
+}
+
 func (table redisOnlineIndex) Nearest(feature, variant string, vector []float32, k int32) ([]string, error) {
 	cmd, err := table.createNearestCmd(vector, k)
 	if err != nil {
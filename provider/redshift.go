@@ -66,6 +66,7 @@ func (q redshiftSQLQueries) registerResources(db *sql.DB, tableName string, sche
 		query = fmt.Sprintf("CREATE VIEW %s AS SELECT %s as entity, %s as value, to_timestamp('%s', 'YYYY-DD-MM HH24:MI:SS +0000 UTC')::TIMESTAMPTZ as ts FROM %s", sanitize(tableName),
 			sanitize(schema.Entity), sanitize(schema.Value), time.UnixMilli(0).UTC(), sanitize(schema.SourceTable))
 	}
+	query += filterClause(schema.Filter)
 	if _, err := db.Exec(query); err != nil {
 		return err
 	}
@@ -105,6 +106,17 @@ func (q redshiftSQLQueries) materializationUpdate(db *sql.DB, tableName string,
 	return err
 }
 
+// tableSize rounds up to the 1 MB blocks Redshift stores tables in
+func (q redshiftSQLQueries) tableSize(db *sql.DB, tableName string) (int64, error) {
+	bind := q.newVariableBindingIterator()
+	qry := fmt.Sprintf("SELECT size FROM svv_table_info WHERE \"table\" = %s", bind.Next())
+	mb, err := scanTableSize(db.QueryRow(qry, tableName), tableName)
+	if err != nil {
+		return 0, err
+	}
+	return mb * 1024 * 1024, nil
+}
+
 func (q redshiftSQLQueries) materializationDrop(tableName string) string {
 	return fmt.Sprintf("DROP TABLE %s", sanitize(tableName))
 }
@@ -161,8 +173,10 @@ func (q redshiftSQLQueries) trainingSetQuery(store *sqlOfflineStore, def Trainin
 		tableJoinAlias := fmt.Sprintf("t%d", i+1)
 		selectColumns = append(selectColumns, fmt.Sprintf("%s_rnk", tableJoinAlias))
 		columns = append(columns, santizedName)
-		query = fmt.Sprintf("%s LEFT OUTER JOIN (SELECT entity, value AS %s, ts, RANK() OVER (ORDER BY ts DESC) AS %s_rnk FROM %s ORDER BY ts desc) AS %s ON (%s.entity=t0.entity AND %s.ts <= t0.ts)",
-			query, santizedName, tableJoinAlias, santizedName, tableJoinAlias, tableJoinAlias, tableJoinAlias)
+		// Ranks count up from the feature's newest value, so the joined row with
+		// the lowest rank has the latest value the join strategy allows
+		query = fmt.Sprintf("%s %s (SELECT entity, value AS %s, ts, RANK() OVER (ORDER BY ts DESC) AS %s_rnk FROM %s ORDER BY ts desc) AS %s ON (%s.entity=t0.entity%s)",
+			query, def.JoinPolicy.featureJoin(), santizedName, tableJoinAlias, santizedName, tableJoinAlias, tableJoinAlias, def.JoinStrategy.featureTimeCondition(tableJoinAlias+".ts", "t0.ts"))
 		if i == len(def.Features)-1 {
 			query = fmt.Sprintf("%s )) WHERE rn=1", query)
 		}
@@ -173,7 +187,7 @@ func (q redshiftSQLQueries) trainingSetQuery(store *sqlOfflineStore, def Trainin
 	if !isUpdate {
 		fullQuery := fmt.Sprintf(
 			"CREATE TABLE %s AS (SELECT %s, label FROM ("+
-				"SELECT *, row_number() over(PARTITION BY e, label, time ORDER BY \"time\", %s DESC) AS rn FROM ( "+
+				"SELECT *, row_number() over(PARTITION BY e, label, time ORDER BY \"time\", %s) AS rn FROM ( "+
 				"SELECT t0.entity AS e, t0.value AS label, t0.ts AS time, %s, %s FROM %s AS t0 %s )",
 			sanitize(tableName), columnStr, selectColumnStr, columnStr, selectColumnStr, sanitize(labelName), query)
 		if _, err := store.db.Exec(fullQuery); err != nil {
@@ -183,7 +197,7 @@ func (q redshiftSQLQueries) trainingSetQuery(store *sqlOfflineStore, def Trainin
 		tempTable := sanitize(fmt.Sprintf("tmp_%s", tableName))
 		fullQuery := fmt.Sprintf(
 			"CREATE TABLE %s AS (SELECT %s, label FROM ("+
-				"SELECT *, row_number() over(PARTITION BY e, label, time ORDER BY \"time\", %s desc) AS rn FROM ( "+
+				"SELECT *, row_number() over(PARTITION BY e, label, time ORDER BY \"time\", %s) AS rn FROM ( "+
 				"SELECT t0.entity AS e, t0.value AS label, t0.ts AS time, %s, %s FROM %s AS t0 %s )",
 			tempTable, columnStr, selectColumnStr, columnStr, selectColumnStr, sanitize(labelName), query)
 
@@ -20,10 +20,18 @@ AZURE = "azure"
 GCS = "gcs"
 S3 = "s3"
 
+# Output Formats
+PARQUET = "parquet"
+CSV = "csv"
+
+# Parquet Compression Codecs
+PARQUET_COMPRESSIONS = ("snappy", "zstd", "gzip", "none")
+
 real_path = os.path.realpath(__file__)
 dir_path = os.path.dirname(real_path)
 
-LOCAL_DATA_PATH = f"{dir_path}/.featureform/data"
+# The local executor sets this to a directory of its own for each run
+LOCAL_DATA_PATH = os.getenv("LOCAL_DATA_PATH", f"{dir_path}/.featureform/data")
 
 
 class BlobStore:
@@ -227,6 +235,8 @@ def main(args):
             args.transformation,
             args.sources,
             blob_store,
+            args.output_format,
+            args.parquet_options,
         )
     elif args.transformation_type == "df":
         print(f"starting execution for DF Transformation in {args.mode} mode")
@@ -236,11 +246,21 @@ def main(args):
             args.transformation,
             args.sources,
             blob_store,
+            args.output_format,
+            args.parquet_options,
         )
     return output_location
 
 
-def execute_sql_job(mode, output_uri, transformation, source_list, blob_store):
+def execute_sql_job(
+    mode,
+    output_uri,
+    transformation,
+    source_list,
+    blob_store,
+    output_format=PARQUET,
+    parquet_options=None,
+):
     """
     Executes the SQL Queries:
 
@@ -250,6 +270,8 @@ def execute_sql_job(mode, output_uri, transformation, source_list, blob_store):
         transformation: string (eg. "SELECT * FROM source_0)
         source_list:    List(string) (a list of input sources)
         blob_store:     BlobStore (blob store object)
+        output_format:  string ("parquet", "csv")
+        parquet_options: dict (keyword arguments parquet outputs are written with)
 
     Returns:
         output_uri_with_timestamp: string (output path of blob storage)
@@ -275,14 +297,19 @@ def execute_sql_job(mode, output_uri, transformation, source_list, blob_store):
         output_dataframe = set_bool_columns(transformation_df)
 
         dt = datetime.now()
-        output_uri_with_timestamp = f"{output_uri}/{dt}.parquet"
+        output_uri_with_timestamp = f"{output_uri}/{dt}.{output_format}"
 
         if blob_store.type == LOCAL:
             os.makedirs(output_uri, exist_ok=True)
-            output_dataframe.to_parquet(output_uri_with_timestamp)
+            write_output(
+                output_dataframe,
+                output_uri_with_timestamp,
+                output_format,
+                parquet_options,
+            )
         else:
-            local_output = f"{LOCAL_DATA_PATH}/output.parquet"
-            output_dataframe.to_parquet(local_output)
+            local_output = f"{LOCAL_DATA_PATH}/output.{output_format}"
+            write_output(output_dataframe, local_output, output_format, parquet_options)
             # upload blob to blob store
             output_uri = blob_store.upload(local_output, output_uri_with_timestamp)
 
@@ -292,7 +319,15 @@ def execute_sql_job(mode, output_uri, transformation, source_list, blob_store):
         raise e
 
 
-def execute_df_job(mode, output_uri, code, sources, blob_store):
+def execute_df_job(
+    mode,
+    output_uri,
+    code,
+    sources,
+    blob_store,
+    output_format=PARQUET,
+    parquet_options=None,
+):
     """
     Executes the DF transformation:
 
@@ -302,6 +337,8 @@ def execute_df_job(mode, output_uri, code, sources, blob_store):
         code:             code (python code)
         sources:          List(string) (a list of input sources)
         blob_store:       BlobStore (blob store object)
+        output_format:    string ("parquet", "csv")
+        parquet_options:  dict (keyword arguments parquet outputs are written with)
 
     Returns:
         output_uri_with_timestamp: string (output s3 path)
@@ -340,15 +377,17 @@ def execute_df_job(mode, output_uri, code, sources, blob_store):
         output_df = pd.DataFrame(func(*func_parameters))
 
         dt = datetime.now()
-        output_uri_with_timestamp = f"{output_uri}/{dt}.parquet"
+        output_uri_with_timestamp = f"{output_uri}/{dt}.{output_format}"
 
         print(f"storing output dataframe to {output_uri_with_timestamp}")
         if blob_store.type == LOCAL:
             os.makedirs(output_uri, exist_ok=True)
-            output_df.to_parquet(output_uri_with_timestamp)
+            write_output(
+                output_df, output_uri_with_timestamp, output_format, parquet_options
+            )
         else:
-            local_output = f"{LOCAL_DATA_PATH}/output.parquet"
-            output_df.to_parquet(local_output)
+            local_output = f"{LOCAL_DATA_PATH}/output.{output_format}"
+            write_output(output_df, local_output, output_format, parquet_options)
 
             # upload blob to blob store
             output_uri = blob_store.upload(local_output, output_uri_with_timestamp)
@@ -359,6 +398,48 @@ def execute_df_job(mode, output_uri, code, sources, blob_store):
         raise e
 
 
+def write_output(df, path, output_format, parquet_options=None):
+    """
+    Writes the transformation's output dataframe to a local file.
+
+    Parameters:
+        df:              pd.DataFrame (output of the transformation)
+        path:            string (local path to write to)
+        output_format:   string ("parquet", "csv")
+        parquet_options: dict (keyword arguments parquet outputs are written with)
+
+    Returns:
+        None
+    """
+    if output_format == CSV:
+        df.to_csv(path, index=False)
+    else:
+        df.to_parquet(path, **(parquet_options or {}))
+
+
+def get_parquet_options():
+    """
+    Gets the compression codec and row group size parquet outputs are written
+    with from environment variables.
+
+    Parameters:
+        None
+
+    Returns:
+        dict
+    """
+    compression = os.getenv("PARQUET_COMPRESSION", "snappy")
+    if compression not in PARQUET_COMPRESSIONS:
+        raise ValueError(
+            f"the {compression} parquet compression is not supported. supported codecs are {', '.join(PARQUET_COMPRESSIONS)}."
+        )
+    options = {"compression": None if compression == "none" else compression}
+    row_group_size = os.getenv("PARQUET_ROW_GROUP_SIZE")
+    if row_group_size:
+        options["row_group_size"] = int(row_group_size)
+    return options
+
+
 def get_code_from_file(mode, file_path):
     """
     Reads the code from a pkl file into a python code object.
@@ -431,6 +512,7 @@ def get_args():
     sources = os.getenv("SOURCES", "").split(",")
     transformation_type = os.getenv("TRANSFORMATION_TYPE")
     transformation = os.getenv("TRANSFORMATION")
+    output_format = os.getenv("OUTPUT_FORMAT", PARQUET)
 
     blob_credentials = get_blob_credentials(mode, blob_store_type)
 
@@ -439,6 +521,8 @@ def get_args():
         transformation_type=transformation_type,
         transformation=transformation,
         output_uri=output_uri,
+        output_format=output_format,
+        parquet_options=get_parquet_options(),
         sources=sources,
         blob_credentials=blob_credentials,
     )
@@ -474,6 +558,14 @@ def validate_args(args):
             f"the {args.transformation_type} transformation type is not supported. supported types are 'sql', and 'df'."
         )
 
+    if args.output_format not in (
+        PARQUET,
+        CSV,
+    ):
+        raise ValueError(
+            f"the {args.output_format} output format is not supported. supported formats are '{PARQUET}' and '{CSV}'."
+        )
+
     if not (args.output_uri and args.sources != [""] and args.transformation != ""):
         raise Exception(
             "the environment variables are not set properly; output_uri, sources, and transformation are not set correctly."
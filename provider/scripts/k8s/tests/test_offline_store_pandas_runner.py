@@ -17,6 +17,8 @@ from offline_store_pandas_runner import (
     execute_df_job,
     execute_sql_job,
     get_blob_credentials,
+    get_parquet_options,
+    write_output,
 )
 
 real_path = os.path.realpath(__file__)
@@ -82,6 +84,29 @@ def test_execute_sql_job(variables, expected_output, request):
     set_environment_variables(env, delete=True)
 
 
+def test_execute_sql_job_csv_output(local_variables_success):
+    set_environment_variables(local_variables_success)
+    args = get_args()
+    blob_store = get_blob_store(args.blob_credentials)
+
+    output_file = execute_sql_job(
+        args.mode,
+        args.output_uri,
+        args.transformation,
+        args.sources,
+        blob_store,
+        "csv",
+    )
+
+    assert output_file.endswith(".csv")
+    expected_df = pandas.read_csv(args.sources[0])
+    output_df = pandas.read_csv(output_file)
+    assert list(output_df.columns) == list(expected_df.columns)
+    pandas.testing.assert_frame_equal(expected_df, output_df)
+
+    set_environment_variables(local_variables_success, delete=True)
+
+
 @pytest.mark.parametrize(
     "variables,expected_output",
     [
@@ -120,6 +145,42 @@ def test_execute_df_job(df_transformation, variables, expected_output, request):
     assert len(expected_df) == len(output_df)
 
 
+@pytest.mark.parametrize(
+    "compression,row_group_size,expected_codec",
+    [
+        (None, None, "SNAPPY"),
+        ("zstd", "2", "ZSTD"),
+        ("none", None, "UNCOMPRESSED"),
+        pytest.param("lzo", None, None, marks=pytest.mark.xfail),
+    ],
+)
+def test_write_output_parquet_options(
+    compression, row_group_size, expected_codec, tmp_path
+):
+    import pyarrow.parquet as pq
+
+    env = {}
+    if compression:
+        env["PARQUET_COMPRESSION"] = compression
+    if row_group_size:
+        env["PARQUET_ROW_GROUP_SIZE"] = row_group_size
+    set_environment_variables(env)
+    try:
+        options = get_parquet_options()
+    finally:
+        set_environment_variables(env, delete=True)
+
+    df = pandas.DataFrame({"entity": ["a", "b", "c"], "value": [1, 2, 3]})
+    path = f"{tmp_path}/output.parquet"
+    write_output(df, path, "parquet", options)
+
+    metadata = pq.ParquetFile(path).metadata
+    assert metadata.row_group(0).column(0).compression == expected_codec
+    if row_group_size:
+        assert metadata.num_row_groups == 2
+    pandas.testing.assert_frame_equal(df, pandas.read_parquet(path))
+
+
 @pytest.mark.parametrize(
     "variables",
     [
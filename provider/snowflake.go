@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 
@@ -54,3 +55,9 @@ func snowflakeOfflineStoreFactory(config pc.SerializedConfig) (Provider, error)
 func (q snowflakeSQLQueries) materializationDrop(tableName string) string {
 	return fmt.Sprintf("DROP TABLE %s", sanitize(tableName))
 }
+
+// tableSize doesn't include data kept for Time Travel and Fail-safe
+func (q snowflakeSQLQueries) tableSize(db *sql.DB, tableName string) (int64, error) {
+	qry := "SELECT BYTES FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = CURRENT_SCHEMA() AND TABLE_NAME = ?"
+	return scanTableSize(db.QueryRow(qry, tableName), tableName)
+}
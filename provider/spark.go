@@ -546,6 +546,10 @@ type defaultPythonOfflineQueries struct{}
 
 func (q defaultPythonOfflineQueries) materializationCreate(schema ResourceSchema) string {
 	timestampColumn := schema.TS
+	source := "source_0"
+	if schema.Filter != "" {
+		source = fmt.Sprintf("(SELECT * FROM source_0%s) AS filtered_source", filterClause(schema.Filter))
+	}
 	if schema.TS == "" {
 		// If the schema lacks a timestamp, we assume each entity only has single entry. The
 		// below query enforces this assumption by:
@@ -559,7 +563,7 @@ func (q defaultPythonOfflineQueries) materializationCreate(schema ResourceSchema
 					-- 0 AS ts, -- TODO: determine if we even need to add this zeroed-out timestamp column
 					ROW_NUMBER() over (PARTITION BY %s ORDER BY (SELECT NULL)) AS row_number
 				FROM
-					source_0
+					%s
 			),
 			max_row_per_entity AS (
 				SELECT 
@@ -579,14 +583,14 @@ func (q defaultPythonOfflineQueries) materializationCreate(schema ResourceSchema
 			JOIN ordered_rows ord
 				ON ord.entity = maxr.entity AND ord.row_number = maxr.max_row
 			ORDER BY
-				maxr.max_row DESC`, schema.Entity, schema.Value, schema.Entity)
+				maxr.max_row DESC`, schema.Entity, schema.Value, schema.Entity, source)
 	}
 	return fmt.Sprintf(
 		"SELECT entity, value, ts, ROW_NUMBER() over (ORDER BY (SELECT NULL)) AS row_number, rn2 FROM "+
 			"(SELECT entity, value, ts, ROW_NUMBER() OVER (PARTITION BY entity ORDER BY ts DESC) AS rn2 FROM "+
 			"(SELECT entity, value, ts, rn FROM (SELECT %s AS entity, %s AS value, %s AS ts, "+
 			"ROW_NUMBER() OVER (ORDER BY (SELECT NULL)) AS rn FROM %s) t ORDER BY rn DESC) t2 ) t3 WHERE rn2=1",
-		schema.Entity, schema.Value, timestampColumn, "source_0")
+		schema.Entity, schema.Value, timestampColumn, source)
 }
 
 // Spark SQL _seems_ to have some issues with double quotes in column names based on troubleshooting
@@ -609,7 +613,7 @@ func (q defaultPythonOfflineQueries) trainingSetCreate(def TrainingSetDef, featu
 		} else {
 			featureWindowQuery = fmt.Sprintf("SELECT * FROM (SELECT %s as t%d_entity, %s as %s, %s as t%d_ts FROM source_%d) ORDER BY t%d_ts ASC", featureSchemas[i].Entity, i+1, featureSchemas[i].Value, featureColumnName, featureSchemas[i].TS, i+1, i+1, i+1)
 		}
-		featureJoinQuery := fmt.Sprintf("LEFT OUTER JOIN (%s) t%d ON (t%d_entity = entity AND t%d_ts <= label_ts)", featureWindowQuery, i+1, i+1, i+1)
+		featureJoinQuery := fmt.Sprintf("%s (%s) t%d ON (t%d_entity = entity%s)", def.JoinPolicy.featureJoin(), featureWindowQuery, i+1, i+1, def.JoinStrategy.featureTimeCondition(fmt.Sprintf("t%d_ts", i+1), "label_ts"))
 		joinQueries = append(joinQueries, featureJoinQuery)
 		feature_timestamps = append(feature_timestamps, fmt.Sprintf("t%d_ts", i+1))
 	}
@@ -659,9 +663,16 @@ type SparkOfflineStore struct {
 	Store    SparkFileStore
 	Logger   *zap.SugaredLogger
 	query    *defaultPythonOfflineQueries
+	// Names the directories table data is written to. Defaults to the time
+	// each is created.
+	names NameGenerator
 	BaseProvider
 }
 
+func (spark *SparkOfflineStore) SetNameGenerator(names NameGenerator) {
+	spark.names = names
+}
+
 func (store *SparkOfflineStore) AsOfflineStore() (OfflineStore, error) {
 	return store, nil
 }
@@ -724,11 +735,28 @@ type SparkExecutor interface {
 	GetDFArgs(outputURI filestore.Filepath, code string, sources []string, store SparkFileStore) ([]string, error)
 }
 
+// emrStepAPI is the part of the EMR client EMRExecutor runs steps with
+type emrStepAPI interface {
+	AddJobFlowSteps(ctx context.Context, params *emr.AddJobFlowStepsInput, optFns ...func(*emr.Options)) (*emr.AddJobFlowStepsOutput, error)
+	DescribeStep(ctx context.Context, params *emr.DescribeStepInput, optFns ...func(*emr.Options)) (*emr.DescribeStepOutput, error)
+}
+
+// How long EMRExecutor waits for a step to finish, and for the log file of a
+// failed step to be written
+const (
+	emrStepTimeout    = 3 * time.Hour
+	emrLogFileTimeout = 5 * time.Minute
+)
+
+const defaultEMRPollInterval = 10 * time.Second
+
 type EMRExecutor struct {
-	client       *emr.Client
+	client       emrStepAPI
 	clusterName  string
 	logger       *zap.SugaredLogger
 	logFileStore *FileStore
+	// How often the status of a running step is checked
+	pollInterval time.Duration
 }
 
 func (e EMRExecutor) InitializeExecutor(store SparkFileStore) error {
@@ -999,6 +1027,7 @@ func NewEMRExecutor(emrConfig pc.EMRConfig, logger *zap.SugaredLogger) (SparkExe
 		logger:       logger,
 		clusterName:  emrConfig.ClusterName,
 		logFileStore: logFileStore,
+		pollInterval: defaultEMRPollInterval,
 	}
 	return &emrExecutor, nil
 }
@@ -1019,69 +1048,83 @@ func (e *EMRExecutor) RunSparkJob(args []string, store SparkFileStore) error {
 	}
 	resp, err := e.client.AddJobFlowSteps(context.TODO(), params)
 	if err != nil {
-		e.logger.Errorw("Could not add job flow steps to EMR cluster", err)
+		e.logger.Errorw("Could not add job flow steps to EMR cluster", "error", err)
 		return err
 	}
+	if len(resp.StepIds) == 0 {
+		return fmt.Errorf("EMR cluster '%s' did not return the id of the added step", e.clusterName)
+	}
 	stepId := resp.StepIds[0]
-	var waitDuration time.Duration = time.Hour * 3
-	e.logger.Debugw("Waiting for EMR job to complete")
-	stepCompleteWaiter := emr.NewStepCompleteWaiter(e.client)
-	_, err = stepCompleteWaiter.WaitForOutput(context.TODO(), &emr.DescribeStepInput{
-		ClusterId: aws.String(e.clusterName),
-		StepId:    aws.String(stepId),
-	}, waitDuration)
-	if err != nil {
-		errorMessage, getErr := e.getStepErrorMessage(e.clusterName, stepId)
-		if getErr != nil {
-			e.logger.Infof("could not get error message for EMR step '%s': %s", stepId, getErr)
-		}
-		if errorMessage != "" {
-			return fmt.Errorf("the EMR step '%s' failed: %s", stepId, errorMessage)
-		}
-
-		e.logger.Errorf("Failure waiting for completion of EMR cluster: %s", err)
-		return fmt.Errorf("failure waiting for completion of EMR cluster: %s", err)
+	e.logger.Debugw("Waiting for EMR job to complete", "step", stepId)
+	step, err := e.waitForStep(stepId)
+	if err != nil {
+		e.logger.Errorw("Failure waiting for completion of EMR step", "step", stepId, "error", err)
+		return err
 	}
-	return nil
-}
-
-func (e *EMRExecutor) getStepErrorMessage(clusterId string, stepId string) (string, error) {
-	if e.logFileStore == nil {
-		return "", fmt.Errorf("cannot get error message for EMR step '%s' because the log file store is not set", stepId)
+	if step.Status.State == emrTypes.StepStateCompleted {
+		return nil
 	}
-
-	stepResults, err := e.client.DescribeStep(context.TODO(), &emr.DescribeStepInput{
-		ClusterId: aws.String(clusterId),
-		StepId:    aws.String(stepId),
-	})
+	errorMessage, err := e.getStepErrorMessage(step)
 	if err != nil {
-		return "", fmt.Errorf("could not get information on EMR step '%s'", stepId)
+		e.logger.Infof("could not get error message for EMR step '%s': %s", stepId, err)
+	}
+	if errorMessage == "" {
+		return fmt.Errorf("the EMR step '%s' ended with state %s", stepId, step.Status.State)
 	}
+	return fmt.Errorf("the EMR step '%s' ended with state %s: %s", stepId, step.Status.State, errorMessage)
+}
 
-	if stepResults.Step.Status.State == "FAILED" {
-		var errorMsg string
-		// check if there are any errors
-		if stepResults.Step.Status.FailureDetails.Message != nil {
-			// get the error message
-			errorMsg = *stepResults.Step.Status.FailureDetails.Message
+// waitForStep polls a step until it stops running, and returns it
+func (e *EMRExecutor) waitForStep(stepId string) (*emrTypes.Step, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), emrStepTimeout)
+	defer cancel()
+	ticker := time.NewTicker(e.pollInterval)
+	defer ticker.Stop()
+	for {
+		resp, err := e.client.DescribeStep(ctx, &emr.DescribeStepInput{
+			ClusterId: aws.String(e.clusterName),
+			StepId:    aws.String(stepId),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not get status of EMR step '%s': %w", stepId, err)
 		}
-		if errorMsg != "" {
-			return errorMsg, nil
+		if resp.Step == nil || resp.Step.Status == nil {
+			return nil, fmt.Errorf("EMR step '%s' has no status", stepId)
 		}
-
-		if stepResults.Step.Status.FailureDetails.LogFile != nil {
-			logFile := *stepResults.Step.Status.FailureDetails.LogFile
-
-			errorMessage, err := e.getLogFileMessage(logFile)
-			if err != nil {
-				return "", fmt.Errorf("could not get error message from log file '%s': %v", logFile, err)
-			}
-
-			return errorMessage, nil
+		switch resp.Step.Status.State {
+		case emrTypes.StepStateCompleted, emrTypes.StepStateFailed, emrTypes.StepStateCancelled, emrTypes.StepStateInterrupted:
+			return resp.Step, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("EMR step '%s' did not finish within %s", stepId, emrStepTimeout)
+		case <-ticker.C:
 		}
 	}
+}
 
-	return "", nil
+// getStepErrorMessage returns why a step failed, from its failure details if
+// EMR found the cause, or otherwise from the end of its log file
+func (e *EMRExecutor) getStepErrorMessage(step *emrTypes.Step) (string, error) {
+	details := step.Status.FailureDetails
+	if details == nil {
+		return "", nil
+	}
+	if details.Message != nil && *details.Message != "" {
+		return *details.Message, nil
+	}
+	if details.LogFile == nil {
+		return "", nil
+	}
+	if e.logFileStore == nil {
+		return "", fmt.Errorf("cannot read the log file of EMR step '%s' because the log file store is not set", aws.ToString(step.Id))
+	}
+	logFile := *details.LogFile
+	errorMessage, err := e.getLogFileMessage(logFile)
+	if err != nil {
+		return "", fmt.Errorf("could not get error message from log file '%s': %v", logFile, err)
+	}
+	return errorMessage, nil
 }
 
 func (e *EMRExecutor) getLogFileMessage(logFile string) (string, error) {
@@ -1109,8 +1152,10 @@ func (e *EMRExecutor) getLogFileMessage(logFile string) (string, error) {
 	return errorMessage, nil
 }
 
+// waitForLogFile waits for EMR to copy a step's log file to S3, which happens
+// a few minutes after the step ends
 func (e *EMRExecutor) waitForLogFile(logFile filestore.Filepath) error {
-	// wait until log file exists
+	deadline := time.Now().Add(emrLogFileTimeout)
 	for {
 		fileExists, err := (*e.logFileStore).Exists(logFile)
 		if err != nil {
@@ -1120,6 +1165,9 @@ func (e *EMRExecutor) waitForLogFile(logFile filestore.Filepath) error {
 		if fileExists {
 			return nil
 		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("log file '%s' was not written within %s", logFile.ToURI(), emrLogFileTimeout)
+		}
 
 		time.Sleep(2 * time.Second)
 	}
@@ -1234,6 +1282,14 @@ func (spark *SparkOfflineStore) RegisterPrimaryFromSourceTable(id ResourceID, so
 	return blobRegisterPrimary(id, sourcePath, spark.Logger, spark.Store)
 }
 
+func (spark *SparkOfflineStore) RegisterPrimaryFromNewestFiles(id ResourceID, dir string, n int) (PrimaryTable, error) {
+	return blobRegisterPrimaryFromNewestFiles(id, dir, n, spark.Logger, spark.Store)
+}
+
+func (spark *SparkOfflineStore) SourceTableColumns(sourcePath string) ([]string, error) {
+	return blobSourceTableColumns(sourcePath, spark.Store)
+}
+
 func (spark *SparkOfflineStore) pysparkArgs(destinationURI string, templatedQuery string, sourceList []string, jobType JobType) *[]string {
 	args := []string{}
 	return &args
@@ -1248,6 +1304,11 @@ func (spark *SparkOfflineStore) CreateTransformation(config TransformationConfig
 }
 
 func (spark *SparkOfflineStore) transformation(config TransformationConfig, isUpdate bool) error {
+	if format, err := config.outputFormat(); err != nil {
+		return err
+	} else if format != filestore.Parquet {
+		return fmt.Errorf("spark transformations can only be output as parquet")
+	}
 	if config.Type == SQLTransformation {
 		return spark.sqlTransformation(config, isUpdate)
 	} else if config.Type == DFTransformation {
@@ -1579,6 +1640,11 @@ func (spark *SparkOfflineStore) GetTransformationTable(id ResourceID) (Transform
 	if err != nil {
 		return nil, fmt.Errorf("could not create file path due to error %w (store type: %s; path: %s)", err, spark.Store.FilestoreType(), id.ToFilestorePath())
 	}
+	if exists, err := fileStoreTransformationExists(spark.Store, transformationPath); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, &TableNotFound{id.Name, id.Variant}
+	}
 	spark.Logger.Debugw("Retrieved transformation source", "id", id, "filePath", transformationPath.ToURI())
 	return &FileStorePrimaryTable{spark.Store, transformationPath, TableSchema{}, true, id}, nil
 }
@@ -1605,7 +1671,7 @@ func (spark *SparkOfflineStore) CreatePrimaryTable(id ResourceID, schema TableSc
 		return nil, &TableAlreadyExists{id.Name, id.Variant}
 	}
 	// Create a URL in the same directory as the primary table that follows the naming convention <VARIANT>_src.parquet
-	schema.SourceTable = fmt.Sprintf("%s/%s/src.parquet", primaryTableFilepath.ToURI(), time.Now().Format("2006-01-02-15-04-05-999999"))
+	schema.SourceTable = fmt.Sprintf("%s/%s/src.parquet", primaryTableFilepath.ToURI(), namesOrDefault(spark.names, dateTimeNameGenerator{}).NewName())
 	data, err := schema.Serialize()
 	if err != nil {
 		return nil, err
@@ -1634,51 +1700,7 @@ func (spark *SparkOfflineStore) GetPrimaryTable(id ResourceID) (PrimaryTable, er
 // One option is the keep with the above pattern by populating "SourceTable" with the path to a source table contained in a subdirectory of
 // the resource directory in the pattern Spark uses (i.e. /featureform/Feature/<NAME DIR>/<VARIANT DIR>/<DATETIME DIR>/src.parquet).
 func (spark *SparkOfflineStore) CreateResourceTable(id ResourceID, schema TableSchema) (OfflineTable, error) {
-	if err := id.check(Feature, Label); err != nil {
-		return nil, fmt.Errorf("ID check failed: %v", err)
-	}
-	resourceTableFilepath, err := spark.Store.CreateFilePath(id.ToFilestorePath())
-	if err != nil {
-		return nil, fmt.Errorf("could not create file path due to error %w (store type: %s; path: %s)", err, spark.Store.FilestoreType(), id.ToFilestorePath())
-	}
-	if exists, err := spark.Store.Exists(resourceTableFilepath); err != nil {
-		return nil, fmt.Errorf("could not check if table exists: %v", err)
-	} else if exists {
-		return nil, &TableAlreadyExists{id.Name, id.Variant}
-	}
-	table := BlobOfflineTable{
-		store: spark.Store,
-		schema: ResourceSchema{
-			// Create a URI in the same directory as the resource table that follows the naming convention <VARIANT>_src.parquet
-			SourceTable: fmt.Sprintf("%s/%s/src.parquet", resourceTableFilepath.ToURI(), time.Now().Format("2006-01-02-15-04-05-999999")),
-		},
-	}
-	for _, col := range schema.Columns {
-		switch col.Name {
-		case string(Entity):
-			table.schema.Entity = col.Name
-		case string(Value):
-			table.schema.Value = col.Name
-		case string(TS):
-			table.schema.TS = col.Name
-		default:
-			// TODO: verify the assumption that col.Name should be:
-			// * Entity ("entity")
-			// * Value ("value")
-			// * TS ("ts")
-			// makes sense in the context of the schema
-			return nil, fmt.Errorf("unexpected column name: %s", col.Name)
-		}
-	}
-	data, err := table.schema.Serialize()
-	if err != nil {
-		return nil, fmt.Errorf("could not serialize schema: %v", err)
-	}
-	err = spark.Store.Write(resourceTableFilepath, data)
-	if err != nil {
-		return nil, fmt.Errorf("could not write schema to file: %v", err)
-	}
-	return &table, nil
+	return fileStoreCreateResourceTable(id, schema, spark.Store, spark.names)
 }
 
 func (spark *SparkOfflineStore) GetResourceTable(id ResourceID) (OfflineTable, error) {
@@ -1770,7 +1792,7 @@ func blobSparkMaterialization(id ResourceID, spark *SparkOfflineStore, isUpdate
 		return nil, fmt.Errorf("materialization not found in directory: %s", destinationPath.ToURI())
 	}
 	spark.Logger.Debugw("Successfully created materialization", "id", id)
-	return &FileStoreMaterialization{materializationID, spark.Store}, nil
+	return &FileStoreMaterialization{id: materializationID, store: spark.Store}, nil
 }
 
 func (spark *SparkOfflineStore) CreateMaterialization(id ResourceID) (Materialization, error) {
@@ -1785,6 +1807,27 @@ func (spark *SparkOfflineStore) UpdateMaterialization(id ResourceID) (Materializ
 	return blobSparkMaterialization(id, spark, true)
 }
 
+func (spark *SparkOfflineStore) DeletePrimaryTable(id ResourceID) error {
+	if err := id.check(Primary); err != nil {
+		return fmt.Errorf("ID check failed: %w", err)
+	}
+	return fileStoreDeleteTable(id, spark.Store, spark.Logger)
+}
+
+func (spark *SparkOfflineStore) DeleteResourceTable(id ResourceID) error {
+	if err := id.check(Feature, Label); err != nil {
+		return fmt.Errorf("ID check failed: %w", err)
+	}
+	return fileStoreDeleteTable(id, spark.Store, spark.Logger)
+}
+
+func (spark *SparkOfflineStore) DeleteTransformationTable(id ResourceID) error {
+	if err := id.check(Transformation); err != nil {
+		return fmt.Errorf("ID check failed: %w", err)
+	}
+	return fileStoreDeleteTable(id, spark.Store, spark.Logger)
+}
+
 func (spark *SparkOfflineStore) DeleteMaterialization(id MaterializationID) error {
 	return fileStoreDeleteMaterialization(id, spark.Store, spark.Logger)
 }
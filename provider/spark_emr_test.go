@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/emr"
+	emrTypes "github.com/aws/aws-sdk-go-v2/service/emr/types"
+	"go.uber.org/zap/zaptest"
+)
+
+// fakeEMRSteps runs a single step, which goes through states each time it's
+// described
+type fakeEMRSteps struct {
+	states   []emrTypes.StepState
+	details  *emrTypes.FailureDetails
+	args     []string
+	describe int
+}
+
+func (f *fakeEMRSteps) AddJobFlowSteps(ctx context.Context, params *emr.AddJobFlowStepsInput, optFns ...func(*emr.Options)) (*emr.AddJobFlowStepsOutput, error) {
+	f.args = params.Steps[0].HadoopJarStep.Args
+	return &emr.AddJobFlowStepsOutput{StepIds: []string{"s-1"}}, nil
+}
+
+func (f *fakeEMRSteps) DescribeStep(ctx context.Context, params *emr.DescribeStepInput, optFns ...func(*emr.Options)) (*emr.DescribeStepOutput, error) {
+	if f.describe >= len(f.states) {
+		return nil, errors.New("step described after it finished")
+	}
+	state := f.states[f.describe]
+	f.describe++
+	status := &emrTypes.StepStatus{State: state}
+	if state == emrTypes.StepStateFailed {
+		status.FailureDetails = f.details
+	}
+	return &emr.DescribeStepOutput{Step: &emrTypes.Step{Id: params.StepId, Status: status}}, nil
+}
+
+func TestEMRExecutorRunSparkJob(t *testing.T) {
+	cases := map[string]struct {
+		states   []emrTypes.StepState
+		details  *emrTypes.FailureDetails
+		expected string
+	}{
+		"Completed": {
+			states: []emrTypes.StepState{emrTypes.StepStatePending, emrTypes.StepStateRunning, emrTypes.StepStateCompleted},
+		},
+		"Failed": {
+			states:   []emrTypes.StepState{emrTypes.StepStateRunning, emrTypes.StepStateFailed},
+			details:  &emrTypes.FailureDetails{Message: aws.String("Table or view not found: transactions")},
+			expected: "Table or view not found: transactions",
+		},
+		"FailedWithoutLogs": {
+			states:   []emrTypes.StepState{emrTypes.StepStateFailed},
+			details:  &emrTypes.FailureDetails{LogFile: aws.String("s3://logs/steps/s-1")},
+			expected: "ended with state FAILED",
+		},
+		"Cancelled": {
+			states:   []emrTypes.StepState{emrTypes.StepStateRunning, emrTypes.StepStateCancelled},
+			expected: "ended with state CANCELLED",
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			client := &fakeEMRSteps{states: c.states, details: c.details}
+			executor := EMRExecutor{
+				client:       client,
+				clusterName:  "j-cluster",
+				logger:       zaptest.NewLogger(t).Sugar(),
+				pollInterval: time.Millisecond,
+			}
+			err := executor.RunSparkJob([]string{"spark-submit", "script.py"}, nil)
+			if c.expected == "" {
+				if err != nil {
+					t.Fatalf("expected step to succeed, got %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), c.expected) {
+				t.Fatalf("expected error containing %q, got %v", c.expected, err)
+			}
+			if client.describe != len(c.states) {
+				t.Fatalf("expected step to be described %d times, got %d", len(c.states), client.describe)
+			}
+			if strings.Join(client.args, " ") != "spark-submit script.py" {
+				t.Fatalf("expected step to run the job's args, got %v", client.args)
+			}
+		})
+	}
+}
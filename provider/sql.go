@@ -6,7 +6,10 @@ package provider
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -15,12 +18,17 @@ import (
 
 	pc "github.com/featureform/provider/provider_config"
 	pt "github.com/featureform/provider/provider_type"
-	db "github.com/jackc/pgx/v4"
 	sf "github.com/snowflakedb/gosnowflake"
 )
 
+// sanitize quotes ident as an identifier for the stores that quote them with
+// double quotes. Double quotes in it are doubled, so it can't end the
+// identifier early; semicolons, backslashes and the like are plain characters
+// inside the quotes. NUL bytes can't be quoted and are dropped, and names that
+// may contain them should go through SanitizeIdentifier instead.
 func sanitize(ident string) string {
-	return db.Identifier{ident}.Sanitize()
+	ident = strings.ReplaceAll(ident, "\x00", "")
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
 }
 
 type SQLOfflineStoreConfig struct {
@@ -40,6 +48,8 @@ type OfflineTableQueries interface {
 	primaryTableRegister(tableName string, sourceName string) string
 	primaryTableCreate(name string, columnString string) string
 	getColumns(db *sql.DB, tableName string) ([]TableColumn, error)
+	// tableSize returns how many bytes a table takes up in storage
+	tableSize(db *sql.DB, tableName string) (int64, error)
 	getValueColumnTypes(tableName string) string
 	determineColumnType(valueType ValueType) (string, error)
 	materializationCreate(tableName string, sourceName string) string
@@ -48,11 +58,23 @@ type OfflineTableQueries interface {
 	materializationDrop(tableName string) string
 	getTable() string
 	dropTable(tableName string) string
+	dropView(tableName string) string
+	// commentOn sets the comment of a table, or a view if isView is set
+	commentOn(tableName string, isView bool, comment string) string
 	materializationIterateSegment(tableName string) string
+	// sampleLatestValues picks n of a resource table's entities at random,
+	// with their latest values
+	sampleLatestValues(tableName string, n int64) string
+	// materializationIterateSegmentSince is materializationIterateSegment
+	// with a third binding that only keeps rows with a later timestamp
+	materializationIterateSegmentSince(tableName string) string
 	newSQLOfflineTable(name string, columnType string) string
 	writeUpdate(table string) string
 	writeInserts(table string) string
 	writeExists(table string) string
+	// bulkInsert appends rows to table using the fewest round trips the
+	// database supports
+	bulkInsert(db *sql.DB, table string, columns []string, rows [][]interface{}) error
 	createValuePlaceholderString(columns []TableColumn) string
 	trainingSetCreate(store *sqlOfflineStore, def TrainingSetDef, tableName string, labelName string) error
 	trainingSetUpdate(store *sqlOfflineStore, def TrainingSetDef, tableName string, labelName string) error
@@ -109,18 +131,60 @@ func (store *sqlOfflineStore) getResourceTableName(id ResourceID) (string, error
 	} else {
 		idType = "label"
 	}
-	return fmt.Sprintf("featureform_resource_%s__%s__%s", idType, id.Name, id.Variant), nil
+	return store.fitIdentifier(fmt.Sprintf("featureform_resource_%s__%s__%s", idType, id.Name, id.Variant)), nil
 }
 
 func (store *sqlOfflineStore) getMaterializationTableName(id MaterializationID) string {
-	return fmt.Sprintf("featureform_materialization_%s", id)
+	return store.fitIdentifier(fmt.Sprintf("featureform_materialization_%s", id))
 }
 
 func (store *sqlOfflineStore) getTrainingSetName(id ResourceID) (string, error) {
 	if err := checkName(id); err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("featureform_trainingset__%s__%s", id.Name, id.Variant), nil
+	return store.fitIdentifier(fmt.Sprintf("featureform_trainingset__%s__%s", id.Name, id.Variant)), nil
+}
+
+// identifierLengthLimit is implemented by the queries of databases that reject
+// names longer than a limit, rather than truncating them like Postgres does
+type identifierLengthLimit interface {
+	maxIdentifierLength() int
+}
+
+// fitIdentifier shortens a table name the store generated if it's too long for
+// its database. The end of the name is replaced with a hash of the whole name,
+// so shortened names stay unique. Resource table names are also used as
+// training set columns, so they're shortened the same way.
+func (store *sqlOfflineStore) fitIdentifier(name string) string {
+	limit, ok := store.query.(identifierLengthLimit)
+	if !ok {
+		return name
+	}
+	return shortenIdentifier(name, limit.maxIdentifierLength())
+}
+
+// queryTableCreator is implemented by the queries of databases whose CREATE
+// TABLE AS loses the column types of the query it's given
+type queryTableCreator interface {
+	createTableFromQuery(db *sql.DB, name string, query string) error
+}
+
+// createTableFromQuery stores the results of query as a new table
+func (store *sqlOfflineStore) createTableFromQuery(name string, query string) error {
+	if creator, ok := store.query.(queryTableCreator); ok {
+		return creator.createTableFromQuery(store.db, name, query)
+	}
+	_, err := store.db.Exec(store.query.transformationCreate(name, query))
+	return err
+}
+
+func shortenIdentifier(name string, maxLength int) string {
+	if len(name) <= maxLength {
+		return name
+	}
+	hash := sha256.Sum256([]byte(name))
+	suffix := "_" + hex.EncodeToString(hash[:8])
+	return name[:maxLength-len(suffix)] + suffix
 }
 
 func GetPrimaryTableName(id ResourceID) (string, error) {
@@ -131,8 +195,16 @@ func GetPrimaryTableName(id ResourceID) (string, error) {
 	return fmt.Sprintf("featureform_primary__%s__%s", id.Name, id.Variant), nil
 }
 
-func (store *sqlOfflineStore) tableExists(id ResourceID) (bool, error) {
-	n := -1
+func (store *sqlOfflineStore) getPrimaryTableName(id ResourceID) (string, error) {
+	name, err := GetPrimaryTableName(id)
+	if err != nil {
+		return "", err
+	}
+	return store.fitIdentifier(name), nil
+}
+
+// tableName returns the name of the table or view a resource is stored in
+func (store *sqlOfflineStore) tableName(id ResourceID) (string, error) {
 	var tableName string
 	var err error
 	if id.check(Feature, Label) == nil {
@@ -140,8 +212,14 @@ func (store *sqlOfflineStore) tableExists(id ResourceID) (bool, error) {
 	} else if id.check(TrainingSet) == nil {
 		tableName, err = store.getTrainingSetName(id)
 	} else if id.check(Primary) == nil || id.check(Transformation) == nil {
-		tableName, err = GetPrimaryTableName(id)
+		tableName, err = store.getPrimaryTableName(id)
 	}
+	return tableName, err
+}
+
+func (store *sqlOfflineStore) tableExists(id ResourceID) (bool, error) {
+	n := -1
+	tableName, err := store.tableName(id)
 	if err != nil {
 		return false, fmt.Errorf("type check: %v: %v", id, err)
 	}
@@ -162,6 +240,27 @@ func (store *sqlOfflineStore) tableExists(id ResourceID) (bool, error) {
 	return false, nil
 }
 
+// TagTable sets the comment of a resource's table or view to its tags, encoded
+// as JSON.
+func (store *sqlOfflineStore) TagTable(id ResourceID, tags ResourceTags) error {
+	tableName, err := store.tableName(id)
+	if err != nil {
+		return fmt.Errorf("type check: %v: %v", id, err)
+	}
+	comment, err := json.Marshal(tableTags(id, tags))
+	if err != nil {
+		return err
+	}
+	n := -1
+	if err := store.db.QueryRow(store.query.viewExists(), tableName).Scan(&n); err != nil {
+		return fmt.Errorf("view exists check: %v", err)
+	}
+	if _, err := store.db.Exec(store.query.commentOn(tableName, n > 0, string(comment))); err != nil {
+		return fmt.Errorf("could not tag %s: %w", tableName, err)
+	}
+	return nil
+}
+
 func (store *sqlOfflineStore) AsOfflineStore() (OfflineStore, error) {
 	return store, nil
 }
@@ -182,6 +281,15 @@ func (store *sqlOfflineStore) RegisterResourceFromSourceTable(id ResourceID, sch
 	if schema.Entity == "" || schema.Value == "" {
 		return nil, fmt.Errorf("non-empty entity and value columns required")
 	}
+	if schema.Filter != "" {
+		columns, err := store.query.getColumns(store.db, schema.SourceTable)
+		if err != nil {
+			return nil, fmt.Errorf("get source columns: %w", err)
+		}
+		if err := validateFilter(schema.Filter, columns); err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+	}
 	tableName, err := store.getResourceTableName(id)
 	if err != nil {
 		return nil, fmt.Errorf("get name: %w", err)
@@ -212,7 +320,7 @@ func (store *sqlOfflineStore) RegisterPrimaryFromSourceTable(id ResourceID, sour
 	} else if exists {
 		return nil, &TableAlreadyExists{id.Name, id.Variant}
 	}
-	tableName, err := GetPrimaryTableName(id)
+	tableName, err := store.getPrimaryTableName(id)
 	if err != nil {
 		return nil, fmt.Errorf("get name: %w", err)
 	}
@@ -231,6 +339,55 @@ func (store *sqlOfflineStore) RegisterPrimaryFromSourceTable(id ResourceID, sour
 	}, nil
 }
 
+func (store *sqlOfflineStore) SourceTableColumns(sourceName string) ([]string, error) {
+	columns, err := store.query.getColumns(store.db, sourceName)
+	if err != nil {
+		return nil, fmt.Errorf("get columns: %w", err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("source table %s has no columns or does not exist", sourceName)
+	}
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	return names, nil
+}
+
+// RegisterPrimaryFromQuery runs query once and stores its results as the
+// primary table, so later changes to the tables it reads aren't picked up.
+func (store *sqlOfflineStore) RegisterPrimaryFromQuery(id ResourceID, query string) (PrimaryTable, error) {
+	if err := id.check(Primary); err != nil {
+		return nil, fmt.Errorf("check fail: %w", err)
+	}
+	if err := ValidateReadOnlyQuery(query); err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	if exists, err := store.tableExists(id); err != nil {
+		return nil, fmt.Errorf("table exist: %w", err)
+	} else if exists {
+		return nil, &TableAlreadyExists{id.Name, id.Variant}
+	}
+	tableName, err := store.getPrimaryTableName(id)
+	if err != nil {
+		return nil, fmt.Errorf("get name: %w", err)
+	}
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	if err := store.createTableFromQuery(tableName, query); err != nil {
+		return nil, fmt.Errorf("snapshot query: %w", err)
+	}
+	columnNames, err := store.query.getColumns(store.db, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("get columns: %w", err)
+	}
+	return &sqlPrimaryTable{
+		db:     store.db,
+		name:   tableName,
+		schema: TableSchema{Columns: columnNames},
+		query:  store.query,
+	}, nil
+}
+
 func (store *sqlOfflineStore) CreatePrimaryTable(id ResourceID, schema TableSchema) (PrimaryTable, error) {
 	if err := id.check(Primary); err != nil {
 		return nil, err
@@ -243,7 +400,7 @@ func (store *sqlOfflineStore) CreatePrimaryTable(id ResourceID, schema TableSche
 	if len(schema.Columns) == 0 {
 		return nil, fmt.Errorf("cannot create primary table without columns")
 	}
-	tableName, err := GetPrimaryTableName(id)
+	tableName, err := store.getPrimaryTableName(id)
 	if err != nil {
 		return nil, err
 	}
@@ -288,7 +445,7 @@ func (store *sqlOfflineStore) createsqlPrimaryTableQuery(name string, schema Tab
 }
 
 func (store *sqlOfflineStore) GetPrimaryTable(id ResourceID) (PrimaryTable, error) {
-	name, err := GetPrimaryTableName(id)
+	name, err := store.getPrimaryTableName(id)
 	if err != nil {
 		return nil, err
 	}
@@ -307,19 +464,76 @@ func (store *sqlOfflineStore) GetPrimaryTable(id ResourceID) (PrimaryTable, erro
 	}, nil
 }
 
-func (store *sqlOfflineStore) GetTransformationTable(id ResourceID) (TransformationTable, error) {
-	name, err := GetPrimaryTableName(id)
+// DeletePrimaryTable drops a primary table, which is a view if it was
+// registered from a source table.
+func (store *sqlOfflineStore) DeletePrimaryTable(id ResourceID) error {
+	if err := id.check(Primary); err != nil {
+		return fmt.Errorf("check fail: %w", err)
+	}
+	tableName, err := store.getPrimaryTableName(id)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("get name: %w", err)
+	}
+	return store.dropTableOrView(tableName)
+}
+
+// DeleteResourceTable drops a feature or label's resource table, which is a
+// view if it was registered from a source table.
+func (store *sqlOfflineStore) DeleteResourceTable(id ResourceID) error {
+	if err := id.check(Feature, Label); err != nil {
+		return fmt.Errorf("check fail: %w", err)
 	}
-	existsQuery := store.query.tableExists()
-	rows, err := store.db.Query(existsQuery, name)
+	tableName, err := store.getResourceTableName(id)
+	if err != nil {
+		return fmt.Errorf("get name: %w", err)
+	}
+	return store.dropTableOrView(tableName)
+}
+
+func (store *sqlOfflineStore) DeleteTransformationTable(id ResourceID) error {
+	if err := id.check(Transformation); err != nil {
+		return fmt.Errorf("check fail: %w", err)
+	}
+	tableName, err := store.getPrimaryTableName(id)
+	if err != nil {
+		return fmt.Errorf("get name: %w", err)
+	}
+	return store.dropTableOrView(tableName)
+}
+
+// dropTableOrView drops tableName, whether it's a table or a view, and does
+// nothing if it doesn't exist.
+func (store *sqlOfflineStore) dropTableOrView(tableName string) error {
+	n := -1
+	if err := store.db.QueryRow(store.query.viewExists(), tableName).Scan(&n); err != nil {
+		return fmt.Errorf("view exists check: %w", err)
+	}
+	if n > 0 {
+		if _, err := store.db.Exec(store.query.dropView(tableName)); err != nil {
+			return fmt.Errorf("drop view: %w", err)
+		}
+		return nil
+	}
+	if err := store.db.QueryRow(store.query.tableExists(), tableName).Scan(&n); err != nil {
+		return fmt.Errorf("table exists check: %w", err)
+	}
+	if n > 0 {
+		if _, err := store.db.Exec(store.query.dropTable(tableName)); err != nil {
+			return fmt.Errorf("drop table: %w", err)
+		}
+	}
+	return nil
+}
+
+func (store *sqlOfflineStore) GetTransformationTable(id ResourceID) (TransformationTable, error) {
+	name, err := store.getPrimaryTableName(id)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	if !rows.Next() {
-		return nil, fmt.Errorf("transformation not found: %v", name)
+	if exists, err := store.tableExists(id); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, &TableNotFound{id.Name, id.Variant}
 	}
 	columnNames, err := store.query.getColumns(store.db, name)
 
@@ -428,6 +642,23 @@ func (mat *sqlMaterialization) IterateSegment(start, end int64) (FeatureIterator
 	return newsqlFeatureIterator(rows, colType, mat.query), nil
 }
 
+// IterateSegmentSince reads the rows of a segment with a timestamp after since,
+// leaving the database to skip the others
+func (mat *sqlMaterialization) IterateSegmentSince(start, end int64, since time.Time) (FeatureIterator, error) {
+	query := mat.query.materializationIterateSegmentSince(mat.tableName)
+	rows, err := mat.db.Query(query, start, end, since)
+	if err != nil {
+		return nil, err
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	colType := mat.query.getValueColumnType(types[1])
+	return newsqlFeatureIterator(rows, colType, mat.query), nil
+}
+
 type sqlFeatureIterator struct {
 	rows         *sql.Rows
 	err          error
@@ -470,13 +701,36 @@ func (iter *sqlFeatureIterator) Value() ResourceRecord {
 }
 
 func (iter *sqlFeatureIterator) Err() error {
-	return nil
+	return iter.err
 }
 
 func (iter *sqlFeatureIterator) Close() error {
 	return iter.rows.Close()
 }
 
+func (store *sqlOfflineStore) SampleLatestFeatureValues(id ResourceID, n int64) ([]ResourceRecord, error) {
+	table, err := store.getsqlResourceTable(id)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := store.db.Query(store.query.sampleLatestValues(table.name, n))
+	if err != nil {
+		return nil, err
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	iter := newsqlFeatureIterator(rows, store.query.getValueColumnType(types[1]), store.query)
+	defer iter.Close()
+	records := make([]ResourceRecord, 0, n)
+	for iter.Next() {
+		records = append(records, iter.Value())
+	}
+	return records, iter.Err()
+}
+
 func (store *sqlOfflineStore) CreateMaterialization(id ResourceID) (Materialization, error) {
 	if id.Type != Feature {
 		return nil, errors.New("only features can be materialized")
@@ -802,6 +1056,18 @@ func (table *sqlPrimaryTable) GetName() string {
 	return table.name
 }
 
+func (table *sqlPrimaryTable) SizeBytes() (int64, error) {
+	return table.query.tableSize(table.db, table.name)
+}
+
+func (table *sqlPrimaryTable) GetSchema() (TableSchema, error) {
+	columns, err := table.query.getColumns(table.db, table.name)
+	if err != nil {
+		return TableSchema{}, fmt.Errorf("get columns: %w", err)
+	}
+	return TableSchema{Columns: columns}, nil
+}
+
 func (table *sqlPrimaryTable) Write(rec GenericRecord) error {
 	tb := sanitize(table.name)
 	columns := table.getColumnNameString()
@@ -816,12 +1082,18 @@ func (table *sqlPrimaryTable) Write(rec GenericRecord) error {
 }
 
 func (table *sqlPrimaryTable) WriteBatch(recs []GenericRecord) error {
-	for _, rec := range recs {
-		if err := table.Write(rec); err != nil {
-			return err
-		}
+	if len(recs) == 0 {
+		return nil
 	}
-	return nil
+	columns := make([]string, len(table.schema.Columns))
+	for i, column := range table.schema.Columns {
+		columns[i] = column.Name
+	}
+	rows := make([][]interface{}, len(recs))
+	for i, rec := range recs {
+		rows[i] = rec
+	}
+	return table.query.bulkInsert(table.db, table.name, columns, rows)
 }
 
 func (table *sqlPrimaryTable) getColumnNameString() string {
@@ -859,6 +1131,38 @@ func (pt *sqlPrimaryTable) IterateSegment(n int64) (GenericTableIterator, error)
 	return newsqlGenericTableIterator(rows, colTypes, columnNames, pt.query), nil
 }
 
+// IterateOrdered reads every row of the table, sorted by column, which is
+// matched to the table's columns ignoring case
+func (pt *sqlPrimaryTable) IterateOrdered(column string) (GenericTableIterator, error) {
+	columns, err := pt.query.getColumns(pt.db, pt.name)
+	if err != nil {
+		return nil, err
+	}
+	columnNames := make([]string, 0)
+	orderBy := ""
+	for _, col := range columns {
+		columnNames = append(columnNames, sanitize(col.Name))
+		if strings.EqualFold(col.Name, column) {
+			orderBy = sanitize(col.Name)
+		}
+	}
+	if orderBy == "" {
+		return nil, fmt.Errorf("column %s not in table %s", column, pt.name)
+	}
+	names := strings.Join(columnNames, ", ")
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY CASE WHEN %s IS NULL THEN 0 ELSE 1 END, %s", names, sanitize(pt.name), orderBy, orderBy)
+	rows, err := pt.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	colTypes, err := pt.getValueColumnTypes(pt.name)
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	return newsqlGenericTableIterator(rows, colTypes, columnNames, pt.query), nil
+}
+
 func (pt *sqlPrimaryTable) getValueColumnTypes(table string) ([]interface{}, error) {
 	query := pt.query.getValueColumnTypes(table)
 	rows, err := pt.db.Query(query)
@@ -956,13 +1260,43 @@ func (table *sqlOfflineTable) Write(rec ResourceRecord) error {
 	return nil
 }
 
+// WriteBatch bulk inserts recs if the table is empty, such as when it's being
+// seeded. Otherwise each record is upserted with Write, since it has to replace
+// any existing value for the same entity and timestamp.
 func (table *sqlOfflineTable) WriteBatch(recs []ResourceRecord) error {
-	for _, rec := range recs {
-		if err := table.Write(rec); err != nil {
-			return err
+	if len(recs) == 0 {
+		return nil
+	}
+	empty, err := table.isEmpty()
+	if err != nil {
+		return err
+	}
+	if !empty {
+		for _, rec := range recs {
+			if err := table.Write(rec); err != nil {
+				return err
+			}
 		}
+		return nil
 	}
-	return nil
+	latest, err := latestResourceRecords(recs)
+	if err != nil {
+		return err
+	}
+	rows := make([][]interface{}, len(latest))
+	for i, rec := range latest {
+		rows[i] = []interface{}{rec.Entity, rec.Value, rec.TS}
+	}
+	return table.query.bulkInsert(table.db, table.name, []string{"entity", "value", "ts"}, rows)
+}
+
+func (table *sqlOfflineTable) isEmpty() (bool, error) {
+	rows, err := table.db.Query(fmt.Sprintf("SELECT 1 FROM %s LIMIT 1", sanitize(table.name)))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	return !rows.Next(), rows.Err()
 }
 
 func (table *sqlOfflineTable) resourceExists(rec ResourceRecord) (bool, error) {
@@ -989,8 +1323,7 @@ func (store *sqlOfflineStore) CreateTransformation(config TransformationConfig)
 	if err != nil {
 		return err
 	}
-	query := store.query.transformationCreate(name, config.Query)
-	if _, err := store.db.Exec(query); err != nil {
+	if err := store.createTableFromQuery(name, config.Query); err != nil {
 		return err
 	}
 
@@ -1013,7 +1346,7 @@ func (store *sqlOfflineStore) UpdateTransformation(config TransformationConfig)
 func (store *sqlOfflineStore) createTransformationName(id ResourceID) (string, error) {
 	switch id.Type {
 	case Transformation:
-		return GetPrimaryTableName(id)
+		return store.getPrimaryTableName(id)
 	case Label:
 		return "", TransformationTypeError{"Invalid Transformation Type: Label"}
 	case Feature:
@@ -1147,15 +1480,22 @@ func (q defaultOfflineSQLQueries) viewExists() string {
 	return genericExists
 }
 
+// snowflakeLiteral quotes a name for IDENTIFIER and TABLE, which take the
+// names of columns and tables as string literals
+func snowflakeLiteral(name string) string {
+	return quoteLiteral(pt.SnowflakeOffline, name)
+}
+
 func (q defaultOfflineSQLQueries) registerResources(db *sql.DB, tableName string, schema ResourceSchema, timestamp bool) error {
 	var query string
 	if timestamp {
-		query = fmt.Sprintf("CREATE VIEW %s AS SELECT IDENTIFIER('%s') as entity,  IDENTIFIER('%s') as value,  IDENTIFIER('%s') as ts FROM TABLE('%s')", sanitize(tableName),
-			schema.Entity, schema.Value, schema.TS, sanitize(schema.SourceTable))
+		query = fmt.Sprintf("CREATE VIEW %s AS SELECT IDENTIFIER(%s) as entity,  IDENTIFIER(%s) as value,  IDENTIFIER(%s) as ts FROM TABLE(%s)", sanitize(tableName),
+			snowflakeLiteral(schema.Entity), snowflakeLiteral(schema.Value), snowflakeLiteral(schema.TS), snowflakeLiteral(sanitize(schema.SourceTable)))
 	} else {
-		query = fmt.Sprintf("CREATE VIEW %s AS SELECT IDENTIFIER('%s') as entity, IDENTIFIER('%s') as value, to_timestamp_ntz('%s', 'YYYY-DD-MM HH24:MI:SS +0000 UTC')::TIMESTAMP_NTZ as ts FROM TABLE('%s')", sanitize(tableName),
-			schema.Entity, schema.Value, time.UnixMilli(0).UTC(), sanitize(schema.SourceTable))
+		query = fmt.Sprintf("CREATE VIEW %s AS SELECT IDENTIFIER(%s) as entity, IDENTIFIER(%s) as value, to_timestamp_ntz('%s', 'YYYY-DD-MM HH24:MI:SS +0000 UTC')::TIMESTAMP_NTZ as ts FROM TABLE(%s)", sanitize(tableName),
+			snowflakeLiteral(schema.Entity), snowflakeLiteral(schema.Value), time.UnixMilli(0).UTC(), snowflakeLiteral(sanitize(schema.SourceTable)))
 	}
+	query += filterClause(schema.Filter)
 	if _, err := db.Exec(query); err != nil {
 		return err
 	}
@@ -1163,7 +1503,7 @@ func (q defaultOfflineSQLQueries) registerResources(db *sql.DB, tableName string
 }
 
 func (q defaultOfflineSQLQueries) primaryTableRegister(tableName string, sourceName string) string {
-	return fmt.Sprintf("CREATE VIEW %s AS SELECT * FROM TABLE('%s')", sanitize(tableName), sourceName)
+	return fmt.Sprintf("CREATE VIEW %s AS SELECT * FROM TABLE(%s)", sanitize(tableName), snowflakeLiteral(sourceName))
 }
 func (q defaultOfflineSQLQueries) getColumns(db *sql.DB, name string) ([]TableColumn, error) {
 	bind := q.newVariableBindingIterator()
@@ -1183,6 +1523,30 @@ func (q defaultOfflineSQLQueries) getColumns(db *sql.DB, name string) ([]TableCo
 	}
 	return columnNames, nil
 }
+
+// tableSize includes the table's indexes and TOAST data. Views take up no
+// space of their own.
+func (q defaultOfflineSQLQueries) tableSize(db *sql.DB, name string) (int64, error) {
+	bind := q.newVariableBindingIterator()
+	qry := fmt.Sprintf("SELECT pg_total_relation_size(%s::regclass)", bind.Next())
+	return scanTableSize(db.QueryRow(qry, sanitize(name)), name)
+}
+
+// scanTableSize reads a size queried from a database's catalog, which has none
+// for tables it doesn't store itself, such as views
+func scanTableSize(row *sql.Row, name string) (int64, error) {
+	var size sql.NullInt64
+	if err := row.Scan(&size); err == sql.ErrNoRows {
+		return 0, fmt.Errorf("size of %s is unknown", name)
+	} else if err != nil {
+		return 0, err
+	}
+	if !size.Valid {
+		return 0, fmt.Errorf("size of %s is unknown", name)
+	}
+	return size.Int64, nil
+}
+
 func (q defaultOfflineSQLQueries) primaryTableCreate(name string, columnString string) string {
 	return fmt.Sprintf("CREATE TABLE %s ( %s )", sanitize(name), columnString)
 }
@@ -1232,6 +1596,18 @@ func (q defaultOfflineSQLQueries) dropTable(tableName string) string {
 	return fmt.Sprintf("DROP TABLE %s", sanitize(tableName))
 }
 
+func (q defaultOfflineSQLQueries) dropView(tableName string) string {
+	return fmt.Sprintf("DROP VIEW %s", sanitize(tableName))
+}
+
+func (q defaultOfflineSQLQueries) commentOn(tableName string, isView bool, comment string) string {
+	objectType := "TABLE"
+	if isView {
+		objectType = "VIEW"
+	}
+	return fmt.Sprintf("COMMENT ON %s %s IS '%s'", objectType, sanitize(tableName), strings.ReplaceAll(comment, "'", "''"))
+}
+
 func (q defaultOfflineSQLQueries) trainingRowSelect(columns string, trainingSetName string) string {
 	return fmt.Sprintf("SELECT %s FROM %s", columns, sanitize(trainingSetName))
 }
@@ -1275,6 +1651,40 @@ func (q defaultOfflineSQLQueries) writeInserts(table string) string {
 	bind := q.newVariableBindingIterator()
 	return fmt.Sprintf("INSERT INTO %s (entity, value, ts) VALUES (%s, %s, %s)", table, bind.Next(), bind.Next(), bind.Next())
 }
+
+// Rows per INSERT statement in bulkInsert, which keeps each statement well
+// under databases' limits on bound parameters
+const bulkInsertStatementRows = 500
+
+func (q defaultOfflineSQLQueries) bulkInsert(db *sql.DB, table string, columns []string, rows [][]interface{}) error {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = sanitize(column)
+	}
+	for start := 0; start < len(rows); start += bulkInsertStatementRows {
+		end := start + bulkInsertStatementRows
+		if end > len(rows) {
+			end = len(rows)
+		}
+		bind := q.newVariableBindingIterator()
+		values := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*len(columns))
+		for _, row := range rows[start:end] {
+			placeholders := make([]string, len(row))
+			for i := range row {
+				placeholders[i] = bind.Next()
+			}
+			values = append(values, fmt.Sprintf("(%s)", strings.Join(placeholders, ", ")))
+			args = append(args, row...)
+		}
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", sanitize(table), strings.Join(quoted, ", "), strings.Join(values, ", "))
+		if _, err := db.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (q defaultOfflineSQLQueries) writeExists(table string) string {
 	bind := q.newVariableBindingIterator()
 	return fmt.Sprintf("SELECT COUNT (*) FROM %s WHERE entity=%s AND ts=%s", table, bind.Next(), bind.Next())
@@ -1285,6 +1695,15 @@ func (q defaultOfflineSQLQueries) materializationIterateSegment(tableName string
 	return fmt.Sprintf("SELECT entity, value, ts FROM ( SELECT * FROM %s WHERE row_number>%s AND row_number<=%s)t1", sanitize(tableName), bind.Next(), bind.Next())
 }
 
+func (q defaultOfflineSQLQueries) sampleLatestValues(tableName string, n int64) string {
+	return fmt.Sprintf("SELECT entity, value, ts FROM (SELECT entity, value, ts, row_number() OVER (PARTITION BY entity ORDER BY ts DESC) AS rn FROM %s) t WHERE rn=1 ORDER BY RANDOM() LIMIT %d", sanitize(tableName), n)
+}
+
+func (q defaultOfflineSQLQueries) materializationIterateSegmentSince(tableName string) string {
+	bind := q.newVariableBindingIterator()
+	return fmt.Sprintf("SELECT entity, value, ts FROM ( SELECT * FROM %s WHERE row_number>%s AND row_number<=%s AND ts>%s)t1", sanitize(tableName), bind.Next(), bind.Next(), bind.Next())
+}
+
 func (q defaultOfflineSQLQueries) createValuePlaceholderString(columns []TableColumn) string {
 	placeholders := make([]string, 0)
 	for _ = range columns {
@@ -1295,6 +1714,10 @@ func (q defaultOfflineSQLQueries) createValuePlaceholderString(columns []TableCo
 
 func (q defaultOfflineSQLQueries) trainingSetQuery(store *sqlOfflineStore, def TrainingSetDef, tableName string, labelName string, isUpdate bool) error {
 	columns := make([]string, 0)
+	// Each label row is joined to every value the join strategy allows, and
+	// the row with the newest of each is kept
+	timestamps := make([]string, 0)
+	newestFirst := make([]string, 0)
 	query := ""
 	for i, feature := range def.Features {
 		tableName, err := store.getResourceTableName(feature)
@@ -1304,8 +1727,10 @@ func (q defaultOfflineSQLQueries) trainingSetQuery(store *sqlOfflineStore, def T
 		}
 		tableJoinAlias := fmt.Sprintf("t%d", i+1)
 		columns = append(columns, santizedName)
-		query = fmt.Sprintf("%s LEFT OUTER JOIN (SELECT entity, value as %s, ts FROM %s ORDER BY ts desc) as %s ON (%s.entity=t0.entity AND %s.ts <= t0.ts)",
-			query, santizedName, santizedName, tableJoinAlias, tableJoinAlias, tableJoinAlias)
+		timestamps = append(timestamps, fmt.Sprintf("%s.ts as %s_ts", tableJoinAlias, tableJoinAlias))
+		newestFirst = append(newestFirst, fmt.Sprintf("%s_ts desc", tableJoinAlias))
+		query = fmt.Sprintf("%s %s (SELECT entity, value as %s, ts FROM %s ORDER BY ts desc) as %s ON (%s.entity=t0.entity%s)",
+			query, def.JoinPolicy.featureJoin(), santizedName, santizedName, tableJoinAlias, tableJoinAlias, def.JoinStrategy.featureTimeCondition(tableJoinAlias+".ts", "t0.ts"))
 
 	}
 	for i, lagFeature := range def.LagFeatures {
@@ -1321,6 +1746,8 @@ func (q defaultOfflineSQLQueries) trainingSetQuery(store *sqlOfflineStore, def T
 		columns = append(columns, lagColumnName)
 		sanitizedName := sanitize(tableName)
 		tableJoinAlias := fmt.Sprintf("t%d", lagFeaturesOffset+i+1)
+		timestamps = append(timestamps, fmt.Sprintf("%s.ts as %s_ts", tableJoinAlias, tableJoinAlias))
+		newestFirst = append(newestFirst, fmt.Sprintf("%s_ts desc", tableJoinAlias))
 		timeDeltaSeconds := lagFeature.LagDelta.Seconds()
 		query = fmt.Sprintf("%s LEFT OUTER JOIN (SELECT entity, value as %s, ts FROM %s ORDER BY ts desc) as %s ON (%s.entity=t0.entity AND (%s.ts + INTERVAL '%f') <= t0.ts)",
 			query, lagColumnName, sanitizedName, tableJoinAlias, tableJoinAlias, tableJoinAlias, timeDeltaSeconds)
@@ -1328,12 +1755,14 @@ func (q defaultOfflineSQLQueries) trainingSetQuery(store *sqlOfflineStore, def T
 
 	query = fmt.Sprintf("%s )) WHERE rn=1", query)
 	columnStr := strings.Join(columns, ", ")
+	timestampStr := strings.Join(timestamps, ", ")
+	newestFirstStr := strings.Join(newestFirst, ", ")
 	if !isUpdate {
 		fullQuery := fmt.Sprintf(
 			"CREATE TABLE %s AS (SELECT %s, label FROM ("+
-				"SELECT *, row_number() over(PARTITION BY e, label, time ORDER BY time desc) as rn FROM ( "+
-				"SELECT t0.entity as e, t0.value as label, t0.ts as time, %s from %s as t0 %s )",
-			sanitize(tableName), columnStr, columnStr, sanitize(labelName), query)
+				"SELECT *, row_number() over(PARTITION BY e, label, time ORDER BY %s) as rn FROM ( "+
+				"SELECT t0.entity as e, t0.value as label, t0.ts as time, %s, %s from %s as t0 %s )",
+			sanitize(tableName), columnStr, newestFirstStr, columnStr, timestampStr, sanitize(labelName), query)
 		if _, err := store.db.Exec(fullQuery); err != nil {
 			return err
 		}
@@ -1341,9 +1770,9 @@ func (q defaultOfflineSQLQueries) trainingSetQuery(store *sqlOfflineStore, def T
 		tempTable := sanitize(fmt.Sprintf("tmp_%s", tableName))
 		fullQuery := fmt.Sprintf(
 			"CREATE TABLE %s AS (SELECT %s, label FROM ("+
-				"SELECT *, row_number() over(PARTITION BY e, label, time ORDER BY time desc) as rn FROM ( "+
-				"SELECT t0.entity as e, t0.value as label, t0.ts as time, %s from %s as t0 %s )",
-			tempTable, columnStr, columnStr, sanitize(labelName), query)
+				"SELECT *, row_number() over(PARTITION BY e, label, time ORDER BY %s) as rn FROM ( "+
+				"SELECT t0.entity as e, t0.value as label, t0.ts as time, %s, %s from %s as t0 %s )",
+			tempTable, columnStr, newestFirstStr, columnStr, timestampStr, sanitize(labelName), query)
 		err := q.atomicUpdate(store.db, tableName, tempTable, fullQuery)
 		return err
 	}
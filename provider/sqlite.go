@@ -0,0 +1,437 @@
+//go:build cgo
+// +build cgo
+
+package provider
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	pc "github.com/featureform/provider/provider_config"
+	pt "github.com/featureform/provider/provider_type"
+	"github.com/mattn/go-sqlite3"
+)
+
+// SQLiteSupported is whether this build can open SQLite offline stores. The
+// SQLite driver is written in C, so it needs a build with cgo enabled.
+const SQLiteSupported = true
+
+// The SQLite driver, wrapped so that it writes timestamps in UTC
+const sqliteDriverName = "sqlite3_featureform"
+
+// Tables the SQLite store keeps alongside the ones it creates for resources
+const (
+	sqliteEpochTable = "featureform_epoch"
+	sqliteTagsTable  = "featureform_tags"
+)
+
+type sqliteColumnType string
+
+const (
+	sqInteger   sqliteColumnType = "INTEGER"
+	sqReal      sqliteColumnType = "REAL"
+	sqText      sqliteColumnType = "TEXT"
+	sqBoolean   sqliteColumnType = "BOOLEAN"
+	sqTimestamp sqliteColumnType = "TIMESTAMP"
+	// Columns declared without a type, or with one the driver doesn't
+	// convert, whose values are returned as they're stored
+	sqUntyped sqliteColumnType = ""
+)
+
+// newSQLOfflineTable is given the Postgres name of the value column's type,
+// which is stored as the type castTableItemType converts
+var sqliteValueColumnTypes = map[string]sqliteColumnType{
+	"INT":         sqInteger,
+	"FLOAT8":      sqReal,
+	"VARCHAR":     sqText,
+	"BOOLEAN":     sqBoolean,
+	"TIMESTAMPTZ": sqTimestamp,
+}
+
+func init() {
+	sql.Register(sqliteDriverName, sqliteDriver{&sqlite3.SQLiteDriver{}})
+}
+
+// sqliteDriver opens connections that write timestamps in UTC. SQLite doesn't
+// have a timestamp type, so the driver writes them as text in the time zone
+// they're in, and the same time written from two zones wouldn't be equal to
+// itself or sort correctly.
+type sqliteDriver struct {
+	*sqlite3.SQLiteDriver
+}
+
+func (d sqliteDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+type sqliteConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (c sqliteConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if ts, ok := nv.Value.(time.Time); ok {
+		nv.Value = ts.UTC()
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+func sqliteOfflineStoreFactory(config pc.SerializedConfig) (Provider, error) {
+	sc := pc.SQLiteConfig{}
+	if err := sc.Deserialize(config); err != nil {
+		return nil, fmt.Errorf("invalid sqlite config: %v", config)
+	}
+	if sc.Path == "" {
+		return nil, fmt.Errorf("invalid sqlite config: path required")
+	}
+	queries := sqliteSQLQueries{}
+	queries.setVariableBinding(MySQLBindingStyle)
+	sgConfig := SQLOfflineStoreConfig{
+		Config:        config,
+		ConnectionURL: sqliteConnectionURL(sc),
+		Driver:        sqliteDriverName,
+		ProviderType:  pt.SQLiteOffline,
+		QueryImpl:     &queries,
+	}
+
+	store, err := NewSQLOfflineStore(sgConfig)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// sqliteConnectionURL builds the DSN for a SQLite config. Timestamps are read
+// in UTC, and transactions take the write lock when they begin, rather than
+// failing if another connection writes before they do. Each connection to
+// ":memory:" would get a database of its own, so the in-memory database is
+// shared by every connection in the process instead. File databases use a
+// write-ahead log, so that reads and writes don't block each other.
+func sqliteConnectionURL(sc pc.SQLiteConfig) string {
+	params := url.Values{}
+	params.Set("_loc", "UTC")
+	params.Set("_busy_timeout", "10000")
+	params.Set("_txlock", "immediate")
+	if sc.Path == pc.SQLiteMemoryPath {
+		params.Set("mode", "memory")
+		params.Set("cache", "shared")
+		return fmt.Sprintf("file:featureform?%s", params.Encode())
+	}
+	params.Set("_journal_mode", "WAL")
+	return fmt.Sprintf("%s?%s", sc.Path, params.Encode())
+}
+
+type sqliteSQLQueries struct {
+	defaultOfflineSQLQueries
+}
+
+// SQLite names are matched without regard to case, quoted or not
+func (q sqliteSQLQueries) tableExists() string {
+	return "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ? COLLATE NOCASE"
+}
+
+func (q sqliteSQLQueries) viewExists() string {
+	return "SELECT COUNT(*) FROM sqlite_master WHERE type = 'view' AND name = ? COLLATE NOCASE"
+}
+
+func (q sqliteSQLQueries) getTable() string {
+	return "SELECT name FROM sqlite_master WHERE type IN ('table', 'view') AND name = ? COLLATE NOCASE"
+}
+
+// registerResources creates a view of the resource's columns. The driver only
+// parses the values of columns declared as TIMESTAMP, DATETIME or DATE as
+// timestamps, so the source's timestamp column has to be declared as one of
+// them. A literal doesn't have a declared type, so resources without
+// timestamps take theirs from the single row of sqliteEpochTable.
+func (q sqliteSQLQueries) registerResources(db *sql.DB, tableName string, schema ResourceSchema, timestamp bool) error {
+	var query string
+	if timestamp {
+		query = fmt.Sprintf("CREATE VIEW %s AS SELECT %s AS entity, %s AS value, %s AS ts FROM %s", sanitize(tableName),
+			sanitize(schema.Entity), sanitize(schema.Value), sanitize(schema.TS), sanitize(schema.SourceTable))
+	} else {
+		epoch := time.UnixMilli(0).UTC().Format(sqlite3.SQLiteTimestampFormats[0])
+		query = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (featureform_ts TIMESTAMP PRIMARY KEY); INSERT OR IGNORE INTO %s VALUES ('%s'); "+
+			"CREATE VIEW %s AS SELECT %s AS entity, %s AS value, featureform_ts AS ts FROM %s, %s", sanitize(sqliteEpochTable), sanitize(sqliteEpochTable), epoch,
+			sanitize(tableName), sanitize(schema.Entity), sanitize(schema.Value), sanitize(schema.SourceTable), sanitize(sqliteEpochTable))
+	}
+	query += filterClause(schema.Filter)
+	if _, err := db.Exec(query); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (q sqliteSQLQueries) primaryTableRegister(tableName string, sourceName string) string {
+	return fmt.Sprintf("CREATE VIEW %s AS SELECT * FROM %s", sanitize(tableName), sourceName)
+}
+
+func (q sqliteSQLQueries) getColumns(db *sql.DB, tableName string) ([]TableColumn, error) {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?) ORDER BY cid", tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columnNames := make([]TableColumn, 0)
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columnNames = append(columnNames, TableColumn{Name: column})
+	}
+	return columnNames, rows.Err()
+}
+
+// tableSize needs the dbstat virtual table, which SQLite is only sometimes
+// compiled with
+func (q sqliteSQLQueries) tableSize(db *sql.DB, tableName string) (int64, error) {
+	return scanTableSize(db.QueryRow("SELECT SUM(pgsize) FROM dbstat WHERE name = ?", tableName), tableName)
+}
+
+func (q sqliteSQLQueries) determineColumnType(valueType ValueType) (string, error) {
+	switch valueType {
+	case Int, Int32, Int64:
+		return string(sqInteger), nil
+	case Float32, Float64:
+		return string(sqReal), nil
+	case String:
+		return string(sqText), nil
+	case Bool:
+		return string(sqBoolean), nil
+	case Timestamp:
+		return string(sqTimestamp), nil
+	case NilType:
+		return string(sqText), nil
+	default:
+		return "", fmt.Errorf("cannot find column type for value type: %s", valueType)
+	}
+}
+
+func (q sqliteSQLQueries) newSQLOfflineTable(name string, columnType string) string {
+	if t, has := sqliteValueColumnTypes[columnType]; has {
+		columnType = string(t)
+	}
+	return fmt.Sprintf("CREATE TABLE %s (entity TEXT, value %s, ts %s, UNIQUE (entity, ts))", sanitize(name), columnType, sqTimestamp)
+}
+
+// SQLite doesn't have materialized views, so materializations are plain views
+// of the resource's latest values, and there's nothing to update.
+func (q sqliteSQLQueries) materializationCreate(tableName string, sourceName string) string {
+	return fmt.Sprintf(
+		"CREATE VIEW %s AS SELECT entity, value, ts, row_number() OVER (ORDER BY entity) AS row_number FROM "+
+			"(SELECT entity, ts, value, row_number() OVER (PARTITION BY entity ORDER BY ts DESC) "+
+			"AS rn FROM %s) t WHERE rn=1", sanitize(tableName), sanitize(sourceName))
+}
+
+func (q sqliteSQLQueries) materializationUpdate(db *sql.DB, tableName string, sourceName string) error {
+	return nil
+}
+
+func (q sqliteSQLQueries) materializationExists() string {
+	return q.getTable()
+}
+
+func (q sqliteSQLQueries) materializationDrop(tableName string) string {
+	return q.dropView(tableName)
+}
+
+// SQLite doesn't have comments, so tags are kept in sqliteTagsTable
+func (q sqliteSQLQueries) commentOn(tableName string, isView bool, comment string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (table_name TEXT PRIMARY KEY, tags TEXT); "+
+		"INSERT INTO %s (table_name, tags) VALUES (%s, %s) ON CONFLICT (table_name) DO UPDATE SET tags = excluded.tags",
+		sanitize(sqliteTagsTable), sanitize(sqliteTagsTable), quoteLiteral(pt.SQLiteOffline, tableName), quoteLiteral(pt.SQLiteOffline, comment))
+}
+
+func (q sqliteSQLQueries) trainingSetCreate(store *sqlOfflineStore, def TrainingSetDef, tableName string, labelName string) error {
+	query, err := q.trainingSetQuery(store, def, labelName)
+	if err != nil {
+		return err
+	}
+	return q.tableFromQuery(store.db, tableName, query, false)
+}
+
+func (q sqliteSQLQueries) trainingSetUpdate(store *sqlOfflineStore, def TrainingSetDef, tableName string, labelName string) error {
+	query, err := q.trainingSetQuery(store, def, labelName)
+	if err != nil {
+		return err
+	}
+	return q.tableFromQuery(store.db, tableName, query, true)
+}
+
+// trainingSetQuery joins each label to every value of each feature the join
+// strategy allows and keeps the newest, like the default training set query,
+// since SQLite doesn't have lateral joins. Labels are numbered so that
+// identical label rows are kept apart. Timestamps are compared as the text
+// they're stored as, which sorts in time order for the UTC timestamps the
+// store writes, except for lag features' deltas, which are added to them as
+// Julian days.
+func (q sqliteSQLQueries) trainingSetQuery(store *sqlOfflineStore, def TrainingSetDef, labelName string) (string, error) {
+	columns := make([]string, 0)
+	values := make([]string, 0)
+	newestFirst := make([]string, 0)
+	joins := ""
+	for i, feature := range def.Features {
+		tableName, err := store.getResourceTableName(feature)
+		if err != nil {
+			return "", err
+		}
+		alias := fmt.Sprintf("t%d", i)
+		columns = append(columns, sanitize(tableName))
+		values = append(values, fmt.Sprintf("%s.value AS %s", alias, sanitize(tableName)))
+		newestFirst = append(newestFirst, fmt.Sprintf("%s.ts DESC", alias))
+		joins = fmt.Sprintf("%s %s %s %s ON %s.entity=l.entity%s", joins, def.JoinPolicy.featureJoin(), sanitize(tableName), alias, alias,
+			def.JoinStrategy.featureTimeCondition(alias+".ts", "l.ts"))
+	}
+	for i, lagFeature := range def.LagFeatures {
+		tableName, err := store.getResourceTableName(ResourceID{lagFeature.FeatureName, lagFeature.FeatureVariant, Feature})
+		if err != nil {
+			return "", err
+		}
+		lagColumnName := lagFeature.LagName
+		if lagColumnName == "" {
+			lagColumnName = fmt.Sprintf("%s_lag_%s", tableName, lagFeature.LagDelta)
+		}
+		alias := fmt.Sprintf("t%d", len(def.Features)+i)
+		columns = append(columns, sanitize(lagColumnName))
+		values = append(values, fmt.Sprintf("%s.value AS %s", alias, sanitize(lagColumnName)))
+		newestFirst = append(newestFirst, fmt.Sprintf("%s.ts DESC", alias))
+		joins = fmt.Sprintf("%s LEFT OUTER JOIN %s %s ON %s.entity=l.entity AND julianday(%s.ts) + %f <= julianday(l.ts)",
+			joins, sanitize(tableName), alias, alias, alias, lagFeature.LagDelta.Hours()/24)
+	}
+	query := fmt.Sprintf(
+		"SELECT %s, label FROM (SELECT %s, l.value AS label, row_number() OVER (PARTITION BY l.label_row ORDER BY %s) AS rn FROM "+
+			"(SELECT entity, value, ts, row_number() OVER () AS label_row FROM %s) l%s) WHERE rn=1",
+		strings.Join(columns, ", "), strings.Join(values, ", "), strings.Join(newestFirst, ", "), sanitize(labelName), joins)
+	return query, nil
+}
+
+// createTableFromQuery stores the results of query as a new table. CREATE
+// TABLE AS only keeps the affinity of the query's columns, and not the types
+// they were declared with, which the driver converts timestamps and booleans
+// by, so the table is created with the declared type of each column first.
+func (q sqliteSQLQueries) createTableFromQuery(db *sql.DB, name string, query string) error {
+	return q.tableFromQuery(db, name, query, false)
+}
+
+// tableFromQuery creates a table from query in a transaction, after dropping
+// the table it replaces if replace is set. SQLite's DDL is transactional, so
+// readers see either the old table or the new one.
+func (q sqliteSQLQueries) tableFromQuery(db *sql.DB, name string, query string, replace bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if replace {
+		if _, err := tx.Exec(q.dropTable(name)); err != nil {
+			return fmt.Errorf("drop previous %s: %w", name, err)
+		}
+	}
+	columns, err := sqliteColumnDefinitions(tx, query)
+	if err != nil {
+		return fmt.Errorf("get columns of %s: %w", name, err)
+	}
+	if _, err := tx.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", sanitize(name), strings.Join(columns, ", "))); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf("INSERT INTO %s SELECT * FROM (%s)", sanitize(name), query)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// sqliteColumnDefinitions returns the definitions of the columns of query's
+// results, with the types they were declared with. Expressions don't have
+// declared types, so their columns are defined without one, and their values
+// are stored as they are.
+func sqliteColumnDefinitions(tx *sql.Tx, query string) ([]string, error) {
+	rows, err := tx.Query(fmt.Sprintf("SELECT * FROM (%s) LIMIT 0", query))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, len(types))
+	for i, t := range types {
+		columns[i] = strings.TrimSpace(fmt.Sprintf("%s %s", sanitize(t.Name()), t.DatabaseTypeName()))
+	}
+	return columns, nil
+}
+
+func (q sqliteSQLQueries) transformationUpdate(db *sql.DB, tableName string, query string) error {
+	return q.tableFromQuery(db, tableName, query, true)
+}
+
+func (q sqliteSQLQueries) transformationExists() string {
+	return q.getTable()
+}
+
+// castTableItemType converts a value read from SQLite to the Go type of its
+// column. SQLite stores a value as whatever type it's given if it can't be
+// converted to its column's affinity, so values that don't match their
+// column are returned as they are.
+func (q sqliteSQLQueries) castTableItemType(v interface{}, t interface{}) interface{} {
+	if v == nil {
+		return v
+	}
+	switch t {
+	case sqInteger:
+		if i, ok := v.(int64); ok {
+			return int(i)
+		}
+	case sqReal:
+		switch val := v.(type) {
+		case float64:
+			return val
+		case int64:
+			return float64(val)
+		}
+	case sqBoolean:
+		if i, ok := v.(int64); ok {
+			return i != 0
+		}
+	case sqTimestamp:
+		if ts, ok := v.(time.Time); ok {
+			return ts.UTC()
+		}
+	}
+	return v
+}
+
+// getValueColumnType goes by the type a column was declared with, the way
+// SQLite picks a column's affinity. The driver only converts booleans and
+// timestamps whose declared types are named exactly.
+func (q sqliteSQLQueries) getValueColumnType(t *sql.ColumnType) interface{} {
+	declared := strings.ToUpper(t.DatabaseTypeName())
+	switch {
+	case declared == "BOOLEAN":
+		return sqBoolean
+	case declared == "TIMESTAMP" || declared == "DATETIME" || declared == "DATE":
+		return sqTimestamp
+	case strings.Contains(declared, "INT"):
+		return sqInteger
+	case strings.Contains(declared, "CHAR") || strings.Contains(declared, "CLOB") || strings.Contains(declared, "TEXT"):
+		return sqText
+	case strings.Contains(declared, "REAL") || strings.Contains(declared, "FLOA") || strings.Contains(declared, "DOUB"):
+		return sqReal
+	}
+	return sqUntyped
+}
+
+func (q sqliteSQLQueries) numRows(n interface{}) (int64, error) {
+	if i, ok := n.(int64); ok {
+		return i, nil
+	}
+	return 0, fmt.Errorf("not an integer: %v", n)
+}
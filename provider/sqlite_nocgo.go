@@ -0,0 +1,18 @@
+//go:build !cgo
+// +build !cgo
+
+package provider
+
+import (
+	"fmt"
+
+	pc "github.com/featureform/provider/provider_config"
+)
+
+// SQLiteSupported is false without cgo, which the SQLite driver needs. The
+// coordinator and runner images are built without it.
+const SQLiteSupported = false
+
+func sqliteOfflineStoreFactory(config pc.SerializedConfig) (Provider, error) {
+	return nil, fmt.Errorf("sqlite offline store is not supported by builds without cgo")
+}
@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ResourceTags record who a resource belongs to, so that the cost of the
+// tables created for it can be allocated to them.
+type ResourceTags struct {
+	Owner string
+	Team  string
+}
+
+// TaggableOfflineStore is implemented by offline stores that can tag the table
+// a resource is stored in with its ID and tags, using whatever the backend
+// supports, such as table comments or labels.
+type TaggableOfflineStore interface {
+	OfflineStore
+	TagTable(id ResourceID, tags ResourceTags) error
+}
+
+// tableTags returns the tags set on a resource's table. Owner and team are
+// left out if they're empty.
+func tableTags(id ResourceID, tags ResourceTags) map[string]string {
+	tagMap := map[string]string{
+		"featureform_resource": fmt.Sprintf("%s__%s__%s", id.Type, id.Name, id.Variant),
+	}
+	if tags.Owner != "" {
+		tagMap["featureform_owner"] = tags.Owner
+	}
+	if tags.Team != "" {
+		tagMap["featureform_team"] = tags.Team
+	}
+	return tagMap
+}
+
+// Longest value BigQuery allows for a label
+const maxLabelValueLength = 63
+
+// labelValue converts a tag to a valid BigQuery label value, which may only
+// contain lowercase letters, digits, underscores and dashes.
+func labelValue(tag string) string {
+	value := strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-') {
+			return '_'
+		}
+		return r
+	}, tag)
+	if len(value) > maxLabelValueLength {
+		value = value[:maxLabelValueLength]
+	}
+	return value
+}
@@ -0,0 +1,23 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLabelValue(t *testing.T) {
+	cases := map[string]string{
+		"data-platform":         "data-platform",
+		"Owner@Featureform.com": "owner_featureform_com",
+		"Primary__name__v1":     "primary__name__v1",
+		"café":                  "caf_",
+	}
+	for tag, expected := range cases {
+		if actual := labelValue(tag); actual != expected {
+			t.Errorf("labelValue(%q): expected %q, got %q", tag, expected, actual)
+		}
+	}
+	if actual := labelValue(strings.Repeat("a", 100)); len(actual) != maxLabelValueLength {
+		t.Errorf("expected label value to be truncated to %d characters, got %d", maxLabelValueLength, len(actual))
+	}
+}
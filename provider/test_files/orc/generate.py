@@ -0,0 +1,44 @@
+# This Source Code Form is subject to the terms of the Mozilla Public
+# License, v. 2.0. If a copy of the MPL was not distributed with this
+# file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+# Writes the ORC files TestORCFixtures reads, one per compression codec, with
+# the same rows as testORCRecords in provider/orc_test.go. Run it from this
+# directory with pyarrow installed:
+#
+#   python generate.py
+
+from datetime import datetime, timedelta
+
+import pyarrow as pa
+import pyarrow.orc as orc
+
+NUM_ROWS = 600
+
+ids, names, points, scores, registered, created = [], [], [], [], [], []
+for i in range(NUM_ROWS):
+    ids.append(i)
+    # The second row's values are all null, other than its ID
+    if i == 1:
+        for column in (names, points, scores, registered, created):
+            column.append(None)
+        continue
+    names.append(f"name {i}")
+    points.append(i + 0.1)
+    scores.append(i + 0.1)
+    registered.append(i % 3 == 0)
+    created.append(datetime(1970, 1, 1) + timedelta(milliseconds=i))
+
+table = pa.table(
+    {
+        "ID": pa.array(ids, pa.int64()),
+        "Name": pa.array(names, pa.string()),
+        "Points": pa.array(points, pa.float32()),
+        "Score": pa.array(scores, pa.float64()),
+        "Registered": pa.array(registered, pa.bool_()),
+        "Created": pa.array(created, pa.timestamp("ms", tz="UTC")),
+    }
+)
+
+for compression in ("uncompressed", "zlib", "snappy", "lz4", "zstd"):
+    orc.write_table(table, f"{compression}.orc", compression=compression)
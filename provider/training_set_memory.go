@@ -0,0 +1,223 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	cfg "github.com/featureform/config"
+	"github.com/featureform/filestore"
+)
+
+// InMemoryTrainingSetStore is an OfflineStore that can also build a training set
+// by joining its label and features in this process, rather than running a job.
+// It's meant for training sets whose sources are small enough to read into
+// memory, where starting the job takes far longer than the join itself.
+type InMemoryTrainingSetStore interface {
+	OfflineStore
+	// TrainingSetSourceRows returns the total number of rows in the sources of
+	// a training set's label and features.
+	TrainingSetSourceRows(def TrainingSetDef) (int64, error)
+	// CreateTrainingSetInMemory creates the same training set as
+	// CreateTrainingSet. Training sets with lag features aren't supported.
+	CreateTrainingSetInMemory(def TrainingSetDef) error
+}
+
+func (k8s *K8sOfflineStore) TrainingSetSourceRows(def TrainingSetDef) (int64, error) {
+	ids := append([]ResourceID{def.Label}, def.Features...)
+	files := make([]filestore.Filepath, len(ids))
+	for i, id := range ids {
+		schema, err := k8s.registeredResourceSchema(id)
+		if err != nil {
+			return 0, fmt.Errorf("could not get schema of %s: %w", id, err)
+		}
+		file, err := k8s.newestSourceFile(schema)
+		if err != nil {
+			return 0, err
+		}
+		files[i] = file
+	}
+	return NumRowsOfFiles(k8s.store, files, cfg.GetNumRowsParallelism())
+}
+
+func (k8s *K8sOfflineStore) CreateTrainingSetInMemory(def TrainingSetDef) error {
+	if err := def.check(); err != nil {
+		return err
+	}
+	if len(def.LagFeatures) > 0 {
+		return fmt.Errorf("in memory training sets don't support lag features: %v", def.ID)
+	}
+	destinationPath, err := k8s.store.CreateFilePath(fileStoreResourcePath(def.ID))
+	if err != nil {
+		return fmt.Errorf("could not create file path: %w", err)
+	}
+	trainingSetExactPath, err := k8s.store.NewestFileOfType(destinationPath, filestore.Parquet)
+	if err != nil {
+		return fmt.Errorf("could not get training set path: %v", err)
+	}
+	trainingSetExists, err := k8s.store.Exists(trainingSetExactPath)
+	if err != nil {
+		return fmt.Errorf("error checking if training set exists: %v", err)
+	}
+	if trainingSetExists {
+		return fmt.Errorf("k8s training set already exists: %v", def.ID)
+	}
+	labelRecs, err := k8s.readSourceRecords(def.Label)
+	if err != nil {
+		return fmt.Errorf("could not read label %s: %w", def.Label, err)
+	}
+	features := make([]*memoryOfflineTable, len(def.Features))
+	for i, id := range def.Features {
+		recs, err := k8s.readSourceRecords(id)
+		if err != nil {
+			return fmt.Errorf("could not read feature %s: %w", id, err)
+		}
+		features[i] = newMemoryOfflineTable()
+		if err := features[i].WriteBatch(recs); err != nil {
+			return fmt.Errorf("could not load feature %s: %w", id, err)
+		}
+	}
+	rows := joinTrainingRows(trainingSetLabels(labelRecs), features, def.JoinPolicy, def.JoinStrategy)
+	content, err := trainingRowsToParquet(def, rows, def.ParquetConfig.WithDefaults(k8s.parquetConfig))
+	if err != nil {
+		return err
+	}
+	outputPath, err := k8s.store.CreateFilePath(fmt.Sprintf("%s/%s/part-00000.parquet", fileStoreResourcePath(def.ID), time.Now().Format("2006-01-02-15-04-05-999999")))
+	if err != nil {
+		return fmt.Errorf("could not create file path: %w", err)
+	}
+	k8s.logger.Debugw("Writing in memory training set", "id", def.ID, "rows", len(rows), "path", outputPath.ToURI())
+	if err := k8s.store.Write(outputPath, content); err != nil {
+		return fmt.Errorf("could not write training set %v: %w", def.ID, err)
+	}
+	return nil
+}
+
+// newestSourceFile returns the file a training set job would read a resource's
+// source from
+func (k8s *K8sOfflineStore) newestSourceFile(schema ResourceSchema) (filestore.Filepath, error) {
+	path, err := k8s.store.CreateFilePath(schema.SourceTable)
+	if err != nil {
+		return nil, fmt.Errorf("could not create file path: %w", err)
+	}
+	file, err := k8s.store.NewestFileOfType(path, path.Ext())
+	if err != nil {
+		return nil, fmt.Errorf("could not get latest source file of %s: %v", schema.SourceTable, err)
+	}
+	return file, nil
+}
+
+// readSourceRecords reads every row of a registered label or feature's source
+func (k8s *K8sOfflineStore) readSourceRecords(id ResourceID) ([]ResourceRecord, error) {
+	schema, err := k8s.registeredResourceSchema(id)
+	if err != nil {
+		return nil, err
+	}
+	file, err := k8s.newestSourceFile(schema)
+	if err != nil {
+		return nil, err
+	}
+	iter, err := k8s.store.Serve([]filestore.Filepath{file})
+	if err != nil {
+		return nil, fmt.Errorf("could not serve source: %w", err)
+	}
+	recs := make([]ResourceRecord, 0)
+	for {
+		row, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			return recs, nil
+		}
+		entity, ok := row[schema.Entity].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected entity column %s to be a string, but got %T", schema.Entity, row[schema.Entity])
+		}
+		rec := ResourceRecord{Entity: entity, Value: row[schema.Value]}
+		// Rows without a timestamp column are left with a zero timestamp, which
+		// checkTimestamp sets to the same epoch a training set job uses.
+		if schema.TS != "" {
+			ts, ok := row[schema.TS].(time.Time)
+			if !ok {
+				return nil, fmt.Errorf("expected timestamp column %s to be of type time.Time, but got %T", schema.TS, row[schema.TS])
+			}
+			rec.TS = ts
+		}
+		recs = append(recs, checkTimestamp(rec))
+	}
+}
+
+// trainingSetLabels orders label records by timestamp and drops exact
+// duplicates, as the query a training set job runs does.
+func trainingSetLabels(recs []ResourceRecord) []ResourceRecord {
+	sort.SliceStable(recs, func(i, j int) bool {
+		return recs[i].TS.Before(recs[j].TS)
+	})
+	type labelKey struct {
+		entity string
+		value  string
+		ts     time.Time
+	}
+	seen := make(map[labelKey]bool, len(recs))
+	labels := make([]ResourceRecord, 0, len(recs))
+	for _, rec := range recs {
+		key := labelKey{rec.Entity, fmt.Sprintf("%v", rec.Value), rec.TS}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		labels = append(labels, rec)
+	}
+	return labels
+}
+
+// trainingRowsToParquet encodes training rows with the same columns a training
+// set job writes, which FileStoreTrainingSet reads features and labels from.
+func trainingRowsToParquet(def TrainingSetDef, rows trainingRows, config ParquetWriteConfig) ([]byte, error) {
+	ids := append(append([]ResourceID{}, def.Features...), def.Label)
+	records := make([]GenericRecord, len(rows))
+	for i, row := range rows {
+		records[i] = append(append(GenericRecord{}, row.Features...), row.Label)
+	}
+	schema := TableSchema{Columns: make([]TableColumn, len(ids))}
+	for i, id := range ids {
+		schema.Columns[i] = TableColumn{
+			Name:      fmt.Sprintf("%s__%s__%s", id.Type, id.Name, id.Variant),
+			ValueType: columnScalarType(records, i),
+		}
+	}
+	content, err := schema.ToParquetBytes(records, config)
+	if err != nil {
+		return nil, fmt.Errorf("could not write training set %v: %w", def.ID, err)
+	}
+	return content, nil
+}
+
+// columnScalarType returns the type of the first non-null value in a column,
+// or String if every value is null
+func columnScalarType(records []GenericRecord, col int) ScalarType {
+	for _, record := range records {
+		switch record[col].(type) {
+		case nil:
+			continue
+		case int:
+			return Int
+		case int32:
+			return Int32
+		case int64:
+			return Int64
+		case float32:
+			return Float32
+		case float64:
+			return Float64
+		case bool:
+			return Bool
+		case time.Time:
+			return Timestamp
+		default:
+			return String
+		}
+	}
+	return String
+}
@@ -0,0 +1,167 @@
+//go:build k8s
+// +build k8s
+
+package provider
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	pc "github.com/featureform/provider/provider_config"
+	"github.com/google/uuid"
+	"go.uber.org/zap/zaptest"
+)
+
+func newLocalK8sOfflineStore(tb testing.TB) *K8sOfflineStore {
+	logger := zaptest.NewLogger(tb).Sugar()
+	executorConfig := LocalExecutorConfig{
+		ScriptPath: "./scripts/k8s/offline_store_pandas_runner.py",
+	}
+	serializedExecutor, err := executorConfig.Serialize()
+	if err != nil {
+		tb.Fatalf("could not serialize local executor config: %v", err)
+	}
+	executor, err := NewLocalExecutor(Config(serializedExecutor), logger)
+	if err != nil {
+		tb.Fatalf("could not create local executor: %v", err)
+	}
+	storeConfig := pc.LocalFileStoreConfig{DirPath: fmt.Sprintf("file:///%s", tb.TempDir())}
+	serializedStore, err := storeConfig.Serialize()
+	if err != nil {
+		tb.Fatalf("could not serialize file store config: %v", err)
+	}
+	store, err := NewLocalFileStore(serializedStore)
+	if err != nil {
+		tb.Fatalf("could not create local file store: %v", err)
+	}
+	return &K8sOfflineStore{
+		executor: executor,
+		store:    store,
+		logger:   logger,
+		query:    &pandasOfflineQueries{},
+		BaseProvider: BaseProvider{
+			ProviderType: "K8S_OFFLINE",
+		},
+	}
+}
+
+// registerTrainingSetSources writes a label source and two feature sources of
+// numEntities entities each, registers them, and returns a training set
+// definition over them. Every row has a distinct timestamp, so both ways of
+// building the training set agree on which feature value each label gets.
+func registerTrainingSetSources(tb testing.TB, k8s *K8sOfflineStore, numEntities int) TrainingSetDef {
+	schema := TableSchema{
+		Columns: []TableColumn{
+			{Name: "entity", ValueType: String},
+			{Name: "value", ValueType: Int},
+			{Name: "ts", ValueType: Timestamp},
+		},
+	}
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	register := func(resType OfflineResourceType, offset int) ResourceID {
+		records := make([]GenericRecord, 0, numEntities*2)
+		for i := 0; i < numEntities; i++ {
+			for version := 0; version < 2; version++ {
+				ts := start.Add(time.Duration(version*numEntities*3+i*3+offset) * time.Minute)
+				records = append(records, GenericRecord{fmt.Sprintf("entity_%d", i), i*10 + version + offset, ts})
+			}
+		}
+		content, err := schema.ToParquetBytes(records, ParquetWriteConfig{})
+		if err != nil {
+			tb.Fatalf("could not write source: %v", err)
+		}
+		id := ResourceID{Name: uuidWithoutDashes(), Variant: "v1", Type: resType}
+		key := fmt.Sprintf("sources/%s.parquet", id.Name)
+		path, err := k8s.store.CreateFilePath(key)
+		if err != nil {
+			tb.Fatalf("could not create file path: %v", err)
+		}
+		if err := k8s.store.Write(path, content); err != nil {
+			tb.Fatalf("could not write source: %v", err)
+		}
+		resourceSchema := ResourceSchema{Entity: "entity", Value: "value", TS: "ts", SourceTable: key}
+		if _, err := k8s.RegisterResourceFromSourceTable(id, resourceSchema); err != nil {
+			tb.Fatalf("could not register %s: %v", id, err)
+		}
+		return id
+	}
+	return TrainingSetDef{
+		Label:    register(Label, 2),
+		Features: []ResourceID{register(Feature, 0), register(Feature, 1)},
+	}
+}
+
+func readTrainingRows(tb testing.TB, store OfflineStore, id ResourceID) []trainingRow {
+	iter, err := store.GetTrainingSet(id)
+	if err != nil {
+		tb.Fatalf("could not get training set: %v", err)
+	}
+	rows := make([]trainingRow, 0)
+	for iter.Next() {
+		rows = append(rows, trainingRow{Features: iter.Features(), Label: iter.Label()})
+	}
+	if err := iter.Err(); err != nil {
+		tb.Fatalf("could not iterate training set: %v", err)
+	}
+	return rows
+}
+
+// The in memory training set can't match the job's output byte for byte, since
+// the two are written by different parquet writers, so this compares the rows
+// each one reads back as instead.
+func TestCreateTrainingSetInMemory(t *testing.T) {
+	k8s := newLocalK8sOfflineStore(t)
+	def := registerTrainingSetSources(t, k8s, 10)
+
+	sourceRows, err := k8s.TrainingSetSourceRows(def)
+	if err != nil {
+		t.Fatalf("could not count source rows: %v", err)
+	}
+	if sourceRows != 60 {
+		t.Fatalf("expected 60 source rows, got %d", sourceRows)
+	}
+
+	jobDef := def
+	jobDef.ID = ResourceID{Name: uuidWithoutDashes(), Variant: "job", Type: TrainingSet}
+	if err := k8s.CreateTrainingSet(jobDef); err != nil {
+		t.Fatalf("could not create training set: %v", err)
+	}
+	memoryDef := def
+	memoryDef.ID = ResourceID{Name: uuidWithoutDashes(), Variant: "memory", Type: TrainingSet}
+	if err := k8s.CreateTrainingSetInMemory(memoryDef); err != nil {
+		t.Fatalf("could not create training set in memory: %v", err)
+	}
+	if err := k8s.CreateTrainingSetInMemory(memoryDef); err == nil {
+		t.Fatalf("expected creating an existing training set to fail")
+	}
+
+	jobRows := readTrainingRows(t, k8s, jobDef.ID)
+	memoryRows := readTrainingRows(t, k8s, memoryDef.ID)
+	if len(jobRows) != 20 {
+		t.Fatalf("expected 20 training rows, got %d", len(jobRows))
+	}
+	if !reflect.DeepEqual(jobRows, memoryRows) {
+		t.Fatalf("expected in memory training set to match the job's\njob:    %v\nmemory: %v", jobRows, memoryRows)
+	}
+}
+
+func BenchmarkCreateTrainingSet(b *testing.B) {
+	k8s := newLocalK8sOfflineStore(b)
+	def := registerTrainingSetSources(b, k8s, 100)
+	create := map[string]func(TrainingSetDef) error{
+		"job":      k8s.CreateTrainingSet,
+		"inMemory": k8s.CreateTrainingSetInMemory,
+	}
+	for name, createFn := range create {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				def.ID = ResourceID{Name: uuid.NewString(), Variant: name, Type: TrainingSet}
+				if err := createFn(def); err != nil {
+					b.Fatalf("could not create training set: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -7,7 +7,9 @@ package provider
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"reflect"
+	"strconv"
 	"time"
 )
 
@@ -30,6 +32,161 @@ func (t VectorType) IsVector() bool {
 	return true
 }
 
+// vector32 returns value as a []float32 with the type's dimension. Vectors of
+// float64, as Python clients send, are narrowed. A dimension of 0 accepts
+// vectors of any length.
+func (t VectorType) vector32(value interface{}) ([]float32, error) {
+	var vector []float32
+	switch v := value.(type) {
+	case []float32:
+		vector = v
+	case []float64:
+		vector = make([]float32, len(v))
+		for i, element := range v {
+			vector[i] = float32(element)
+		}
+	default:
+		return nil, fmt.Errorf("cannot use %T as a vector", value)
+	}
+	if t.Dimension != 0 && int32(len(vector)) != t.Dimension {
+		return nil, fmt.Errorf("expected a vector of dimension %d, got %d", t.Dimension, len(vector))
+	}
+	return vector, nil
+}
+
+// DecimalType is a fixed-precision number, such as an amount of money, that's
+// stored exactly rather than as a float. Values are written from *big.Rat,
+// strings such as "12.34", integers or floats, and read back as *big.Rat.
+type DecimalType struct {
+	// Total number of digits, from 1 to maxDecimalPrecision
+	Precision int32
+	// Number of those digits after the decimal point
+	Scale int32
+}
+
+// The largest precision Spark and most warehouses support
+const maxDecimalPrecision = 38
+
+func (t DecimalType) Scalar() ScalarType {
+	return Decimal
+}
+
+func (t DecimalType) IsVector() bool {
+	return false
+}
+
+func (t DecimalType) validate() error {
+	if t.Precision < 1 || t.Precision > maxDecimalPrecision {
+		return fmt.Errorf("decimal precision must be between 1 and %d, got %d", maxDecimalPrecision, t.Precision)
+	}
+	if t.Scale < 0 || t.Scale > t.Precision {
+		return fmt.Errorf("decimal scale must be between 0 and the precision %d, got %d", t.Precision, t.Scale)
+	}
+	return nil
+}
+
+// parquetType is the Go type a decimal column is written to parquet from. Small
+// precisions fit in the int32 and int64 physical types, and larger ones are
+// written as big-endian two's complement bytes.
+func (t DecimalType) parquetType() reflect.Type {
+	switch {
+	case t.Precision <= 9:
+		return reflect.PointerTo(reflect.TypeOf(int32(0)))
+	case t.Precision <= 18:
+		return reflect.PointerTo(reflect.TypeOf(int64(0)))
+	default:
+		return reflect.TypeOf([]byte(nil))
+	}
+}
+
+// unscaled returns value as a whole number of units of its last decimal place,
+// such as 1234 for 12.34 at a scale of 2. Values aren't rounded, so one with
+// more decimal places than the scale or more digits than the precision is an
+// error.
+func (t DecimalType) unscaled(value interface{}) (*big.Int, error) {
+	rat := new(big.Rat)
+	switch v := value.(type) {
+	case *big.Rat:
+		rat.Set(v)
+	case string:
+		if _, ok := rat.SetString(v); !ok {
+			return nil, fmt.Errorf("%q is not a decimal number", v)
+		}
+	case int:
+		rat.SetInt64(int64(v))
+	case int32:
+		rat.SetInt64(int64(v))
+	case int64:
+		rat.SetInt64(v)
+	case float32:
+		// The shortest representation of a float is the decimal it was meant
+		// to be, such as 0.1 rather than 0.100000001490116
+		rat.SetString(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	case float64:
+		rat.SetString(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return nil, fmt.Errorf("cannot convert %T to a decimal", value)
+	}
+	scaled := new(big.Rat).Mul(rat, new(big.Rat).SetInt(t.scaleFactor()))
+	if !scaled.IsInt() {
+		return nil, fmt.Errorf("%s has more than %d decimal places", rat.RatString(), t.Scale)
+	}
+	unscaled := scaled.Num()
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Precision)), nil)
+	if new(big.Int).Abs(unscaled).Cmp(limit) >= 0 {
+		return nil, fmt.Errorf("%s has more than %d digits", rat.FloatString(int(t.Scale)), t.Precision)
+	}
+	return unscaled, nil
+}
+
+func (t DecimalType) scaleFactor() *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Scale)), nil)
+}
+
+// toParquet returns value as the type parquetType says it's written from
+func (t DecimalType) toParquet(value interface{}) (reflect.Value, error) {
+	unscaled, err := t.unscaled(value)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	switch {
+	case t.Precision <= 9:
+		v := int32(unscaled.Int64())
+		return reflect.ValueOf(&v), nil
+	case t.Precision <= 18:
+		v := unscaled.Int64()
+		return reflect.ValueOf(&v), nil
+	default:
+		// One byte more than the magnitude needs leaves room for the sign bit
+		size := unscaled.BitLen()/8 + 1
+		if unscaled.Sign() < 0 {
+			unscaled = new(big.Int).Add(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*size)))
+		}
+		return reflect.ValueOf(unscaled.FillBytes(make([]byte, size))), nil
+	}
+}
+
+// fromParquet reads a value of a decimal column as parquet-go returns it
+func (t DecimalType) fromParquet(value interface{}) (*big.Rat, error) {
+	unscaled := new(big.Int)
+	switch v := value.(type) {
+	case int32:
+		unscaled.SetInt64(int64(v))
+	case int64:
+		unscaled.SetInt64(v)
+	case []byte:
+		unscaled.SetBytes(v)
+		if len(v) > 0 && v[0]&0x80 != 0 {
+			unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*len(v))))
+		}
+	case string:
+		return t.fromParquet([]byte(v))
+	default:
+		return nil, fmt.Errorf("cannot read %T as a decimal", value)
+	}
+	return new(big.Rat).SetFrac(unscaled, t.scaleFactor()), nil
+}
+
 type ScalarType string
 
 func (t ScalarType) Scalar() ScalarType {
@@ -56,6 +213,12 @@ func (t ScalarType) Type() reflect.Type {
 		return reflect.PointerTo(reflect.TypeOf(float64(0)))
 	case String:
 		return reflect.PointerTo(reflect.TypeOf(string("")))
+	case Bytes:
+		// A nil slice is already written as null, so no pointer is needed
+		return reflect.TypeOf([]byte(nil))
+	case JSON:
+		// Written as the JSON text, annotated as JSON
+		return reflect.PointerTo(reflect.TypeOf(string("")))
 	case Bool:
 		return reflect.PointerTo(reflect.TypeOf(bool(false)))
 	case Timestamp:
@@ -78,6 +241,16 @@ const (
 	Bool      ScalarType = "bool"
 	Timestamp ScalarType = "time.Time"
 	Datetime  ScalarType = "datetime"
+	// Bytes holds binary data. It's written to parquet without the UTF8
+	// annotation that strings have, and read back as []byte.
+	Bytes ScalarType = "bytes"
+	// Decimal is the scalar type of every DecimalType, whatever its precision
+	// and scale
+	Decimal ScalarType = "decimal"
+	// JSON holds semi-structured values, such as nested objects and arrays.
+	// They're stored as JSON text and read back as map[string]interface{},
+	// []interface{} or whichever JSON scalar they are.
+	JSON ScalarType = "json"
 )
 
 var ScalarTypes = map[ScalarType]bool{
@@ -88,16 +261,58 @@ var ScalarTypes = map[ScalarType]bool{
 	Float32:   true,
 	Float64:   true,
 	String:    true,
+	Bytes:     true,
 	Bool:      true,
 	Timestamp: true,
 	Datetime:  true,
+	Decimal:   true,
+	JSON:      true,
+}
+
+// jsonText encodes a value of a JSON column as the text it's stored as. A
+// []byte is taken to be JSON text already; anything else, including strings,
+// is marshalled, so that values are written the same way they're read back.
+func jsonText(value interface{}) (string, error) {
+	if raw, isRaw := value.([]byte); isRaw {
+		if !json.Valid(raw) {
+			return "", fmt.Errorf("invalid JSON: %q", raw)
+		}
+		return string(raw), nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("cannot write %T as JSON: %w", value, err)
+	}
+	return string(b), nil
+}
+
+// parseJSON decodes the text of a value of a JSON column
+func parseJSON(text []byte) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(text, &value); err != nil {
+		return nil, fmt.Errorf("cannot parse JSON: %w", err)
+	}
+	return value, nil
 }
 
 type ValueTypeJSONWrapper struct {
 	ValueType
 }
 
+// decimalTypeJSON tags a DecimalType with its scalar type, which tells it apart
+// from a VectorType when it's deserialized
+type decimalTypeJSON struct {
+	ScalarType ScalarType
+	DecimalType
+}
+
 func (vt *ValueTypeJSONWrapper) UnmarshalJSON(data []byte) error {
+	d := map[string]decimalTypeJSON{"ValueType": {}}
+	if err := json.Unmarshal(data, &d); err == nil && d["ValueType"].ScalarType == Decimal {
+		vt.ValueType = d["ValueType"].DecimalType
+		return nil
+	}
+
 	v := map[string]VectorType{"ValueType": {}}
 	if err := json.Unmarshal(data, &v); err == nil {
 		vt.ValueType = v["ValueType"]
@@ -117,6 +332,8 @@ func (vt ValueTypeJSONWrapper) MarshalJSON() ([]byte, error) {
 	switch vt.ValueType.(type) {
 	case VectorType:
 		return json.Marshal(map[string]VectorType{"ValueType": vt.ValueType.(VectorType)})
+	case DecimalType:
+		return json.Marshal(map[string]decimalTypeJSON{"ValueType": {ScalarType: Decimal, DecimalType: vt.ValueType.(DecimalType)}})
 	case ScalarType:
 		return json.Marshal(map[string]ScalarType{"ValueType": vt.ValueType.(ScalarType)})
 	default:
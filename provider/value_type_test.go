@@ -2,6 +2,8 @@ package provider
 
 import (
 	"encoding/json"
+	"math/big"
+	"reflect"
 	"testing"
 )
 
@@ -69,3 +71,89 @@ func TestVectorTypeMarshaling(t *testing.T) {
 		}
 	}
 }
+
+func TestDecimalTypeJSON(t *testing.T) {
+	wrapped := ValueTypeJSONWrapper{ValueType: DecimalType{Precision: 12, Scale: 2}}
+	serialized, err := json.Marshal(wrapped)
+	if err != nil {
+		t.Fatalf("failed to marshal decimal type: %v", err)
+	}
+	expected := `{"ValueType":{"ScalarType":"decimal","Precision":12,"Scale":2}}`
+	if string(serialized) != expected {
+		t.Fatalf("expected %s, got %s", expected, serialized)
+	}
+	vt := ValueTypeJSONWrapper{}
+	if err := vt.UnmarshalJSON(serialized); err != nil {
+		t.Fatalf("failed to unmarshal decimal type: %v", err)
+	}
+	if vt.ValueType != wrapped.ValueType {
+		t.Fatalf("expected %v, got %v", wrapped.ValueType, vt.ValueType)
+	}
+}
+
+func TestDecimalTypeUnscaled(t *testing.T) {
+	decimal := DecimalType{Precision: 5, Scale: 2}
+	cases := []struct {
+		value     interface{}
+		expected  int64
+		expectErr bool
+	}{
+		{"123.45", 12345, false},
+		{"-0.5", -50, false},
+		{big.NewRat(1, 4), 25, false},
+		{7, 700, false},
+		{0.1, 10, false},
+		{"1.005", 0, true},
+		{"1000", 0, true},
+		{true, 0, true},
+	}
+	for _, c := range cases {
+		unscaled, err := decimal.unscaled(c.value)
+		if c.expectErr {
+			if err == nil {
+				t.Errorf("expected %v to fail, got %v", c.value, unscaled)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("could not convert %v: %v", c.value, err)
+			continue
+		}
+		if unscaled.Int64() != c.expected {
+			t.Errorf("expected %v to be %d, got %v", c.value, c.expected, unscaled)
+		}
+	}
+}
+
+func TestVectorTypeVector32(t *testing.T) {
+	vectorType := VectorType{ScalarType: Float32, Dimension: 3}
+	cases := []struct {
+		value     interface{}
+		expected  []float32
+		expectErr bool
+	}{
+		{[]float32{1, 2, 3}, []float32{1, 2, 3}, false},
+		{[]float64{0.5, 1.5, 2.5}, []float32{0.5, 1.5, 2.5}, false},
+		{[]float32{1, 2}, nil, true},
+		{"1,2,3", nil, true},
+	}
+	for _, c := range cases {
+		vector, err := vectorType.vector32(c.value)
+		if c.expectErr {
+			if err == nil {
+				t.Errorf("expected %v to fail, got %v", c.value, vector)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("could not convert %v: %v", c.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(vector, c.expected) {
+			t.Errorf("expected %v to be %v, got %v", c.value, c.expected, vector)
+		}
+	}
+	if _, err := (VectorType{ScalarType: Float32}).vector32([]float32{1, 2}); err != nil {
+		t.Errorf("expected a vector without a dimension to accept any length: %v", err)
+	}
+}
@@ -6,9 +6,14 @@ package runner
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	cfg "github.com/featureform/config"
 	"github.com/featureform/metadata"
 	"github.com/featureform/provider"
 	pc "github.com/featureform/provider/provider_config"
@@ -22,12 +27,93 @@ type IndexRunner interface {
 	SetIndex(index int) error
 }
 
+// ErrJobCancelled is returned by the watchers of jobs that stopped because they
+// were cancelled
+var ErrJobCancelled = errors.New("job cancelled")
+
+// CancellableRunner is implemented by runners that can stop while they run.
+// Once cancel is closed, the job stops and its watcher returns ErrJobCancelled.
+type CancellableRunner interface {
+	types.Runner
+	SetCancel(cancel <-chan struct{})
+}
+
+// isCancelled reports whether cancel has been closed. A nil cancel never is.
+func isCancelled(cancel <-chan struct{}) bool {
+	select {
+	case <-cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// PooledRunner is implemented by runners that can share a pool of workers with
+// other runners, so that no more of them run at once than the pool's capacity.
+// A runner takes a worker by sending to the pool, and returns it by receiving.
+type PooledRunner interface {
+	types.Runner
+	SetPool(pool chan struct{})
+}
+
+// ChunkOrder determines which rows of a materialization each chunk copies to
+// the online store. It doesn't change how many chunks run or how they're
+// scheduled.
+type ChunkOrder string
+
+const (
+	// RowChunkOrder gives each chunk a contiguous range of rows, in the order
+	// the offline store returns them, and is the default.
+	RowChunkOrder ChunkOrder = ""
+	// EntityHashChunkOrder assigns each entity to a chunk by a hash of its name,
+	// so an entity is always written by the same chunk index, regardless of the
+	// order the offline store returns rows in. Every chunk reads the whole
+	// materialization and skips the rows of other chunks' entities.
+	EntityHashChunkOrder ChunkOrder = "entity_hash"
+)
+
 type MaterializedChunkRunner struct {
-	Materialized provider.Materialization
-	Table        provider.OnlineStoreTable
-	Store        provider.OnlineStore
-	ChunkSize    int64
-	ChunkIdx     int64
+	Materialized  provider.Materialization
+	Table         provider.OnlineStoreTable
+	Store         provider.OnlineStore
+	ChunkSize     int64
+	ChunkIdx      int64
+	MergeStrategy provider.MergeStrategy
+	// The most rows read from the offline store that can be waiting to be
+	// written to the online store. Reads block once it's reached, so a slow
+	// online store doesn't cause rows to pile up in memory. Defaults to
+	// config.MaterializeBufferSize.
+	BufferSize int
+	ChunkOrder ChunkOrder
+	// Remember what each entity held before the chunk wrote it, so the chunk's
+	// writes can be rolled back if another chunk fails. The online store is
+	// then left open when the chunk finishes, until release is called.
+	TrackWrites bool
+	// Only copy rows with a timestamp after Since, which an earlier incremental
+	// materialization has already written up to. Zero copies every row.
+	Since time.Time
+	// How long each value written stays in the online table. Zero keeps them
+	// until they're overwritten.
+	TTL time.Duration
+	// Rows written to the online table so far, updated atomically
+	rowsWritten int64
+	// The newest timestamp written so far, as a time.Time, if any row with a
+	// timestamp has been written
+	newestTS atomic.Value
+	// The value each entity written held beforehand, if TrackWrites is set
+	previous map[string]previousValue
+	// If TrackWrites is set and the table keeps history, the time every version
+	// the chunk writes is recorded at, so that they can be removed again
+	writtenAt time.Time
+	// Closed if the chunk should stop copying rows
+	cancel <-chan struct{}
+	// Where the chunk takes a worker from before copying, if anywhere
+	pool chan struct{}
+}
+
+type previousValue struct {
+	value   interface{}
+	existed bool
 }
 
 type ResultSync struct {
@@ -51,6 +137,15 @@ func (m *MaterializedChunkRunner) Run() (types.CompletionWatcher, error) {
 		DoneChannel: done,
 	}
 	go func() {
+		if m.pool != nil {
+			select {
+			case m.pool <- struct{}{}:
+				defer func() { <-m.pool }()
+			case <-m.cancel:
+				jobWatcher.EndWatch(ErrJobCancelled)
+				return
+			}
+		}
 		if m.ChunkSize == 0 {
 			jobWatcher.EndWatch(nil)
 			return
@@ -65,42 +160,298 @@ func (m *MaterializedChunkRunner) Run() (types.CompletionWatcher, error) {
 			return
 		}
 
-		rowStart := m.ChunkIdx * m.ChunkSize
-		rowEnd := rowStart + m.ChunkSize
-		if rowEnd > numRows {
-			rowEnd = numRows
-		}
-		it, err := m.Materialized.IterateSegment(rowStart, rowEnd)
-		if err != nil {
-			jobWatcher.EndWatch(fmt.Errorf("failed to create iterator: %w", err))
+		mergeTable, isMergeable := m.Table.(provider.MergeableOnlineStoreTable)
+		if m.MergeStrategy != provider.OverwriteMerge && !isMergeable {
+			jobWatcher.EndWatch(fmt.Errorf("online table does not support %s merges", m.MergeStrategy))
 			return
 		}
-		i := 0
-		for it.Next() {
-			i += 1
-			value := it.Value().Value
-			entity := it.Value().Entity
-			err := m.Table.Set(entity, value)
-			if err != nil {
-				jobWatcher.EndWatch(fmt.Errorf("could not set table: %w", err))
+		if m.TTL > 0 {
+			// Merged values would have to keep the TTL of the value they
+			// were merged into
+			if m.MergeStrategy != provider.OverwriteMerge {
+				jobWatcher.EndWatch(fmt.Errorf("values with a ttl can't be combined with %s merges", m.MergeStrategy))
+				return
+			}
+			if _, ok := m.Table.(provider.ExpiringOnlineStoreTable); !ok {
+				jobWatcher.EndWatch(fmt.Errorf("online table does not support values with a ttl"))
+				return
+			}
+		}
+		if _, historical := m.Table.(provider.HistoricalOnlineStoreTable); historical && m.TrackWrites {
+			if _, ok := m.Table.(provider.RevertibleHistoricalOnlineStoreTable); !ok {
+				jobWatcher.EndWatch(fmt.Errorf("online table can't roll back the versions it records"))
 				return
 			}
+			m.writtenAt = time.Now()
 		}
-		if err = it.Err(); err != nil {
-			jobWatcher.EndWatch(fmt.Errorf("iteration failed with error: %w", err))
+		it, err := m.chunkIterator(numRows)
+		if err != nil {
+			jobWatcher.EndWatch(err)
+			return
+		}
+		if err := m.copyRows(it, mergeTable); err != nil {
+			jobWatcher.EndWatch(err)
 			return
 		}
 		err = it.Close()
 		if err != nil {
 			jobWatcher.EndWatch(fmt.Errorf("failed to close iterator: %w", err))
 		}
+		if m.TrackWrites {
+			jobWatcher.EndWatch(nil)
+			return
+		}
 		err = m.Store.Close()
 		if err != nil {
 			jobWatcher.EndWatch(fmt.Errorf("failed to close Online Store: %w", err))
 		}
 		jobWatcher.EndWatch(nil)
 	}()
-	return jobWatcher, nil
+	return &chunkWatcher{SyncWatcher: jobWatcher, runner: m}, nil
+}
+
+// chunkWatcher also reports how many rows its chunk has written so far
+type chunkWatcher struct {
+	*SyncWatcher
+	runner *MaterializedChunkRunner
+}
+
+func (w *chunkWatcher) RowsWritten() int64 {
+	return atomic.LoadInt64(&w.runner.rowsWritten)
+}
+
+// Watermark returns the newest timestamp the chunk has written, or the zero
+// time if it hasn't written any rows with one.
+func (w *chunkWatcher) Watermark() time.Time {
+	newest, _ := w.runner.newestTS.Load().(time.Time)
+	return newest
+}
+
+// release closes the online store of a chunk that tracks its writes, once the
+// chunk has finished, first restoring every entity it wrote to its previous
+// value if rollback is set. Entities that didn't exist before are deleted, and
+// the versions a historical table recorded are removed from its history.
+func (w *chunkWatcher) release(rollback bool) error {
+	m := w.runner
+	if !m.TrackWrites {
+		return nil
+	}
+	defer m.Store.Close()
+	if !rollback {
+		return nil
+	}
+	deletable, canDelete := m.Table.(provider.DeletableOnlineStoreTable)
+	for entity, previous := range m.previous {
+		var err error
+		if !m.writtenAt.IsZero() {
+			err = m.Table.(provider.RevertibleHistoricalOnlineStoreTable).DeleteVersionsAt(entity, m.writtenAt)
+		} else if previous.existed {
+			err = m.Table.Set(entity, previous.value)
+		} else if canDelete {
+			err = deletable.Delete(entity)
+		} else {
+			err = fmt.Errorf("online table does not support deleting entities")
+		}
+		if err != nil {
+			return fmt.Errorf("could not roll back %s: %w", entity, err)
+		}
+	}
+	return nil
+}
+
+// chunkIterator returns an iterator over the rows of the materialization that
+// belong to this chunk under its ChunkOrder.
+func (m *MaterializedChunkRunner) chunkIterator(numRows int64) (provider.FeatureIterator, error) {
+	switch m.ChunkOrder {
+	case RowChunkOrder:
+		rowStart := m.ChunkIdx * m.ChunkSize
+		rowEnd := rowStart + m.ChunkSize
+		if rowEnd > numRows {
+			rowEnd = numRows
+		}
+		it, err := m.iterateSegment(rowStart, rowEnd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create iterator: %w", err)
+		}
+		return it, nil
+	case EntityHashChunkOrder:
+		numChunks := numRows / m.ChunkSize
+		if numChunks*m.ChunkSize < numRows {
+			numChunks++
+		}
+		it, err := m.iterateSegment(0, numRows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create iterator: %w", err)
+		}
+		return &entityHashIterator{FeatureIterator: it, numChunks: numChunks, chunkIdx: m.ChunkIdx}, nil
+	default:
+		return nil, fmt.Errorf("unknown chunk order: %s", m.ChunkOrder)
+	}
+}
+
+// iterateSegment reads a segment of the materialization. If Since is set, the
+// rows that aren't newer than it are skipped by the offline store if it can, and
+// by a sinceIterator otherwise.
+func (m *MaterializedChunkRunner) iterateSegment(begin, end int64) (provider.FeatureIterator, error) {
+	if m.Since.IsZero() {
+		return m.Materialized.IterateSegment(begin, end)
+	}
+	if filtered, ok := m.Materialized.(provider.SinceMaterialization); ok {
+		return filtered.IterateSegmentSince(begin, end, m.Since)
+	}
+	it, err := m.Materialized.IterateSegment(begin, end)
+	if err != nil {
+		return nil, err
+	}
+	return &sinceIterator{FeatureIterator: it, since: m.Since}, nil
+}
+
+// sinceIterator skips the rows that aren't newer than since. Rows without a
+// timestamp are never newer.
+type sinceIterator struct {
+	provider.FeatureIterator
+	since time.Time
+}
+
+func (it *sinceIterator) Next() bool {
+	for it.FeatureIterator.Next() {
+		if it.Value().TS.After(it.since) {
+			return true
+		}
+	}
+	return false
+}
+
+// EntityChunk returns the index of the chunk that writes entity when a
+// materialization is split into numChunks chunks by EntityHashChunkOrder.
+func EntityChunk(entity string, numChunks int64) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(entity))
+	return int64(h.Sum32() % uint32(numChunks))
+}
+
+// entityHashIterator skips the rows of entities that belong to other chunks.
+type entityHashIterator struct {
+	provider.FeatureIterator
+	numChunks int64
+	chunkIdx  int64
+}
+
+func (it *entityHashIterator) Next() bool {
+	for it.FeatureIterator.Next() {
+		if EntityChunk(it.Value().Entity, it.numChunks) == it.chunkIdx {
+			return true
+		}
+	}
+	return false
+}
+
+// set writes an entity's value, expiring it after the chunk's TTL if it has
+// one, or recording it at the chunk's writtenAt if it's tracking the writes
+// to a historical table
+func (m *MaterializedChunkRunner) set(entity string, value interface{}) error {
+	if !m.writtenAt.IsZero() {
+		return m.Table.(provider.HistoricalOnlineStoreTable).SetAt(entity, value, m.writtenAt)
+	}
+	if m.TTL > 0 {
+		return m.Table.(provider.ExpiringOnlineStoreTable).SetWithTTL(entity, value, m.TTL)
+	}
+	return m.Table.Set(entity, value)
+}
+
+// copyRows writes the iterator's rows to the online table. Rows are read in a
+// separate goroutine and handed to the writer over a channel of BufferSize rows.
+func (m *MaterializedChunkRunner) copyRows(it provider.FeatureIterator, mergeTable provider.MergeableOnlineStoreTable) error {
+	bufferSize := m.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = cfg.MaterializeBufferSize
+	}
+	records := make(chan provider.ResourceRecord, bufferSize)
+	stop := make(chan struct{})
+	var readErr error
+	go func() {
+		defer close(records)
+		for it.Next() {
+			select {
+			case records <- it.Value():
+			case <-stop:
+				return
+			}
+		}
+		readErr = it.Err()
+	}()
+	var writeErr error
+	for record := range records {
+		select {
+		case <-m.cancel:
+			writeErr = ErrJobCancelled
+		default:
+		}
+		if writeErr != nil {
+			close(stop)
+			break
+		}
+		var previous previousValue
+		var untracked bool
+		if m.TrackWrites {
+			previous, untracked, writeErr = m.lookupPrevious(record.Entity)
+			if writeErr != nil {
+				close(stop)
+				break
+			}
+		}
+		if m.MergeStrategy == provider.OverwriteMerge {
+			writeErr = m.set(record.Entity, record.Value)
+		} else {
+			writeErr = mergeTable.Merge(record.Entity, record.Value, m.MergeStrategy)
+		}
+		if writeErr != nil {
+			close(stop)
+			break
+		}
+		if untracked {
+			m.previous[record.Entity] = previous
+		}
+		atomic.AddInt64(&m.rowsWritten, 1)
+		if newest, _ := m.newestTS.Load().(time.Time); record.TS.After(newest) {
+			m.newestTS.Store(record.TS)
+		}
+	}
+	// Wait for the reader to finish before the iterator is used again
+	for range records {
+	}
+	if writeErr != nil {
+		return fmt.Errorf("could not set table: %w", writeErr)
+	}
+	if readErr != nil {
+		return fmt.Errorf("iteration failed with error: %w", readErr)
+	}
+	return nil
+}
+
+// lookupPrevious returns the value entity holds before the chunk first writes
+// it, and whether it still needs to be recorded because the chunk hasn't
+// written it yet.
+func (m *MaterializedChunkRunner) lookupPrevious(entity string) (previousValue, bool, error) {
+	if m.previous == nil {
+		m.previous = make(map[string]previousValue)
+	}
+	if _, tracked := m.previous[entity]; tracked {
+		return previousValue{}, false, nil
+	}
+	// Versions written to a historical table are removed rather than
+	// overwritten, so its previous value isn't needed
+	if !m.writtenAt.IsZero() {
+		return previousValue{}, true, nil
+	}
+	value, err := m.Table.Get(entity)
+	if _, notFound := err.(*provider.EntityNotFound); notFound {
+		return previousValue{}, true, nil
+	}
+	if err != nil {
+		return previousValue{}, false, fmt.Errorf("could not get previous value of %s: %w", entity, err)
+	}
+	return previousValue{value: value, existed: true}, true, nil
 }
 
 func (m *MaterializedChunkRunner) SetIndex(index int) error {
@@ -108,6 +459,14 @@ func (m *MaterializedChunkRunner) SetIndex(index int) error {
 	return nil
 }
 
+func (m *MaterializedChunkRunner) SetCancel(cancel <-chan struct{}) {
+	m.cancel = cancel
+}
+
+func (m *MaterializedChunkRunner) SetPool(pool chan struct{}) {
+	m.pool = pool
+}
+
 func (c *SyncWatcher) EndWatch(err error) {
 	c.ResultSync.DoneWithError(err)
 	close(c.DoneChannel)
@@ -172,7 +531,16 @@ type MaterializedChunkRunnerConfig struct {
 	ChunkSize      int64
 	ChunkIdx       int64
 	IsUpdate       bool
-	Logger         *zap.SugaredLogger
+	MergeStrategy  provider.MergeStrategy
+	BufferSize     int
+	ChunkOrder     ChunkOrder
+	TrackWrites    bool
+	Since          time.Time
+	TTL            time.Duration
+	// The feature's type, which some materializations need to read its values.
+	// Nil if it isn't known.
+	VType  *provider.ValueTypeJSONWrapper
+	Logger *zap.SugaredLogger
 }
 
 func (m *MaterializedChunkRunnerConfig) Serialize() (Config, error) {
@@ -199,24 +567,27 @@ func MaterializedChunkRunnerFactory(config Config) (types.Runner, error) {
 
 	onlineProvider, err := provider.Get(runnerConfig.OnlineType, runnerConfig.OnlineConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to configure online provider: %v", err)
+		return nil, fmt.Errorf("failed to configure online provider: %w", err)
 	}
 	offlineProvider, err := provider.Get(runnerConfig.OfflineType, runnerConfig.OfflineConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to configure offline provider: %v", err)
+		return nil, fmt.Errorf("failed to configure offline provider: %w", err)
 	}
 	onlineStore, err := onlineProvider.AsOnlineStore()
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert provider to online store: %v", err)
+		return nil, fmt.Errorf("failed to convert provider to online store: %w", err)
 	}
 	offlineStore, err := offlineProvider.AsOfflineStore()
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert provider to offline store: %v", err)
+		return nil, fmt.Errorf("failed to convert provider to offline store: %w", err)
 	}
 	materialization, err := offlineStore.GetMaterialization(runnerConfig.MaterializedID)
 	if err != nil {
 		return nil, fmt.Errorf("cannot get materialization: %v", err)
 	}
+	if typed, ok := materialization.(provider.ValueTypedMaterialization); ok && runnerConfig.VType != nil {
+		typed.SetValueType(runnerConfig.VType.ValueType)
+	}
 	numRows, err := materialization.NumRows()
 	if err != nil {
 		return nil, fmt.Errorf("cannot get materialization num rows: %v", err)
@@ -229,10 +600,16 @@ func MaterializedChunkRunnerFactory(config Config) (types.Runner, error) {
 		return nil, fmt.Errorf("error getting online table: %v", err)
 	}
 	return &MaterializedChunkRunner{
-		Materialized: materialization,
-		Table:        table,
-		Store:        onlineStore,
-		ChunkSize:    runnerConfig.ChunkSize,
-		ChunkIdx:     runnerConfig.ChunkIdx,
+		Materialized:  materialization,
+		Table:         table,
+		Store:         onlineStore,
+		ChunkSize:     runnerConfig.ChunkSize,
+		ChunkIdx:      runnerConfig.ChunkIdx,
+		MergeStrategy: runnerConfig.MergeStrategy,
+		BufferSize:    runnerConfig.BufferSize,
+		ChunkOrder:    runnerConfig.ChunkOrder,
+		TrackWrites:   runnerConfig.TrackWrites,
+		Since:         runnerConfig.Since,
+		TTL:           runnerConfig.TTL,
 	}, nil
 }
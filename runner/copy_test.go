@@ -9,8 +9,11 @@ import (
 	"fmt"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis"
 	"github.com/featureform/provider"
 	pc "github.com/featureform/provider/provider_config"
 	pt "github.com/featureform/provider/provider_type"
@@ -175,11 +178,12 @@ type JobTestParams struct {
 }
 
 type ErrorJobTestParams struct {
-	ErrorName    string
-	Materialized provider.Materialization
-	Table        provider.OnlineStoreTable
-	ChunkSize    int64
-	ChunkIdx     int64
+	ErrorName     string
+	Materialized  provider.Materialization
+	Table         provider.OnlineStoreTable
+	ChunkSize     int64
+	ChunkIdx      int64
+	MergeStrategy provider.MergeStrategy
 }
 
 func testParams(params JobTestParams) error {
@@ -230,11 +234,12 @@ func testParams(params JobTestParams) error {
 func testBreakingParams(params ErrorJobTestParams) error {
 	online := NewMockOnlineStore()
 	job := &MaterializedChunkRunner{
-		Materialized: params.Materialized,
-		Table:        params.Table,
-		Store:        online,
-		ChunkSize:    params.ChunkSize,
-		ChunkIdx:     params.ChunkIdx,
+		Materialized:  params.Materialized,
+		Table:         params.Table,
+		Store:         online,
+		ChunkSize:     params.ChunkSize,
+		ChunkIdx:      params.ChunkIdx,
+		MergeStrategy: params.MergeStrategy,
 	}
 	completionWatcher, err := job.Run()
 	if err != nil {
@@ -252,6 +257,415 @@ func testBreakingParams(params ErrorJobTestParams) error {
 	return nil
 }
 
+func TestChunkRunnerSumMerge(t *testing.T) {
+	mRedis, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("could not start mock redis: %v", err)
+	}
+	defer mRedis.Close()
+	redisConfig := &pc.RedisConfig{Addr: mRedis.Addr()}
+	materialized := CreateMockFeatureRows([]interface{}{1, 2, 3})
+	for i := 0; i < 2; i++ {
+		runRedisMergeChunk(t, redisConfig, &materialized, provider.Int, provider.SumMerge)
+	}
+	online, err := provider.NewRedisOnlineStore(redisConfig)
+	if err != nil {
+		t.Fatalf("could not create redis online store: %v", err)
+	}
+	defer online.Close()
+	table, err := online.GetTable("feature", "variant")
+	if err != nil {
+		t.Fatalf("could not get online table: %v", err)
+	}
+	for _, row := range materialized.Rows {
+		value, err := table.Get(row.Entity)
+		if err != nil {
+			t.Fatalf("could not get value for %s: %v", row.Entity, err)
+		}
+		if expected := 2 * row.Value.(int); value != expected {
+			t.Fatalf("expected merged value %d for %s, got %v", expected, row.Entity, value)
+		}
+	}
+}
+
+func TestChunkRunnerUnionMerge(t *testing.T) {
+	mRedis, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("could not start mock redis: %v", err)
+	}
+	defer mRedis.Close()
+	redisConfig := &pc.RedisConfig{Addr: mRedis.Addr()}
+	first := CreateMockFeatureRows([]interface{}{[]string{"a", "b"}, []string{}})
+	second := CreateMockFeatureRows([]interface{}{[]string{"b", "c"}, []string{}})
+	runRedisMergeChunk(t, redisConfig, &first, provider.JSON, provider.UnionMerge)
+	runRedisMergeChunk(t, redisConfig, &second, provider.JSON, provider.UnionMerge)
+	online, err := provider.NewRedisOnlineStore(redisConfig)
+	if err != nil {
+		t.Fatalf("could not create redis online store: %v", err)
+	}
+	defer online.Close()
+	table, err := online.GetTable("feature", "variant")
+	if err != nil {
+		t.Fatalf("could not get online table: %v", err)
+	}
+	expected := map[string]interface{}{
+		"entity_0": []interface{}{"a", "b", "c"},
+		"entity_1": []interface{}{},
+	}
+	for entity, expectedValue := range expected {
+		value, err := table.Get(entity)
+		if err != nil {
+			t.Fatalf("could not get value for %s: %v", entity, err)
+		}
+		if !reflect.DeepEqual(expectedValue, value) {
+			t.Fatalf("expected merged value %v for %s, got %v", expectedValue, entity, value)
+		}
+	}
+}
+
+// runRedisMergeChunk merges materialized into the feature's Redis table,
+// creating it if it doesn't exist yet
+func runRedisMergeChunk(t *testing.T, redisConfig *pc.RedisConfig, materialized *MockMaterializedFeatures, valueType provider.ValueType, strategy provider.MergeStrategy) {
+	// The chunk runner closes its online store when it finishes
+	online, err := provider.NewRedisOnlineStore(redisConfig)
+	if err != nil {
+		t.Fatalf("could not create redis online store: %v", err)
+	}
+	table, err := online.GetTable("feature", "variant")
+	if _, notFound := err.(*provider.TableNotFound); notFound {
+		table, err = online.CreateTable("feature", "variant", valueType)
+	}
+	if err != nil {
+		t.Fatalf("could not get online table: %v", err)
+	}
+	job := &MaterializedChunkRunner{
+		Materialized:  materialized,
+		Table:         table,
+		Store:         online,
+		ChunkSize:     int64(len(materialized.Rows)),
+		MergeStrategy: strategy,
+	}
+	watcher, err := job.Run()
+	if err != nil {
+		t.Fatalf("could not start chunk runner: %v", err)
+	}
+	if err := watcher.Wait(); err != nil {
+		t.Fatalf("chunk runner failed: %v", err)
+	}
+}
+
+func TestChunkRunnerMergeUnsupported(t *testing.T) {
+	materialized := CreateMockFeatureRows([]interface{}{1})
+	err := testBreakingParams(ErrorJobTestParams{
+		ErrorName:     "merge into table without merge support",
+		Materialized:  &materialized,
+		Table:         &MockOnlineTable{DataTable: make(map[string]interface{})},
+		ChunkSize:     1,
+		MergeStrategy: provider.MaxMerge,
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+}
+
+// expiringOnlineTable records the ttl each entity was last set with
+type expiringOnlineTable struct {
+	MockOnlineTable
+	mtx  sync.Mutex
+	ttls map[string]time.Duration
+}
+
+func (m *expiringOnlineTable) Set(entity string, value interface{}) error {
+	return m.SetWithTTL(entity, value, 0)
+}
+
+func (m *expiringOnlineTable) SetWithTTL(entity string, value interface{}, ttl time.Duration) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.ttls[entity] = ttl
+	return m.MockOnlineTable.Set(entity, value)
+}
+
+func TestChunkRunnerTTL(t *testing.T) {
+	materialized := CreateMockFeatureRows([]interface{}{1, 2, 3})
+	table := &expiringOnlineTable{
+		MockOnlineTable: MockOnlineTable{DataTable: make(map[string]interface{})},
+		ttls:            make(map[string]time.Duration),
+	}
+	job := &MaterializedChunkRunner{
+		Materialized: &materialized,
+		Table:        table,
+		Store:        NewMockOnlineStore(),
+		ChunkSize:    3,
+		TTL:          time.Minute,
+	}
+	watcher, err := job.Run()
+	if err != nil {
+		t.Fatalf("could not start chunk runner: %v", err)
+	}
+	if err := watcher.Wait(); err != nil {
+		t.Fatalf("chunk runner failed: %v", err)
+	}
+	for _, row := range materialized.Rows {
+		if ttl := table.ttls[row.Entity]; ttl != time.Minute {
+			t.Fatalf("expected %s to be set with a ttl of %s, got %s", row.Entity, time.Minute, ttl)
+		}
+	}
+}
+
+func TestChunkRunnerTTLUnsupported(t *testing.T) {
+	materialized := CreateMockFeatureRows([]interface{}{1})
+	job := &MaterializedChunkRunner{
+		Materialized: &materialized,
+		Table:        &MockOnlineTable{DataTable: make(map[string]interface{})},
+		Store:        NewMockOnlineStore(),
+		ChunkSize:    1,
+		TTL:          time.Minute,
+	}
+	watcher, err := job.Run()
+	if err != nil {
+		t.Fatalf("could not start chunk runner: %v", err)
+	}
+	if err := watcher.Wait(); err == nil {
+		t.Fatalf("expected a ttl on a table that can't expire values to fail")
+	}
+}
+
+func TestChunkRunnerEntityHashOrder(t *testing.T) {
+	rows := make([]provider.ResourceRecord, 10)
+	for i := range rows {
+		rows[i] = provider.ResourceRecord{Entity: fmt.Sprintf("entity_%d", i), Value: i}
+	}
+	reversed := make([]provider.ResourceRecord, len(rows))
+	for i, row := range rows {
+		reversed[len(rows)-1-i] = row
+	}
+	const chunkSize = 3
+	assignChunks := func(rows []provider.ResourceRecord) map[string]int64 {
+		materialized := &MockMaterializedFeatures{Rows: rows}
+		chunks := make(map[string]int64)
+		for idx := int64(0); idx*chunkSize < int64(len(rows)); idx++ {
+			table := &MockOnlineTable{DataTable: make(map[string]interface{})}
+			job := &MaterializedChunkRunner{
+				Materialized: materialized,
+				Table:        table,
+				Store:        NewMockOnlineStore(),
+				ChunkSize:    chunkSize,
+				ChunkIdx:     idx,
+				ChunkOrder:   EntityHashChunkOrder,
+			}
+			watcher, err := job.Run()
+			if err != nil {
+				t.Fatalf("could not start chunk runner: %v", err)
+			}
+			if err := watcher.Wait(); err != nil {
+				t.Fatalf("chunk runner failed: %v", err)
+			}
+			for entity := range table.DataTable {
+				if prev, has := chunks[entity]; has {
+					t.Fatalf("%s written by chunks %d and %d", entity, prev, idx)
+				}
+				chunks[entity] = idx
+			}
+		}
+		return chunks
+	}
+	first := assignChunks(rows)
+	if len(first) != len(rows) {
+		t.Fatalf("expected all %d entities to be written, got %d", len(rows), len(first))
+	}
+	if second := assignChunks(reversed); !reflect.DeepEqual(first, second) {
+		t.Fatalf("chunk assignments changed between runs: %v != %v", first, second)
+	}
+}
+
+type countingFeatureIterator struct {
+	provider.FeatureIterator
+	read *int64
+}
+
+func (it *countingFeatureIterator) Next() bool {
+	if !it.FeatureIterator.Next() {
+		return false
+	}
+	atomic.AddInt64(it.read, 1)
+	return true
+}
+
+type countingMaterializedFeatures struct {
+	MockMaterializedFeatures
+	read *int64
+}
+
+func (m *countingMaterializedFeatures) IterateSegment(begin int64, end int64) (provider.FeatureIterator, error) {
+	it, err := m.MockMaterializedFeatures.IterateSegment(begin, end)
+	if err != nil {
+		return nil, err
+	}
+	return &countingFeatureIterator{FeatureIterator: it, read: m.read}, nil
+}
+
+// slowOnlineTable takes a while to write each value and records the most rows
+// that were read but not yet written at any point.
+type slowOnlineTable struct {
+	MockOnlineTable
+	delay       time.Duration
+	read        *int64
+	written     int64
+	maxBuffered int64
+}
+
+func (m *slowOnlineTable) Set(entity string, value interface{}) error {
+	if buffered := atomic.LoadInt64(m.read) - m.written; buffered > m.maxBuffered {
+		m.maxBuffered = buffered
+	}
+	time.Sleep(m.delay)
+	m.written++
+	return m.MockOnlineTable.Set(entity, value)
+}
+
+func TestChunkRunnerBackpressure(t *testing.T) {
+	numRows := 50
+	bufferSize := 5
+	data := make([]interface{}, numRows)
+	for i := range data {
+		data[i] = i
+	}
+	var read int64
+	materialized := &countingMaterializedFeatures{MockMaterializedFeatures: CreateMockFeatureRows(data), read: &read}
+	table := &slowOnlineTable{
+		MockOnlineTable: MockOnlineTable{DataTable: make(map[string]interface{})},
+		delay:           time.Millisecond,
+		read:            &read,
+	}
+	job := &MaterializedChunkRunner{
+		Materialized: materialized,
+		Table:        table,
+		Store:        NewMockOnlineStore(),
+		ChunkSize:    int64(numRows),
+		BufferSize:   bufferSize,
+	}
+	watcher, err := job.Run()
+	if err != nil {
+		t.Fatalf("could not start chunk runner: %v", err)
+	}
+	if err := watcher.Wait(); err != nil {
+		t.Fatalf("chunk runner failed: %v", err)
+	}
+	// Besides the buffered rows, the reader can hold one row it's waiting to
+	// buffer and the writer the row it's writing.
+	if maxBuffered := int64(bufferSize + 2); table.maxBuffered > maxBuffered {
+		t.Fatalf("expected at most %d rows to be read ahead of writes, got %d", maxBuffered, table.maxBuffered)
+	}
+	if len(table.DataTable) != numRows {
+		t.Fatalf("expected %d values to be written, got %d", numRows, len(table.DataTable))
+	}
+	for _, row := range materialized.Rows {
+		if value := table.DataTable[row.Entity]; value != row.Value {
+			t.Fatalf("expected %v for %s, got %v", row.Value, row.Entity, value)
+		}
+	}
+}
+
+// cancellingOnlineTable closes cancel once it has written after values
+type cancellingOnlineTable struct {
+	MockOnlineTable
+	after  int
+	cancel chan struct{}
+}
+
+func (m *cancellingOnlineTable) Set(entity string, value interface{}) error {
+	if err := m.MockOnlineTable.Set(entity, value); err != nil {
+		return err
+	}
+	if len(m.DataTable) == m.after {
+		close(m.cancel)
+	}
+	return nil
+}
+
+func TestChunkRunnerCancel(t *testing.T) {
+	data := make([]interface{}, 20)
+	for i := range data {
+		data[i] = i
+	}
+	materialized := CreateMockFeatureRows(data)
+	table := &cancellingOnlineTable{
+		MockOnlineTable: MockOnlineTable{DataTable: make(map[string]interface{})},
+		after:           5,
+		cancel:          make(chan struct{}),
+	}
+	job := &MaterializedChunkRunner{
+		Materialized: &materialized,
+		Table:        table,
+		Store:        NewMockOnlineStore(),
+		ChunkSize:    int64(len(data)),
+	}
+	var _ CancellableRunner = job
+	job.SetCancel(table.cancel)
+	watcher, err := job.Run()
+	if err != nil {
+		t.Fatalf("could not start chunk runner: %v", err)
+	}
+	if err := watcher.Wait(); !errors.Is(err, ErrJobCancelled) {
+		t.Fatalf("expected chunk runner to be cancelled, got %v", err)
+	}
+	if len(table.DataTable) != table.after {
+		t.Fatalf("expected %d values to be written before the chunk was cancelled, got %d", table.after, len(table.DataTable))
+	}
+}
+
+// sinceMaterializedFeatures filters rows by timestamp the way an offline store
+// would, and fails if the chunk runner reads a segment unfiltered
+type sinceMaterializedFeatures struct {
+	MockMaterializedFeatures
+}
+
+func (m *sinceMaterializedFeatures) IterateSegment(begin int64, end int64) (provider.FeatureIterator, error) {
+	return nil, fmt.Errorf("expected segment to be read with IterateSegmentSince")
+}
+
+func (m *sinceMaterializedFeatures) IterateSegmentSince(begin, end int64, since time.Time) (provider.FeatureIterator, error) {
+	newer := make([]provider.ResourceRecord, 0)
+	for _, row := range m.Rows[begin:end] {
+		if row.TS.After(since) {
+			newer = append(newer, row)
+		}
+	}
+	return &MockFeatureIterator{CurrentIndex: -1, Slice: newer}, nil
+}
+
+func TestChunkRunnerSinceFilteredByStore(t *testing.T) {
+	since := time.UnixMilli(10).UTC()
+	materialized := &sinceMaterializedFeatures{MockMaterializedFeatures{
+		id: provider.MaterializationID(uuid.NewString()),
+		Rows: []provider.ResourceRecord{
+			{Entity: "a", Value: 1, TS: time.UnixMilli(5).UTC()},
+			{Entity: "b", Value: 2, TS: time.UnixMilli(10).UTC()},
+			{Entity: "c", Value: 3, TS: time.UnixMilli(15).UTC()},
+		},
+	}}
+	table := &MockOnlineTable{DataTable: make(map[string]interface{})}
+	job := &MaterializedChunkRunner{
+		Materialized: materialized,
+		Table:        table,
+		Store:        NewMockOnlineStore(),
+		ChunkSize:    3,
+		Since:        since,
+	}
+	watcher, err := job.Run()
+	if err != nil {
+		t.Fatalf("could not start chunk runner: %v", err)
+	}
+	if err := watcher.Wait(); err != nil {
+		t.Fatalf("chunk runner failed: %v", err)
+	}
+	expected := map[string]interface{}{"c": 3}
+	if !reflect.DeepEqual(table.DataTable, expected) {
+		t.Fatalf("expected only rows after %v to be written, got %v", since, table.DataTable)
+	}
+}
+
 type CopyTestData struct {
 	Rows []interface{}
 }
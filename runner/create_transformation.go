@@ -23,22 +23,31 @@ func (c *CreateTransformationRunner) Run() (types.CompletionWatcher, error) {
 		DoneChannel: done,
 	}
 	go func() {
+		// The offline store can't be interrupted once it's creating the
+		// transformation, so a cancelled job only stops before it starts. Either
+		// way, the watcher doesn't return until the store is done with it.
+		if isCancelled(c.cancel) {
+			transformationWatcher.EndWatch(ErrJobCancelled)
+			return
+		}
+		var err error
 		if !c.IsUpdate {
-			if err := c.Offline.CreateTransformation(c.TransformationConfig); err != nil {
-				transformationWatcher.EndWatch(err)
-				return
-			}
+			err = c.Offline.CreateTransformation(c.TransformationConfig)
 		} else {
-			if err := c.Offline.UpdateTransformation(c.TransformationConfig); err != nil {
-				transformationWatcher.EndWatch(err)
-				return
-			}
+			err = c.Offline.UpdateTransformation(c.TransformationConfig)
+		}
+		if err == nil && isCancelled(c.cancel) {
+			err = ErrJobCancelled
 		}
-		transformationWatcher.EndWatch(nil)
+		transformationWatcher.EndWatch(err)
 	}()
 	return transformationWatcher, nil
 }
 
+func (c *CreateTransformationRunner) SetCancel(cancel <-chan struct{}) {
+	c.cancel = cancel
+}
+
 type CreateTransformationConfig struct {
 	OfflineType          pt.Type
 	OfflineConfig        pc.SerializedConfig
@@ -66,6 +75,7 @@ type CreateTransformationRunner struct {
 	Offline              provider.OfflineStore
 	TransformationConfig provider.TransformationConfig
 	IsUpdate             bool
+	cancel               <-chan struct{}
 }
 
 func (c CreateTransformationRunner) Resource() metadata.ResourceID {
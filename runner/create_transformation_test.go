@@ -5,6 +5,7 @@
 package runner
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
@@ -81,9 +82,9 @@ func (m MockOfflineCreateTransformationFail) Close() error {
 
 func TestRun(t *testing.T) {
 	runner := CreateTransformationRunner{
-		MockOfflineStore{},
-		provider.TransformationConfig{},
-		false,
+		Offline:              MockOfflineStore{},
+		TransformationConfig: provider.TransformationConfig{},
+		IsUpdate:             false,
 	}
 	watcher, err := runner.Run()
 	if err != nil {
@@ -96,9 +97,9 @@ func TestRun(t *testing.T) {
 
 func TestFail(t *testing.T) {
 	runner := CreateTransformationRunner{
-		MockOfflineCreateTransformationFail{},
-		provider.TransformationConfig{},
-		false,
+		Offline:              MockOfflineCreateTransformationFail{},
+		TransformationConfig: provider.TransformationConfig{},
+		IsUpdate:             false,
 	}
 	watcher, err := runner.Run()
 	if err != nil {
@@ -109,6 +110,23 @@ func TestFail(t *testing.T) {
 	}
 }
 
+func TestCancel(t *testing.T) {
+	runner := &CreateTransformationRunner{
+		Offline:              MockOfflineStore{},
+		TransformationConfig: provider.TransformationConfig{},
+	}
+	cancel := make(chan struct{})
+	close(cancel)
+	runner.SetCancel(cancel)
+	watcher, err := runner.Run()
+	if err != nil {
+		t.Fatalf("failed to create create transformation runner: %v", err)
+	}
+	if err := watcher.Wait(); !errors.Is(err, ErrJobCancelled) {
+		t.Fatalf("expected cancelled transformation runner to fail with %v, got %v", ErrJobCancelled, err)
+	}
+}
+
 func testTransformationErrorConfigsFactory(config Config) error {
 	_, err := Create(CREATE_TRANSFORMATION, config)
 	return err
@@ -6,7 +6,10 @@ package runner
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -40,6 +43,52 @@ type MaterializeRunner struct {
 	IsUpdate bool
 	Cloud    JobCloud
 	Logger   *zap.SugaredLogger
+	// How materialized values are combined with values already in the online
+	// store. Defaults to overwriting them.
+	MergeStrategy provider.MergeStrategy
+	// The most rows each chunk buffers between reading from the offline store
+	// and writing to the online store.
+	BufferSize int
+	// Remove every entity already in the online table before writing, so
+	// entities that are no longer in the source don't keep serving stale values.
+	Truncate bool
+	// Which rows each chunk copies. Defaults to contiguous ranges of rows.
+	ChunkOrder ChunkOrder
+	// What happens to the rows of chunks that succeeded when another chunk
+	// fails. Defaults to BestEffortChunkFailure.
+	ChunkFailurePolicy ChunkFailurePolicy
+	// The most rows each chunk copies. Defaults to MAXIMUM_CHUNK_ROWS.
+	MaxChunkRows int64
+	// The most chunks that copy rows at once, if they run locally. The rest
+	// wait for one of them to finish. Defaults to running every chunk at once.
+	Parallelism int
+	// Keep every version of each entity's value in the online store, so it can
+	// be read as of a past time. The online store must be a
+	// provider.HistoricalOnlineStore.
+	Historical bool
+	// Where the materialization's progress is recorded while it runs, if
+	// anywhere
+	Progress ProgressRecorder
+	// Closed to stop the materialization's chunks, if they run locally
+	Cancel <-chan struct{}
+	// Only copy rows with a timestamp after Since, so that a materialization
+	// that's already been written up to Since only adds the rows appended to
+	// its source after it. Its watcher is a WatermarkWatcher that reports what
+	// to pass as Since next time. Zero copies every row. It requires chunks to
+	// run locally.
+	Since time.Time
+	// How long each value written stays in the online store, which must then
+	// have provider.ExpiringOnlineStoreTable tables. Zero keeps values until
+	// they're overwritten.
+	TTL time.Duration
+}
+
+func (m *MaterializeRunner) SetProgressRecorder(recorder ProgressRecorder) {
+	m.Progress = recorder
+}
+
+func (m *MaterializeRunner) SetCancel(cancel <-chan struct{}) {
+	m.Cancel = cancel
 }
 
 func (m MaterializeRunner) Resource() metadata.ResourceID {
@@ -91,14 +140,166 @@ func (w WatcherMultiplex) Err() error {
 	return nil
 }
 
+// MaterializeProgress is how far a materialization has gotten copying its rows
+// to the online store.
+type MaterializeProgress struct {
+	// Rows in the materialization, or 0 if they aren't known
+	TotalRows   int64
+	RowsWritten int64
+	TotalChunks int64
+	// Chunks that finished without error
+	CompletedChunks int64
+}
+
+// Percent returns the share of the materialization that has been written. It's
+// counted in rows when the total number of rows is known and in chunks
+// otherwise.
+func (p MaterializeProgress) Percent() float64 {
+	if p.TotalRows > 0 {
+		return 100 * float64(p.RowsWritten) / float64(p.TotalRows)
+	}
+	if p.TotalChunks > 0 {
+		return 100 * float64(p.CompletedChunks) / float64(p.TotalChunks)
+	}
+	return 0
+}
+
+// JobProgress is how far a running job has gotten, as recorded for clients
+// that aren't watching the job themselves.
+type JobProgress struct {
+	TotalChunks     int64
+	CompletedChunks int64
+	StartedAt       time.Time
+}
+
+// ProgressRecorder saves a job's progress somewhere it can be read while the
+// job runs.
+type ProgressRecorder interface {
+	RecordProgress(progress JobProgress) error
+}
+
+// ProgressRecordingRunner is implemented by runners that can record their
+// progress with a ProgressRecorder.
+type ProgressRecordingRunner interface {
+	types.Runner
+	SetProgressRecorder(recorder ProgressRecorder)
+}
+
+// How often a materialization records its progress
+const progressRecordInterval = time.Second
+
+// ProgressWatcher is implemented by the completion watchers of jobs that can
+// report their progress while they run.
+type ProgressWatcher interface {
+	types.CompletionWatcher
+	Progress() MaterializeProgress
+}
+
+// WatermarkWatcher is implemented by the completion watchers of
+// materializations that report the newest timestamp they've written, which an
+// incremental materialization starts after.
+type WatermarkWatcher interface {
+	types.CompletionWatcher
+	Watermark() time.Time
+}
+
+// ChunkFailurePolicy determines what a materialization leaves in the online
+// store when some of its chunks fail.
+type ChunkFailurePolicy string
+
+const (
+	// BestEffortChunkFailure keeps the rows of the chunks that succeeded, and of
+	// the failed chunks up to where they failed. The materialization fails with
+	// a PartialMaterializationError. It's the default.
+	BestEffortChunkFailure ChunkFailurePolicy = "best-effort"
+	// AtomicChunkFailure restores every entity written by any chunk to the value
+	// it held before the materialization started, so that a failed
+	// materialization leaves the online store as it found it. Each chunk reads
+	// the previous value of every entity before writing it and holds them all
+	// in memory until the materialization finishes, and the online table must
+	// be able to delete entities. Historical tables have the versions the chunks
+	// recorded removed instead. If the table is truncated first, all of its
+	// values are read beforehand and put back, which historical tables don't
+	// support. It requires chunks to run locally.
+	AtomicChunkFailure ChunkFailurePolicy = "atomic"
+)
+
+// PartialMaterializationError is returned when some chunks of a materialization
+// failed under BestEffortChunkFailure, leaving the rows of the others written.
+type PartialMaterializationError struct {
+	CompletedChunks int64
+	TotalChunks     int64
+	Err             error
+}
+
+func (e PartialMaterializationError) Error() string {
+	return fmt.Sprintf("materialization partially completed, %d of %d chunks were written: %v", e.CompletedChunks, e.TotalChunks, e.Err)
+}
+
+func (e PartialMaterializationError) Unwrap() error {
+	return e.Err
+}
+
+// materializeWatcher reports the progress of a materialization's chunks. Rows
+// are only counted for chunks that run in this process; chunks that run
+// elsewhere count as written once the whole materialization succeeds.
+type materializeWatcher struct {
+	*SyncWatcher
+	totalRows   int64
+	totalChunks int64
+	chunks      []types.CompletionWatcher
+	since       time.Time
+}
+
+// Watermark returns the newest timestamp written by any chunk, or the one the
+// materialization started after if none of them wrote a newer one.
+func (w *materializeWatcher) Watermark() time.Time {
+	watermark := w.since
+	for _, chunk := range w.chunks {
+		if marked, ok := chunk.(interface{ Watermark() time.Time }); ok && marked.Watermark().After(watermark) {
+			watermark = marked.Watermark()
+		}
+	}
+	return watermark
+}
+
+func (w *materializeWatcher) Progress() MaterializeProgress {
+	progress := MaterializeProgress{TotalRows: w.totalRows, TotalChunks: w.totalChunks}
+	if w.Complete() && w.Err() == nil {
+		progress.RowsWritten = w.totalRows
+		progress.CompletedChunks = w.totalChunks
+		return progress
+	}
+	for _, chunk := range w.chunks {
+		if chunk.Complete() && chunk.Err() == nil {
+			progress.CompletedChunks++
+		}
+		if counter, ok := chunk.(interface{ RowsWritten() int64 }); ok {
+			progress.RowsWritten += counter.RowsWritten()
+		}
+	}
+	if progress.RowsWritten > progress.TotalRows {
+		progress.RowsWritten = progress.TotalRows
+	}
+	return progress
+}
+
 func (m MaterializeRunner) Run() (types.CompletionWatcher, error) {
 	m.Logger.Infow("Starting Materialization Runner", "name", m.ID.Name, "variant", m.ID.Variant)
+	startedAt := time.Now()
 	var materialization provider.Materialization
 	var err error
 
 	if m.IsUpdate {
 		m.Logger.Infow("Updating Materialization", "name", m.ID.Name, "variant", m.ID.Variant)
 		materialization, err = m.Offline.UpdateMaterialization(m.ID)
+		// Features that were streamed to the online store, or whose first run
+		// failed before staging, have nothing to update yet
+		var notFound *provider.MaterializationNotFound
+		if errors.As(err, &notFound) {
+			m.Logger.Infow("No materialization to update, creating one", "name", m.ID.Name, "variant", m.ID.Variant)
+			materialization, err = m.Offline.CreateMaterialization(m.ID)
+		}
 	} else {
 		m.Logger.Infow("Creating Materialization", "name", m.ID.Name, "variant", m.ID.Variant)
 		materialization, err = m.Offline.CreateMaterialization(m.ID)
@@ -125,7 +326,7 @@ func (m MaterializeRunner) Run() (types.CompletionWatcher, error) {
 		}
 	}
 	m.Logger.Infow("Creating Table", "name", m.ID.Name, "variant", m.ID.Variant)
-	_, err = m.Online.CreateTable(m.ID.Name, m.ID.Variant, m.VType)
+	err = m.createTable()
 	_, exists := err.(*provider.TableAlreadyExists)
 	if err != nil && !exists {
 		return nil, fmt.Errorf("create table error: %w", err)
@@ -133,7 +334,26 @@ func (m MaterializeRunner) Run() (types.CompletionWatcher, error) {
 	if exists && !m.IsUpdate {
 		return nil, fmt.Errorf("table already exists despite being new job")
 	}
+	if m.Truncate && !m.Since.IsZero() {
+		return nil, fmt.Errorf("cannot truncate an incremental materialization")
+	}
+	// The values a truncate removes, if they have to be put back when an atomic
+	// materialization fails
+	var truncated map[string]interface{}
+	if m.Truncate {
+		if m.ChunkFailurePolicy == AtomicChunkFailure {
+			if truncated, err = m.saveTable(); err != nil {
+				return nil, err
+			}
+		}
+		if err := m.truncateTable(); err != nil {
+			return nil, err
+		}
+	}
 	chunkSize := MAXIMUM_CHUNK_ROWS
+	if m.MaxChunkRows > 0 {
+		chunkSize = m.MaxChunkRows
+	}
 	var numChunks int64
 	m.Logger.Debugw("Getting number of rows", "name", m.ID.Name, "variant", m.ID.Variant)
 	numRows, err := materialization.NumRows()
@@ -141,7 +361,7 @@ func (m MaterializeRunner) Run() (types.CompletionWatcher, error) {
 		return nil, fmt.Errorf("num rows: %w", err)
 	}
 	m.Logger.Debugw("Got materialization rows", "name", m.ID.Name, "variant", m.ID.Variant, "count", numRows)
-	if numRows <= MAXIMUM_CHUNK_ROWS {
+	if numRows <= chunkSize {
 		chunkSize = numRows
 		numChunks = 1
 	} else if chunkSize == 0 {
@@ -152,7 +372,7 @@ func (m MaterializeRunner) Run() (types.CompletionWatcher, error) {
 			numChunks += 1
 		}
 	}
-	m.Logger.Infow("Creating chunks", "name", m.ID.Name, "variant", m.ID.Variant, "count", numChunks)
+	m.Logger.Infow("Creating chunks", "name", m.ID.Name, "variant", m.ID.Variant, "count", numChunks, "parallelism", m.Parallelism)
 	config := &MaterializedChunkRunnerConfig{
 		OnlineType:     m.Online.Type(),
 		OfflineType:    m.Offline.Type(),
@@ -161,15 +381,32 @@ func (m MaterializeRunner) Run() (types.CompletionWatcher, error) {
 		MaterializedID: materialization.ID(),
 		ResourceID:     m.ID,
 		ChunkSize:      chunkSize,
+		MergeStrategy:  m.MergeStrategy,
+		BufferSize:     m.BufferSize,
+		ChunkOrder:     m.ChunkOrder,
+		TrackWrites:    m.ChunkFailurePolicy == AtomicChunkFailure,
+		Since:          m.Since,
+		TTL:            m.TTL,
 		Logger:         m.Logger,
 	}
+	if m.VType != nil {
+		config.VType = &provider.ValueTypeJSONWrapper{ValueType: m.VType}
+	}
 	serializedConfig, err := config.Serialize()
 	if err != nil {
 		return nil, fmt.Errorf("could not serialize config : %w", err)
 	}
 	var cloudWatcher types.CompletionWatcher
+	// The watchers of each chunk, if they run in this process
+	var completionList []types.CompletionWatcher
 	switch m.Cloud {
 	case KubernetesMaterializeRunner:
+		if m.ChunkFailurePolicy == AtomicChunkFailure {
+			return nil, fmt.Errorf("%s chunk failure policy requires chunks to run locally", AtomicChunkFailure)
+		}
+		if !m.Since.IsZero() {
+			return nil, fmt.Errorf("incremental materialization requires chunks to run locally")
+		}
 		pandas_image := cfg.GetPandasRunnerImage()
 		envVars := map[string]string{"NAME": string(COPY_TO_ONLINE), "CONFIG": string(serializedConfig), "PANDAS_RUNNER_IMAGE": pandas_image}
 		kubernetesConfig := kubernetes.KubernetesRunnerConfig{
@@ -189,12 +426,27 @@ func (m MaterializeRunner) Run() (types.CompletionWatcher, error) {
 		}
 	case LocalMaterializeRunner:
 		m.Logger.Infow("Making Local Runner", "name", m.ID.Name, "variant", m.ID.Variant)
-		completionList := make([]types.CompletionWatcher, int(numChunks))
+		completionList = make([]types.CompletionWatcher, int(numChunks))
+		var pool chan struct{}
+		if m.Parallelism > 0 {
+			pool = make(chan struct{}, m.Parallelism)
+		}
 		for i := 0; i < int(numChunks); i++ {
 			localRunner, err := Create(string(COPY_TO_ONLINE), serializedConfig)
 			if err != nil {
 				return nil, fmt.Errorf("local runner create: %w", err)
 			}
+			if indexRunner, ok := localRunner.(IndexRunner); ok {
+				if err := indexRunner.SetIndex(i); err != nil {
+					return nil, fmt.Errorf("local runner set index: %w", err)
+				}
+			}
+			if cancellable, ok := localRunner.(CancellableRunner); ok {
+				cancellable.SetCancel(m.Cancel)
+			}
+			if pooled, ok := localRunner.(PooledRunner); ok && pool != nil {
+				pooled.SetPool(pool)
+			}
 			watcher, err := localRunner.Run()
 			if err != nil {
 				return nil, fmt.Errorf("local runner run: %w", err)
@@ -206,12 +458,35 @@ func (m MaterializeRunner) Run() (types.CompletionWatcher, error) {
 		return nil, fmt.Errorf("no valid job cloud set")
 	}
 	done := make(chan interface{})
-	materializeWatcher := &SyncWatcher{
-		ResultSync:  &ResultSync{},
-		DoneChannel: done,
+	materializeWatcher := &materializeWatcher{
+		SyncWatcher: &SyncWatcher{
+			ResultSync:  &ResultSync{},
+			DoneChannel: done,
+		},
+		totalRows:   numRows,
+		totalChunks: numChunks,
+		chunks:      completionList,
+		since:       m.Since,
+	}
+	// Recording stops before the watcher ends, so that nothing is recorded
+	// after whoever is waiting on the job has cleaned its progress up
+	stopRecording := make(chan struct{})
+	var recorder sync.WaitGroup
+	if m.Progress != nil {
+		recorder.Add(1)
+		go func() {
+			defer recorder.Done()
+			m.recordProgress(materializeWatcher, stopRecording, startedAt)
+		}()
 	}
 	go func() {
-		if err := cloudWatcher.Wait(); err != nil {
+		err := cloudWatcher.Wait()
+		if len(completionList) > 0 {
+			err = m.finishChunks(completionList, err, truncated)
+		}
+		close(stopRecording)
+		recorder.Wait()
+		if err != nil {
 			materializeWatcher.EndWatch(fmt.Errorf("cloud watch: %w", err))
 			return
 		}
@@ -220,6 +495,140 @@ func (m MaterializeRunner) Run() (types.CompletionWatcher, error) {
 	return materializeWatcher, nil
 }
 
+// recordProgress records the materialization's progress every
+// progressRecordInterval until stop is closed, and once more when it is.
+func (m MaterializeRunner) recordProgress(watcher *materializeWatcher, stop <-chan struct{}, startedAt time.Time) {
+	record := func() {
+		progress := watcher.Progress()
+		jobProgress := JobProgress{
+			TotalChunks:     progress.TotalChunks,
+			CompletedChunks: progress.CompletedChunks,
+			StartedAt:       startedAt,
+		}
+		if err := m.Progress.RecordProgress(jobProgress); err != nil {
+			m.Logger.Warnw("Could not record materialization progress", "name", m.ID.Name, "variant", m.ID.Variant, "error", err)
+		}
+	}
+	ticker := time.NewTicker(progressRecordInterval)
+	defer ticker.Stop()
+	for {
+		record()
+		select {
+		case <-stop:
+			record()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// finishChunks waits for every local chunk to finish after the first of them
+// failed with err, and applies the ChunkFailurePolicy to what they wrote. The
+// values in truncated are put back once the chunks' writes are rolled back.
+func (m MaterializeRunner) finishChunks(chunks []types.CompletionWatcher, err error, truncated map[string]interface{}) error {
+	var completed int64
+	for _, chunk := range chunks {
+		if chunk.Wait() == nil {
+			completed++
+		}
+	}
+	rollback := err != nil && m.ChunkFailurePolicy == AtomicChunkFailure
+	var rollbackErr error
+	for _, chunk := range chunks {
+		tracked, ok := chunk.(*chunkWatcher)
+		if !ok {
+			continue
+		}
+		if releaseErr := tracked.release(rollback); releaseErr != nil && rollbackErr == nil {
+			rollbackErr = releaseErr
+		}
+	}
+	if rollback && rollbackErr == nil && truncated != nil {
+		rollbackErr = m.restoreTable(truncated)
+	}
+	switch {
+	case err == nil:
+		return rollbackErr
+	case rollback && rollbackErr != nil:
+		return fmt.Errorf("%v; rolling back the other chunks also failed: %w", err, rollbackErr)
+	case rollback:
+		m.Logger.Infow("Rolled back failed materialization", "name", m.ID.Name, "variant", m.ID.Variant)
+		return fmt.Errorf("materialization was rolled back: %w", err)
+	default:
+		return PartialMaterializationError{CompletedChunks: completed, TotalChunks: int64(len(chunks)), Err: err}
+	}
+}
+
+// saveTable reads every value of the table before it's truncated, so that a
+// failed atomic materialization can put them back. The history of a
+// historical table can't be put back, so it can't be truncated atomically.
+func (m MaterializeRunner) saveTable() (map[string]interface{}, error) {
+	if m.Historical {
+		return nil, fmt.Errorf("%s chunk failure policy can't restore the history of a truncated table", AtomicChunkFailure)
+	}
+	table, err := m.Online.GetTable(m.ID.Name, m.ID.Variant)
+	if err != nil {
+		return nil, fmt.Errorf("get table: %w", err)
+	}
+	enumerable, ok := table.(provider.EnumerableOnlineStoreTable)
+	if !ok {
+		return nil, fmt.Errorf("%s chunk failure policy can't save the values of a truncated table of online store type %s", AtomicChunkFailure, m.Online.Type())
+	}
+	entities, err := enumerable.Entities()
+	if err != nil {
+		return nil, fmt.Errorf("list entities: %w", err)
+	}
+	values, err := provider.BatchGet(table, entities)
+	if err != nil {
+		return nil, fmt.Errorf("save values: %w", err)
+	}
+	return values, nil
+}
+
+// restoreTable puts back the values saveTable read before the table was
+// truncated
+func (m MaterializeRunner) restoreTable(values map[string]interface{}) error {
+	table, err := m.Online.GetTable(m.ID.Name, m.ID.Variant)
+	if err != nil {
+		return fmt.Errorf("get table: %w", err)
+	}
+	for entity, value := range values {
+		if err := table.Set(entity, value); err != nil {
+			return fmt.Errorf("could not restore %s: %w", entity, err)
+		}
+	}
+	return nil
+}
+
+func (m MaterializeRunner) truncateTable() error {
+	m.Logger.Infow("Truncating Table", "name", m.ID.Name, "variant", m.ID.Variant)
+	table, err := m.Online.GetTable(m.ID.Name, m.ID.Variant)
+	if err != nil {
+		return fmt.Errorf("get table: %w", err)
+	}
+	truncatable, ok := table.(provider.TruncatableOnlineStoreTable)
+	if !ok {
+		return fmt.Errorf("cannot truncate table of online store type %s", m.Online.Type())
+	}
+	if err := truncatable.Truncate(); err != nil {
+		return fmt.Errorf("truncate table: %w", err)
+	}
+	return nil
+}
+
+func (m MaterializeRunner) createTable() error {
+	if !m.Historical {
+		_, err := m.Online.CreateTable(m.ID.Name, m.ID.Variant, m.VType)
+		return err
+	}
+	historicalStore, ok := m.Online.(provider.HistoricalOnlineStore)
+	if !ok {
+		return fmt.Errorf("online store %s does not support historical tables", m.Online.Type())
+	}
+	_, err := historicalStore.CreateHistoricalTable(m.ID.Name, m.ID.Variant, m.VType)
+	return err
+}
+
 type MaterializedRunnerConfig struct {
 	OnlineType    pt.Type
 	OfflineType   pt.Type
@@ -229,6 +638,21 @@ type MaterializedRunnerConfig struct {
 	VType         provider.ValueTypeJSONWrapper
 	Cloud         JobCloud
 	IsUpdate      bool
+	MergeStrategy provider.MergeStrategy
+	BufferSize    int
+	Truncate      bool
+	ChunkOrder    ChunkOrder
+	Historical    bool
+	// Defaults to BestEffortChunkFailure
+	ChunkFailurePolicy ChunkFailurePolicy
+	// Defaults to MAXIMUM_CHUNK_ROWS
+	MaxChunkRows int64
+	// Defaults to running every chunk at once
+	Parallelism int
+	// Zero materializes every row
+	Since time.Time
+	// Zero writes values that don't expire
+	TTL time.Duration
 }
 
 func (m *MaterializedRunnerConfig) Serialize() (Config, error) {
@@ -254,27 +678,37 @@ func MaterializeRunnerFactory(config Config) (types.Runner, error) {
 	}
 	onlineProvider, err := provider.Get(runnerConfig.OnlineType, runnerConfig.OnlineConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to configure online provider: %v", err)
+		return nil, fmt.Errorf("failed to configure online provider: %w", err)
 	}
 	offlineProvider, err := provider.Get(runnerConfig.OfflineType, runnerConfig.OfflineConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to configure offline provider: %v", err)
+		return nil, fmt.Errorf("failed to configure offline provider: %w", err)
 	}
 	onlineStore, err := onlineProvider.AsOnlineStore()
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert provider to online store: %v", err)
+		return nil, fmt.Errorf("failed to convert provider to online store: %w", err)
 	}
 	offlineStore, err := offlineProvider.AsOfflineStore()
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert provider to offline store: %v", err)
+		return nil, fmt.Errorf("failed to convert provider to offline store: %w", err)
 	}
 	return &MaterializeRunner{
-		Online:   onlineStore,
-		Offline:  offlineStore,
-		ID:       runnerConfig.ResourceID,
-		VType:    runnerConfig.VType.ValueType,
-		IsUpdate: runnerConfig.IsUpdate,
-		Cloud:    runnerConfig.Cloud,
-		Logger:   logging.NewLogger("materializer"),
+		Online:             onlineStore,
+		Offline:            offlineStore,
+		ID:                 runnerConfig.ResourceID,
+		VType:              runnerConfig.VType.ValueType,
+		IsUpdate:           runnerConfig.IsUpdate,
+		Cloud:              runnerConfig.Cloud,
+		Logger:             logging.NewLogger("materializer"),
+		MergeStrategy:      runnerConfig.MergeStrategy,
+		BufferSize:         runnerConfig.BufferSize,
+		Truncate:           runnerConfig.Truncate,
+		ChunkOrder:         runnerConfig.ChunkOrder,
+		Historical:         runnerConfig.Historical,
+		ChunkFailurePolicy: runnerConfig.ChunkFailurePolicy,
+		MaxChunkRows:       runnerConfig.MaxChunkRows,
+		Parallelism:        runnerConfig.Parallelism,
+		Since:              runnerConfig.Since,
+		TTL:                runnerConfig.TTL,
 	}, nil
 }
@@ -5,10 +5,16 @@
 package runner
 
 import (
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis"
 	"github.com/featureform/metadata"
 	"github.com/featureform/provider"
+	pc "github.com/featureform/provider/provider_config"
 	"github.com/featureform/types"
 	"go.uber.org/zap/zaptest"
 )
@@ -87,6 +93,725 @@ func TestMockMaterializeRunner(t *testing.T) {
 
 }
 
+type recordedProgress struct {
+	mtx     sync.Mutex
+	records []JobProgress
+}
+
+func (r *recordedProgress) RecordProgress(progress JobProgress) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.records = append(r.records, progress)
+	return nil
+}
+
+func TestMaterializeRunnerRecordsProgress(t *testing.T) {
+	recorder := &recordedProgress{}
+	materializeRunner := &MaterializeRunner{
+		Online:  MockOnlineStore{},
+		Offline: MockOfflineStore{},
+		ID:      provider.ResourceID{Name: "test", Variant: "test", Type: provider.Feature},
+		VType:   provider.String,
+		Cloud:   LocalMaterializeRunner,
+		Logger:  zaptest.NewLogger(t).Sugar(),
+	}
+	var _ ProgressRecordingRunner = materializeRunner
+	materializeRunner.SetProgressRecorder(recorder)
+	delete(factoryMap, string(COPY_TO_ONLINE))
+	defer delete(factoryMap, string(COPY_TO_ONLINE))
+	if err := RegisterFactory(string(COPY_TO_ONLINE), mockChunkRunnerFactory); err != nil {
+		t.Fatalf("Failed to register factory: %v", err)
+	}
+	started := time.Now()
+	watcher, err := materializeRunner.Run()
+	if err != nil {
+		t.Fatalf("Failed to create materialize runner: %v", err)
+	}
+	if err := watcher.Wait(); err != nil {
+		t.Fatalf("Failed to run materialize runner: %v", err)
+	}
+	// Every record is made before the watcher finishes
+	recorder.mtx.Lock()
+	defer recorder.mtx.Unlock()
+	if len(recorder.records) == 0 {
+		t.Fatalf("Expected progress to be recorded")
+	}
+	last := recorder.records[len(recorder.records)-1]
+	if last.TotalChunks != 1 || last.CompletedChunks != 1 {
+		t.Fatalf("Expected final progress of 1 of 1 chunks, got %d of %d", last.CompletedChunks, last.TotalChunks)
+	}
+	if last.StartedAt.Before(started.Add(-time.Second)) || last.StartedAt.After(time.Now()) {
+		t.Fatalf("Expected start time around %v, got %v", started, last.StartedAt)
+	}
+}
+
+func TestMaterializeRunnerTruncate(t *testing.T) {
+	mRedis, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("could not start mock redis: %v", err)
+	}
+	defer mRedis.Close()
+	redisConfig := &pc.RedisConfig{Addr: mRedis.Addr()}
+	id := provider.ResourceID{Name: "feature", Variant: "variant", Type: provider.Feature}
+	values := map[string]int{"A": 1, "B": 2, "C": 3, "D": 4, "E": 5}
+
+	materialize := func(entities []string, isUpdate, truncate bool) error {
+		offline := provider.NewMemoryOfflineStore()
+		table, err := offline.CreateResourceTable(id, provider.TableSchema{})
+		if err != nil {
+			return fmt.Errorf("create resource table: %w", err)
+		}
+		for _, entity := range entities {
+			if err := table.Write(provider.ResourceRecord{Entity: entity, Value: values[entity]}); err != nil {
+				return fmt.Errorf("write %s: %w", entity, err)
+			}
+		}
+		// The memory offline store isn't shared between provider.Get calls, so
+		// chunks read the materialization from this store directly.
+		delete(factoryMap, string(COPY_TO_ONLINE))
+		defer delete(factoryMap, string(COPY_TO_ONLINE))
+		chunkFactory := func(config Config) (types.Runner, error) {
+			chunkConfig := &MaterializedChunkRunnerConfig{}
+			if err := chunkConfig.Deserialize(config); err != nil {
+				return nil, err
+			}
+			materialization, err := offline.GetMaterialization(chunkConfig.MaterializedID)
+			if err != nil {
+				return nil, err
+			}
+			online, err := provider.NewRedisOnlineStore(redisConfig)
+			if err != nil {
+				return nil, err
+			}
+			table, err := online.GetTable(id.Name, id.Variant)
+			if err != nil {
+				return nil, err
+			}
+			return &MaterializedChunkRunner{
+				Materialized: materialization,
+				Table:        table,
+				Store:        online,
+				ChunkSize:    chunkConfig.ChunkSize,
+				ChunkIdx:     chunkConfig.ChunkIdx,
+			}, nil
+		}
+		if err := RegisterFactory(string(COPY_TO_ONLINE), chunkFactory); err != nil {
+			return err
+		}
+		online, err := provider.NewRedisOnlineStore(redisConfig)
+		if err != nil {
+			return fmt.Errorf("create redis online store: %w", err)
+		}
+		defer online.Close()
+		materializeRunner := MaterializeRunner{
+			Online:   online,
+			Offline:  offline,
+			ID:       id,
+			VType:    provider.Int,
+			IsUpdate: isUpdate,
+			Truncate: truncate,
+			Cloud:    LocalMaterializeRunner,
+			Logger:   zaptest.NewLogger(t).Sugar(),
+		}
+		watcher, err := materializeRunner.Run()
+		if err != nil {
+			return fmt.Errorf("run: %w", err)
+		}
+		return watcher.Wait()
+	}
+
+	if err := materialize([]string{"A", "B", "C", "D", "E"}, false, false); err != nil {
+		t.Fatalf("could not materialize A-E: %v", err)
+	}
+	if err := materialize([]string{"A", "B", "C"}, true, true); err != nil {
+		t.Fatalf("could not truncate and materialize A-C: %v", err)
+	}
+	online, err := provider.NewRedisOnlineStore(redisConfig)
+	if err != nil {
+		t.Fatalf("could not create redis online store: %v", err)
+	}
+	defer online.Close()
+	table, err := online.GetTable(id.Name, id.Variant)
+	if err != nil {
+		t.Fatalf("could not get online table: %v", err)
+	}
+	for _, entity := range []string{"A", "B", "C"} {
+		value, err := table.Get(entity)
+		if err != nil {
+			t.Fatalf("could not get value for %s: %v", entity, err)
+		}
+		if value != values[entity] {
+			t.Fatalf("expected %d for %s, got %v", values[entity], entity, value)
+		}
+	}
+	for _, entity := range []string{"D", "E"} {
+		if value, err := table.Get(entity); err == nil {
+			t.Fatalf("expected %s to be truncated, got %v", entity, value)
+		}
+	}
+}
+
+// failingTable fails every write, like an online store that has gone away
+type failingTable struct {
+	provider.OnlineStoreTable
+}
+
+func (t failingTable) Set(entity string, value interface{}) error {
+	return fmt.Errorf("online store unavailable")
+}
+
+func TestMaterializeChunkFailurePolicy(t *testing.T) {
+	id := provider.ResourceID{Name: "feature", Variant: "variant", Type: provider.Feature}
+	original := map[string]int{"A": 1, "B": 2, "C": 3, "D": 4, "E": 5, "F": 6}
+	updated := map[string]int{"A": 10, "B": 20, "C": 30, "D": 40, "E": 50, "F": 60, "G": 70}
+
+	// materialize copies values to the online store in chunks of three rows
+	// with the options set in runner, and every write of the chunk at failChunk
+	// fails, if it's set.
+	materialize := func(redisConfig *pc.RedisConfig, values map[string]int, failChunk int64, runner MaterializeRunner) error {
+		offline := provider.NewMemoryOfflineStore()
+		table, err := offline.CreateResourceTable(id, provider.TableSchema{})
+		if err != nil {
+			return fmt.Errorf("create resource table: %w", err)
+		}
+		for entity, value := range values {
+			if err := table.Write(provider.ResourceRecord{Entity: entity, Value: value}); err != nil {
+				return fmt.Errorf("write %s: %w", entity, err)
+			}
+		}
+		delete(factoryMap, string(COPY_TO_ONLINE))
+		defer delete(factoryMap, string(COPY_TO_ONLINE))
+		// Chunks are created in order of their index
+		var nextIdx int64
+		chunkFactory := func(config Config) (types.Runner, error) {
+			chunkConfig := &MaterializedChunkRunnerConfig{}
+			if err := chunkConfig.Deserialize(config); err != nil {
+				return nil, err
+			}
+			materialization, err := offline.GetMaterialization(chunkConfig.MaterializedID)
+			if err != nil {
+				return nil, err
+			}
+			online, err := provider.NewRedisOnlineStore(redisConfig)
+			if err != nil {
+				return nil, err
+			}
+			table, err := online.GetTable(id.Name, id.Variant)
+			if err != nil {
+				return nil, err
+			}
+			if nextIdx == failChunk {
+				table = failingTable{table}
+			}
+			nextIdx++
+			return &MaterializedChunkRunner{
+				Materialized: materialization,
+				Table:        table,
+				Store:        online,
+				ChunkSize:    chunkConfig.ChunkSize,
+				TrackWrites:  chunkConfig.TrackWrites,
+			}, nil
+		}
+		if err := RegisterFactory(string(COPY_TO_ONLINE), chunkFactory); err != nil {
+			return err
+		}
+		online, err := provider.NewRedisOnlineStore(redisConfig)
+		if err != nil {
+			return fmt.Errorf("create redis online store: %w", err)
+		}
+		defer online.Close()
+		runner.Online = online
+		runner.Offline = offline
+		runner.ID = id
+		runner.VType = provider.Int
+		runner.Cloud = LocalMaterializeRunner
+		runner.Logger = zaptest.NewLogger(t).Sugar()
+		runner.MaxChunkRows = 3
+		watcher, err := runner.Run()
+		if err != nil {
+			return fmt.Errorf("run: %w", err)
+		}
+		return watcher.Wait()
+	}
+
+	// onlineValues returns the value of each entity in updated that's in the
+	// online store
+	onlineValues := func(redisConfig *pc.RedisConfig) map[string]interface{} {
+		online, err := provider.NewRedisOnlineStore(redisConfig)
+		if err != nil {
+			t.Fatalf("could not create redis online store: %v", err)
+		}
+		defer online.Close()
+		table, err := online.GetTable(id.Name, id.Variant)
+		if err != nil {
+			t.Fatalf("could not get online table: %v", err)
+		}
+		found := make(map[string]interface{})
+		for entity := range updated {
+			if value, err := table.Get(entity); err == nil {
+				found[entity] = value
+			}
+		}
+		return found
+	}
+
+	tests := []struct {
+		name       string
+		policy     ChunkFailurePolicy
+		truncate   bool
+		historical bool
+	}{
+		{"Atomic", AtomicChunkFailure, false, false},
+		{"AtomicTruncate", AtomicChunkFailure, true, false},
+		{"AtomicHistorical", AtomicChunkFailure, false, true},
+		{"BestEffort", BestEffortChunkFailure, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mRedis, err := miniredis.Run()
+			if err != nil {
+				t.Fatalf("could not start mock redis: %v", err)
+			}
+			defer mRedis.Close()
+			redisConfig := &pc.RedisConfig{Addr: mRedis.Addr()}
+			runner := MaterializeRunner{ChunkFailurePolicy: tt.policy, Historical: tt.historical}
+			if err := materialize(redisConfig, original, -1, runner); err != nil {
+				t.Fatalf("could not materialize original values: %v", err)
+			}
+			// The update has seven rows, so its middle chunk of three fails
+			runner.IsUpdate = true
+			runner.Truncate = tt.truncate
+			err = materialize(redisConfig, updated, 1, runner)
+			if err == nil {
+				t.Fatalf("expected materialization with a failing chunk to fail")
+			}
+			found := onlineValues(redisConfig)
+			switch tt.policy {
+			case AtomicChunkFailure:
+				if len(found) != len(original) {
+					t.Fatalf("expected only the original entities after rollback, got %v", found)
+				}
+				for entity, value := range original {
+					if found[entity] != value {
+						t.Fatalf("expected %s to be rolled back to %d, got %v", entity, value, found[entity])
+					}
+				}
+			case BestEffortChunkFailure:
+				var partial PartialMaterializationError
+				if !errors.As(err, &partial) {
+					t.Fatalf("expected partial materialization error, got %v", err)
+				}
+				if partial.CompletedChunks != 2 || partial.TotalChunks != 3 {
+					t.Fatalf("expected 2 of 3 chunks completed, got %d of %d", partial.CompletedChunks, partial.TotalChunks)
+				}
+				written := 0
+				for entity, value := range found {
+					if value == updated[entity] {
+						written++
+					} else if value != original[entity] {
+						t.Fatalf("expected %s to hold its original or updated value, got %v", entity, value)
+					}
+				}
+				if written != 4 {
+					t.Fatalf("expected the 4 rows of the successful chunks to be kept, got %d: %v", written, found)
+				}
+			}
+		})
+	}
+
+	t.Run("AtomicTruncateHistorical", func(t *testing.T) {
+		mRedis, err := miniredis.Run()
+		if err != nil {
+			t.Fatalf("could not start mock redis: %v", err)
+		}
+		defer mRedis.Close()
+		redisConfig := &pc.RedisConfig{Addr: mRedis.Addr()}
+		runner := MaterializeRunner{ChunkFailurePolicy: AtomicChunkFailure, Historical: true}
+		if err := materialize(redisConfig, original, -1, runner); err != nil {
+			t.Fatalf("could not materialize original values: %v", err)
+		}
+		runner.IsUpdate = true
+		runner.Truncate = true
+		if err := materialize(redisConfig, updated, -1, runner); err == nil {
+			t.Fatalf("expected truncating a historical table under the atomic policy to fail")
+		}
+		found := onlineValues(redisConfig)
+		if len(found) != len(original) {
+			t.Fatalf("expected the table to be left untouched, got %v", found)
+		}
+	})
+}
+
+// concurrencyTable counts how many chunks are writing to the online store at
+// once
+type concurrencyTable struct {
+	provider.OnlineStoreTable
+	mtx     *sync.Mutex
+	running *int
+	most    *int
+}
+
+func (t concurrencyTable) Set(entity string, value interface{}) error {
+	t.mtx.Lock()
+	*t.running++
+	if *t.running > *t.most {
+		*t.most = *t.running
+	}
+	t.mtx.Unlock()
+	defer func() {
+		t.mtx.Lock()
+		*t.running--
+		t.mtx.Unlock()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	return t.OnlineStoreTable.Set(entity, value)
+}
+
+func TestMaterializeParallelism(t *testing.T) {
+	id := provider.ResourceID{Name: "feature", Variant: "variant", Type: provider.Feature}
+	values := map[string]int{"A": 1, "B": 2, "C": 3, "D": 4, "E": 5}
+	cases := []struct {
+		name        string
+		parallelism int
+		expectMost  int
+	}{
+		{"Limited", 2, 2},
+		{"More Workers Than Rows", 8, len(values)},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mRedis, err := miniredis.Run()
+			if err != nil {
+				t.Fatalf("could not start mock redis: %v", err)
+			}
+			defer mRedis.Close()
+			redisConfig := &pc.RedisConfig{Addr: mRedis.Addr()}
+			offline := provider.NewMemoryOfflineStore()
+			table, err := offline.CreateResourceTable(id, provider.TableSchema{})
+			if err != nil {
+				t.Fatalf("could not create resource table: %v", err)
+			}
+			for entity, value := range values {
+				if err := table.Write(provider.ResourceRecord{Entity: entity, Value: value}); err != nil {
+					t.Fatalf("could not write %s: %v", entity, err)
+				}
+			}
+			var mtx sync.Mutex
+			var running, most int
+			delete(factoryMap, string(COPY_TO_ONLINE))
+			defer delete(factoryMap, string(COPY_TO_ONLINE))
+			chunkFactory := func(config Config) (types.Runner, error) {
+				chunkConfig := &MaterializedChunkRunnerConfig{}
+				if err := chunkConfig.Deserialize(config); err != nil {
+					return nil, err
+				}
+				materialization, err := offline.GetMaterialization(chunkConfig.MaterializedID)
+				if err != nil {
+					return nil, err
+				}
+				online, err := provider.NewRedisOnlineStore(redisConfig)
+				if err != nil {
+					return nil, err
+				}
+				table, err := online.GetTable(id.Name, id.Variant)
+				if err != nil {
+					return nil, err
+				}
+				return &MaterializedChunkRunner{
+					Materialized: materialization,
+					Table:        concurrencyTable{table, &mtx, &running, &most},
+					Store:        online,
+					ChunkSize:    chunkConfig.ChunkSize,
+				}, nil
+			}
+			if err := RegisterFactory(string(COPY_TO_ONLINE), chunkFactory); err != nil {
+				t.Fatalf("could not register chunk factory: %v", err)
+			}
+			online, err := provider.NewRedisOnlineStore(redisConfig)
+			if err != nil {
+				t.Fatalf("could not create redis online store: %v", err)
+			}
+			defer online.Close()
+			materializeRunner := MaterializeRunner{
+				Online:       online,
+				Offline:      offline,
+				ID:           id,
+				VType:        provider.Int,
+				Cloud:        LocalMaterializeRunner,
+				Logger:       zaptest.NewLogger(t).Sugar(),
+				MaxChunkRows: 1,
+				Parallelism:  c.parallelism,
+			}
+			watcher, err := materializeRunner.Run()
+			if err != nil {
+				t.Fatalf("could not run materialization: %v", err)
+			}
+			if err := watcher.Wait(); err != nil {
+				t.Fatalf("materialization failed: %v", err)
+			}
+			progress := watcher.(ProgressWatcher).Progress()
+			if progress.CompletedChunks != int64(len(values)) || progress.TotalChunks != int64(len(values)) {
+				t.Fatalf("expected all %d chunks to complete, got %d of %d", len(values), progress.CompletedChunks, progress.TotalChunks)
+			}
+			if most > c.expectMost {
+				t.Fatalf("expected at most %d chunks to write at once, got %d", c.expectMost, most)
+			}
+			onlineTable, err := online.GetTable(id.Name, id.Variant)
+			if err != nil {
+				t.Fatalf("could not get online table: %v", err)
+			}
+			for entity, value := range values {
+				found, err := onlineTable.Get(entity)
+				if err != nil {
+					t.Fatalf("could not get %s: %v", entity, err)
+				}
+				if found != value {
+					t.Fatalf("expected %s to be %d, got %v", entity, value, found)
+				}
+			}
+		})
+	}
+}
+
+// writesTable records which entities are written to the online store
+type writesTable struct {
+	provider.OnlineStoreTable
+	mtx     *sync.Mutex
+	written map[string]interface{}
+}
+
+func (t writesTable) Set(entity string, value interface{}) error {
+	t.mtx.Lock()
+	t.written[entity] = value
+	t.mtx.Unlock()
+	return t.OnlineStoreTable.Set(entity, value)
+}
+
+func TestMaterializeIncremental(t *testing.T) {
+	mRedis, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("could not start mock redis: %v", err)
+	}
+	defer mRedis.Close()
+	redisConfig := &pc.RedisConfig{Addr: mRedis.Addr()}
+	id := provider.ResourceID{Name: "feature", Variant: "variant", Type: provider.Feature}
+	initialValues := []provider.ResourceRecord{
+		{Entity: "a", Value: 1, TS: time.UnixMilli(0).UTC()},
+		{Entity: "b", Value: 2, TS: time.UnixMilli(0).UTC()},
+		{Entity: "c", Value: 3, TS: time.UnixMilli(0).UTC()},
+		{Entity: "d", Value: 4, TS: time.UnixMilli(0).UTC()},
+		{Entity: "e", Value: 5, TS: time.UnixMilli(0).UTC()},
+	}
+	appendedValues := []provider.ResourceRecord{
+		{Entity: "a", Value: 6, TS: time.UnixMilli(1).UTC()},
+		{Entity: "c", Value: 8, TS: time.UnixMilli(2).UTC()},
+		{Entity: "f", Value: 11, TS: time.UnixMilli(1).UTC()},
+	}
+	offline := provider.NewMemoryOfflineStore()
+	table, err := offline.CreateResourceTable(id, provider.TableSchema{})
+	if err != nil {
+		t.Fatalf("could not create resource table: %v", err)
+	}
+	var mtx sync.Mutex
+	written := make(map[string]interface{})
+	delete(factoryMap, string(COPY_TO_ONLINE))
+	defer delete(factoryMap, string(COPY_TO_ONLINE))
+	chunkFactory := func(config Config) (types.Runner, error) {
+		chunkConfig := &MaterializedChunkRunnerConfig{}
+		if err := chunkConfig.Deserialize(config); err != nil {
+			return nil, err
+		}
+		materialization, err := offline.GetMaterialization(chunkConfig.MaterializedID)
+		if err != nil {
+			return nil, err
+		}
+		online, err := provider.NewRedisOnlineStore(redisConfig)
+		if err != nil {
+			return nil, err
+		}
+		table, err := online.GetTable(id.Name, id.Variant)
+		if err != nil {
+			return nil, err
+		}
+		return &MaterializedChunkRunner{
+			Materialized: materialization,
+			Table:        writesTable{table, &mtx, written},
+			Store:        online,
+			ChunkSize:    chunkConfig.ChunkSize,
+			Since:        chunkConfig.Since,
+		}, nil
+	}
+	if err := RegisterFactory(string(COPY_TO_ONLINE), chunkFactory); err != nil {
+		t.Fatalf("could not register chunk factory: %v", err)
+	}
+	online, err := provider.NewRedisOnlineStore(redisConfig)
+	if err != nil {
+		t.Fatalf("could not create redis online store: %v", err)
+	}
+	defer online.Close()
+	materialize := func(records []provider.ResourceRecord, isUpdate bool, since time.Time) time.Time {
+		for _, record := range records {
+			if err := table.Write(record); err != nil {
+				t.Fatalf("could not write %s: %v", record.Entity, err)
+			}
+		}
+		for entity := range written {
+			delete(written, entity)
+		}
+		materializeRunner := MaterializeRunner{
+			Online:   online,
+			Offline:  offline,
+			ID:       id,
+			VType:    provider.Int,
+			IsUpdate: isUpdate,
+			Cloud:    LocalMaterializeRunner,
+			Logger:   zaptest.NewLogger(t).Sugar(),
+			Since:    since,
+		}
+		watcher, err := materializeRunner.Run()
+		if err != nil {
+			t.Fatalf("could not run materialization: %v", err)
+		}
+		if err := watcher.Wait(); err != nil {
+			t.Fatalf("materialization failed: %v", err)
+		}
+		return watcher.(WatermarkWatcher).Watermark()
+	}
+
+	watermark := materialize(initialValues, false, time.Time{})
+	if len(written) != len(initialValues) {
+		t.Fatalf("expected the first run to write all %d entities, got %v", len(initialValues), written)
+	}
+	if !watermark.Equal(time.UnixMilli(0).UTC()) {
+		t.Fatalf("expected a watermark of %v, got %v", time.UnixMilli(0).UTC(), watermark)
+	}
+
+	watermark = materialize(appendedValues, true, watermark)
+	expected := map[string]interface{}{"a": 6, "c": 8, "f": 11}
+	if len(written) != len(expected) {
+		t.Fatalf("expected only the appended rows to be written, got %v", written)
+	}
+	for entity, value := range expected {
+		if written[entity] != value {
+			t.Fatalf("expected %s to be written as %v, got %v", entity, value, written[entity])
+		}
+	}
+	if !watermark.Equal(time.UnixMilli(2).UTC()) {
+		t.Fatalf("expected a watermark of %v, got %v", time.UnixMilli(2).UTC(), watermark)
+	}
+	onlineTable, err := online.GetTable(id.Name, id.Variant)
+	if err != nil {
+		t.Fatalf("could not get online table: %v", err)
+	}
+	for entity, value := range map[string]int{"a": 6, "b": 2, "c": 8, "d": 4, "e": 5, "f": 11} {
+		found, err := onlineTable.Get(entity)
+		if err != nil {
+			t.Fatalf("could not get %s: %v", entity, err)
+		}
+		if found != value {
+			t.Fatalf("expected %s to be %d, got %v", entity, value, found)
+		}
+	}
+
+	// Nothing new keeps the watermark where it was
+	if next := materialize(nil, true, watermark); len(written) != 0 || !next.Equal(watermark) {
+		t.Fatalf("expected nothing to be written and the watermark to stay at %v, got %v and %v", watermark, written, next)
+	}
+}
+
+// TestMaterializeRunnerTTL checks that a feature's TTL set on the runner's
+// config reaches every chunk's writes
+func TestMaterializeRunnerTTL(t *testing.T) {
+	id := provider.ResourceID{Name: "feature", Variant: "variant", Type: provider.Feature}
+	offline := provider.NewMemoryOfflineStore()
+	resourceTable, err := offline.CreateResourceTable(id, provider.TableSchema{})
+	if err != nil {
+		t.Fatalf("could not create resource table: %v", err)
+	}
+	entities := []string{"a", "b", "c", "d"}
+	for i, entity := range entities {
+		if err := resourceTable.Write(provider.ResourceRecord{Entity: entity, Value: i}); err != nil {
+			t.Fatalf("could not write %s: %v", entity, err)
+		}
+	}
+	online := NewMockOnlineStore()
+	table := &expiringOnlineTable{
+		MockOnlineTable: MockOnlineTable{DataTable: make(map[string]interface{})},
+		ttls:            make(map[string]time.Duration),
+	}
+	delete(factoryMap, string(COPY_TO_ONLINE))
+	defer delete(factoryMap, string(COPY_TO_ONLINE))
+	chunkFactory := func(config Config) (types.Runner, error) {
+		chunkConfig := &MaterializedChunkRunnerConfig{}
+		if err := chunkConfig.Deserialize(config); err != nil {
+			return nil, err
+		}
+		materialization, err := offline.GetMaterialization(chunkConfig.MaterializedID)
+		if err != nil {
+			return nil, err
+		}
+		return &MaterializedChunkRunner{
+			Materialized: materialization,
+			Table:        table,
+			Store:        online,
+			ChunkSize:    chunkConfig.ChunkSize,
+			ChunkIdx:     chunkConfig.ChunkIdx,
+			TTL:          chunkConfig.TTL,
+		}, nil
+	}
+	if err := RegisterFactory(string(COPY_TO_ONLINE), chunkFactory); err != nil {
+		t.Fatalf("could not register chunk factory: %v", err)
+	}
+	serialized, err := (&MaterializedRunnerConfig{TTL: time.Hour}).Serialize()
+	if err != nil {
+		t.Fatalf("could not serialize runner config: %v", err)
+	}
+	runnerConfig := &MaterializedRunnerConfig{}
+	if err := runnerConfig.Deserialize(serialized); err != nil {
+		t.Fatalf("could not deserialize runner config: %v", err)
+	}
+	materializeRunner := MaterializeRunner{
+		Online:       online,
+		Offline:      offline,
+		ID:           id,
+		VType:        provider.Int,
+		Cloud:        LocalMaterializeRunner,
+		Logger:       zaptest.NewLogger(t).Sugar(),
+		MaxChunkRows: 1,
+		TTL:          runnerConfig.TTL,
+	}
+	watcher, err := materializeRunner.Run()
+	if err != nil {
+		t.Fatalf("could not run materialization: %v", err)
+	}
+	if err := watcher.Wait(); err != nil {
+		t.Fatalf("materialization failed: %v", err)
+	}
+	for _, entity := range entities {
+		if ttl, has := table.ttls[entity]; !has || ttl != time.Hour {
+			t.Fatalf("expected %s to be written with a ttl of %s, got %s", entity, time.Hour, ttl)
+		}
+	}
+}
+
+func TestMaterializeProgressPercent(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress MaterializeProgress
+		expected float64
+	}{
+		{"rows", MaterializeProgress{TotalRows: 200, RowsWritten: 50, TotalChunks: 2}, 25},
+		{"rows complete", MaterializeProgress{TotalRows: 200, RowsWritten: 200, TotalChunks: 2, CompletedChunks: 2}, 100},
+		{"unknown rows", MaterializeProgress{TotalChunks: 4, CompletedChunks: 1}, 25},
+		{"nothing to do", MaterializeProgress{}, 0},
+	}
+	for _, test := range tests {
+		if percent := test.progress.Percent(); percent != test.expected {
+			t.Errorf("%s: expected %v%%, got %v%%", test.name, test.expected, percent)
+		}
+	}
+}
+
 func TestWatcherMultiplex(t *testing.T) {
 	watcherList := make([]types.CompletionWatcher, 1)
 	watcherList[0] = &mockCompletionWatcher{}
@@ -79,11 +79,11 @@ func RegisterSourceRunnerFactory(config Config) (types.Runner, error) {
 	}
 	offlineProvider, err := provider.Get(registerConfig.OfflineType, registerConfig.OfflineConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to configure offline provider: %v", err)
+		return nil, fmt.Errorf("failed to configure offline provider: %w", err)
 	}
 	offlineStore, err := offlineProvider.AsOfflineStore()
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert provider to offline store: %v", err)
+		return nil, fmt.Errorf("failed to convert provider to offline store: %w", err)
 	}
 	return &RegisterSourceRunner{
 		Offline:         offlineStore,
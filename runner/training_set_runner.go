@@ -19,6 +19,7 @@ type TrainingSetRunner struct {
 	Offline  provider.OfflineStore
 	Def      provider.TrainingSetDef
 	IsUpdate bool
+	cancel   <-chan struct{}
 }
 
 func (m TrainingSetRunner) Run() (types.CompletionWatcher, error) {
@@ -28,22 +29,30 @@ func (m TrainingSetRunner) Run() (types.CompletionWatcher, error) {
 		DoneChannel: done,
 	}
 	go func() {
+		// Like transformations, a training set that's being created can't be
+		// interrupted, so cancelling only stops one that hasn't started
+		if isCancelled(m.cancel) {
+			trainingSetWatcher.EndWatch(ErrJobCancelled)
+			return
+		}
+		var err error
 		if !m.IsUpdate {
-			if err := m.Offline.CreateTrainingSet(m.Def); err != nil {
-				trainingSetWatcher.EndWatch(err)
-				return
-			}
+			err = m.Offline.CreateTrainingSet(m.Def)
 		} else {
-			if err := m.Offline.UpdateTrainingSet(m.Def); err != nil {
-				trainingSetWatcher.EndWatch(err)
-				return
-			}
+			err = m.Offline.UpdateTrainingSet(m.Def)
+		}
+		if err == nil && isCancelled(m.cancel) {
+			err = ErrJobCancelled
 		}
-		trainingSetWatcher.EndWatch(nil)
+		trainingSetWatcher.EndWatch(err)
 	}()
 	return trainingSetWatcher, nil
 }
 
+func (m *TrainingSetRunner) SetCancel(cancel <-chan struct{}) {
+	m.cancel = cancel
+}
+
 type TrainingSetRunnerConfig struct {
 	OfflineType   pt.Type
 	OfflineConfig pc.SerializedConfig
@@ -87,11 +96,11 @@ func TrainingSetRunnerFactory(config Config) (types.Runner, error) {
 	}
 	offlineProvider, err := provider.Get(runnerConfig.OfflineType, runnerConfig.OfflineConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to configure offline provider: %v", err)
+		return nil, fmt.Errorf("failed to configure offline provider: %w", err)
 	}
 	offlineStore, err := offlineProvider.AsOfflineStore()
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert provider to offline store: %v", err)
+		return nil, fmt.Errorf("failed to convert provider to offline store: %w", err)
 	}
 	return &TrainingSetRunner{
 		Offline:  offlineStore,
@@ -5,6 +5,7 @@
 package runner
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -74,9 +75,9 @@ func (m MockOfflineCreateTrainingSetFail) Close() error {
 
 func TestRunTrainingSet(t *testing.T) {
 	runner := TrainingSetRunner{
-		MockOfflineStore{},
-		provider.TrainingSetDef{},
-		false,
+		Offline:  MockOfflineStore{},
+		Def:      provider.TrainingSetDef{},
+		IsUpdate: false,
 	}
 	watcher, err := runner.Run()
 	if err != nil {
@@ -89,9 +90,9 @@ func TestRunTrainingSet(t *testing.T) {
 
 func TestFailTrainingSet(t *testing.T) {
 	runner := TrainingSetRunner{
-		MockOfflineCreateTrainingSetFail{},
-		provider.TrainingSetDef{},
-		false,
+		Offline:  MockOfflineCreateTrainingSetFail{},
+		Def:      provider.TrainingSetDef{},
+		IsUpdate: false,
 	}
 	watcher, err := runner.Run()
 	if err != nil {
@@ -102,6 +103,23 @@ func TestFailTrainingSet(t *testing.T) {
 	}
 }
 
+func TestCancelTrainingSet(t *testing.T) {
+	runner := &TrainingSetRunner{
+		Offline: MockOfflineStore{},
+		Def:     provider.TrainingSetDef{},
+	}
+	cancel := make(chan struct{})
+	close(cancel)
+	runner.SetCancel(cancel)
+	watcher, err := runner.Run()
+	if err != nil {
+		t.Fatalf("failed to create create training set runner: %v", err)
+	}
+	if err := watcher.Wait(); !errors.Is(err, ErrJobCancelled) {
+		t.Fatalf("expected cancelled training set runner to fail with %v, got %v", ErrJobCancelled, err)
+	}
+}
+
 func testTrainingSetErrorConfigsFactory(config Config) error {
 	_, err := Create("TEST_CREATE_TRAINING_SET", config)
 	return err
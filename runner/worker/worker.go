@@ -67,6 +67,16 @@ func CreateAndRun() error {
 		}
 		jobRunner = indexRunner
 	}
+	if recording, ok := jobRunner.(runner.ProgressRecordingRunner); ok {
+		if progressConf, hasEtcd := os.LookupEnv("ETCD_CONFIG"); hasEtcd {
+			cli, err := newEtcdClient(progressConf)
+			if err != nil {
+				return fmt.Errorf("connect to etcd to record progress: %w", err)
+			}
+			defer cli.Close()
+			recording.SetProgressRecorder(coordinator.NewEtcdProgressRecorder(cli, jobRunner.Resource()))
+		}
+	}
 	watcher, err := jobRunner.Run()
 	if err != nil {
 		return err
@@ -78,12 +88,7 @@ func CreateAndRun() error {
 	if jobRunner.IsUpdateJob() {
 		jobResource := jobRunner.Resource()
 		logger.Infof("Logging update success in etcd for job: %v", jobResource)
-		etcdConfig := &coordinator.ETCDConfig{}
-		err := etcdConfig.Deserialize(coordinator.Config(etcdConf))
-		if err != nil {
-			return err
-		}
-		cli, err := clientv3.New(clientv3.Config{Endpoints: etcdConfig.Endpoints, Username: etcdConfig.Username, Password: etcdConfig.Password, DialTimeout: time.Second * 5})
+		cli, err := newEtcdClient(etcdConf)
 		if err != nil {
 			return err
 		}
@@ -108,3 +113,11 @@ func CreateAndRun() error {
 	}
 	return nil
 }
+
+func newEtcdClient(conf string) (*clientv3.Client, error) {
+	etcdConfig := &coordinator.ETCDConfig{}
+	if err := etcdConfig.Deserialize(coordinator.Config(conf)); err != nil {
+		return nil, err
+	}
+	return clientv3.New(clientv3.Config{Endpoints: etcdConfig.Endpoints, Username: etcdConfig.Username, Password: etcdConfig.Password, DialTimeout: time.Second * 5})
+}